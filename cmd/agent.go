@@ -3,9 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/i18n"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +23,13 @@ func newAgentCmd() *cobra.Command {
 	var verbose bool
 	var useWebSocket bool
 	var streamTokens bool
+	var envVars []string
+	var tags []string
+	var project string
+	var review bool
+	var reviewModel string
+	var verifyCommand string
+	var dirtyTree string
 
 	cmd := &cobra.Command{
 		Use:   "agent",
@@ -43,6 +54,18 @@ Examples:
   # Use WebSocket for bidirectional communication
   zen-claw agent --ws "analyze codebase"
 
+  # Inject env vars into exec/process tools (secrets are redacted from output)
+  zen-claw agent --env DATABASE_URL=keyring:myapp/db --env API_TOKEN=op://dev/api/token "run integration tests"
+
+  # Tag a session (filter later with: zen-claw sessions list --tag infra)
+  zen-claw agent --session vpc --tag infra "review the terraform plan"
+
+  # Review the changes (diff + build/tests) before finishing; fixes go back to the agent
+  zen-claw agent --review --review-model qwen/qwen-max "add pagination to the users API"
+
+  # Stash your uncommitted changes while the agent works; they come back after the run
+  zen-claw agent --dirty-tree stash "rename the config package"
+
 Multi-AI modes (separate commands):
   zen-claw consensus   # 3 AIs → arbiter → better blueprints
   zen-claw factory     # Coordinator + specialist AIs`,
@@ -52,7 +75,12 @@ Multi-AI modes (separate commands):
 			if len(args) > 0 {
 				task = args[0]
 			}
-			runAgent(task, model, provider, workingDir, sessionID, showProgress, maxSteps, verbose, useWebSocket, streamTokens)
+			env, err := parseEnvFlags(envVars)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			runAgent(task, model, provider, workingDir, sessionID, showProgress, maxSteps, verbose, useWebSocket, streamTokens, env, tags, project, reviewOptions(review, reviewModel, verifyCommand), dirtyTree, false)
 		},
 	}
 
@@ -65,14 +93,57 @@ Multi-AI modes (separate commands):
 	cmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output for debugging")
 	cmd.Flags().BoolVar(&useWebSocket, "ws", false, "Use WebSocket instead of SSE streaming")
 	cmd.Flags().BoolVar(&streamTokens, "stream", false, "Stream AI response token-by-token")
+	cmd.Flags().StringArrayVar(&envVars, "env", nil, "Session env var KEY=VALUE for exec/process tools (VALUE may be keyring:<service>/<account> or op://... if the gateway's tools.secret_refs allows it)")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Tag the session (repeatable or comma-separated, e.g. --tag infra)")
+	cmd.Flags().StringVar(&project, "project", "", "Project for the session (default: derived from the working dir's git remote)")
+	cmd.Flags().BoolVar(&review, "review", false, "Review the changes against the task and run build/tests before finishing (default: agent.review)")
+	cmd.Flags().StringVar(&reviewModel, "review-model", "", "Reviewer as provider/model or model (implies --review; default: the session's model)")
+	cmd.Flags().StringVar(&verifyCommand, "verify", "", "Build/test command the review runs (implies --review; default: detected, \"none\" skips)")
+	cmd.Flags().StringVar(&dirtyTree, "dirty-tree", "", "Uncommitted changes the agent didn't make: warn, stash (restored after each run) or off (default: agent.dirty_tree)")
 
 	return cmd
 }
 
-func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showProgress bool, maxSteps int, verbose bool, useWebSocket bool, streamTokens bool) {
+// parseEnvFlags parses repeated --env KEY=VALUE flags
+func parseEnvFlags(flags []string) (map[string]string, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(flags))
+	for _, kv := range flags {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --env %q (want KEY=VALUE)", kv)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// reviewOptions turns the review flags into the request's review settings
+// (nil leaves the gateway's agent.review in effect)
+func reviewOptions(review bool, reviewModel, verifyCommand string) *types.SelfReview {
+	if !review && reviewModel == "" && verifyCommand == "" {
+		return nil
+	}
+	opts := &types.SelfReview{Enabled: true, VerifyCommand: verifyCommand}
+	if provider, model, ok := strings.Cut(reviewModel, "/"); ok {
+		opts.Provider, opts.Model = provider, model
+	} else {
+		opts.Model = reviewModel
+	}
+	return opts
+}
+
+func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showProgress bool, maxSteps int, verbose bool, useWebSocket bool, streamTokens bool, env map[string]string, tags []string, project string, review *types.SelfReview, dirtyTree string, readOnly bool) {
+	// Send an absolute root: the gateway resolves relative paths against its own cwd
+	if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+		workingDir = abs
+	}
+
 	// Interactive mode if no task provided
 	if task == "" {
-		runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID, showProgress, maxSteps, verbose, useWebSocket, streamTokens)
+		runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID, showProgress, maxSteps, verbose, useWebSocket, streamTokens, env, tags, project, review, dirtyTree, readOnly)
 		return
 	}
 	// Token streaming is passed in the request below
@@ -83,7 +154,7 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 
 	// Use WebSocket if requested
 	if useWebSocket {
-		runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID, maxSteps, verbose)
+		runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID, maxSteps, verbose, env, tags, project)
 		return
 	}
 
@@ -101,11 +172,15 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 
 	// Create gateway client
 	client := NewGatewayClient(getGatewayURL())
+	client.SetEnv(env)
+	client.SetLabels(tags, project)
+	client.SetReview(review)
+	client.SetDirtyTree(dirtyTree)
+	client.SetReadOnly(readOnly)
 
 	// Check if gateway is running
-	if err := client.HealthCheck(); err != nil {
+	if err := ensureGateway(client); err != nil {
 		fmt.Printf("\n❌ Gateway not available: %v\n", err)
-		fmt.Println("   Start the gateway first: zen-claw gateway start")
 		os.Exit(1)
 	}
 
@@ -127,15 +202,27 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 		modelName = providers.GetDefaultModel(providerName)
 	}
 
-	fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	// Without flags a resumed session keeps its saved provider/model
+	reqProvider, reqModel := "", ""
+	if providerFlag != "" || modelFlag != "" {
+		reqProvider, reqModel = providerName, modelName
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	} else if sessionID != "" {
+		fmt.Printf("Provider: session setting (default %s, model %s)\n", providerName, modelName)
+	} else {
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	}
+
+	// Progress and result headers follow the configured language
+	uiLang := configuredLanguage()
 
 	// Prepare request
 	req := ChatRequest{
 		SessionID:  sessionID,
 		UserInput:  task,
 		WorkingDir: workingDir,
-		Provider:   providerName,
-		Model:      modelName,
+		Provider:   reqProvider,
+		Model:      reqModel,
 		MaxSteps:   maxSteps,
 		Stream:     streamTokens,
 	}
@@ -144,7 +231,10 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 
 	// Use streaming for better UX
 	resp, err := client.SendWithProgress(req, func(event ProgressEvent) {
-		displayProgressEvent(event)
+		displayProgressEvent(event, uiLang)
+		if verbose {
+			displayVerboseEvent(event)
+		}
 	})
 	if err != nil {
 		fmt.Printf("\n❌ Gateway request failed: %v\n", err)
@@ -158,7 +248,7 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 
 	// Print result
 	fmt.Println("\n" + strings.Repeat("═", 80))
-	fmt.Println("🎯 RESULT")
+	fmt.Println(i18n.T(uiLang, "result"))
 	fmt.Println(strings.Repeat("═", 80))
 	fmt.Println(resp.Result)
 	fmt.Println(strings.Repeat("═", 80))
@@ -186,20 +276,52 @@ func runAgent(task, modelFlag, providerFlag, workingDir, sessionID string, showP
 		}
 	}
 
+	if r := resp.Review; r != nil {
+		printReviewOutcome(r)
+	}
+
+	if limit := resp.StepLimit; limit != nil {
+		fmt.Println("\n" + i18n.T(uiLang, "step_limit_partial", limit.Steps))
+		fmt.Printf("   zen-claw agent --session %s --max-steps %d %q\n", resp.SessionID, limit.Extend, types.ContinuePrompt)
+	}
+
 	if showProgress {
 		fmt.Printf("\n💡 To continue this session:\n")
 		fmt.Printf("   zen-claw agent --session %s \"your next task\"\n", resp.SessionID)
 	}
 }
 
+// printReviewOutcome shows how the self-review of the changes went
+func printReviewOutcome(r *types.ReviewOutcome) {
+	verify := ""
+	if r.VerifyPassed != nil {
+		verify = fmt.Sprintf(", %s: failed", r.VerifyCommand)
+		if *r.VerifyPassed {
+			verify = fmt.Sprintf(", %s: passed", r.VerifyCommand)
+		}
+	}
+	if r.Approved {
+		fmt.Printf("\n🔍 Review approved the changes (%d review(s)%s)\n", r.Rounds, verify)
+		return
+	}
+	fmt.Printf("\n⚠️  Review still found issues after %d review(s)%s:\n", r.Rounds, verify)
+	for _, issue := range r.Issues {
+		fmt.Printf("   - %s\n", issue)
+	}
+}
+
 // runAgentWebSocket runs the agent using WebSocket connection
-func runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID string, maxSteps int, verbose bool) {
+func runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID string, maxSteps int, verbose bool, env map[string]string, tags []string, project string) {
 	fmt.Println("🚀 Zen Agent (WebSocket)")
 	fmt.Println("═" + strings.Repeat("═", 78))
 	fmt.Printf("Task: %s\n", task)
 	fmt.Printf("Working directory: %s\n", workingDir)
 
-	// Connect via WebSocket
+	// Connect via WebSocket (once a gateway runs; this may start one)
+	if err := ensureGateway(NewGatewayClient(getGatewayURL())); err != nil {
+		fmt.Printf("\n❌ Gateway not available: %v\n", err)
+		os.Exit(1)
+	}
 	wsURL := getGatewayWSURL()
 	if verbose {
 		fmt.Printf("Connecting to %s...\n", wsURL)
@@ -229,17 +351,32 @@ func runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID stri
 		modelName = providers.GetDefaultModel(providerName)
 	}
 
-	fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	// Without flags a resumed session keeps its saved provider/model
+	reqProvider, reqModel := "", ""
+	if providerFlag != "" || modelFlag != "" {
+		reqProvider, reqModel = providerName, modelName
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	} else if sessionID != "" {
+		fmt.Printf("Provider: session setting (default %s, model %s)\n", providerName, modelName)
+	} else {
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	}
 	fmt.Println()
 
+	// Progress and result headers follow the configured language
+	uiLang := configuredLanguage()
+
 	// Create request
 	req := WSChatRequest{
 		SessionID:  sessionID,
 		UserInput:  task,
 		WorkingDir: workingDir,
-		Provider:   providerName,
-		Model:      modelName,
+		Provider:   reqProvider,
+		Model:      reqModel,
 		MaxSteps:   maxSteps,
+		Env:        env,
+		Tags:       tags,
+		Project:    project,
 	}
 
 	// Run chat with progress
@@ -249,7 +386,10 @@ func runAgentWebSocket(task, modelFlag, providerFlag, workingDir, sessionID stri
 
 	go func() {
 		client.Chat(req, func(event ProgressEvent) {
-			displayProgressEvent(event)
+			displayProgressEvent(event, uiLang)
+			if verbose {
+				displayVerboseEvent(event)
+			}
 		}, func(resp *ChatResponse, err error) {
 			finalResp = resp
 			finalErr = err
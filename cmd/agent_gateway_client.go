@@ -3,32 +3,129 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/gateway"
 	"github.com/neves/zen-claw/internal/types"
 )
 
 // GatewayClient handles communication with the Zen Claw gateway
 type GatewayClient struct {
-	baseURL string
-	client  *http.Client
+	baseURL   string
+	client    *http.Client
+	env       map[string]string // Sent with every request that doesn't set its own Env
+	tags      []string          // Session tags sent with every request that doesn't set its own
+	project   string            // Session project override (empty = derived by the gateway)
+	review    *types.SelfReview // Self-review settings (nil = the gateway's agent.review)
+	dirtyTree string            // Dirty tree policy ("" = the gateway's agent.dirty_tree)
+	readOnly  bool              // Run every turn with only the tools that change nothing
 }
 
 // NewGatewayClient creates a new gateway client
+// (http://host:port, or unix:///path for a gateway on a unix socket)
 func NewGatewayClient(baseURL string) *GatewayClient {
+	client := &http.Client{
+		// Large tasks can take a long time - similar to how Cursor handles them.
+		// Complex multi-step tasks with large context models may need 30+ minutes.
+		// We use 45 minutes to be generous and match the gateway's internal timeout.
+		// Individual AI calls have their own 5-minute per-step timeout.
+		Timeout: 45 * time.Minute,
+	}
+	if network, addr := gatewayDialAddr(baseURL); network == "unix" {
+		// Every request goes over the socket; the host in the URL is unused
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+		baseURL = "http://unix"
+	}
 	return &GatewayClient{
 		baseURL: baseURL,
-		client: &http.Client{
-			// Large tasks can take a long time - similar to how Cursor handles them.
-			// Complex multi-step tasks with large context models may need 30+ minutes.
-			// We use 45 minutes to be generous and match the gateway's internal timeout.
-			// Individual AI calls have their own 5-minute per-step timeout.
-			Timeout: 45 * time.Minute,
-		},
+		client:  client,
+	}
+}
+
+// gatewayDialAddr returns the network and address behind a gateway URL:
+// unix and the socket path for unix:///path, otherwise tcp and host:port
+func gatewayDialAddr(gatewayURL string) (network, addr string) {
+	if path, ok := strings.CutPrefix(gatewayURL, "unix://"); ok {
+		return "unix", path
+	}
+	_, rest, ok := strings.Cut(gatewayURL, "://")
+	if !ok {
+		rest = gatewayURL
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return "tcp", host
+}
+
+// dialGateway checks that something accepts connections at a gateway URL
+func dialGateway(gatewayURL string, timeout time.Duration) bool {
+	network, addr := gatewayDialAddr(gatewayURL)
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// SetEnv sets the session environment sent with each chat request
+func (gc *GatewayClient) SetEnv(env map[string]string) {
+	gc.env = env
+}
+
+// SetLabels sets the session tags and project sent with each chat request
+func (gc *GatewayClient) SetLabels(tags []string, project string) {
+	gc.tags = tags
+	gc.project = project
+}
+
+// SetReview sets the self-review settings sent with each chat request
+func (gc *GatewayClient) SetReview(review *types.SelfReview) {
+	gc.review = review
+}
+
+// SetDirtyTree sets the dirty tree policy sent with each chat request
+func (gc *GatewayClient) SetDirtyTree(policy string) {
+	gc.dirtyTree = policy
+}
+
+// SetReadOnly makes every chat request read-only
+func (gc *GatewayClient) SetReadOnly(readOnly bool) {
+	gc.readOnly = readOnly
+}
+
+// applyDefaults fills request fields the caller left unset from client settings
+func (gc *GatewayClient) applyDefaults(req *ChatRequest) {
+	if req.Env == nil {
+		req.Env = gc.env
+	}
+	if req.Tags == nil {
+		req.Tags = gc.tags
+	}
+	if req.Project == "" {
+		req.Project = gc.project
+	}
+	if req.Review == nil {
+		req.Review = gc.review
+	}
+	if req.DirtyTree == "" {
+		req.DirtyTree = gc.dirtyTree
+	}
+	if gc.readOnly {
+		req.ReadOnly = true
 	}
 }
 
@@ -55,6 +152,7 @@ func (gc *GatewayClient) HealthCheck() error {
 
 // Send sends a chat request to the gateway
 func (gc *GatewayClient) Send(req ChatRequest) (*ChatResponse, error) {
+	gc.applyDefaults(&req)
 	url := fmt.Sprintf("%s/chat", gc.baseURL)
 
 	jsonReq, err := json.Marshal(req)
@@ -133,15 +231,17 @@ func (gc *GatewayClient) StartStream(sessionID, task string) (string, error) {
 	return streamID, nil
 }
 
-// ProgressEvent represents a streaming progress event
+// ProgressEvent represents a streaming progress event: the typed schema plus
+// the fields of the final "done" event
 type ProgressEvent struct {
-	Type        string                 `json:"type"`
-	Step        int                    `json:"step,omitempty"`
-	Message     string                 `json:"message,omitempty"`
-	Data        interface{}            `json:"data,omitempty"`
-	SessionID   string                 `json:"session_id,omitempty"`
+	types.ProgressEvent
 	Result      string                 `json:"result,omitempty"`
 	SessionInfo map[string]interface{} `json:"session_info,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	ErrorCode   types.ErrorCode        `json:"error_code,omitempty"`
+	Output      json.RawMessage        `json:"output,omitempty"`
+	StepLimit   *types.StepLimit       `json:"step_limit,omitempty"`
+	Review      *types.ReviewOutcome   `json:"review,omitempty"`
 }
 
 // SessionListResponse represents the response from /sessions endpoint
@@ -154,26 +254,38 @@ type SessionListResponse struct {
 
 // SessionEntry represents a session in the list
 type SessionEntry struct {
-	ID                string `json:"id"`
-	CreatedAt         string `json:"created_at"`
-	UpdatedAt         string `json:"updated_at"`
-	MessageCount      int    `json:"message_count"`
-	UserMessages      int    `json:"user_messages"`
-	AssistantMessages int    `json:"assistant_messages"`
-	ToolMessages      int    `json:"tool_messages"`
-	WorkingDir        string `json:"working_dir"`
-	State             string `json:"state"`
-	ClientID          string `json:"client_id"`
-	LastUsed          string `json:"last_used"`
+	ID                string   `json:"id"`
+	Title             string   `json:"title,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	Project           string   `json:"project,omitempty"`
+	CreatedAt         string   `json:"created_at"`
+	UpdatedAt         string   `json:"updated_at"`
+	MessageCount      int      `json:"message_count"`
+	UserMessages      int      `json:"user_messages"`
+	AssistantMessages int      `json:"assistant_messages"`
+	ToolMessages      int      `json:"tool_messages"`
+	WorkingDir        string   `json:"working_dir"`
+	State             string   `json:"state"`
+	ClientID          string   `json:"client_id"`
+	LastUsed          string   `json:"last_used"`
 }
 
 // StatsResponse represents statistics from the gateway
 type StatsResponse struct {
-	Usage        string  `json:"usage"`
-	CacheHits    int     `json:"cache_hits"`
-	CacheMisses  int     `json:"cache_misses"`
-	CacheSize    int     `json:"cache_size"`
-	CacheHitRate float64 `json:"cache_hit_rate"`
+	Usage        string         `json:"usage"`
+	CacheHits    int            `json:"cache_hits"`
+	CacheMisses  int            `json:"cache_misses"`
+	CacheSize    int            `json:"cache_size"`
+	CacheHitRate float64        `json:"cache_hit_rate"`
+	Feedback     []FeedbackStat `json:"feedback,omitempty"`
+}
+
+// FeedbackStat is the user rating count for a provider/model
+type FeedbackStat struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Good     int    `json:"good"`
+	Bad      int    `json:"bad"`
 }
 
 // GetStats retrieves usage and cache statistics
@@ -198,6 +310,7 @@ func (gc *GatewayClient) GetStats() (*StatsResponse, error) {
 			Size    int     `json:"size"`
 			HitRate float64 `json:"hit_rate"`
 		} `json:"cache"`
+		Feedback []FeedbackStat `json:"feedback"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
 		return nil, err
@@ -209,6 +322,7 @@ func (gc *GatewayClient) GetStats() (*StatsResponse, error) {
 		CacheMisses:  raw.Cache.Misses,
 		CacheSize:    raw.Cache.Size,
 		CacheHitRate: raw.Cache.HitRate,
+		Feedback:     raw.Feedback,
 	}, nil
 }
 
@@ -271,6 +385,214 @@ func (gc *GatewayClient) ActivateSession(sessionID, clientID string) error {
 	return nil
 }
 
+// RateSession rates the last answer in a session ("good" or "bad") and returns
+// the gateway's confirmation message
+func (gc *GatewayClient) RateSession(sessionID, rating, comment string) (string, error) {
+	url := fmt.Sprintf("%s/sessions/%s/feedback", gc.baseURL, sessionID)
+
+	body := map[string]string{"rating": rating, "comment": comment}
+	jsonBody, _ := json.Marshal(body)
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return "", &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return "", fmt.Errorf("failed to rate session: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
+
+// GetSession returns a session's details (without its messages)
+func (gc *GatewayClient) GetSession(sessionID string) (*SessionEntry, error) {
+	url := fmt.Sprintf("%s/sessions/%s?summary=true", gc.baseURL, sessionID)
+
+	resp, err := gc.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get session: %d", resp.StatusCode)
+	}
+
+	var result SessionEntry
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TagSession adds and removes session tags and returns the resulting tags
+func (gc *GatewayClient) TagSession(sessionID string, add, remove []string) ([]string, error) {
+	url := fmt.Sprintf("%s/sessions/%s/tags", gc.baseURL, sessionID)
+
+	body := map[string][]string{"add": add, "remove": remove}
+	jsonBody, _ := json.Marshal(body)
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return nil, fmt.Errorf("failed to tag session: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Tags, nil
+}
+
+// AllowProtected allows and revokes protected path patterns for a session
+// and returns the protected and allowed patterns
+func (gc *GatewayClient) AllowProtected(sessionID string, allow, revoke []string) ([]string, []string, error) {
+	url := fmt.Sprintf("%s/sessions/%s/protected", gc.baseURL, sessionID)
+
+	body := map[string][]string{"allow": allow, "revoke": revoke}
+	jsonBody, _ := json.Marshal(body)
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return nil, nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return nil, nil, fmt.Errorf("failed to update protected paths: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Protected []string `json:"protected"`
+		Allowed   []string `json:"allowed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, err
+	}
+	return result.Protected, result.Allowed, nil
+}
+
+// ApproveExec approves and revokes shell command classes for a session and
+// returns the exec approval policy and the approved classes
+func (gc *GatewayClient) ApproveExec(sessionID string, allow, revoke []string) (string, []string, error) {
+	url := fmt.Sprintf("%s/sessions/%s/exec-approvals", gc.baseURL, sessionID)
+
+	body := map[string][]string{"allow": allow, "revoke": revoke}
+	jsonBody, _ := json.Marshal(body)
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return "", nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return "", nil, fmt.Errorf("failed to update exec approvals: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Policy   string   `json:"policy"`
+		Approved []string `json:"approved"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", nil, err
+	}
+	return result.Policy, result.Approved, nil
+}
+
+// Pins lists a session's pinned messages. With pin set, it first pins (or,
+// with unpin, unpins) the message at index (-1 = latest user message).
+func (gc *GatewayClient) Pins(sessionID string, pin bool, index int, unpin bool) ([]agent.PinnedMessage, error) {
+	url := fmt.Sprintf("%s/sessions/%s/pins", gc.baseURL, sessionID)
+
+	var resp *http.Response
+	var err error
+	if pin {
+		jsonBody, _ := json.Marshal(map[string]interface{}{"index": index, "unpin": unpin})
+		resp, err = gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	} else {
+		resp, err = gc.client.Get(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return nil, fmt.Errorf("failed to update pins: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Pinned []agent.PinnedMessage `json:"pinned"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Pinned, nil
+}
+
+// DoctorSession checks a session's transcript for tool calls and results
+// providers would reject, and with repair fixes it
+func (gc *GatewayClient) DoctorSession(sessionID string, repair bool) (*gateway.DoctorReport, error) {
+	url := fmt.Sprintf("%s/sessions/%s/doctor", gc.baseURL, sessionID)
+
+	jsonBody, _ := json.Marshal(map[string]bool{"repair": repair})
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return nil, fmt.Errorf("failed to check session: %d", resp.StatusCode)
+	}
+
+	var report gateway.DoctorReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // DeleteSession deletes a session
 func (gc *GatewayClient) DeleteSession(sessionID string) error {
 	url := fmt.Sprintf("%s/sessions/%s", gc.baseURL, sessionID)
@@ -324,21 +646,109 @@ func (gc *GatewayClient) UpdatePreferences(updates map[string]interface{}) error
 		return err
 	}
 
-	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Rate limit changes need the gateway's operator token
+	if token := os.Getenv("ZEN_CLAW_OPERATOR_TOKEN"); token != "" {
+		req.Header.Set("X-Operator-Token", token)
+	}
+	resp, err := gc.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
 		return fmt.Errorf("failed to update preferences: %d", resp.StatusCode)
 	}
 
 	return nil
 }
 
-// SendWithProgress sends a chat request with SSE streaming for progress
+// RecordRun reports a consensus, fabric or factory run to the gateway
+func (gc *GatewayClient) RecordRun(run types.RunRecord) (string, error) {
+	url := fmt.Sprintf("%s/runs", gc.baseURL)
+
+	jsonBody, err := json.Marshal(run)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return "", &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return "", fmt.Errorf("failed to record run: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// TriageIssue has the gateway classify, summarize and label a GitHub issue
+func (gc *GatewayClient) TriageIssue(req gateway.TriageRequest) (*gateway.IssueTriage, error) {
+	url := fmt.Sprintf("%s/github/triage", gc.baseURL)
+
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := gc.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp types.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			return nil, &types.Error{Code: errResp.ErrorCode, Message: errResp.Error}
+		}
+		return nil, fmt.Errorf("failed to triage issue: %d", resp.StatusCode)
+	}
+
+	var triage gateway.IssueTriage
+	if err := json.NewDecoder(resp.Body).Decode(&triage); err != nil {
+		return nil, err
+	}
+	return &triage, nil
+}
+
+const (
+	// streamIdleTimeout drops a stream that sent nothing, not even a
+	// heartbeat (every 15s), for this long
+	streamIdleTimeout = 45 * time.Second
+
+	// streamReconnects is how often a dropped stream is resumed before
+	// giving up
+	streamReconnects = 5
+)
+
+// SendWithProgress sends a chat request with SSE streaming for progress. A
+// dropped or silent stream is resumed from the last event received; the
+// request keeps running on the gateway meanwhile.
 func (gc *GatewayClient) SendWithProgress(req ChatRequest, onProgress func(ProgressEvent)) (*ChatResponse, error) {
+	gc.applyDefaults(&req)
 	url := fmt.Sprintf("%s/chat/stream", gc.baseURL)
 
 	jsonReq, err := json.Marshal(req)
@@ -360,17 +770,112 @@ func (gc *GatewayClient) SendWithProgress(req ChatRequest, onProgress func(Progr
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("gateway request failed: %d", resp.StatusCode)
 	}
 
-	// Read SSE stream
-	scanner := bufio.NewScanner(resp.Body)
-	var finalResponse *ChatResponse
+	// Gateways without resumable streams send no stream ID; their streams
+	// are read once
+	lastEventID := ""
+	if id := resp.Header.Get("X-Stream-ID"); id != "" {
+		lastEventID = id + ":0"
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for attempt := 1; ; attempt++ {
+		final, err := readEventStream(resp.Body, &lastEventID, onProgress)
+		if final != nil || lastEventID == "" || attempt > streamReconnects {
+			return final, err
+		}
+
+		if onProgress != nil {
+			onProgress(ProgressEvent{ProgressEvent: types.ProgressEvent{
+				Type:    "reconnecting",
+				Message: fmt.Sprintf("Stream interrupted (%v), reconnecting (%d/%d)...", err, attempt, streamReconnects),
+			}})
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+
+		resp, err = gc.resumeStream(lastEventID)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
 
-		// SSE format: "data: {...}"
+// resumeStream reopens a stream after the event with the given ID
+func (gc *GatewayClient) resumeStream(lastEventID string) (*http.Response, error) {
+	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/chat/stream", gc.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Last-Event-ID", lastEventID)
+
+	resp, err := gc.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("resuming stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("resuming stream failed: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// readEventStream reads SSE events until the final one, recording the ID of
+// each event in lastEventID. It closes body, and returns an error without a
+// response when the stream breaks or stays silent for streamIdleTimeout.
+func readEventStream(body io.ReadCloser, lastEventID *string, onProgress func(ProgressEvent)) (*ChatResponse, error) {
+	defer body.Close()
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Large results arrive as one line
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-stop:
+				return
+			}
+		}
+		readErr <- scanner.Err()
+		close(lines)
+	}()
+
+	idle := time.NewTimer(streamIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				if err := <-readErr; err != nil {
+					return nil, fmt.Errorf("stream error: %w", err)
+				}
+				return nil, fmt.Errorf("stream ended without final response")
+			}
+			line = l
+		case <-idle.C:
+			return nil, fmt.Errorf("no events for %s", streamIdleTimeout)
+		}
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		idle.Reset(streamIdleTimeout)
+
+		// SSE format: "id: <stream>:<seq>" then "data: {...}"
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			*lastEventID = id
+			continue
+		}
 		if !strings.HasPrefix(line, "data: ") {
 			continue
 		}
@@ -392,28 +897,24 @@ func (gc *GatewayClient) SendWithProgress(req ChatRequest, onProgress func(Progr
 
 		// Check for final "done" event
 		if event.Type == "done" {
-			finalResponse = &ChatResponse{
+			return &ChatResponse{
 				SessionID:   event.SessionID,
 				Result:      event.Result,
 				SessionInfo: event.SessionInfo,
-			}
+				Error:       event.Error,
+				ErrorCode:   event.ErrorCode,
+				Output:      event.Output,
+				StepLimit:   event.StepLimit,
+				Review:      event.Review,
+			}, nil
 		}
 
 		// Check for error
 		if event.Type == "error" {
 			return &ChatResponse{
-				Error: event.Message,
+				Error:     event.Message,
+				ErrorCode: event.ErrorCode,
 			}, nil
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("stream error: %w", err)
-	}
-
-	if finalResponse == nil {
-		return nil, fmt.Errorf("stream ended without final response")
-	}
-
-	return finalResponse, nil
 }
@@ -6,15 +6,21 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/chzyer/readline"
+	"github.com/neves/zen-claw/internal/agent"
 	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/i18n"
+	"github.com/neves/zen-claw/internal/macros"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // runInteractiveMode runs the agent in interactive mode
-func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, showProgress bool, maxSteps int, verbose bool, useWebSocket bool, streamTokens bool) {
+func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, showProgress bool, maxSteps int, verbose bool, useWebSocket bool, streamTokens bool, env map[string]string, tags []string, project string, review *types.SelfReview, dirtyTree string, readOnly bool) {
 	// streamTokens is passed in requests below
 	fmt.Println("🚀 Zen Agent")
 	if useWebSocket {
@@ -29,20 +35,32 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 		fmt.Println("Fresh context (use --session <name> to save)")
 	}
 	fmt.Printf("Working directory: %s\n", workingDir)
+	if readOnly {
+		fmt.Println("Read-only: tools that change nothing")
+	}
 	fmt.Println()
-	fmt.Println("Commands: /help, /session list, /session load, /models, /provider, /exit")
+	fmt.Println("Commands: /help, /session list, /session load, /models, /provider, /lang, /exit")
 	fmt.Println("═" + strings.Repeat("═", 78))
 
 	// Thinking level for reasoning-capable models
 	thinkingLevel := "" // off, low, medium, high (empty = model default)
 
+	// Answer language: sent once chosen with /lang, otherwise the session's
+	// or the gateway's default applies. UI strings follow the same choice.
+	language := ""
+	uiLang := configuredLanguage()
+
 	// Create gateway client
 	client := NewGatewayClient(getGatewayURL())
+	client.SetEnv(env)
+	client.SetLabels(tags, project)
+	client.SetReview(review)
+	client.SetDirtyTree(dirtyTree)
+	client.SetReadOnly(readOnly)
 
 	// Check if gateway is running
-	if err := client.HealthCheck(); err != nil {
+	if err := ensureGateway(client); err != nil {
 		fmt.Printf("\n❌ Gateway not available: %v\n", err)
-		fmt.Println("   Start the gateway first: zen-claw gateway start")
 		return
 	}
 
@@ -60,7 +78,17 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 		modelName = providers.GetDefaultModel(providerName)
 	}
 
-	fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	// Provider/model are only sent once chosen (flags, /provider, /model);
+	// until then the gateway uses the session's saved choice or its default
+	reqProvider, reqModel := "", ""
+	if providerFlag != "" || modelFlag != "" {
+		reqProvider, reqModel = providerName, modelName
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	} else if sessionID != "" {
+		fmt.Printf("Provider: session setting (default %s, model %s)\n", providerName, modelName)
+	} else {
+		fmt.Printf("Provider: %s, Model: %s\n", providerName, modelName)
+	}
 	fmt.Println("═" + strings.Repeat("═", 78))
 
 	// Setup readline for improved interactive mode
@@ -76,11 +104,14 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 	})
 	if err != nil {
 		fmt.Printf("Warning: readline not available, using basic input: %v\n", err)
-		runBasicInteractiveMode(client, sessionID, workingDir, providerName, modelName, maxSteps)
+		runBasicInteractiveMode(client, sessionID, workingDir, reqProvider, reqModel, maxSteps)
 		return
 	}
 	defer rl.Close()
 
+	// Turns sent while recording a macro (/record)
+	var recorder *macros.Recorder
+
 	// Interactive loop with readline
 	for {
 		input, err := rl.Readline()
@@ -111,6 +142,15 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 			thinkingLevel = handleThinkCommand(input, thinkingLevel)
 			continue
 
+		case input == "/lang" || strings.HasPrefix(input, "/lang "):
+			language = handleLangCommand(input, language, uiLang)
+			if language == "default" {
+				uiLang = configuredLanguage()
+			} else if language != "" {
+				uiLang = language
+			}
+			continue
+
 		case input == "/clear":
 			sessionID = ""
 			fmt.Println("✓ Cleared. Fresh context.")
@@ -120,6 +160,26 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 			handleStatsCommand(client)
 			continue
 
+		case isRateCommand(input):
+			handleRateCommand(client, input, sessionID)
+			continue
+
+		case input == "/tag" || strings.HasPrefix(input, "/tag ") || strings.HasPrefix(input, "/untag "):
+			handleTagCommand(client, input, sessionID)
+			continue
+
+		case input == "/pin" || input == "/pins" || input == "/unpin" || strings.HasPrefix(input, "/unpin "):
+			handlePinCommand(client, input, sessionID)
+			continue
+
+		case input == "/protected" || strings.HasPrefix(input, "/protected "):
+			handleProtectedCommand(client, input, sessionID)
+			continue
+
+		case input == "/approve" || strings.HasPrefix(input, "/approve "):
+			handleApproveCommand(client, input, sessionID)
+			continue
+
 		case input == "/sessions" || input == "/sessions list" || input == "/session" || input == "/session list":
 			handleSessionsListCommand(client, sessionID)
 			continue
@@ -184,12 +244,17 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 			handlePrefsFactoryCommand(client)
 			continue
 
+		case strings.HasPrefix(input, "/prefs ratelimit"):
+			handlePrefsRateLimitCommand(client, input)
+			continue
+
 		case input == "/providers" || input == "/provider":
 			printProvidersList()
 			continue
 
 		case strings.HasPrefix(input, "/provider ") || strings.HasPrefix(input, "/providers "):
 			providerName, modelName = handleProviderSwitchCommand(input, providerName)
+			reqProvider, reqModel = providerName, modelName
 			continue
 
 		case input == "/models":
@@ -198,14 +263,15 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 
 		case strings.HasPrefix(input, "/model "):
 			modelName = handleModelSwitchCommand(input, providerName)
+			reqProvider, reqModel = providerName, modelName
 			continue
 
 		case strings.HasPrefix(input, "/context-limit"):
-			handleContextLimitCommand(client, input, sessionID, workingDir, providerName, modelName, maxSteps)
+			handleContextLimitCommand(client, input, sessionID, workingDir, reqProvider, reqModel, maxSteps)
 			continue
 
 		case strings.HasPrefix(input, "/qwen-large-context"):
-			handleQwenLargeContextCommand(client, input, sessionID, workingDir, providerName, modelName, maxSteps)
+			handleQwenLargeContextCommand(client, input, sessionID, workingDir, reqProvider, reqModel, maxSteps)
 			continue
 
 		case input == "/cost" || strings.HasPrefix(input, "/cost "):
@@ -217,39 +283,100 @@ func runInteractiveMode(modelFlag, providerFlag, workingDir, sessionID string, s
 			continue
 		}
 
-		// Process task
+		if input == "/record" || strings.HasPrefix(input, "/record ") {
+			recorder = handleRecordCommand(input, recorder, workingDir, reqProvider, reqModel)
+			continue
+		}
+
+		// A saved prompt is sent as the message
+		if input == "/prompt" || strings.HasPrefix(input, "/prompt ") {
+			if input = handlePromptCommand(input); input == "" {
+				continue
+			}
+		}
+
+		// Process task (/pin <text> sends the message pinned)
+		pin := strings.HasPrefix(input, "/pin ")
+		if pin {
+			input = strings.TrimSpace(strings.TrimPrefix(input, "/pin "))
+		}
 		req := ChatRequest{
 			SessionID:     sessionID,
 			UserInput:     input,
 			WorkingDir:    workingDir,
-			Provider:      providerName,
-			Model:         modelName,
+			Provider:      reqProvider,
+			Model:         reqModel,
 			MaxSteps:      maxSteps,
 			ThinkingLevel: thinkingLevel,
+			Language:      language,
 			Stream:        streamTokens,
+			Pin:           pin,
 		}
 
-		resp, err := client.SendWithProgress(req, func(event ProgressEvent) {
-			displayProgressEvent(event)
-		})
-		if err != nil {
-			fmt.Printf("❌ Error: %v\n", err)
-			continue
-		}
+		// A run stopping at the step limit can be extended on the spot
+		for {
+			resp, err := client.SendWithProgress(req, func(event ProgressEvent) {
+				displayProgressEvent(event, uiLang)
+				if verbose {
+					displayVerboseEvent(event)
+				}
+			})
+			if err != nil {
+				fmt.Println(i18n.T(uiLang, "error", err))
+				break
+			}
 
-		if resp.Error != "" {
-			fmt.Printf("❌ Agent error: %s\n", resp.Error)
-			continue
+			if resp.Error != "" {
+				fmt.Println(i18n.T(uiLang, "agent_error", resp.Error))
+				break
+			}
+
+			fmt.Println("\n" + strings.Repeat("═", 80))
+			fmt.Println(i18n.T(uiLang, "result"))
+			fmt.Println(strings.Repeat("═", 80))
+			fmt.Println(resp.Result)
+			fmt.Println(strings.Repeat("═", 80))
+			if resp.Review != nil {
+				printReviewOutcome(resp.Review)
+			}
+
+			sessionID = resp.SessionID
+			if recorder != nil && req.UserInput == input {
+				recorder.Add(input)
+			}
+			if resp.StepLimit == nil {
+				break
+			}
+			extend := askStepExtension(rl, resp.StepLimit, uiLang)
+			if extend == 0 {
+				break
+			}
+			req.SessionID, req.UserInput, req.MaxSteps, req.Pin = sessionID, types.ContinuePrompt, extend, false
 		}
+	}
+}
 
-		fmt.Println("\n" + strings.Repeat("═", 80))
-		fmt.Println("🎯 RESULT")
-		fmt.Println(strings.Repeat("═", 80))
-		fmt.Println(resp.Result)
-		fmt.Println(strings.Repeat("═", 80))
+// askStepExtension asks whether to continue a run that stopped at the step
+// limit, and returns the max steps to continue with (0 keeps the partial
+// result)
+func askStepExtension(rl *readline.Instance, limit *types.StepLimit, lang string) int {
+	rl.SetPrompt(i18n.T(lang, "step_limit_ask", limit.Steps, limit.Extend))
+	defer rl.SetPrompt("> ")
 
-		sessionID = resp.SessionID
+	answer, err := rl.Readline()
+	if err != nil {
+		return 0
 	}
+	switch answer = strings.ToLower(strings.TrimSpace(answer)); answer {
+	case "", "y", "yes", "s", "sim", "si", "sí":
+		return limit.Extend
+	case "n", "no", "não", "nao":
+		return 0
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n > 0 {
+		return n
+	}
+	return 0
 }
 
 // runBasicInteractiveMode is a fallback when readline is not available
@@ -287,7 +414,7 @@ func runBasicInteractiveMode(client *GatewayClient, sessionID, workingDir, provi
 		}
 
 		resp, err := client.SendWithProgress(req, func(event ProgressEvent) {
-			displayProgressEvent(event)
+			displayProgressEvent(event, configuredLanguage())
 		})
 		if err != nil {
 			fmt.Printf("❌ Error: %v\n", err)
@@ -306,6 +433,9 @@ func runBasicInteractiveMode(client *GatewayClient, sessionID, workingDir, provi
 		fmt.Println(strings.Repeat("═", 80))
 
 		sessionID = resp.SessionID
+		if resp.StepLimit != nil {
+			fmt.Printf("⏸  Stopped at the step limit (%d). Send \"continue\" to go on.\n", resp.StepLimit.Steps)
+		}
 	}
 }
 
@@ -315,19 +445,28 @@ func printInteractiveHelp() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  /clear              - Clear conversation history (fresh start)")
 	fmt.Println("  /stats              - Show usage and cache statistics")
+	fmt.Println("  /rate good|bad [why] - Rate the last answer (also /good, /bad)")
+	fmt.Println("  /tag [tags...]      - Show or add session tags (/untag <tag> removes)")
+	fmt.Println("  /pin [message]      - Pin the last message, or send one pinned (never trimmed)")
+	fmt.Println("  /pins, /unpin <n>   - List pinned messages, unpin one")
+	fmt.Println("  /protected [allow|revoke <pattern>] - Show protected paths, allow one for this session")
+	fmt.Println("  /approve [<class>|revoke <class>] - Approve mutating/network-egress/destructive commands for this session")
+	fmt.Println("  /prompt [name k=v]  - List saved prompts, or send one with its variables")
+	fmt.Println("  /record start|stop <name> - Record your messages as a macro (replay: zen-claw play)")
 	fmt.Println("  /cost [prompt]      - Estimate cost for a prompt")
 	fmt.Println("  /compare            - Compare provider costs")
 	fmt.Println("  /models             - List available models")
 	fmt.Println("  /model <name>       - Switch to a different model")
 	fmt.Println("  /provider <name>    - Switch provider (deepseek, qwen, minimax, kimi)")
 	fmt.Println("  /think [level]      - Set thinking level (off, low, medium, high)")
-	fmt.Println("  /context-limit [n]  - Set context limit (0=unlimited)")
+	fmt.Println("  /lang [tag]         - Answer in a language, e.g. pt-BR (default resets)")
+	fmt.Println("  /context-limit [n]  - Cap history tokens, e.g. 32k (0=model's window)")
 	fmt.Println()
 	fmt.Println("Session management:")
 	fmt.Println("  /session list       - List saved sessions")
 	fmt.Println("  /session load <n>   - Switch to a saved session")
 	fmt.Println("  /session info       - Show storage info (path, size)")
-	fmt.Println("  /session clean      - Clean sessions (--all or --older 7d)")
+	fmt.Println("  /session clean      - Clean sessions (--all, --older 7d, --policy; --dry-run)")
 	fmt.Println("  /session delete <n> - Delete a specific session")
 	fmt.Println()
 	fmt.Println("  /exit               - Exit")
@@ -362,6 +501,32 @@ func handleThinkCommand(input, currentLevel string) string {
 	}
 }
 
+// handleLangCommand shows or sets the answer language; "default" is sent so
+// the gateway clears the session's choice
+func handleLangCommand(input, current, uiLang string) string {
+	if input == "/lang" {
+		if current == "" || current == "default" {
+			fmt.Println(i18n.T(uiLang, "lang_default", i18n.Name(uiLang)))
+		} else {
+			fmt.Println(i18n.T(uiLang, "lang_current", i18n.Name(current)))
+		}
+		fmt.Println(i18n.T(uiLang, "lang_usage"))
+		return current
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(input, "/lang "))
+	if arg == "default" {
+		fmt.Println(i18n.T(configuredLanguage(), "lang_reset"))
+		return "default"
+	}
+	tag := i18n.Normalize(arg)
+	if tag == "" {
+		fmt.Println(i18n.T(uiLang, "lang_invalid", arg))
+		return current
+	}
+	fmt.Println(i18n.T(tag, "lang_set", i18n.Name(tag)))
+	return tag
+}
+
 func handleStatsCommand(client *GatewayClient) {
 	stats, err := client.GetStats()
 	if err != nil {
@@ -374,9 +539,228 @@ func handleStatsCommand(client *GatewayClient) {
 	fmt.Printf("Cache: %d hits, %d misses (%.1f%% hit rate)\n",
 		stats.CacheHits, stats.CacheMisses, stats.CacheHitRate*100)
 	fmt.Printf("Cache size: %d entries\n", stats.CacheSize)
+	if len(stats.Feedback) > 0 {
+		fmt.Println("Ratings:")
+		for _, f := range stats.Feedback {
+			fmt.Printf("  %-30s 👍 %d  👎 %d\n", f.Provider+"/"+f.Model, f.Good, f.Bad)
+		}
+	}
 	fmt.Println(strings.Repeat("─", 50))
 }
 
+// handleTagCommand handles /tag (show), /tag <tags...> and /untag <tags...>.
+// Before the first message tags are sent with it; afterwards the session is updated.
+func handleTagCommand(client *GatewayClient, input, sessionID string) {
+	fields := strings.Fields(input)
+	remove := fields[0] == "/untag"
+	tags := fields[1:]
+
+	if len(tags) == 0 {
+		if sessionID == "" {
+			fmt.Printf("Tags for next message: %s\n", formatTags(client.tags))
+			return
+		}
+		session, err := client.GetSession(sessionID)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Tags: %s\n", formatTags(session.Tags))
+		if session.Project != "" {
+			fmt.Printf("Project: %s\n", session.Project)
+		}
+		fmt.Println("Usage: /tag <tag>..., /untag <tag>...")
+		return
+	}
+
+	// Removed tags must not be re-added by the next request
+	if remove {
+		client.tags = slices.DeleteFunc(client.tags, func(t string) bool { return slices.Contains(tags, t) })
+	}
+	if sessionID == "" {
+		if !remove {
+			client.tags = append(client.tags, tags...)
+		}
+		fmt.Printf("✓ Tags for next message: %s\n", formatTags(client.tags))
+		return
+	}
+
+	var add, del []string
+	if remove {
+		del = tags
+	} else {
+		add = tags
+	}
+	current, err := client.TagSession(sessionID, add, del)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Tags: %s\n", formatTags(current))
+}
+
+// handlePinCommand handles /pin (pin the latest user message), /pins and /unpin <index>
+func handlePinCommand(client *GatewayClient, input, sessionID string) {
+	if sessionID == "" {
+		fmt.Println("Nothing to pin yet. Use /pin <message> to send a pinned message.")
+		return
+	}
+
+	fields := strings.Fields(input)
+	var pinned []agent.PinnedMessage
+	var err error
+	switch fields[0] {
+	case "/pin":
+		pinned, err = client.Pins(sessionID, true, -1, false)
+	case "/unpin":
+		if len(fields) != 2 {
+			fmt.Println("Usage: /unpin <index>  (see /pins)")
+			return
+		}
+		index, convErr := strconv.Atoi(fields[1])
+		if convErr != nil {
+			fmt.Printf("❌ Invalid index: %s\n", fields[1])
+			return
+		}
+		pinned, err = client.Pins(sessionID, true, index, true)
+	default:
+		pinned, err = client.Pins(sessionID, false, 0, false)
+	}
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	if len(pinned) == 0 {
+		fmt.Println("No pinned messages")
+		return
+	}
+	fmt.Printf("📌 Pinned messages (%d):\n", len(pinned))
+	for _, p := range pinned {
+		fmt.Printf("  [%d] %s: %s\n", p.Index, p.Role, truncateString(strings.Join(strings.Fields(p.Content), " "), 70))
+	}
+}
+
+// handleProtectedCommand handles /protected (show), /protected allow <pattern>
+// and /protected revoke <pattern>: the user, as operator, lets the agent
+// modify protected paths in this session
+func handleProtectedCommand(client *GatewayClient, input, sessionID string) {
+	if sessionID == "" {
+		fmt.Println("No session yet. Send a message first.")
+		return
+	}
+
+	fields := strings.Fields(input)
+	var allow, revoke []string
+	if len(fields) > 1 {
+		if len(fields) < 3 || (fields[1] != "allow" && fields[1] != "revoke") {
+			fmt.Println("Usage: /protected [allow|revoke <pattern>...]  (\"*\" = all)")
+			return
+		}
+		if fields[1] == "allow" {
+			allow = fields[2:]
+		} else {
+			revoke = fields[2:]
+		}
+	}
+
+	protected, allowed, err := client.AllowProtected(sessionID, allow, revoke)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("🔒 Protected: %s\n", strings.Join(protected, ", "))
+	if len(allowed) > 0 {
+		fmt.Printf("🔓 Allowed in this session: %s\n", strings.Join(allowed, ", "))
+	}
+}
+
+// handleApproveCommand handles /approve (show), /approve <class>... and
+// /approve revoke <class>...: under the read-only exec approval policy the
+// user, as operator, lets the agent run commands of those classes
+func handleApproveCommand(client *GatewayClient, input, sessionID string) {
+	if sessionID == "" {
+		fmt.Println("No session yet. Send a message first.")
+		return
+	}
+
+	fields := strings.Fields(input)
+	var allow, revoke []string
+	if len(fields) > 1 {
+		if fields[1] == "revoke" {
+			if len(fields) < 3 {
+				fmt.Println("Usage: /approve [<class>...|revoke <class>...]  (mutating, network-egress, destructive, \"*\" = all)")
+				return
+			}
+			revoke = fields[2:]
+		} else {
+			allow = fields[1:]
+		}
+	}
+
+	policy, approved, err := client.ApproveExec(sessionID, allow, revoke)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	if policy != "read-only" {
+		fmt.Printf("⚙️  Exec approval policy: %s (every command runs; approvals apply under read-only)\n", policy)
+	} else {
+		fmt.Println("⚙️  Exec approval policy: read-only (other commands need approval)")
+	}
+	if len(approved) > 0 {
+		fmt.Printf("✅ Approved in this session: %s\n", strings.Join(approved, ", "))
+	}
+}
+
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "(none)"
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+// isRateCommand matches /rate, /good and /bad
+func isRateCommand(input string) bool {
+	for _, cmd := range []string{"/rate", "/good", "/bad"} {
+		if input == cmd || strings.HasPrefix(input, cmd+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// handleRateCommand handles /rate good|bad [comment], /good [comment] and /bad [comment]
+func handleRateCommand(client *GatewayClient, input, sessionID string) {
+	fields := strings.SplitN(input, " ", 2)
+	rating, comment := strings.TrimPrefix(fields[0], "/"), ""
+	if len(fields) > 1 {
+		comment = strings.TrimSpace(fields[1])
+	}
+	if rating == "rate" {
+		parts := strings.SplitN(comment, " ", 2)
+		rating, comment = parts[0], ""
+		if len(parts) > 1 {
+			comment = strings.TrimSpace(parts[1])
+		}
+	}
+	if rating != "good" && rating != "bad" {
+		fmt.Println("Usage: /rate good|bad [comment]  (or /good, /bad)")
+		return
+	}
+	if sessionID == "" {
+		fmt.Println("Nothing to rate yet - ask something first")
+		return
+	}
+
+	msg, err := client.RateSession(sessionID, rating, comment)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ %s\n", msg)
+}
+
 func handleSessionsListCommand(client *GatewayClient, currentSessionID string) {
 	sessions, err := client.ListSessions()
 	if err != nil {
@@ -403,7 +787,7 @@ func handleSessionsListCommand(client *GatewayClient, currentSessionID string) {
 		fmt.Printf("  • %s (%d messages)%s\n", s.ID, s.MessageCount, current)
 	}
 	fmt.Println(strings.Repeat("─", 50))
-	fmt.Println("Commands: /sessions info, /sessions clean [--all|--older 7d]")
+	fmt.Println("Commands: /sessions info, /sessions clean [--all|--older 7d|--policy] [--dry-run]")
 }
 
 func handlePrefsCommand(client *GatewayClient) {
@@ -426,7 +810,7 @@ func handlePrefsCommand(client *GatewayClient) {
 		}
 	}
 	fmt.Println(strings.Repeat("─", 60))
-	fmt.Println("Use /prefs fallback, /prefs consensus, /prefs factory for details")
+	fmt.Println("Use /prefs fallback, /prefs consensus, /prefs factory, /prefs ratelimit for details")
 }
 
 func handlePrefsFallbackCommand(client *GatewayClient, input string) {
@@ -515,6 +899,115 @@ func handlePrefsFactoryCommand(client *GatewayClient) {
 	fmt.Println(strings.Repeat("─", 60))
 }
 
+// handlePrefsRateLimitCommand shows or changes the gateway's rate limits:
+//
+//	/prefs ratelimit                          show limits, tiers and keys
+//	/prefs ratelimit rps=20 burst=40          default limits per client
+//	/prefs ratelimit tier ci rps=50 burst=100 add or change a tier (off removes it)
+//	/prefs ratelimit key <api-key> ci         assign a key to a tier (off removes it)
+func handlePrefsRateLimitCommand(client *GatewayClient, input string) {
+	args := strings.Fields(strings.TrimPrefix(input, "/prefs ratelimit"))
+	if len(args) == 0 {
+		prefs, err := client.GetPreferences("rate_limit")
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return
+		}
+		fmt.Println("\n🚦 Rate Limits:")
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("Default: %v req/s, burst %v (per client)\n", prefs["requests_per_second"], prefs["burst"])
+		if tiers, ok := prefs["tiers"].(map[string]interface{}); ok && len(tiers) > 0 {
+			fmt.Println("Tiers:")
+			for name, t := range tiers {
+				if tm, ok := t.(map[string]interface{}); ok {
+					fmt.Printf("  %s: %v req/s, burst %v\n", name, tm["requests_per_second"], tm["burst"])
+				}
+			}
+		}
+		if keys, ok := prefs["keys"].(map[string]interface{}); ok && len(keys) > 0 {
+			fmt.Println("API keys:")
+			for key, tier := range keys {
+				fmt.Printf("  %s → %v\n", key, tier)
+			}
+		}
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println("To change: /prefs ratelimit rps=20 burst=40")
+		fmt.Println("           /prefs ratelimit tier <name> rps=50 burst=100 (or off)")
+		fmt.Println("           /prefs ratelimit key <api-key> <tier> (or off)")
+		return
+	}
+
+	rateLimit := make(map[string]interface{})
+	switch args[0] {
+	case "tier":
+		if len(args) < 3 {
+			fmt.Println("Usage: /prefs ratelimit tier <name> rps=<n> burst=<n> | off")
+			return
+		}
+		var tier interface{} // nil removes the tier
+		if args[2] != "off" {
+			limits, err := parseRateLimitArgs(args[2:])
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return
+			}
+			tier = limits
+		}
+		rateLimit["tiers"] = map[string]interface{}{args[1]: tier}
+	case "key":
+		if len(args) != 3 {
+			fmt.Println("Usage: /prefs ratelimit key <api-key> <tier> | off")
+			return
+		}
+		tier := args[2]
+		if tier == "off" {
+			tier = ""
+		}
+		rateLimit["keys"] = map[string]string{args[1]: tier}
+	default:
+		limits, err := parseRateLimitArgs(args)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		rateLimit = limits
+	}
+
+	if err := client.UpdatePreferences(map[string]interface{}{"rate_limit": rateLimit}); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	fmt.Println("✓ Rate limits updated")
+}
+
+// parseRateLimitArgs parses rps=<n> and burst=<n> into preference fields
+func parseRateLimitArgs(args []string) (map[string]interface{}, error) {
+	limits := make(map[string]interface{})
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected rps=<n> or burst=<n>, got %q", arg)
+		}
+		switch name {
+		case "rps":
+			rps, err := strconv.ParseFloat(value, 64)
+			if err != nil || rps <= 0 {
+				return nil, fmt.Errorf("invalid rps %q", value)
+			}
+			limits["requests_per_second"] = rps
+		case "burst":
+			burst, err := strconv.Atoi(value)
+			if err != nil || burst <= 0 {
+				return nil, fmt.Errorf("invalid burst %q", value)
+			}
+			limits["burst"] = burst
+		default:
+			return nil, fmt.Errorf("unknown setting %q (use rps or burst)", name)
+		}
+	}
+	return limits, nil
+}
+
 func printProvidersList() {
 	fmt.Println("Available providers:")
 	fmt.Println("  - deepseek  (default)")
@@ -604,10 +1097,11 @@ func handleModelSwitchCommand(input, providerName string) string {
 func handleContextLimitCommand(client *GatewayClient, input, sessionID, workingDir, providerName, modelName string, maxSteps int) {
 	parts := strings.Fields(input)
 	if len(parts) == 1 {
-		fmt.Println("Usage: /context-limit [number]")
-		fmt.Println("  Set context limit (number of messages to send)")
-		fmt.Println("  Use 0 for unlimited, default is 50")
-		fmt.Println("  Example: /context-limit 100")
+		fmt.Println("Usage: /context-limit [tokens]")
+		fmt.Println("  Cap the conversation history sent per request (in tokens)")
+		fmt.Println("  Older messages beyond the cap are summarized; pinned messages (/pin) are kept")
+		fmt.Println("  Use 0 for the model's full context window (default)")
+		fmt.Println("  Example: /context-limit 32k")
 	} else {
 		req := ChatRequest{
 			SessionID:  sessionID,
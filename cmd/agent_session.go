@@ -72,31 +72,56 @@ func cleanSessionsInteractive(args string) {
 		return
 	}
 
-	if strings.HasPrefix(args, "--older ") || strings.HasPrefix(args, "older ") {
-		durationStr := strings.TrimPrefix(args, "--older ")
-		durationStr = strings.TrimPrefix(durationStr, "older ")
-		durationStr = strings.TrimSpace(durationStr)
+	// --older <duration>, --policy and --dry-run
+	retention := gateway.RetentionPolicyFromConfig(cfg.Sessions.Retention)
+	var olderThan string
+	var policy, dryRun bool
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		switch strings.TrimPrefix(fields[i], "--") {
+		case "older":
+			if i+1 < len(fields) {
+				i++
+				olderThan = fields[i]
+			}
+		case "policy":
+			policy = true
+		case "dry-run":
+			dryRun = true
+		}
+	}
 
-		duration, err := parseDurationAgent(durationStr)
+	switch {
+	case olderThan != "":
+		duration, err := parseDurationAgent(olderThan)
 		if err != nil {
 			fmt.Printf("❌ Invalid duration: %v\n", err)
 			fmt.Println("Examples: 24h, 7d, 30d")
 			return
 		}
-		count, err := store.CleanOldSessions(duration)
-		if err != nil {
-			fmt.Printf("❌ Error: %v\n", err)
+		retention.MaxAge, retention.MaxTotalBytes = duration, 0
+	case policy:
+		if !cfg.Sessions.Retention.Enabled() {
+			fmt.Println("❌ No retention policy configured (sessions.retention in config.yaml)")
 			return
 		}
-		fmt.Printf("✓ Deleted %d sessions older than %s\n", count, durationStr)
+	default:
+		fmt.Println("Usage: /sessions clean [--all | --older <duration> | --policy] [--dry-run]")
+		fmt.Println("Examples:")
+		fmt.Println("  /sessions clean --all                 # Delete all sessions")
+		fmt.Println("  /sessions clean --older 7d            # Delete sessions older than 7 days")
+		fmt.Println("  /sessions clean --older 24h --dry-run # Show what would be deleted")
+		fmt.Println("  /sessions clean --policy              # Apply sessions.retention now")
+		fmt.Println("Sessions tagged 'pinned' (/tag pinned) are never deleted.")
 		return
 	}
 
-	fmt.Println("Usage: /sessions clean [--all | --older <duration>]")
-	fmt.Println("Examples:")
-	fmt.Println("  /sessions clean --all        # Delete all sessions")
-	fmt.Println("  /sessions clean --older 7d   # Delete sessions older than 7 days")
-	fmt.Println("  /sessions clean --older 24h  # Delete sessions older than 24 hours")
+	report, err := store.ApplyRetention(retention, dryRun)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	printRetentionReport(report)
 }
 
 // deleteSessionByName deletes a specific session
@@ -5,11 +5,17 @@ import (
 	"strings"
 
 	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/gateway"
+	"github.com/neves/zen-claw/internal/i18n"
 	"github.com/neves/zen-claw/internal/providers"
 )
 
-// getGatewayURL returns the gateway HTTP URL from config
+// getGatewayURL returns the gateway HTTP URL: the one the running gateway
+// recorded, else the one from config
 func getGatewayURL() string {
+	if url := gateway.DiscoverURL(); url != "" {
+		return url
+	}
 	cfg, err := config.LoadConfig("")
 	if err != nil {
 		return "http://localhost:8080" // Fallback
@@ -17,8 +23,15 @@ func getGatewayURL() string {
 	return cfg.Gateway.GetURL()
 }
 
-// getGatewayWSURL returns the gateway WebSocket URL from config
+// getGatewayWSURL returns the gateway WebSocket URL, discovered like
+// getGatewayURL
 func getGatewayWSURL() string {
+	if url := gateway.DiscoverURL(); url != "" {
+		if rest, ok := strings.CutPrefix(url, "http"); ok {
+			return "ws" + rest + "/ws" // http(s)://host:port -> ws(s)://host:port/ws
+		}
+		return url // unix:///path
+	}
 	cfg, err := config.LoadConfig("")
 	if err != nil {
 		return "ws://localhost:8080/ws" // Fallback
@@ -26,6 +39,15 @@ func getGatewayWSURL() string {
 	return cfg.Gateway.GetWSURL()
 }
 
+// configuredLanguage returns the default answer language from config ("" = English)
+func configuredLanguage() string {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return ""
+	}
+	return cfg.Default.Language
+}
+
 // inferProviderFromModel uses centralized provider detection
 func inferProviderFromModel(modelName string) string {
 	return providers.InferProviderFromModel(modelName)
@@ -56,16 +78,26 @@ func isModelCompatibleWithProvider(modelName, provider string) bool {
 }
 
 // displayProgressEvent prints a progress event to the console with minimal formatting
-func displayProgressEvent(event ProgressEvent) {
+func displayProgressEvent(event ProgressEvent, lang string) {
 	switch event.Type {
 	case "session_resumed":
 		// Show that context was restored
 		fmt.Printf("📂 %s\n", event.Message)
+	case "queued":
+		fmt.Printf("⏳ %s\n", event.Message)
+	case "dirty_tree":
+		fmt.Printf("📝 %s\n", event.Message)
+	case "files_changed":
+		fmt.Printf("👀 %s\n", event.Message)
 	case "start":
 		// Skip - already shown in header
 	case "step":
-		// Show compact step indicator
-		fmt.Printf("\n[%d] ", event.Step)
+		// Show compact step indicator, with the running cost once known
+		if event.Total != nil {
+			fmt.Printf("\n[%d · %s] ", event.Step, event.Total)
+		} else {
+			fmt.Printf("\n[%d] ", event.Step)
+		}
 	case "thinking":
 		// Skip - not useful to show
 	case "ai_response":
@@ -81,20 +113,47 @@ func displayProgressEvent(event ProgressEvent) {
 			fmt.Printf("%s\n", msg)
 		}
 	case "tool_call":
-		// Show tool call compactly
-		fmt.Printf("    %s\n", event.Message)
+		// Show tool call compactly, with its duration when known
+		if d := event.Duration(); event.Tool != nil && d != "" {
+			fmt.Printf("    %s (%s)\n", event.Message, d)
+		} else {
+			fmt.Printf("    %s\n", event.Message)
+		}
 	case "tool_result":
 		// Skip detailed results - tool_call already shows summary
 	case "token":
 		// Stream token without newline for real-time output
 		fmt.Print(event.Message)
 	case "complete":
-		fmt.Printf("\n✅ Done (%d steps)\n", event.Step)
+		done := i18n.T(lang, "done", event.Step)
+		if event.Total != nil {
+			done += " · " + event.Total.String()
+		}
+		fmt.Printf("\n%s\n", done)
 	case "error":
 		fmt.Printf("\n❌ %s\n", event.Message)
+	case "cancelled":
+		fmt.Printf("\n⏹  %s\n", event.Message)
+	case "step_limit":
+		fmt.Printf("\n⏸  %s\n", event.Message)
+	case "review":
+		fmt.Printf("\n🔍 %s\n", event.Message)
+	case "reconnecting", "events_dropped":
+		fmt.Printf("\n⚠️  %s\n", event.Message)
+	case "heartbeat":
+		// Skip - only keeps the connection alive
 	case "done":
 		// Final result will be displayed separately
 	default:
 		// Skip unknown events
 	}
 }
+
+// displayVerboseEvent prints the progress events only shown with --verbose
+func displayVerboseEvent(event ProgressEvent) {
+	switch event.Type {
+	case "redacted":
+		// What privacy scrubbing kept out of the prompt (kinds and counts only)
+		fmt.Printf("🔒 %s\n", event.Message)
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/prompts"
+	"github.com/spf13/cobra"
+)
+
+// addAliasCommands adds a command for each alias in the config. Aliases
+// named like a built-in command are skipped with a warning.
+func addAliasCommands(root *cobra.Command) {
+	cfg, err := config.LoadConfig("")
+	if err != nil || len(cfg.Aliases) == 0 {
+		return
+	}
+	taken := make(map[string]bool)
+	for _, c := range root.Commands() {
+		taken[c.Name()] = true
+		for _, a := range c.Aliases {
+			taken[a] = true
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Aliases)) {
+		if taken[name] || name == "help" || name == "completion" {
+			fmt.Fprintf(os.Stderr, "Warning: alias %q is a zen-claw command; rename it in the config\n", name)
+			continue
+		}
+		root.AddCommand(newAliasCmd(name, cfg.Aliases[name]))
+	}
+}
+
+// newAliasCmd creates the command of an alias
+func newAliasCmd(name string, alias config.AliasConfig) *cobra.Command {
+	var vars []string
+	var workingDir, sessionID string
+	var showProgress, dryRun bool
+
+	short := alias.Description
+	if short == "" {
+		short = "Alias: " + aliasSummary(alias)
+	}
+	cmd := &cobra.Command{
+		Use:   name + " [task]",
+		Short: short,
+		Long: fmt.Sprintf(`%s
+
+Alias from the config (aliases.%s): %s.
+Without a task or prompt it starts interactive mode with these settings.`, short, name, aliasSummary(alias)),
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			task := ""
+			if len(args) > 0 {
+				task = args[0]
+			}
+			if alias.Prompt != "" {
+				rendered, err := renderAliasPrompt(alias, vars)
+				if err != nil {
+					fmt.Printf("❌ %v\n", err)
+					os.Exit(1)
+				}
+				task = strings.TrimSpace(rendered + "\n\n" + task)
+			} else if len(vars) > 0 {
+				fmt.Printf("❌ --var needs a prompt (aliases.%s.prompt)\n", name)
+				os.Exit(1)
+			}
+			if dryRun {
+				fmt.Println(task)
+				return
+			}
+			if sessionID == "" {
+				sessionID = alias.Session
+			}
+			maxSteps := alias.MaxSteps
+			if maxSteps <= 0 {
+				maxSteps = 100
+			}
+			runAgent(task, alias.Model, alias.Provider, workingDir, sessionID, showProgress, maxSteps, false, false, false, nil, alias.Tags, "", nil, "", alias.ReadOnly())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Prompt variable name=value, overriding the alias's (repeatable)")
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Working directory for tools")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session to save/resume (default: the alias's)")
+	cmd.Flags().BoolVar(&showProgress, "progress", false, "Show progress in console (CLI only)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the task instead of running it")
+
+	return cmd
+}
+
+// renderAliasPrompt renders an alias's prompt with its vars overridden by
+// the --var flags
+func renderAliasPrompt(alias config.AliasConfig, flags []string) (string, error) {
+	p, err := prompts.Get(prompts.DefaultDir(), alias.Prompt)
+	if err != nil {
+		return "", err
+	}
+	overrides, err := prompts.ParseVars(flags)
+	if err != nil {
+		return "", err
+	}
+	values := maps.Clone(alias.Vars)
+	if values == nil {
+		values = make(map[string]string)
+	}
+	maps.Copy(values, overrides)
+	return p.Render(values)
+}
+
+// aliasSummary describes an alias's settings in one line
+func aliasSummary(alias config.AliasConfig) string {
+	var parts []string
+	switch {
+	case alias.Provider != "" && alias.Model != "":
+		parts = append(parts, alias.Provider+"/"+alias.Model)
+	case alias.Model != "":
+		parts = append(parts, alias.Model)
+	case alias.Provider != "":
+		parts = append(parts, alias.Provider)
+	}
+	if alias.ReadOnly() {
+		parts = append(parts, "read-only tools")
+	}
+	if alias.Prompt != "" {
+		parts = append(parts, "prompt "+alias.Prompt)
+	}
+	if len(parts) == 0 {
+		return "agent with the default settings"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+// AskAnswer is the answer to a question about the code
+type AskAnswer struct {
+	Answer    string        `json:"answer"`
+	Citations []AskCitation `json:"citations"`
+}
+
+// AskCitation is a line range the answer relies on
+type AskCitation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Note      string `json:"note"`
+}
+
+// askSchema is the shape of the answer
+var askSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"answer": map[string]interface{}{
+			"type":        "string",
+			"description": "The answer in markdown, referring to code as path:line",
+		},
+		"citations": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path":       map[string]interface{}{"type": "string", "description": "File relative to the working directory"},
+					"start_line": map[string]interface{}{"type": "integer", "minimum": 1},
+					"end_line":   map[string]interface{}{"type": "integer", "minimum": 1},
+					"note":       map[string]interface{}{"type": "string", "description": "What this range shows, in a few words"},
+				},
+				"required": []string{"path", "start_line", "end_line", "note"},
+			},
+		},
+	},
+	"required": []string{"answer", "citations"},
+}
+
+func newAskCmd() *cobra.Command {
+	var workingDir, sessionID, provider, model string
+	var maxSteps int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "ask <question>",
+		Short: "Answer a question about the codebase, citing the code",
+		Long: `Answer a question about the code in the working directory. The agent
+only gets the tools that read, search and navigate code (files, search, the
+code index, definitions and references, git log): it can't run commands or
+change anything.
+
+The answer cites the files and line ranges it relies on. Tool results are
+shared by the questions asked about the same tree until a commit or an edit
+changes it, so follow-up questions are cheaper; --session keeps the
+conversation for them.`,
+		Example: `  zen-claw ask "how does session eviction work?"
+  zen-claw ask --working-dir ~/git/api "where are webhooks authenticated?"
+  zen-claw ask --session explain-cache "and when is the cache invalidated?"
+  zen-claw ask --json "which packages call the AI router?" > answer.json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			question := strings.Join(args, " ")
+			if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+				workingDir = abs
+			}
+
+			client := NewGatewayClient(getGatewayURL())
+			if err := ensureGateway(client); err != nil {
+				return err
+			}
+
+			uiLang := configuredLanguage()
+			onProgress := func(event ProgressEvent) {
+				if !asJSON {
+					displayProgressEvent(event, uiLang)
+				}
+			}
+			if !asJSON {
+				fmt.Printf("❓ %s\n", question)
+			}
+			resp, err := client.SendWithProgress(ChatRequest{
+				SessionID:      sessionID,
+				UserInput:      askPrompt(question),
+				WorkingDir:     workingDir,
+				Provider:       provider,
+				Model:          model,
+				MaxSteps:       maxSteps,
+				ResponseSchema: askSchema,
+				Explain:        true,
+				Tags:           []string{"ask"},
+			}, onProgress)
+			if err != nil {
+				return fmt.Errorf("gateway request failed: %w", err)
+			}
+			if resp.Error != "" {
+				return fmt.Errorf("ask failed: %s", resp.Error)
+			}
+
+			var answer AskAnswer
+			if err := json.Unmarshal(resp.Output, &answer); err != nil {
+				// No structured answer (e.g. stopped at the step limit): show the text
+				fmt.Println(resp.Result)
+				return fmt.Errorf("the agent returned no answer with citations")
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(answer)
+			}
+			fmt.Println("\n" + strings.Repeat("═", 80))
+			fmt.Print(formatAskAnswer(&answer, workingDir))
+			fmt.Println(strings.Repeat("═", 80))
+			if resp.SessionID != "" {
+				fmt.Printf("Ask a follow-up with: zen-claw ask --session %s \"...\"\n", resp.SessionID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Codebase to answer about")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session to save/resume (omit for fresh context)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (default: the gateway's)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (default: the provider's)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 15, "Maximum tool execution steps")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the answer and citations as JSON")
+	return cmd
+}
+
+// askPrompt is the task of a question
+func askPrompt(question string) string {
+	return fmt.Sprintf(`Answer this question about the codebase in the working directory:
+
+%s
+
+How to answer:
+1. Find the relevant code with code_search, find_symbol or search_files, then read it with read_file. Follow definitions and references where the answer depends on them.
+2. Answer from the code you read, not from how such code usually works. Explain the flow step by step and name the functions involved.
+3. Cite every file and line range your answer relies on, with line numbers as read_file showed them. Only cite code you read.
+4. If the code doesn't settle the question, say what is missing instead of guessing.
+`, question)
+}
+
+// formatAskAnswer renders an answer for the terminal; citations of files
+// that don't exist under dir are flagged
+func formatAskAnswer(a *AskAnswer, dir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", strings.TrimSpace(a.Answer))
+	if len(a.Citations) > 0 {
+		b.WriteString("\nSources:\n")
+		for _, c := range a.Citations {
+			location := fmt.Sprintf("%s:%d", c.Path, c.StartLine)
+			if c.EndLine > c.StartLine {
+				location += fmt.Sprintf("-%d", c.EndLine)
+			}
+			path := c.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				location += " (not found)"
+			}
+			fmt.Fprintf(&b, "   • %-40s %s\n", location, c.Note)
+		}
+	}
+	return b.String()
+}
@@ -23,11 +23,13 @@ func newConfigCmd() *cobra.Command {
 		RunE:  runConfigInit,
 	})
 
-	cmd.AddCommand(&cobra.Command{
+	showCmd := &cobra.Command{
 		Use:   "show",
 		Short: "Show current configuration",
 		RunE:  runConfigShow,
-	})
+	}
+	showCmd.Flags().Bool("reveal", false, "Show API keys and tokens in full (masked by default)")
+	cmd.AddCommand(showCmd)
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "path",
@@ -43,6 +45,51 @@ func newConfigCmd() *cobra.Command {
 		RunE:  runConfigCheck,
 	})
 
+	envCmd := &cobra.Command{
+		Use:   "env",
+		Short: "List and validate the ZEN_CLAW_* environment variables",
+		Long: `List the ZEN_CLAW_* environment variables that configure zen-claw
+without a config file, and validate the resulting configuration.
+
+They override the config file's settings. With ZEN_CLAW_CONFIG=env the file
+is ignored: the defaults plus these variables are the whole configuration,
+as a container in Kubernetes gets it from a ConfigMap and a Secret.
+The command fails when a variable doesn't parse or the result is invalid.`,
+		RunE: runConfigEnv,
+	}
+	envCmd.Flags().Bool("set", false, "Only list the variables that are set")
+	cmd.AddCommand(envCmd)
+
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt-keys",
+		Short: "Encrypt the provider API keys in the config file",
+		Long: `Encrypt the plaintext provider API keys in the config file with AES-256-GCM.
+The rest of the file is kept as it is, and zen-claw decrypts the keys when it
+loads the config.
+
+The data key they are encrypted with is kept at --key:
+  keyring:<service>/<account>   OS keyring: macOS Keychain, Linux Secret Service (default keyring:zen-claw/config-key)
+  age:<identity file>           Encrypted to the age identity (needs the age CLI)
+  awskms:<key id or alias>      Encrypted with AWS KMS (needs the aws CLI)
+  env                           Printed once; give it to zen-claw as ZEN_CLAW_CONFIG_KEY
+
+Run it again after adding a key: new plaintext keys are encrypted with the
+same data key. ZEN_CLAW_CONFIG_KEY, when set, is used instead of the key source.`,
+		Example: `  zen-claw config encrypt-keys
+  zen-claw config encrypt-keys --key age:~/.config/age/key.txt
+  zen-claw config encrypt-keys --key awskms:alias/zen-claw`,
+		RunE: runConfigEncryptKeys,
+	}
+	encryptCmd.Flags().String("key", "", "Where the data key is kept (default: keyring:zen-claw/config-key, or the file's)")
+	cmd.AddCommand(encryptCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "repo [dir]",
+		Short: "Show the repo-local .zenclaw.yaml applying to a directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runConfigRepo,
+	})
+
 	return cmd
 }
 
@@ -88,6 +135,11 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("load config: %w", err)
 	}
 
+	// Keys were decrypted on load; keep them off the screen unless asked
+	if reveal, _ := cmd.Flags().GetBool("reveal"); !reveal {
+		cfg.MaskKeys()
+	}
+
 	// Convert to YAML for display
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
@@ -99,6 +151,94 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runConfigEnv(cmd *cobra.Command, args []string) error {
+	onlySet, _ := cmd.Flags().GetBool("set")
+	known, unknown := config.SetEnvVars()
+
+	if config.EnvOnly() {
+		fmt.Printf("Configuration from the environment only (%s=env)\n\n", config.EnvConfig)
+	} else {
+		fmt.Printf("Configuration from %s, overridden by the environment\n\n", config.DefaultConfigPath())
+	}
+	for _, v := range config.EnvVars {
+		value, set := os.LookupEnv(v.Name)
+		set = set && value != ""
+		if onlySet && !set {
+			continue
+		}
+		status := "-"
+		switch {
+		case set && v.Secret:
+			status = "(set)"
+		case set:
+			status = value
+		}
+		fmt.Printf("  %-34s %-24s %s\n", v.Name, status, v.Description)
+	}
+	fmt.Printf("\n%d of %d variables set\n", len(known), len(config.EnvVars))
+	for _, name := range unknown {
+		fmt.Printf("⚠️  %s is not a zen-claw variable\n", name)
+	}
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	fmt.Printf("✅ Valid: %s/%s, gateway on %s\n", cfg.Default.Provider, cfg.GetModel(cfg.Default.Provider), cfg.Gateway.GetAddr())
+	return nil
+}
+
+func runConfigEncryptKeys(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("path")
+	if configPath == "" {
+		configPath = config.DefaultConfigPath()
+	}
+	source, _ := cmd.Flags().GetString("key")
+
+	encrypted, envKey, err := config.EncryptKeys(configPath, source)
+	if err != nil {
+		return fmt.Errorf("encrypt keys: %w", err)
+	}
+	// Make sure the file loads again before reporting success
+	if _, err := config.LoadConfig(configPath); err != nil {
+		return fmt.Errorf("encrypted config doesn't load: %w", err)
+	}
+
+	fmt.Printf("✅ Encrypted %d API key(s) in %s\n", encrypted, configPath)
+	if envKey != "" {
+		fmt.Printf("\nData key (shown once; store it in your secret manager):\n\n  %s=%s\n", config.EnvConfigKey, envKey)
+	}
+	return nil
+}
+
+func runConfigRepo(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	repo, err := config.LoadRepoConfig(dir)
+	if err != nil {
+		return err
+	}
+	if repo == nil {
+		fmt.Printf("No %s applies to %s (looked up to the git root)\n", config.RepoConfigFile, dir)
+		return nil
+	}
+
+	data, err := yaml.Marshal(repo)
+	if err != nil {
+		return fmt.Errorf("marshal repo config: %w", err)
+	}
+	fmt.Printf("Repo configuration from: %s\n\n", repo.Path)
+	fmt.Println(string(data))
+	fmt.Println("Flags and the session's model win over it; it can only tighten the user config's")
+	fmt.Println("limits (read-only tools, fewer steps, a lower budget, more protected paths).")
+	return nil
+}
+
 func runConfigCheck(cmd *cobra.Command, args []string) error {
 	configPath, _ := cmd.Flags().GetString("path")
 	if configPath == "" {
@@ -9,6 +9,8 @@ import (
 
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/consensus"
+	"github.com/neves/zen-claw/internal/judge"
+	"github.com/neves/zen-claw/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +18,11 @@ func newConsensusCmd() *cobra.Command {
 	var role string
 	var verbose bool
 	var showStats bool
+	var budgetUSD float64
+	var budgetTokens int
+	var useJudge bool
+	var judgePreset string
+	var exec blueprintExecution
 
 	cmd := &cobra.Command{
 		Use:   "consensus [prompt]",
@@ -47,8 +54,22 @@ Examples:
   # Custom role
   zen-claw consensus --role "kubernetes_operator_expert" "Design CRD for database management"
 
+  # Cap the run's estimated spend (remaining workers are canceled once it is used)
+  zen-claw consensus --budget 0.05 "Design a rate limiter"
+
+  # Have a judge score the workers on weighted criteria before synthesis
+  # (presets: code-quality, security, api-design, or consensus.judge_presets)
+  zen-claw consensus --judge-preset security --role security_architect "Design secret rotation"
+
+  # Implement the blueprint right away: an agent session gets it as its plan
+  # and reports which sections it implemented
+  zen-claw consensus --execute --session rate-limiter --report compliance.md "Add rate limiting to the API"
+
   # View worker performance stats
-  zen-claw consensus --stats`,
+  zen-claw consensus --stats
+
+Set consensus.draft_workers in the config to let cheap models draft first;
+the workers then refine the best drafts instead of starting from scratch.`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			// Show stats mode
@@ -77,18 +98,27 @@ Examples:
 				os.Exit(1)
 			}
 
-			runConsensus(prompt, role, verbose)
+			runConsensus(prompt, role, verbose, budgetUSD, budgetTokens, useJudge || judgePreset != "", judgePreset, exec)
 		},
 	}
 
 	cmd.Flags().StringVar(&role, "role", "software_architect", "Expert role for all workers (e.g., security_architect, api_designer)")
 	cmd.Flags().BoolVar(&verbose, "verbose", false, "Show individual worker responses")
 	cmd.Flags().BoolVar(&showStats, "stats", false, "Show worker performance statistics")
+	cmd.Flags().Float64Var(&budgetUSD, "budget", 0, "Estimated spend limit in USD (default: consensus.budget_usd)")
+	cmd.Flags().IntVar(&budgetTokens, "budget-tokens", 0, "Estimated token limit (default: consensus.budget_tokens)")
+	cmd.Flags().BoolVar(&useJudge, "judge", false, "Have an LLM judge score the workers before synthesis")
+	cmd.Flags().StringVar(&judgePreset, "judge-preset", "", "Weighted criteria preset for the judge (implies --judge; default: consensus.judge_preset)")
+	cmd.Flags().BoolVar(&exec.enabled, "execute", false, "Implement the blueprint in an agent session and report compliance")
+	cmd.Flags().StringVar(&exec.sessionID, "session", "", "Named agent session for --execute (omit for fresh context)")
+	cmd.Flags().StringVar(&exec.workingDir, "working-dir", ".", "Working directory for --execute")
+	cmd.Flags().IntVar(&exec.maxSteps, "max-steps", 100, "Maximum agent steps for --execute")
+	cmd.Flags().StringVar(&exec.reportFile, "report", "", "Write the --execute compliance report to this markdown file")
 
 	return cmd
 }
 
-func runConsensus(prompt, role string, verbose bool) {
+func runConsensus(prompt, role string, verbose bool, budgetUSD float64, budgetTokens int, useJudge bool, judgePreset string, exec blueprintExecution) {
 	fmt.Println("🤖 Zen Claw - Multi-AI Consensus")
 	fmt.Println("═" + strings.Repeat("═", 78))
 	fmt.Printf("Role: %s (all workers + arbiter)\n", role)
@@ -131,11 +161,17 @@ func runConsensus(prompt, role string, verbose bool) {
 	// Run consensus
 	fmt.Println("⏳ Querying AI workers in parallel...")
 	start := time.Now()
+	report := newRunReport(types.RunConsensus, prompt)
 
 	result, err := engine.Generate(ctx, consensus.ConsensusRequest{
-		Prompt: prompt,
-		Role:   role,
+		Prompt:       prompt,
+		Role:         role,
+		BudgetUSD:    budgetUSD,
+		BudgetTokens: budgetTokens,
+		UseJudge:     useJudge,
+		JudgePreset:  judgePreset,
 	})
+	recordConsensusRun(report, result, err)
 
 	if err != nil {
 		fmt.Printf("\n❌ Consensus failed: %v\n", err)
@@ -152,10 +188,17 @@ func runConsensus(prompt, role string, verbose bool) {
 		os.Exit(1)
 	}
 
-	// Show timing
+	// Show timing and spend
+	if len(result.Drafts) > 0 {
+		fmt.Printf("\n✓ %d drafts refined by the workers\n", len(result.Drafts))
+	}
 	fmt.Printf("\n✓ Workers completed in %v (parallel)\n", result.WorkerDuration.Round(time.Millisecond))
 	fmt.Printf("✓ Arbiter (%s) synthesized in %v\n", result.ArbiterModel, result.ArbiterDuration.Round(time.Millisecond))
 	fmt.Printf("✓ Total time: %v\n", time.Since(start).Round(time.Millisecond))
+	fmt.Printf("✓ Estimated spend: ~$%.4f (%d tokens)\n", result.Spend.CostUSD, result.Spend.Tokens())
+	if result.BudgetExceeded {
+		fmt.Println("⚠️  Budget used up: remaining workers were canceled")
+	}
 
 	// Show worker scores
 	fmt.Println("\n📊 Worker Scores (by arbiter):")
@@ -177,6 +220,10 @@ func runConsensus(prompt, role string, verbose bool) {
 		}
 	}
 
+	if result.JudgeResult != nil {
+		printJudgeResult(result.JudgeResult)
+	}
+
 	// Show individual worker responses if verbose
 	if verbose {
 		fmt.Println("\n" + strings.Repeat("─", 80))
@@ -203,6 +250,70 @@ func runConsensus(prompt, role string, verbose bool) {
 	fmt.Println(result.Blueprint)
 	fmt.Println()
 	fmt.Println(strings.Repeat("═", 80))
+
+	if exec.enabled {
+		executeBlueprint(prompt, result.Blueprint, exec)
+	}
+}
+
+// printJudgeResult shows the judge's pick and, for weighted criteria, how
+// each worker's score adds up
+func printJudgeResult(r *judge.Result) {
+	eval := r.Evaluation
+	title := "\n⚖️  Judge"
+	if eval.Preset != "" {
+		title += " (" + eval.Preset + ")"
+	}
+	fmt.Printf("%s: %s (score %.2f, confidence %.2f)\n", title, r.Winner.Provider, eval.WinnerScore, eval.Confidence)
+	for _, b := range eval.Breakdown {
+		var parts []string
+		for _, c := range b.Criteria {
+			parts = append(parts, fmt.Sprintf("%s %.2f×%.0f%%", c.Criterion, c.Score, c.Weight*100))
+		}
+		fmt.Printf("   • %s: %.2f = %s\n", b.Provider, b.Score, strings.Join(parts, " + "))
+	}
+	if eval.Reasoning != "" {
+		fmt.Printf("   %s\n", truncatePrompt(eval.Reasoning, 200))
+	}
+}
+
+// recordConsensusRun reports the run's calls to the gateway: drafts,
+// workers with their scores, and the arbiter
+func recordConsensusRun(report *runReport, result *consensus.ConsensusResult, err error) {
+	if result == nil {
+		report.finish(err)
+		return
+	}
+	workerCost := 0.0
+	addResults := func(results []consensus.WorkerResult, role string) {
+		for _, r := range results {
+			call := types.RunCall{
+				Provider:   r.Worker.Provider,
+				Model:      r.Worker.Model,
+				Role:       role,
+				DurationMs: r.Duration.Milliseconds(),
+				CostUSD:    r.CostUSD,
+				Score:      r.Score,
+			}
+			if r.Error != nil {
+				call.Error = r.Error.Error()
+			}
+			workerCost += r.CostUSD
+			report.add(call)
+		}
+	}
+	addResults(result.Drafts, "draft")
+	addResults(result.WorkerResults, "worker")
+	if result.ArbiterModel != "" {
+		report.add(types.RunCall{
+			Provider:   result.ArbiterModel,
+			Role:       "arbiter",
+			DurationMs: result.ArbiterDuration.Milliseconds(),
+			CostUSD:    max(result.Spend.CostUSD-workerCost, 0),
+		})
+	}
+	report.run.Tokens = result.Spend.Tokens()
+	report.finish(err)
 }
 
 func showWorkerStats() {
@@ -224,7 +335,7 @@ func showWorkerStats() {
 		return
 	}
 
-	fmt.Printf("\n%-25s %8s %8s %8s   %s\n", "WORKER", "TASKS", "AVG", "TOTAL", "BEST ROLES")
+	fmt.Printf("\n%-25s %8s %8s %8s %7s   %s\n", "WORKER", "TASKS", "AVG", "TOTAL", "👍/👎", "BEST ROLES")
 	fmt.Println(strings.Repeat("─", 80))
 
 	for key, s := range stats {
@@ -232,12 +343,13 @@ func showWorkerStats() {
 		if len(s.BestRoles) > 0 {
 			rolesStr = strings.Join(s.BestRoles, ", ")
 		}
-		fmt.Printf("%-25s %8d %8.1f %8d   %s\n",
-			key, s.TotalTasks, s.AvgScore, s.TotalScore, rolesStr)
+		fmt.Printf("%-25s %8d %8.1f %8d %7s   %s\n",
+			key, s.TotalTasks, s.AvgScore, s.TotalScore, fmt.Sprintf("%d/%d", s.RatedGood, s.RatedBad), rolesStr)
 	}
 
 	fmt.Println("\n💡 Higher average score = better performance in consensus tasks")
 	fmt.Println("   Best roles = roles where worker scored 8+")
+	fmt.Println("   👍/👎 = user ratings (/rate) of this model's agent answers")
 }
 
 func truncatePrompt(s string, maxLen int) string {
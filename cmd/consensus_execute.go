@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/consensus"
+	"github.com/neves/zen-claw/internal/i18n"
+)
+
+// blueprintExecution holds the consensus --execute options
+type blueprintExecution struct {
+	enabled    bool
+	sessionID  string
+	workingDir string
+	maxSteps   int
+	reportFile string
+}
+
+// executeBlueprint hands the blueprint to an agent session as its plan and
+// prints the compliance report
+func executeBlueprint(task, blueprint string, opts blueprintExecution) {
+	workingDir := opts.workingDir
+	if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+		workingDir = abs
+	}
+	sections := consensus.SplitSections(blueprint)
+
+	fmt.Println("\n🛠️  Executing blueprint")
+	fmt.Println("═" + strings.Repeat("═", 78))
+	fmt.Printf("Sections: %d\n", len(sections))
+	for _, s := range sections {
+		fmt.Printf("   [%s] %s\n", s.ID, s.Title)
+	}
+	fmt.Printf("Working directory: %s\n\n", workingDir)
+
+	client := NewGatewayClient(getGatewayURL())
+	if err := ensureGateway(client); err != nil {
+		fmt.Printf("❌ Gateway not available: %v\n", err)
+		fmt.Println("   The blueprint above was not executed.")
+		os.Exit(1)
+	}
+
+	uiLang := configuredLanguage()
+	onProgress := func(event ProgressEvent) { displayProgressEvent(event, uiLang) }
+	resp, err := client.SendWithProgress(ChatRequest{
+		SessionID:  opts.sessionID,
+		UserInput:  consensus.ExecutionPrompt(task, sections),
+		WorkingDir: workingDir,
+		MaxSteps:   opts.maxSteps,
+	}, onProgress)
+	if err != nil {
+		fmt.Printf("\n❌ Gateway request failed: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.Error != "" {
+		fmt.Printf("\n❌ Agent execution failed: %s\n", resp.Error)
+		os.Exit(1)
+	}
+
+	report, found := consensus.ParseCompliance(resp.Result, sections)
+	answer := consensus.StripCompliance(resp.Result)
+	if !found {
+		// Stopped at the step limit or forgot the block: ask for it alone
+		fmt.Println("\n📋 Asking the agent for its compliance report...")
+		followUp, err := client.SendWithProgress(ChatRequest{
+			SessionID:  resp.SessionID,
+			UserInput:  consensus.CompliancePrompt(sections),
+			WorkingDir: workingDir,
+			MaxSteps:   5,
+		}, onProgress)
+		if err == nil && followUp.Error == "" {
+			report, _ = consensus.ParseCompliance(followUp.Result, sections)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("═", 80))
+	fmt.Println(i18n.T(uiLang, "result"))
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Println(answer)
+
+	fmt.Println("\n" + strings.Repeat("═", 80))
+	fmt.Println("📋 BLUEPRINT COMPLIANCE")
+	fmt.Println(strings.Repeat("═", 80))
+	icons := map[string]string{
+		consensus.StatusImplemented: "✅",
+		consensus.StatusPartial:     "🟡",
+		consensus.StatusSkipped:     "⏭️ ",
+		consensus.StatusUnreported:  "❔",
+	}
+	for _, s := range report.Sections {
+		fmt.Printf("%s [%s] %s (%s)\n", icons[s.Status], s.Section.ID, s.Section.Title, s.Status)
+		if s.Notes != "" {
+			fmt.Printf("      %s\n", s.Notes)
+		}
+	}
+	fmt.Printf("\n%d/%d sections implemented, %d partial, %d skipped, %d unreported\n",
+		report.Count(consensus.StatusImplemented), len(report.Sections), report.Count(consensus.StatusPartial),
+		report.Count(consensus.StatusSkipped), report.Count(consensus.StatusUnreported))
+	if resp.StepLimit != nil {
+		fmt.Printf("⚠️  The agent stopped at the step limit; continue with:\n   zen-claw agent --session %s --max-steps %d \"Continue implementing the blueprint.\"\n",
+			resp.SessionID, resp.StepLimit.Extend)
+	}
+
+	if opts.reportFile != "" {
+		if err := os.WriteFile(opts.reportFile, []byte(complianceMarkdown(task, report)), 0644); err != nil {
+			fmt.Printf("❌ Failed to write report: %v\n", err)
+		} else {
+			fmt.Printf("📝 Report written to %s\n", opts.reportFile)
+		}
+	}
+	if !report.Complete() {
+		os.Exit(2)
+	}
+}
+
+// complianceMarkdown renders the report for --report
+func complianceMarkdown(task string, report consensus.ComplianceReport) string {
+	var sb strings.Builder
+	sb.WriteString("# Blueprint compliance\n\n")
+	fmt.Fprintf(&sb, "Task: %s\n\n", strings.TrimSpace(task))
+	fmt.Fprintf(&sb, "%d/%d sections implemented.\n\n", report.Count(consensus.StatusImplemented), len(report.Sections))
+	sb.WriteString("| Section | Title | Status | Notes |\n|---|---|---|---|\n")
+	for _, s := range report.Sections {
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", s.Section.ID, s.Section.Title, s.Status, strings.ReplaceAll(s.Notes, "|", "\\|"))
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/recorder"
+	"github.com/spf13/cobra"
+)
+
+func newDatasetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Export recorded runs as fine-tuning or eval datasets",
+		Long: `Export runs recorded by the gateway as JSONL datasets.
+
+Recording is opt-in. Enable it in ~/.zen/zen-claw/config.yaml:
+
+  recording:
+    enabled: true
+    dir: ~/.zen/zen-claw/data/recordings   # default
+    providers: [deepseek, qwen]            # optional, default all
+
+Secrets (API keys, tokens, private keys), e-mail addresses and your home
+directory are scrubbed before records are written.
+
+Examples:
+  # Everything recorded
+  zen-claw dataset export > all.jsonl
+
+  # Only runs rated good, in OpenAI chat fine-tuning format
+  zen-claw dataset export --min-rating 1 --format chat > train.jsonl
+
+  # Only DeepSeek runs
+  zen-claw dataset export --provider deepseek`,
+	}
+
+	cmd.AddCommand(newDatasetExportCmd())
+	return cmd
+}
+
+func newDatasetExportCmd() *cobra.Command {
+	var provider, format, dir string
+	var minRating int
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write recorded runs to stdout as JSONL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				cfg, err := config.LoadConfig("")
+				if err != nil {
+					return fmt.Errorf("load config: %w", err)
+				}
+				dir = cfg.GetRecordingDir()
+			}
+
+			filter := recorder.Filter{Provider: provider}
+			if cmd.Flags().Changed("min-rating") {
+				filter.MinRating = &minRating
+			}
+			records, err := recorder.Load(dir, filter)
+			if err != nil {
+				return fmt.Errorf("load recordings: %w", err)
+			}
+
+			enc := json.NewEncoder(os.Stdout)
+			for _, rec := range records {
+				switch format {
+				case "raw":
+					err = enc.Encode(rec)
+				case "chat":
+					if rec.Error != "" || rec.Response == "" {
+						continue // Failed runs make bad training examples
+					}
+					messages := append(rec.Messages, ai.Message{Role: "assistant", Content: rec.Response})
+					chat := make([]map[string]string, 0, len(messages))
+					for _, m := range messages {
+						if m.Role == "tool" || (m.Role == "assistant" && len(m.ToolCalls) > 0) {
+							continue // Chat format carries only the visible conversation
+						}
+						chat = append(chat, map[string]string{"role": m.Role, "content": m.Content})
+					}
+					err = enc.Encode(map[string]interface{}{"messages": chat})
+				default:
+					return fmt.Errorf("unknown format %q (use raw or chat)", format)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(os.Stderr, "Exported %d records from %s\n", len(records), dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Only runs on this provider")
+	cmd.Flags().IntVar(&minRating, "min-rating", 0, "Only runs rated at least this (1 = good, -1 = bad); unrated runs are excluded")
+	cmd.Flags().StringVar(&format, "format", "raw", "Output format: raw (full record with tool trace) or chat (OpenAI fine-tuning messages)")
+	cmd.Flags().StringVar(&dir, "dir", "", "Recordings directory (default from config)")
+	return cmd
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/rag"
+	"github.com/spf13/cobra"
+)
+
+// ArchitectureDiagrams are the diagrams the model draws from the repo map
+type ArchitectureDiagrams struct {
+	Summary    string `json:"summary"`
+	Components string `json:"components"`
+	Sequence   string `json:"sequence"`
+}
+
+// diagramSchema is the shape of the diagrams
+func diagramSchema(format string) map[string]interface{} {
+	language := "Mermaid (flowchart for components, sequenceDiagram for the sequence)"
+	if format == "dot" {
+		language = "Graphviz DOT (a digraph each)"
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"summary": map[string]interface{}{
+				"type":        "string",
+				"description": "The architecture in a short paragraph: the main components and how a request flows through them",
+			},
+			"components": map[string]interface{}{
+				"type":        "string",
+				"description": "Component view in " + language + ", without markdown fences",
+			},
+			"sequence": map[string]interface{}{
+				"type":        "string",
+				"description": "Sequence view in " + language + ", without markdown fences",
+			},
+		},
+		"required": []string{"summary", "components", "sequence"},
+	}
+}
+
+func newDiagramCmd() *cobra.Command {
+	var outDir, format, flow, sessionID, provider, model string
+	var depth, maxSteps int
+	var graphOnly, reindex bool
+
+	cmd := &cobra.Command{
+		Use:   "diagram [path]",
+		Short: "Draw architecture diagrams from the repo index",
+		Long: `Build the package dependency graph of a repository from its index
+(zen-claw index build; built on first use) and ask the model for two
+architecture diagrams: a component view and a sequence view of one flow.
+The agent gets the read-only tools to check the code behind the graph.
+
+The diagrams and the dependency graph are written to docs/ (--out), as
+Mermaid in architecture.md or as Graphviz .dot files (--format dot), and
+previewed in the terminal. --graph-only writes the dependency graph alone,
+without the model.
+
+Go imports are resolved through go.mod, JavaScript/TypeScript relative
+imports and Python imports of the repo's packages; third-party imports
+aren't drawn. --depth merges deeper packages into their parent directory
+for big repositories.`,
+		Example: `  zen-claw diagram
+  zen-claw diagram --flow "a chat request from the CLI to the provider" --depth 2
+  zen-claw diagram ~/git/api --format dot --out docs/architecture
+  zen-claw diagram --graph-only --reindex`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			root, err := filepath.Abs(agent.ExpandPath(dir))
+			if err != nil {
+				return err
+			}
+			if format != "mermaid" && format != "dot" {
+				return fmt.Errorf("unknown format %q (use mermaid or dot)", format)
+			}
+
+			graph, err := repoGraph(root, reindex)
+			if err != nil {
+				return err
+			}
+			graph = graph.Collapse(depth)
+			if len(graph.Packages) == 0 {
+				return fmt.Errorf("no source packages in the index of %s", root)
+			}
+			fmt.Printf("📦 %d packages\n\n%s\n", len(graph.Packages), graph)
+
+			var diagrams *ArchitectureDiagrams
+			if !graphOnly {
+				client := NewGatewayClient(getGatewayURL())
+				if err := ensureGateway(client); err != nil {
+					return err
+				}
+				uiLang := configuredLanguage()
+				resp, err := client.SendWithProgress(ChatRequest{
+					SessionID:      sessionID,
+					UserInput:      diagramPrompt(graph, format, flow),
+					WorkingDir:     root,
+					Provider:       provider,
+					Model:          model,
+					MaxSteps:       maxSteps,
+					ResponseSchema: diagramSchema(format),
+					ReadOnly:       true,
+					Tags:           []string{"diagram"},
+				}, func(event ProgressEvent) { displayProgressEvent(event, uiLang) })
+				if err != nil {
+					return fmt.Errorf("gateway request failed: %w", err)
+				}
+				if resp.Error != "" {
+					return fmt.Errorf("diagram failed: %s", resp.Error)
+				}
+				diagrams = &ArchitectureDiagrams{}
+				if err := json.Unmarshal(resp.Output, diagrams); err != nil {
+					fmt.Println(resp.Result)
+					return fmt.Errorf("the agent returned no diagrams")
+				}
+				diagrams.Components = stripDiagramFences(diagrams.Components)
+				diagrams.Sequence = stripDiagramFences(diagrams.Sequence)
+				for name, src := range map[string]string{"component": diagrams.Components, "sequence": diagrams.Sequence} {
+					if !validDiagram(src, format) {
+						fmt.Printf("⚠️  The %s diagram doesn't look like %s; check it before publishing\n", name, format)
+					}
+				}
+			}
+
+			if !filepath.IsAbs(outDir) {
+				outDir = filepath.Join(root, outDir)
+			}
+			written, err := writeDiagrams(outDir, format, graph, diagrams)
+			if err != nil {
+				return err
+			}
+			if diagrams != nil {
+				fmt.Println("\n" + strings.Repeat("═", 80))
+				fmt.Printf("%s\n\nComponents:\n%s\nSequence:\n%s", diagrams.Summary,
+					indentDiagram(diagrams.Components), indentDiagram(diagrams.Sequence))
+				fmt.Println(strings.Repeat("═", 80))
+			}
+			for _, path := range written {
+				rel, err := filepath.Rel(root, path)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					rel = path
+				}
+				fmt.Printf("✓ Wrote %s\n", rel)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outDir, "out", "docs", "Directory the diagrams are written to (relative to the repo)")
+	cmd.Flags().StringVar(&format, "format", "mermaid", "Diagram format: mermaid (architecture.md) or dot (.dot files)")
+	cmd.Flags().StringVar(&flow, "flow", "", "Flow the sequence view shows (default: the main request path)")
+	cmd.Flags().IntVar(&depth, "depth", 0, "Merge packages deeper than this many directories into their parent (0 = all packages)")
+	cmd.Flags().BoolVar(&graphOnly, "graph-only", false, "Write the dependency graph only, without asking the model")
+	cmd.Flags().BoolVar(&reindex, "reindex", false, "Rebuild the index first")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session to save/resume (omit for fresh context)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (default: the gateway's)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (default: the provider's)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 20, "Maximum tool execution steps")
+	return cmd
+}
+
+// repoGraph builds the package graph from root's index, indexing root
+// first when its index is empty (or reindex is set)
+func repoGraph(root string, reindex bool) (*rag.PackageGraph, error) {
+	indexer, err := rag.NewIndexer(&rag.IndexerConfig{DBPath: rag.DefaultIndexDBPath(filepath.Base(root)), RootDir: root})
+	if err != nil {
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	defer indexer.Close()
+
+	files, err := indexer.Files()
+	if err == nil && (reindex || len(files) == 0) {
+		fmt.Printf("Indexing: %s\n", root)
+		if _, err := indexer.Index(); err != nil {
+			return nil, fmt.Errorf("index %s: %w", root, err)
+		}
+		files, err = indexer.Files()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	return rag.BuildPackageGraph(files, root), nil
+}
+
+// diagramPromptPackagesMax caps the packages described with their symbols
+const diagramPromptPackagesMax = 150
+
+// diagramPrompt is the task of drawing the diagrams
+func diagramPrompt(graph *rag.PackageGraph, format, flow string) string {
+	var b strings.Builder
+	b.WriteString("Draw architecture diagrams of the repository in the working directory.\n\n")
+	b.WriteString("Package dependency graph from the repo index (package (files) → the repo packages it imports):\n")
+	b.WriteString(graph.String())
+	b.WriteString("\nSymbols defined per package:\n")
+	for i, pkg := range graph.Packages {
+		if i == diagramPromptPackagesMax {
+			fmt.Fprintf(&b, "... %d more packages\n", len(graph.Packages)-i)
+			break
+		}
+		if len(pkg.Symbols) > 0 {
+			fmt.Fprintf(&b, "%s: %s\n", pkg.Path, strings.Join(pkg.Symbols, ", "))
+		}
+	}
+	if flow == "" {
+		flow = "the main request path: from the entry point (CLI command, HTTP handler or main) to where the work is done"
+	}
+	fmt.Fprintf(&b, `
+Diagrams:
+1. Component view: the main components (group packages that belong together; skip test helpers and trivial utilities) and the dependencies between them, labelled with what flows along them.
+2. Sequence view of %s: the participants are components or key types, the messages the calls made.
+
+Use the graph for the structure; read the entry points and the key files (read_file, find_symbol, code_search) to name the components and to get the sequence right. Don't draw anything the code doesn't show.
+`, flow)
+	if format == "dot" {
+		b.WriteString("Write both diagrams in Graphviz DOT, each a complete digraph.\n")
+	} else {
+		b.WriteString("Write the component view as a Mermaid flowchart and the sequence view as a Mermaid sequenceDiagram; quote labels with special characters.\n")
+	}
+	return b.String()
+}
+
+// writeDiagrams writes the dependency graph and the model's diagrams (nil
+// for --graph-only) to dir and returns the files written
+func writeDiagrams(dir, format string, graph *rag.PackageGraph, d *ArchitectureDiagrams) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	if format == "dot" {
+		files["architecture-packages.dot"] = graph.DOT()
+		if d != nil {
+			files["architecture-components.dot"] = d.Components + "\n"
+			files["architecture-sequence.dot"] = d.Sequence + "\n"
+		}
+	} else {
+		var b strings.Builder
+		b.WriteString("# Architecture\n\n")
+		if d != nil {
+			fmt.Fprintf(&b, "%s\n\n## Components\n\n```mermaid\n%s\n```\n\n## Sequence\n\n```mermaid\n%s\n```\n\n",
+				strings.TrimSpace(d.Summary), d.Components, d.Sequence)
+		}
+		fmt.Fprintf(&b, "## Package dependencies\n\n```mermaid\n%s```\n\n_Generated by `zen-claw diagram` from the repo index._\n", graph.Mermaid())
+		files["architecture.md"] = b.String()
+	}
+
+	var written []string
+	for _, name := range []string{"architecture.md", "architecture-packages.dot", "architecture-components.dot", "architecture-sequence.dot"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// stripDiagramFences removes the markdown fences models wrap diagrams in
+func stripDiagramFences(src string) string {
+	src = strings.TrimSpace(src)
+	if !strings.HasPrefix(src, "```") {
+		return src
+	}
+	if i := strings.Index(src, "\n"); i >= 0 {
+		src = src[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(src), "```"))
+}
+
+// validDiagram checks that a diagram starts like one of its format
+func validDiagram(src, format string) bool {
+	first := strings.TrimSpace(src)
+	if format == "dot" {
+		return strings.HasPrefix(first, "digraph") || strings.HasPrefix(first, "strict digraph")
+	}
+	for _, kind := range []string{"flowchart", "graph", "sequenceDiagram", "C4", "classDiagram"} {
+		if strings.HasPrefix(first, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// indentDiagram indents a diagram's source for the terminal preview
+func indentDiagram(src string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(src, "\n"), "\n") {
+		fmt.Fprintf(&b, "   %s\n", line)
+	}
+	return b.String()
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/roles"
+	"github.com/neves/zen-claw/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -35,6 +37,7 @@ type FabricProfile struct {
 
 func newFabricCmd() *cobra.Command {
 	var profile string
+	var resume string
 	var verbose bool
 
 	cmd := &cobra.Command{
@@ -49,13 +52,17 @@ Features:
   - Multiple workers with different specializations (Go, TypeScript, etc.)
   - Parallel execution for independent subtasks
   - Profiles to save/load configurations
+  - Named sessions that survive restarts (team, conversations, history)
 
 Examples:
   # Interactive mode
   zen-claw fabric
 
   # Load a saved profile
-  zen-claw fabric --profile fullstack`,
+  zen-claw fabric --profile fullstack
+
+  # Resume a named session (created if it doesn't exist yet)
+  zen-claw fabric --resume fullstack-refactor`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			task := ""
@@ -64,7 +71,7 @@ Examples:
 			}
 
 			if task == "" {
-				runFabricInteractive(profile, verbose)
+				runFabricInteractive(profile, resume, verbose)
 			} else {
 				fmt.Println("For one-shot tasks, use interactive mode: zen-claw fabric")
 			}
@@ -72,6 +79,7 @@ Examples:
 	}
 
 	cmd.Flags().StringVar(&profile, "profile", "", "Load a saved profile")
+	cmd.Flags().StringVar(&resume, "resume", "", "Resume a named session, or start one with this name")
 	cmd.Flags().BoolVar(&verbose, "verbose", false, "Show detailed output")
 
 	return cmd
@@ -89,18 +97,26 @@ type FabricSession struct {
 	workerMsgs  map[string][]ai.Message // worker name -> messages
 	coordMsgs   []ai.Message
 	profilesDir string
+	sessionName string // Saved after every change when set
+	roles       *roles.Registry
+}
+
+// modelParams returns the sampling settings for a call to provider: the
+// call's defaults with the params configured for its model on top
+func (s *FabricSession) modelParams(provider string, defaults types.ModelParams) types.ModelParams {
+	return defaults.Merge(s.cfg.GetModelParams(provider, s.cfg.GetModel(provider)))
 }
 
 // FabricExchange represents one task execution
 type FabricExchange struct {
-	Task          string
-	WorkerOutputs map[string]string
-	CoordReview   string
-	Timestamp     time.Time
+	Task          string            `json:"task"`
+	WorkerOutputs map[string]string `json:"worker_outputs"`
+	CoordReview   string            `json:"coordinator_review"`
+	Timestamp     time.Time         `json:"timestamp"`
 }
 
 // runFabricInteractive runs the fabric session in interactive mode
-func runFabricInteractive(profileName string, verbose bool) {
+func runFabricInteractive(profileName, sessionName string, verbose bool) {
 	fmt.Println("🧵 Zen Claw - Multi-Worker Fabric")
 	fmt.Println("═" + strings.Repeat("═", 78))
 	fmt.Println()
@@ -117,6 +133,9 @@ func runFabricInteractive(profileName string, verbose bool) {
 	fmt.Println("  /profile delete <name>       - Delete a profile")
 	fmt.Println()
 	fmt.Println("Session Commands:")
+	fmt.Println("  /session save <name>         - Save the session (resume with --resume <name>)")
+	fmt.Println("  /session list                - List saved sessions")
+	fmt.Println("  /session delete <name>       - Delete a saved session")
 	fmt.Println("  /status                      - Show current team")
 	fmt.Println("  /history                     - Show task history")
 	fmt.Println("  /clear                       - Clear history")
@@ -146,6 +165,7 @@ func runFabricInteractive(profileName string, verbose bool) {
 		workerMsgs:  make(map[string][]ai.Message),
 		coordMsgs:   []ai.Message{},
 		profilesDir: filepath.Join(home, ".zen", "zen-claw", "fabric-profiles"),
+		roles:       roles.Load(roles.DefaultDir()),
 	}
 
 	// Ensure profiles directory exists
@@ -160,6 +180,23 @@ func runFabricInteractive(profileName string, verbose bool) {
 		}
 	}
 
+	// Resume a named session; a new name starts a session saved under it
+	if sessionName != "" {
+		if err := session.loadSession(sessionName); err == nil {
+			fmt.Printf("✓ Resumed session: %s (%d tasks)\n", sessionName, len(session.history))
+			if profileName != "" {
+				fmt.Println("   The session's team is used; the profile was ignored.")
+			}
+		} else if os.IsNotExist(err) {
+			session.sessionName = sessionName
+			session.persistSession()
+			fmt.Printf("✓ New session: %s\n", sessionName)
+		} else {
+			fmt.Printf("❌ Could not resume session '%s': %v\n", sessionName, err)
+			return
+		}
+	}
+
 	// If no workers, add defaults
 	if len(session.workers) == 0 {
 		fmt.Println("\n💡 No workers configured. Add workers with: /worker add <name> <provider> <role>")
@@ -220,12 +257,18 @@ func (s *FabricSession) handleCommand(input string) {
 
 	case strings.HasPrefix(input, "/coordinator"):
 		s.handleCoordinator(input)
+		s.persistSession()
 
 	case strings.HasPrefix(input, "/worker"):
 		s.handleWorker(input)
+		s.persistSession()
 
 	case strings.HasPrefix(input, "/profile"):
 		s.handleProfile(input)
+		s.persistSession()
+
+	case strings.HasPrefix(input, "/session"):
+		s.handleSession(input)
 
 	case input == "/status":
 		s.printStatus()
@@ -237,6 +280,7 @@ func (s *FabricSession) handleCommand(input string) {
 		s.history = []FabricExchange{}
 		s.workerMsgs = make(map[string][]ai.Message)
 		s.coordMsgs = []ai.Message{}
+		s.persistSession()
 		fmt.Println("✓ History cleared")
 
 	case input == "/verbose":
@@ -251,6 +295,7 @@ func (s *FabricSession) handleCommand(input string) {
 			return
 		}
 		s.runTask(input)
+		s.persistSession()
 	}
 }
 
@@ -319,6 +364,9 @@ func (s *FabricSession) handleWorker(input string) {
 			Role:     role,
 		})
 		fmt.Printf("✓ Added worker: %s (%s, role: %s)\n", name, provider, role)
+		if _, known := s.roles.Get(role); !known {
+			fmt.Printf("   Role '%s' is not in the role library; add it with: zen-claw roles add %s\n", role, role)
+		}
 
 	case "remove":
 		if len(parts) < 3 {
@@ -490,6 +538,9 @@ func (s *FabricSession) printStatus() {
 		fmt.Printf("    • %s: %s (role: %s)\n", w.Name, w.Provider, w.Role)
 	}
 	fmt.Printf("  History: %d tasks\n", len(s.history))
+	if s.sessionName != "" {
+		fmt.Printf("  Session: %s\n", s.sessionName)
+	}
 	fmt.Println(strings.Repeat("─", 50))
 }
 
@@ -509,7 +560,7 @@ func (s *FabricSession) printHistory() {
 func (s *FabricSession) printHelp() {
 	fmt.Println("Team: /coordinator, /worker add/remove/list")
 	fmt.Println("Profile: /profile save/load/list/delete")
-	fmt.Println("Session: /status, /history, /clear, /verbose, /exit")
+	fmt.Println("Session: /session save/list/delete, /status, /history, /clear, /verbose, /exit")
 }
 
 // runTask executes a task through the fabric pipeline
@@ -524,12 +575,19 @@ func (s *FabricSession) runTask(task string) {
 
 	totalStart := time.Now()
 
+	// Report the run to the gateway when it ends
+	report := newRunReport(types.RunFabric, task)
+	var runErr error
+	defer func() { report.finish(runErr) }()
+
 	// Create coordinator
 	coordinator, err := s.factory.CreateProvider(s.coordinator)
 	if err != nil {
 		fmt.Printf("❌ Coordinator not available: %v\n", err)
+		runErr = err
 		return
 	}
+	coordModel := s.cfg.GetModel(s.coordinator)
 
 	// ═══════════════════════════════════════════════════════════════════════
 	// PHASE 1: Coordinator creates plan and assigns subtasks to workers
@@ -569,14 +627,18 @@ Assign work to ALL workers. Be specific about what each worker should do.`, work
 
 	s.coordMsgs = append(s.coordMsgs, ai.Message{Role: "user", Content: planPrompt})
 
+	params := s.modelParams(s.coordinator, types.ModelParams{MaxTokens: 2000, Temperature: 0.7})
 	planResp, err := coordinator.Chat(ctx, ai.ChatRequest{
-		Model:       s.cfg.GetModel(s.coordinator),
+		Model:       coordModel,
 		Messages:    s.coordMsgs,
-		MaxTokens:   2000,
-		Temperature: 0.7,
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
 	})
+	report.chat(s.coordinator, coordModel, "coordinator", s.coordMsgs, responseContent(planResp), time.Since(planStart), err)
 	if err != nil {
 		fmt.Printf("❌ Coordinator failed: %v\n", err)
+		runErr = err
 		return
 	}
 
@@ -599,7 +661,7 @@ Assign work to ALL workers. Be specific about what each worker should do.`, work
 	fmt.Println("\n⚙️  Phase 2: Workers executing in parallel...")
 	execStart := time.Now()
 
-	workerOutputs := s.executeWorkersParallel(ctx, assignments)
+	workerOutputs := s.executeWorkersParallel(ctx, assignments, report)
 
 	fmt.Printf("✓ All workers done (%v)\n", time.Since(execStart).Round(time.Millisecond))
 
@@ -644,14 +706,18 @@ Be decisive and actionable.`, task, outputsSummary.String())
 
 	s.coordMsgs = append(s.coordMsgs, ai.Message{Role: "user", Content: synthPrompt})
 
+	params = s.modelParams(s.coordinator, types.ModelParams{MaxTokens: 4000, Temperature: 0.5})
 	synthResp, err := coordinator.Chat(ctx, ai.ChatRequest{
-		Model:       s.cfg.GetModel(s.coordinator),
+		Model:       coordModel,
 		Messages:    s.coordMsgs,
-		MaxTokens:   4000,
-		Temperature: 0.5,
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
 	})
+	report.chat(s.coordinator, coordModel, "coordinator", s.coordMsgs, responseContent(synthResp), time.Since(synthStart), err)
 	if err != nil {
 		fmt.Printf("❌ Synthesis failed: %v\n", err)
+		runErr = err
 		return
 	}
 
@@ -733,8 +799,9 @@ func (s *FabricSession) parseAssignments(plan, originalTask string) []WorkerAssi
 	return assignments
 }
 
-// executeWorkersParallel runs all workers in parallel
-func (s *FabricSession) executeWorkersParallel(ctx context.Context, assignments []WorkerAssignment) map[string]string {
+// executeWorkersParallel runs all workers in parallel, recording their calls
+// in the run report
+func (s *FabricSession) executeWorkersParallel(ctx context.Context, assignments []WorkerAssignment, report *runReport) map[string]string {
 	outputs := make(map[string]string)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -757,16 +824,19 @@ func (s *FabricSession) executeWorkersParallel(ctx context.Context, assignments
 				mu.Lock()
 				outputs[w.Name] = fmt.Sprintf("ERROR: %v", err)
 				mu.Unlock()
+				report.chat(w.Provider, "", w.Name, nil, "", time.Since(start), err)
 				return
 			}
 
 			prompt := fmt.Sprintf(`You are a %s.
 
+%s
+
 YOUR TASK:
 %s
 
 Provide a detailed, actionable response from your specialized perspective.
-Include specific recommendations and examples where relevant.`, w.Role, subtask)
+Include specific recommendations and examples where relevant.`, w.Role, s.roles.Lookup(w.Role).Expertise, subtask)
 
 			// Get or create worker message history
 			mu.Lock()
@@ -774,12 +844,16 @@ Include specific recommendations and examples where relevant.`, w.Role, subtask)
 			msgs = append(msgs, ai.Message{Role: "user", Content: prompt})
 			mu.Unlock()
 
+			model := s.cfg.GetModel(w.Provider)
+			params := s.modelParams(w.Provider, types.ModelParams{MaxTokens: 4000, Temperature: 0.7})
 			resp, err := provider.Chat(ctx, ai.ChatRequest{
-				Model:       s.cfg.GetModel(w.Provider),
+				Model:       model,
 				Messages:    msgs,
-				MaxTokens:   4000,
-				Temperature: 0.7,
+				MaxTokens:   params.MaxTokens,
+				Temperature: params.Temperature,
+				TopP:        params.TopP,
 			})
+			report.chat(w.Provider, model, w.Name, msgs, responseContent(resp), time.Since(start), err)
 
 			mu.Lock()
 			if err != nil {
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// FabricSessionState is a named fabric session as saved on disk, so that
+// `zen-claw fabric --resume <name>` can pick it up after a restart
+type FabricSessionState struct {
+	Name        string                  `json:"name"`
+	Coordinator string                  `json:"coordinator"`
+	Workers     []SpecializedWorker     `json:"workers"`
+	History     []FabricExchange        `json:"history"`
+	WorkerMsgs  map[string][]ai.Message `json:"worker_messages"`
+	CoordMsgs   []ai.Message            `json:"coordinator_messages"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+// sessionsDir is where named sessions live, next to the profiles
+func (s *FabricSession) sessionsDir() string {
+	return filepath.Join(s.profilesDir, "sessions")
+}
+
+// sessionNamePattern is what a session name may contain: it becomes a file
+// name in sessionsDir
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// sessionPath returns the file of a named session, refusing names that
+// could point outside sessionsDir
+func (s *FabricSession) sessionPath(name string) (string, error) {
+	if !sessionNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(s.sessionsDir(), name+".json"), nil
+}
+
+// saveSession writes the session state if the session is named. The file is
+// replaced atomically so an interrupted write never loses the previous state.
+func (s *FabricSession) saveSession() error {
+	if s.sessionName == "" {
+		return nil
+	}
+	state := FabricSessionState{
+		Name:        s.sessionName,
+		Coordinator: s.coordinator,
+		Workers:     s.workers,
+		History:     s.history,
+		WorkerMsgs:  s.workerMsgs,
+		CoordMsgs:   s.coordMsgs,
+		UpdatedAt:   time.Now(),
+	}
+	path, err := s.sessionPath(s.sessionName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.sessionsDir(), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadSession restores a named session: team, coordinator and worker
+// conversations, and task history
+func (s *FabricSession) loadSession(name string) error {
+	path, err := s.sessionPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var state FabricSessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	s.sessionName = name
+	s.coordinator = state.Coordinator
+	s.workers = state.Workers
+	s.history = state.History
+	s.workerMsgs = state.WorkerMsgs
+	if s.workerMsgs == nil {
+		s.workerMsgs = make(map[string][]ai.Message)
+	}
+	s.coordMsgs = state.CoordMsgs
+	return nil
+}
+
+// persistSession saves a named session after a change, warning on failure
+func (s *FabricSession) persistSession() {
+	if err := s.saveSession(); err != nil {
+		fmt.Printf("⚠️ Could not save session '%s': %v\n", s.sessionName, err)
+	}
+}
+
+// handleSession manages named sessions
+func (s *FabricSession) handleSession(input string) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		if s.sessionName == "" {
+			fmt.Println("Session not saved. Name it with: /session save <name>")
+		} else {
+			path, _ := s.sessionPath(s.sessionName)
+			fmt.Printf("Session: %s (%s)\n", s.sessionName, path)
+		}
+		fmt.Println("Usage:")
+		fmt.Println("  /session save <name>")
+		fmt.Println("  /session list")
+		fmt.Println("  /session delete <name>")
+		return
+	}
+
+	switch parts[1] {
+	case "save":
+		if len(parts) < 3 {
+			fmt.Println("Usage: /session save <name>")
+			return
+		}
+		if _, err := s.sessionPath(parts[2]); err != nil {
+			fmt.Printf("❌ Failed to save: %v\n", err)
+			return
+		}
+		s.sessionName = parts[2]
+		if err := s.saveSession(); err != nil {
+			fmt.Printf("❌ Failed to save: %v\n", err)
+			return
+		}
+		fmt.Printf("✓ Session saved: %s (resume with: zen-claw fabric --resume %s)\n", s.sessionName, s.sessionName)
+
+	case "list":
+		s.listSessions()
+
+	case "delete":
+		if len(parts) < 3 {
+			fmt.Println("Usage: /session delete <name>")
+			return
+		}
+		name := parts[2]
+		path, err := s.sessionPath(name)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to delete: %v\n", err)
+			return
+		}
+		if name == s.sessionName {
+			s.sessionName = ""
+		}
+		fmt.Printf("✓ Session deleted: %s\n", name)
+
+	default:
+		fmt.Println("Unknown action. Use: save, list, delete")
+	}
+}
+
+// listSessions shows saved sessions, most recent first
+func (s *FabricSession) listSessions() {
+	entries, err := os.ReadDir(s.sessionsDir())
+	if err != nil {
+		fmt.Println("No sessions found.")
+		return
+	}
+
+	var states []FabricSessionState
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.sessionsDir(), e.Name()))
+		if err != nil {
+			continue
+		}
+		var state FabricSessionState
+		if json.Unmarshal(data, &state) == nil {
+			state.Name = strings.TrimSuffix(e.Name(), ".json")
+			states = append(states, state)
+		}
+	}
+	if len(states) == 0 {
+		fmt.Println("No sessions found.")
+		return
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].UpdatedAt.After(states[j].UpdatedAt) })
+
+	fmt.Println("\n💾 Saved Sessions:")
+	for _, st := range states {
+		fmt.Printf("   • %s: coord=%s, %d workers, %d tasks, updated %s\n",
+			st.Name, st.Coordinator, len(st.Workers), len(st.History), st.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+func TestFabricSessionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s := &FabricSession{
+		profilesDir: dir,
+		coordinator: "deepseek",
+		workers:     []SpecializedWorker{{Name: "go_expert", Provider: "qwen", Role: "go_developer"}},
+		history: []FabricExchange{{
+			Task:          "add retries",
+			WorkerOutputs: map[string]string{"go_expert": "done"},
+			CoordReview:   "looks good",
+			Timestamp:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+		workerMsgs: map[string][]ai.Message{"go_expert": {{Role: "user", Content: "add retries"}, {Role: "assistant", Content: "done"}}},
+		coordMsgs:  []ai.Message{{Role: "system", Content: "You coordinate."}, {Role: "user", Content: "review"}},
+	}
+	s.handleSession("/session save team-1.a_b")
+	if s.sessionName != "team-1.a_b" {
+		t.Fatalf("sessionName = %q after save", s.sessionName)
+	}
+
+	resumed := &FabricSession{profilesDir: dir}
+	if err := resumed.loadSession("team-1.a_b"); err != nil {
+		t.Fatalf("loadSession() error = %v", err)
+	}
+	if resumed.coordinator != s.coordinator || !reflect.DeepEqual(resumed.workers, s.workers) || !reflect.DeepEqual(resumed.history, s.history) {
+		t.Errorf("resumed team/history = %s %+v %+v", resumed.coordinator, resumed.workers, resumed.history)
+	}
+	if !reflect.DeepEqual(resumed.workerMsgs, s.workerMsgs) {
+		t.Errorf("worker messages = %+v, want %+v", resumed.workerMsgs, s.workerMsgs)
+	}
+	if !reflect.DeepEqual(resumed.coordMsgs, s.coordMsgs) {
+		t.Errorf("coordinator messages = %+v, want %+v", resumed.coordMsgs, s.coordMsgs)
+	}
+
+	// Names never leave the sessions directory
+	for _, name := range []string{"../escape", "a/b", "..", "x..y", "", `a\b`} {
+		if _, err := s.sessionPath(name); err == nil {
+			t.Errorf("sessionPath(%q) accepted", name)
+		}
+		if err := resumed.loadSession(name); err == nil || os.IsNotExist(err) {
+			t.Errorf("loadSession(%q) = %v, want an invalid name error", name, err)
+		}
+	}
+	s.handleSession("/session save ../escape")
+	if s.sessionName != "team-1.a_b" {
+		t.Errorf("sessionName = %q after an invalid save", s.sessionName)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.json")); err == nil {
+		t.Error("saved outside the sessions directory")
+	}
+}
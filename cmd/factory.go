@@ -10,6 +10,7 @@ import (
 
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/factory"
+	"github.com/neves/zen-claw/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -240,7 +241,10 @@ func runFactoryStart(blueprintFile, workingDir, maxDuration string, maxCost floa
 	fmt.Println("🚀 Starting factory execution...")
 	fmt.Println(strings.Repeat("─", 80))
 
-	if err := f.Run(ctx, *blueprint); err != nil {
+	report := newRunReport(types.RunFactory, factoryRunTask(*blueprint))
+	err = f.Run(ctx, *blueprint)
+	recordFactoryRun(report, f.GetState(), nil, err)
+	if err != nil {
 		fmt.Printf("\n❌ Factory stopped: %v\n", err)
 
 		state := f.GetState()
@@ -362,9 +366,18 @@ func runFactoryResume(project string) {
 		}
 	}()
 
+	// Phases already done were reported by the run that did them
+	done := make(map[string]bool)
+	for name := range state.PhaseResults {
+		done[name] = true
+	}
+
 	// Resume
 	ctx := context.Background()
-	if err := f.Resume(ctx); err != nil {
+	report := newRunReport(types.RunFactory, factoryRunTask(state.Blueprint))
+	err = f.Resume(ctx)
+	recordFactoryRun(report, f.GetState(), done, err)
+	if err != nil {
 		fmt.Printf("\n❌ Resume failed: %v\n", err)
 		os.Exit(1)
 	}
@@ -372,6 +385,46 @@ func runFactoryResume(project string) {
 	fmt.Println("\n✅ Factory completed successfully")
 }
 
+// factoryRunTask describes a factory run for the gateway's run history
+func factoryRunTask(bp factory.Blueprint) string {
+	if bp.Description == "" {
+		return bp.Project
+	}
+	return bp.Project + ": " + bp.Description
+}
+
+// recordFactoryRun reports the phases the run executed (skipping those in
+// done) to the gateway
+func recordFactoryRun(report *runReport, state *factory.FactoryState, done map[string]bool, err error) {
+	if state != nil {
+		for _, phase := range state.Blueprint.Phases {
+			name := phase.Name
+			result, ok := state.PhaseResults[name]
+			if !ok || done[name] {
+				continue
+			}
+			call := types.RunCall{
+				Provider:   result.Provider,
+				Model:      result.Model,
+				Role:       name,
+				DurationMs: result.Duration.Milliseconds(),
+				CostUSD:    result.EstimatedCost,
+			}
+			if result.Status == factory.StatusFailed {
+				call.Error = strings.Join(result.Errors, "; ")
+			}
+			report.add(call)
+		}
+		if state.Paused {
+			report.run.Status = types.RunPaused
+			if err == nil {
+				report.run.Error = state.PauseReason
+			}
+		}
+	}
+	report.finish(err)
+}
+
 // Example blueprint for quick start
 func printExampleBlueprint() {
 	example := factory.Blueprint{
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/flaky"
+	"github.com/spf13/cobra"
+)
+
+// FlakyFix is a fix the agent proposes for a flaky test
+type FlakyFix struct {
+	Test        string `json:"test"`
+	Cause       string `json:"cause"` // timing, ordering, shared-state, concurrency, external or unknown
+	Explanation string `json:"explanation"`
+	File        string `json:"file"`
+	Fix         string `json:"fix"`
+}
+
+// flakyFixSchema is the shape of the proposals
+var flakyFixSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"fixes": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"test":        map[string]interface{}{"type": "string", "description": "Package-qualified test name from the report"},
+					"cause":       map[string]interface{}{"type": "string", "enum": []string{"timing", "ordering", "shared-state", "concurrency", "external", "unknown"}},
+					"explanation": map[string]interface{}{"type": "string", "description": "Why the test is nondeterministic, pointing at the code"},
+					"file":        map[string]interface{}{"type": "string", "description": "File to change, as path:line"},
+					"fix":         map[string]interface{}{"type": "string", "description": "The proposed change, with a code snippet"},
+				},
+				"required": []string{"test", "cause", "explanation", "file", "fix"},
+			},
+		},
+	},
+	"required": []string{"fixes"},
+}
+
+func newFlakyCmd() *cobra.Command {
+	var workingDir, run, out, sessionID, provider, model string
+	var runs, parallel, maxSteps int
+	var timeout time.Duration
+	var race, shuffle, fix, asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "flaky [packages...]",
+		Short: "Rerun Go tests to find flaky ones and report them",
+		Long: `Run the Go test packages many times (--runs) in parallel git worktrees
+and compare the outcome of every test: tests that pass in some runs and fail
+in others are flaky, tests that fail in every run are reported apart. The
+worktrees hold HEAD plus your uncommitted changes, so runs don't share files
+the tests write. Outside a git repository the runs take turns in place.
+
+--shuffle runs the tests in a different order each time (the failing seeds
+are reported, to reproduce with -shuffle=<seed>); --race adds the race
+detector. With --fix the agent reads the flaky tests and proposes fixes
+(timeouts, ordering, shared state), without changing anything.`,
+		Example: `  zen-claw flaky ./internal/gateway/...
+  zen-claw flaky --runs 30 --shuffle --race ./...
+  zen-claw flaky --run 'TestSession' --fix --out flaky-report.md ./internal/gateway`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+				workingDir = abs
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			opts := flaky.Options{
+				Dir:      workingDir,
+				Packages: args,
+				Runs:     runs,
+				Parallel: parallel,
+				Run:      run,
+				Race:     race,
+				Shuffle:  shuffle,
+				Timeout:  timeout,
+			}
+			if !asJSON {
+				fmt.Printf("🔁 Running %s %d times\n", strings.Join(defaultPackages(args), " "), opts.Runs)
+				opts.Progress = func(r flaky.RunResult) {
+					switch {
+					case r.Error != "":
+						fmt.Printf("   run %d: ⚠️  %s\n", r.Index, firstLine(r.Error))
+					case r.Passed:
+						fmt.Printf("   run %d: ✓ (%.1fs)\n", r.Index, r.Seconds)
+					default:
+						fmt.Printf("   run %d: ✗ %s (%.1fs)\n", r.Index, strings.Join(r.Failed, ", "), r.Seconds)
+					}
+				}
+			}
+			report, err := flaky.Detect(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			markdown := report.Markdown()
+			var fixes []FlakyFix
+			if fix && len(report.Flaky) > 0 {
+				if fixes, err = proposeFlakyFixes(report, markdown, workingDir, sessionID, provider, model, maxSteps, asJSON); err != nil {
+					return err
+				}
+				markdown += formatFlakyFixes(fixes)
+			}
+
+			if out != "" {
+				if err := os.WriteFile(out, []byte(markdown), 0644); err != nil {
+					return err
+				}
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(struct {
+					*flaky.Report
+					Fixes []FlakyFix `json:"fixes,omitempty"`
+				}{report, fixes})
+			}
+			fmt.Println("\n" + strings.Repeat("═", 80))
+			fmt.Print(markdown)
+			fmt.Println(strings.Repeat("═", 80))
+			if out != "" {
+				fmt.Printf("✓ Wrote %s\n", out)
+			}
+			if len(report.Flaky) > 0 && !fix {
+				fmt.Println("Ask for fixes with --fix")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Go module to test")
+	cmd.Flags().IntVar(&runs, "runs", flaky.DefaultRuns, "Times the packages are run")
+	cmd.Flags().IntVar(&parallel, "parallel", 0, "Worktrees running at once (default: up to 4, one per CPU)")
+	cmd.Flags().StringVar(&run, "run", "", "Only run tests matching this regexp (go test -run)")
+	cmd.Flags().BoolVar(&race, "race", false, "Enable the race detector")
+	cmd.Flags().BoolVar(&shuffle, "shuffle", false, "Run the tests in a random order each run")
+	cmd.Flags().DurationVar(&timeout, "timeout", flaky.DefaultTimeout, "Timeout of one go test run")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Have the agent propose fixes for the flaky tests")
+	cmd.Flags().StringVar(&out, "out", "", "Write the report (markdown) to this file")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session for --fix (omit for fresh context)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider for --fix (default: the gateway's)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model for --fix (default: the provider's)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 30, "Maximum tool execution steps for --fix")
+	return cmd
+}
+
+// defaultPackages returns the packages go test runs for args
+func defaultPackages(args []string) []string {
+	if len(args) == 0 {
+		return []string{"./..."}
+	}
+	return args
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// proposeFlakyFixes asks the agent (read-only) why the flaky tests are
+// nondeterministic and how to fix them
+func proposeFlakyFixes(report *flaky.Report, markdown, workingDir, sessionID, provider, model string, maxSteps int, quiet bool) ([]FlakyFix, error) {
+	client := NewGatewayClient(getGatewayURL())
+	if err := ensureGateway(client); err != nil {
+		return nil, err
+	}
+	uiLang := configuredLanguage()
+	if !quiet {
+		fmt.Printf("\n🔍 Asking the agent about %d flaky tests\n", len(report.Flaky))
+	}
+	resp, err := client.SendWithProgress(ChatRequest{
+		SessionID:      sessionID,
+		UserInput:      flakyFixPrompt(markdown),
+		WorkingDir:     workingDir,
+		Provider:       provider,
+		Model:          model,
+		MaxSteps:       maxSteps,
+		ResponseSchema: flakyFixSchema,
+		ReadOnly:       true,
+		Tags:           []string{"flaky"},
+	}, func(event ProgressEvent) {
+		if !quiet {
+			displayProgressEvent(event, uiLang)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gateway request failed: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("fix proposals failed: %s", resp.Error)
+	}
+	var proposals struct {
+		Fixes []FlakyFix `json:"fixes"`
+	}
+	if err := json.Unmarshal(resp.Output, &proposals); err != nil {
+		fmt.Println(resp.Result)
+		return nil, fmt.Errorf("the agent returned no fix proposals")
+	}
+	return proposals.Fixes, nil
+}
+
+// flakyFixPrompt is the task of proposing fixes
+func flakyFixPrompt(report string) string {
+	return fmt.Sprintf(`These Go tests are flaky: they passed in some runs and failed in others with the same code.
+
+%s
+
+For each flaky test, find out why it is nondeterministic and propose a fix:
+1. Read the test and the code it exercises (find_symbol, read_file). Use the failure output above.
+2. Look for the usual causes: sleeps and timeouts that race with real work (timing), dependence on test or map order (ordering), globals, files, ports or env vars shared between tests (shared-state), unsynchronized goroutines (concurrency), network or clock access (external).
+3. Propose the smallest change that makes the test deterministic, e.g. wait on a channel instead of sleeping, t.TempDir() instead of a fixed path, t.Setenv, resetting globals with t.Cleanup. Fix the code rather than the test when the test caught a real race.
+
+Don't change any file: describe the fix with a snippet. If you can't tell the cause, say so with cause "unknown".
+`, report)
+}
+
+// formatFlakyFixes renders the proposals for the report
+func formatFlakyFixes(fixes []FlakyFix) string {
+	if len(fixes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## Proposed fixes\n")
+	for _, f := range fixes {
+		fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n\n`%s`\n\n%s\n", f.Test, f.Cause, f.Explanation, f.File, f.Fix)
+	}
+	return b.String()
+}
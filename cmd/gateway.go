@@ -1,8 +1,8 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"os/exec"
 	"strconv"
@@ -15,10 +15,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	pidFile = "/tmp/zen-claw-gateway.pid"
-)
-
 // getGatewayPort returns the configured gateway port
 func getGatewayPort() string {
 	cfg, err := config.LoadConfig("")
@@ -41,11 +37,21 @@ func newGatewayCmd() *cobra.Command {
 	// Add config flag to all subcommands
 	cmd.PersistentFlags().String("config", "", "Config file path (default: ~/.zen/zen-claw/config.yaml)")
 
-	cmd.AddCommand(&cobra.Command{
+	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start gateway server",
-		RunE:  runGatewayStart,
-	})
+		Long: `Start the gateway server.
+
+The gateway listens on gateway.host:gateway.port from config (default :8080),
+or only on a unix socket when gateway.socket is set - reachable by this user
+alone, nothing is exposed on the network. The address is recorded in
+~/.zen/zen-claw/run/gateway.json, where the CLI discovers it.`,
+		RunE: runGatewayStart,
+	}
+	startCmd.Flags().String("host", "", "Listen address (overrides gateway.host)")
+	startCmd.Flags().Int("port", 0, "Listen port (overrides gateway.port)")
+	startCmd.Flags().String("socket", "", "Listen on this unix socket instead of TCP (overrides gateway.socket)")
+	cmd.AddCommand(startCmd)
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "stop",
@@ -59,11 +65,13 @@ func newGatewayCmd() *cobra.Command {
 		RunE:  runGatewayRestart,
 	})
 
-	cmd.AddCommand(&cobra.Command{
+	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check gateway status",
 		RunE:  runGatewayStatus,
-	})
+	}
+	statusCmd.Flags().Bool("json", false, "Print the status as JSON")
+	cmd.AddCommand(statusCmd)
 
 	return cmd
 }
@@ -77,6 +85,15 @@ func runGatewayStart(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if cmd.Flags().Changed("host") {
+		cfg.Gateway.Host, _ = cmd.Flags().GetString("host")
+	}
+	if cmd.Flags().Changed("port") {
+		cfg.Gateway.Port, _ = cmd.Flags().GetInt("port")
+	}
+	if cmd.Flags().Changed("socket") {
+		cfg.Gateway.Socket, _ = cmd.Flags().GetString("socket")
+	}
 
 	// Create gateway server
 	server := gateway.NewServer(cfg)
@@ -93,21 +110,21 @@ func runGatewayStart(cmd *cobra.Command, args []string) error {
 func runGatewayStop(cmd *cobra.Command, args []string) error {
 	fmt.Println("Stopping Zen Claw gateway...")
 
-	// Method 1: Try PID file
-	if pidData, err := os.ReadFile(pidFile); err == nil {
-		pidStr := strings.TrimSpace(string(pidData))
-		if pid, err := strconv.Atoi(pidStr); err == nil {
-			if process, err := os.FindProcess(pid); err == nil {
-				if err := process.Signal(syscall.SIGTERM); err == nil {
-					fmt.Printf("Sent SIGTERM to PID %d\n", pid)
-					os.Remove(pidFile)
-					// Wait a moment for graceful shutdown
-					time.Sleep(500 * time.Millisecond)
-					return nil
+	// Method 1: The recorded gateway (verified to be a live zen-claw process)
+	if state, err := gateway.ReadRunState(gateway.DefaultRunDir()); err == nil && state != nil {
+		if process, err := os.FindProcess(state.PID); err == nil {
+			if err := process.Signal(syscall.SIGTERM); err == nil {
+				fmt.Printf("Sent SIGTERM to PID %d\n", state.PID)
+				// Wait for the graceful shutdown to finish in-flight requests
+				for i := 0; i < 100; i++ {
+					if running, _ := gateway.ReadRunState(gateway.DefaultRunDir()); running == nil {
+						break
+					}
+					time.Sleep(100 * time.Millisecond)
 				}
+				return nil
 			}
 		}
-		os.Remove(pidFile) // Remove stale PID file
 	}
 
 	// Method 2: Find process by port using lsof
@@ -127,30 +144,28 @@ func runGatewayStop(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Method 3: Check if port is actually in use
-	conn, err := net.DialTimeout("tcp", "localhost:"+getGatewayPort(), time.Second)
-	if err != nil {
+	// Method 3: Check if the address is actually in use
+	gatewayURL := getGatewayURL()
+	if !dialGateway(gatewayURL, time.Second) {
 		fmt.Println("Gateway is not running")
 		return nil
 	}
-	conn.Close()
 
-	return fmt.Errorf("could not stop gateway - process found on port %s but unable to kill", getGatewayPort())
+	return fmt.Errorf("could not stop gateway - something answers at %s but unable to kill it", gatewayURL)
 }
 
 func runGatewayRestart(cmd *cobra.Command, args []string) error {
 	fmt.Println("Restarting Zen Claw gateway...")
 
 	// Stop first (ignore errors - might not be running)
+	gatewayURL := getGatewayURL()
 	_ = runGatewayStop(cmd, args)
 
-	// Wait for port to be free
+	// Wait for the address to be free
 	for i := 0; i < 10; i++ {
-		conn, err := net.DialTimeout("tcp", "localhost:"+getGatewayPort(), 100*time.Millisecond)
-		if err != nil {
-			break // Port is free
+		if !dialGateway(gatewayURL, 100*time.Millisecond) {
+			break
 		}
-		conn.Close()
 		time.Sleep(200 * time.Millisecond)
 	}
 
@@ -158,29 +173,68 @@ func runGatewayRestart(cmd *cobra.Command, args []string) error {
 	return runGatewayStart(cmd, args)
 }
 
+// gatewayStatus is the output of gateway status --json
+type gatewayStatus struct {
+	Status        string     `json:"status"` // running, stopped, or unmanaged (port answers, no gateway recorded)
+	PID           int        `json:"pid,omitempty"`
+	Addr          string     `json:"addr,omitempty"`
+	URL           string     `json:"url,omitempty"` // Where clients connect
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	UptimeSeconds int64      `json:"uptime_seconds,omitempty"`
+	Healthy       bool       `json:"healthy"`
+}
+
 func runGatewayStatus(cmd *cobra.Command, args []string) error {
-	// Check if port is in use
-	conn, err := net.DialTimeout("tcp", "localhost:"+getGatewayPort(), time.Second)
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	// The recorded gateway; stale state from a crash is cleaned up here
+	state, err := gateway.ReadRunState(gateway.DefaultRunDir())
 	if err != nil {
-		fmt.Println("Gateway status: stopped")
-		return nil
+		return fmt.Errorf("failed to read gateway state: %w", err)
+	}
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		cfg = config.NewDefaultConfig()
+	}
+	status := gatewayStatus{Status: "stopped", Addr: cfg.Gateway.GetAddr(), URL: cfg.Gateway.GetURL()}
+	if state != nil {
+		status.Status, status.PID, status.Addr = "running", state.PID, state.Addr
+		if state.URL != "" {
+			status.URL = state.URL
+		}
+		status.StartedAt = &state.StartedAt
+		status.UptimeSeconds = int64(time.Since(state.StartedAt).Seconds())
 	}
-	conn.Close()
 
-	fmt.Println("Gateway status: running")
-	fmt.Printf("Listening on: :%s\n", getGatewayPort())
+	if dialGateway(status.URL, time.Second) {
+		if state == nil {
+			status.Status = "unmanaged"
+		}
+		status.Healthy = NewGatewayClient(status.URL).HealthCheck() == nil
+	}
 
-	// Check PID file
-	if pidData, err := os.ReadFile(pidFile); err == nil {
-		pidStr := strings.TrimSpace(string(pidData))
-		fmt.Printf("PID: %s\n", pidStr)
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
 	}
 
-	// Try to get health info
-	client := NewGatewayClient("http://localhost:" + getGatewayPort())
-	if err := client.HealthCheck(); err == nil {
+	switch status.Status {
+	case "stopped":
+		fmt.Println("Gateway status: stopped")
+		return nil
+	case "unmanaged":
+		fmt.Println("Gateway status: running (not started by this user's zen-claw, or an older version)")
+	default:
+		fmt.Println("Gateway status: running")
+		fmt.Printf("PID: %d\n", status.PID)
+		fmt.Printf("Uptime: %s\n", time.Duration(status.UptimeSeconds)*time.Second)
+	}
+	fmt.Printf("Listening on: %s\n", status.URL)
+	if status.Healthy {
 		fmt.Println("Health: OK")
+	} else {
+		fmt.Println("Health: not responding")
 	}
-
 	return nil
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/gateway"
+)
+
+// autoStartGateway is set by --auto-start: start the gateway without asking
+// when it isn't running
+var autoStartGateway bool
+
+// gatewayStartTimeout is how long to wait for a started gateway's /health
+const gatewayStartTimeout = 15 * time.Second
+
+// ensureGateway checks that the gateway answers. When it doesn't, it offers
+// to start one in the background (or does so silently with --auto-start),
+// waits until it is healthy, and points client at it.
+func ensureGateway(client *GatewayClient) error {
+	err := client.HealthCheck()
+	if err == nil {
+		return nil
+	}
+	if !autoStartGateway {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("%w (start it with: zen-claw gateway start, or pass --auto-start)", err)
+		}
+		fmt.Printf("Gateway not available: %v\n", err)
+		fmt.Print("Start it in the background now? [Y/n] ")
+		if answer := strings.ToLower(readLine()); answer != "" && answer != "y" && answer != "yes" {
+			return fmt.Errorf("%w (start it with: zen-claw gateway start)", err)
+		}
+	}
+
+	logPath, err := startGatewayDaemon()
+	if err != nil {
+		return fmt.Errorf("failed to start gateway: %w", err)
+	}
+
+	// The new gateway records its address once it's up; follow it there
+	deadline := time.Now().Add(gatewayStartTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		started := NewGatewayClient(getGatewayURL())
+		if err = started.HealthCheck(); err == nil {
+			client.baseURL, client.client.Transport = started.baseURL, started.client.Transport
+			if !autoStartGateway {
+				fmt.Printf("✓ Gateway started (log: %s)\n", logPath)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("gateway did not become healthy within %s: %v (see %s)", gatewayStartTimeout, err, logPath)
+}
+
+// startGatewayDaemon runs "zen-claw gateway start" detached from this
+// terminal, logging to the run directory
+func startGatewayDaemon() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	dir := gateway.DefaultRunDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	logPath := filepath.Join(dir, "gateway.log")
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer logFile.Close()
+
+	daemon := exec.Command(exe, "gateway", "start")
+	daemon.Stdout, daemon.Stderr = logFile, logFile
+	detach(daemon)
+	if err := daemon.Start(); err != nil {
+		return "", err
+	}
+	return logPath, daemon.Process.Release()
+}
+
+// stdinIsTerminal reports whether a user can answer a prompt
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// readLine reads one line from stdin without buffering past it, so input
+// meant for a later reader isn't consumed
+func readLine() string {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil || buf[0] == '\n' {
+			return strings.TrimSpace(string(line))
+		}
+		line = append(line, buf[0])
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach puts the daemon in its own session, so Ctrl-C in this terminal
+// doesn't reach the gateway
+func detach(daemon *exec.Cmd) {
+	daemon.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package cmd
+
+import "os/exec"
+
+// detach is a no-op: console signals don't reach a process without a console
+func detach(daemon *exec.Cmd) {}
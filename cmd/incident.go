@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// IncidentReport is the structured result of an incident investigation
+type IncidentReport struct {
+	Summary       string             `json:"summary"`
+	ProbableCause string             `json:"probable_cause"`
+	Confidence    float64            `json:"confidence"`
+	Evidence      []IncidentEvidence `json:"evidence"`
+	Remediation   []IncidentStep     `json:"remediation"`
+	Runbooks      []string           `json:"runbooks"`
+	NextChecks    []string           `json:"next_checks"`
+}
+
+// IncidentEvidence is one observation backing the probable cause
+type IncidentEvidence struct {
+	Source string `json:"source"` // Where it was seen: pod logs, events, describe, runbook
+	Detail string `json:"detail"`
+}
+
+// IncidentStep is a suggested remediation step; commands are for a human to run
+type IncidentStep struct {
+	Step    string `json:"step"`
+	Command string `json:"command"`
+	Risk    string `json:"risk"` // low, medium or high
+}
+
+// incidentSchema is the shape of the report
+var incidentSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{
+			"type":        "string",
+			"description": "What is happening, in two or three sentences",
+		},
+		"probable_cause": map[string]interface{}{"type": "string"},
+		"confidence":     map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+		"evidence": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"source": map[string]interface{}{"type": "string", "description": "Where it was seen, e.g. logs of pod api-7f9c, events of namespace prod, a runbook"},
+					"detail": map[string]interface{}{"type": "string", "description": "The observation, quoting the log or event line"},
+				},
+				"required": []string{"source", "detail"},
+			},
+		},
+		"remediation": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"step":    map[string]interface{}{"type": "string"},
+					"command": map[string]interface{}{"type": "string", "description": "Command for an operator to run, empty if none"},
+					"risk":    map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high"}},
+				},
+				"required": []string{"step", "command", "risk"},
+			},
+		},
+		"runbooks": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Runbook sections used, as file#heading",
+		},
+		"next_checks": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "What to look at next if the cause is not confirmed",
+		},
+	},
+	"required": []string{"summary", "probable_cause", "confidence", "evidence", "remediation", "runbooks", "next_checks"},
+}
+
+func newIncidentCmd() *cobra.Command {
+	var namespace, kubeContext, sessionID, provider, model string
+	var maxSteps int
+	var allowChanges, asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "incident <alert>",
+		Short: "Investigate an alert and write an incident report",
+		Long: `Investigate an alert the way an on-call engineer would: the agent reads
+pod logs, events and resource usage with the k8s tool, searches the runbooks
+(incident.runbooks in the config) and answers with a structured report:
+probable cause, the evidence for it and suggested remediation steps.
+
+The investigation is read-only: the agent gets the inspection tools only
+(k8s reads, runbooks, files, search), so it can't run commands or change
+the cluster. Remediation commands are suggestions for you to run.
+--allow-changes gives the agent its full toolset.
+
+kubectl runs on the gateway host with its kubeconfig (or the KUBECONFIG of
+the session env).`,
+		Example: `  zen-claw incident "KubePodCrashLooping: api-7f9c in prod restarted 12 times"
+  zen-claw incident --namespace payments --context prod-eu "p99 latency > 2s on checkout"
+  zen-claw incident --json "$(cat alert.txt)" > report.json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			alert := strings.Join(args, " ")
+			cfg := loadConfigForSessions()
+			if namespace == "" {
+				namespace = cfg.Incident.Namespace
+			}
+			if kubeContext == "" {
+				kubeContext = cfg.Incident.Context
+			}
+
+			client := NewGatewayClient(getGatewayURL())
+			if err := ensureGateway(client); err != nil {
+				return err
+			}
+
+			uiLang := configuredLanguage()
+			onProgress := func(event ProgressEvent) {
+				if !asJSON {
+					displayProgressEvent(event, uiLang)
+				}
+			}
+			if !asJSON {
+				fmt.Printf("🚨 Investigating: %s\n", alert)
+				if !allowChanges {
+					fmt.Println("🔒 Read-only investigation")
+				}
+			}
+			resp, err := client.SendWithProgress(ChatRequest{
+				SessionID:      sessionID,
+				UserInput:      incidentPrompt(alert, namespace, kubeContext),
+				Provider:       provider,
+				Model:          model,
+				MaxSteps:       maxSteps,
+				ResponseSchema: incidentSchema,
+				ReadOnly:       !allowChanges,
+				Tags:           []string{"incident"},
+			}, onProgress)
+			if err != nil {
+				return fmt.Errorf("gateway request failed: %w", err)
+			}
+			if resp.Error != "" {
+				return fmt.Errorf("investigation failed: %s", resp.Error)
+			}
+
+			var report IncidentReport
+			if err := json.Unmarshal(resp.Output, &report); err != nil {
+				// No structured report (e.g. stopped at the step limit): show the text
+				fmt.Println(resp.Result)
+				return fmt.Errorf("the agent returned no incident report")
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			fmt.Println("\n" + strings.Repeat("═", 80))
+			fmt.Print(formatIncidentReport(&report))
+			fmt.Println(strings.Repeat("═", 80))
+			if resp.SessionID != "" {
+				fmt.Printf("Follow up with: zen-claw agent --session %s \"...\"\n", resp.SessionID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace to start from (default: incident.namespace, else the context's)")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "kubeconfig context (default: incident.context, else the current one)")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session to save/resume (omit for fresh context)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (default: the gateway's)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (default: the provider's)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 40, "Maximum tool execution steps")
+	cmd.Flags().BoolVar(&allowChanges, "allow-changes", false, "Give the agent its full toolset instead of the read-only one")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	return cmd
+}
+
+// incidentPrompt is the task of an investigation
+func incidentPrompt(alert, namespace, kubeContext string) string {
+	var b strings.Builder
+	b.WriteString("Investigate this production alert and write an incident report.\n\n")
+	fmt.Fprintf(&b, "Alert:\n%s\n\n", alert)
+	if namespace != "" {
+		fmt.Fprintf(&b, "Start in namespace %q.\n", namespace)
+	}
+	if kubeContext != "" {
+		fmt.Fprintf(&b, "Pass context %q to every k8s call.\n", kubeContext)
+	}
+	b.WriteString(`
+How to investigate:
+1. Find the affected workload with k8s get (use a selector or the names in the alert).
+2. Read the warning events (k8s events with field_selector type=Warning) and describe the failing objects.
+3. Read the logs of failing pods; for restarting containers read the previous instance's logs too.
+4. Check resource usage (k8s top) when the alert hints at CPU, memory or latency.
+5. Search the runbooks (runbook_search) for the alert name, the component and the errors you found; follow what they say to check.
+
+Only state what you observed: quote the log or event lines you rely on as evidence. If the evidence doesn't settle the cause, say so with a low confidence and list the next checks. Remediation steps are for an operator: give the exact commands, mark their risk, and prefer the runbook's procedure when one applies. Don't change anything yourself.
+`)
+	return b.String()
+}
+
+// formatIncidentReport renders a report for the terminal
+func formatIncidentReport(r *IncidentReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🚨 INCIDENT REPORT\n\n%s\n\n", r.Summary)
+	fmt.Fprintf(&b, "Probable cause (confidence %.0f%%):\n   %s\n", r.Confidence*100, r.ProbableCause)
+	if len(r.Evidence) > 0 {
+		b.WriteString("\nEvidence:\n")
+		for _, e := range r.Evidence {
+			fmt.Fprintf(&b, "   • [%s] %s\n", e.Source, e.Detail)
+		}
+	}
+	if len(r.Remediation) > 0 {
+		b.WriteString("\nSuggested remediation:\n")
+		for i, s := range r.Remediation {
+			fmt.Fprintf(&b, "   %d. %s (risk: %s)\n", i+1, s.Step, s.Risk)
+			if s.Command != "" {
+				fmt.Fprintf(&b, "      $ %s\n", s.Command)
+			}
+		}
+	}
+	if len(r.Runbooks) > 0 {
+		fmt.Fprintf(&b, "\nRunbooks: %s\n", strings.Join(r.Runbooks, ", "))
+	}
+	if len(r.NextChecks) > 0 {
+		b.WriteString("\nNext checks:\n")
+		for _, c := range r.NextChecks {
+			fmt.Fprintf(&b, "   • %s\n", c)
+		}
+	}
+	return b.String()
+}
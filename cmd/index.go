@@ -38,6 +38,7 @@ Examples:
 
 func newIndexBuildCmd() *cobra.Command {
 	var patterns, excludes []string
+	var includeIgnored bool
 
 	cmd := &cobra.Command{
 		Use:   "build [path]",
@@ -67,6 +68,8 @@ func newIndexBuildCmd() *cobra.Command {
 				RootDir:  absDir,
 				Patterns: patterns,
 				Excludes: excludes,
+
+				IncludeIgnored: includeIgnored,
 			})
 			if err != nil {
 				fmt.Printf("Error creating indexer: %v\n", err)
@@ -95,6 +98,7 @@ func newIndexBuildCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&patterns, "pattern", "p", nil, "File patterns to include (e.g., *.go)")
 	cmd.Flags().StringSliceVarP(&excludes, "exclude", "e", nil, "Patterns to exclude (e.g., vendor/*)")
+	cmd.Flags().BoolVar(&includeIgnored, "include-ignored", false, "Also index files .gitignore and .zenclawignore exclude")
 
 	return cmd
 }
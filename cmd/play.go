@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/i18n"
+	"github.com/neves/zen-claw/internal/macros"
+	"github.com/neves/zen-claw/internal/prompts"
+	"github.com/spf13/cobra"
+)
+
+func newPlayCmd() *cobra.Command {
+	var vars []string
+	var model, provider, workingDir, sessionID string
+	var maxSteps int
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "play [name]",
+		Short: "Replay a macro recorded with /record",
+		Long: `Replay a macro: the user turns recorded in interactive mode between
+/record start <name> and /record stop, sent one after another in one session.
+
+Paths in the recorded turns are variables defaulting to the recorded paths;
+--var changes them. Without a name the recorded macros are listed.
+
+Macro directory: ~/.zen/zen-claw/macros/
+
+Examples:
+  zen-claw play                                   # List macros
+  zen-claw play release-prep --dry-run            # Show the steps and variables
+  zen-claw play release-prep --working-dir ~/git/web --var changelog_md=CHANGELOG.md`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				listMacros()
+				return
+			}
+			m, err := macros.Get(macros.DefaultDir(), args[0])
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			values, err := prompts.ParseVars(vars)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			steps, err := m.Render(values)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			if dryRun {
+				for _, v := range m.Vars {
+					value := values[v.Name]
+					if _, ok := values[v.Name]; !ok && v.Default != nil {
+						value = *v.Default
+					}
+					fmt.Printf("  %-20s %s\n", v.Name, value)
+				}
+				for i, step := range steps {
+					fmt.Printf("\n%d. %s\n", i+1, step)
+				}
+				return
+			}
+			if provider == "" && model == "" {
+				provider, model = m.Provider, m.Model
+			}
+			if err := playMacro(m.Name, steps, provider, model, workingDir, sessionID, maxSteps); err != nil {
+				fmt.Printf("\n❌ %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Variable value name=value (repeatable)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (default: the one the macro was recorded with)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (default: the one the macro was recorded with)")
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Working directory for tools")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session the steps run in (default: a fresh one)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 100, "Maximum tool execution steps per turn")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the variables and steps instead of running them")
+
+	return cmd
+}
+
+// listMacros prints the recorded macros
+func listMacros() {
+	names := macros.List(macros.DefaultDir())
+	fmt.Printf("Macros (%d):\n\n", len(names))
+	for _, name := range names {
+		m, err := macros.Get(macros.DefaultDir(), name)
+		if err != nil {
+			fmt.Printf("  %-24s (%v)\n", name, err)
+			continue
+		}
+		vars := make([]string, len(m.Vars))
+		for i, v := range m.Vars {
+			vars[i] = v.Name
+		}
+		fmt.Printf("  %-24s %d steps, recorded %s\n", m.Name, len(m.Steps), m.Recorded.Local().Format("2006-01-02"))
+		if len(vars) > 0 {
+			fmt.Printf("  %-24s vars: %s\n", "", strings.Join(vars, ", "))
+		}
+	}
+	fmt.Printf("\nMacro directory: %s\n", macros.DefaultDir())
+	fmt.Println("Record one in interactive mode with: /record start <name>")
+}
+
+// playMacro sends the steps of a macro in order, in one session, and stops
+// at the first that fails
+func playMacro(name string, steps []string, provider, model, workingDir, sessionID string, maxSteps int) error {
+	if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+		workingDir = abs
+	}
+	client := NewGatewayClient(getGatewayURL())
+	if err := ensureGateway(client); err != nil {
+		return fmt.Errorf("gateway not available: %w", err)
+	}
+	uiLang := configuredLanguage()
+
+	fmt.Printf("▶ Playing %s (%d steps) in %s\n", name, len(steps), workingDir)
+	for i, step := range steps {
+		fmt.Println("\n" + strings.Repeat("═", 80))
+		fmt.Printf("Step %d/%d: %s\n", i+1, len(steps), step)
+		fmt.Println(strings.Repeat("═", 80))
+		resp, err := client.SendWithProgress(ChatRequest{
+			SessionID:  sessionID,
+			UserInput:  step,
+			WorkingDir: workingDir,
+			Provider:   provider,
+			Model:      model,
+			MaxSteps:   maxSteps,
+			Tags:       []string{"macro"},
+		}, func(event ProgressEvent) {
+			displayProgressEvent(event, uiLang)
+		})
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("step %d: %s", i+1, resp.Error)
+		}
+		fmt.Println("\n" + i18n.T(uiLang, "result"))
+		fmt.Println(resp.Result)
+		sessionID = resp.SessionID
+		if resp.StepLimit != nil {
+			return fmt.Errorf("step %d stopped at the step limit (%d); continue with: zen-claw agent --session %s", i+1, resp.StepLimit.Steps, sessionID)
+		}
+	}
+	fmt.Printf("\n✓ Played %s (%d steps, session %s)\n", name, len(steps), sessionID)
+	return nil
+}
+
+// handleRecordCommand handles /record in interactive mode and returns the
+// recorder to use from now on (nil when not recording):
+//
+//	/record start <name>   start recording the turns sent
+//	/record stop [name]    save the recording (optionally under another name)
+//	/record cancel         drop it
+//	/record                show the recording
+func handleRecordCommand(input string, rec *macros.Recorder, workingDir, provider, model string) *macros.Recorder {
+	fields := strings.Fields(strings.TrimPrefix(input, "/record"))
+	action := ""
+	if len(fields) > 0 {
+		action = fields[0]
+	}
+	switch action {
+	case "":
+		if rec == nil {
+			fmt.Println("Not recording. Start with: /record start <name>")
+		} else {
+			fmt.Printf("⏺ Recording %s: %d turns so far (/record stop saves it)\n", rec.Name(), rec.Steps())
+		}
+		return rec
+	case "start":
+		if rec != nil {
+			fmt.Printf("Already recording %s; /record stop or /record cancel first\n", rec.Name())
+			return rec
+		}
+		if len(fields) < 2 {
+			fmt.Println("Usage: /record start <name>")
+			return nil
+		}
+		started, err := macros.NewRecorder(fields[1], workingDir, provider, model)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return nil
+		}
+		fmt.Printf("⏺ Recording %s. Your messages are recorded until /record stop.\n", started.Name())
+		return started
+	case "stop":
+		if rec == nil {
+			fmt.Println("Not recording")
+			return nil
+		}
+		m := rec.Macro()
+		if len(fields) > 1 {
+			m.Name = fields[1]
+		}
+		if len(m.Steps) == 0 {
+			fmt.Printf("Nothing recorded; %s not saved\n", m.Name)
+			return nil
+		}
+		path, err := macros.Save(macros.DefaultDir(), m, true)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return rec
+		}
+		fmt.Printf("✓ Saved macro %s (%d steps): %s\n", macros.Normalize(m.Name), len(m.Steps), path)
+		if len(m.Vars) > 0 {
+			names := make([]string, len(m.Vars))
+			for i, v := range m.Vars {
+				names[i] = v.Name
+			}
+			fmt.Printf("  Path variables: %s\n", strings.Join(names, ", "))
+		}
+		fmt.Printf("  Replay with: zen-claw play %s\n", macros.Normalize(m.Name))
+		return nil
+	case "cancel":
+		if rec != nil {
+			fmt.Printf("Dropped the recording of %s\n", rec.Name())
+		}
+		return nil
+	}
+	fmt.Println("Usage: /record start <name> | stop [name] | cancel")
+	return rec
+}
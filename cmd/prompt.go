@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/prompts"
+	"github.com/spf13/cobra"
+)
+
+func newPromptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Manage the prompt library",
+		Long: `Manage saved, parameterized prompts.
+
+A prompt is a markdown file: optional front matter with a description and
+declared variables, then a Go template over them ({{.table}}). Run one as a
+task with 'zen-claw prompt run', or send it in interactive mode with
+/prompt <name> key=value.
+
+Prompt directory: ~/.zen/zen-claw/prompts/`,
+	}
+
+	cmd.AddCommand(newPromptListCmd())
+	cmd.AddCommand(newPromptShowCmd())
+	cmd.AddCommand(newPromptSaveCmd())
+	cmd.AddCommand(newPromptRunCmd())
+	cmd.AddCommand(newPromptDeleteCmd())
+
+	return cmd
+}
+
+func newPromptListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List saved prompts",
+		Run: func(cmd *cobra.Command, args []string) {
+			list := prompts.Load(prompts.DefaultDir())
+			fmt.Printf("Prompts (%d):\n\n", len(list))
+			for _, p := range list {
+				fmt.Printf("  %-24s %s\n", p.Name, p.Description)
+				fmt.Printf("  %-24s vars: %s\n", "", p.VarNames())
+			}
+			fmt.Printf("\nPrompt directory: %s\n", prompts.DefaultDir())
+			fmt.Println("Save a prompt with: zen-claw prompt save <name> --text \"...\"")
+		},
+	}
+}
+
+func newPromptShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a prompt's variables and template",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := prompts.Get(prompts.DefaultDir(), args[0])
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Prompt: %s\n", p.Name)
+			if p.Description != "" {
+				fmt.Printf("Description: %s\n", p.Description)
+			}
+			fmt.Printf("Source: %s\n", p.Path)
+			if len(p.Vars) > 0 {
+				fmt.Println("\nVariables:")
+				for _, v := range p.Vars {
+					detail := "required"
+					if v.Default != nil {
+						detail = fmt.Sprintf("default %q", *v.Default)
+					}
+					if v.Description != "" {
+						detail = v.Description + ", " + detail
+					}
+					fmt.Printf("  %-16s %s\n", v.Name, detail)
+				}
+			}
+			fmt.Printf("\n%s\n", p.Template)
+		},
+	}
+}
+
+func newPromptSaveCmd() *cobra.Command {
+	var description, text, file string
+	var vars []string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Save a prompt to the library",
+		Long: `Save a prompt to the library.
+
+The template comes from --text, --file or stdin. Variables the template uses
+are required unless declared with a default (--var name=default).
+
+Examples:
+  zen-claw prompt save review-migration --description "Review a DB migration" \
+    --var engine=postgres \
+    --text "Review the migration of {{.table}} for {{.engine}}: locking, backfills, rollback."
+
+  zen-claw prompt save release-notes --file release-notes.md`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if text == "" && file != "" {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					fmt.Printf("❌ %v\n", err)
+					os.Exit(1)
+				}
+				text = string(data)
+			} else if text == "" {
+				stat, _ := os.Stdin.Stat()
+				if (stat.Mode() & os.ModeCharDevice) == 0 {
+					data, _ := io.ReadAll(os.Stdin)
+					text = string(data)
+				}
+			}
+			if strings.TrimSpace(text) == "" {
+				fmt.Println("❌ No template: use --text, --file or pipe it via stdin")
+				os.Exit(1)
+			}
+
+			p := prompts.Prompt{Name: args[0], Description: description, Template: text}
+			for _, v := range vars {
+				name, value, hasDefault := strings.Cut(v, "=")
+				declared := prompts.Var{Name: name}
+				if hasDefault {
+					declared.Default = &value
+				}
+				p.Vars = append(p.Vars, declared)
+			}
+
+			path, err := prompts.Save(prompts.DefaultDir(), p, force)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				if !force && strings.Contains(err.Error(), "already exists") {
+					fmt.Println("   Use --force to replace it.")
+				}
+				os.Exit(1)
+			}
+			saved, _ := prompts.ReadFile(path)
+			fmt.Printf("✓ Saved prompt: %s\n", saved.Name)
+			fmt.Printf("  File: %s\n", path)
+			fmt.Printf("  Variables: %s\n", saved.VarNames())
+			fmt.Printf("\nRun it with: zen-claw prompt run %s --var name=value\n", saved.Name)
+		},
+	}
+
+	cmd.Flags().StringVar(&description, "description", "", "One-line description")
+	cmd.Flags().StringVar(&text, "text", "", "Prompt template, e.g. \"Review {{.table}}\"")
+	cmd.Flags().StringVar(&file, "file", "", "Read the template (with optional front matter) from a file")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Declare a variable: name (required) or name=default (repeatable)")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing prompt")
+
+	return cmd
+}
+
+func newPromptRunCmd() *cobra.Command {
+	var vars []string
+	var dryRun bool
+	var model, provider, workingDir, sessionID, project string
+	var showProgress bool
+	var maxSteps int
+	var tags []string
+
+	cmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a saved prompt as an agent task",
+		Long: `Run a saved prompt as a one-shot agent task.
+
+Examples:
+  zen-claw prompt run review-migration --var table=users
+  zen-claw prompt run release-notes --var tag=v1.4.0 --session releases
+  zen-claw prompt run review-migration --var table=users --dry-run   # Print the task only`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			task, err := renderPrompt(args[0], vars)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			if dryRun {
+				fmt.Println(task)
+				return
+			}
+			runAgent(task, model, provider, workingDir, sessionID, showProgress, maxSteps, false, false, false, nil, tags, project, nil, "", false)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Variable value name=value (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the rendered task instead of running it")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (e.g., deepseek-chat)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (deepseek, openai, glm, minimax, qwen, kimi)")
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Working directory for tools")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session to save/resume (omit for fresh context)")
+	cmd.Flags().BoolVar(&showProgress, "progress", false, "Show progress in console (CLI only)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 100, "Maximum tool execution steps")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Tag the session (repeatable or comma-separated)")
+	cmd.Flags().StringVar(&project, "project", "", "Project for the session (default: derived from the working dir's git remote)")
+
+	return cmd
+}
+
+func newPromptDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a saved prompt",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := prompts.Delete(prompts.DefaultDir(), args[0]); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Deleted prompt: %s\n", prompts.Normalize(args[0]))
+		},
+	}
+}
+
+// renderPrompt renders a saved prompt with name=value variables
+func renderPrompt(name string, vars []string) (string, error) {
+	p, err := prompts.Get(prompts.DefaultDir(), name)
+	if err != nil {
+		return "", err
+	}
+	values, err := prompts.ParseVars(vars)
+	if err != nil {
+		return "", err
+	}
+	return p.Render(values)
+}
+
+// handlePromptCommand handles /prompt in interactive mode: without a name it
+// lists the prompts, with one it returns the rendered prompt to send ("" if
+// there is nothing to send)
+func handlePromptCommand(input string) string {
+	words, err := prompts.SplitArgs(strings.TrimPrefix(input, "/prompt"))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return ""
+	}
+	if len(words) == 0 {
+		list := prompts.Load(prompts.DefaultDir())
+		if len(list) == 0 {
+			fmt.Println("No saved prompts. Save one with: zen-claw prompt save <name> --text \"...\"")
+			return ""
+		}
+		fmt.Println("\nPrompts:")
+		for _, p := range list {
+			fmt.Printf("  %-24s %s (vars: %s)\n", p.Name, p.Description, p.VarNames())
+		}
+		fmt.Println("\nUsage: /prompt <name> key=value ...")
+		return ""
+	}
+	task, err := renderPrompt(words[0], words[1:])
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return ""
+	}
+	fmt.Printf("📋 %s\n", task)
+	return task
+}
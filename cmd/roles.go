@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/roles"
+	"github.com/spf13/cobra"
+)
+
+func newRolesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "roles",
+		Short: "Manage the role library",
+		Long: `Manage the expert roles used by consensus, fabric and factory.
+
+A role is a markdown file: optional front matter with a description and
+evaluation criteria (what the consensus arbiter scores answers on), then the
+expertise put in the prompt. A file named like a built-in role replaces it.
+Factory phases whose domain names a role use it as their system prompt.
+
+Role directory: ~/.zen/zen-claw/roles/`,
+	}
+
+	cmd.AddCommand(newRolesListCmd())
+	cmd.AddCommand(newRolesShowCmd())
+	cmd.AddCommand(newRolesAddCmd())
+
+	return cmd
+}
+
+func newRolesListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List available roles",
+		Run: func(cmd *cobra.Command, args []string) {
+			list := roles.Load(roles.DefaultDir()).List()
+			fmt.Printf("Roles (%d):\n\n", len(list))
+			for _, r := range list {
+				source := "built-in"
+				if !r.Builtin() {
+					source = r.Path
+				}
+				fmt.Printf("  %-24s %s\n", r.Name, r.Description)
+				fmt.Printf("  %-24s (%s)\n", "", source)
+			}
+			fmt.Printf("\nRole directory: %s\n", roles.DefaultDir())
+			fmt.Println("Add a role with: zen-claw roles add <name>")
+		},
+	}
+}
+
+func newRolesShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a role's expertise and evaluation criteria",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			role, ok := roles.Load(roles.DefaultDir()).Get(args[0])
+			if !ok {
+				fmt.Printf("❌ Role '%s' not found. Run 'zen-claw roles list' to see the roles.\n", args[0])
+				os.Exit(1)
+			}
+
+			fmt.Printf("Role: %s\n", role.Name)
+			if role.Description != "" {
+				fmt.Printf("Description: %s\n", role.Description)
+			}
+			if role.Builtin() {
+				fmt.Println("Source: built-in")
+			} else {
+				fmt.Printf("Source: %s\n", role.Path)
+			}
+			fmt.Printf("\nEvaluation criteria: %s\n", strings.Join(role.EvaluationCriteria(), ", "))
+			fmt.Printf("\n%s\n", role.Expertise)
+		},
+	}
+}
+
+func newRolesAddCmd() *cobra.Command {
+	var description, expertise string
+	var criteria []string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add a role to the library",
+		Long: `Add a role to the library.
+
+Without --expertise a template is written for you to edit.
+
+Examples:
+  zen-claw roles add sre_lead --description "Reliability and incident response" \
+    --criteria "concrete SLOs" --criteria "rollback safety" \
+    --expertise "Your expertise includes SLO design, error budgets and incident response."
+
+  # Start from a built-in role
+  zen-claw roles show security_architect
+  zen-claw roles add security_architect --force --expertise "..."`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			role := roles.Role{
+				Name:        args[0],
+				Description: description,
+				Criteria:    criteria,
+				Expertise:   expertise,
+			}
+			if role.Expertise == "" {
+				role.Expertise = "Your expertise includes:\n- TODO: what this expert knows and cares about"
+				if len(role.Criteria) == 0 {
+					role.Criteria = []string{"TODO: what good answers get right"}
+				}
+			}
+
+			path, err := roles.Save(roles.DefaultDir(), role, force)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				if !force {
+					fmt.Println("   Use --force to replace it.")
+				}
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Added role: %s\n", roles.Normalize(role.Name))
+			fmt.Printf("  File: %s\n", path)
+			if expertise == "" {
+				fmt.Println("\nEdit the file to describe the role's expertise and evaluation criteria.")
+			}
+			fmt.Printf("\nUse it with: zen-claw consensus --role %s \"...\"\n", roles.Normalize(role.Name))
+		},
+	}
+
+	cmd.Flags().StringVar(&description, "description", "", "One-line description")
+	cmd.Flags().StringVar(&expertise, "expertise", "", "Expertise text put in the prompt")
+	cmd.Flags().StringArrayVar(&criteria, "criteria", nil, "Evaluation criterion (repeatable)")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing role file")
+
+	return cmd
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/neves/zen-claw/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +27,10 @@ Quick start:
   zen-claw factory start plan.yaml  # Run multi-phase project
 
 Providers: DeepSeek, Qwen, MiniMax, Kimi, OpenAI, GLM`,
+	Version: version.Version,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		hintNewRelease(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Display capabilities at startup
 		displayCapabilities()
@@ -38,19 +43,35 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVar(&autoStartGateway, "auto-start", false, "Start the gateway in the background if it isn't running")
 	rootCmd.AddCommand(newAgentCmd())
+	rootCmd.AddCommand(newAskCmd())
+	rootCmd.AddCommand(newDiagramCmd())
+	rootCmd.AddCommand(newFlakyCmd())
+	rootCmd.AddCommand(newUpgradeCmd())
 	rootCmd.AddCommand(newConfigCmd())
 	rootCmd.AddCommand(newConsensusCmd())
+	rootCmd.AddCommand(newDatasetCmd())
 	rootCmd.AddCommand(newFabricCmd())
 	rootCmd.AddCommand(newFactoryCmd())
 	rootCmd.AddCommand(newGatewayCmd())
 	rootCmd.AddCommand(newMCPCmd())
 	rootCmd.AddCommand(newSessionsCmd())
 	rootCmd.AddCommand(newSessionCmd())
+	rootCmd.AddCommand(newPlayCmd())
 	rootCmd.AddCommand(newPluginsCmd())
+	rootCmd.AddCommand(newPromptCmd())
+	rootCmd.AddCommand(newRolesCmd())
+	rootCmd.AddCommand(newIncidentCmd())
 	rootCmd.AddCommand(newIndexCmd())
+	rootCmd.AddCommand(newSimulateCmd())
 	rootCmd.AddCommand(newSlackCmd())
 	rootCmd.AddCommand(newToolsCmd())
+	rootCmd.AddCommand(newTriageCmd())
+	rootCmd.AddCommand(newUpdateCmd())
+	rootCmd.AddCommand(newUsageCmd())
+	// Aliases from the config, after the built-in commands they may not shadow
+	addAliasCommands(rootCmd)
 }
 
 // displayCapabilities shows the AI's capabilities at startup
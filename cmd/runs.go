@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// runReportTimeout bounds reporting a run, so an unresponsive gateway
+// doesn't hold up the CLI
+const runReportTimeout = 5 * time.Second
+
+// runReport collects the model calls of a consensus, fabric or factory run
+// and reports the run to the gateway when it finishes
+type runReport struct {
+	mu    sync.Mutex
+	start time.Time
+	run   types.RunRecord
+}
+
+func newRunReport(kind, task string) *runReport {
+	now := time.Now()
+	return &runReport{start: now, run: types.RunRecord{Kind: kind, Task: task, StartedAt: now, Calls: []types.RunCall{}}}
+}
+
+// add records a call whose cost is already known
+func (r *runReport) add(call types.RunCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run.Calls = append(r.run.Calls, call)
+	r.run.CostUSD += call.CostUSD
+}
+
+// chat records a chat call, estimating its cost from the message sizes
+// (~4 chars per token)
+func (r *runReport) chat(provider, model, role string, messages []ai.Message, response string, d time.Duration, err error) {
+	in := 0
+	for _, m := range messages {
+		in += len(m.Content) / 4
+	}
+	out := len(response) / 4
+	call := types.RunCall{Provider: provider, Model: model, Role: role, DurationMs: d.Milliseconds()}
+	if err != nil {
+		call.Error = err.Error()
+	} else {
+		call.CostUSD = cost.CalculateUSD(provider, model, in, out)
+		r.mu.Lock()
+		r.run.Tokens += in + out
+		r.mu.Unlock()
+	}
+	r.add(call)
+}
+
+// responseContent returns a chat response's content (empty when the call failed)
+func responseContent(resp *ai.ChatResponse) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Content
+}
+
+// finish completes the run and reports it to the gateway. Runs are
+// reported best effort: without a gateway they stay CLI-only.
+func (r *runReport) finish(err error) {
+	r.mu.Lock()
+	r.run.DurationMs = time.Since(r.start).Milliseconds()
+	if err != nil {
+		r.run.Error = err.Error()
+		if r.run.Status == "" {
+			r.run.Status = types.RunFailed
+		}
+	}
+	if r.run.Status == "" {
+		r.run.Status = types.RunCompleted
+	}
+	run := r.run
+	r.mu.Unlock()
+
+	client := NewGatewayClient(getGatewayURL())
+	client.client.Timeout = runReportTimeout
+	client.RecordRun(run)
+}
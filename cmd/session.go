@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/neves/zen-claw/internal/agent"
 	"github.com/spf13/cobra"
 )
 
@@ -19,10 +20,72 @@ func newSessionCmd() *cobra.Command {
 			fmt.Println("Session listing coming soon")
 		},
 	})
+	cmd.AddCommand(newSessionsSearchCmd())
+	cmd.AddCommand(newSessionDoctorCmd())
 
 	return cmd
 }
 
+func newSessionDoctorCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor <session-id>",
+		Short: "Check and repair a session's transcript",
+		Long: `Check a session's history for tool calls and results providers reject:
+calls without a result, results not right after their call, duplicate
+results, and results answering no call. Crashes and cancels can leave such
+a history, and every later request in the session fails.
+
+Repairs move results back after their call, add an error result to calls
+without one, and drop the rest. The gateway also repairs sessions when it
+loads them; those repairs are listed too.`,
+		Example: `  zen-claw session doctor my-feature
+  zen-claw session doctor my-feature --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := NewGatewayClient(getGatewayURL())
+			if err := ensureGateway(client); err != nil {
+				return err
+			}
+			report, err := client.DoctorSession(args[0], !dryRun)
+			if err != nil {
+				return err
+			}
+
+			if len(report.RepairedOnLoad) > 0 {
+				fmt.Printf("Repaired when the gateway loaded the session (%d):\n", len(report.RepairedOnLoad))
+				printTranscriptIssues(report.RepairedOnLoad)
+			}
+			switch {
+			case len(report.Issues) == 0:
+				fmt.Printf("✅ Transcript of %s is consistent\n", report.ID)
+			case report.Repaired:
+				fmt.Printf("🔧 Repaired %d issue(s) in %s:\n", len(report.Issues), report.ID)
+				printTranscriptIssues(report.Issues)
+			default:
+				fmt.Printf("⚠️  %d issue(s) in %s:\n", len(report.Issues), report.ID)
+				printTranscriptIssues(report.Issues)
+				fmt.Printf("   Run without --dry-run to repair them\n")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report issues, don't repair them")
+	return cmd
+}
+
+func printTranscriptIssues(issues []agent.TranscriptIssue) {
+	for _, issue := range issues {
+		call := issue.ToolCallID
+		if issue.Tool != "" {
+			call = issue.Tool + " " + call
+		}
+		fmt.Printf("  • message %d  %-16s %s: %s\n", issue.Index, issue.Kind, call, issue.Detail)
+	}
+}
+
 // Helper function to truncate strings for display
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -22,12 +22,15 @@ func newSessionsCmd() *cobra.Command {
 	cmd.AddCommand(newSessionsListCmd())
 	cmd.AddCommand(newSessionsCleanCmd())
 	cmd.AddCommand(newSessionsInfoCmd())
+	cmd.AddCommand(newSessionsSearchCmd())
 
 	return cmd
 }
 
 func newSessionsListCmd() *cobra.Command {
-	return &cobra.Command{
+	var filter gateway.SessionFilter
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all saved sessions",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -42,7 +45,12 @@ func newSessionsListCmd() *cobra.Command {
 			}
 			defer store.Close()
 
-			sessions := store.ListSessionsWithState()
+			var sessions []gateway.SessionListEntry
+			for _, s := range store.ListSessionsWithState() {
+				if filter.Matches(s.Stats) {
+					sessions = append(sessions, s)
+				}
+			}
 			if len(sessions) == 0 {
 				fmt.Println("No saved sessions")
 				return
@@ -53,26 +61,95 @@ func newSessionsListCmd() *cobra.Command {
 			for _, s := range sessions {
 				age := time.Since(s.LastUsed)
 				ageStr := formatDuration(age)
-				fmt.Printf("  • %-20s  %3d msgs  %s ago\n",
-					s.Stats.SessionID, s.Stats.MessageCount, ageStr)
+				fmt.Printf("  • %-20s  %3d msgs  %4s ago  %s\n",
+					s.Stats.SessionID, s.Stats.MessageCount, ageStr, s.Stats.Title)
+				if labels := sessionLabels(s.Stats.Project, s.Stats.Tags); labels != "" {
+					fmt.Printf("      %s\n", labels)
+				}
 			}
 		},
 	}
+
+	cmd.Flags().StringVar(&filter.Tag, "tag", "", "Only sessions with this tag")
+	cmd.Flags().StringVar(&filter.Project, "project", "", "Only sessions for this project (e.g. owner/repo)")
+	return cmd
+}
+
+func newSessionsSearchCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search session titles and transcripts",
+		Long: `Find saved sessions whose title or conversation contains all query terms.
+
+Uses SQLite full-text search (stemmed, ranked) when zen-claw is built with
+-tags sqlite_fts5; otherwise falls back to case-insensitive substring matching.
+
+Examples:
+  zen-claw session search "circuit breaker"
+  zen-claw session search migration postgres --limit 5`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadConfigForSessions()
+			store, err := gateway.NewSessionStore(&gateway.SessionStoreConfig{
+				DBPath:      cfg.GetSessionDBPath(),
+				MaxSessions: 100, // Just for searching
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			defer store.Close()
+
+			results, err := store.Search(strings.Join(args, " "), limit)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			if len(results) == 0 {
+				fmt.Println("No matching sessions")
+				return
+			}
+
+			fmt.Printf("Matching sessions (%d):\n", len(results))
+			fmt.Println(strings.Repeat("─", 60))
+			for _, r := range results {
+				fmt.Printf("  • %-20s  %s ago  %d matches  %s\n",
+					r.SessionID, formatDuration(time.Since(r.UpdatedAt)), r.Matches, r.Title)
+				if r.Snippet != "" {
+					fmt.Printf("      %s\n", truncateString(r.Snippet, 160))
+				}
+			}
+			fmt.Println(strings.Repeat("─", 60))
+			fmt.Println("Resume with: zen-claw agent --session <name>")
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum sessions to show")
+	return cmd
 }
 
 func newSessionsCleanCmd() *cobra.Command {
 	var all bool
 	var olderThan string
+	var policy bool
+	var dryRun bool
 
 	cmd := &cobra.Command{
 		Use:   "clean",
 		Short: "Clean session data",
 		Long: `Delete session data to free space.
 
+Sessions tagged "pinned" and sessions listed in sessions.retention.keep are
+never deleted by --older or --policy. The gateway applies sessions.retention
+automatically; --policy runs it now.
+
 Examples:
-  zen-claw sessions clean --all           # Delete ALL sessions
-  zen-claw sessions clean --older 7d      # Delete sessions older than 7 days
-  zen-claw sessions clean --older 24h     # Delete sessions older than 24 hours`,
+  zen-claw sessions clean --all                  # Delete ALL sessions
+  zen-claw sessions clean --older 7d             # Delete sessions older than 7 days
+  zen-claw sessions clean --older 24h --dry-run  # Show what would be deleted
+  zen-claw sessions clean --policy --dry-run     # Preview the configured retention policy`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cfg := loadConfigForSessions()
 			store, err := gateway.NewSessionStore(&gateway.SessionStoreConfig{
@@ -95,33 +172,61 @@ Examples:
 				return
 			}
 
-			if olderThan != "" {
+			retention := gateway.RetentionPolicyFromConfig(cfg.Sessions.Retention)
+			switch {
+			case olderThan != "":
 				duration, err := parseDuration(olderThan)
 				if err != nil {
 					fmt.Printf("Invalid duration: %v\n", err)
 					fmt.Println("Examples: 24h, 7d, 30d")
 					return
 				}
-				count, err := store.CleanOldSessions(duration)
-				if err != nil {
-					fmt.Printf("Error: %v\n", err)
+				retention.MaxAge, retention.MaxTotalBytes = duration, 0
+			case policy:
+				if !cfg.Sessions.Retention.Enabled() {
+					fmt.Println("No retention policy configured (sessions.retention.max_age_days / max_total_mb)")
 					return
 				}
-				fmt.Printf("Deleted %d sessions older than %s\n", count, olderThan)
+			default:
+				fmt.Println("Specify --all, --older <duration> or --policy")
+				fmt.Println("Run 'zen-claw sessions clean --help' for examples")
 				return
 			}
 
-			fmt.Println("Specify --all or --older <duration>")
-			fmt.Println("Run 'zen-claw sessions clean --help' for examples")
+			report, err := store.ApplyRetention(retention, dryRun)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			printRetentionReport(report)
 		},
 	}
 
 	cmd.Flags().BoolVar(&all, "all", false, "Delete all sessions")
 	cmd.Flags().StringVar(&olderThan, "older", "", "Delete sessions older than duration (e.g., 7d, 24h)")
+	cmd.Flags().BoolVar(&policy, "policy", false, "Apply sessions.retention from config")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be deleted without deleting")
 
 	return cmd
 }
 
+// printRetentionReport lists sessions removed (or due for removal) by a retention pass
+func printRetentionReport(report *gateway.RetentionReport) {
+	verb := "Deleted"
+	if report.DryRun {
+		verb = "Would delete"
+	}
+	for _, c := range report.Deleted {
+		title := c.Title
+		if title == "" {
+			title = "-"
+		}
+		fmt.Printf("  %-24s %-10s %-30s %s\n", c.SessionID, formatBytes(c.Bytes), c.Reason, truncateString(title, 40))
+	}
+	fmt.Printf("%s %d of %d sessions (%s freed, %d protected)\n",
+		verb, len(report.Deleted), report.Sessions, formatBytes(report.FreedBytes), report.Protected)
+}
+
 func newSessionsInfoCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "info",
@@ -164,6 +269,18 @@ func newSessionsInfoCmd() *cobra.Command {
 	}
 }
 
+// sessionLabels renders "project #tag1 #tag2" (empty when neither is set)
+func sessionLabels(project string, tags []string) string {
+	var parts []string
+	if project != "" {
+		parts = append(parts, project)
+	}
+	for _, t := range tags {
+		parts = append(parts, "#"+t)
+	}
+	return strings.Join(parts, " ")
+}
+
 func loadConfigForSessions() *config.Config {
 	// Try default config path
 	home, _ := os.UserHomeDir()
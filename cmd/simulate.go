@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// simulateScript is the input of zen-claw simulate
+type simulateScript struct {
+	Task    string            `yaml:"task"`    // The user message (default "Simulated task")
+	Files   map[string]string `yaml:"files"`   // Workspace files to create (relative path -> content)
+	Outputs []simulatedOutput `yaml:"outputs"` // Model outputs, one per model call
+}
+
+// simulatedOutput is one fake model answer: text (tool calls in it are
+// parsed like a model's) and/or native tool calls
+type simulatedOutput struct {
+	Content   string `yaml:"content"`
+	ToolCalls []struct {
+		ID   string                 `yaml:"id"`
+		Name string                 `yaml:"name"`
+		Args map[string]interface{} `yaml:"args"`
+	} `yaml:"tool_calls"`
+}
+
+// UnmarshalYAML accepts a plain string as text content
+func (o *simulatedOutput) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		o.Content = node.Value
+		return nil
+	}
+	type plain simulatedOutput
+	return node.Decode((*plain)(o))
+}
+
+func newSimulateCmd() *cobra.Command {
+	var workspace string
+	var keep bool
+	var maxSteps int
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "simulate <script.yaml>",
+		Short: "Run the agent loop on scripted model outputs (no model, no tokens)",
+		Long: `Feed a scripted sequence of model outputs into the agent loop, in a temporary
+workspace, and print every parsed tool call, its result, and what changed in the
+session and the workspace after each step. For debugging tool-call parsing and
+tool execution without a model.
+
+The script is YAML:
+
+  task: Fix the typo in main.go
+  files:                      # Created in the workspace
+    main.go: |
+      package main
+  outputs:                    # One per model call
+    - |                       # Text; tool calls in it are parsed
+      <function=read_file>
+      <parameter=path>main.go</parameter>
+      </function>
+    - tool_calls:             # Native tool calls
+        - name: edit_file
+          args: {path: main.go, old_string: mian, new_string: main}
+    - Fixed the typo.`,
+		Example: `  zen-claw simulate script.yaml
+  zen-claw simulate --workspace ./testdata/project --keep script.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !verbose {
+				log.SetOutput(io.Discard) // The trace is the output
+			}
+			return runSimulate(args[0], workspace, keep, maxSteps)
+		},
+	}
+
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Copy this directory into the temporary workspace first")
+	cmd.Flags().BoolVar(&keep, "keep", false, "Keep the temporary workspace after the run")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 50, "Maximum agent steps")
+	cmd.Flags().BoolVar(&verbose, "verbose", false, "Also print the agent's log")
+	return cmd
+}
+
+func runSimulate(scriptPath, seed string, keep bool, maxSteps int) error {
+	data, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return err
+	}
+	var script simulateScript
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return fmt.Errorf("parse %s: %w", scriptPath, err)
+	}
+	if len(script.Outputs) == 0 {
+		return fmt.Errorf("%s has no outputs", scriptPath)
+	}
+	if script.Task == "" {
+		script.Task = "Simulated task"
+	}
+
+	dir, err := os.MkdirTemp("", "zen-claw-simulate-")
+	if err != nil {
+		return err
+	}
+	if keep {
+		defer fmt.Printf("\nWorkspace kept: %s\n", dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+	if seed != "" {
+		if err := copyDir(seed, dir); err != nil {
+			return fmt.Errorf("copy workspace: %w", err)
+		}
+	}
+	for name, content := range script.Files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	// Local tools only: nothing leaves the machine
+	tools := []agent.Tool{
+		agent.NewExecTool(""),
+		agent.NewReadFileTool(""),
+		agent.NewWriteFileTool(""),
+		agent.NewEditFileTool(""),
+		agent.NewMultiEditTool(""),
+		agent.NewAppendFileTool(""),
+		agent.NewListDirTool(""),
+		agent.NewSearchFilesTool(""),
+		agent.NewGitStatusTool(""),
+		agent.NewGitDiffTool(""),
+		agent.NewGitAddTool(""),
+		agent.NewGitCommitTool(""),
+		agent.NewGitLogTool(""),
+		agent.NewPreviewWriteTool(""),
+		agent.NewPreviewEditTool(""),
+		agent.NewProcessTool(""),
+		agent.NewApplyPatchTool(""),
+		agent.NewExpandResultTool(),
+	}
+
+	caller := &simulatedCaller{outputs: script.Outputs}
+	a := agent.NewAgent(caller, tools, maxSteps)
+	a.Use(traceToolsMiddleware(), agent.PostProcessMiddleware())
+	session := agent.NewSession("simulate")
+	session.SetWorkingDir(dir)
+
+	state := snapshotSimulation(session, dir)
+	a.SetProgressCallback(func(event agent.ProgressEvent) {
+		switch event.Type {
+		case "step":
+			if event.Step > 1 {
+				state = printSimulationChanges(session, dir, state)
+			}
+			fmt.Printf("\n━━ Step %d ━━\n", event.Step)
+		case "ai_response":
+			// The output itself is printed by the caller
+		case "tool_call", "tool_result", "thinking", "token", "complete":
+			// Traced by the middleware / printed below
+		default:
+			fmt.Printf("📣 %s: %s\n", event.Type, event.Message)
+		}
+	})
+
+	fmt.Printf("Workspace: %s\nTask: %s\n", dir, script.Task)
+	_, result, runErr := a.Run(context.Background(), session, script.Task)
+	printSimulationChanges(session, dir, state)
+
+	fmt.Println("\n" + strings.Repeat("═", 80))
+	switch {
+	case caller.exhausted:
+		fmt.Printf("⏹  Script ended: the agent asked for output %d of %d\n", caller.calls, len(script.Outputs))
+	case runErr != nil:
+		fmt.Printf("❌ Run failed: %v\n", runErr)
+	default:
+		fmt.Printf("✅ Result:\n%s\n", result)
+	}
+	if unused := len(script.Outputs) - caller.calls; unused > 0 && !caller.exhausted {
+		fmt.Printf("⚠️  %d scripted output(s) not used\n", unused)
+	}
+	if runErr != nil && !caller.exhausted {
+		return runErr
+	}
+	return nil
+}
+
+// simulatedCaller answers model calls with the script's outputs, in order
+type simulatedCaller struct {
+	outputs   []simulatedOutput
+	calls     int
+	exhausted bool
+}
+
+func (c *simulatedCaller) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	c.calls++
+	if c.calls > len(c.outputs) {
+		c.exhausted = true
+		return nil, fmt.Errorf("script has no output %d", c.calls)
+	}
+	out := c.outputs[c.calls-1]
+	resp := &ai.ChatResponse{Content: out.Content, FinishReason: "stop"}
+	for i, call := range out.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("sim_%d_%d", c.calls, i+1)
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ai.ToolCall{ID: id, Name: call.Name, Args: call.Args})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+
+	fmt.Printf("🤖 Model output %d/%d (request: %d messages)\n", c.calls, len(c.outputs), len(req.Messages))
+	if out.Content != "" {
+		fmt.Println(indentLines(out.Content, "   │ "))
+	}
+	for _, call := range resp.ToolCalls {
+		fmt.Printf("   native call %s %s\n", call.ID, call.Name)
+	}
+	return resp, nil
+}
+
+func (c *simulatedCaller) ChatStream(ctx context.Context, req ai.ChatRequest, callback ai.StreamCallback) (*ai.ChatResponse, error) {
+	return c.Chat(ctx, req)
+}
+
+// traceToolsMiddleware prints each tool call as parsed and its result
+func traceToolsMiddleware() agent.ToolMiddleware {
+	return func(next agent.ToolHandler) agent.ToolHandler {
+		return func(ctx context.Context, inv *agent.ToolInvocation) (interface{}, error) {
+			args, _ := json.MarshalIndent(inv.Args, "   ", "  ")
+			fmt.Printf("🔧 %s (call %s)\n   %s\n", inv.Name, inv.CallID, args)
+			result, err := next(ctx, inv)
+			if err != nil {
+				fmt.Printf("   ❌ error: %v\n", err)
+				return result, err
+			}
+			out, ok := result.(string)
+			if !ok {
+				data, _ := json.MarshalIndent(result, "", "  ")
+				out = string(data)
+			}
+			fmt.Printf("   ✓ result:\n%s\n", indentLines(out, "   │ "))
+			return result, nil
+		}
+	}
+}
+
+// simulationState is what a step may change: the session and the workspace
+type simulationState struct {
+	messages   int
+	workingDir string
+	files      map[string]string // Relative path -> content hash
+}
+
+func snapshotSimulation(session *agent.Session, dir string) simulationState {
+	state := simulationState{
+		messages:   len(session.GetMessages()),
+		workingDir: session.GetWorkingDir(),
+		files:      make(map[string]string),
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				sum := sha256.Sum256(data)
+				rel, _ := filepath.Rel(dir, path)
+				state.files[rel] = hex.EncodeToString(sum[:])
+			}
+		}
+		return nil
+	})
+	return state
+}
+
+// printSimulationChanges prints what changed since before, and returns the
+// new state
+func printSimulationChanges(session *agent.Session, dir string, before simulationState) simulationState {
+	after := snapshotSimulation(session, dir)
+	var lines []string
+
+	messages := session.GetMessages()
+	if after.messages > before.messages {
+		var roles []string
+		for _, msg := range messages[before.messages:] {
+			role := msg.Role
+			if len(msg.ToolCalls) > 0 {
+				role += fmt.Sprintf("(%d call(s))", len(msg.ToolCalls))
+			}
+			roles = append(roles, role)
+		}
+		lines = append(lines, fmt.Sprintf("messages +%d: %s", after.messages-before.messages, strings.Join(roles, ", ")))
+	}
+	if after.workingDir != before.workingDir {
+		lines = append(lines, fmt.Sprintf("working dir: %s → %s", before.workingDir, after.workingDir))
+	}
+
+	var files []string
+	for path, hash := range after.files {
+		switch old, ok := before.files[path]; {
+		case !ok:
+			files = append(files, "+ "+path)
+		case old != hash:
+			files = append(files, "~ "+path)
+		}
+	}
+	for path := range before.files {
+		if _, ok := after.files[path]; !ok {
+			files = append(files, "- "+path)
+		}
+	}
+	slices.SortFunc(files, func(a, b string) int { return strings.Compare(a[2:], b[2:]) })
+	for _, f := range files {
+		lines = append(lines, "file "+f)
+	}
+
+	if len(lines) > 0 {
+		fmt.Println("📝 State changes:")
+		for _, line := range lines {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+	return after
+}
+
+// indentLines prefixes every line of s
+func indentLines(s, prefix string) string {
+	return prefix + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n"+prefix)
+}
+
+// copyDir copies the regular files under src into dst
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
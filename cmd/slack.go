@@ -18,6 +18,8 @@ func newSlackCmd() *cobra.Command {
 	var workingDir string
 	var provider string
 	var model string
+	var language string
+	var statePath string
 	var maxSteps int
 	var debug bool
 
@@ -27,7 +29,9 @@ func newSlackCmd() *cobra.Command {
 		Long: `Start the Zen Claw Slack bot that connects to the gateway via WebSocket.
 
 The Slack bot allows users to interact with the AI agent directly from Slack.
-Each thread becomes a separate session with its own context.
+Each thread becomes a separate session with its own context. Threads and
+their settings are kept in a state file, so they continue after the bot
+restarts.
 
 Required environment variables:
   SLACK_BOT_TOKEN  - Bot User OAuth Token (xoxb-...)
@@ -39,9 +43,13 @@ Setup:
   1. Create a Slack App at https://api.slack.com/apps
   2. Enable Socket Mode (Settings > Socket Mode)
   3. Add Bot Token Scopes: app_mentions:read, chat:write, im:history, im:read, im:write
-  4. Subscribe to Events: app_mention, message.im
-  5. Install to workspace
-  6. Copy the Bot Token and App Token
+  4. Subscribe to Events: app_mention, message.im, app_home_opened
+  5. Enable the Home Tab (App Home) and Interactivity
+  6. Install to workspace
+  7. Copy the Bot Token and App Token
+
+The App Home tab lists each user's sessions with buttons to resume one in a
+new DM thread, see its estimated cost, or delete it.
 
 Examples:
   # Start with environment variables
@@ -62,6 +70,8 @@ Examples:
 				DefaultDir: workingDir,
 				Provider:   provider,
 				Model:      model,
+				Language:   language,
+				StatePath:  statePath,
 				MaxSteps:   maxSteps,
 				Debug:      debug,
 			})
@@ -74,6 +84,8 @@ Examples:
 	cmd.Flags().StringVar(&workingDir, "dir", ".", "Default working directory for tools")
 	cmd.Flags().StringVar(&provider, "provider", "", "Default AI provider (deepseek, openai, qwen, glm, minimax, kimi)")
 	cmd.Flags().StringVar(&model, "model", "", "Default AI model")
+	cmd.Flags().StringVar(&language, "lang", "", "Default answer language, e.g. pt-BR (users can change it with /lang)")
+	cmd.Flags().StringVar(&statePath, "state", slackbot.DefaultStatePath(), "File thread sessions are kept in across restarts (\"\" = don't keep them)")
 	cmd.Flags().IntVar(&maxSteps, "max-steps", 100, "Maximum tool execution steps")
 	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
 
@@ -111,6 +123,12 @@ func runSlackBot(cfg slackbot.Config) {
 	if cfg.Model != "" {
 		fmt.Printf("Model: %s\n", cfg.Model)
 	}
+	if cfg.Language != "" {
+		fmt.Printf("Language: %s\n", cfg.Language)
+	}
+	if cfg.StatePath != "" {
+		fmt.Printf("Thread State: %s\n", cfg.StatePath)
+	}
 	fmt.Printf("Max Steps: %d\n", cfg.MaxSteps)
 	fmt.Println()
 
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/lsp"
+	"github.com/neves/zen-claw/internal/tickets"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +21,11 @@ func newToolsCmd() *cobra.Command {
 				agent.NewReadFileTool("."),
 				agent.NewWriteFileTool("."),
 				agent.NewEditFileTool("."),
+				agent.NewMultiEditTool("."),
 				agent.NewAppendFileTool("."),
+				agent.NewBeginWriteTool("."),
+				agent.NewAppendChunkTool(),
+				agent.NewCommitWriteTool(),
 				agent.NewListDirTool("."),
 				agent.NewSearchFilesTool("."),
 				agent.NewSystemInfoTool(),
@@ -30,6 +36,7 @@ func newToolsCmd() *cobra.Command {
 				agent.NewGitCommitTool("."),
 				agent.NewGitPushTool("."),
 				agent.NewGitLogTool("."),
+				agent.NewGitBisectTool("."),
 				// Preview (diff before write)
 				agent.NewPreviewWriteTool("."),
 				agent.NewPreviewEditTool("."),
@@ -40,7 +47,29 @@ func newToolsCmd() *cobra.Command {
 				agent.NewProcessTool("."),
 				// Multi-file patches
 				agent.NewApplyPatchTool("."),
+				// Generation from exemplars
+				agent.NewGenerateLikeTool("."),
+				// Go refactoring
+				agent.NewGoRenameTool("."),
+				agent.NewGoMoveFuncTool("."),
+				agent.NewGoOrganizeImportsTool("."),
+				// Project analysis
+				agent.NewCoverageTool("."),
+				agent.NewGoBenchTool("."),
+				agent.NewPprofTool("."),
+				agent.NewGoDepsTool("."),
+				agent.NewProjectTasksTool("."),
+				agent.NewTerraformPlanTool("."),
+				// Operations (read-only)
+				agent.NewK8sTool("."),
+				agent.NewRunbookSearchTool(nil), // Directories from config
 			}
+			// Ticket, docs and artifact tools (listed even when none are configured)
+			tools = append(tools, agent.NewTicketTools(tickets.NewWorkspaces(""))...)
+			tools = append(tools, agent.NewDocsTools(nil)...)
+			tools = append(tools, agent.NewUploadArtifactTool(".", nil, 0))
+			// Language server navigation (gopls/tsserver)
+			tools = append(tools, lsp.NewManager().GetTools(".")...)
 
 			fmt.Println("Available Tools:")
 			fmt.Println("════════════════════════════════════════════════════════════════════════════════")
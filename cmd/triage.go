@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/gateway"
+	"github.com/spf13/cobra"
+)
+
+func newTriageCmd() *cobra.Command {
+	var dryRun, asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "triage <owner/repo#number>",
+		Short: "Classify, summarize and label a GitHub issue",
+		Long: `Have the gateway triage a GitHub issue: classify it (bug, feature,
+question), name the affected area from the repo's directories, pick labels
+among the repo's own and summarize it in one paragraph.
+
+The triage is posted on the issue, and the labels are added when the model's
+confidence reaches github.repos.<repo>.triage_min_confidence (default 0.8).
+With --dry-run nothing is written to GitHub. The gateway needs a GitHub token
+(github.token or GITHUB_TOKEN).
+
+Issues of repos with triage: true are also triaged when opened, through a
+hook of kind github.`,
+		Example: `  zen-claw triage acme/api#123
+  zen-claw triage acme/api#123 --dry-run --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, number, ok := strings.Cut(args[0], "#")
+			issue, err := strconv.Atoi(number)
+			if !ok || err != nil || !strings.Contains(repo, "/") {
+				return fmt.Errorf("invalid issue %q (use owner/repo#number)", args[0])
+			}
+
+			client := NewGatewayClient(getGatewayURL())
+			if err := ensureGateway(client); err != nil {
+				return err
+			}
+			triage, err := client.TriageIssue(gateway.TriageRequest{Repo: repo, Issue: issue, DryRun: dryRun})
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(triage)
+			}
+			fmt.Printf("%s#%d: %s", triage.Repo, triage.Issue, triage.Kind)
+			if triage.Area != "" {
+				fmt.Printf(" in %s", triage.Area)
+			}
+			fmt.Printf(" (confidence %.0f%%)\n\n%s\n\n", triage.Confidence*100, triage.Summary)
+			switch {
+			case len(triage.Applied) > 0:
+				fmt.Printf("🏷️  Labeled: %s\n", strings.Join(triage.Applied, ", "))
+			case len(triage.Labels) > 0:
+				fmt.Printf("🏷️  Suggested labels: %s\n", strings.Join(triage.Labels, ", "))
+			}
+			if triage.Commented {
+				fmt.Println("💬 Posted the triage on the issue")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only propose: don't label or comment on the issue")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the triage as JSON")
+	return cmd
+}
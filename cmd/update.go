@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/gateway"
+	"github.com/neves/zen-claw/internal/update"
+	"github.com/neves/zen-claw/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func newUpdateCmd() *cobra.Command {
+	var channel string
+	var checkOnly, force bool
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update zen-claw to the latest release",
+		Long: `Update zen-claw to the latest GitHub release.
+
+Downloads the binary for this OS/architecture, verifies it against the
+release's checksums.txt and its signature (by the public key built into
+zen-claw, or update.public_key), and atomically replaces the running
+executable. Builds without a key refuse to update unless
+update.skip_signature is set.
+
+Channels: stable (releases only, default) or beta (pre-releases too).`,
+		Example: `  zen-claw update --check
+  zen-claw update
+  zen-claw update --channel beta`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig("")
+			if err != nil {
+				cfg = config.NewDefaultConfig()
+			}
+			if channel == "" {
+				channel = cfg.Update.Channel
+			}
+			if channel == "" {
+				channel = update.ChannelStable
+			}
+			client := update.NewClient("")
+			if cfg.Update.PublicKey != "" {
+				if client.PublicKey, err = update.ParsePublicKey(cfg.Update.PublicKey); err != nil {
+					return fmt.Errorf("update.public_key: %w", err)
+				}
+			}
+			if cfg.Update.SkipSignature {
+				client.SkipSignature = true
+				fmt.Fprintln(os.Stderr, "⚠️  update.skip_signature is set: releases are verified by checksum only")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			release, err := client.Latest(ctx, channel)
+			if err != nil {
+				return fmt.Errorf("failed to check for releases: %w", err)
+			}
+			if release == nil {
+				fmt.Printf("No %s releases found.\n", channel)
+				return nil
+			}
+			newer := update.Compare(release.Version(), version.Version) > 0
+			if !newer && !force {
+				fmt.Printf("zen-claw %s is up to date (latest %s: %s).\n", version.Version, channel, release.Version())
+				return nil
+			}
+			if checkOnly {
+				fmt.Printf("zen-claw %s is available (you have %s): %s\n", release.Version(), version.Version, release.URL)
+				fmt.Println("   Run: zen-claw update")
+				return nil
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Downloading zen-claw %s for %s/%s...\n", release.Version(), runtime.GOOS, runtime.GOARCH)
+			data, err := client.Download(ctx, release, runtime.GOOS, runtime.GOARCH)
+			if err != nil {
+				return fmt.Errorf("update failed: %w", err)
+			}
+			if !client.SkipSignature {
+				fmt.Println("✓ Signature and checksum verified")
+			} else {
+				fmt.Println("✓ Checksum verified")
+			}
+			if err := update.Install(exe, data); err != nil {
+				return fmt.Errorf("failed to install %s: %w", exe, err)
+			}
+			fmt.Printf("✅ Updated zen-claw %s → %s\n", version.Version, release.Version())
+
+			if state, _ := gateway.ReadRunState(gateway.DefaultRunDir()); state != nil {
+				fmt.Printf("   The gateway (PID %d) still runs the old version: zen-claw gateway restart\n", state.PID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&channel, "channel", "", "Release channel: stable or beta (default: update.channel)")
+	cmd.Flags().BoolVar(&checkOnly, "check", false, "Only report whether a newer release exists")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall even if already on the latest release")
+	return cmd
+}
+
+// hintNewRelease prints a one-line hint when the last background check found
+// a newer release, and refreshes that check once a day. It never blocks the
+// command: the refreshed result is shown on a later run.
+func hintNewRelease(cmd *cobra.Command) {
+	if cmd.Name() == "update" || os.Getenv("ZEN_CLAW_NO_UPDATE_CHECK") != "" {
+		return
+	}
+	// Only for people at a terminal: not in scripts, or the gateway's log
+	if stat, err := os.Stderr.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	cfg, err := config.LoadConfig("")
+	if err != nil || cfg.Update.DisableCheck {
+		return
+	}
+	channel := cfg.Update.Channel
+	if channel == "" {
+		channel = update.ChannelStable
+	}
+
+	path := update.DefaultCheckPath()
+	last := update.ReadCheck(path)
+	if last != nil && last.Channel == channel && last.Latest != "" && update.Compare(last.Latest, version.Version) > 0 {
+		fmt.Fprintf(os.Stderr, "💡 zen-claw %s is available (you have %s): zen-claw update\n", last.Latest, version.Version)
+	}
+	if last.Stale(channel) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			update.NewClient("").Check(ctx, path, channel)
+		}()
+	}
+}
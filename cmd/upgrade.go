@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/migrate"
+	"github.com/neves/zen-claw/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// upgradeWorklistMax is the errors shown to the agent per round; the
+// compiler stops at 10 per package anyway, the next build lists the rest
+const upgradeWorklistMax = 60
+
+// upgradeStallRounds stops the loop after rounds that didn't reduce the errors
+const upgradeStallRounds = 2
+
+// UpgradeRound is one fix round of an upgrade
+type UpgradeRound struct {
+	Round        int         `json:"round"`
+	ErrorsBefore int         `json:"errors_before"`
+	ErrorsAfter  int         `json:"errors_after"`
+	StepLimit    bool        `json:"step_limit,omitempty"` // The agent stopped at --max-steps
+	Usage        types.Usage `json:"usage"`
+}
+
+// BreakingChange is how a breaking change of the dependency was handled
+type BreakingChange struct {
+	Change   string   `json:"change"`
+	Handling string   `json:"handling"`
+	Files    []string `json:"files"`
+}
+
+// UpgradeSummary is the agent's account of the upgrade
+type UpgradeSummary struct {
+	BreakingChanges []BreakingChange `json:"breaking_changes"`
+	FollowUps       []string         `json:"follow_ups"`
+}
+
+// UpgradeReport is the outcome of zen-claw upgrade
+type UpgradeReport struct {
+	Bump          *migrate.Bump          `json:"bump"`
+	SessionID     string                 `json:"session_id,omitempty"`
+	InitialErrors int                    `json:"initial_errors"`
+	Rounds        []UpgradeRound         `json:"rounds"`
+	Remaining     []migrate.CompileError `json:"remaining,omitempty"` // Errors left when the loop stopped
+	Stopped       string                 `json:"stopped"`             // builds, max-errors, rounds, budget, stalled or worklist-only
+	Usage         types.Usage            `json:"usage"`
+	Summary       *UpgradeSummary        `json:"summary,omitempty"`
+}
+
+// upgradeSummarySchema is the shape of the summary
+var upgradeSummarySchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"breaking_changes": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"change":   map[string]interface{}{"type": "string", "description": "The API change in the dependency, e.g. Client.Do now takes a context"},
+					"handling": map[string]interface{}{"type": "string", "description": "How the call sites were adapted"},
+					"files":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				},
+				"required": []string{"change", "handling", "files"},
+			},
+		},
+		"follow_ups": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Behavior changes to check, deprecations left in place, errors not fixed",
+		},
+	},
+	"required": []string{"breaking_changes", "follow_ups"},
+}
+
+func newUpgradeCmd() *cobra.Command {
+	var workingDir, out, sessionID, provider, model string
+	var rounds, maxSteps, maxErrors int
+	var budget float64
+	var worklistOnly, asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "upgrade <module>@<version>",
+		Short: "Upgrade a Go dependency and fix the code it breaks",
+		Long: `Bump a Go dependency with go get, build the code and its tests, and turn
+the compiler errors into a worklist of call sites. The agent fixes them in
+rounds, reading the old and new sources of the module from the module
+cache; after each round the build runs again and the remaining errors
+become the next worklist. When the build passes, the agent summarizes the
+breaking changes and how each was handled.
+
+The loop stops when the build passes or at a guardrail:
+  --rounds      fix rounds (each up to --max-steps tool steps)
+  --budget      estimated spend in USD, checked between rounds
+  --max-errors  refuse to start on a larger worklist (upgrade in smaller steps)
+  and after ` + fmt.Sprint(upgradeStallRounds) + ` rounds that don't reduce the errors.
+
+go.mod and the fixes stay in the working tree for you to review; nothing is
+committed. --worklist-only bumps and prints the worklist without the agent.`,
+		Example: `  zen-claw upgrade github.com/spf13/cobra@v1.9.0
+  zen-claw upgrade github.com/redis/go-redis/v9@latest --budget 0.50
+  zen-claw upgrade k8s.io/client-go@v0.31.0 --worklist-only`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := migrate.ParseTarget(args[0])
+			if err != nil {
+				return err
+			}
+			if abs, err := filepath.Abs(agent.ExpandPath(workingDir)); err == nil {
+				workingDir = abs
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			say := func(format string, a ...interface{}) {
+				if !asJSON {
+					fmt.Printf(format, a...)
+				}
+			}
+
+			say("⬆️  go get %s\n", target)
+			bump, err := migrate.Upgrade(ctx, workingDir, target)
+			if err != nil {
+				return err
+			}
+			if bump.From == bump.To {
+				say("   %s is already at %s\n", bump.Module, bump.To)
+			} else {
+				say("   %s %s → %s\n", bump.Module, orNone(bump.From), bump.To)
+			}
+
+			report := &UpgradeReport{Bump: bump, Rounds: []UpgradeRound{}}
+			say("🔨 Building\n")
+			errs, _, err := migrate.Build(ctx, workingDir)
+			if err != nil {
+				return err
+			}
+			report.InitialErrors, report.Remaining = len(errs), errs
+			say("   %d compile errors in %d files\n", len(errs), len(migrate.Worklist(errs)))
+
+			switch {
+			case len(errs) == 0:
+				report.Stopped = "builds"
+			case worklistOnly:
+				report.Stopped = "worklist-only"
+			case maxErrors > 0 && len(errs) > maxErrors:
+				report.Stopped = "max-errors"
+			default:
+				if errs, err = runUpgradeRounds(ctx, report, errs, workingDir, sessionID, provider, model, rounds, maxSteps, budget, asJSON); err != nil {
+					return err
+				}
+				report.Remaining = errs
+				if report.SessionID != "" {
+					if report.Summary, err = summarizeUpgrade(report, workingDir, provider, model, asJSON); err != nil {
+						say("⚠️  %v\n", err)
+					}
+				}
+			}
+
+			markdown := formatUpgradeReport(report, maxErrors)
+			if out != "" {
+				if err := os.WriteFile(out, []byte(markdown), 0644); err != nil {
+					return err
+				}
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			fmt.Println("\n" + strings.Repeat("═", 80))
+			fmt.Print(markdown)
+			fmt.Println(strings.Repeat("═", 80))
+			if out != "" {
+				fmt.Printf("✓ Wrote %s\n", out)
+			}
+			if report.Stopped == "builds" {
+				fmt.Println("Review the changes (git diff) and run the tests before committing")
+			} else if report.SessionID != "" {
+				fmt.Printf("Continue in the session: zen-claw agent --session %s\n", report.SessionID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workingDir, "working-dir", ".", "Go module to upgrade")
+	cmd.Flags().IntVar(&rounds, "rounds", 6, "Maximum fix rounds (build, fix, rebuild)")
+	cmd.Flags().IntVar(&maxSteps, "max-steps", 25, "Maximum tool execution steps per round")
+	cmd.Flags().Float64Var(&budget, "budget", 0, "Estimated spend limit in USD, checked between rounds (0 = none)")
+	cmd.Flags().IntVar(&maxErrors, "max-errors", 300, "Don't start the agent on more compile errors than this (0 = no limit)")
+	cmd.Flags().BoolVar(&worklistOnly, "worklist-only", false, "Bump and print the worklist without fixing anything")
+	cmd.Flags().StringVar(&out, "out", "", "Write the report (markdown) to this file")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	cmd.Flags().StringVar(&sessionID, "session", "", "Named session for the fix rounds (omit for fresh context)")
+	cmd.Flags().StringVar(&provider, "provider", "", "AI provider (default: the gateway's)")
+	cmd.Flags().StringVar(&model, "model", "", "AI model (default: the provider's)")
+	return cmd
+}
+
+// runUpgradeRounds has the agent fix the worklist until the build passes
+// or a guardrail stops it, and returns the errors left
+func runUpgradeRounds(ctx context.Context, report *UpgradeReport, errs []migrate.CompileError, workingDir, sessionID, provider, model string, rounds, maxSteps int, budget float64, quiet bool) ([]migrate.CompileError, error) {
+	client := NewGatewayClient(getGatewayURL())
+	if err := ensureGateway(client); err != nil {
+		return errs, err
+	}
+	uiLang := configuredLanguage()
+	stalled := 0
+	for round := 1; ; round++ {
+		switch {
+		case len(errs) == 0:
+			report.Stopped = "builds"
+		case round > rounds:
+			report.Stopped = "rounds"
+		case budget > 0 && report.Usage.CostUSD >= budget:
+			report.Stopped = "budget"
+		case stalled >= upgradeStallRounds:
+			report.Stopped = "stalled"
+		}
+		if report.Stopped != "" {
+			return errs, nil
+		}
+
+		if !quiet {
+			fmt.Printf("\n🔧 Round %d: %d errors\n", round, len(errs))
+		}
+		prompt := upgradeRoundPrompt(report.Bump, errs)
+		if round == 1 {
+			prompt = upgradeFirstPrompt(report.Bump, errs)
+		}
+		var usage types.Usage
+		resp, err := client.SendWithProgress(ChatRequest{
+			SessionID:  sessionID,
+			UserInput:  prompt,
+			WorkingDir: workingDir,
+			Provider:   provider,
+			Model:      model,
+			MaxSteps:   maxSteps,
+			Tags:       []string{"upgrade"},
+		}, func(event ProgressEvent) {
+			if event.Total != nil {
+				usage = *event.Total
+			}
+			if !quiet {
+				displayProgressEvent(event, uiLang)
+			}
+		})
+		if err != nil {
+			return errs, fmt.Errorf("gateway request failed: %w", err)
+		}
+		if resp.Error != "" {
+			return errs, fmt.Errorf("round %d failed: %s", round, resp.Error)
+		}
+		// Later rounds continue the conversation: the agent keeps what it learned of the API
+		sessionID, report.SessionID = resp.SessionID, resp.SessionID
+
+		before := len(errs)
+		if errs, _, err = migrate.Build(ctx, workingDir); err != nil {
+			return errs, err
+		}
+		report.Usage = report.Usage.Add(usage)
+		report.Rounds = append(report.Rounds, UpgradeRound{
+			Round: round, ErrorsBefore: before, ErrorsAfter: len(errs),
+			StepLimit: resp.StepLimit != nil, Usage: usage,
+		})
+		if len(errs) < before {
+			stalled = 0
+		} else {
+			stalled++
+		}
+		if !quiet {
+			fmt.Printf("   %d → %d errors (%s)\n", before, len(errs), usage)
+		}
+	}
+}
+
+// upgradeFirstPrompt starts the fix rounds: the upgrade, where to read the
+// module's sources and the rules of the loop
+func upgradeFirstPrompt(bump *migrate.Bump, errs []migrate.CompileError) string {
+	var sources strings.Builder
+	if bump.OldDir != "" {
+		fmt.Fprintf(&sources, "- Old version (%s): %s\n", bump.From, bump.OldDir)
+	}
+	if bump.NewDir != "" {
+		fmt.Fprintf(&sources, "- New version (%s): %s\n", bump.To, bump.NewDir)
+	}
+	if sources.Len() == 0 {
+		sources.WriteString("- Not in the module cache; use go doc\n")
+	}
+	return fmt.Sprintf(`We're upgrading the Go dependency %s from %s to %s. go.mod is already updated; the build now fails.
+
+Sources of the module (read-only, for comparing APIs; look for a CHANGELOG or migration guide there too):
+%s
+Worklist of compile errors, by file:
+%s
+Fix the call sites:
+1. For each error, find out what changed in the dependency's API (read the old and new declarations, go doc %s.<Symbol>) before editing.
+2. Adapt the call sites to the new API, keeping the behavior. When a change affects many sites, fix them consistently.
+3. Check your fixes with go build ./... and go vet ./... as you go.
+
+Rules: don't edit go.mod/go.sum or downgrade the module, don't touch the module cache, and don't delete or skip code or tests to silence errors. If something can't be fixed without a decision (a removed feature, a behavior change), leave a TODO comment and say so.
+
+End with a short note of each breaking change you handled.
+`, bump.Module, orNone(bump.From), bump.To, sources.String(), migrate.Markdown(migrate.Worklist(errs), upgradeWorklistMax), bump.Module)
+}
+
+// upgradeRoundPrompt continues the fix rounds with the errors left
+func upgradeRoundPrompt(bump *migrate.Bump, errs []migrate.CompileError) string {
+	return fmt.Sprintf(`The build of the %s upgrade still fails. Remaining compile errors, by file:
+%s
+Continue fixing them with the same rules. If an error is caused by your previous fixes, correct them.
+`, bump.Module, migrate.Markdown(migrate.Worklist(errs), upgradeWorklistMax))
+}
+
+// summarizeUpgrade asks the agent (read-only, in the fix session) for the
+// breaking changes it handled
+func summarizeUpgrade(report *UpgradeReport, workingDir, provider, model string, quiet bool) (*UpgradeSummary, error) {
+	client := NewGatewayClient(getGatewayURL())
+	var usage types.Usage
+	resp, err := client.SendWithProgress(ChatRequest{
+		SessionID: report.SessionID,
+		UserInput: fmt.Sprintf(`The upgrade loop stopped (%s, %d compile errors left). Summarize the upgrade of %s to %s: each breaking change of the dependency you handled, how, and in which files (check git diff). List as follow-ups what needs a human: behavior changes to verify, deprecations left in place, errors not fixed.`,
+			report.Stopped, len(report.Remaining), report.Bump.Module, report.Bump.To),
+		WorkingDir:     workingDir,
+		Provider:       provider,
+		Model:          model,
+		MaxSteps:       5,
+		ResponseSchema: upgradeSummarySchema,
+		ReadOnly:       true,
+	}, func(event ProgressEvent) {
+		if event.Total != nil {
+			usage = *event.Total
+		}
+	})
+	report.Usage = report.Usage.Add(usage)
+	if err != nil {
+		return nil, fmt.Errorf("summary request failed: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("summary failed: %s", resp.Error)
+	}
+	var summary UpgradeSummary
+	if err := json.Unmarshal(resp.Output, &summary); err != nil {
+		if !quiet {
+			fmt.Println(resp.Result)
+		}
+		return nil, fmt.Errorf("the agent returned no summary")
+	}
+	return &summary, nil
+}
+
+// formatUpgradeReport renders the report as markdown
+func formatUpgradeReport(report *UpgradeReport, maxErrors int) string {
+	var b strings.Builder
+	bump := report.Bump
+	fmt.Fprintf(&b, "# Upgrade of %s: %s → %s\n\n", bump.Module, orNone(bump.From), bump.To)
+	switch report.Stopped {
+	case "builds":
+		if len(report.Rounds) == 0 {
+			b.WriteString("The code builds with the new version: nothing to fix.\n")
+		} else {
+			fmt.Fprintf(&b, "✓ Fixed %d compile errors in %d rounds.\n", report.InitialErrors, len(report.Rounds))
+		}
+	case "worklist-only":
+		fmt.Fprintf(&b, "%d compile errors to fix.\n", report.InitialErrors)
+	case "max-errors":
+		fmt.Fprintf(&b, "⚠️  %d compile errors, more than --max-errors %d: try an intermediate version first.\n", report.InitialErrors, maxErrors)
+	default:
+		reasons := map[string]string{
+			"rounds":  "the last round (--rounds)",
+			"budget":  "the budget (--budget)",
+			"stalled": fmt.Sprintf("%d rounds without progress", upgradeStallRounds),
+		}
+		fmt.Fprintf(&b, "⚠️  Stopped at %s with %d of %d compile errors left.\n", reasons[report.Stopped], len(report.Remaining), report.InitialErrors)
+	}
+
+	if len(report.Rounds) > 0 {
+		b.WriteString("\n## Rounds\n\n| Round | Errors | Cost |\n|---|---|---|\n")
+		for _, r := range report.Rounds {
+			limit := ""
+			if r.StepLimit {
+				limit = " (step limit)"
+			}
+			fmt.Fprintf(&b, "| %d | %d → %d%s | %s |\n", r.Round, r.ErrorsBefore, r.ErrorsAfter, limit, r.Usage)
+		}
+		fmt.Fprintf(&b, "\nTotal: %s\n", report.Usage)
+	}
+	if s := report.Summary; s != nil {
+		if len(s.BreakingChanges) > 0 {
+			b.WriteString("\n## Breaking changes\n")
+			for _, c := range s.BreakingChanges {
+				fmt.Fprintf(&b, "\n- **%s**: %s", c.Change, c.Handling)
+				if len(c.Files) > 0 {
+					fmt.Fprintf(&b, " (%s)", strings.Join(c.Files, ", "))
+				}
+			}
+			b.WriteString("\n")
+		}
+		if len(s.FollowUps) > 0 {
+			b.WriteString("\n## Follow-ups\n\n")
+			for _, f := range s.FollowUps {
+				fmt.Fprintf(&b, "- %s\n", f)
+			}
+		}
+	}
+	if len(report.Remaining) > 0 {
+		b.WriteString("\n## Worklist\n\n")
+		b.WriteString(migrate.Markdown(migrate.Worklist(report.Remaining), 0))
+	}
+	return b.String()
+}
+
+// orNone shows a missing version
+func orNone(version string) string {
+	if version == "" {
+		return "(none)"
+	}
+	return version
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/gateway"
+	"github.com/spf13/cobra"
+)
+
+func newUsageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report model usage and cost",
+		Long:  `Report the model usage and estimated cost recorded by the gateway.`,
+	}
+
+	cmd.AddCommand(newUsageReportCmd())
+	cmd.AddCommand(newUsageExportCmd())
+	return cmd
+}
+
+// openUsageStore opens the session database the gateway records usage in
+func openUsageStore() (*gateway.SessionStore, error) {
+	cfg := loadConfigForSessions()
+	return gateway.NewSessionStore(&gateway.SessionStoreConfig{
+		DBPath:      cfg.GetSessionDBPath(),
+		MaxSessions: 100, // Just for reporting
+	})
+}
+
+func newUsageReportCmd() *cobra.Command {
+	var since string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Break cost and tokens down by provider, project and day",
+		Long: `Sum the model calls recorded by the gateway since a time, by provider/model,
+by project and by day. Costs are estimates from the gateway's price table.
+
+Examples:
+  zen-claw usage report
+  zen-claw usage report --since 30d
+  zen-claw usage report --since 24h --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			period, err := parseDuration(since)
+			if err != nil || period <= 0 {
+				return fmt.Errorf("invalid --since %q (use e.g. 7d or 12h)", since)
+			}
+
+			store, err := openUsageStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			report, err := store.UsageReport(time.Now().Add(-period))
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			printUsageReport(report, since)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "7d", "Report period (e.g. 7d, 30d, 12h)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	return cmd
+}
+
+func newUsageExportCmd() *cobra.Command {
+	var since, format, output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export usage records as CSV or JSON",
+		Long: `Write every model call recorded by the gateway since a time, one record per
+call (time, provider, model, project, session, tokens, estimated cost), for
+spreadsheets and cost-tracking tools.
+
+To send usage to a webhook or Prometheus pushgateway on a schedule instead,
+set usage.push in the config.
+
+Examples:
+  zen-claw usage export --since 30d > usage.csv
+  zen-claw usage export --since 7d --format json -o usage.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			period, err := parseDuration(since)
+			if err != nil || period <= 0 {
+				return fmt.Errorf("invalid --since %q (use e.g. 7d or 12h)", since)
+			}
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("invalid --format %q (use csv or json)", format)
+			}
+
+			store, err := openUsageStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			records, err := store.UsageRecords(time.Now().Add(-period), 0, 0)
+			if err != nil {
+				return err
+			}
+
+			out := os.Stdout
+			if output != "" && output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if format == "json" {
+				if records == nil {
+					records = []gateway.UsageRecord{}
+				}
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(records)
+			} else {
+				err = gateway.WriteUsageCSV(out, records)
+			}
+			if err != nil {
+				return err
+			}
+			if out != os.Stdout {
+				fmt.Fprintf(os.Stderr, "Exported %d records to %s\n", len(records), output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "30d", "Export period (e.g. 30d, 7d, 12h)")
+	cmd.Flags().StringVar(&format, "format", "csv", "Output format: csv or json")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write to a file instead of stdout")
+	return cmd
+}
+
+func printUsageReport(report *gateway.UsageReport, since string) {
+	fmt.Printf("Usage in the last %s (since %s)\n", since, report.Since.Format("2006-01-02 15:04"))
+	if report.Total.Calls == 0 {
+		fmt.Println("No model calls recorded")
+		return
+	}
+
+	section := func(title string, rows []gateway.UsageRow, label func(gateway.UsageRow) string) {
+		fmt.Printf("\n%s\n%s\n", title, strings.Repeat("─", 78))
+		fmt.Printf("  %-36s %7s %12s %10s %9s\n", "", "calls", "input", "output", "cost")
+		for _, row := range rows {
+			fmt.Printf("  %-36s %7d %12s %10s %9s\n", truncateString(label(row), 36), row.Calls,
+				formatTokens(row.InputTokens), formatTokens(row.OutputTokens), fmt.Sprintf("$%.4f", row.CostUSD))
+		}
+	}
+	section("By provider/model", report.ByModel, func(r gateway.UsageRow) string { return r.Provider + "/" + r.Model })
+	section("By project", report.ByProject, func(r gateway.UsageRow) string {
+		if r.Project == "" {
+			return "(no project)"
+		}
+		return r.Project
+	})
+	section("By day", report.ByDay, func(r gateway.UsageRow) string { return r.Day })
+
+	fmt.Println(strings.Repeat("─", 78))
+	fmt.Printf("  %-36s %7d %12s %10s %9s\n", "Total", report.Total.Calls,
+		formatTokens(report.Total.InputTokens), formatTokens(report.Total.OutputTokens), fmt.Sprintf("$%.4f", report.Total.CostUSD))
+}
+
+// formatTokens shortens a token count (1234567 -> 1.23M)
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.2fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // WSClient handles WebSocket communication with the gateway
@@ -35,6 +38,11 @@ type WSChatRequest struct {
 	Provider   string `json:"provider,omitempty"`
 	Model      string `json:"model,omitempty"`
 	MaxSteps   int    `json:"max_steps,omitempty"`
+
+	Env map[string]string `json:"env,omitempty"` // Session environment (values may be secret refs)
+
+	Tags    []string `json:"tags,omitempty"`    // Labels added to the session
+	Project string   `json:"project,omitempty"` // Overrides the project derived from the git remote
 }
 
 // NewWSClient creates a WebSocket client connection
@@ -42,6 +50,14 @@ func NewWSClient(url string) (*WSClient, error) {
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
 	}
+	// unix:///path: the handshake goes over the socket to the /ws endpoint
+	if network, addr := gatewayDialAddr(url); network == "unix" {
+		dialer.NetDialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+		url = "ws://unix/ws"
+	}
 
 	conn, _, err := dialer.Dial(url, nil)
 	if err != nil {
@@ -143,14 +159,8 @@ func (c *WSClient) Chat(req WSChatRequest, onProgress func(ProgressEvent), onRes
 	c.RegisterCallback(msgID, func(msg WSMessage) {
 		switch msg.Type {
 		case "progress":
-			var progressData map[string]interface{}
-			if err := json.Unmarshal(msg.Data, &progressData); err == nil {
-				event := ProgressEvent{
-					Type:    getString(progressData, "type"),
-					Step:    getInt(progressData, "step"),
-					Message: getString(progressData, "message"),
-					Data:    progressData["data"],
-				}
+			var event ProgressEvent
+			if err := json.Unmarshal(msg.Data, &event); err == nil {
 				if onProgress != nil {
 					onProgress(event)
 				}
@@ -174,12 +184,10 @@ func (c *WSClient) Chat(req WSChatRequest, onProgress func(ProgressEvent), onRes
 			close(done)
 
 		case "error":
-			var errData struct {
-				Error string `json:"error"`
-			}
+			var errData types.ErrorResponse
 			if err := json.Unmarshal(msg.Data, &errData); err == nil {
 				if onResult != nil {
-					onResult(nil, fmt.Errorf("%s", errData.Error))
+					onResult(nil, &types.Error{Code: errData.ErrorCode, Message: errData.Error})
 				}
 			}
 			close(done)
@@ -219,18 +227,3 @@ func (c *WSClient) Cancel() error {
 		Type: "cancel",
 	})
 }
-
-// Helper functions
-func getString(m map[string]interface{}, key string) string {
-	if v, ok := m[key].(string); ok {
-		return v
-	}
-	return ""
-}
-
-func getInt(m map[string]interface{}, key string) int {
-	if v, ok := m[key].(float64); ok {
-		return int(v)
-	}
-	return 0
-}
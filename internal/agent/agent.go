@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // AICaller interface for making AI calls
@@ -20,6 +22,13 @@ type AICaller interface {
 	ChatStream(ctx context.Context, req ai.ChatRequest, callback ai.StreamCallback) (*ai.ChatResponse, error)
 }
 
+// defaultModelParams are used for fields SetModelParams leaves unset
+var defaultModelParams = types.ModelParams{Temperature: 0.7, MaxTokens: 4000}
+
+// maxMalformedArgRetries caps consecutive steps where the model's tool
+// arguments could not be parsed (even after repair) before the run fails
+const maxMalformedArgRetries = 3
+
 // ToolResult represents the result of a tool execution
 type ToolResult struct {
 	ToolCallID string
@@ -30,13 +39,9 @@ type ToolResult struct {
 // ProgressCallback is called during agent execution to report progress
 type ProgressCallback func(event ProgressEvent)
 
-// ProgressEvent represents a progress event during agent execution
-type ProgressEvent struct {
-	Type    string      `json:"type"`    // "step", "thinking", "tool_call", "tool_result", "complete", "error"
-	Step    int         `json:"step"`    // Current step number
-	Message string      `json:"message"` // Human-readable message
-	Data    interface{} `json:"data,omitempty"`
-}
+// ProgressEvent represents a progress event during agent execution (the
+// gateway's typed schema, see types.ProgressSchemaVersion)
+type ProgressEvent = types.ProgressEvent
 
 // Agent is a minimal agent focused only on tool execution
 // Uses AICaller interface for AI communication
@@ -46,7 +51,20 @@ type Agent struct {
 	maxSteps         int
 	currentModel     string
 	progressCallback ProgressCallback
-	streamCallback   ai.StreamCallback // Token-by-token streaming
+	streamCallback   ai.StreamCallback      // Token-by-token streaming
+	runStart         time.Time              // Start of the current Run, for event timing
+	responseSchema   map[string]interface{} // Final answer must match (structured output)
+	middleware       []ToolMiddleware       // Wraps every tool call (see Use)
+	contextWindow    int                    // History tokens the model accepts (0 = unknown)
+	params           types.ModelParams      // Sampling settings for model calls
+	profile          PromptProfile          // Prompting adapted to the model family
+	language         string                 // Language tag answers are written in ("" = English)
+	instructions     string                 // Project instructions added to the system prompt (see SetInstructions)
+	stepLimitReached bool                   // The last Run ended at max steps (see stopAtStepLimit)
+	review           *SelfReview            // Review pass before finalizing changes (see SetSelfReview)
+	reviewOutcome    *types.ReviewOutcome   // The last Run's review
+	dirtyTree        string                 // Policy for the user's uncommitted changes (see SetDirtyTree)
+	userTree         *dirtyTree             // The user's uncommitted changes found before the current Run
 }
 
 // AgentEvent represents a progress event during agent execution (deprecated, use ProgressEvent)
@@ -70,6 +88,8 @@ func NewAgent(aiCaller AICaller, tools []Tool, maxSteps int) *Agent {
 		tools:        toolMap,
 		maxSteps:     maxSteps,
 		currentModel: "deepseek-chat", // Default model
+		params:       defaultModelParams,
+		profile:      PromptProfiles["chat"],
 	}
 }
 
@@ -83,22 +103,47 @@ func (a *Agent) SetStreamCallback(cb ai.StreamCallback) {
 	a.streamCallback = cb
 }
 
+// SetModelParams sets temperature, max tokens and top_p for model calls;
+// unset fields keep the defaults (temperature 0.7, 4000 tokens)
+func (a *Agent) SetModelParams(params types.ModelParams) {
+	a.params = defaultModelParams.Merge(params)
+}
+
 // emitProgress sends a progress event if callback is set
 func (a *Agent) emitProgress(eventType string, step int, message string, data interface{}) {
-	if a.progressCallback != nil {
-		a.progressCallback(ProgressEvent{
-			Type:    eventType,
-			Step:    step,
-			Message: message,
-			Data:    data,
-		})
-	}
+	a.emit(ProgressEvent{
+		Type:    eventType,
+		Step:    step,
+		Message: message,
+		Data:    data,
+	})
 }
 
 // Run executes a task with the given session
 // Returns updated session and final result
-func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*Session, string, error) {
+func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (updated *Session, result string, err error) {
+	// The user's stashed changes come back however the run ends
+	defer func() {
+		if note := a.restoreDirtyTree(ctx, a.userTree); note != "" {
+			result = strings.TrimSpace(result + "\n\n⚠️ " + note)
+		}
+	}()
+	// A crash ends the run, not the request or the gateway (see panics.go)
+	defer func() {
+		if r := recover(); r != nil {
+			updated, result, err = session, "", a.recoverStep(session, r)
+		}
+	}()
+	return a.run(ctx, session, userInput)
+}
+
+// run is Run without the crash recovery
+func (a *Agent) run(ctx context.Context, session *Session, userInput string) (*Session, string, error) {
 	log.Printf("[Agent] Running: %s", userInput)
+	a.runStart = time.Now()
+	a.stepLimitReached = false
+	a.reviewOutcome = nil
+	a.userTree = nil
 
 	// Handle model switching commands
 	if userInput == "/models" {
@@ -134,32 +179,29 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 		return session, fmt.Sprintf("Model switched to: %s", model), nil
 	}
 
-	// Handle context limit command: /context-limit <number> or /context-limit (show current)
+	// Handle context limit command: /context-limit <tokens> or /context-limit (show current)
 	if strings.HasPrefix(userInput, "/context-limit") {
 		parts := strings.Fields(userInput)
 		if len(parts) == 1 {
 			// Show current limit
 			limit := session.GetContextLimit()
 			if limit == 0 {
-				return session, "Context limit: unlimited (0)", nil
+				return session, "Context limit: model's context window (0)", nil
 			}
-			return session, fmt.Sprintf("Context limit: %d messages", limit), nil
+			return session, fmt.Sprintf("Context limit: %d tokens", limit), nil
 		} else if len(parts) == 2 {
 			// Set limit
-			var limit int
-			if _, err := fmt.Sscanf(parts[1], "%d", &limit); err != nil {
-				return session, fmt.Sprintf("Invalid context limit: %s. Use a number or 0 for unlimited.", parts[1]), nil
-			}
-			if limit < 0 {
-				return session, "Context limit must be >= 0 (0 = unlimited)", nil
+			limit, err := ParseTokenCount(parts[1])
+			if err != nil {
+				return session, fmt.Sprintf("Invalid context limit: %s. Use a token count like 32000 or 32k, or 0 for the model's window.", parts[1]), nil
 			}
 			session.SetContextLimit(limit)
 			if limit == 0 {
-				return session, "Context limit set to: unlimited (0)", nil
+				return session, "Context limit set to: model's context window (0)", nil
 			}
-			return session, fmt.Sprintf("Context limit set to: %d messages", limit), nil
+			return session, fmt.Sprintf("Context limit set to: %d tokens (oldest messages beyond this are summarized)", limit), nil
 		} else {
-			return session, "Usage: /context-limit [number] (0 = unlimited, default 50)", nil
+			return session, "Usage: /context-limit [tokens] (e.g. 32k; 0 = model's context window, the default)", nil
 		}
 	}
 
@@ -209,33 +251,96 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 		Content: userInput,
 	})
 
+	// Make the session available to tools for per-session state (e.g. file read hashes),
+	// and the model to the tools that call it
+	ctx = WithSession(ctx, session)
+	ctx = WithModel(ctx, a.aiCaller, a.currentModel)
+
+	// The user's work in progress is left alone (or stashed for the run)
+	a.userTree = a.checkDirtyTree(ctx, session)
+
+	// Files the session has seen may be edited by someone else meanwhile
+	watcher := newFileWatcher(session)
+	defer watcher.close()
+
+	// Snapshot the tree the review diffs against
+	runStartMessage := len(session.GetMessages())
+	var baseline *reviewBaseline
+	if a.review != nil {
+		baseline = newReviewBaseline(ctx, BaseDir(ctx, ""))
+	}
+
 	// Execute agent loop
+	malformedSteps := 0
+	reviews := 0
+	loops := newLoopDetector()
 	for step := 0; step < a.maxSteps; step++ {
 		stepNum := step + 1
+		if canceled(ctx) {
+			return session, "", a.stopCanceled(ctx, session, stepNum)
+		}
 		log.Printf("[Agent] Step %d", stepNum)
 		a.emitProgress("step", stepNum, fmt.Sprintf("Step %d/%d: Thinking...", stepNum, a.maxSteps), nil)
+		watcher.watch()
+		a.noteFileChanges(session, stepNum, watcher.changes())
 
 		// Get AI response
 		a.emitProgress("thinking", stepNum, "Waiting for AI response...", nil)
+		callStart := time.Now()
 		resp, err := a.getAIResponse(ctx, session)
+		if err != nil && canceled(ctx) {
+			return session, "", a.stopCanceled(ctx, session, stepNum)
+		}
 		if err != nil {
 			a.emitProgress("error", stepNum, fmt.Sprintf("AI error: %v", err), nil)
 			return session, "", fmt.Errorf("AI response failed: %w", err)
 		}
-
-		// Parse tool calls from response content (text-based tool calling)
-		toolCalls := a.parseToolCallsFromText(resp.Content)
+		callMs := time.Since(callStart).Milliseconds()
+		usage := callUsage(session.GetMessages(), resp)
+
+		// Parse tool calls from response content (text-based tool calling);
+		// with native calls, call blocks in the text are echoes
+		var toolCalls []ai.ToolCall
+		if len(resp.ToolCalls) == 0 {
+			toolCalls = a.parseToolCallsFromText(resp.Content)
+		}
 
 		// If no tool calls, we're done
 		if len(toolCalls) == 0 && len(resp.ToolCalls) == 0 {
+			final := resp.Content
+
+			// Self-review: fix instructions go back into the loop
+			if a.review != nil {
+				reviews++
+				fix := a.selfReview(ctx, userInput, final, baseline, session.GetMessages()[runStartMessage:], stepNum, reviews)
+				if fix != "" && reviews <= a.review.maxRounds() {
+					session.AddMessage(ai.Message{Role: "assistant", Content: final})
+					session.AddMessage(ai.Message{Role: "user", Content: fix})
+					continue
+				}
+				if fix != "" {
+					a.emitProgress("review", stepNum, fmt.Sprintf("Review still found issues after %d fix round(s)", a.review.maxRounds()), nil)
+				}
+			}
+			if a.responseSchema != nil {
+				if final, err = a.finalizeStructured(ctx, session, final, stepNum); err != nil {
+					a.emitProgress("error", stepNum, err.Error(), nil)
+					return session, "", err
+				}
+			}
 			session.AddMessage(ai.Message{
 				Role:    "assistant",
-				Content: resp.Content,
+				Content: final,
 			})
-			a.emitProgress("complete", stepNum, "Task completed", map[string]interface{}{
-				"total_steps": stepNum,
+			a.emit(ProgressEvent{
+				Type:       "complete",
+				Step:       stepNum,
+				Message:    "Task completed",
+				Data:       map[string]interface{}{"total_steps": stepNum},
+				DurationMs: time.Since(a.runStart).Milliseconds(),
+				Usage:      usage,
 			})
-			return session, resp.Content, nil
+			return session, final, nil
 		}
 
 		// Combine structured tool calls with parsed text tool calls
@@ -247,9 +352,18 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 		// Clean content - remove XML tool call tags for cleaner display
 		cleanedContent := a.cleanToolCallTags(resp.Content)
 
-		// Emit AI response if there's content
-		if cleanedContent != "" {
-			a.emitProgress("ai_response", stepNum, cleanedContent, nil)
+		// Emit AI response (the message is empty when the model only called tools)
+		a.emit(ProgressEvent{Type: "ai_response", Step: stepNum, Message: cleanedContent, DurationMs: callMs, Usage: usage})
+
+		// Unparseable arguments are answered with an error asking for a resend; give up if it keeps happening
+		if hasMalformedArgs(allToolCalls) {
+			malformedSteps++
+			if malformedSteps > maxMalformedArgRetries {
+				a.emitProgress("error", stepNum, "Model keeps sending malformed tool arguments", nil)
+				return session, "", types.Errorf(types.ErrInvalidArgument, "model sent malformed tool arguments %d steps in a row", malformedSteps)
+			}
+		} else {
+			malformedSteps = 0
 		}
 
 		log.Printf("[Agent] Executing %d tool calls (%d from text parsing)", len(allToolCalls), len(toolCalls))
@@ -268,11 +382,16 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 			ToolCalls: allToolCalls,
 		})
 
-		// Add tool results to session
+		// Add tool results to session; large ones go to the result store
+		// so they stay retrievable when trimmed from context
+		toolNames := make(map[string]string, len(allToolCalls))
+		for _, call := range allToolCalls {
+			toolNames[call.ID] = call.Name
+		}
 		for _, result := range toolResults {
 			session.AddMessage(ai.Message{
 				Role:       "tool",
-				Content:    result.Content,
+				Content:    referenceToolResult(session, toolNames[result.ToolCallID], result.Content),
 				ToolCallID: result.ToolCallID,
 			})
 
@@ -284,14 +403,32 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 				if err := json.Unmarshal([]byte(result.Content), &toolResult); err == nil {
 					// Check for new_working_dir field
 					if newDir, ok := toolResult["new_working_dir"].(string); ok && newDir != "" {
-						session.SetWorkingDir(newDir)
-						log.Printf("[Agent] Updated session working directory to: %s", newDir)
+						if err := GetWorkingDirRoots().Check(newDir); err != nil {
+							log.Printf("[Agent] Kept session working directory: %v", err)
+						} else {
+							session.SetWorkingDir(newDir)
+							log.Printf("[Agent] Updated session working directory to: %s", newDir)
+						}
 					}
 				}
 			}
 		}
 
 		log.Printf("[Agent] Added %d tool results, continuing...", len(toolResults))
+		if canceled(ctx) {
+			return session, "", a.stopCanceled(ctx, session, stepNum)
+		}
+
+		// Repeated or oscillating calls: point it out once, then stop
+		switch action, desc := loops.observe(allToolCalls, toolResults, a.summarizeArgs); action {
+		case loopIntervene:
+			log.Printf("[Agent] Loop detected at step %d: %s", stepNum, desc)
+			a.emitProgress("loop_detected", stepNum, "Loop detected: "+desc, map[string]interface{}{"action": "intervene"})
+			session.AddMessage(ai.Message{Role: "user", Content: loopIntervention(desc)})
+		case loopHalt:
+			a.emitProgress("loop_detected", stepNum, "Stopped in a loop: "+desc, map[string]interface{}{"action": "halt"})
+			return session, "", types.Errorf(types.ErrBudgetExceeded, "agent stopped in a loop at step %d: %s, and kept on after a warning. Check the last tool results, then rephrase the task or provide what is missing", stepNum, desc)
+		}
 
 		// Check if we should stop early (e.g., task completed)
 		if a.shouldStopEarly(cleanedContent, toolResults) {
@@ -302,6 +439,11 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 				return session, "", fmt.Errorf("final AI response failed: %w", err)
 			}
 			finalCleaned := a.cleanToolCallTags(finalResp.Content)
+			if a.responseSchema != nil {
+				if finalCleaned, err = a.finalizeStructured(ctx, session, finalCleaned, stepNum); err != nil {
+					return session, "", err
+				}
+			}
 			session.AddMessage(ai.Message{
 				Role:    "assistant",
 				Content: finalCleaned,
@@ -310,7 +452,8 @@ func (a *Agent) Run(ctx context.Context, session *Session, userInput string) (*S
 		}
 	}
 
-	return session, "", fmt.Errorf("exceeded maximum steps (%d). For complex tasks: 1) Try --max-steps 200 for large refactoring, 2) Break task into smaller pieces, 3) Use /context-limit to reduce context if AI is exploring too much", a.maxSteps)
+	result, err := a.stopAtStepLimit(ctx, session)
+	return session, result, err
 }
 
 // getAIResponse gets a response from the AI caller with session messages
@@ -319,19 +462,28 @@ func (a *Agent) getAIResponse(ctx context.Context, session *Session) (*ai.ChatRe
 	// Models like Qwen 3 Coder (262K), Gemini 3 Flash (1M) can handle long conversations
 	messages := session.GetMessages()
 
-	// Convert tools to AI tool definitions
-	toolDefs := a.getToolDefinitions()
+	// Convert tools to AI tool definitions (text-tool models get them in the prompt)
+	var toolDefs []ai.Tool
+	if !a.profile.TextTools {
+		toolDefs = a.getToolDefinitions()
+	}
 
 	// Create chat request with generous max tokens for complex responses
 	req := ai.ChatRequest{
 		Model:                   a.currentModel, // Use current model
 		Messages:                messages,
 		Tools:                   toolDefs,
-		Temperature:             0.7,
-		MaxTokens:               4000, // Increased for complex analysis tasks
+		Temperature:             a.params.Temperature,
+		MaxTokens:               a.params.MaxTokens,
+		TopP:                    a.params.TopP,
 		ContextLimit:            session.GetContextLimit(),
 		QwenLargeContextEnabled: session.GetQwenLargeContextEnabled(),
 	}
+	if a.responseSchema != nil {
+		req.Messages = withSchemaInstruction(messages, a.responseSchema)
+	}
+	req.Messages = withDirtyTreeNote(req.Messages, a.userTree)
+	req.Messages = a.applyProfile(req.Messages)
 
 	// Per-step timeout: Each AI call gets its own generous timeout
 	// This is per-step, not per-task, so large tasks with many steps work fine.
@@ -354,7 +506,7 @@ func (a *Agent) getAIResponse(ctx context.Context, session *Session) (*ai.ChatRe
 	if err != nil {
 		// Check if it's a context deadline exceeded error
 		if ctx.Err() == context.DeadlineExceeded || stepCtx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("AI response timed out after 5 minutes (this step). Large models with long context may need more time. Try reducing context with /context-limit command")
+			return nil, types.Errorf(types.ErrTimeout, "AI response timed out after 5 minutes (this step). Large models with long context may need more time. Try reducing context with /context-limit command")
 		}
 		return nil, err
 	}
@@ -377,12 +529,51 @@ func isReadOnlyTool(name string) bool {
 	return readOnly[name]
 }
 
+// inspectionTools look at the workspace without changing it or reaching
+// outside it (a read-only run may be driven by untrusted input)
+var inspectionTools = map[string]bool{
+	"read_file":       true,
+	"list_dir":        true,
+	"search_files":    true,
+	"system_info":     true,
+	"git_status":      true,
+	"git_diff":        true,
+	"git_log":         true,
+	"code_search":     true,
+	"find_symbol":     true,
+	"get_context":     true,
+	"find_definition": true,
+	"find_references": true,
+	"diagnostics":     true,
+	"expand_result":   true,
+	"preview_write":   true,
+	"preview_edit":    true,
+	"k8s":             true,
+	"runbook_search":  true,
+}
+
+// ReadOnlyTools returns the tools a read-only run may use
+func ReadOnlyTools(tools []Tool) []Tool {
+	var kept []Tool
+	for _, tool := range tools {
+		if inspectionTools[tool.Name()] {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
+
 // executeToolCallsWithProgress executes tool calls with parallel execution for read-only tools
 func (a *Agent) executeToolCallsWithProgress(ctx context.Context, toolCalls []ai.ToolCall, step int) ([]ToolResult, error) {
 	if len(toolCalls) == 0 {
 		return nil, nil
 	}
 
+	// The model's depends_on hints, when given, replace the default schedule
+	if calls, deps, hinted := planToolCalls(ctx, toolCalls); hinted {
+		return a.executeWithDependencies(ctx, calls, deps, step), nil
+	}
+
 	// Separate into parallel-safe (read-only) and sequential (write) tools
 	var parallelCalls []ai.ToolCall
 	var sequentialCalls []ai.ToolCall
@@ -438,30 +629,129 @@ func (a *Agent) executeToolCallsWithProgress(ctx context.Context, toolCalls []ai
 	return results, nil
 }
 
+// hasMalformedArgs reports whether any call carries arguments it can't run with
+func hasMalformedArgs(calls []ai.ToolCall) bool {
+	for _, call := range calls {
+		if _, ok := call.Args["_raw"]; ok {
+			return true
+		}
+		if _, ok := call.Args["_repaired"]; ok && !inspectionTools[call.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutRepairMark returns args without the provider's "_repaired" mark
+func withoutRepairMark(args map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != "_repaired" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
 // executeSingleTool executes a single tool call and returns the result
-func (a *Agent) executeSingleTool(ctx context.Context, call ai.ToolCall, step int) ToolResult {
+func (a *Agent) executeSingleTool(ctx context.Context, call ai.ToolCall, step int) (res ToolResult) {
 	log.Printf("[Agent] Executing tool: %s", call.Name)
+	start := time.Now()
+
+	// A panicking tool (or middleware) fails this call, not the run
+	defer func() {
+		if r := recover(); r != nil {
+			res = a.panicToolResult(call, step, start, r)
+		}
+	}()
 
 	// Build argument summary for display
 	argSummary := a.summarizeArgs(call.Args)
 
+	// Canceled runs start no further tools
+	if canceled(ctx) {
+		a.emitTool(step, call, argSummary, start, types.ToolStatusCanceled, "canceled", fmt.Sprintf("🔧 %s(%s) ⏹ skipped (canceled)", call.Name, argSummary))
+		return canceledToolResult(call)
+	}
+
 	tool, exists := a.tools[call.Name]
 	if !exists {
 		errMsg := fmt.Sprintf("Tool '%s' not found", call.Name)
-		a.emitProgress("tool_call", step, fmt.Sprintf("🔧 %s(%s) ❌ not found", call.Name, argSummary), nil)
+		a.emitTool(step, call, argSummary, start, types.ToolStatusNotFound, errMsg, fmt.Sprintf("🔧 %s(%s) ❌ not found", call.Name, argSummary))
+		errorJSON, _ := json.Marshal(map[string]interface{}{
+			"error":      errMsg,
+			"error_code": types.ErrNotFound,
+		})
 		return ToolResult{
 			ToolCallID: call.ID,
-			Content:    fmt.Sprintf("Error: %s", errMsg),
+			Content:    string(errorJSON),
 			IsError:    true,
 		}
 	}
 
-	// Execute tool
-	result, err := tool.Execute(ctx, call.Args)
+	// Arguments the provider could not parse (even after JSON repair): ask the model to resend
+	if raw, ok := call.Args["_raw"].(string); ok {
+		a.emitTool(step, call, "", start, types.ToolStatusInvalidArgs, "malformed arguments", fmt.Sprintf("🔧 %s ❌ malformed arguments", call.Name))
+		errorJSON, _ := json.Marshal(map[string]interface{}{
+			"error":      fmt.Sprintf("Arguments for %s are not valid JSON. Resend the tool call with a complete, valid JSON object.", call.Name),
+			"error_code": types.ErrInvalidArgument,
+			"received":   truncateString(raw, 200),
+		})
+		return ToolResult{
+			ToolCallID: call.ID,
+			Content:    string(errorJSON),
+			IsError:    true,
+		}
+	}
+
+	// Arguments that only parsed after repair may have been cut off (a
+	// truncated write_file content would be written as if complete): only
+	// tools that change nothing run with them
+	if raw, ok := call.Args["_repaired"].(string); ok {
+		if !inspectionTools[call.Name] {
+			a.emitTool(step, call, "", start, types.ToolStatusInvalidArgs, "incomplete arguments", fmt.Sprintf("🔧 %s ❌ incomplete arguments", call.Name))
+			errorJSON, _ := json.Marshal(map[string]interface{}{
+				"error":      fmt.Sprintf("Arguments for %s were malformed or cut off (e.g. by the output token limit) and could only be repaired by guessing, so nothing was run. Resend the complete tool call; split large content with begin_write/append_chunk.", call.Name),
+				"error_code": types.ErrInvalidArgument,
+				"received":   truncateString(raw, 200),
+			})
+			return ToolResult{
+				ToolCallID: call.ID,
+				Content:    string(errorJSON),
+				IsError:    true,
+			}
+		}
+		call.Args = withoutRepairMark(call.Args)
+	}
+
+	// Execute tool through the middleware chain
+	result, err := a.toolHandler()(ctx, &ToolInvocation{
+		Tool:   tool,
+		Name:   call.Name,
+		Args:   call.Args,
+		CallID: call.ID,
+		Step:   step,
+	})
+	var argsErr *ArgsError
+	if errors.As(err, &argsErr) {
+		a.emitTool(step, call, argSummary, start, types.ToolStatusInvalidArgs, strings.Join(argsErr.Violations, "; "), fmt.Sprintf("🔧 %s(%s) ❌ invalid arguments", call.Name, argSummary))
+		errorJSON, _ := json.Marshal(map[string]interface{}{
+			"error":      fmt.Sprintf("Invalid arguments for %s. Fix them and resend the tool call.", call.Name),
+			"error_code": types.ErrInvalidArgument,
+			"violations": argsErr.Violations,
+			"parameters": argsErr.Parameters,
+		})
+		return ToolResult{
+			ToolCallID: call.ID,
+			Content:    string(errorJSON),
+			IsError:    true,
+		}
+	}
 	if err != nil {
-		a.emitProgress("tool_call", step, fmt.Sprintf("🔧 %s(%s) ❌ %v", call.Name, argSummary, err), nil)
+		a.emitTool(step, call, argSummary, start, types.ToolStatusError, err.Error(), fmt.Sprintf("🔧 %s(%s) ❌ %v", call.Name, argSummary, err))
 		errorResult := map[string]interface{}{
-			"error": fmt.Sprintf("Error executing %s: %v", call.Name, err),
+			"error":      redactSessionVars(ctx, fmt.Sprintf("Error executing %s: %v", call.Name, err)),
+			"error_code": types.CodeOf(err),
 		}
 		errorJSON, _ := json.Marshal(errorResult)
 		return ToolResult{
@@ -471,6 +761,16 @@ func (a *Agent) executeSingleTool(ctx context.Context, call ai.ToolCall, step in
 		}
 	}
 
+	// Tools report failures as {"error": ...}; make sure every one carries a code
+	if m, ok := result.(map[string]interface{}); ok {
+		if msg, ok := m["error"].(string); ok && msg != "" {
+			m["error"] = redactSessionVars(ctx, msg)
+			if _, coded := m["error_code"]; !coded {
+				m["error_code"] = types.CodeOfMessage(msg)
+			}
+		}
+	}
+
 	// Convert result to JSON string to preserve structure
 	resultJSON, err := json.Marshal(result)
 	if err != nil {
@@ -485,7 +785,11 @@ func (a *Agent) executeSingleTool(ctx context.Context, call ai.ToolCall, step in
 
 	// Emit combined tool call + result (compact format)
 	resultSummary := a.summarizeResult(string(resultJSON))
-	a.emitProgress("tool_call", step, fmt.Sprintf("🔧 %s(%s) → %s", call.Name, argSummary, resultSummary), nil)
+	status := types.ToolStatusOK
+	if m, ok := result.(map[string]interface{}); ok && m["error"] != nil && m["error"] != "" {
+		status = types.ToolStatusError
+	}
+	a.emitTool(step, call, argSummary, start, status, resultSummary, fmt.Sprintf("🔧 %s(%s) → %s", call.Name, argSummary, resultSummary))
 
 	log.Printf("[Agent] Tool %s completed", call.Name)
 
@@ -604,9 +908,15 @@ func (a *Agent) cleanToolCallTags(content string) string {
 }
 
 // parseToolCallsFromText parses text-based tool calls like <function=name>...</function>
+// from the content's tool section (see toolSection)
 func (a *Agent) parseToolCallsFromText(content string) []ai.ToolCall {
 	var toolCalls []ai.ToolCall
 
+	content, ignored := toolSection(content)
+	if ignored > 0 {
+		log.Printf("[Agent] Ignored %d echoed or quoted tool calls outside the tool section", ignored)
+	}
+
 	// Look for patterns like <function=name>...</function>
 	// Example: <function=list_dir><parameter=path>~/git</parameter></function>
 
@@ -615,9 +925,23 @@ func (a *Agent) parseToolCallsFromText(content string) []ai.ToolCall {
 	inToolCall := false
 	var currentToolCall *ai.ToolCall
 	var currentArgs map[string]interface{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	var openParam string // Parameter whose value spans lines
+	var paramLines []string
+
+	for _, rawLine := range lines {
+		line := strings.TrimSpace(rawLine)
+
+		// Inside a multi-line parameter value: keep lines verbatim until </parameter>
+		if openParam != "" {
+			if before, found := strings.CutSuffix(strings.TrimRight(rawLine, " \t\r"), "</parameter>"); found {
+				paramLines = append(paramLines, before)
+				currentArgs[openParam] = strings.Join(paramLines, "\n")
+				openParam, paramLines = "", nil
+			} else {
+				paramLines = append(paramLines, rawLine)
+			}
+			continue
+		}
 
 		// Check for function start
 		if strings.HasPrefix(line, "<function=") && strings.HasSuffix(line, ">") {
@@ -642,8 +966,15 @@ func (a *Agent) parseToolCallsFromText(content string) []ai.ToolCall {
 			parts := strings.SplitN(paramLine, ">", 2)
 			if len(parts) == 2 {
 				paramName := strings.TrimSuffix(parts[0], "</parameter")
-				paramValue := parts[1]
-				paramValue = strings.TrimSuffix(paramValue, "</parameter>")
+				paramValue, closed := strings.CutSuffix(parts[1], "</parameter>")
+				if !closed {
+					// Value continues on the next lines
+					openParam = paramName
+					if paramValue != "" {
+						paramLines = []string{paramValue}
+					}
+					continue
+				}
 				currentArgs[paramName] = paramValue
 			}
 			continue
@@ -673,6 +1004,9 @@ func (a *Agent) parseToolCallsFromText(content string) []ai.ToolCall {
 	}
 
 	// If we're still in a tool call at the end, add it
+	if openParam != "" {
+		currentArgs[openParam] = strings.Join(paramLines, "\n")
+	}
 	if inToolCall && currentToolCall != nil {
 		toolCalls = append(toolCalls, *currentToolCall)
 	}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// CANCELLATION
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A client cancels a run by canceling its context (the gateway does it for
+// POST /sessions/{id}/cancel). Running commands are killed through the
+// context; the loop stops at the next safe point: before a step, before each
+// tool call, and after the step's tool results are in the session. Work done
+// so far stays in the session, followed by a note that the run was canceled.
+
+// canceled reports whether the run's context was canceled (not timed out)
+func canceled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// stopCanceled records the cancellation in the session and returns the
+// run's error
+func (a *Agent) stopCanceled(ctx context.Context, session *Session, step int) error {
+	reason := "canceled by the client"
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		reason = cause.Error()
+	}
+	session.AddMessage(ai.Message{
+		Role:    "assistant",
+		Content: fmt.Sprintf("[Run canceled at step %d: %s. Changes made so far are kept.]", step, reason),
+	})
+	a.emit(ProgressEvent{
+		Type:       "cancelled",
+		Step:       step,
+		Message:    "Run canceled: " + reason,
+		DurationMs: time.Since(a.runStart).Milliseconds(),
+	})
+	return types.Errorf(types.ErrCanceled, "run canceled at step %d: %s", step, reason)
+}
+
+// canceledToolResult answers a tool call skipped because the run was canceled
+func canceledToolResult(call ai.ToolCall) ToolResult {
+	errorJSON, _ := json.Marshal(map[string]interface{}{
+		"error":      fmt.Sprintf("%s was not run: the run was canceled", call.Name),
+		"error_code": types.ErrCanceled,
+	})
+	return ToolResult{
+		ToolCallID: call.ID,
+		Content:    string(errorJSON),
+		IsError:    true,
+	}
+}
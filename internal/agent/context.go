@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+)
+
+// sessionContextKey is the context key for the session running a tool call
+type sessionContextKey struct{}
+
+// WithSession returns a context carrying the session so tools can access per-session state
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session stored in ctx, or nil if there is none
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// modelContextKey is the context key for the model a tool may call
+type modelContextKey struct{}
+
+// toolModel is the caller and model of the run executing a tool call
+type toolModel struct {
+	caller AICaller
+	model  string
+}
+
+// WithModel returns a context carrying the run's model, for tools that
+// delegate a focused generation to it (generate_like)
+func WithModel(ctx context.Context, caller AICaller, model string) context.Context {
+	return context.WithValue(ctx, modelContextKey{}, toolModel{caller, model})
+}
+
+// ModelFromContext returns the caller and model stored in ctx, or a nil caller
+func ModelFromContext(ctx context.Context) (AICaller, string) {
+	m, _ := ctx.Value(modelContextKey{}).(toolModel)
+	return m.caller, m.model
+}
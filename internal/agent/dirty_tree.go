@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// DIRTY WORKING TREE
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// People often start the agent in a repository with work in progress. Before
+// a run, uncommitted changes the session didn't make (the user's) are found
+// with git status: files whose content differs from what the session's tools
+// last read or wrote. With the warn policy the model is told to leave them
+// alone, and the client gets a dirty_tree event. With stash, they are stashed
+// for the run and restored after it, so the agent works on the committed
+// version and its changes never mix with the user's. When the run changed a
+// stashed file, the stash is kept for the user to apply instead.
+
+// Dirty tree policies
+const (
+	DirtyTreeWarn  = "warn"  // Tell the model and the client (default)
+	DirtyTreeStash = "stash" // Stash the user's changes for the run
+	DirtyTreeOff   = "off"   // Don't check
+)
+
+const maxDirtyNoteFiles = 30
+
+// SetDirtyTree sets how a run treats uncommitted changes the session didn't
+// make ("" = warn)
+func (a *Agent) SetDirtyTree(policy string) {
+	a.dirtyTree = policy
+}
+
+// dirtyTree is the user's uncommitted work found before a run
+type dirtyTree struct {
+	root    string   // Repository root
+	files   []string // Relative to root
+	stashed string   // Message of the run's stash ("" = not stashed)
+}
+
+// checkDirtyTree finds the user's uncommitted changes in the session's
+// working tree and applies the policy; nil when there are none (or the
+// session has no working directory)
+func (a *Agent) checkDirtyTree(ctx context.Context, session *Session) *dirtyTree {
+	dir := session.GetWorkingDir()
+	if a.dirtyTree == DirtyTreeOff || dir == "" {
+		return nil
+	}
+	root, err := gitOutput(ctx, ExpandPath(dir), "rev-parse", "--show-toplevel")
+	if err != nil || root == "" {
+		return nil
+	}
+	files := userChanges(ctx, root, session)
+	if len(files) == 0 {
+		return nil
+	}
+	tree := &dirtyTree{root: root, files: files}
+
+	data := map[string]interface{}{"files": files, "action": DirtyTreeWarn}
+	message := fmt.Sprintf("%d file(s) have uncommitted changes not made by the agent: %s", len(files), summarizeFiles(files))
+	if a.dirtyTree == DirtyTreeStash {
+		tree.stash(ctx, session.ID)
+		if tree.stashed != "" {
+			data["action"] = "stashed"
+			message = fmt.Sprintf("Stashed your uncommitted changes to %d file(s) for this run; they are restored after it", len(files))
+		}
+	}
+	// Tell the client once per set of changes, not on every turn
+	if tree.stashed != "" || session.noteDirtyTree(strings.Join(files, "\x00")) {
+		a.emitProgress("dirty_tree", 0, message, data)
+	}
+	return tree
+}
+
+// dirtyFiles lists the uncommitted files in a repository (relative to root)
+func dirtyFiles(ctx context.Context, root string) []string {
+	// Not gitOutput: trimming would eat the first entry's status
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "-z", "--untracked-files=all")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	var files []string
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // The original path follows
+		}
+	}
+	return files
+}
+
+// userChanges lists uncommitted files whose content the session didn't see
+func userChanges(ctx context.Context, root string, session *Session) []string {
+	var files []string
+	for _, path := range dirtyFiles(ctx, root) {
+		full := filepath.Join(root, path)
+		known, seen := session.GetFileHash(full)
+		content, err := os.ReadFile(full)
+		switch {
+		case err != nil && seen:
+			continue // Deleted after the session had it
+		case err == nil && seen && known == contentHash(content):
+			continue // As the session last read or wrote it
+		}
+		files = append(files, path)
+	}
+	slices.Sort(files)
+	return files
+}
+
+// stash stashes the files; on failure the run goes on with a warning
+func (t *dirtyTree) stash(ctx context.Context, sessionID string) {
+	message := fmt.Sprintf("zen-claw: user changes during a run of session %s (%s)", sessionID, time.Now().Format(time.RFC3339Nano))
+	args := append([]string{"stash", "push", "--include-untracked", "-m", message, "--"}, t.files...)
+	if out, err := gitOutput(ctx, t.root, args...); err != nil {
+		log.Printf("[Agent] Failed to stash user changes: %v %s", err, out)
+		return
+	}
+	t.stashed = message
+}
+
+// restoreDirtyTree applies the run's stash back. When the run changed a
+// stashed file, applying it would conflict: the stash is kept and the
+// returned note says how to apply it.
+func (a *Agent) restoreDirtyTree(ctx context.Context, t *dirtyTree) string {
+	if t == nil || t.stashed == "" {
+		return ""
+	}
+	// The run's own context may be canceled by now
+	ctx = context.WithoutCancel(ctx)
+	ref := t.stashRef(ctx)
+	if ref == "" {
+		return ""
+	}
+
+	var touched []string
+	for _, path := range dirtyFiles(ctx, t.root) {
+		if slices.Contains(t.files, path) {
+			touched = append(touched, path)
+		}
+	}
+	if len(touched) == 0 {
+		if _, err := gitOutput(ctx, t.root, "stash", "pop", ref); err == nil {
+			a.emitProgress("dirty_tree", 0, fmt.Sprintf("Restored your uncommitted changes to %d file(s)", len(t.files)),
+				map[string]interface{}{"files": t.files, "action": "restored"})
+			return ""
+		}
+	}
+	note := fmt.Sprintf("Your uncommitted changes were kept in %s (the run changed %s). Apply them with: git stash pop %s", ref, summarizeFiles(touched), ref)
+	if len(touched) == 0 {
+		note = fmt.Sprintf("Your uncommitted changes could not be restored automatically and are kept in %s. Apply them with: git stash pop %s", ref, ref)
+	}
+	a.emitProgress("dirty_tree", 0, note, map[string]interface{}{"files": t.files, "action": "kept", "stash": ref})
+	return note
+}
+
+// stashRef finds the run's stash (stash@{N}) by its message
+func (t *dirtyTree) stashRef(ctx context.Context) string {
+	out, err := gitOutput(ctx, t.root, "stash", "list", "--format=%gd %s")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if ref, subject, ok := strings.Cut(line, " "); ok && strings.HasSuffix(subject, t.stashed) {
+			return ref
+		}
+	}
+	return ""
+}
+
+// withDirtyTreeNote tells the model about the user's uncommitted work
+func withDirtyTreeNote(messages []ai.Message, t *dirtyTree) []ai.Message {
+	if t == nil {
+		return messages
+	}
+	note := fmt.Sprintf("The user has uncommitted changes in %s that are not yours: %s. "+
+		"They are the user's work in progress: don't modify, revert, stage, stash or commit these files unless asked to, "+
+		"and keep your changes to other files where you can. Don't use git add -A, git commit -a, git checkout . or git stash.",
+		t.root, summarizeFiles(t.files))
+	if t.stashed != "" {
+		note = fmt.Sprintf("The user's uncommitted changes to %s were stashed for this run and are restored after it; "+
+			"you see the committed version of these files. Don't run git stash pop or git stash drop.", summarizeFiles(t.files))
+	}
+	return append([]ai.Message{{Role: "system", Content: note}}, messages...)
+}
+
+// summarizeFiles lists files, cut to maxDirtyNoteFiles
+func summarizeFiles(files []string) string {
+	if len(files) <= maxDirtyNoteFiles {
+		return strings.Join(files, ", ")
+	}
+	return strings.Join(files[:maxDirtyNoteFiles], ", ") + fmt.Sprintf(" and %d more", len(files)-maxDirtyNoteFiles)
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/shellrisk"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// NETWORK EGRESS POLICY
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// The egress policy limits which hosts tools may reach, so an agent working
+// on a private codebase can't send it anywhere else. It covers web_fetch
+// (redirects included), web_search, any tool taking a "url" argument
+// (http_request, plugins, MCP tools), and exec/process commands, whose
+// destinations are read from the parsed command line (see shellrisk).
+//
+// Patterns are host names (github.com), wildcards (*.github.com matches
+// subdomains, not github.com itself), IPs or CIDR ranges (10.0.0.0/8), or
+// "*". Deny wins over allow; when an allowlist is set, only matching hosts
+// are reachable and commands whose destination can't be told are refused.
+// That includes commands running code the command line doesn't show
+// (python3 -c, node -e, go run/test, npm install, make, scripts, unknown
+// programs): it could connect anywhere, and the policy is not enforced at
+// the network level.
+
+// EgressPolicy holds the allowed and denied host patterns (per gateway instance)
+type EgressPolicy struct {
+	allow []string
+	deny  []string
+	mu    sync.RWMutex
+}
+
+var globalEgressPolicy = &EgressPolicy{}
+
+// GetEgressPolicy returns the global egress policy
+func GetEgressPolicy() *EgressPolicy {
+	return globalEgressPolicy
+}
+
+// Set replaces the allowlist and denylist (both empty = no restriction)
+func (p *EgressPolicy) Set(allow, deny []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allow = normalizeHostPatterns(allow)
+	p.deny = normalizeHostPatterns(deny)
+}
+
+// Rules returns the allowlist and denylist
+func (p *EgressPolicy) Rules() (allow, deny []string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.allow), slices.Clone(p.deny)
+}
+
+// Active reports whether any host is restricted
+func (p *EgressPolicy) Active() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.allow) > 0 || len(p.deny) > 0
+}
+
+// Check returns a PERMISSION_DENIED error when host may not be reached
+func (p *EgressPolicy) Check(host string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.deny {
+		if matchHost(pattern, host) {
+			return types.Errorf(types.ErrPermissionDenied, "network access to %s is denied by the egress policy (%q)", host, pattern)
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, pattern := range p.allow {
+		if matchHost(pattern, host) {
+			return nil
+		}
+	}
+	return types.Errorf(types.ErrPermissionDenied, "network access to %s is not allowed by the egress policy (allowed: %s)", host, strings.Join(p.allow, ", "))
+}
+
+// CheckURL checks the host of a URL. A URL without a host it can read is
+// refused: the tool receiving it might still resolve one.
+func (p *EgressPolicy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return types.Errorf(types.ErrPermissionDenied, "can't tell which host %q reaches, and the egress policy restricts network access. Pass a full URL such as https://host/path", rawURL)
+	}
+	return p.Check(u.Hostname())
+}
+
+func normalizeHostPatterns(patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			out = append(out, strings.TrimSuffix(p, "."))
+		}
+	}
+	return out
+}
+
+// matchHost matches a host against one pattern
+func matchHost(pattern, host string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	case strings.Contains(pattern, "/"):
+		_, network, err := net.ParseCIDR(pattern)
+		ip := net.ParseIP(host)
+		return err == nil && ip != nil && network.Contains(ip)
+	}
+	return pattern == host
+}
+
+// webSearchHost is where web_search sends queries
+const webSearchHost = "api.search.brave.com"
+
+// EgressMiddleware refuses tool calls that would reach a host the egress
+// policy doesn't allow
+func EgressMiddleware() ToolMiddleware {
+	return ApprovalMiddleware(func(ctx context.Context, inv *ToolInvocation) error {
+		policy := GetEgressPolicy()
+		if !policy.Active() {
+			return nil
+		}
+		if inv.Name == "web_search" {
+			return policy.Check(webSearchHost)
+		}
+		if rawURL, ok := inv.Args["url"].(string); ok && rawURL != "" {
+			return policy.CheckURL(rawURL)
+		}
+
+		analysis := ClassifyToolCall(inv.Name, inv.Args)
+		if analysis == nil {
+			return nil
+		}
+		allow, _ := policy.Rules()
+		if len(allow) > 0 && len(analysis.RunsCode) > 0 {
+			return types.Errorf(types.ErrPermissionDenied,
+				"%s runs code that may connect anywhere, and the egress policy only allows %s. Use commands whose destinations are on the command line (curl https://...), or ask the user to run it",
+				strings.Join(analysis.RunsCode, ", "), strings.Join(allow, ", "))
+		}
+		if !analysis.Has(shellrisk.NetworkEgress) {
+			return nil
+		}
+		hosts := analysis.Hosts
+		var unknown []string
+		for _, remote := range analysis.Remotes {
+			if host := remoteHost(ctx, remote); host != "" {
+				hosts = append(hosts, host)
+			} else {
+				unknown = append(unknown, "git remote "+remote)
+			}
+		}
+		for _, host := range hosts {
+			if err := policy.Check(host); err != nil {
+				return err
+			}
+		}
+		unknown = append(unknown, analysis.UnknownEgress...)
+		if len(allow) > 0 && len(unknown) > 0 {
+			return types.Errorf(types.ErrPermissionDenied,
+				"can't tell which host %s reaches, and the egress policy only allows %s. Name the destination explicitly (a full URL) or ask the user",
+				strings.Join(unknown, ", "), strings.Join(allow, ", "))
+		}
+		return nil
+	})
+}
+
+// remoteHost returns the host of a git remote in the working directory
+func remoteHost(ctx context.Context, remote string) string {
+	out, err := gitOutput(ctx, BaseDir(ctx, ""), "remote", "get-url", remote)
+	if err != nil {
+		return ""
+	}
+	return shellrisk.HostOf(out)
+}
+
+// checkRedirect applies the egress policy to HTTP redirects
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 5 {
+		return fmt.Errorf("too many redirects")
+	}
+	return GetEgressPolicy().Check(req.URL.Hostname())
+}
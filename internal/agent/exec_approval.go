@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/shellrisk"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// EXEC APPROVAL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// exec and process commands are parsed and classified (read-only, mutating,
+// network-egress, destructive; see internal/shellrisk) before they run. The
+// classification goes into the audit log, and the approval policy decides
+// which commands run unattended: "all" runs everything, "read-only" only
+// auto-approves read-only commands - any other class is refused until an
+// operator approves it for the session (Session.ApproveExec).
+
+const (
+	ExecApprovalAll      = "all"       // Every command runs (default)
+	ExecApprovalReadOnly = "read-only" // Only read-only commands run without approval
+)
+
+// ExecApproval holds the approval policy (per gateway instance)
+type ExecApproval struct {
+	policy string
+	mu     sync.RWMutex
+}
+
+var globalExecApproval = &ExecApproval{policy: ExecApprovalAll}
+
+// GetExecApproval returns the global exec approval policy
+func GetExecApproval() *ExecApproval {
+	return globalExecApproval
+}
+
+// Set changes the policy ("" = all)
+func (e *ExecApproval) Set(policy string) error {
+	if policy == "" {
+		policy = ExecApprovalAll
+	}
+	if policy != ExecApprovalAll && policy != ExecApprovalReadOnly {
+		return fmt.Errorf("unknown exec approval policy %q (use %q or %q)", policy, ExecApprovalAll, ExecApprovalReadOnly)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+	return nil
+}
+
+// Policy returns the current policy
+func (e *ExecApproval) Policy() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policy
+}
+
+// ClassifyToolCall classifies the shell command of an exec, process or
+// git_bisect call, and terraform_plan, whose providers run code that may
+// connect anywhere (nil for other tools and process actions that don't
+// start a command)
+func ClassifyToolCall(tool string, args map[string]interface{}) *shellrisk.Analysis {
+	if tool == "terraform_plan" {
+		return &shellrisk.Analysis{
+			Class:         shellrisk.NetworkEgress,
+			Classes:       []shellrisk.Class{shellrisk.Mutating, shellrisk.NetworkEgress},
+			Reasons:       []string{"terraform runs provider plugins, which reach their APIs"},
+			Programs:      []string{"terraform"},
+			RunsCode:      []string{"terraform"},
+			UnknownEgress: []string{"terraform"},
+		}
+	}
+	if tool != "exec" && tool != "process" && tool != "git_bisect" {
+		return nil
+	}
+	command, _ := args["command"].(string)
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	analysis := shellrisk.Classify(command)
+	return &analysis
+}
+
+// ExecApprovalMiddleware refuses exec/process commands the policy doesn't
+// auto-approve and the session has not been allowed
+func ExecApprovalMiddleware() ToolMiddleware {
+	return ApprovalMiddleware(func(ctx context.Context, inv *ToolInvocation) error {
+		if GetExecApproval().Policy() != ExecApprovalReadOnly {
+			return nil
+		}
+		analysis := ClassifyToolCall(inv.Name, inv.Args)
+		if analysis == nil || analysis.Class == shellrisk.ReadOnly {
+			return nil
+		}
+
+		var approved []string
+		sessionID := "{id}"
+		if session := SessionFromContext(ctx); session != nil {
+			approved = session.ExecApproved()
+			sessionID = session.ID
+		}
+		var missing []string
+		for _, class := range analysis.Classes {
+			if class != shellrisk.ReadOnly && !slices.Contains(approved, string(class)) && !slices.Contains(approved, "*") {
+				missing = append(missing, string(class))
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		return types.Errorf(types.ErrPermissionDenied,
+			"command is %s (%s): only read-only commands run without approval. Use a read-only command or ask the user; an operator can approve it for this session with POST /sessions/%s/exec-approvals {\"allow\": [\"%s\"]}",
+			strings.Join(missing, ", "), strings.Join(analysis.Reasons, "; "), sessionID, strings.Join(missing, `", "`))
+	})
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// EXPLAIN RUNS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// An explain run answers a question about the code (zen-claw ask): the model
+// gets the tools that read, search and navigate code only, and their results
+// are shared by the explain runs of a working tree until it changes, so
+// follow-up questions about the same code don't read it again.
+
+// explainTools read, search and navigate code
+var explainTools = map[string]bool{
+	"read_file":       true,
+	"list_dir":        true,
+	"search_files":    true,
+	"code_search":     true,
+	"find_symbol":     true,
+	"get_context":     true,
+	"find_definition": true,
+	"find_references": true,
+	"git_log":         true,
+	"expand_result":   true,
+}
+
+// ExplainTools returns the tools an explain run may use
+func ExplainTools(tools []Tool) []Tool {
+	var kept []Tool
+	for _, tool := range tools {
+		if explainTools[tool.Name()] {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
+
+// DefaultResultCacheTTL is how long a shared result is reused
+const DefaultResultCacheTTL = 30 * time.Minute
+
+// DefaultResultCacheEntries caps the shared results kept
+const DefaultResultCacheEntries = 2000
+
+// ResultCache keeps explain tools' results across runs, keyed by the
+// working tree's state (HEAD and the uncommitted changes): a commit or an
+// edit makes a new key
+type ResultCache struct {
+	ttl     time.Duration
+	max     int
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result  interface{}
+	expires time.Time
+}
+
+// NewResultCache creates a shared result cache (0 = the defaults)
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	if ttl <= 0 {
+		ttl = DefaultResultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultResultCacheEntries
+	}
+	return &ResultCache{ttl: ttl, max: maxEntries, entries: make(map[string]cachedResult)}
+}
+
+// Len returns the number of cached results
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Middleware reuses cached results of explain tools; one per run, as the
+// tree's state is taken once, at the run's first call
+func (c *ResultCache) Middleware() ToolMiddleware {
+	var once sync.Once
+	var tree string
+
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			// Stored outputs belong to their session
+			if !explainTools[inv.Name] || inv.Name == "expand_result" {
+				return next(ctx, inv)
+			}
+			base := BaseDir(ctx, "")
+			once.Do(func() { tree = treeState(ctx, base) })
+
+			argsJSON, _ := json.Marshal(inv.Args) // Map keys are sorted
+			key := base + "\x00" + tree + "\x00" + inv.Name + ":" + string(argsJSON)
+			now := time.Now()
+			c.mu.Lock()
+			entry, ok := c.entries[key]
+			c.mu.Unlock()
+			if ok && now.Before(entry.expires) {
+				return entry.result, nil
+			}
+
+			result, err := next(ctx, inv)
+			if err != nil {
+				return result, err
+			}
+			// Results pointing at a session's stored output aren't shared
+			if out, _ := json.Marshal(result); ResultRef(string(out)) != "" {
+				return result, nil
+			}
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if len(c.entries) >= c.max {
+				c.evict(now)
+			}
+			c.entries[key] = cachedResult{result: result, expires: now.Add(c.ttl)}
+			return result, nil
+		}
+	}
+}
+
+// evict drops expired entries, or all when none has expired (caller holds mu)
+func (c *ResultCache) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) >= c.max {
+		clear(c.entries)
+	}
+}
+
+// treeState identifies the state of the git tree at dir: HEAD plus the
+// uncommitted files with their sizes and modification times. Outside a git
+// repository it is "", and results live until they expire.
+func treeState(ctx context.Context, dir string) string {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	head, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	status, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return ""
+	}
+	top, _ := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	root := strings.TrimSpace(string(top))
+
+	h := sha256.New()
+	h.Write(head)
+	for _, entry := range strings.Split(string(status), "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		fmt.Fprintf(h, "%s\n", entry)
+		if info, err := os.Stat(filepath.Join(root, entry[3:])); err == nil {
+			fmt.Fprintf(h, "%d %d\n", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
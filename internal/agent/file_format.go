@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// FILE FORMAT (line endings, BOM, encoding)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// File tools work on UTF-8 text with \n line endings. A file stored with
+// CRLF line endings, a byte order mark or as UTF-16 is decoded to that form
+// when read or edited and encoded back the way it was when written, so a
+// Windows file stays a Windows file. Other encodings (Latin-1, Shift JIS...)
+// and binary files are refused rather than silently re-encoded.
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Line endings of a file
+const (
+	lineEndingLF    = "lf"
+	lineEndingCRLF  = "crlf"
+	lineEndingMixed = "mixed" // Left as they are
+)
+
+// fileFormat is how a text file is stored on disk
+type fileFormat struct {
+	encoding   string // "utf-8", "utf-16le" or "utf-16be"
+	bom        bool
+	lineEnding string
+}
+
+// defaultFileFormat is the format of new files
+var defaultFileFormat = fileFormat{encoding: "utf-8", lineEnding: lineEndingLF}
+
+// String is the format as reported in tool results, e.g. "utf-8 bom crlf"
+func (f fileFormat) String() string {
+	s := f.encoding
+	if f.bom {
+		s += " bom"
+	}
+	return s + " " + f.lineEnding
+}
+
+// plain reports whether the file is UTF-8 with \n line endings and no BOM
+func (f fileFormat) plain() bool {
+	return f == defaultFileFormat
+}
+
+// unsupportedEncodingError is returned for files that are neither UTF-8 nor
+// UTF-16 with a BOM
+type unsupportedEncodingError struct {
+	binary bool
+}
+
+func (e *unsupportedEncodingError) Error() string {
+	if e.binary {
+		return "unsupported encoding: the file is binary; file tools only edit text files"
+	}
+	return "unsupported encoding: the file is not UTF-8 or UTF-16 (probably a legacy encoding such as Latin-1); convert it with exec (iconv) before editing"
+}
+
+// decodeText detects the format of data and returns its text, UTF-8 with
+// \n line endings (mixed line endings are kept as they are)
+func decodeText(data []byte) (string, fileFormat, error) {
+	f := fileFormat{encoding: "utf-8"}
+	var text string
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		f.bom = true
+		data = data[len(bomUTF8):]
+		fallthrough
+	case !bytes.HasPrefix(data, bomUTF16LE) && !bytes.HasPrefix(data, bomUTF16BE):
+		if !utf8.Valid(data) {
+			return "", f, &unsupportedEncodingError{}
+		}
+		if bytes.IndexByte(data, 0) >= 0 {
+			return "", f, &unsupportedEncodingError{binary: true}
+		}
+		text = string(data)
+	default:
+		var order binary.ByteOrder = binary.LittleEndian
+		f.encoding = "utf-16le"
+		if bytes.HasPrefix(data, bomUTF16BE) {
+			order = binary.BigEndian
+			f.encoding = "utf-16be"
+		}
+		f.bom = true
+		data = data[2:]
+		if len(data)%2 != 0 {
+			return "", f, &unsupportedEncodingError{binary: true}
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		text = string(utf16.Decode(units))
+	}
+
+	crlf := strings.Count(text, "\r\n")
+	switch lf := strings.Count(text, "\n"); {
+	case crlf == 0:
+		f.lineEnding = lineEndingLF
+	case crlf == lf:
+		f.lineEnding = lineEndingCRLF
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	default:
+		f.lineEnding = lineEndingMixed
+	}
+	return text, f, nil
+}
+
+// normalize converts text given by the model (old_string, new_string) to
+// the line endings decodeText returned for this file
+func (f fileFormat) normalize(text string) string {
+	if f.lineEnding == lineEndingCRLF {
+		return strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return text
+}
+
+// encode stores text in this format, BOM included
+func (f fileFormat) encode(text string) []byte {
+	body := f.encodeBody(text)
+	switch {
+	case !f.bom:
+		return body
+	case f.encoding == "utf-16le":
+		return append(append([]byte{}, bomUTF16LE...), body...)
+	case f.encoding == "utf-16be":
+		return append(append([]byte{}, bomUTF16BE...), body...)
+	default:
+		return append(append([]byte{}, bomUTF8...), body...)
+	}
+}
+
+// encodeBody stores text in this format without a BOM, e.g. to append it
+func (f fileFormat) encodeBody(text string) []byte {
+	if f.lineEnding == lineEndingCRLF {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	}
+	if f.encoding == "utf-8" {
+		return []byte(text)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if f.encoding == "utf-16be" {
+		order = binary.BigEndian
+	}
+	units := utf16.Encode([]rune(text))
+	out := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(out[2*i:], u)
+	}
+	return out
+}
+
+// formatErrorResult is the result of a file tool refusing a file it can't decode
+func formatErrorResult(path string, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"path":    path,
+		"error":   err.Error(),
+		"success": false,
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// FILE MODES
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A file that is overwritten keeps its permissions (a script stays
+// executable), a new one is created 0666 narrowed by the process umask, as
+// editors and shells do. write_file and begin_write take an explicit mode.
+
+// newFileMode is the mode new files are created with, before the umask
+const newFileMode os.FileMode = 0666
+
+// fileModeParam is the schema of the optional mode argument
+var fileModeParam = map[string]interface{}{
+	"type":        "string",
+	"description": "Permissions in octal, e.g. \"0755\" for a script (default: an existing file keeps its own, a new one gets 0666 minus the umask)",
+}
+
+// parseFileMode reads the optional mode argument: an octal string ("0755",
+// "755") or a number whose digits are octal (755). ok is false when unset.
+func parseFileMode(v interface{}) (mode os.FileMode, ok bool, err error) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return 0, false, nil
+	case string:
+		s = strings.TrimPrefix(strings.TrimSpace(v), "0o")
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return 0, false, fmt.Errorf("mode must be an octal string such as \"0755\"")
+	}
+	if s == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0777 {
+		return 0, false, fmt.Errorf("invalid mode %q: use octal permission bits from 0000 to 0777, e.g. \"0755\"", s)
+	}
+	return os.FileMode(n), true, nil
+}
+
+// formatFileMode is a mode as reported in tool results, e.g. "0755"
+func formatFileMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// createTempFile creates a new file in dir whose name starts with prefix,
+// with newFileMode narrowed by the umask (os.CreateTemp always uses 0600)
+func createTempFile(dir, prefix string) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(rand.Uint64(), 36))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, newFileMode)
+		if os.IsExist(err) && i < 10 {
+			continue
+		}
+		return f, err
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// contentHash returns the hex-encoded SHA-256 of file content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordFileRead remembers the content hash the session last saw for a file
+func recordFileRead(ctx context.Context, fullPath string, content []byte) {
+	if session := SessionFromContext(ctx); session != nil {
+		session.SetFileHash(fullPath, contentHash(content))
+	}
+}
+
+// RecordFileWrite remembers content a tool wrote, so the write is neither a
+// conflict for the next edit nor reported to the model as an outside change
+func RecordFileWrite(ctx context.Context, fullPath string, content []byte) {
+	recordFileRead(ctx, fullPath, content)
+}
+
+// forgetFile stops tracking a file a tool deleted or moved away
+func forgetFile(ctx context.Context, fullPath string) {
+	if session := SessionFromContext(ctx); session != nil {
+		session.forgetFileHash(fullPath)
+	}
+}
+
+// checkFileConflict reports whether the file on disk changed since the session last read or wrote it.
+// Files the session never read are not tracked and never conflict.
+func checkFileConflict(ctx context.Context, fullPath string, current []byte) bool {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return false
+	}
+	known, ok := session.GetFileHash(fullPath)
+	if !ok {
+		return false
+	}
+	return known != contentHash(current)
+}
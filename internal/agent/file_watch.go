@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// FILE WATCHING
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A person may edit a file while the agent works on it. Edit tools already
+// refuse to write over a file that changed since it was read, but the model
+// only learns that when an edit fails. During a run, the directories of the
+// files the session has read or written are watched (fsnotify); before each
+// step, files whose content no longer matches what the session last saw are
+// reported to the model, which is told to re-read them before editing.
+// Changes made between turns are found at the first step. Each change is
+// reported once.
+
+const maxWatchedDirs = 200
+
+// fileChange is a tracked file whose content changed outside the agent's tools
+type fileChange struct {
+	path    string // Absolute
+	deleted bool
+}
+
+// fileWatcher notices changes to the files a session tracks during a run
+type fileWatcher struct {
+	session *Session
+	watcher *fsnotify.Watcher // nil when watching is unavailable: every file is checked each step
+	mu      sync.Mutex
+	dirs    map[string]bool
+	pending map[string]bool // Files with events since the last check
+	checked bool            // Every tracked file was checked once (changes between turns)
+}
+
+// newFileWatcher starts watching the session's files; close it when the run ends
+func newFileWatcher(session *Session) *fileWatcher {
+	w := &fileWatcher{session: session, dirs: make(map[string]bool), pending: make(map[string]bool)}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("[Agent] File watching unavailable: %v", err)
+		return w
+	}
+	w.watcher = watcher
+	w.watch()
+	go w.run()
+	return w
+}
+
+// close stops watching
+func (w *fileWatcher) close() {
+	if w != nil && w.watcher != nil {
+		w.watcher.Close()
+	}
+}
+
+// watch adds the directories of newly tracked files
+func (w *fileWatcher) watch() {
+	if w.watcher == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path := range w.session.trackedFiles() {
+		dir := filepath.Dir(path)
+		if w.dirs[dir] || len(w.dirs) >= maxWatchedDirs {
+			continue
+		}
+		// Directories, not files: editors save by replacing the file
+		if err := w.watcher.Add(dir); err != nil {
+			log.Printf("[Agent] Not watching %s: %v", dir, err)
+		}
+		w.dirs[dir] = true
+	}
+}
+
+// run records events on tracked files until the watcher is closed
+func (w *fileWatcher) run() {
+	defer recoverGoroutine("file watcher")
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if _, tracked := w.session.GetFileHash(event.Name); tracked {
+				w.mu.Lock()
+				w.pending[event.Name] = true
+				w.mu.Unlock()
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[Agent] File watcher: %v", err)
+		}
+	}
+}
+
+// changes returns the tracked files changed outside the agent's tools since
+// the last check and not reported yet. The agent's own writes update the
+// session's hashes, so their events don't count.
+func (w *fileWatcher) changes() []fileChange {
+	tracked := w.session.trackedFiles()
+	w.mu.Lock()
+	candidates := w.pending
+	w.pending = make(map[string]bool)
+	if !w.checked || w.watcher == nil {
+		for path := range tracked {
+			candidates[path] = true
+		}
+		w.checked = true
+	}
+	w.mu.Unlock()
+
+	var changed []fileChange
+	for path := range candidates {
+		known, ok := tracked[path]
+		if !ok {
+			continue
+		}
+		hash := ""
+		if content, err := os.ReadFile(path); err == nil {
+			hash = contentHash(content)
+		} else if !os.IsNotExist(err) {
+			continue
+		}
+		if hash == known || !w.session.noteExternalChange(path, hash) {
+			continue
+		}
+		changed = append(changed, fileChange{path: path, deleted: hash == ""})
+	}
+	slices.SortFunc(changed, func(a, b fileChange) int { return strings.Compare(a.path, b.path) })
+	return changed
+}
+
+// noteFileChanges tells the model (and the client) which files changed
+// outside its tools, so it re-reads them before editing
+func (a *Agent) noteFileChanges(session *Session, step int, changed []fileChange) {
+	if len(changed) == 0 {
+		return
+	}
+	base := expandPath(session.GetWorkingDir(), session.GetEnv())
+	files := make([]string, len(changed))
+	var deleted []string
+	for i, c := range changed {
+		files[i] = c.path
+		if rel, err := filepath.Rel(base, c.path); err == nil && base != "" && !strings.HasPrefix(rel, "..") {
+			files[i] = rel
+		}
+		if c.deleted {
+			deleted = append(deleted, files[i])
+		}
+	}
+	note := fmt.Sprintf("[Files changed outside your tools since you last read them (by a person or a command): %s. "+
+		"Your view of them is stale: read them again before editing them, and keep the other changes in them.]", summarizeFiles(files))
+	if len(deleted) > 0 {
+		note = fmt.Sprintf("%s\n[Deleted: %s.]", note, summarizeFiles(deleted))
+	}
+	session.AddMessage(ai.Message{Role: "user", Content: note})
+	a.emitProgress("files_changed", step, fmt.Sprintf("%d file(s) changed outside the agent: %s", len(files), summarizeFiles(files)),
+		map[string]interface{}{"files": files, "deleted": deleted})
+}
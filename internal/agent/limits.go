@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TOOL RESOURCE LIMITS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ToolLimits holds the gateway-wide resource limits for exec/process commands
+type ToolLimits struct {
+	limits types.ResourceLimits
+	mu     sync.RWMutex
+}
+
+// Global tool limits (per gateway instance, set from config)
+var globalToolLimits = &ToolLimits{}
+
+// GetToolLimits returns the global tool limits
+func GetToolLimits() *ToolLimits {
+	return globalToolLimits
+}
+
+// Set replaces the gateway-wide limits
+func (l *ToolLimits) Set(limits types.ResourceLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+// Get returns the gateway-wide limits
+func (l *ToolLimits) Get() types.ResourceLimits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.limits
+}
+
+// tighter returns the stricter of two limit values where 0 means unlimited
+func tighter(base, override int) int {
+	if override <= 0 {
+		return base
+	}
+	if base <= 0 || override < base {
+		return override
+	}
+	return base
+}
+
+// mergeLimits applies a session override on top of the gateway limits.
+// Sessions may only tighten limits, never loosen them.
+func mergeLimits(base types.ResourceLimits, override *types.ResourceLimits) types.ResourceLimits {
+	if override == nil {
+		return base
+	}
+	return types.ResourceLimits{
+		TimeoutSeconds: tighter(base.TimeoutSeconds, override.TimeoutSeconds),
+		CPUSeconds:     tighter(base.CPUSeconds, override.CPUSeconds),
+		MemoryMB:       tighter(base.MemoryMB, override.MemoryMB),
+		MaxProcesses:   tighter(base.MaxProcesses, override.MaxProcesses),
+		FileSizeMB:     tighter(base.FileSizeMB, override.FileSizeMB),
+		CPUPercent:     tighter(base.CPUPercent, override.CPUPercent),
+		Cgroup:         base.Cgroup || override.Cgroup,
+	}
+}
+
+// effectiveLimits returns the limits for a command run on behalf of the session in ctx
+func effectiveLimits(ctx context.Context) types.ResourceLimits {
+	limits := GetToolLimits().Get()
+	if session := SessionFromContext(ctx); session != nil {
+		limits = mergeLimits(limits, session.GetResourceLimits())
+	}
+	return limits
+}
+
+// cgroupAvailable reports whether commands can be placed in a transient systemd scope
+func cgroupAvailable() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := exec.LookPath("systemd-run")
+	return err == nil
+}
+
+// limitCommand wraps a bash command so it runs under the given limits.
+// rlimits are set with ulimit in the same shell (inherited by every child);
+// with Cgroup set on Linux the command runs in a systemd-run scope instead, so
+// memory, CPU and processes are accounted for the whole process tree. A
+// limit only a scope can enforce (cpu_percent, and max_processes, since
+// ulimit -u counts all of the user's processes) refuses the command when
+// there is none, as does Cgroup when systemd-run is missing.
+func limitCommand(command string, l types.ResourceLimits) string {
+	useCgroup := l.Cgroup && (l.MemoryMB > 0 || l.CPUPercent > 0 || l.MaxProcesses > 0)
+	switch {
+	case useCgroup && !cgroupAvailable():
+		return refuseCommand("cgroup", 1, "systemd-run --user --scope is not available")
+	case !useCgroup && l.CPUPercent > 0:
+		return refuseCommand("cpu_percent", l.CPUPercent, "needs cgroup: true")
+	case !useCgroup && l.MaxProcesses > 0:
+		return refuseCommand("max_processes", l.MaxProcesses, "needs cgroup: true")
+	case useCgroup:
+		args := []string{"systemd-run", "--user", "--scope", "--quiet", "--collect"}
+		if l.MemoryMB > 0 {
+			args = append(args, "-p", fmt.Sprintf("MemoryMax=%dM", l.MemoryMB))
+		}
+		if l.CPUPercent > 0 {
+			args = append(args, "-p", fmt.Sprintf("CPUQuota=%d%%", l.CPUPercent))
+		}
+		if l.MaxProcesses > 0 {
+			args = append(args, "-p", fmt.Sprintf("TasksMax=%d", l.MaxProcesses))
+		}
+		// Limits the scope can't express still go through ulimit inside it
+		inner := limitCommand(command, types.ResourceLimits{CPUSeconds: l.CPUSeconds, FileSizeMB: l.FileSizeMB})
+		return strings.Join(args, " ") + " -- bash -c " + shellQuote(inner)
+	}
+
+	var ulimits []string
+	add := func(name string, value int, ulimit string) {
+		// A limit that can't be set (e.g. -v on macOS, or above the hard
+		// limit) refuses the command rather than running it unrestricted
+		ulimits = append(ulimits, fmt.Sprintf("%s 2>/dev/null || { echo %s >&2; exit %d; }",
+			ulimit, shellQuote(fmt.Sprintf("%s %s=%d (%s)", limitFailedMarker, name, value, ulimit)), limitFailedExit))
+	}
+	if l.CPUSeconds > 0 {
+		add("cpu_seconds", l.CPUSeconds, fmt.Sprintf("ulimit -t %d", l.CPUSeconds))
+	}
+	if l.MemoryMB > 0 {
+		add("memory_mb", l.MemoryMB, fmt.Sprintf("ulimit -v %d", l.MemoryMB*1024))
+	}
+	if l.FileSizeMB > 0 {
+		add("file_size_mb", l.FileSizeMB, fmt.Sprintf("ulimit -f %d", l.FileSizeMB*1024)) // 1K blocks in bash
+	}
+	if len(ulimits) == 0 {
+		return command
+	}
+	return strings.Join(ulimits, "\n") + "\n" + command
+}
+
+// refuseCommand replaces a command whose limit can't be set by one failing
+// the way an unsettable ulimit does
+func refuseCommand(name string, value int, reason string) string {
+	return fmt.Sprintf("echo %s >&2; exit %d", shellQuote(fmt.Sprintf("%s %s=%d (%s)", limitFailedMarker, name, value, reason)), limitFailedExit)
+}
+
+// limitFailedMarker starts the message of a command refused because a limit couldn't be set
+const limitFailedMarker = "zen-claw: cannot apply resource limit"
+
+// limitFailedExit is the exit status of such a command
+const limitFailedExit = 125
+
+// limitFailure returns the message of a command refused because a limit
+// couldn't be set, or "" if it ran
+func limitFailure(output string, exitCode int) string {
+	if exitCode != limitFailedExit {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, limitFailedMarker) {
+			return line + "; the command was not run"
+		}
+	}
+	return ""
+}
+
+// limitTimeout applies the wall-clock limit to ctx (no-op when unset)
+func limitTimeout(ctx context.Context, l types.ResourceLimits) (context.Context, context.CancelFunc) {
+	if l.TimeoutSeconds <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(l.TimeoutSeconds)*time.Second)
+}
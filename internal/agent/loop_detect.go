@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// LOOP DETECTION
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A stuck model repeats the same tool call with identical arguments and gets
+// the same result, or flips a file back and forth between two edits, until
+// the steps run out. Waiting on a background process (process poll/log/list)
+// is not a loop.
+// The loop detector watches the calls of a run: the first time a loop shows
+// up it adds an intervention message telling the model what it is doing;
+// if the loop goes on, the run halts with diagnostics instead of burning the
+// rest of the step allowance.
+
+const (
+	// loopRepeatThreshold identical calls in a row trigger an intervention,
+	// loopHaltThreshold halt the run
+	loopRepeatThreshold = 3
+	loopHaltThreshold   = 5
+
+	// loopOscillationCycles A,B,A,B cycles of changing calls trigger an
+	// intervention; one more cycle halts the run
+	loopOscillationCycles = 2
+)
+
+// loopAction is what the agent does about the calls so far
+type loopAction int
+
+const (
+	loopNone loopAction = iota
+	loopIntervene
+	loopHalt
+)
+
+// loopCall is one observed tool call
+type loopCall struct {
+	signature string // Name, argument and result digest
+	name      string
+	args      string // Summary for messages
+	mutating  bool
+}
+
+// loopDetector tracks the tool calls of one run
+type loopDetector struct {
+	calls      []loopCall
+	intervened map[string]bool // Loops already pointed out
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{intervened: make(map[string]bool)}
+}
+
+// observe records a step's calls and their results and returns what to do,
+// with a description of the loop for the intervention message or the halt
+// error
+func (d *loopDetector) observe(calls []ai.ToolCall, results []ToolResult, summarize func(map[string]interface{}) string) (loopAction, string) {
+	for i, call := range calls {
+		signature := call.Name + ":" + argsDigest(call.Args)
+		if i < len(results) {
+			sum := sha256.Sum256([]byte(results[i].Content))
+			signature += ":" + hex.EncodeToString(sum[:6])
+		}
+		if pollingCall(call) {
+			signature = fmt.Sprintf("poll:%d", len(d.calls)) // Never repeats
+		}
+		d.calls = append(d.calls, loopCall{
+			signature: signature,
+			name:      call.Name,
+			args:      summarize(call.Args),
+			mutating:  !isReadOnlyTool(call.Name),
+		})
+	}
+
+	if n := d.repeats(); n >= loopRepeatThreshold {
+		last := d.calls[len(d.calls)-1]
+		desc := fmt.Sprintf("%s(%s) was called %d times in a row with identical arguments and results", last.name, last.args, n)
+		return d.verdict("repeat:"+last.signature, desc, n >= loopHaltThreshold)
+	}
+	if cycles := d.oscillations(); cycles >= loopOscillationCycles {
+		a, b := d.calls[len(d.calls)-2], d.calls[len(d.calls)-1]
+		desc := fmt.Sprintf("%s(%s) and %s(%s) alternated %d times, undoing each other", a.name, a.args, b.name, b.args, cycles)
+		key := "oscillate:" + min(a.signature, b.signature) + "|" + max(a.signature, b.signature)
+		return d.verdict(key, desc, cycles > loopOscillationCycles)
+	}
+	return loopNone, ""
+}
+
+// pollingCall reports whether a call checks on a background process, which
+// a model waiting for a build or server repeats legitimately
+func pollingCall(call ai.ToolCall) bool {
+	action, _ := call.Args["action"].(string)
+	return call.Name == "process" && (action == "poll" || action == "log" || action == "list")
+}
+
+// verdict intervenes the first time a loop is seen and halts when it goes
+// on after that (or reaches the halt threshold)
+func (d *loopDetector) verdict(key, desc string, pastHalt bool) (loopAction, string) {
+	if d.intervened[key] && pastHalt {
+		return loopHalt, desc
+	}
+	if d.intervened[key] {
+		return loopNone, ""
+	}
+	d.intervened[key] = true
+	return loopIntervene, desc
+}
+
+// repeats counts the trailing identical calls
+func (d *loopDetector) repeats() int {
+	n := 0
+	for i := len(d.calls) - 1; i >= 0 && d.calls[i].signature == d.calls[len(d.calls)-1].signature; i-- {
+		n++
+	}
+	return n
+}
+
+// oscillations counts trailing A,B cycles of two different changing calls
+func (d *loopDetector) oscillations() int {
+	n := len(d.calls)
+	if n < 4 {
+		return 0
+	}
+	a, b := d.calls[n-2], d.calls[n-1]
+	if a.signature == b.signature || !a.mutating || !b.mutating {
+		return 0
+	}
+	cycles := 0
+	for i := n - 2; i >= 0; i -= 2 {
+		if d.calls[i].signature != a.signature || d.calls[i+1].signature != b.signature {
+			break
+		}
+		cycles++
+	}
+	return cycles
+}
+
+// loopIntervention is added to the session when a loop first shows up
+func loopIntervention(desc string) string {
+	return strings.Join([]string{
+		"[Loop guard] " + desc + ". Repeating it will not give a different result.",
+		"Stop and reconsider: re-read the last results and error messages, check your assumptions",
+		"(paths, file contents, whether the change is already applied), and try a different approach.",
+		"If you are blocked, explain what is blocking you and finish with your best answer.",
+	}, "\n")
+}
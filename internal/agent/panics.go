@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PANIC RECOVERY
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A panic in a tool, its middleware, or an agent step must not kill the
+// request or the gateway. A tool panic becomes an INTERNAL error result the
+// model sees like any failed tool call. A panic elsewhere in a step ends the
+// run with an INTERNAL error, after repairing the session: tool calls left
+// without a result get one, and a note records the failure, so the session
+// can be continued with another message. Stacks go to the log; the counts
+// are exported in /metrics.
+
+// Where a recovered panic happened
+const (
+	PanicTool      = "tool"      // A tool call (or its middleware)
+	PanicStep      = "step"      // The agent loop, outside tool calls
+	PanicGoroutine = "goroutine" // A tool's background goroutine
+)
+
+var panicCounts = map[string]*atomic.Int64{
+	PanicTool:      new(atomic.Int64),
+	PanicStep:      new(atomic.Int64),
+	PanicGoroutine: new(atomic.Int64),
+}
+
+// PanicCounts returns the number of recovered panics by where they happened
+func PanicCounts() map[string]int64 {
+	counts := make(map[string]int64, len(panicCounts))
+	for where, n := range panicCounts {
+		counts[where] = n.Load()
+	}
+	return counts
+}
+
+// logPanic logs a recovered panic with its stack and counts it
+func logPanic(where, what string, value interface{}) {
+	panicCounts[where].Add(1)
+	log.Printf("[Agent] PANIC in %s %s: %v\n%s", where, what, value, debug.Stack())
+}
+
+// recoverGoroutine recovers a panic in a background goroutine (deferred)
+func recoverGoroutine(what string) {
+	if r := recover(); r != nil {
+		logPanic(PanicGoroutine, what, r)
+	}
+}
+
+// panicToolResult answers a tool call whose execution panicked
+func (a *Agent) panicToolResult(call ai.ToolCall, step int, start time.Time, value interface{}) ToolResult {
+	logPanic(PanicTool, call.Name, value)
+	errMsg := fmt.Sprintf("%s crashed: %v", call.Name, value)
+	a.emitTool(step, call, "", start, types.ToolStatusError, errMsg, fmt.Sprintf("🔧 %s 💥 crashed", call.Name))
+	errorJSON, _ := json.Marshal(map[string]interface{}{
+		"error":      errMsg + ". This is a bug in the tool, not in your call; try another way to get the same result",
+		"error_code": types.ErrInternal,
+	})
+	return ToolResult{
+		ToolCallID: call.ID,
+		Content:    string(errorJSON),
+		IsError:    true,
+	}
+}
+
+// recoverStep turns a panic in the agent loop into the run's error, leaving
+// the session consistent so it can be continued
+func (a *Agent) recoverStep(session *Session, value interface{}) error {
+	logPanic(PanicStep, "session "+session.ID, value)
+
+	// Every tool call needs a result, or providers reject the history
+	session.RepairTranscript("the run crashed")
+	session.AddMessage(ai.Message{
+		Role:    "assistant",
+		Content: fmt.Sprintf("[Run stopped by an internal error: %v. Changes made so far are kept.]", value),
+	})
+	session.MarkRecovered()
+
+	a.emit(ProgressEvent{
+		Type:       "error",
+		Message:    fmt.Sprintf("Internal error: %v", value),
+		DurationMs: time.Since(a.runStart).Milliseconds(),
+	})
+	return types.Errorf(types.ErrInternal, "agent crashed: %v. The session was kept and can be continued with another message", value)
+}
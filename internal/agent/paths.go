@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// envVarRe matches $VAR and ${VAR} references in paths
+var envVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// pathEnvVars are the only process variables a path may reference. Paths
+// come from the model, so expanding any other ($DEEPSEEK_API_KEY) would
+// hand the gateway's secrets to it in the "no such file" error.
+var pathEnvVars = map[string]bool{"HOME": true, "USER": true, "PWD": true, "TMPDIR": true}
+
+// ExpandPath expands a leading ~ (or ~/) to the home directory and
+// $VAR/${VAR} references to HOME, USER, PWD and TMPDIR. Other variables and
+// ~user forms are left untouched so the resulting error names what the
+// model actually asked for.
+func ExpandPath(path string) string {
+	return expandPath(path, nil)
+}
+
+// expandSessionPath is ExpandPath that also expands the literal variables
+// of the context's session env (secret references stay as written)
+func expandSessionPath(ctx context.Context, path string) string {
+	var env map[string]string
+	if session := SessionFromContext(ctx); session != nil {
+		env = session.GetEnv()
+	}
+	return expandPath(path, env)
+}
+
+func expandPath(path string, env map[string]string) string {
+	path = envVarRe.ReplaceAllStringFunc(path, func(ref string) string {
+		name := strings.Trim(ref, "${}")
+		if value, ok := env[name]; ok && !isSecretRef(value) {
+			return value
+		}
+		if pathEnvVars[name] {
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+		}
+		return ref
+	})
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return path
+}
+
+// redactSessionVars puts the $NAME back in place of the session env values
+// a tool error may carry from an expanded path, the way command output is
+// scrubbed of them
+func redactSessionVars(ctx context.Context, msg string) string {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return msg
+	}
+	for name, value := range session.GetEnv() {
+		if len(value) >= minRedactLen && !isSecretRef(value) {
+			msg = strings.ReplaceAll(msg, value, "$"+name)
+		}
+	}
+	return msg
+}
+
+// BaseDir returns the directory relative tool paths resolve against: the
+// session's working directory when the context carries one, otherwise the
+// tool's own working directory. An empty result means the process directory.
+func BaseDir(ctx context.Context, workingDir string) string {
+	if session := SessionFromContext(ctx); session != nil {
+		if dir := session.GetWorkingDir(); dir != "" {
+			return expandSessionPath(ctx, dir)
+		}
+	}
+	return expandSessionPath(ctx, workingDir)
+}
+
+// ResolvePath resolves a path argument from a tool call. Every tool goes
+// through here so ~, $VARS and per-session roots behave the same everywhere.
+func ResolvePath(ctx context.Context, workingDir, path string) string {
+	path = expandSessionPath(ctx, path)
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	if base := BaseDir(ctx, workingDir); base != "" {
+		return filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
+
+// ResolveAbsPath is ResolvePath made absolute, for tools that compare paths
+// or walk up the tree looking for a module root.
+func ResolveAbsPath(ctx context.Context, workingDir, path string) string {
+	path = ResolvePath(ctx, workingDir, path)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
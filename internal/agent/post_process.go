@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ARGUMENT POST-PROCESSING (content cleanup before writes)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Models often wrap file content in ```go fences, leave trailing spaces or
+// mix line endings, and all of it would end up in the written file. Before
+// a write or edit tool runs, its content arguments are cleaned up:
+//
+//	strip_fences         content wrapped whole in a ``` fence is unwrapped
+//	trailing_whitespace  spaces and tabs at line ends are removed
+//	newlines             CRLF becomes LF; write_file content ends in one newline
+//
+// Markdown keeps its fences and trailing spaces (line breaks), patches
+// their whitespace. A call opts out with "raw": true; tools.post_process
+// disables processors for the gateway. The result lists what was changed.
+
+// Post-processor names
+const (
+	PostProcessFences     = "strip_fences"
+	PostProcessWhitespace = "trailing_whitespace"
+	PostProcessNewlines   = "newlines"
+)
+
+// PostProcessors lists the processors
+var PostProcessors = []string{PostProcessFences, PostProcessWhitespace, PostProcessNewlines}
+
+// postProcessArgs are the content arguments of each tool; "edits" holds
+// new_string objects. Whole-file contents get the final newline.
+var postProcessArgs = map[string][]string{
+	"write_file":    {"content"},
+	"preview_write": {"content"},
+	"append_file":   {"content"},
+	"edit_file":     {"new_string"},
+	"preview_edit":  {"new_string"},
+	"multi_edit":    {"edits"},
+}
+
+// wholeFileArgs are the tools whose content is the entire file
+var wholeFileArgs = map[string]bool{"write_file": true, "preview_write": true}
+
+// fencedRe matches content wrapped whole in a fence: ```lang ... ```
+var fencedRe = regexp.MustCompile("(?s)^\\s*```[\\w.+-]*[ \\t]*\\n(.*?)\\n?```\\s*$")
+
+// trailingSpaceRe matches spaces and tabs at line ends
+var trailingSpaceRe = regexp.MustCompile(`[ \t]+(\n|$)`)
+
+// PostProcess is the set of enabled processors
+type PostProcess struct {
+	disabled map[string]bool
+	mu       sync.RWMutex
+}
+
+// Global post-processing (per gateway instance)
+var globalPostProcess = &PostProcess{disabled: map[string]bool{}}
+
+// GetPostProcess returns the global post-processing
+func GetPostProcess() *PostProcess {
+	return globalPostProcess
+}
+
+// Set disables the named processors (the others run)
+func (p *PostProcess) Set(disabled []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disabled = map[string]bool{}
+	for _, name := range disabled {
+		p.disabled[name] = true
+	}
+}
+
+func (p *PostProcess) enabled(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return !p.disabled[name]
+}
+
+// clean applies the enabled processors to content written to path and
+// returns it with the names of the processors that changed it
+func (p *PostProcess) clean(path, content string, wholeFile bool) (string, []string) {
+	ext := strings.ToLower(filepath.Ext(path))
+	markdown := ext == ".md" || ext == ".markdown" || ext == ".mdx"
+	patch := ext == ".diff" || ext == ".patch"
+	var applied []string
+	apply := func(name string, fn func(string) string) {
+		if !p.enabled(name) {
+			return
+		}
+		if cleaned := fn(content); cleaned != content {
+			content = cleaned
+			applied = append(applied, name)
+		}
+	}
+
+	apply(PostProcessNewlines, func(s string) string {
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+		if wholeFile && strings.TrimSpace(s) != "" {
+			s = strings.TrimRight(s, "\n") + "\n"
+		}
+		return s
+	})
+	if !markdown {
+		apply(PostProcessFences, func(s string) string {
+			m := fencedRe.FindStringSubmatch(s)
+			if m == nil || strings.Contains(m[1], "\n```") {
+				return s // Not one fenced block
+			}
+			if strings.HasSuffix(s, "\n") && !strings.HasSuffix(m[1], "\n") {
+				return m[1] + "\n"
+			}
+			return m[1]
+		})
+	}
+	if !markdown && !patch {
+		apply(PostProcessWhitespace, func(s string) string {
+			return trailingSpaceRe.ReplaceAllString(s, "$1")
+		})
+	}
+	return content, applied
+}
+
+// PostProcessMiddleware cleans the content arguments of write and edit
+// tools, unless the call passes "raw": true
+func PostProcessMiddleware() ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			keys := postProcessArgs[inv.Name]
+			if raw, _ := inv.Args["raw"].(bool); raw || len(keys) == 0 {
+				return next(ctx, inv)
+			}
+			path, _ := inv.Args["path"].(string)
+			applied := map[string]bool{}
+			args := make(map[string]interface{}, len(inv.Args))
+			for k, v := range inv.Args {
+				args[k] = v
+			}
+			for _, key := range keys {
+				switch value := args[key].(type) {
+				case string:
+					cleaned, names := globalPostProcess.clean(path, value, wholeFileArgs[inv.Name])
+					args[key] = cleaned
+					for _, name := range names {
+						applied[name] = true
+					}
+				case []interface{}:
+					edits := make([]interface{}, len(value))
+					for i, item := range value {
+						edits[i] = item
+						edit, ok := item.(map[string]interface{})
+						if !ok {
+							continue
+						}
+						newString, ok := edit["new_string"].(string)
+						if !ok {
+							continue
+						}
+						cleaned, names := globalPostProcess.clean(path, newString, false)
+						copied := make(map[string]interface{}, len(edit))
+						for k, v := range edit {
+							copied[k] = v
+						}
+						copied["new_string"] = cleaned
+						edits[i] = copied
+						for _, name := range names {
+							applied[name] = true
+						}
+					}
+					args[key] = edits
+				}
+			}
+			inv.Args = args
+
+			result, err := next(ctx, inv)
+			if m, ok := result.(map[string]interface{}); ok && len(applied) > 0 {
+				var names []string
+				for _, name := range PostProcessors {
+					if applied[name] {
+						names = append(names, name)
+					}
+				}
+				m["post_processed"] = names // Pass raw: true to write the content as given
+			}
+			return result, err
+		}
+	}
+}
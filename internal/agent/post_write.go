@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// POST-WRITE HOOKS (formatters / linters)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// postWriteHookTimeout bounds how long a single formatter/linter may run
+const postWriteHookTimeout = 30 * time.Second
+
+// PostWriteHooks runs commands after file writes, keyed by file extension.
+// Commands use {file} as a placeholder for the absolute path of the written file.
+type PostWriteHooks struct {
+	commands map[string][]string
+	mu       sync.RWMutex
+}
+
+// DefaultPostWriteHooks returns the built-in formatter commands per extension
+func DefaultPostWriteHooks() map[string][]string {
+	return map[string][]string{
+		".go":  {"gofmt -w {file}"},
+		".ts":  {"prettier --write {file}"},
+		".tsx": {"prettier --write {file}"},
+	}
+}
+
+// Global post-write hooks (per gateway instance)
+var globalPostWriteHooks = &PostWriteHooks{
+	commands: DefaultPostWriteHooks(),
+}
+
+// GetPostWriteHooks returns the global post-write hooks
+func GetPostWriteHooks() *PostWriteHooks {
+	return globalPostWriteHooks
+}
+
+// Set replaces the configured commands (nil map disables all hooks)
+func (h *PostWriteHooks) Set(commands map[string][]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = commands
+}
+
+// commandsFor returns the commands configured for a file's extension
+func (h *PostWriteHooks) commandsFor(path string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.commands[strings.ToLower(filepath.Ext(path))]
+}
+
+// PostWriteResult is the outcome of one post-write command
+type PostWriteResult struct {
+	Command string `json:"command"`
+	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"` // Tool binary not installed
+	Output  string `json:"output,omitempty"`
+}
+
+// Run executes the hooks for fullPath. Commands whose binary is not on PATH are skipped.
+func (h *PostWriteHooks) Run(ctx context.Context, fullPath string) []PostWriteResult {
+	commands := h.commandsFor(fullPath)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	var results []PostWriteResult
+	for _, command := range commands {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			results = append(results, PostWriteResult{Command: command, Skipped: true, Success: true})
+			continue
+		}
+
+		expanded := strings.ReplaceAll(command, "{file}", shellQuote(fullPath))
+		hookCtx, cancel := context.WithTimeout(ctx, postWriteHookTimeout)
+		cmd := exec.CommandContext(hookCtx, "bash", "-c", expanded)
+		cmd.Dir = filepath.Dir(fullPath)
+		output, err := cmd.CombinedOutput()
+		cancel()
+
+		results = append(results, PostWriteResult{
+			Command: command,
+			Success: err == nil,
+			Output:  truncateOutput(strings.TrimSpace(string(output)), 4000),
+		})
+	}
+	return results
+}
+
+// shellQuote quotes s for safe use as a single bash word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// applyPostWriteHooks runs post-write hooks for a written file and adds their outcome to the tool result.
+// Failures are reported back to the model so it can fix the code.
+func applyPostWriteHooks(ctx context.Context, fullPath string, result map[string]interface{}) {
+	hookResults := GetPostWriteHooks().Run(ctx, fullPath)
+	if len(hookResults) == 0 {
+		return
+	}
+
+	// Formatters may rewrite the file; keep the conflict-detection hash in sync
+	if content, err := os.ReadFile(fullPath); err == nil {
+		recordFileRead(ctx, fullPath, content)
+	}
+
+	result["post_write"] = hookResults
+	var failed []string
+	for _, r := range hookResults {
+		if !r.Success {
+			failed = append(failed, r.Command+": "+r.Output)
+		}
+	}
+	if len(failed) > 0 {
+		result["post_write_errors"] = failed
+		result["hint"] = "The file was written, but formatter/linter reported errors. Fix them before continuing."
+	}
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/i18n"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROMPTING PROFILES
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// The system prompt and tool setup are tuned for chat models. Reasoning models
+// (deepseek-reasoner, Kimi thinking) do better with less steering and their
+// own sampling settings, and some have no native function calling at all:
+// only those use the text tool protocol (the "-text" profiles).
+// A profile adapts each request to the model; the gateway picks it from the
+// model registry (config.GetModelProfile).
+
+// PromptProfile adapts how the agent prompts a family of models
+type PromptProfile struct {
+	Name      string
+	Guidance  string            // Added to the system prompt of each request
+	TextTools bool              // Describe tools in the prompt and parse calls from the text instead of native function calling
+	Params    types.ModelParams // Sampling defaults for the model (config and requests override)
+
+	SystemAsUser bool // The model ignores system prompts: send the prompt as the first user message
+	NoJSONMode   bool // The model rejects JSON mode: structured output relies on the prompt alone
+}
+
+const reasoningGuidance = `You think before you answer. Spend that thinking on the next step only:
+decide which tool calls you need now, make them, and read their results before
+planning further. Don't restate tool output or re-plan the whole task each step.
+When no tool is needed, answer directly.`
+
+// PromptProfiles are the known profiles by name
+var PromptProfiles = map[string]PromptProfile{
+	"chat": {Name: "chat"},
+	"chat-text": {
+		Name:      "chat-text",
+		TextTools: true,
+	},
+	"reasoning": {
+		Name:     "reasoning",
+		Guidance: reasoningGuidance,
+		Params:   types.ModelParams{Temperature: 1.0, MaxTokens: 16000}, // Thinking counts against max_tokens
+	},
+	"reasoning-text": {
+		Name:      "reasoning-text",
+		Guidance:  reasoningGuidance,
+		TextTools: true,
+		Params:    types.ModelParams{Temperature: 1.0, MaxTokens: 16000},
+	},
+}
+
+// ProfileFor returns the named profile ("chat" for unknown names)
+func ProfileFor(name string) PromptProfile {
+	if profile, ok := PromptProfiles[name]; ok {
+		return profile
+	}
+	return PromptProfiles["chat"]
+}
+
+// Adapt returns the profile adjusted to what a capability probe found
+// the model lacks
+func (p PromptProfile) Adapt(caps types.ModelCapabilities) PromptProfile {
+	if !caps.Tools && !p.TextTools {
+		p.TextTools = true
+		p.Name += "-text"
+	}
+	p.SystemAsUser = !caps.SystemPrompt
+	p.NoJSONMode = !caps.JSONMode
+	return p
+}
+
+// SetPromptProfile adapts the agent's requests to a model family
+func (a *Agent) SetPromptProfile(profile PromptProfile) {
+	a.profile = profile
+}
+
+// SetLanguage makes final answers use the language (a tag like pt-BR; ""
+// or English = no instruction). Tool calls and code stay in English.
+func (a *Agent) SetLanguage(tag string) {
+	a.language = i18n.Normalize(tag)
+}
+
+// languageInstruction asks for answers in the session's language
+func (a *Agent) languageInstruction() string {
+	if a.language == "" || strings.HasPrefix(a.language, "en") {
+		return ""
+	}
+	return fmt.Sprintf("LANGUAGE: Write your messages and final answer to the user in %s. "+
+		"Keep tool calls and their arguments, code, code comments, identifiers, file contents, "+
+		"commit messages and command lines in English.", i18n.Name(a.language))
+}
+
+// SetInstructions sets project instructions (a repo's .zenclaw.yaml) added
+// to the system prompt
+func (a *Agent) SetInstructions(text string) {
+	a.instructions = strings.TrimSpace(text)
+}
+
+// projectInstructions returns the project instructions for the system prompt
+func (a *Agent) projectInstructions() string {
+	if a.instructions == "" {
+		return ""
+	}
+	return "PROJECT INSTRUCTIONS (from the repository's .zenclaw.yaml):\n" + a.instructions
+}
+
+// applyProfile returns the messages with the profile's guidance (and, for
+// text tools, the tool descriptions), the project instructions and the
+// language instruction added to the system prompt. The session keeps the plain prompt, so switching models or
+// languages switches the guidance.
+func (a *Agent) applyProfile(messages []ai.Message) []ai.Message {
+	guidance := a.profile.Guidance
+	if a.profile.TextTools {
+		guidance += "\n\n" + a.textToolsPrompt()
+	}
+	guidance = strings.TrimSpace(guidance + "\n\n" + a.projectInstructions())
+	guidance = strings.TrimSpace(guidance + "\n\n" + a.languageInstruction())
+	if guidance != "" {
+		if len(messages) > 0 && messages[0].Role == "system" {
+			out := make([]ai.Message, len(messages))
+			copy(out, messages)
+			out[0].Content += "\n\n" + guidance
+			messages = out
+		} else {
+			messages = append([]ai.Message{{Role: "system", Content: guidance}}, messages...)
+		}
+	}
+	if a.profile.SystemAsUser {
+		messages = systemAsUser(messages)
+	}
+	return messages
+}
+
+// systemAsUser moves the system prompt into the first user message, for
+// models that ignore the system role
+func systemAsUser(messages []ai.Message) []ai.Message {
+	if len(messages) == 0 || messages[0].Role != "system" {
+		return messages
+	}
+	instructions := "[Instructions]\n" + messages[0].Content
+	if len(messages) > 1 && messages[1].Role == "user" {
+		out := make([]ai.Message, len(messages)-1)
+		copy(out, messages[1:])
+		out[0].Content = instructions + "\n\n[Request]\n" + out[0].Content
+		return out
+	}
+	out := make([]ai.Message, len(messages))
+	copy(out, messages)
+	out[0].Role = "user"
+	out[0].Content = instructions
+	return out
+}
+
+// textToolsPrompt describes the tools and the text format parseToolCallsFromText reads
+func (a *Agent) textToolsPrompt() string {
+	names := make([]string, 0, len(a.tools))
+	for name := range a.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("TOOLS - call them by writing, one block per call:\n<function=tool_name>\n<parameter=name>value</parameter>\n</function>\n")
+	sb.WriteString("Values may span several lines. Put all calls of a step together at the end of your message;\n")
+	sb.WriteString("calls inside code blocks, quotes or earlier in the text are not run. Wait for the results before relying on them.\n")
+	for _, name := range names {
+		tool := a.tools[name]
+		fmt.Fprintf(&sb, "\n- %s: %s", name, tool.Description())
+		props, _ := tool.Parameters()["properties"].(map[string]interface{})
+		params := make([]string, 0, len(props))
+		for param, spec := range props {
+			typ, _ := spec.(map[string]interface{})["type"].(string)
+			params = append(params, param+" ("+typ+")")
+		}
+		sort.Strings(params)
+		if len(params) > 0 {
+			sb.WriteString("\n  parameters: " + strings.Join(params, ", "))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROGRESS EVENTS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Events follow the typed schema in types.ProgressEvent: each carries the
+// time since the run started, tool calls carry their name, argument digest,
+// status and duration, and model calls their (estimated) tokens. The gateway
+// adds the cost, since only it knows the provider's pricing.
+
+// emit stamps an event with the schema version and run time and sends it
+func (a *Agent) emit(event ProgressEvent) {
+	if a.progressCallback == nil {
+		return
+	}
+	event.Version = types.ProgressSchemaVersion
+	if !a.runStart.IsZero() {
+		event.ElapsedMs = time.Since(a.runStart).Milliseconds()
+	}
+	a.progressCallback(event)
+}
+
+// emitTool reports a finished tool call
+func (a *Agent) emitTool(step int, call ai.ToolCall, args string, start time.Time, status, result, message string) {
+	duration := time.Since(start).Milliseconds()
+	a.emit(ProgressEvent{
+		Type:       "tool_call",
+		Step:       step,
+		Message:    message,
+		DurationMs: duration,
+		Tool: &types.ToolProgress{
+			Name:       call.Name,
+			CallID:     call.ID,
+			Args:       args,
+			ArgsDigest: argsDigest(call.Args),
+			Status:     status,
+			Result:     result,
+			DurationMs: duration,
+		},
+	})
+}
+
+// argsDigest is a short hash of a call's arguments (map keys marshal sorted,
+// so equal arguments give equal digests)
+func argsDigest(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// callUsage estimates the tokens of one model call
+func callUsage(messages []ai.Message, resp *ai.ChatResponse) *types.Usage {
+	return &types.Usage{
+		InputTokens:  MessagesTokens(messages),
+		OutputTokens: MessagesTokens([]ai.Message{{Content: resp.Content, ToolCalls: resp.ToolCalls}}),
+	}
+}
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/ignore"
+	"github.com/neves/zen-claw/internal/shellrisk"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROTECTED PATHS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Protected paths are globs no tool may modify: write/edit/patch tools and
+// shell commands that visibly write to them (redirections, rm, mv, sed -i,
+// ...) are refused. A pattern without a slash matches file names anywhere
+// (*.pem); one with a slash matches at any depth (secrets/** also protects
+// app/secrets/key), unless it starts with / (relative to the working dir).
+// ** matches any number of directories. An operator can allow a pattern
+// for one session (Session.AllowProtected).
+
+// DefaultProtectedPaths are protected unless the config sets its own list
+var DefaultProtectedPaths = []string{".git/**", "go.sum", "secrets/**", "*.pem", "*.key"}
+
+// ProtectedPaths holds the protected globs (per gateway instance)
+type ProtectedPaths struct {
+	patterns []string
+	mu       sync.RWMutex
+}
+
+var globalProtectedPaths = &ProtectedPaths{patterns: DefaultProtectedPaths}
+
+// NewProtectedPaths returns protected paths holding patterns (a repo's
+// .zenclaw.yaml, protected in addition to the global ones)
+func NewProtectedPaths(patterns []string) *ProtectedPaths {
+	return &ProtectedPaths{patterns: patterns}
+}
+
+// GetProtectedPaths returns the global protected paths
+func GetProtectedPaths() *ProtectedPaths {
+	return globalProtectedPaths
+}
+
+// Set replaces the protected globs (empty protects nothing)
+func (p *ProtectedPaths) Set(patterns []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.patterns = patterns
+}
+
+// Patterns returns the protected globs
+func (p *ProtectedPaths) Patterns() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.patterns)
+}
+
+// Match returns the first pattern protecting path (absolute, or relative
+// to base) that allowed does not cover
+func (p *ProtectedPaths) Match(base, path string, allowed []string) (string, bool) {
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	rel := ""
+	if base != "" {
+		if r, err := filepath.Rel(filepath.Clean(base), path); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = r
+		}
+	}
+
+	for _, pattern := range p.Patterns() {
+		if slices.Contains(allowed, pattern) || slices.Contains(allowed, "*") {
+			continue
+		}
+		if matchProtected(pattern, path, rel) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// matchProtected matches one glob against a path (rel is the path relative
+// to the working dir, "" when outside it)
+func matchProtected(pattern, path, rel string) bool {
+	pattern = filepath.ToSlash(pattern)
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		ok, _ := filepath.Match(strings.TrimSuffix(pattern, "/"), filepath.Base(path))
+		if ok {
+			return true
+		}
+		// A bare directory name (secrets/) protects what is inside it
+		if strings.HasSuffix(pattern, "/") {
+			pattern = "**/" + pattern + "**"
+		} else {
+			return false
+		}
+	}
+
+	pattern = strings.TrimSuffix(pattern, "/")
+	if anchored, ok := strings.CutPrefix(pattern, "/"); ok {
+		return rel != "" && matchSegments(strings.Split(anchored, "/"), strings.Split(filepath.ToSlash(rel), "/"))
+	}
+	segs := strings.Split(strings.TrimPrefix(filepath.ToSlash(path), "/"), "/")
+	pat := strings.Split(pattern, "/")
+	for i := range segs {
+		if matchSegments(pat, segs[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches glob segments against path segments like ignore
+// files do, except that dir/** also protects dir itself (rm -r dir)
+func matchSegments(pat, segs []string) bool {
+	if ignore.MatchSegments(pat, segs) {
+		return true
+	}
+	n := len(pat)
+	return n > 1 && pat[n-1] == "**" && ignore.MatchSegments(pat[:n-1], segs)
+}
+
+// ProtectedPathsMiddleware refuses tool calls that would modify a protected
+// path the session has not been allowed
+func ProtectedPathsMiddleware() ToolMiddleware {
+	return GetProtectedPaths().Middleware("")
+}
+
+// Middleware refuses tool calls modifying these protected paths; patterns
+// starting with / are relative to root ("" = the working dir)
+func (p *ProtectedPaths) Middleware(root string) ToolMiddleware {
+	return ApprovalMiddleware(func(ctx context.Context, inv *ToolInvocation) error {
+		var allowed []string
+		sessionID := "{id}"
+		if session := SessionFromContext(ctx); session != nil {
+			allowed = session.ProtectedAllowed()
+			sessionID = session.ID
+		}
+		wd := BaseDir(ctx, "")
+		base := root
+		if base == "" {
+			base = wd
+		}
+		paths := modifiedPaths(inv.Name, inv.Args)
+		if planner, ok := inv.Tool.(PathPlanner); ok {
+			planned, err := planner.PlannedPaths(ctx, inv.Args)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, planned...)
+		}
+		for _, path := range paths {
+			target := expandSessionPath(ctx, path)
+			if !filepath.IsAbs(target) && wd != "" {
+				target = filepath.Join(wd, target)
+			}
+			if pattern, ok := p.Match(base, target, allowed); ok {
+				return types.Errorf(types.ErrPermissionDenied,
+					"%s is protected (%q): tools may not modify it. Leave it unchanged or ask the user; an operator can allow it for this session with POST /sessions/%s/protected {\"allow\": [%q]}",
+					path, pattern, sessionID, pattern)
+			}
+		}
+		return nil
+	})
+}
+
+// PathPlanner is implemented by tools whose arguments don't name every file
+// they modify (refactors, language-server renames). PlannedPaths works out
+// those files without changing anything, so protected paths are checked
+// before the call runs; an error refuses the call.
+type PathPlanner interface {
+	PlannedPaths(ctx context.Context, args map[string]interface{}) ([]string, error)
+}
+
+// modifiedPaths returns the paths a tool call would write, delete or move,
+// as far as its arguments tell (see PathPlanner)
+func modifiedPaths(tool string, args map[string]interface{}) []string {
+	switch tool {
+	case "write_file", "edit_file", "multi_edit", "append_file", "begin_write", "generate_like":
+		if path, _ := args["path"].(string); path != "" {
+			return []string{path}
+		}
+	case "go_organize_imports":
+		if path, _ := args["path"].(string); path != "" && args["apply"] == true {
+			return []string{path}
+		}
+	case "apply_patch":
+		input, _ := args["input"].(string)
+		return patchPaths(input)
+	case "exec", "process":
+		command, _ := args["command"].(string)
+		return shellWriteTargets(command)
+	}
+	return nil
+}
+
+// patchPaths lists the files an apply_patch input adds, updates, deletes or moves to
+func patchPaths(input string) []string {
+	var paths []string
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"*** Add File:", "*** Update File:", "*** Delete File:", "*** Move to:"} {
+			if path, ok := strings.CutPrefix(line, prefix); ok {
+				paths = append(paths, strings.TrimSpace(path))
+			}
+		}
+	}
+	return paths
+}
+
+// shellWriteTargets finds the paths a shell command visibly writes: output
+// redirections, and the file arguments of commands that change files. It
+// can't see everything a command does (a script may write anywhere), so it
+// catches the common cases rather than sandboxing.
+func shellWriteTargets(command string) []string {
+	return shellrisk.Classify(command).Writes
+}
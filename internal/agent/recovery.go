@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ERROR RECOVERY ADVICE
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Models often react to a failed command by running it again, or a variant
+// that fails the same way. Before a failed result goes back to the model, the
+// recovery adviser adds a "recovery" object with what the output says: the
+// missing binary and how to install it, the file:line locations of compile
+// errors, the failing tests, and hints on what to do next instead of retrying.
+
+// RecoveryAdvice annotates a failed tool result
+type RecoveryAdvice struct {
+	Hints         []string        `json:"hints"`
+	MissingBinary string          `json:"missing_binary,omitempty"`
+	Install       string          `json:"install,omitempty"` // Command installing MissingBinary, when known
+	Locations     []ErrorLocation `json:"locations,omitempty"`
+	FailedTests   []string        `json:"failed_tests,omitempty"`
+}
+
+// ErrorLocation is a compiler or runtime error position in the output
+type ErrorLocation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	maxAdviceLocations = 10
+	maxAdviceTests     = 10
+)
+
+var (
+	// bash: foo: command not found / sh: 1: foo: not found / zsh: command not found: foo
+	notFoundPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)(?:^|: )([\w.+-]+): command not found`),
+		regexp.MustCompile(`(?m)command not found: ([\w.+-]+)`),
+		regexp.MustCompile(`(?m)^(?:/bin/)?sh: \d+: ([\w.+-]+): not found`),
+		regexp.MustCompile(`exec: "([\w.+-]+)": executable file not found`),
+	}
+
+	// Go, gcc/clang, rustc (--> file:line:col), eslint-style: file:line:col: message
+	fileLinePattern = regexp.MustCompile(`(?m)^(?:\s*--> )?\s*([\w./\\-]+\.(?:go|rs|c|cc|cpp|h|hpp|java|kt|swift|cs|rb|php|ts|tsx|js|jsx|mjs|vue|py|tf|ex|exs|scala|zig)):(\d+)(?::(\d+))?:?\s*(.*)$`)
+	// tsc: file.ts(12,5): error TS2322: ...
+	tscPattern = regexp.MustCompile(`(?m)^([\w./\\-]+\.(?:ts|tsx|js|jsx))\((\d+),(\d+)\): (.*)$`)
+	// Python tracebacks: File "app/x.py", line 12, in main
+	pyTracePattern = regexp.MustCompile(`(?m)^\s*File "([^"]+)", line (\d+)`)
+
+	// go test, pytest, cargo test, jest, rspec
+	failedTestPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^\s*--- FAIL: (\S+)`),
+		regexp.MustCompile(`(?m)^FAILED (\S+)`),
+		regexp.MustCompile(`(?m)^test (\S+) \.\.\. FAILED`),
+		regexp.MustCompile(`(?m)^\s*● (.+ › .+)$`),
+		regexp.MustCompile(`(?m)^rspec (\./\S+)`),
+	}
+)
+
+// recoveryMiddleware adds recovery advice to failed results
+func recoveryMiddleware(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+		result, err := next(ctx, inv)
+		if m, ok := result.(map[string]interface{}); ok && err == nil {
+			if advice := adviseRecovery(m); advice != nil {
+				m["recovery"] = advice
+			}
+		}
+		return result, err
+	}
+}
+
+// adviseRecovery returns advice for a failed result (non-zero exit code or
+// an error), or nil when it has none
+func adviseRecovery(result map[string]interface{}) *RecoveryAdvice {
+	exitCode, hasExit := exitCodeOf(result["exit_code"])
+	errMsg, _ := result["error"].(string)
+	if (!hasExit || exitCode == 0) && errMsg == "" {
+		return nil
+	}
+	output, _ := result["output"].(string)
+	text := output + "\n" + errMsg
+	command, _ := result["command"].(string)
+
+	advice := &RecoveryAdvice{}
+	if binary := missingBinary(text, exitCode); binary != "" {
+		advice.MissingBinary = binary
+		advice.Install = installCommand(binary)
+		hint := fmt.Sprintf("%q is not installed. Don't retry the command as is.", binary)
+		if advice.Install != "" {
+			hint += fmt.Sprintf(" Install it with `%s` if installing is acceptable, or", advice.Install)
+		}
+		hint += " use a tool that is available (check with `command -v`)."
+		advice.Hints = append(advice.Hints, hint)
+	}
+
+	advice.FailedTests = failedTests(text)
+	if len(advice.FailedTests) > 0 {
+		advice.Hints = append(advice.Hints, fmt.Sprintf("%d test(s) failed. Read the assertion output above, fix the code (or the test, if it is wrong), then rerun only these tests%s.",
+			len(advice.FailedTests), rerunExample(command, advice.FailedTests)))
+	}
+
+	advice.Locations = errorLocations(text)
+	if len(advice.Locations) > 0 {
+		first := advice.Locations[0]
+		advice.Hints = append(advice.Hints, fmt.Sprintf("Errors point to %d location(s). Read the code around them (e.g. read_file %s near line %d) and fix the first one before re-running; later errors are often caused by it.",
+			len(advice.Locations), first.File, first.Line))
+	}
+
+	lower := strings.ToLower(text)
+	switch {
+	case strings.Contains(lower, "permission denied"):
+		advice.Hints = append(advice.Hints, "Permission denied: check the file mode (ls -l) or whether the path is outside the working directory. Don't retry with sudo unless the user asked for it.")
+	case strings.Contains(lower, "no such file or directory") && advice.MissingBinary == "":
+		advice.Hints = append(advice.Hints, "A path does not exist: check it with list_dir or search_files instead of guessing variations.")
+	}
+
+	if len(advice.Hints) == 0 {
+		return nil
+	}
+	return advice
+}
+
+// exitCodeOf reads an exit code from a tool result (int, or float64 after JSON)
+func exitCodeOf(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// missingBinary finds the command the shell could not find
+func missingBinary(text string, exitCode int) string {
+	if exitCode != 127 && !strings.Contains(text, "not found") {
+		return ""
+	}
+	for _, pattern := range notFoundPatterns {
+		if m := pattern.FindStringSubmatch(text); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// errorLocations extracts file:line positions, first occurrence first
+func errorLocations(text string) []ErrorLocation {
+	var locations []ErrorLocation
+	seen := make(map[string]bool)
+	add := func(file, line, column, message string) {
+		n, err := strconv.Atoi(line)
+		key := file + ":" + line
+		if err != nil || seen[key] || len(locations) >= maxAdviceLocations {
+			return
+		}
+		seen[key] = true
+		col, _ := strconv.Atoi(column)
+		locations = append(locations, ErrorLocation{File: file, Line: n, Column: col, Message: truncateString(strings.TrimSpace(message), 160)})
+	}
+
+	for _, m := range fileLinePattern.FindAllStringSubmatch(text, -1) {
+		add(m[1], m[2], m[3], m[4])
+	}
+	for _, m := range tscPattern.FindAllStringSubmatch(text, -1) {
+		add(m[1], m[2], m[3], m[4])
+	}
+	// Python: the innermost frame is the last one
+	frames := pyTracePattern.FindAllStringSubmatch(text, -1)
+	for i := len(frames) - 1; i >= 0; i-- {
+		add(frames[i][1], frames[i][2], "", "")
+	}
+	return locations
+}
+
+// failedTests extracts the names of failing tests
+func failedTests(text string) []string {
+	var tests []string
+	seen := make(map[string]bool)
+	for _, pattern := range failedTestPatterns {
+		for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+			name := strings.TrimSpace(m[1])
+			if !seen[name] && len(tests) < maxAdviceTests {
+				seen[name] = true
+				tests = append(tests, name)
+			}
+		}
+	}
+	return tests
+}
+
+// rerunExample suggests rerunning only the failed Go tests
+func rerunExample(command string, tests []string) string {
+	if !strings.Contains(command, "go test") {
+		return ""
+	}
+	var names []string
+	for _, test := range tests {
+		name, _, _ := strings.Cut(test, "/") // Subtests run with their parent
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return fmt.Sprintf(" (e.g. go test -run '^(%s)$' <package>)", strings.Join(names, "|"))
+}
+
+// Tools installed by a language toolchain rather than the system package manager
+var toolchainInstalls = map[string]string{
+	"golangci-lint": "go install github.com/golangci/golangci-lint/cmd/golangci-lint@latest",
+	"staticcheck":   "go install honnef.co/go/tools/cmd/staticcheck@latest",
+	"gopls":         "go install golang.org/x/tools/gopls@latest",
+	"goimports":     "go install golang.org/x/tools/cmd/goimports@latest",
+	"tsc":           "npm install --save-dev typescript (or run it with npx tsc)",
+	"eslint":        "npm install --save-dev eslint (or run it with npx eslint)",
+	"prettier":      "npm install --save-dev prettier (or run it with npx prettier)",
+	"jest":          "npm install --save-dev jest (or run it with npx jest)",
+	"pytest":        "pip install pytest (or python -m pytest)",
+	"black":         "pip install black",
+	"ruff":          "pip install ruff",
+	"mypy":          "pip install mypy",
+}
+
+// System package names that differ from the binary, by package manager
+var packageNames = map[string]map[string]string{
+	"rg":      {"": "ripgrep"},
+	"fd":      {"apt-get": "fd-find"},
+	"ag":      {"apt-get": "silversearcher-ag", "brew": "the_silver_searcher"},
+	"go":      {"apt-get": "golang"},
+	"node":    {"apt-get": "nodejs"},
+	"npm":     {"brew": "node"},
+	"npx":     {"apt-get": "npm", "brew": "node"},
+	"python":  {"apt-get": "python3", "brew": "python"},
+	"python3": {"brew": "python"},
+	"pip":     {"apt-get": "python3-pip", "brew": "python"},
+	"pip3":    {"apt-get": "python3-pip", "brew": "python"},
+	"cargo":   {"brew": "rust"},
+	"rustc":   {"brew": "rust"},
+	"gcc":     {"apt-get": "build-essential"},
+	"make":    {"apt-get": "build-essential"},
+	"psql":    {"apt-get": "postgresql-client", "brew": "libpq"},
+}
+
+var (
+	packageManagerOnce sync.Once
+	packageManager     string
+)
+
+// installCommand suggests how to install a missing binary ("" if unknown)
+func installCommand(binary string) string {
+	if cmd, ok := toolchainInstalls[binary]; ok {
+		return cmd
+	}
+	packageManagerOnce.Do(func() {
+		for _, pm := range []string{"brew", "apt-get", "dnf", "yum", "apk", "pacman"} {
+			if _, err := exec.LookPath(pm); err == nil {
+				packageManager = pm
+				return
+			}
+		}
+	})
+
+	pkg := binary
+	if names, ok := packageNames[binary]; ok {
+		if name, ok := names[packageManager]; ok {
+			pkg = name
+		} else if name, ok := names[""]; ok {
+			pkg = name
+		}
+	}
+	switch packageManager {
+	case "brew":
+		return "brew install " + pkg
+	case "apt-get":
+		return "sudo apt-get install -y " + pkg
+	case "dnf", "yum":
+		return "sudo " + packageManager + " install -y " + pkg
+	case "apk":
+		return "apk add " + pkg
+	case "pacman":
+		return "sudo pacman -S --noconfirm " + pkg
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TOOL RESULT REFERENCES
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Large tool outputs are kept in full in the session's result store and
+// reach the context with a reference line on top:
+//
+//	[full output: expand_result ref=r3]
+//
+// Whatever later trims the result (tool output limits, history pruning), the
+// model can read the original again with expand_result.
+
+const (
+	// resultRefMinBytes is the smallest tool result given a reference; smaller
+	// results are never pruned
+	resultRefMinBytes = 4000
+
+	// maxStoredResultBytes caps one stored output
+	maxStoredResultBytes = 1 << 20
+)
+
+var resultRefPattern = regexp.MustCompile(`expand_result ref=(r\d+)`)
+
+// ResultRef returns the stored-output reference in a tool result ("" if none)
+func ResultRef(content string) string {
+	if m := resultRefPattern.FindStringSubmatch(content); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ResultRefNote is the line pointing the model at a stored output
+func ResultRefNote(ref string) string {
+	return fmt.Sprintf("[full output: expand_result ref=%s]", ref)
+}
+
+// truncateWithRef is truncateOutput that keeps the full output retrievable:
+// when output is cut and a session is running, it is stored and referenced
+func truncateWithRef(ctx context.Context, output string, maxBytes int) string {
+	truncated := truncateOutput(output, maxBytes)
+	if truncated == output {
+		return output
+	}
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return truncated
+	}
+	return ResultRefNote(session.StoreResult(output)) + "\n" + truncated
+}
+
+// referenceToolResult stores a large tool result in the session and returns
+// the content to put in context: the reference line plus the result, cut to
+// MaxToolOutputBytes. Results already carrying a reference are left alone.
+func referenceToolResult(session *Session, toolName, content string) string {
+	if session == nil || toolName == "expand_result" || len(content) < resultRefMinBytes || ResultRef(content) != "" {
+		return content
+	}
+	ref := session.StoreResult(content)
+	return ResultRefNote(ref) + "\n" + truncateOutput(content, MaxToolOutputBytes)
+}
+
+// ExpandResultTool reads a stored tool output back by reference
+type ExpandResultTool struct {
+	BaseTool
+}
+
+// NewExpandResultTool creates the expand_result tool
+func NewExpandResultTool() *ExpandResultTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Reference from a [full output: expand_result ref=...] line, e.g. r3",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Byte offset to start reading at (default 0; use next_offset to continue)",
+			},
+			"length": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Bytes to read (default and max %d)", MaxToolOutputBytes),
+			},
+		},
+		"required": []string{"ref"},
+	}
+
+	return &ExpandResultTool{
+		BaseTool: NewBaseTool(
+			"expand_result",
+			"Retrieve the full text of an earlier tool output that was shortened in context. Only for results marked [full output: expand_result ref=...].",
+			params,
+		),
+	}
+}
+
+func (t *ExpandResultTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	ref, _ := args["ref"].(string)
+	if ref == "" {
+		return nil, fmt.Errorf("ref is required")
+	}
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("no session: stored outputs are only available inside a session")
+	}
+	content, ok := session.GetResult(ref)
+	if !ok {
+		return nil, fmt.Errorf("unknown result reference: %s", ref)
+	}
+
+	offset := 0
+	if o, ok := args["offset"].(float64); ok && o > 0 {
+		offset = int(o)
+	}
+	length := MaxToolOutputBytes
+	if l, ok := args["length"].(float64); ok && l > 0 && int(l) < length {
+		length = max(int(l), utf8.UTFMax)
+	}
+	if offset > len(content) {
+		offset = len(content)
+	}
+	end := offset + length
+	if end > len(content) {
+		end = len(content)
+	}
+	// Don't split UTF-8 sequences
+	for offset > 0 && offset < len(content) && !utf8.RuneStart(content[offset]) {
+		offset--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end--
+	}
+
+	result := map[string]interface{}{
+		"ref":         ref,
+		"offset":      offset,
+		"total_bytes": len(content),
+		"content":     content[offset:end],
+	}
+	if end < len(content) {
+		result["next_offset"] = end
+	}
+	return result, nil
+}
@@ -0,0 +1,347 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/jsonrepair"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SELF-REVIEW
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// When the agent believes a task that changed files is done, a reviewer (the
+// same model or another one) gets the original request, the diff of the run
+// and the output of the build/test command. It approves, or its concrete
+// fix instructions go back into the loop, at most MaxRounds times.
+
+const (
+	defaultReviewRounds  = 2
+	verifyTimeout        = 10 * time.Minute
+	maxReviewDiffBytes   = 60000
+	maxVerifyOutputBytes = 8000
+)
+
+// SelfReview configures the review pass (see SetSelfReview). A review that
+// still finds problems after MaxRounds fixes is reported, not sent back.
+type SelfReview struct {
+	Caller        AICaller // Reviewer; nil reviews with the agent's own caller
+	Model         string   // Reviewer model ("" = the agent's)
+	VerifyCommand string   // Build/test command ("" = detected from the project, "none" = skip)
+	MaxRounds     int      // Fix rounds sent back to the agent (default 2)
+}
+
+// SetSelfReview enables the review pass before a run that changed files
+// is finalized (nil disables it)
+func (a *Agent) SetSelfReview(review *SelfReview) {
+	a.review = review
+}
+
+// ReviewOutcome returns how the last Run's review went (nil when there was
+// no review: disabled, or nothing changed)
+func (a *Agent) ReviewOutcome() *types.ReviewOutcome {
+	return a.reviewOutcome
+}
+
+// reviewBaseline is the working tree before the run, to diff against
+type reviewBaseline struct {
+	dir       string
+	commit    string          // Snapshot of tracked files (git stash create, or HEAD)
+	untracked map[string]bool // Untracked files that already existed
+}
+
+// newReviewBaseline snapshots the working tree without touching it; nil
+// outside a git repository (changes are then taken from the tool calls)
+func newReviewBaseline(ctx context.Context, dir string) *reviewBaseline {
+	if out, err := gitOutput(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil || out != "true" {
+		return nil
+	}
+	commit, err := gitOutput(ctx, dir, "stash", "create")
+	if err != nil {
+		return nil
+	}
+	if commit == "" { // Clean tree
+		if commit, err = gitOutput(ctx, dir, "rev-parse", "HEAD"); err != nil {
+			return nil // No commits yet
+		}
+	}
+	b := &reviewBaseline{dir: dir, commit: commit, untracked: make(map[string]bool)}
+	for _, f := range untrackedFiles(ctx, dir) {
+		b.untracked[f] = true
+	}
+	return b
+}
+
+// diff returns the changes since the baseline, new untracked files included
+func (b *reviewBaseline) diff(ctx context.Context) string {
+	var sb strings.Builder
+	if out, err := gitOutput(ctx, b.dir, "diff", b.commit); err == nil && out != "" {
+		sb.WriteString(out)
+		sb.WriteString("\n")
+	}
+	for _, f := range untrackedFiles(ctx, b.dir) {
+		if b.untracked[f] {
+			continue
+		}
+		// --no-index exits 1 when the files differ, which they always do here
+		cmd := exec.CommandContext(ctx, "git", "diff", "--no-index", "--", os.DevNull, f)
+		cmd.Dir = b.dir
+		out, _ := cmd.Output()
+		sb.Write(out)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func untrackedFiles(ctx context.Context, dir string) []string {
+	out, err := gitOutput(ctx, dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// fileWriteTools are the tools whose "path" argument names a file they change
+var fileWriteTools = map[string]bool{
+	"write_file":    true,
+	"edit_file":     true,
+	"multi_edit":    true,
+	"append_file":   true,
+	"begin_write":   true, // Chunked writes name the file when they begin
+	"generate_like": true,
+}
+
+// writtenFiles lists the files the run's tool calls wrote, with their
+// current content, for reviews outside git
+func writtenFiles(ctx context.Context, messages []ai.Message) string {
+	paths := make(map[string]bool)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			if path, _ := call.Args["path"].(string); path != "" && fileWriteTools[call.Name] {
+				paths[ResolvePath(ctx, "", path)] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var sb strings.Builder
+	for _, path := range sorted {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "=== %s (unreadable: %v)\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "=== %s\n%s\n", path, content)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// detectVerifyCommand picks a build/test command for the project in dir
+func detectVerifyCommand(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return "go build ./... && go vet ./... && go test ./..."
+	case exists("Cargo.toml"):
+		return "cargo build && cargo test"
+	case exists("package.json"):
+		if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil && strings.Contains(string(data), `"test"`) {
+			return "npm test"
+		}
+	case exists("pyproject.toml"), exists("pytest.ini"), exists("setup.py"):
+		return "python -m pytest -q"
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Makefile")); err == nil {
+		for _, task := range parseMakefile(string(data)) {
+			if task.Name == "test" {
+				return task.Run
+			}
+		}
+	}
+	return ""
+}
+
+// verification is the outcome of the build/test command
+type verification struct {
+	command string
+	passed  bool
+	output  string
+}
+
+// runVerification runs the review's build/test command in dir; nil when
+// there is none
+func (a *Agent) runVerification(ctx context.Context, dir string) *verification {
+	command := a.review.VerifyCommand
+	if command == "" {
+		command = detectVerifyCommand(dir)
+	}
+	if command == "" || command == "none" {
+		return nil
+	}
+
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return &verification{command: command, output: err.Error()}
+	}
+	verifyCtx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(verifyCtx, "bash", "-c", command)
+	cmd.Dir = dir
+	env.apply(cmd)
+	output, err := cmd.CombinedOutput()
+
+	// Failures are usually at the end
+	out := env.redact(strings.TrimSpace(string(output)))
+	if len(out) > maxVerifyOutputBytes {
+		out = "...\n" + out[len(out)-maxVerifyOutputBytes:]
+	}
+	if verifyCtx.Err() == context.DeadlineExceeded {
+		out += fmt.Sprintf("\n(timed out after %s)", verifyTimeout)
+	}
+	return &verification{command: command, passed: err == nil, output: out}
+}
+
+// reviewVerdict is the reviewer's JSON answer
+type reviewVerdict struct {
+	Approved     bool     `json:"approved"`
+	Issues       []string `json:"issues"`
+	Instructions string   `json:"instructions"`
+}
+
+const reviewPromptTemplate = `You are reviewing another engineer's work before it is handed back. Check the diff against the original request: is everything asked for done, correctly, without unrelated changes, leftover debugging or broken code?
+
+ORIGINAL REQUEST:
+%s
+
+THE ENGINEER'S SUMMARY:
+%s
+
+CHANGES:
+%s
+
+%s
+Reply with ONLY a JSON object:
+{"approved": true|false, "issues": ["each concrete problem"], "instructions": "precise steps to fix them (files, functions, what to change)"}
+Approve unless something is wrong or missing; do not ask for stylistic rewrites.`
+
+// selfReview reviews the run's changes. It returns the instructions to send
+// back to the agent, or "" when the work is approved (or there is nothing
+// to review).
+func (a *Agent) selfReview(ctx context.Context, request, summary string, baseline *reviewBaseline, messages []ai.Message, step, round int) string {
+	dir := BaseDir(ctx, "")
+	var changes string
+	if baseline != nil {
+		changes = baseline.diff(ctx)
+	} else {
+		changes = writtenFiles(ctx, messages)
+	}
+	if changes == "" {
+		return "" // Nothing changed, nothing to review
+	}
+	if len(changes) > maxReviewDiffBytes {
+		changes = changes[:maxReviewDiffBytes] + "\n... (diff truncated)"
+	}
+
+	a.emitProgress("review", step, fmt.Sprintf("Reviewing the changes (review %d)...", round), nil)
+	verify := a.runVerification(ctx, dir)
+	verifySection := ""
+	if verify != nil {
+		status := "PASSED"
+		if !verify.passed {
+			status = "FAILED"
+		}
+		verifySection = fmt.Sprintf("BUILD/TESTS (%s): %s\n%s\n", verify.command, status, verify.output)
+	}
+
+	outcome := &types.ReviewOutcome{Rounds: round}
+	if verify != nil {
+		outcome.VerifyCommand = verify.command
+		outcome.VerifyPassed = &verify.passed
+	}
+	a.reviewOutcome = outcome
+
+	caller, model := a.review.Caller, a.review.Model
+	if caller == nil {
+		caller = a.aiCaller
+	}
+	if model == "" {
+		model = a.currentModel
+	}
+	prompt := fmt.Sprintf(reviewPromptTemplate, request, summary, changes, verifySection)
+	reviewCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	resp, err := caller.Chat(reviewCtx, ai.ChatRequest{
+		Model:       model,
+		Messages:    []ai.Message{{Role: "user", Content: prompt}},
+		Temperature: 0.2,
+		MaxTokens:   2000,
+	})
+
+	var verdict reviewVerdict
+	if err == nil {
+		_, err = jsonrepair.Unmarshal(resp.Content, &verdict)
+	}
+	if err != nil {
+		// A broken reviewer doesn't block the task, failing tests still do
+		log.Printf("[Agent] Self-review failed: %v", err)
+		verdict = reviewVerdict{Approved: true}
+	}
+	if verify != nil && !verify.passed {
+		verdict.Approved = false
+		verdict.Issues = append(verdict.Issues, fmt.Sprintf("%s fails", verify.command))
+	}
+
+	outcome.Approved = verdict.Approved
+	outcome.Issues = verdict.Issues
+	if verdict.Approved {
+		a.emitProgress("review", step, "Review approved the changes", map[string]interface{}{"round": round})
+		return ""
+	}
+	a.emitProgress("review", step, fmt.Sprintf("Review found %d issue(s), fixing", len(verdict.Issues)), map[string]interface{}{
+		"round":  round,
+		"issues": verdict.Issues,
+	})
+
+	var fix strings.Builder
+	fix.WriteString("A review of your changes found problems. Fix them, verify, then give your final answer again.\n")
+	for _, issue := range verdict.Issues {
+		fix.WriteString("- " + issue + "\n")
+	}
+	if verdict.Instructions != "" {
+		fix.WriteString("\nInstructions: " + verdict.Instructions + "\n")
+	}
+	if verify != nil && !verify.passed {
+		fmt.Fprintf(&fix, "\nOutput of `%s`:\n%s\n", verify.command, verify.output)
+	}
+	return fix.String()
+}
+
+func (r *SelfReview) maxRounds() int {
+	if r.MaxRounds > 0 {
+		return r.MaxRounds
+	}
+	return defaultReviewRounds
+}
@@ -1,10 +1,15 @@
 package agent
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // Session manages conversation state and history
@@ -14,11 +19,47 @@ type Session struct {
 	createdAt               time.Time
 	updatedAt               time.Time
 	workingDir              string
-	contextLimit            int  // Limit on messages sent (0 = no limit, default 50)
-	qwenLargeContextEnabled bool // Enable 256k context for Qwen (default false)
+	contextLimit            int                   // Token cap on history sent (0 = target model's window)
+	qwenLargeContextEnabled bool                  // Enable 256k context for Qwen (default false)
+	fileHashes              map[string]string     // Content hash of files as last read/written by tools
+	env                     map[string]string     // Variables injected into exec/process (values may be secret refs)
+	resourceLimits          *types.ResourceLimits // Per-session tightening of tool limits (nil = gateway defaults)
+	title                   string                // Short summary of the conversation (from the first exchange)
+	tags                    []string              // User labels (sorted, lowercase)
+	project                 string                // Project the session belongs to (e.g. owner/repo from the git remote)
+	provider                string                // Provider chosen for this session ("" = gateway default)
+	model                   string                // Model chosen for this session ("" = provider default)
+	thinkingLevel           string                // Thinking level chosen for this session ("" = model default)
+	language                string                // Language tag for answers, e.g. pt-BR ("" = gateway default)
+	lastExchange            Exchange              // Provider/model/record of the latest answer
+	feedback                []Feedback            // User ratings of answers
+	results                 map[string]string     // Full tool outputs by reference (see expand_result)
+	protectedAllowed        []string              // Protected path patterns an operator allowed (not persisted)
+	execApproved            []string              // Command classes an operator approved (not persisted)
+	recoveredAt             time.Time             // When a run last crashed and the session was repaired (not persisted)
+	dirtyTreeSeen           string                // User changes last reported to the client (not persisted)
+	externalSeen            map[string]string     // Outside changes to tracked files already reported to the model (not persisted)
 	mu                      sync.RWMutex
 }
 
+// Exchange identifies who produced the latest answer in a session
+type Exchange struct {
+	Provider string
+	Model    string
+	RecordID string // Dataset record (empty when recording is disabled)
+}
+
+// Feedback is a user rating of an assistant answer
+type Feedback struct {
+	Rating       int       `json:"rating"` // +1 good, -1 bad
+	Comment      string    `json:"comment,omitempty"`
+	MessageIndex int       `json:"message_index"` // Index of the rated assistant message
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	RecordID     string    `json:"record_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // NewSession creates a new session
 func NewSession(id string) *Session {
 	if id == "" {
@@ -31,8 +72,10 @@ func NewSession(id string) *Session {
 		createdAt:               now,
 		updatedAt:               now,
 		messages:                []ai.Message{},
-		contextLimit:            50,    // Default context limit
 		qwenLargeContextEnabled: false, // Default: disabled to avoid crashes
+		fileHashes:              make(map[string]string),
+		env:                     make(map[string]string),
+		results:                 make(map[string]string),
 	}
 }
 
@@ -41,6 +84,9 @@ func (s *Session) AddMessage(msg ai.Message) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
 	s.messages = append(s.messages, msg)
 	s.updatedAt = time.Now()
 }
@@ -56,6 +102,55 @@ func (s *Session) GetMessages() []ai.Message {
 	return messages
 }
 
+// PinnedMessage is a pinned message and its position in the history
+type PinnedMessage struct {
+	Index   int    `json:"index"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// PinMessage pins or unpins the message at index; pinned messages are never
+// dropped, summarized or compressed when the history is trimmed. An index of
+// -1 selects the latest user message.
+func (s *Session) PinMessage(index int, pinned bool) (PinnedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index == -1 {
+		for i := len(s.messages) - 1; i >= 0; i-- {
+			if s.messages[i].Role == "user" {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return PinnedMessage{}, types.Errorf(types.ErrNotFound, "no message to pin yet")
+		}
+	}
+	if index < 0 || index >= len(s.messages) {
+		return PinnedMessage{}, types.Errorf(types.ErrInvalidArgument, "message index %d out of range (0-%d)", index, len(s.messages)-1)
+	}
+
+	s.messages[index].Pinned = pinned
+	s.updatedAt = time.Now()
+	msg := s.messages[index]
+	return PinnedMessage{Index: index, Role: msg.Role, Content: msg.Content}, nil
+}
+
+// GetPinnedMessages returns pinned messages in history order
+func (s *Session) GetPinnedMessages() []PinnedMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pinned := []PinnedMessage{}
+	for i, msg := range s.messages {
+		if msg.Pinned {
+			pinned = append(pinned, PinnedMessage{Index: i, Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return pinned
+}
+
 // ClearMessages clears all messages except system messages
 func (s *Session) ClearMessages() {
 	s.mu.Lock()
@@ -89,20 +184,37 @@ func (s *Session) GetWorkingDir() string {
 	return s.workingDir
 }
 
-// SetContextLimit sets the context limit (0 = no limit)
+// SetContextLimit caps the tokens of history sent per request (0 = fit the model's window)
 func (s *Session) SetContextLimit(limit int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.contextLimit = limit
 }
 
-// GetContextLimit returns the context limit
+// GetContextLimit returns the history token cap (0 = model's window)
 func (s *Session) GetContextLimit() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.contextLimit
 }
 
+// ParseTokenCount parses a token count such as "32000", "32k" or "1m"
+func ParseTokenCount(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1000, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		multiplier, s = 1000000, strings.TrimSuffix(s, "m")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid token count %q", s)
+	}
+	return n * multiplier, nil
+}
+
 // SetQwenLargeContextEnabled enables/disables Qwen large context window
 func (s *Session) SetQwenLargeContextEnabled(enabled bool) {
 	s.mu.Lock()
@@ -117,17 +229,423 @@ func (s *Session) GetQwenLargeContextEnabled() bool {
 	return s.qwenLargeContextEnabled
 }
 
+// SetFileHash records the content hash of a file as last seen by this session
+func (s *Session) SetFileHash(path, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fileHashes == nil {
+		s.fileHashes = make(map[string]string)
+	}
+	s.fileHashes[path] = hash
+}
+
+// GetFileHash returns the content hash of a file as last seen by this session
+func (s *Session) GetFileHash(path string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hash, ok := s.fileHashes[path]
+	return hash, ok
+}
+
+// forgetFileHash stops tracking a file
+func (s *Session) forgetFileHash(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.fileHashes, path)
+}
+
+// trackedFiles returns the files the session's tools read or wrote, with
+// their content hashes
+func (s *Session) trackedFiles() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	files := make(map[string]string, len(s.fileHashes))
+	for path, hash := range s.fileHashes {
+		files[path] = hash
+	}
+	return files
+}
+
+// noteExternalChange records a change to a tracked file made outside the
+// session's tools ("" = deleted), and whether it wasn't reported yet
+func (s *Session) noteExternalChange(path, hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seen, ok := s.externalSeen[path]; ok && seen == hash {
+		return false
+	}
+	if s.externalSeen == nil {
+		s.externalSeen = make(map[string]string)
+	}
+	s.externalSeen[path] = hash
+	return true
+}
+
+// SetEnv merges variables into the session environment (an empty value removes the variable)
+func (s *Session) SetEnv(vars map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range vars {
+		if v == "" {
+			delete(s.env, k)
+		} else {
+			s.env[k] = v
+		}
+	}
+}
+
+// GetEnv returns a copy of the session environment
+func (s *Session) GetEnv() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	env := make(map[string]string, len(s.env))
+	for k, v := range s.env {
+		env[k] = v
+	}
+	return env
+}
+
+// AllowProtected lets tools modify paths matching the given protected
+// patterns in this session ("*" allows all), and revokes others
+func (s *Session) AllowProtected(allow, revoke []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protectedAllowed = slices.DeleteFunc(s.protectedAllowed, func(p string) bool { return slices.Contains(revoke, p) })
+	for _, p := range allow {
+		if p = strings.TrimSpace(p); p != "" && !slices.Contains(s.protectedAllowed, p) {
+			s.protectedAllowed = append(s.protectedAllowed, p)
+		}
+	}
+}
+
+// ProtectedAllowed returns the protected path patterns allowed in this session
+func (s *Session) ProtectedAllowed() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.protectedAllowed)
+}
+
+// ApproveExec lets exec/process commands of the given classes run in this
+// session under the read-only approval policy ("*" approves all), and
+// revokes others
+func (s *Session) ApproveExec(allow, revoke []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.execApproved = slices.DeleteFunc(s.execApproved, func(c string) bool { return slices.Contains(revoke, c) })
+	for _, c := range allow {
+		if c = strings.TrimSpace(c); c != "" && !slices.Contains(s.execApproved, c) {
+			s.execApproved = append(s.execApproved, c)
+		}
+	}
+}
+
+// ExecApproved returns the command classes approved in this session
+func (s *Session) ExecApproved() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.execApproved)
+}
+
+// MarkRecovered records that a run crashed and the session was repaired
+// to be continued (see recoverStep)
+func (s *Session) MarkRecovered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recoveredAt = time.Now()
+}
+
+// RecoveredAt returns when a run last crashed (zero if none did)
+func (s *Session) RecoveredAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recoveredAt
+}
+
+// noteDirtyTree records the user's uncommitted files reported to the
+// client, and whether they differ from the last report
+func (s *Session) noteDirtyTree(files string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.dirtyTreeSeen != files
+	s.dirtyTreeSeen = files
+	return changed
+}
+
+// SetResourceLimits sets per-session tool limits (nil restores the gateway defaults)
+func (s *Session) SetResourceLimits(limits *types.ResourceLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resourceLimits = limits
+}
+
+// GetResourceLimits returns the per-session tool limits, or nil if none are set
+func (s *Session) GetResourceLimits() *types.ResourceLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resourceLimits
+}
+
+// SetTitle sets the session's title
+func (s *Session) SetTitle(title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.title = title
+}
+
+// GetTitle returns the session's title (empty until the first exchange)
+func (s *Session) GetTitle() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.title
+}
+
+// AddTags adds labels to the session (normalized to lowercase, duplicates ignored)
+func (s *Session) AddTags(tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !slices.Contains(s.tags, tag) {
+			s.tags = append(s.tags, tag)
+		}
+	}
+	slices.Sort(s.tags)
+}
+
+// RemoveTags removes labels from the session
+func (s *Session) RemoveTags(tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		s.tags = slices.DeleteFunc(s.tags, func(t string) bool { return t == tag })
+	}
+}
+
+// GetTags returns a copy of the session's labels
+func (s *Session) GetTags() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.tags)
+}
+
+// HasTag reports whether the session carries a label
+func (s *Session) HasTag(tag string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Contains(s.tags, NormalizeTag(tag))
+}
+
+// NormalizeTag lowercases a tag and strips a leading '#' and surrounding space
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// SetProject associates the session with a project
+func (s *Session) SetProject(project string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.project = project
+}
+
+// GetProject returns the session's project (empty if unknown)
+func (s *Session) GetProject() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.project
+}
+
+// SetModel remembers the provider and model for later requests to the session,
+// whichever client sends them ("" model = the provider's default)
+func (s *Session) SetModel(provider, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.provider = provider
+	s.model = model
+}
+
+// GetModel returns the session's provider and model ("" = not chosen)
+func (s *Session) GetModel() (provider, model string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.provider, s.model
+}
+
+// SetThinkingLevel remembers the thinking level for later requests
+func (s *Session) SetThinkingLevel(level string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.thinkingLevel = level
+}
+
+// GetThinkingLevel returns the session's thinking level ("" = model default)
+func (s *Session) GetThinkingLevel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.thinkingLevel
+}
+
+// SetLanguage remembers the language answers are written in ("" = default)
+func (s *Session) SetLanguage(tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.language = tag
+}
+
+// GetLanguage returns the session's language tag ("" = gateway default)
+func (s *Session) GetLanguage() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.language
+}
+
+// SetLastExchange records which provider/model produced the latest answer
+func (s *Session) SetLastExchange(ex Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastExchange = ex
+}
+
+// RateLastExchange attaches feedback to the latest assistant answer
+func (s *Session) RateLastExchange(rating int, comment string) (Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := -1
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].Role == "assistant" && len(s.messages[i].ToolCalls) == 0 {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return Feedback{}, types.Errorf(types.ErrNotFound, "no answer to rate yet")
+	}
+
+	fb := Feedback{
+		Rating:       rating,
+		Comment:      comment,
+		MessageIndex: index,
+		Provider:     s.lastExchange.Provider,
+		Model:        s.lastExchange.Model,
+		RecordID:     s.lastExchange.RecordID,
+		CreatedAt:    time.Now(),
+	}
+	// Re-rating the same answer replaces the earlier rating
+	if n := len(s.feedback); n > 0 && s.feedback[n-1].MessageIndex == index {
+		s.feedback[n-1] = fb
+	} else {
+		s.feedback = append(s.feedback, fb)
+	}
+	s.updatedAt = time.Now()
+	return fb, nil
+}
+
+// AddFeedback appends stored feedback (used when loading persisted sessions)
+func (s *Session) AddFeedback(fb Feedback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.feedback = append(s.feedback, fb)
+}
+
+// GetFeedback returns a copy of the session's feedback
+func (s *Session) GetFeedback() []Feedback {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	feedback := make([]Feedback, len(s.feedback))
+	copy(feedback, s.feedback)
+	return feedback
+}
+
+// StoreResult keeps a full tool output and returns its reference (r1, r2, ...).
+// Outputs over maxStoredResultBytes are cut to that size.
+func (s *Session) StoreResult(content string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(content) > maxStoredResultBytes {
+		content = content[:maxStoredResultBytes] + "\n... [stored output capped at 1 MB]"
+	}
+	if s.results == nil {
+		s.results = make(map[string]string)
+	}
+	ref := fmt.Sprintf("r%d", len(s.results)+1)
+	s.results[ref] = content
+	return ref
+}
+
+// SetResult restores a stored tool output (used when loading a session)
+func (s *Session) SetResult(ref, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.results == nil {
+		s.results = make(map[string]string)
+	}
+	s.results[ref] = content
+}
+
+// GetResult returns a stored tool output by reference
+func (s *Session) GetResult(ref string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, ok := s.results[ref]
+	return content, ok
+}
+
+// GetResults returns a copy of all stored tool outputs by reference
+func (s *Session) GetResults() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[string]string, len(s.results))
+	for ref, content := range s.results {
+		results[ref] = content
+	}
+	return results
+}
+
 // GetStats returns session statistics
 func (s *Session) GetStats() SessionStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	stats := SessionStats{
-		SessionID:    s.ID,
-		CreatedAt:    s.createdAt,
-		UpdatedAt:    s.updatedAt,
-		MessageCount: len(s.messages),
-		WorkingDir:   s.workingDir,
+		SessionID:     s.ID,
+		Title:         s.title,
+		Tags:          slices.Clone(s.tags),
+		Project:       s.project,
+		Provider:      s.provider,
+		Model:         s.model,
+		ThinkingLevel: s.thinkingLevel,
+		Language:      s.language,
+		CreatedAt:     s.createdAt,
+		UpdatedAt:     s.updatedAt,
+		MessageCount:  len(s.messages),
+		WorkingDir:    s.workingDir,
+		StoredResults: len(s.results),
+	}
+	if !s.recoveredAt.IsZero() {
+		recovered := s.recoveredAt
+		stats.RecoveredAt = &recovered
 	}
 
 	// Count message types
@@ -142,6 +660,16 @@ func (s *Session) GetStats() SessionStats {
 		case "system":
 			stats.SystemMessages++
 		}
+		if msg.Pinned {
+			stats.PinnedMessages++
+		}
+	}
+	for _, fb := range s.feedback {
+		if fb.Rating > 0 {
+			stats.RatedGood++
+		} else if fb.Rating < 0 {
+			stats.RatedBad++
+		}
 	}
 
 	return stats
@@ -149,15 +677,27 @@ func (s *Session) GetStats() SessionStats {
 
 // SessionStats contains session statistics
 type SessionStats struct {
-	SessionID         string    `json:"session_id"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
-	MessageCount      int       `json:"message_count"`
-	UserMessages      int       `json:"user_messages"`
-	AssistantMessages int       `json:"assistant_messages"`
-	ToolMessages      int       `json:"tool_messages"`
-	SystemMessages    int       `json:"system_messages"`
-	WorkingDir        string    `json:"working_dir"`
+	SessionID         string     `json:"session_id"`
+	Title             string     `json:"title,omitempty"`
+	Tags              []string   `json:"tags,omitempty"`
+	Project           string     `json:"project,omitempty"`
+	Provider          string     `json:"provider,omitempty"`       // Chosen for the session
+	Model             string     `json:"model,omitempty"`          // Chosen for the session
+	ThinkingLevel     string     `json:"thinking_level,omitempty"` // Chosen for the session
+	Language          string     `json:"language,omitempty"`       // Chosen for the session
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	MessageCount      int        `json:"message_count"`
+	UserMessages      int        `json:"user_messages"`
+	AssistantMessages int        `json:"assistant_messages"`
+	ToolMessages      int        `json:"tool_messages"`
+	SystemMessages    int        `json:"system_messages"`
+	WorkingDir        string     `json:"working_dir"`
+	RatedGood         int        `json:"rated_good,omitempty"`
+	RatedBad          int        `json:"rated_bad,omitempty"`
+	PinnedMessages    int        `json:"pinned_messages,omitempty"`
+	StoredResults     int        `json:"stored_results,omitempty"` // Full tool outputs retrievable with expand_result
+	RecoveredAt       *time.Time `json:"recovered_at,omitempty"`   // Set when a run crashed and the session was repaired
 }
 
 // generateSessionID generates a unique session ID
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SESSION ENVIRONMENT
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Session env values are either literals or secret references:
+//
+//	keyring:<service>/<account>   OS keyring (macOS Keychain, Linux Secret Service)
+//	op://<vault>/<item>/<field>   1Password CLI (op read)
+//
+// Resolved values are injected into exec/process commands and scrubbed from
+// their output, so secrets never reach the transcript or the provider. Any
+// client can set a session's env, so only references the operator allowed
+// (tools.secret_refs) are resolved.
+
+// secretLookupTimeout bounds a single keyring/1Password lookup
+const secretLookupTimeout = 15 * time.Second
+
+// minRedactLen is the shortest literal value scrubbed from output; shorter
+// literals ("1", "dev") would mangle unrelated output. Secret refs are always scrubbed.
+const minRedactLen = 8
+
+// secretValue is an injected value that must be scrubbed from tool output
+type secretValue struct {
+	name  string
+	value string
+}
+
+// sessionEnv is the resolved environment for a command run on behalf of a session
+type sessionEnv struct {
+	vars    []string // KEY=value pairs to append to os.Environ()
+	secrets []secretValue
+}
+
+// isSecretRef reports whether an env value references a secret store
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, "keyring:") || strings.HasPrefix(value, "op://")
+}
+
+// SecretRefPolicy holds the secret references sessions may resolve (per gateway instance)
+type SecretRefPolicy struct {
+	allow []string
+	mu    sync.RWMutex
+}
+
+var globalSecretRefPolicy = &SecretRefPolicy{}
+
+// GetSecretRefPolicy returns the global secret reference policy
+func GetSecretRefPolicy() *SecretRefPolicy {
+	return globalSecretRefPolicy
+}
+
+// Set replaces the allowed refs and ref prefixes (empty = none)
+func (p *SecretRefPolicy) Set(allow []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allow = slices.Clone(allow)
+}
+
+// Allowed reports whether ref is an allowed ref or under an allowed prefix.
+// Only prefixes ending in "/" match more than themselves, so op://dev doesn't
+// allow op://devops/...
+func (p *SecretRefPolicy) Allowed(ref string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, a := range p.allow {
+		if ref == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(ref, a) && !strings.Contains(ref[len(a):], "..")) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSecretRef looks up a keyring: or op:// reference
+func resolveSecretRef(ctx context.Context, ref string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, secretLookupTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch {
+	case strings.HasPrefix(ref, "op://"):
+		cmd = exec.CommandContext(ctx, "op", "read", "--no-newline", ref)
+	case strings.HasPrefix(ref, "keyring:"):
+		service, account, ok := strings.Cut(strings.TrimPrefix(ref, "keyring:"), "/")
+		if !ok || service == "" || account == "" {
+			return "", fmt.Errorf("invalid keyring reference %q (want keyring:<service>/<account>)", ref)
+		}
+		if runtime.GOOS == "darwin" {
+			cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+		} else {
+			cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+		}
+	default:
+		return ref, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Never include stdout: a partial read could contain the secret
+		return "", fmt.Errorf("%s: %v: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// resolveSessionEnv resolves the session's variables (if any) for a command
+func resolveSessionEnv(ctx context.Context) (*sessionEnv, error) {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return &sessionEnv{}, nil
+	}
+	vars := session.GetEnv()
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := &sessionEnv{}
+	for _, name := range names {
+		value := vars[name]
+		secret := isSecretRef(value)
+		if secret {
+			if !GetSecretRefPolicy().Allowed(value) {
+				return nil, fmt.Errorf("resolve %s: %s is not in the gateway's tools.secret_refs", name, value)
+			}
+			resolved, err := resolveSecretRef(ctx, value)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s: %w", name, err)
+			}
+			value = resolved
+		}
+		env.vars = append(env.vars, name+"="+value)
+		if secret || len(value) >= minRedactLen {
+			env.secrets = append(env.secrets, secretValue{name: name, value: value})
+		}
+	}
+	// Scrub longest values first so a value containing another is replaced whole
+	sort.Slice(env.secrets, func(i, j int) bool { return len(env.secrets[i].value) > len(env.secrets[j].value) })
+	return env, nil
+}
+
+// apply injects the variables into cmd
+func (e *sessionEnv) apply(cmd *exec.Cmd) {
+	if len(e.vars) == 0 {
+		return
+	}
+	cmd.Env = append(os.Environ(), e.vars...)
+}
+
+// redact replaces injected values in output with [REDACTED:NAME]
+func (e *sessionEnv) redact(output string) string {
+	for _, s := range e.secrets {
+		if s.value != "" {
+			output = strings.ReplaceAll(output, s.value, "[REDACTED:"+s.name+"]")
+		}
+	}
+	return output
+}
+
+// names returns the injected variable names (never values) for tool results
+func (e *sessionEnv) names() []string {
+	names := make([]string, 0, len(e.vars))
+	for _, kv := range e.vars {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
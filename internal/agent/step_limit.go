@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// STEP LIMIT
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A run that uses all its steps mid-task doesn't fail: the agent asks the
+// model to summarize what is done and what is left, and returns that as a
+// partial result. All work stays in the session, so the client can extend
+// the run by sending types.ContinuePrompt with more steps (the CLI asks in
+// interactive mode) or keep the partial result (headless).
+
+const stepLimitPrompt = `You have used all the steps available for this task, and no more tool calls can run now.
+Summarize for the user, briefly:
+1. What is done (files changed, commands run, findings)
+2. What is left to do, in order
+3. Anything the user should know before continuing (broken state, open questions)
+Do not call tools.`
+
+// StepLimitReached reports whether the last Run stopped at max steps with a
+// progress summary instead of an answer
+func (a *Agent) StepLimitReached() bool {
+	return a.stepLimitReached
+}
+
+// stopAtStepLimit ends a run that reached max steps with a progress summary
+func (a *Agent) stopAtStepLimit(ctx context.Context, session *Session) (string, error) {
+	if a.responseSchema != nil {
+		// A summary can't stand in for a structured answer
+		return "", types.Errorf(types.ErrBudgetExceeded, "exceeded maximum steps (%d) before producing the structured answer", a.maxSteps)
+	}
+
+	a.stepLimitReached = true
+	a.emit(ProgressEvent{
+		Type:    "step_limit",
+		Step:    a.maxSteps,
+		Message: fmt.Sprintf("Reached the step limit (%d), summarizing progress...", a.maxSteps),
+		Data:    map[string]interface{}{"max_steps": a.maxSteps},
+	})
+
+	summary, err := a.progressSummary(ctx, session)
+	if err != nil {
+		log.Printf("[Agent] Progress summary failed: %v", err)
+		summary = fmt.Sprintf("Stopped after %d steps before finishing the task; the work so far is in this session.", a.maxSteps)
+	}
+	session.AddMessage(ai.Message{Role: "assistant", Content: summary})
+
+	a.emit(ProgressEvent{
+		Type:       "complete",
+		Step:       a.maxSteps,
+		Message:    "Stopped at the step limit",
+		Data:       map[string]interface{}{"total_steps": a.maxSteps, "partial": true},
+		DurationMs: time.Since(a.runStart).Milliseconds(),
+	})
+	return summary, nil
+}
+
+// progressSummary asks the model, without tools, for the step limit summary
+func (a *Agent) progressSummary(ctx context.Context, session *Session) (string, error) {
+	messages := append(session.GetMessages(), ai.Message{Role: "user", Content: stepLimitPrompt})
+
+	stepCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	resp, err := a.aiCaller.Chat(stepCtx, ai.ChatRequest{
+		Model:                   a.currentModel,
+		Messages:                a.applyProfile(messages),
+		Temperature:             a.params.Temperature,
+		MaxTokens:               a.params.MaxTokens,
+		TopP:                    a.params.TopP,
+		ContextLimit:            session.GetContextLimit(),
+		QwenLargeContextEnabled: session.GetQwenLargeContextEnabled(),
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := a.cleanToolCallTags(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/jsonrepair"
+	"github.com/neves/zen-claw/internal/schema"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// STRUCTURED OUTPUT
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// maxSchemaRetries caps reprompts when the final answer doesn't match the response schema
+const maxSchemaRetries = 2
+
+// SetResponseSchema requires the final answer to be JSON matching the given
+// JSON Schema (nil disables structured output)
+func (a *Agent) SetResponseSchema(responseSchema map[string]interface{}) {
+	a.responseSchema = responseSchema
+}
+
+// withSchemaInstruction adds the output format instruction to the system prompt
+// of a request's messages (messages is a copy; the session is not modified)
+func withSchemaInstruction(messages []ai.Message, responseSchema map[string]interface{}) []ai.Message {
+	schemaJSON, _ := json.MarshalIndent(responseSchema, "", "  ")
+	instruction := "OUTPUT FORMAT: When you give your FINAL answer (no more tool calls), reply with ONLY a JSON value matching this JSON Schema - no prose, no code fences:\n" + string(schemaJSON)
+
+	if len(messages) > 0 && messages[0].Role == "system" {
+		messages[0].Content += "\n\n" + instruction
+		return messages
+	}
+	return append([]ai.Message{{Role: "system", Content: instruction}}, messages...)
+}
+
+// finalizeStructured validates the final answer against the response schema and
+// returns it as compact JSON. Invalid answers are sent back to the model with the
+// violations (using the provider's native structured output) up to maxSchemaRetries.
+func (a *Agent) finalizeStructured(ctx context.Context, session *Session, answer string, step int) (string, error) {
+	var violations []string
+	for attempt := 0; ; attempt++ {
+		var value interface{}
+		if _, err := jsonrepair.Unmarshal(answer, &value); err != nil {
+			violations = []string{fmt.Sprintf("not valid JSON: %v", err)}
+		} else if violations = schema.Validate(a.responseSchema, value); len(violations) == 0 {
+			out, _ := json.Marshal(value)
+			return string(out), nil
+		}
+		if attempt >= maxSchemaRetries {
+			break
+		}
+
+		a.emitProgress("thinking", step, fmt.Sprintf("Answer does not match response schema, retrying (%d/%d)", attempt+1, maxSchemaRetries), map[string]interface{}{
+			"violations": violations,
+		})
+		messages := append(session.GetMessages(),
+			ai.Message{Role: "assistant", Content: answer},
+			ai.Message{Role: "user", Content: "Your answer does not match the required JSON Schema:\n- " + strings.Join(violations, "\n- ") + "\n\nReply with ONLY the corrected JSON."},
+		)
+		req := ai.ChatRequest{
+			Model:                   a.currentModel,
+			Messages:                withSchemaInstruction(messages, a.responseSchema),
+			Temperature:             0.2, // Fixing, not creating
+			MaxTokens:               a.params.MaxTokens,
+			TopP:                    a.params.TopP,
+			ContextLimit:            session.GetContextLimit(),
+			QwenLargeContextEnabled: session.GetQwenLargeContextEnabled(),
+			ResponseSchema:          a.responseSchema,
+		}
+		if a.profile.SystemAsUser {
+			req.Messages = systemAsUser(req.Messages)
+		}
+		if a.profile.NoJSONMode {
+			req.ResponseSchema = nil
+		}
+		resp, err := a.aiCaller.Chat(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("structured output retry failed: %w", err)
+		}
+		answer = resp.Content
+	}
+	return "", types.Errorf(types.ErrInvalidArgument, "final answer does not match response_schema after %d retries: %s",
+		maxSchemaRetries, strings.Join(violations, "; "))
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// maxReportedSyntaxErrors limits how many parse errors are returned to the model
+const maxReportedSyntaxErrors = 5
+
+// validateSyntax parses content according to the file extension and returns the syntax errors found.
+// Unknown file types are not checked, nor tree-sitter languages in builds without cgo.
+func validateSyntax(path string, content []byte) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if checked, err := validateTreeSitterSyntax(ext, content); checked {
+		return err
+	}
+	switch ext {
+	case ".go":
+		return validateGoSyntax(path, content)
+	case ".json":
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("invalid YAML: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateGoSyntax parses Go source with go/parser
+func validateGoSyntax(path string, content []byte) error {
+	fset := token.NewFileSet()
+	_, err := parser.ParseFile(fset, filepath.Base(path), content, parser.AllErrors)
+	if err == nil {
+		return nil
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err
+	}
+	var msgs []string
+	for i, e := range list {
+		if i == maxReportedSyntaxErrors {
+			msgs = append(msgs, fmt.Sprintf("... and %d more", len(list)-maxReportedSyntaxErrors))
+			break
+		}
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Errorf("Go syntax errors:\n%s", strings.Join(msgs, "\n"))
+}
+
+// syntaxErrorResult builds the tool result returned when a write is blocked by a syntax error
+func syntaxErrorResult(path string, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"path":         path,
+		"error":        err.Error(),
+		"syntax_error": true,
+		"error_code":   types.ErrInvalidArgument,
+		"success":      false,
+		"hint":         "Nothing was written. Fix the syntax and retry, or pass skip_syntax_check: true to write anyway.",
+	}
+}
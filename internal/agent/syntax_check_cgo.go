@@ -0,0 +1,119 @@
+//go:build cgo
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/toml"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// treeSitterAvailable reports whether the tree-sitter grammars are built in
+// (they need cgo)
+const treeSitterAvailable = true
+
+// treeSitterGrammar is a tree-sitter grammar and the language name used in errors
+type treeSitterGrammar struct {
+	name     string
+	language func() *sitter.Language
+}
+
+// treeSitterGrammars are the grammars of the other languages the code index knows
+var treeSitterGrammars = map[string]treeSitterGrammar{
+	".py":   {"Python", python.GetLanguage},
+	".js":   {"JavaScript", javascript.GetLanguage},
+	".jsx":  {"JavaScript", javascript.GetLanguage},
+	".mjs":  {"JavaScript", javascript.GetLanguage},
+	".cjs":  {"JavaScript", javascript.GetLanguage},
+	".ts":   {"TypeScript", typescript.GetLanguage},
+	".tsx":  {"TSX", tsx.GetLanguage},
+	".java": {"Java", java.GetLanguage},
+	".rs":   {"Rust", rust.GetLanguage},
+	".rb":   {"Ruby", ruby.GetLanguage},
+	".c":    {"C", c.GetLanguage},
+	".h":    {"C", c.GetLanguage},
+	".cpp":  {"C++", cpp.GetLanguage},
+	".cc":   {"C++", cpp.GetLanguage},
+	".hpp":  {"C++", cpp.GetLanguage},
+	".toml": {"TOML", toml.GetLanguage},
+}
+
+// validateTreeSitterSyntax parses content with the tree-sitter grammar of
+// ext and reports its ERROR and MISSING nodes (checked false when there is
+// no grammar for ext)
+func validateTreeSitterSyntax(ext string, content []byte) (checked bool, err error) {
+	grammar, ok := treeSitterGrammars[ext]
+	if !ok {
+		return false, nil
+	}
+	return true, treeSitterErrors(grammar, content)
+}
+
+func treeSitterErrors(grammar treeSitterGrammar, content []byte) error {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(grammar.language())
+	tree, err := parser.ParseCtx(context.Background(), nil, content)
+	if err != nil {
+		return nil // Parser gave up (e.g. timeout): don't block the write
+	}
+	defer tree.Close()
+	root := tree.RootNode()
+	if !root.HasError() {
+		return nil
+	}
+
+	var msgs []string
+	count := 0
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		if n.IsMissing() || n.IsError() {
+			count++
+			if count <= maxReportedSyntaxErrors {
+				pos := n.StartPoint()
+				what := "unexpected " + syntaxSnippet(n.Content(content))
+				if n.IsMissing() {
+					what = fmt.Sprintf("missing %q", n.Type())
+				}
+				msgs = append(msgs, fmt.Sprintf("%d:%d: %s", pos.Row+1, pos.Column+1, what))
+			}
+			return
+		}
+		for i := 0; i < int(n.ChildCount()); i++ {
+			if child := n.Child(i); child.HasError() || child.IsMissing() {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	if count > maxReportedSyntaxErrors {
+		msgs = append(msgs, fmt.Sprintf("... and %d more", count-maxReportedSyntaxErrors))
+	}
+	return fmt.Errorf("%s syntax errors:\n%s", grammar.name, strings.Join(msgs, "\n"))
+}
+
+// syntaxSnippet quotes the start of the text of an error node
+func syntaxSnippet(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	if len(text) > 40 {
+		text = text[:40] + "..."
+	}
+	if text == "" {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", text)
+}
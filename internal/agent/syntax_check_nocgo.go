@@ -0,0 +1,32 @@
+//go:build !cgo
+
+package agent
+
+import (
+	"log"
+	"sync"
+)
+
+// treeSitterAvailable reports whether the tree-sitter grammars are built in
+// (they need cgo)
+const treeSitterAvailable = false
+
+// treeSitterExts are the extensions cgo builds check with tree-sitter
+var treeSitterExts = map[string]string{
+	".py": "Python", ".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TSX", ".java": "Java", ".rs": "Rust", ".rb": "Ruby",
+	".c": "C", ".h": "C", ".cpp": "C++", ".cc": "C++", ".hpp": "C++", ".toml": "TOML",
+}
+
+var treeSitterSkipped sync.Map // Language -> logged
+
+// validateTreeSitterSyntax skips the tree-sitter languages in builds without
+// cgo, logging once per language that their writes aren't checked
+func validateTreeSitterSyntax(ext string, content []byte) (checked bool, err error) {
+	if name, ok := treeSitterExts[ext]; ok {
+		if _, logged := treeSitterSkipped.LoadOrStore(name, true); !logged {
+			log.Printf("[Agent] Syntax check: built without cgo, so %s files are written unchecked", name)
+		}
+	}
+	return false, nil
+}
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+
+	"github.com/neves/zen-claw/internal/schema"
 )
 
 // Tool represents an executable tool that the AI can call
@@ -46,3 +48,20 @@ func (t BaseTool) Description() string {
 func (t BaseTool) Parameters() map[string]interface{} {
 	return t.parameters
 }
+
+// validateToolArgs coerces arguments to the tool's parameter schema and
+// returns them with the violations that remain (nil when valid)
+func validateToolArgs(tool Tool, args map[string]interface{}) (map[string]interface{}, []string) {
+	params := tool.Parameters()
+	if len(params) == 0 {
+		return args, nil
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	coerced, ok := schema.Coerce(params, args).(map[string]interface{})
+	if !ok {
+		coerced = args
+	}
+	return coerced, schema.Validate(params, coerced)
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ADAPTIVE TOOL OUTPUT LIMITS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Listing and search tools return up to a fixed number of items whether the
+// context is empty or nearly full. When the agent knows its context window
+// (SetContextWindow), each call's item limit is sized to what the request can
+// still afford: a limit the model asked for is only ever lowered.
+
+const (
+	// outputBudgetShare is the part of the remaining context one tool call
+	// may fill, leaving room for further steps and parallel calls
+	outputBudgetShare = 4
+
+	// minAdaptiveItems is the floor: a result too small to act on only
+	// makes the model call again
+	minAdaptiveItems = 5
+)
+
+// adaptiveLimit describes a tool whose output size is set by an item count
+type adaptiveLimit struct {
+	arg        string // Argument carrying the limit
+	defaultMax int    // Tool's own default (0 = unlimited)
+	itemTokens int    // Estimated tokens per returned item
+}
+
+var adaptiveLimits = map[string]adaptiveLimit{
+	"search_files": {arg: "max_results", defaultMax: 50, itemTokens: 40},
+	"list_dir":     {arg: "max_entries", defaultMax: 0, itemTokens: 25},
+}
+
+// SetContextWindow sets the tokens of history the model accepts (after room
+// for the answer), enabling adaptive tool output limits. 0 disables them.
+func (a *Agent) SetContextWindow(tokens int) {
+	a.contextWindow = tokens
+}
+
+// toolOutputBudget returns the tokens one tool call may return given the
+// session's current history; false when the window is unknown
+func (a *Agent) toolOutputBudget(ctx context.Context) (int, bool) {
+	session := SessionFromContext(ctx)
+	if a.contextWindow <= 0 || session == nil {
+		return 0, false
+	}
+	budget := (a.contextWindow - MessagesTokens(session.GetMessages())) / outputBudgetShare
+	return min(max(budget, 0), MaxToolOutputBytes/4), true
+}
+
+// adaptiveLimitsMiddleware lowers item limits to the current output budget
+func (a *Agent) adaptiveLimitsMiddleware(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+		rule, ok := adaptiveLimits[inv.Name]
+		if !ok {
+			return next(ctx, inv)
+		}
+		budget, known := a.toolOutputBudget(ctx)
+		if !known {
+			return next(ctx, inv)
+		}
+
+		limit := max(budget/rule.itemTokens, minAdaptiveItems)
+		requested := rule.defaultMax
+		if n, ok := inv.Args[rule.arg].(float64); ok && n > 0 {
+			requested = int(n)
+		}
+		if requested > 0 && requested <= limit {
+			return next(ctx, inv)
+		}
+
+		args := make(map[string]interface{}, len(inv.Args)+1)
+		for k, v := range inv.Args {
+			args[k] = v
+		}
+		args[rule.arg] = float64(limit)
+		inv.Args = args
+
+		result, err := next(ctx, inv)
+		if res, ok := result.(map[string]interface{}); ok && res["truncated"] == true {
+			res["note"] = fmt.Sprintf("Limited to %d items to fit the remaining context; narrow the path or pattern to see the rest", limit)
+		}
+		return result, err
+	}
+}
+
+// MessagesTokens estimates the tokens of a conversation (~4 bytes per token)
+func MessagesTokens(messages []ai.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += len(msg.Content)/4 + 4
+		for _, tc := range msg.ToolCalls {
+			if data, err := json.Marshal(tc.Args); err == nil {
+				total += (len(tc.Name) + len(data)) / 4
+			}
+		}
+	}
+	return total
+}
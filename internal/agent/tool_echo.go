@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TOOL CALL ECHOES
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Some models echo earlier tool calls into their answers ("I ran
+// <function=write_file>... and it worked"), quote them in code blocks, or
+// repeat the call format from the prompt. Parsing every block would run those
+// calls again. Text tool calls are only read from the message's tool section:
+// the last run of consecutive call blocks, outside code fences and quotes.
+// Calls before it, or in fences and quotes, are echoes and ignored.
+
+// toolSection returns the lines of content's tool section ("" when it has
+// none) and how many call blocks outside it were ignored
+func toolSection(content string) (string, int) {
+	lines := strings.Split(content, "\n")
+	start, end := -1, -1 // Lines of the last section
+	open := false        // The last section can still grow
+	calls, sectionCalls := 0, 0
+	inFence, inCall, inParam := false, false, false
+
+	for i, rawLine := range lines {
+		line := strings.TrimSpace(rawLine)
+
+		// Inside a call only its own structure matters: parameter values may
+		// hold fences, quotes or text that looks like another call
+		if inParam {
+			inParam = !strings.HasSuffix(strings.TrimRight(rawLine, " \t\r"), "</parameter>")
+			end = i
+			continue
+		}
+		if inCall {
+			switch {
+			case line == "</function>" || line == "</tool_call>":
+				inCall = false
+			case strings.HasPrefix(line, "<parameter=") && strings.Contains(line, ">"):
+				inParam = !strings.HasSuffix(line, "</parameter>")
+			}
+			end = i
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~"):
+			inFence = !inFence
+			open = false
+		case inFence || strings.HasPrefix(line, ">"):
+			if strings.Contains(line, "<function=") {
+				calls++
+			}
+			open = false
+		case strings.HasPrefix(line, "<function=") && strings.HasSuffix(line, ">"):
+			if !open {
+				start, sectionCalls, open = i, 0, true
+			}
+			calls++
+			sectionCalls++
+			inCall = true
+			end = i
+		case line == "" || line == "<tool_call>" || line == "</tool_call>":
+			// Separators between the calls of a section
+		default:
+			open = false // Prose ends the section
+		}
+	}
+
+	if start < 0 {
+		return "", calls
+	}
+	return strings.Join(lines[start:end+1], "\n"), calls - sectionCalls
+}
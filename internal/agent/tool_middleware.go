@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/shellrisk"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TOOL MIDDLEWARE
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Every tool call runs through a chain of middleware around Tool.Execute, so
+// cross-cutting concerns (argument validation, metrics, audit, approval,
+// caching) apply to all tools - built-in, plugin and MCP - the same way.
+// The chain is: argument validation, adaptive output limits, recovery advice
+// on failed results (see recovery.go), then middleware in the order passed to
+// Agent.Use (first = outermost), then the tool.
+
+// ToolInvocation is one tool call passing through the middleware chain
+type ToolInvocation struct {
+	Tool   Tool
+	Name   string
+	Args   map[string]interface{}
+	CallID string
+	Step   int
+}
+
+// ToolHandler runs a tool invocation
+type ToolHandler func(ctx context.Context, inv *ToolInvocation) (interface{}, error)
+
+// ToolMiddleware wraps a handler with a cross-cutting concern
+type ToolMiddleware func(next ToolHandler) ToolHandler
+
+// Use appends middleware to the agent's tool chain
+func (a *Agent) Use(mw ...ToolMiddleware) {
+	a.middleware = append(a.middleware, mw...)
+}
+
+// toolHandler builds the chain around Tool.Execute
+func (a *Agent) toolHandler() ToolHandler {
+	handler := func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+		return inv.Tool.Execute(ctx, inv.Args)
+	}
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		handler = a.middleware[i](handler)
+	}
+	return validateArgsMiddleware(a.adaptiveLimitsMiddleware(recoveryMiddleware(handler)))
+}
+
+// ArgsError reports tool arguments that don't match the tool's schema
+type ArgsError struct {
+	Tool       string
+	Violations []string
+	Parameters map[string]interface{}
+}
+
+func (e *ArgsError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(e.Violations, "; "))
+}
+
+// validateArgsMiddleware checks arguments against the tool's schema, fixing
+// types models commonly get wrong, before anything else sees them
+func validateArgsMiddleware(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+		args, violations := validateToolArgs(inv.Tool, inv.Args)
+		if len(violations) > 0 {
+			return nil, &ArgsError{Tool: inv.Name, Violations: violations, Parameters: inv.Tool.Parameters()}
+		}
+		inv.Args = args
+		return next(ctx, inv)
+	}
+}
+
+// ApprovalMiddleware asks approve before each call; a non-nil error blocks
+// the call and is returned to the model (use types.ErrPermissionDenied)
+func ApprovalMiddleware(approve func(ctx context.Context, inv *ToolInvocation) error) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			if err := approve(ctx, inv); err != nil {
+				return nil, err
+			}
+			return next(ctx, inv)
+		}
+	}
+}
+
+// CacheMiddleware reuses results of identical read-only calls until a
+// call that may change something (any other tool) runs. read_file is never
+// cached: each read records the file's hash for edit conflict checks. Meant
+// for one agent run: create a new one per agent.
+func CacheMiddleware() ToolMiddleware {
+	var mu sync.Mutex
+	cache := make(map[string]interface{})
+	// generation counts mutating calls started or finished; a read that
+	// overlapped one may have seen a half-done change and isn't kept
+	var generation uint64
+	bump := func() {
+		mu.Lock()
+		generation++
+		clear(cache)
+		mu.Unlock()
+	}
+
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			if !isReadOnlyTool(inv.Name) {
+				bump()
+				defer bump()
+				return next(ctx, inv)
+			}
+			if inv.Name == "read_file" {
+				return next(ctx, inv)
+			}
+
+			argsJSON, _ := json.Marshal(inv.Args) // Map keys are sorted
+			key := inv.Name + ":" + string(argsJSON)
+			if session := SessionFromContext(ctx); session != nil {
+				key = session.GetWorkingDir() + ":" + key
+			}
+			mu.Lock()
+			result, ok := cache[key]
+			started := generation
+			mu.Unlock()
+			if ok {
+				return result, nil
+			}
+
+			result, err := next(ctx, inv)
+			if err == nil {
+				mu.Lock()
+				if generation == started {
+					cache[key] = result
+				}
+				mu.Unlock()
+			}
+			return result, err
+		}
+	}
+}
+
+// AuditRecord is one line of the tool audit log
+type AuditRecord struct {
+	Time       time.Time              `json:"time"`
+	SessionID  string                 `json:"session_id,omitempty"`
+	Tool       string                 `json:"tool"`
+	Args       map[string]interface{} `json:"args"`
+	DurationMs int64                  `json:"duration_ms"`
+	Error      string                 `json:"error,omitempty"`
+	ErrorCode  types.ErrorCode        `json:"error_code,omitempty"`
+	Risk       *shellrisk.Analysis    `json:"risk,omitempty"` // Classification of exec/process commands
+}
+
+// maxAuditArgLen caps each string argument in the audit log
+const maxAuditArgLen = 500
+
+// AuditMiddleware writes a JSON line per call to w: who ran which tool with
+// what arguments (long strings cut), how long it took and whether it failed
+func AuditMiddleware(w io.Writer) ToolMiddleware {
+	var mu sync.Mutex
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, inv)
+
+			rec := AuditRecord{
+				Time:       start,
+				Tool:       inv.Name,
+				Args:       make(map[string]interface{}, len(inv.Args)),
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			rec.Risk = ClassifyToolCall(inv.Name, inv.Args)
+			if session := SessionFromContext(ctx); session != nil {
+				rec.SessionID = session.ID
+			}
+			for k, v := range inv.Args {
+				if s, ok := v.(string); ok {
+					v = truncateString(s, maxAuditArgLen)
+				}
+				rec.Args[k] = v
+			}
+			if err != nil {
+				rec.Error, rec.ErrorCode = err.Error(), types.CodeOf(err)
+			} else if m, ok := result.(map[string]interface{}); ok {
+				if msg, ok := m["error"].(string); ok && msg != "" {
+					rec.Error, rec.ErrorCode = msg, types.CodeOfMessage(msg)
+				}
+			}
+
+			line, _ := json.Marshal(rec)
+			mu.Lock()
+			w.Write(append(line, '\n'))
+			mu.Unlock()
+			return result, err
+		}
+	}
+}
+
+// ToolStats is the call count, failures and latency of one tool
+type ToolStats struct {
+	Tool        string  `json:"tool"`
+	Calls       int64   `json:"calls"`
+	Errors      int64   `json:"errors"`
+	TotalMs     int64   `json:"total_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       int64   `json:"max_ms"`
+	LastErrorAt string  `json:"last_error_at,omitempty"`
+}
+
+// ToolMetrics aggregates per-tool timing across agent runs
+type ToolMetrics struct {
+	mu    sync.Mutex
+	tools map[string]*ToolStats
+}
+
+// NewToolMetrics creates an empty metrics collector
+func NewToolMetrics() *ToolMetrics {
+	return &ToolMetrics{tools: make(map[string]*ToolStats)}
+}
+
+// Middleware records each call's duration and outcome
+func (m *ToolMetrics) Middleware() ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, inv *ToolInvocation) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, inv)
+			elapsed := time.Since(start).Milliseconds()
+
+			failed := err != nil
+			if res, ok := result.(map[string]interface{}); ok {
+				if msg, ok := res["error"].(string); ok && msg != "" {
+					failed = true
+				}
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			stats := m.tools[inv.Name]
+			if stats == nil {
+				stats = &ToolStats{Tool: inv.Name}
+				m.tools[inv.Name] = stats
+			}
+			stats.Calls++
+			stats.TotalMs += elapsed
+			if elapsed > stats.MaxMs {
+				stats.MaxMs = elapsed
+			}
+			if failed {
+				stats.Errors++
+				stats.LastErrorAt = start.Format(time.RFC3339)
+			}
+			return result, err
+		}
+	}
+}
+
+// Snapshot returns per-tool stats, most called first
+func (m *ToolMetrics) Snapshot() []ToolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]ToolStats, 0, len(m.tools))
+	for _, stats := range m.tools {
+		s := *stats
+		if s.Calls > 0 {
+			s.AvgMs = float64(s.TotalMs) / float64(s.Calls)
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Calls != list[j].Calls {
+			return list[i].Calls > list[j].Calls
+		}
+		return list[i].Tool < list[j].Tool
+	})
+	return list
+}
@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TOOL CALL DEPENDENCY HINTS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Without hints, read-only tools run in parallel and everything else runs
+// one at a time. A model can do better by adding "depends_on" to the calls
+// of one response: the calls (1-based positions or call IDs) that must finish
+// first, [] for none. Once any call carries the hint, calls run as soon as
+// their dependencies are done; a call without it waits for all earlier calls.
+// Calls touching the same path are ordered regardless of hints, and a call
+// that may change something without naming its files (exec, refactors) never
+// runs alongside another.
+
+// dependsOnArg is the argument carrying a call's dependencies
+const dependsOnArg = "depends_on"
+
+// planToolCalls reads dependency hints. It returns the calls with the hint
+// removed from their arguments, the indices each call waits for, and whether
+// any call carried a hint (false = use the default schedule).
+func planToolCalls(ctx context.Context, calls []ai.ToolCall) ([]ai.ToolCall, [][]int, bool) {
+	hinted := false
+	for _, call := range calls {
+		if _, ok := call.Args[dependsOnArg]; ok {
+			hinted = true
+			break
+		}
+	}
+	if !hinted {
+		return calls, nil, false
+	}
+
+	ids := make(map[string]int, len(calls))
+	for i, call := range calls {
+		ids[call.ID] = i
+	}
+
+	stripped := make([]ai.ToolCall, len(calls))
+	deps := make([][]int, len(calls))
+	paths := make([][]string, len(calls))
+	for i, call := range calls {
+		args := make(map[string]interface{}, len(call.Args))
+		for k, v := range call.Args {
+			args[k] = v
+		}
+		hint, hasHint := args[dependsOnArg]
+		delete(args, dependsOnArg)
+		stripped[i] = ai.ToolCall{ID: call.ID, Name: call.Name, Args: args}
+
+		seen := make(map[int]bool)
+		add := func(j int) {
+			if j >= 0 && j < i && !seen[j] { // Only earlier calls: no cycles
+				seen[j] = true
+				deps[i] = append(deps[i], j)
+			}
+		}
+		paths[i] = callPaths(ctx, stripped[i])
+		if !hasHint {
+			for j := 0; j < i; j++ {
+				add(j)
+			}
+			continue
+		}
+		for _, ref := range dependencyRefs(hint) {
+			if j, ok := ids[ref]; ok {
+				add(j)
+			} else if n, err := strconv.Atoi(ref); err == nil {
+				add(n - 1)
+			}
+		}
+		// A call that may change something but names no file could touch
+		// anything (exec, refactors): it runs after all earlier calls and
+		// before all later ones, whatever the hints say
+		if sequentialCall(calls[i].Name, paths[i]) {
+			for j := 0; j < i; j++ {
+				add(j)
+			}
+			continue
+		}
+		// Same path: a later call never overtakes an earlier write, or a
+		// write an earlier read
+		for j := 0; j < i; j++ {
+			if sequentialCall(calls[j].Name, paths[j]) ||
+				(sharePath(paths[i], paths[j]) && !(isReadOnlyTool(calls[i].Name) && isReadOnlyTool(calls[j].Name))) {
+				add(j)
+			}
+		}
+	}
+	return stripped, deps, true
+}
+
+// dependencyRefs normalizes a depends_on value: a JSON array of positions or
+// IDs, a single one, or (from text tool calls) a string like "[1, 2]" or "1,2"
+func dependencyRefs(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		var decoded []interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+			v = decoded
+		} else {
+			var refs []string
+			for _, part := range strings.Split(s, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					refs = append(refs, part)
+				}
+			}
+			return refs
+		}
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	var refs []string
+	for _, item := range list {
+		switch item := item.(type) {
+		case string:
+			refs = append(refs, item)
+		case float64:
+			refs = append(refs, strconv.Itoa(int(item)))
+		}
+	}
+	return refs
+}
+
+// callPaths returns the absolute files a call reads or writes: its "path",
+// or the files an apply_patch input touches (nil if none are named)
+func callPaths(ctx context.Context, call ai.ToolCall) []string {
+	var paths []string
+	if call.Name == "apply_patch" {
+		input, _ := call.Args["input"].(string)
+		paths = patchPaths(input)
+	} else if path, _ := call.Args["path"].(string); path != "" {
+		paths = []string{path}
+	}
+	for i, path := range paths {
+		paths[i] = ResolvePath(ctx, "", path)
+	}
+	return paths
+}
+
+// sequentialCall reports whether a call must not run alongside any other:
+// it may change something and names no file it's limited to
+func sequentialCall(tool string, paths []string) bool {
+	return !isReadOnlyTool(tool) && len(paths) == 0
+}
+
+// sharePath reports whether two calls name a common file
+func sharePath(a, b []string) bool {
+	for _, p := range a {
+		if slices.Contains(b, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// executeWithDependencies runs each call once the calls it depends on are done
+func (a *Agent) executeWithDependencies(ctx context.Context, calls []ai.ToolCall, deps [][]int, step int) []ToolResult {
+	independent := 0
+	for _, d := range deps {
+		if len(d) == 0 {
+			independent++
+		}
+	}
+	log.Printf("[Agent] Executing %d tool calls by dependency hints (%d independent)", len(calls), independent)
+	if independent > 1 {
+		a.emitProgress("tool_call", step, fmt.Sprintf("⚡ %d tools in parallel", independent), nil)
+	}
+
+	results := make([]ToolResult, len(calls))
+	done := make([]chan struct{}, len(calls))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ai.ToolCall) {
+			defer wg.Done()
+			defer close(done[i])
+			for _, j := range deps[i] {
+				<-done[j]
+			}
+			results[i] = a.executeSingleTool(ctx, call, step)
+		}(i, call)
+	}
+	wg.Wait()
+	return results
+}
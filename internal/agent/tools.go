@@ -10,6 +10,9 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/neves/zen-claw/internal/ignore"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // MaxToolOutputBytes limits tool output to prevent context explosion
@@ -67,29 +70,23 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (in
 		return nil, fmt.Errorf("command parameter is required")
 	}
 
-	// Check for cd command and update working directory
+	// Check for cd command and update working directory. The new directory is
+	// reported back via new_working_dir and stored on the session, never on the
+	// tool, since one tool instance is shared by every gateway session.
 	trimmedCmd := strings.TrimSpace(command)
-	if strings.HasPrefix(trimmedCmd, "cd ") {
-		// Extract directory
-		dir := strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "cd "))
-		// Handle ~ expansion
-		if strings.HasPrefix(dir, "~") {
-			home, err := os.UserHomeDir()
-			if err == nil {
-				dir = home + dir[1:]
-			}
+	if (trimmedCmd == "cd" || strings.HasPrefix(trimmedCmd, "cd ")) && !strings.ContainsAny(trimmedCmd, "&;|") {
+		dir := strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "cd"))
+		if dir == "" {
+			dir = "~"
 		}
+		dir = ResolvePath(ctx, t.workingDir, dir)
 
 		// Update working directory if it exists
-		if _, err := os.Stat(dir); err == nil {
-			t.workingDir = dir
-			return map[string]interface{}{
-				"command":         command,
-				"output":          fmt.Sprintf("Changed directory to: %s", dir),
-				"exit_code":       0,
-				"new_working_dir": dir,
-			}, nil
-		} else {
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			err = fmt.Errorf("not a directory: %s", dir)
+		}
+		if err != nil {
 			return map[string]interface{}{
 				"command":   command,
 				"output":    fmt.Sprintf("Error: Directory %s does not exist", dir),
@@ -97,23 +94,55 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (in
 				"error":     err.Error(),
 			}, nil
 		}
+		if err := GetWorkingDirRoots().Check(dir); err != nil {
+			return map[string]interface{}{
+				"command":    command,
+				"output":     "Error: " + err.Error(),
+				"exit_code":  1,
+				"error":      err.Error(),
+				"error_code": types.CodeOf(err),
+			}, nil
+		}
+		return map[string]interface{}{
+			"command":         command,
+			"output":          fmt.Sprintf("Changed directory to: %s", dir),
+			"exit_code":       0,
+			"new_working_dir": dir,
+		}, nil
 	}
 
-	// Create command with context
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
+	// Session variables (secret refs resolved here, never shown to the model)
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return map[string]interface{}{
+			"command":   command,
+			"exit_code": -1,
+			"error":     err.Error(),
+		}, nil
 	}
 
+	// Apply gateway/session resource limits
+	limits := effectiveLimits(ctx)
+	runCtx, cancel := limitTimeout(ctx, limits)
+	defer cancel()
+
+	// Create command with context
+	cmd := exec.CommandContext(runCtx, "bash", "-c", limitCommand(command, limits))
+	cmd.Dir = BaseDir(ctx, t.workingDir)
+	env.apply(cmd)
+
 	// Execute with timeout
 	output, err := cmd.CombinedOutput()
-	outputStr := truncateOutput(string(output), MaxToolOutputBytes)
+	outputStr := truncateWithRef(ctx, env.redact(string(output)), MaxToolOutputBytes)
 
 	result := map[string]interface{}{
 		"command":   command,
 		"output":    outputStr,
 		"exit_code": cmd.ProcessState.ExitCode(),
 	}
+	if names := env.names(); len(names) > 0 {
+		result["env_injected"] = names
+	}
 
 	if len(output) > MaxToolOutputBytes {
 		result["truncated"] = true
@@ -122,6 +151,14 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (in
 
 	if err != nil {
 		result["error"] = err.Error()
+		if runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			result["error"] = fmt.Sprintf("command exceeded time limit of %ds", limits.TimeoutSeconds)
+			result["error_code"] = types.ErrTimeout
+		}
+		if msg := limitFailure(string(output), cmd.ProcessState.ExitCode()); msg != "" {
+			result["error"] = msg
+			result["error_code"] = types.ErrUnavailable
+		}
 	}
 
 	return result, nil
@@ -162,11 +199,8 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return nil, fmt.Errorf("path parameter is required")
 	}
 
-	// Resolve path relative to working directory
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") {
-		fullPath = t.workingDir + "/" + path
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
 
 	// Read file
 	content, err := os.ReadFile(fullPath)
@@ -177,16 +211,29 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		}, nil // Return error as result, not as Go error
 	}
 
-	contentStr := truncateOutput(string(content), MaxToolOutputBytes)
+	recordFileRead(ctx, fullPath, content)
+
+	// Text comes back as UTF-8 with \n line endings, whatever the file uses
+	text, format, err := decodeText(content)
+	if err != nil {
+		text = string(content) // Binary or legacy encoding: as is
+	}
+	contentStr := truncateWithRef(ctx, text, MaxToolOutputBytes)
 	result := map[string]interface{}{
 		"path":    path,
 		"content": contentStr,
 		"size":    len(content),
 	}
+	if err == nil && !format.plain() {
+		result["format"] = format.String() // Kept by write_file and edit_file
+	}
 
 	if len(content) > MaxToolOutputBytes {
 		result["truncated"] = true
 		result["hint"] = "File truncated. Use search_files to find specific content, or read specific line ranges."
+		if ref := ResultRef(contentStr); ref != "" {
+			result["hint"] = fmt.Sprintf("File truncated. Call expand_result with ref %s to read the rest, or use search_files to find specific content.", ref)
+		}
 	}
 
 	return result, nil
@@ -207,29 +254,96 @@ func NewListDirTool(workingDir string) *ListDirTool {
 				"type":        "string",
 				"description": "Directory path (default: current directory)",
 			},
+			"max_entries": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of entries (default: all; 1000 when recursive)",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "List subdirectories too, down to max_depth (default: false)",
+			},
+			"max_depth": map[string]interface{}{
+				"type":        "integer",
+				"description": "Levels listed when recursive, 1 being the directory itself (default: 3)",
+			},
+			"include_hidden": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include dot files and directories such as .git (default: true, false when recursive)",
+			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include what .gitignore and .zenclawignore exclude, e.g. node_modules or build outputs (default: false)",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"entries", "tree"},
+				"description": "entries: objects with name, type, size and mode (default); tree: an indented outline, compact for project overviews",
+			},
 		},
 	}
 
 	return &ListDirTool{
 		BaseTool: NewBaseTool(
 			"list_dir",
-			"List directory contents",
+			"List directory contents. With recursive and format \"tree\", one call gives an overview of a whole project.",
 			params,
 		),
 		workingDir: workingDir,
 	}
 }
 
+// Recursive listing bounds
+const (
+	listDirDefaultDepth = 3
+	listDirMaxEntries   = 1000
+)
+
+// listedEntry is an entry of a recursive listing
+type listedEntry struct {
+	rel   string // Relative to the listed directory
+	depth int    // 1 for the directory's own entries
+	entry os.DirEntry
+	more  int // Entries of a directory below max_depth, not listed
+}
+
 func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	path := "."
 	if p, ok := args["path"].(string); ok && p != "" {
 		path = p
 	}
 
-	// Resolve path relative to working directory
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") {
-		fullPath = t.workingDir + "/" + path
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+
+	recursive, _ := args["recursive"].(bool)
+	maxDepth, maxEntries := 1, 0
+	if recursive {
+		maxDepth, maxEntries = listDirDefaultDepth, listDirMaxEntries
+		if d, ok := args["max_depth"].(float64); ok && d >= 1 {
+			maxDepth = int(d)
+		}
+	}
+	if me, ok := args["max_entries"].(float64); ok && me > 0 {
+		maxEntries = int(me)
+	}
+	includeHidden := !recursive
+	if h, ok := args["include_hidden"].(bool); ok {
+		includeHidden = h
+	}
+	var ignored *ignore.Matcher
+	if all, _ := args["include_ignored"].(bool); !all {
+		ignored = ignore.New(fullPath)
+	}
+	skipped := 0
+	skip := func(dir string, entry os.DirEntry) bool {
+		if !includeHidden && strings.HasPrefix(entry.Name(), ".") {
+			return true
+		}
+		if ignored.Ignored(filepath.Join(dir, entry.Name()), entry.IsDir()) {
+			skipped++
+			return true
+		}
+		return false
 	}
 
 	// List directory
@@ -241,23 +355,102 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		}, nil
 	}
 
-	// Format entries
-	var files []map[string]interface{}
-	for _, entry := range entries {
-		info, _ := entry.Info()
-		files = append(files, map[string]interface{}{
-			"name": entry.Name(),
-			"type": getFileType(entry),
-			"size": info.Size(),
-			"mode": info.Mode().String(),
-		})
+	// Walk depth first, so a tree reads top to bottom
+	var listing []listedEntry
+	truncated, visible := false, 0
+	var walk func(dir, rel string, entries []os.DirEntry, depth int)
+	walk = func(dir, rel string, entries []os.DirEntry, depth int) {
+		for _, entry := range entries {
+			if skip(dir, entry) {
+				continue
+			}
+			visible++
+			if maxEntries > 0 && len(listing) >= maxEntries {
+				truncated = true
+				if recursive {
+					return
+				}
+				continue // A flat listing counts the rest for total
+			}
+			item := listedEntry{rel: filepath.Join(rel, entry.Name()), depth: depth, entry: entry}
+			listing = append(listing, item)
+			if !entry.IsDir() || !recursive {
+				continue
+			}
+			children, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue // Unreadable: listed, not descended into
+			}
+			if depth >= maxDepth {
+				listing[len(listing)-1].more = len(children)
+				continue
+			}
+			walk(filepath.Join(dir, entry.Name()), item.rel, children, depth+1)
+		}
 	}
+	walk(fullPath, "", entries, 1)
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"path":  path,
-		"files": files,
-		"count": len(files),
-	}, nil
+		"count": len(listing),
+	}
+	if format, _ := args["format"].(string); format == "tree" {
+		result["tree"] = truncateWithRef(ctx, renderTree(listing), MaxToolOutputBytes)
+	} else {
+		// Format entries
+		var files []map[string]interface{}
+		for _, item := range listing {
+			file := map[string]interface{}{
+				"name": item.rel,
+				"type": getFileType(item.entry),
+			}
+			if info, err := item.entry.Info(); err == nil {
+				file["size"] = info.Size()
+				file["mode"] = info.Mode().String()
+			}
+			if item.more > 0 {
+				file["entries"] = item.more // Not listed: below max_depth
+			}
+			files = append(files, file)
+		}
+		result["files"] = files
+	}
+	if truncated {
+		if !recursive {
+			result["total"] = visible
+		}
+		result["truncated"] = true
+	}
+	if skipped > 0 {
+		result["ignored"] = skipped // Pass include_ignored: true to list them
+	}
+	return result, nil
+}
+
+// renderTree outlines a listing, two spaces per level:
+//
+//	cmd/
+//	  main.go
+//	internal/ (12 entries)
+func renderTree(listing []listedEntry) string {
+	var sb strings.Builder
+	for _, item := range listing {
+		sb.WriteString(strings.Repeat("  ", item.depth-1))
+		sb.WriteString(item.entry.Name())
+		switch getFileType(item.entry) {
+		case "directory":
+			sb.WriteString("/")
+		case "symlink":
+			sb.WriteString("@")
+		}
+		if item.more == 1 {
+			sb.WriteString(" (1 entry)")
+		} else if item.more > 1 {
+			fmt.Fprintf(&sb, " (%d entries)", item.more)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
 }
 
 // SystemInfoTool gets system information
@@ -336,6 +529,15 @@ func NewWriteFileTool(workingDir string) *WriteFileTool {
 				"type":        "boolean",
 				"description": "Create parent directories if they don't exist (default: true)",
 			},
+			"skip_syntax_check": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write even if the result fails syntax validation (default: false)",
+			},
+			"mode": fileModeParam,
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write content exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -367,17 +569,32 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		createDirs = cd
 	}
 
-	// Resolve path relative to working directory
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~") {
-		fullPath = filepath.Join(t.workingDir, path)
+	mode, setMode, err := parseFileMode(args["mode"])
+	if err != nil {
+		return map[string]interface{}{
+			"path":    path,
+			"error":   err.Error(),
+			"success": false,
+		}, nil
 	}
 
-	// Handle ~ expansion
-	if strings.HasPrefix(fullPath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			fullPath = filepath.Join(home, fullPath[1:])
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+
+	// Keep the line endings, BOM and encoding of the file being replaced
+	existed := false
+	format := defaultFileFormat
+	if existing, err := os.ReadFile(fullPath); err == nil {
+		existed = true
+		if _, format, err = decodeText(existing); err != nil {
+			return formatErrorResult(path, err), nil
+		}
+	}
+
+	// Refuse to write code that doesn't parse unless explicitly overridden
+	if skip, _ := args["skip_syntax_check"].(bool); !skip {
+		if err := validateSyntax(fullPath, []byte(content)); err != nil {
+			return syntaxErrorResult(path, err), nil
 		}
 	}
 
@@ -393,14 +610,13 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		}
 	}
 
-	// Check if file exists (for reporting)
-	existed := false
-	if _, err := os.Stat(fullPath); err == nil {
-		existed = true
+	// Write file (an existing file keeps its mode)
+	data := format.encode(content)
+	err = os.WriteFile(fullPath, data, newFileMode)
+	if err == nil && setMode {
+		err = os.Chmod(fullPath, mode)
 	}
-
-	// Write file
-	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+	if err != nil {
 		return map[string]interface{}{
 			"path":    path,
 			"error":   err.Error(),
@@ -408,17 +624,26 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		}, nil
 	}
 
+	recordFileRead(ctx, fullPath, data)
+
 	action := "created"
 	if existed {
 		action = "overwritten"
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"path":    path,
 		"action":  action,
-		"size":    len(content),
+		"size":    len(data),
+		"format":  format.String(),
 		"success": true,
-	}, nil
+	}
+	if info, err := os.Stat(fullPath); err == nil {
+		result["mode"] = formatFileMode(info.Mode())
+	}
+	applyPostWriteHooks(ctx, fullPath, result)
+
+	return result, nil
 }
 
 // EditFileTool performs string replacement in files (like Cursor's StrReplace)
@@ -448,6 +673,14 @@ func NewEditFileTool(workingDir string) *EditFileTool {
 				"type":        "boolean",
 				"description": "Replace all occurrences instead of just the first (default: false)",
 			},
+			"skip_syntax_check": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write even if the result fails syntax validation (default: false)",
+			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Use new_string exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
 		},
 		"required": []string{"path", "old_string", "new_string"},
 	}
@@ -483,19 +716,8 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		replaceAll = ra
 	}
 
-	// Resolve path relative to working directory
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~") {
-		fullPath = filepath.Join(t.workingDir, path)
-	}
-
-	// Handle ~ expansion
-	if strings.HasPrefix(fullPath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			fullPath = filepath.Join(home, fullPath[1:])
-		}
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
 
 	// Read existing file
 	content, err := os.ReadFile(fullPath)
@@ -507,7 +729,23 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		}, nil
 	}
 
-	contentStr := string(content)
+	// Reject the edit if someone else changed the file since this session last saw it
+	if checkFileConflict(ctx, fullPath, content) {
+		return map[string]interface{}{
+			"path":       path,
+			"error":      "file changed on disk since it was last read; re-read it with read_file before editing",
+			"conflict":   true,
+			"error_code": types.ErrConflict,
+			"success":    false,
+		}, nil
+	}
+
+	// Edit the text; the file keeps its line endings, BOM and encoding
+	contentStr, format, err := decodeText(content)
+	if err != nil {
+		return formatErrorResult(path, err), nil
+	}
+	oldString, newString = format.normalize(oldString), format.normalize(newString)
 
 	// Check if old_string exists
 	count := strings.Count(contentStr, oldString)
@@ -539,8 +777,16 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		replacements = 1
 	}
 
+	// Refuse to write code that doesn't parse unless explicitly overridden
+	if skip, _ := args["skip_syntax_check"].(bool); !skip {
+		if err := validateSyntax(fullPath, []byte(newContent)); err != nil {
+			return syntaxErrorResult(path, err), nil
+		}
+	}
+
 	// Write back
-	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+	data := format.encode(newContent)
+	if err := os.WriteFile(fullPath, data, newFileMode); err != nil {
 		return map[string]interface{}{
 			"path":    path,
 			"error":   fmt.Sprintf("failed to write file: %v", err),
@@ -548,11 +794,17 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		}, nil
 	}
 
-	return map[string]interface{}{
+	recordFileRead(ctx, fullPath, data)
+
+	result := map[string]interface{}{
 		"path":         path,
 		"replacements": replacements,
+		"format":       format.String(),
 		"success":      true,
-	}, nil
+	}
+	applyPostWriteHooks(ctx, fullPath, result)
+
+	return result, nil
 }
 
 // SearchFilesTool searches for patterns in files (grep-like)
@@ -582,6 +834,10 @@ func NewSearchFilesTool(workingDir string) *SearchFilesTool {
 				"type":        "integer",
 				"description": "Maximum number of results (default: 50)",
 			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search what .gitignore and .zenclawignore exclude (default: false)",
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -617,11 +873,8 @@ func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]interface
 		searchPath = p
 	}
 
-	// Resolve path
-	fullPath := searchPath
-	if t.workingDir != "" && !strings.HasPrefix(searchPath, "/") {
-		fullPath = filepath.Join(t.workingDir, searchPath)
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, searchPath)
 
 	// Get file pattern
 	filePattern := "*"
@@ -635,6 +888,11 @@ func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]interface
 		maxResults = int(mr)
 	}
 
+	var ignored *ignore.Matcher
+	if all, _ := args["include_ignored"].(bool); !all {
+		ignored = ignore.New(fullPath)
+	}
+
 	var results []map[string]interface{}
 
 	// Walk directory
@@ -645,13 +903,17 @@ func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]interface
 
 		// Skip directories
 		if info.IsDir() {
-			// Skip hidden directories and common non-code directories
+			// Skip hidden directories, common non-code directories and ignored ones
+			// (never the search root itself, which may be ".")
 			name := info.Name()
-			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" {
+			if path != fullPath && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || ignored.Ignored(path, true)) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
+		if path != fullPath && ignored.Ignored(path, false) {
+			return nil
+		}
 
 		// Check file pattern
 		if filePattern != "*" {
@@ -745,6 +1007,10 @@ func NewAppendFileTool(workingDir string) *AppendFileTool {
 				"type":        "string",
 				"description": "Content to append to the file",
 			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Append content exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -770,19 +1036,8 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return nil, fmt.Errorf("content parameter is required")
 	}
 
-	// Resolve path relative to working directory
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~") {
-		fullPath = filepath.Join(t.workingDir, path)
-	}
-
-	// Handle ~ expansion
-	if strings.HasPrefix(fullPath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			fullPath = filepath.Join(home, fullPath[1:])
-		}
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
 
 	// Create parent directories if needed
 	dir := filepath.Dir(fullPath)
@@ -794,8 +1049,17 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		}, nil
 	}
 
+	// Appended lines follow the file's line endings and encoding
+	format := defaultFileFormat
+	if existing, err := os.ReadFile(fullPath); err == nil && len(existing) > 0 {
+		if _, format, err = decodeText(existing); err != nil {
+			return formatErrorResult(path, err), nil
+		}
+	}
+	data := format.encodeBody(content)
+
 	// Open file for appending
-	file, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, newFileMode)
 	if err != nil {
 		return map[string]interface{}{
 			"path":    path,
@@ -806,7 +1070,7 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 	defer file.Close()
 
 	// Write content
-	if _, err := file.WriteString(content); err != nil {
+	if _, err := file.Write(data); err != nil {
 		return map[string]interface{}{
 			"path":    path,
 			"error":   err.Error(),
@@ -816,7 +1080,8 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 
 	return map[string]interface{}{
 		"path":          path,
-		"bytes_written": len(content),
+		"bytes_written": len(data),
+		"format":        format.String(),
 		"success":       true,
 	}, nil
 }
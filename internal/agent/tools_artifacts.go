@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/artifacts"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ARTIFACT UPLOAD (S3, GCS)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// DefaultMaxArtifactBytes caps an upload unless artifacts.max_size_mb says otherwise
+const DefaultMaxArtifactBytes = 100 << 20
+
+// artifactSkipDirs are left out of directory archives
+var artifactSkipDirs = map[string]bool{".git": true, "node_modules": true, ".terraform": true}
+
+// UploadArtifactTool uploads a file or directory to the configured bucket
+type UploadArtifactTool struct {
+	BaseTool
+	workingDir string
+	store      *artifacts.Store
+	maxBytes   int64
+}
+
+// NewUploadArtifactTool creates a new artifact upload tool; maxBytes <= 0
+// uses DefaultMaxArtifactBytes
+func NewUploadArtifactTool(workingDir string, store *artifacts.Store, maxBytes int64) *UploadArtifactTool {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxArtifactBytes
+	}
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File to upload; a directory is uploaded as a .tar.gz (without .git and node_modules)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Object name (default: the file name, or <dir>.tar.gz)",
+			},
+			"content_type": map[string]interface{}{
+				"type":        "string",
+				"description": "Content type (default: guessed from the name)",
+			},
+		},
+		"required": []string{"path"},
+	}
+	return &UploadArtifactTool{
+		BaseTool:   NewBaseTool("upload_artifact", "Upload a report, coverage output, patch or directory (as .tar.gz) to the configured object storage bucket and return a URL to share, e.g. with users on another machine.", params),
+		workingDir: workingDir,
+		store:      store,
+		maxBytes:   maxBytes,
+	}
+}
+
+func (t *UploadArtifactTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	if t.store == nil {
+		return nil, types.Errorf(types.ErrInvalidArgument, "no artifact bucket configured (artifacts in the gateway config)")
+	}
+	path, _ := args["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, types.Errorf(types.ErrNotFound, "%s: %v", path, err)
+	}
+
+	name, _ := args["name"].(string)
+	var f *os.File
+	if info.IsDir() {
+		if name == "" {
+			name = filepath.Base(fullPath) + ".tar.gz"
+		}
+		if f, err = tarDirectory(fullPath, t.maxBytes); err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+	} else {
+		if name == "" {
+			name = filepath.Base(fullPath)
+		}
+		if info.Size() > t.maxBytes {
+			return nil, types.Errorf(types.ErrInvalidArgument, "%s is %d bytes, over the %d byte artifact limit", path, info.Size(), t.maxBytes)
+		}
+		if f, err = os.Open(fullPath); err != nil {
+			return nil, err
+		}
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, _ := args["content_type"].(string)
+	if contentType == "" {
+		contentType = artifactContentType(name)
+	}
+	obj, err := t.store.Put(ctx, artifactKey(name, time.Now()), f, stat.Size(), contentType)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"url":     obj.URL,
+		"key":     obj.Key,
+		"bucket":  t.store.Bucket,
+		"size":    obj.Size,
+		"success": true,
+	}
+	if !obj.Expires.IsZero() {
+		result["expires"] = obj.Expires.Format(time.RFC3339)
+	}
+	return result, nil
+}
+
+// artifactKey places an artifact under its upload date and a random part,
+// so names don't collide and keys can't be guessed from the name
+func artifactKey(name string, now time.Time) string {
+	var random [6]byte
+	rand.Read(random[:])
+	name = strings.Trim(filepath.ToSlash(name), "/")
+	return now.UTC().Format("2006/01/02/") + hex.EncodeToString(random[:]) + "/" + name
+}
+
+// artifactContentType guesses the content type of an artifact from its name
+func artifactContentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "application/gzip"
+	case strings.HasSuffix(name, ".md"):
+		return "text/markdown; charset=utf-8"
+	case strings.HasSuffix(name, ".patch"), strings.HasSuffix(name, ".diff"), strings.HasSuffix(name, ".log"), strings.HasSuffix(name, ".out"):
+		return "text/plain; charset=utf-8"
+	}
+	return mime.TypeByExtension(filepath.Ext(name))
+}
+
+// tarDirectory archives a directory into a temporary .tar.gz, failing once
+// the archive grows over maxBytes. The caller removes the file.
+func tarDirectory(dir string, maxBytes int64) (*os.File, error) {
+	f, err := os.CreateTemp("", "zen-claw-artifact-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*os.File, error) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	limited := &limitWriter{w: f, n: maxBytes}
+	gz := gzip.NewWriter(limited)
+	tw := tar.NewWriter(gz)
+	root := filepath.Base(dir)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && artifactSkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil // Sockets, devices and symlinks stay behind
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(root, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if limited.n < 0 {
+		return fail(types.Errorf(types.ErrInvalidArgument, "%s compresses to over the %d byte artifact limit", dir, maxBytes))
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	return f, nil
+}
+
+// limitWriter fails writes past n bytes, leaving n negative
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.n -= int64(len(p))
+	if l.n < 0 {
+		return 0, fmt.Errorf("artifact size limit exceeded")
+	}
+	return l.w.Write(p)
+}
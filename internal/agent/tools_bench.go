@@ -0,0 +1,600 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// BENCHMARK AND PROFILING TOOLS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// go_bench runs Go benchmarks and compares them with a baseline: a saved
+// run (save_as / compare_to) or the same benchmarks run at a git ref in a
+// temporary worktree (baseline_ref), so the agent can measure before and
+// after a change. Every metric is averaged over -count runs; a change
+// smaller than the runs' spread is reported as noise. It can also capture
+// CPU and memory profiles, which pprof reads.
+
+// benchTimeout bounds one go test -bench run
+const benchTimeout = 20 * time.Minute
+
+// benchMinDelta is the smallest change (percent) reported as a regression
+// or improvement, however steady the runs
+const benchMinDelta = 2.0
+
+// benchNameRe validates saved run names
+var benchNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// unsafeNameChars are replaced in the session ID of the profile dir
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// BenchMetric is one metric of a benchmark over its runs
+type BenchMetric struct {
+	Mean   float64 `json:"mean"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Spread float64 `json:"spread_pct"` // Half the min-max range, in percent of the mean
+	values []float64
+}
+
+// BenchResult is a benchmark's metrics, keyed by unit (ns/op, B/op...)
+type BenchResult struct {
+	Package string                  `json:"package,omitempty"`
+	Name    string                  `json:"name"`
+	Runs    int                     `json:"runs"`
+	Metrics map[string]*BenchMetric `json:"metrics"`
+}
+
+// BenchDelta compares a metric with the baseline
+type BenchDelta struct {
+	Name    string  `json:"name"`
+	Unit    string  `json:"unit"`
+	Before  float64 `json:"before"`
+	After   float64 `json:"after"`
+	Delta   float64 `json:"delta_pct"`
+	Noise   float64 `json:"noise_pct"`
+	Verdict string  `json:"verdict"` // regression, improvement or unchanged
+}
+
+// GoBenchTool runs benchmarks and compares them with a baseline
+type GoBenchTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewGoBenchTool creates a Go benchmark tool
+func NewGoBenchTool(workingDir string) *GoBenchTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"packages": map[string]interface{}{
+				"type":        "string",
+				"description": "Package pattern (default: .). Profiles need a single package.",
+			},
+			"bench": map[string]interface{}{
+				"type":        "string",
+				"description": "Benchmark regexp, as go test -bench (default: .)",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "Runs of each benchmark, averaged (default 5)",
+			},
+			"benchtime": map[string]interface{}{
+				"type":        "string",
+				"description": "Time or iterations per run, e.g. 2s or 1000x (default 1s)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to run in (default: working directory)",
+			},
+			"baseline_ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Also run the benchmarks at this git ref (e.g. HEAD, main) in a temporary worktree and compare: before = the ref, after = the working tree",
+			},
+			"compare_to": map[string]interface{}{
+				"type":        "string",
+				"description": "Compare with a run saved earlier with save_as",
+			},
+			"save_as": map[string]interface{}{
+				"type":        "string",
+				"description": "Save this run under a name, to compare later runs to (e.g. before)",
+			},
+			"cpu_profile": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Capture a CPU profile (read it with pprof)",
+			},
+			"mem_profile": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Capture a memory profile (read it with pprof)",
+			},
+		},
+	}
+
+	return &GoBenchTool{
+		BaseTool: NewBaseTool(
+			"go_bench",
+			"Run Go benchmarks (go test -bench, -benchmem) and report ns/op, B/op and allocs/op averaged over several runs with their spread. Compares with a baseline: a git ref run in a temporary worktree (baseline_ref) or a run saved earlier (save_as, compare_to), flagging regressions beyond noise. Can capture CPU/memory profiles for pprof.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GoBenchTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	packages := "."
+	if p, ok := args["packages"].(string); ok && p != "" {
+		packages = p
+	}
+	bench := "."
+	if b, ok := args["bench"].(string); ok && b != "" {
+		bench = b
+	}
+	count := 5
+	if c, ok := args["count"].(float64); ok && c > 0 {
+		count = int(c)
+	}
+	benchtime, _ := args["benchtime"].(string)
+	dir := BaseDir(ctx, t.workingDir)
+	if p, ok := args["path"].(string); ok && p != "" {
+		dir = ResolveAbsPath(ctx, t.workingDir, p)
+	}
+	baselineRef, _ := args["baseline_ref"].(string)
+	compareTo, _ := args["compare_to"].(string)
+	saveAs, _ := args["save_as"].(string)
+	for _, name := range []string{compareTo, saveAs} {
+		if name != "" && !benchNameRe.MatchString(name) {
+			return map[string]interface{}{"error": fmt.Sprintf("invalid run name %q (letters, digits, . _ -)", name), "success": false}, nil
+		}
+	}
+	for _, arg := range []string{packages, bench, benchtime, baselineRef} {
+		if strings.HasPrefix(arg, "-") {
+			return map[string]interface{}{"error": fmt.Sprintf("invalid argument %q", arg), "success": false}, nil
+		}
+	}
+
+	profiles := profileDir(ctx)
+	testArgs := []string{"test", "-run=^$", "-bench=" + bench, "-benchmem", fmt.Sprintf("-count=%d", count)}
+	if benchtime != "" {
+		testArgs = append(testArgs, "-benchtime="+benchtime)
+	}
+	baseArgs := append([]string(nil), testArgs...)
+	result := map[string]interface{}{"packages": packages, "bench": bench, "count": count}
+	cpu, _ := args["cpu_profile"].(bool)
+	mem, _ := args["mem_profile"].(bool)
+	if cpu || mem {
+		if err := os.MkdirAll(profiles, 0o755); err != nil {
+			return nil, fmt.Errorf("create profile dir: %w", err)
+		}
+		stamp := time.Now().Format("20060102-150405")
+		paths := map[string]string{}
+		testArgs = append(testArgs, "-o="+filepath.Join(profiles, stamp+".test"))
+		if cpu {
+			paths["cpu"] = filepath.Join(profiles, stamp+"-cpu.pprof")
+			testArgs = append(testArgs, "-cpuprofile="+paths["cpu"])
+		}
+		if mem {
+			paths["mem"] = filepath.Join(profiles, stamp+"-mem.pprof")
+			testArgs = append(testArgs, "-memprofile="+paths["mem"])
+		}
+		result["profiles"] = paths
+	}
+
+	after, output, err := runBenchmarks(ctx, dir, append(testArgs, packages))
+	if err != nil {
+		result["error"] = err.Error()
+		result["test_output"] = truncateOutput(output, 8000)
+		result["success"] = false
+		return result, nil
+	}
+	result["benchmarks"] = after
+
+	var before []*BenchResult
+	switch {
+	case baselineRef != "":
+		before, output, err = benchmarksAtRef(ctx, dir, baselineRef, append(baseArgs, packages))
+		if err != nil {
+			result["error"] = fmt.Sprintf("baseline at %s: %v", baselineRef, err)
+			result["test_output"] = truncateOutput(output, 8000)
+			result["success"] = false
+			return result, nil
+		}
+		result["baseline"] = baselineRef
+	case compareTo != "":
+		data, err := os.ReadFile(filepath.Join(profiles, compareTo+".bench.json"))
+		if err != nil {
+			result["error"] = fmt.Sprintf("no saved run %q (save one with save_as)", compareTo)
+			result["success"] = false
+			return result, nil
+		}
+		if err := json.Unmarshal(data, &before); err != nil {
+			return nil, fmt.Errorf("read saved run %q: %w", compareTo, err)
+		}
+		result["baseline"] = compareTo
+	}
+	if before != nil {
+		deltas := compareBenchmarks(before, after)
+		result["comparison"] = deltas
+		result["summary"] = summarizeDeltas(deltas)
+	}
+
+	if saveAs != "" {
+		data, _ := json.Marshal(after)
+		if err := os.MkdirAll(profiles, 0o755); err == nil {
+			err = os.WriteFile(filepath.Join(profiles, saveAs+".bench.json"), data, 0o644)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("save run %q: %w", saveAs, err)
+		}
+		result["saved_as"] = saveAs
+	}
+	result["success"] = true
+	return result, nil
+}
+
+// profileDir holds the session's profiles and saved runs
+func profileDir(ctx context.Context) string {
+	id := "default"
+	if session := SessionFromContext(ctx); session != nil && session.ID != "" {
+		id = session.ID
+	}
+	return filepath.Join(os.TempDir(), "zen-claw-profiles", unsafeNameChars.ReplaceAllString(id, "_"))
+}
+
+// runBenchmarks runs go test with the session environment and parses the
+// benchmark lines; the error explains a failed run
+func runBenchmarks(ctx context.Context, dir string, testArgs []string) ([]*BenchResult, string, error) {
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	runCtx, cancel := context.WithTimeout(ctx, benchTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, "go", testArgs...)
+	cmd.Dir = dir
+	env.apply(cmd)
+	out, runErr := cmd.CombinedOutput()
+	output := env.redact(string(out))
+	results := parseBenchOutput(output)
+	if runErr != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			return results, output, fmt.Errorf("benchmarks exceeded %s", benchTimeout)
+		}
+		return results, output, fmt.Errorf("go test failed: %v", runErr)
+	}
+	if len(results) == 0 {
+		return nil, output, fmt.Errorf("no benchmark matched")
+	}
+	return results, output, nil
+}
+
+// benchmarksAtRef runs the benchmarks on ref in a temporary worktree
+func benchmarksAtRef(ctx context.Context, dir, ref string, testArgs []string) ([]*BenchResult, string, error) {
+	top, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("not a git repository")
+	}
+	root := strings.TrimSpace(string(top))
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, "", err
+	}
+	tree, cleanup, err := tempWorktree(ctx, root, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cleanup()
+	return runBenchmarks(ctx, filepath.Join(tree, rel), testArgs)
+}
+
+// parseBenchOutput reads go test -bench output: "BenchmarkX-8  1000  1234 ns/op  56 B/op  2 allocs/op"
+func parseBenchOutput(output string) []*BenchResult {
+	var results []*BenchResult
+	byName := map[string]*BenchResult{}
+	pkg := ""
+	for _, line := range strings.Split(output, "\n") {
+		if p, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(p)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.ParseInt(fields[1], 10, 64); err != nil {
+			continue
+		}
+		key := pkg + " " + fields[0]
+		r := byName[key]
+		if r == nil {
+			r = &BenchResult{Package: pkg, Name: fields[0], Metrics: map[string]*BenchMetric{}}
+			byName[key] = r
+			results = append(results, r)
+		}
+		r.Runs++
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			m := r.Metrics[fields[i+1]]
+			if m == nil {
+				m = &BenchMetric{}
+				r.Metrics[fields[i+1]] = m
+			}
+			m.values = append(m.values, v)
+		}
+	}
+	for _, r := range results {
+		for _, m := range r.Metrics {
+			m.Min, m.Max = math.Inf(1), math.Inf(-1)
+			sum := 0.0
+			for _, v := range m.values {
+				sum += v
+				m.Min, m.Max = math.Min(m.Min, v), math.Max(m.Max, v)
+			}
+			m.Mean = sum / float64(len(m.values))
+			if m.Mean != 0 {
+				m.Spread = round2((m.Max - m.Min) / 2 / m.Mean * 100)
+			}
+		}
+	}
+	return results
+}
+
+// compareBenchmarks compares the metrics of the benchmarks in both runs
+func compareBenchmarks(before, after []*BenchResult) []BenchDelta {
+	base := map[string]*BenchResult{}
+	for _, r := range before {
+		base[r.Package+" "+r.Name] = r
+	}
+	var deltas []BenchDelta
+	for _, r := range after {
+		old := base[r.Package+" "+r.Name]
+		if old == nil {
+			continue
+		}
+		units := make([]string, 0, len(r.Metrics))
+		for unit := range r.Metrics {
+			units = append(units, unit)
+		}
+		sort.Strings(units)
+		for _, unit := range units {
+			m, o := r.Metrics[unit], old.Metrics[unit]
+			if o == nil || o.Mean == 0 {
+				continue
+			}
+			d := BenchDelta{
+				Name: r.Name, Unit: unit, Before: o.Mean, After: m.Mean,
+				Delta: round2((m.Mean - o.Mean) / o.Mean * 100),
+				Noise: math.Max(o.Spread, m.Spread),
+			}
+			worse := d.Delta > 0
+			if strings.HasSuffix(unit, "/s") { // Throughput: higher is better
+				worse = d.Delta < 0
+			}
+			switch {
+			case math.Abs(d.Delta) <= math.Max(d.Noise, benchMinDelta):
+				d.Verdict = "unchanged"
+			case worse:
+				d.Verdict = "regression"
+			default:
+				d.Verdict = "improvement"
+			}
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+// summarizeDeltas counts the verdicts and names the regressions
+func summarizeDeltas(deltas []BenchDelta) string {
+	var regressions, improvements []string
+	unchanged := 0
+	for _, d := range deltas {
+		change := fmt.Sprintf("%s %s %+.1f%%", d.Name, d.Unit, d.Delta)
+		switch d.Verdict {
+		case "regression":
+			regressions = append(regressions, change)
+		case "improvement":
+			improvements = append(improvements, change)
+		default:
+			unchanged++
+		}
+	}
+	summary := fmt.Sprintf("%d regressions, %d improvements, %d unchanged (within noise)", len(regressions), len(improvements), unchanged)
+	if len(regressions) > 0 {
+		summary += ". Regressions: " + strings.Join(regressions, "; ")
+	}
+	if len(improvements) > 0 {
+		summary += ". Improvements: " + strings.Join(improvements, "; ")
+	}
+	return summary
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// PprofTool reads profiles with go tool pprof
+type PprofTool struct {
+	BaseTool
+	workingDir string
+}
+
+// PprofRow is a function of a pprof -top listing
+type PprofRow struct {
+	Flat     string  `json:"flat"`
+	FlatPct  float64 `json:"flat_pct"`
+	Cum      string  `json:"cum"`
+	CumPct   float64 `json:"cum_pct"`
+	Function string  `json:"function"`
+}
+
+// NewPprofTool creates a pprof tool
+func NewPprofTool(workingDir string) *PprofTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"profile": map[string]interface{}{
+				"type":        "string",
+				"description": "Profile file (e.g. from go_bench cpu_profile/mem_profile, or a saved /debug/pprof download)",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"top", "list"},
+				"description": "top: the heaviest functions (default); list: annotated source of the functions matching function",
+			},
+			"function": map[string]interface{}{
+				"type":        "string",
+				"description": "Function regexp for list mode (e.g. 'store.\\(\\*Cache\\).Get'), or focus for top mode",
+			},
+			"nodes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Functions in top mode (default 20)",
+			},
+			"cum": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Sort top by cumulative instead of flat (self) cost",
+			},
+			"sample_index": map[string]interface{}{
+				"type":        "string",
+				"description": "Sample type of memory profiles: alloc_space, alloc_objects, inuse_space (default), inuse_objects",
+			},
+		},
+		"required": []string{"profile"},
+	}
+
+	return &PprofTool{
+		BaseTool: NewBaseTool(
+			"pprof",
+			"Read a Go CPU or memory profile with go tool pprof: the top functions by flat or cumulative cost (parsed), or the annotated source of a function (list) to see which lines cost the most.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *PprofTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	profile, _ := args["profile"].(string)
+	if profile == "" {
+		return map[string]interface{}{"error": "profile is required", "success": false}, nil
+	}
+	profile = ResolveAbsPath(ctx, t.workingDir, profile)
+	if _, err := os.Stat(profile); err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	mode, _ := args["mode"].(string)
+	if mode == "" {
+		mode = "top"
+	}
+	function, _ := args["function"].(string)
+	nodes := 20
+	if n, ok := args["nodes"].(float64); ok && n > 0 {
+		nodes = int(n)
+	}
+	sampleIndex, _ := args["sample_index"].(string)
+	for _, arg := range []string{function, sampleIndex} {
+		if strings.HasPrefix(arg, "-") {
+			return map[string]interface{}{"error": fmt.Sprintf("invalid argument %q", arg), "success": false}, nil
+		}
+	}
+
+	pprofArgs := []string{"tool", "pprof"}
+	if sampleIndex != "" {
+		pprofArgs = append(pprofArgs, "-sample_index="+sampleIndex)
+	}
+	switch mode {
+	case "top":
+		pprofArgs = append(pprofArgs, "-top", fmt.Sprintf("-nodecount=%d", nodes))
+		if cum, _ := args["cum"].(bool); cum {
+			pprofArgs = append(pprofArgs, "-cum")
+		}
+		if function != "" {
+			pprofArgs = append(pprofArgs, "-focus="+function)
+		}
+	case "list":
+		if function == "" {
+			return map[string]interface{}{"error": "list needs function", "success": false}, nil
+		}
+		pprofArgs = append(pprofArgs, "-list="+function)
+	default:
+		return map[string]interface{}{"error": fmt.Sprintf("unknown mode %q (use top or list)", mode), "success": false}, nil
+	}
+	// The test binary next to a go_bench profile symbolizes older profiles
+	pprofArgs = append(pprofArgs, pprofBinary(profile)...)
+	pprofArgs = append(pprofArgs, profile)
+
+	runCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, "go", pprofArgs...)
+	cmd.Dir = BaseDir(ctx, t.workingDir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("pprof failed: %v", err), "output": truncateOutput(string(out), 4000), "success": false}, nil
+	}
+
+	if mode == "list" {
+		return map[string]interface{}{"profile": profile, "listing": truncateWithRef(ctx, string(out), MaxToolOutputBytes), "success": true}, nil
+	}
+	header, rows := parsePprofTop(string(out))
+	return map[string]interface{}{"profile": profile, "header": header, "top": rows, "success": true}, nil
+}
+
+// pprofBinary returns the test binary go_bench kept next to a profile
+func pprofBinary(profile string) []string {
+	stamp, _, ok := strings.Cut(filepath.Base(profile), "-")
+	if !ok {
+		return nil
+	}
+	binary := filepath.Join(filepath.Dir(profile), stamp+".test")
+	if _, err := os.Stat(binary); err != nil {
+		return nil
+	}
+	return []string{binary}
+}
+
+// parsePprofTop parses pprof -top output: the header lines (type,
+// duration, totals) and the rows "flat flat% sum% cum cum% function"
+func parsePprofTop(output string) ([]string, []PprofRow) {
+	var header []string
+	var rows []PprofRow
+	inRows := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !inRows {
+			if fields[0] == "flat" && len(fields) >= 5 {
+				inRows = true
+				continue
+			}
+			header = append(header, strings.TrimSpace(line))
+			continue
+		}
+		if len(fields) < 6 {
+			continue
+		}
+		flatPct, err1 := strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+		cumPct, err2 := strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		rows = append(rows, PprofRow{
+			Flat: fields[0], FlatPct: flatPct, Cum: fields[3], CumPct: cumPct,
+			Function: strings.Join(fields[5:], " "),
+		})
+	}
+	return header, rows
+}
@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// GIT BISECT TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// git_bisect finds the commit that broke something: it bisects between a
+// good and a bad commit, running a test command on each candidate the way
+// `git bisect run` does (exit 0 = good, 125 = can't test, anything else =
+// bad). The candidates are checked out in a temporary worktree, so the
+// user's checkout, branch and uncommitted changes are never touched. The
+// result is the first bad commit with its stat and a trimmed diff.
+
+// bisectDefaultStepTimeout bounds the test command on one commit
+const bisectDefaultStepTimeout = 10 * 60
+
+// bisectMaxSteps stops a bisect that doesn't converge (2^40 commits)
+const bisectMaxSteps = 40
+
+// bisectOutputTail is the test output kept per step
+const bisectOutputTail = 1500
+
+// bisectDiffMax caps the first bad commit's diff
+const bisectDiffMax = 12000
+
+// GitBisectTool finds the first bad commit with a test command
+type GitBisectTool struct {
+	BaseTool
+	workingDir string
+}
+
+// BisectStep is the test of one candidate commit
+type BisectStep struct {
+	Commit   string  `json:"commit"`
+	Subject  string  `json:"subject"`
+	Verdict  string  `json:"verdict"` // good, bad or skip
+	ExitCode int     `json:"exit_code"`
+	Seconds  float64 `json:"seconds"`
+	Output   string  `json:"output,omitempty"` // Tail of the test output (bad and skipped steps)
+}
+
+// NewGitBisectTool creates a git bisect tool
+func NewGitBisectTool(workingDir string) *GitBisectTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"good": map[string]interface{}{
+				"type":        "string",
+				"description": "A commit where the test passes (hash, tag or branch)",
+			},
+			"bad": map[string]interface{}{
+				"type":        "string",
+				"description": "A commit where the test fails (default: HEAD)",
+			},
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Test command run on each candidate from the repo root, e.g. 'go test ./internal/store -run TestOpen'. Exit 0 = good, 125 = can't test this commit (skip), anything else = bad.",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only consider commits touching these paths",
+			},
+			"step_timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seconds the command may run on one commit before it counts as bad (default 600)",
+			},
+		},
+		"required": []string{"good", "command"},
+	}
+
+	return &GitBisectTool{
+		BaseTool: NewBaseTool(
+			"git_bisect",
+			"Find the first bad commit between a good and a bad one with git bisect: runs the test command on each candidate in a temporary worktree (the checkout is never touched) and returns the first bad commit, its diff and the test result of every step. Make sure the command fails on bad and passes on good first.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GitBisectTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	good, _ := args["good"].(string)
+	command, _ := args["command"].(string)
+	if strings.TrimSpace(good) == "" || strings.TrimSpace(command) == "" {
+		return map[string]interface{}{"error": "good and command are required", "success": false}, nil
+	}
+	bad, _ := args["bad"].(string)
+	if bad == "" {
+		bad = "HEAD"
+	}
+	stepTimeout := bisectDefaultStepTimeout
+	if s, ok := args["step_timeout"].(float64); ok && s > 0 {
+		stepTimeout = int(s)
+	}
+	paths := stringList(args["paths"])
+	for _, ref := range append([]string{good, bad}, paths...) {
+		if strings.HasPrefix(ref, "-") {
+			return map[string]interface{}{"error": fmt.Sprintf("invalid argument %q", ref), "success": false}, nil
+		}
+	}
+
+	repo := BaseDir(ctx, t.workingDir)
+	git := func(dir string, args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return string(out), fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+		}
+		return string(out), nil
+	}
+	var commits [2]string
+	for i, ref := range []string{good, bad} {
+		out, err := git(repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		if err != nil {
+			return map[string]interface{}{"error": fmt.Sprintf("unknown commit %q", ref), "success": false}, nil
+		}
+		commits[i] = strings.TrimSpace(out)
+	}
+	if _, err := git(repo, "merge-base", "--is-ancestor", commits[0], commits[1]); err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("%s is not an ancestor of %s: bisect needs good before bad", good, bad), "success": false}, nil
+	}
+
+	// The worktree has its own HEAD and bisect state
+	tree, cleanup, err := tempWorktree(ctx, repo, commits[1])
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	defer cleanup()
+	out, err := git(tree, append([]string{"bisect", "start", commits[1], commits[0], "--"}, paths...)...)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	defer git(tree, "bisect", "reset", "--quiet")
+
+	var steps []BisectStep
+	firstBad, inconclusive := bisectResult(out), false
+	for firstBad == "" && len(steps) < bisectMaxSteps {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		head, _ := git(tree, "log", "-1", "--format=%H%x00%s")
+		hash, subject, _ := strings.Cut(strings.TrimSpace(head), "\x00")
+		step := t.runStep(ctx, tree, command, stepTimeout)
+		step.Commit, step.Subject = hash, subject
+		steps = append(steps, step)
+
+		// The command may leave build output behind; the next checkout needs a clean tree
+		git(tree, "reset", "--quiet", "--hard")
+		git(tree, "clean", "-fdq")
+		out, err = git(tree, "bisect", step.Verdict)
+		if err != nil {
+			if strings.Contains(out, "only 'skip'ped commits left") {
+				inconclusive = true
+				break
+			}
+			return map[string]interface{}{"error": err.Error(), "steps": steps, "success": false}, nil
+		}
+		if strings.Contains(out, "only 'skip'ped commits left") {
+			inconclusive = true
+			break
+		}
+		firstBad = bisectResult(out)
+	}
+
+	result := map[string]interface{}{
+		"good":    commits[0],
+		"bad":     commits[1],
+		"command": command,
+		"steps":   steps,
+	}
+	if log, err := git(tree, "bisect", "log"); err == nil {
+		result["bisect_log"] = truncateOutput(log, 4000)
+	}
+	if firstBad == "" {
+		result["success"] = false
+		if inconclusive {
+			result["error"] = "the first bad commit is among commits that couldn't be tested (exit 125); see bisect_log"
+		} else {
+			result["error"] = fmt.Sprintf("bisect did not converge in %d steps", bisectMaxSteps)
+		}
+		return result, nil
+	}
+
+	info, _ := git(repo, "show", "-s", "--format=%H%x00%an <%ae>%x00%aI%x00%B", firstBad)
+	parts := strings.SplitN(strings.TrimSpace(info), "\x00", 4)
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+	stat, _ := git(repo, "show", "--stat", "--format=", firstBad)
+	diff, _ := git(repo, "show", "--format=", "--no-color", firstBad)
+	result["first_bad_commit"] = map[string]interface{}{
+		"hash":    parts[0],
+		"author":  parts[1],
+		"date":    parts[2],
+		"message": strings.TrimSpace(parts[3]),
+		"stat":    strings.TrimSpace(stat),
+		"diff":    truncateWithRef(ctx, diff, bisectDiffMax),
+	}
+	result["success"] = true
+	return result, nil
+}
+
+// runStep runs the test command on the checked-out candidate
+func (t *GitBisectTool) runStep(ctx context.Context, dir, command string, stepTimeout int) BisectStep {
+	started := time.Now()
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return BisectStep{Verdict: "skip", ExitCode: -1, Output: err.Error()}
+	}
+	limits := effectiveLimits(ctx)
+	limits.TimeoutSeconds = tighter(limits.TimeoutSeconds, stepTimeout)
+	runCtx, cancel := limitTimeout(ctx, limits)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, "bash", "-c", limitCommand(command, limits))
+	cmd.Dir = dir
+	env.apply(cmd)
+	output, _ := cmd.CombinedOutput()
+
+	step := BisectStep{ExitCode: cmd.ProcessState.ExitCode(), Seconds: time.Since(started).Round(100 * time.Millisecond).Seconds()}
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		step.Verdict = "bad"
+		output = append(output, fmt.Sprintf("\n[timed out after %ds]", limits.TimeoutSeconds)...)
+	case step.ExitCode == 0:
+		step.Verdict = "good"
+	case step.ExitCode == 125:
+		step.Verdict = "skip"
+	default:
+		step.Verdict = "bad"
+	}
+	if step.Verdict != "good" {
+		tail := env.redact(string(output))
+		if len(tail) > bisectOutputTail {
+			tail = "..." + strings.ToValidUTF8(tail[len(tail)-bisectOutputTail:], "")
+		}
+		step.Output = strings.TrimSpace(tail)
+	}
+	return step
+}
+
+// bisectResult returns the first bad commit git bisect announced ("" while
+// it goes on)
+func bisectResult(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if hash, ok := strings.CutSuffix(strings.TrimSpace(line), " is the first bad commit"); ok {
+			return hash
+		}
+	}
+	return ""
+}
+
+// tempWorktree checks out commit in a temporary worktree of the repo at
+// dir; cleanup removes it
+func tempWorktree(ctx context.Context, dir, commit string) (string, func(), error) {
+	tree, err := os.MkdirTemp("", "zen-claw-worktree-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("create worktree dir: %w", err)
+	}
+	cleanup := func() {
+		exec.Command("git", "-C", dir, "worktree", "remove", "--force", tree).Run()
+		os.RemoveAll(tree)
+		exec.Command("git", "-C", dir, "worktree", "prune").Run()
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "worktree", "add", "--detach", "--quiet", tree, commit).CombinedOutput()
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("git worktree: %s", strings.TrimSpace(string(out)))
+	}
+	return tree, cleanup, nil
+}
@@ -0,0 +1,439 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// CHUNKED WRITE TOOLS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A file too large for one write_file call (the model's output limit cuts
+// the arguments short) is written in pieces: begin_write opens a pending
+// write, append_chunk adds numbered chunks to a staging file on the server,
+// and commit_write validates the assembled content and renames it over the
+// target in one step. Until the commit the target is untouched; a retried
+// chunk is recognized and ignored, a missing one is reported.
+
+// chunkedWriteTTL discards pending writes left uncommitted
+const chunkedWriteTTL = time.Hour
+
+// chunkedWriteMaxBytes caps an assembled file
+const chunkedWriteMaxBytes = 32 << 20
+
+// pendingWrite is a chunked write between begin_write and commit_write
+type pendingWrite struct {
+	id         string
+	sessionID  string
+	path       string // As given
+	fullPath   string
+	createDirs bool
+	mode       os.FileMode // Set by begin_write's mode argument
+	setMode    bool
+	staging    string // Temp file the chunks are appended to
+	hashes     []string
+	size       int
+	started    time.Time
+	mu         sync.Mutex
+}
+
+// ChunkedWrites holds the pending chunked writes
+type ChunkedWrites struct {
+	writes map[string]*pendingWrite
+	mu     sync.Mutex
+}
+
+// Global pending writes (per gateway instance)
+var globalChunkedWrites = &ChunkedWrites{writes: make(map[string]*pendingWrite)}
+
+// begin opens a pending write, dropping the expired ones
+func (cw *ChunkedWrites) begin(sessionID, path, fullPath string, createDirs bool, mode os.FileMode, setMode bool) (*pendingWrite, error) {
+	staging, err := os.CreateTemp("", "zen-claw-write-*")
+	if err != nil {
+		return nil, fmt.Errorf("create staging file: %w", err)
+	}
+	staging.Close()
+	var random [6]byte
+	rand.Read(random[:])
+	w := &pendingWrite{
+		id:         "w-" + hex.EncodeToString(random[:]),
+		sessionID:  sessionID,
+		path:       path,
+		fullPath:   fullPath,
+		createDirs: createDirs,
+		mode:       mode,
+		setMode:    setMode,
+		staging:    staging.Name(),
+		started:    time.Now(),
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	for id, old := range cw.writes {
+		if time.Since(old.started) > chunkedWriteTTL {
+			os.Remove(old.staging)
+			delete(cw.writes, id)
+		}
+	}
+	cw.writes[w.id] = w
+	return w, nil
+}
+
+// get returns the session's pending write
+func (cw *ChunkedWrites) get(sessionID, id string) (*pendingWrite, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	w, ok := cw.writes[id]
+	if !ok || w.sessionID != sessionID {
+		return nil, fmt.Errorf("no pending write %q (expired, committed or never begun): start again with begin_write", id)
+	}
+	return w, nil
+}
+
+// finish forgets a pending write and removes its staging file
+func (cw *ChunkedWrites) finish(w *pendingWrite) {
+	cw.mu.Lock()
+	delete(cw.writes, w.id)
+	cw.mu.Unlock()
+	os.Remove(w.staging)
+}
+
+// chunkSessionID identifies the session a write belongs to
+func chunkSessionID(ctx context.Context) string {
+	if session := SessionFromContext(ctx); session != nil {
+		return session.ID
+	}
+	return ""
+}
+
+// BeginWriteTool opens a chunked write
+type BeginWriteTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewBeginWriteTool creates a begin_write tool
+func NewBeginWriteTool(workingDir string) *BeginWriteTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File path to write to",
+			},
+			"create_dirs": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Create parent directories at commit if they don't exist (default: true)",
+			},
+			"mode": fileModeParam,
+		},
+		"required": []string{"path"},
+	}
+
+	return &BeginWriteTool{
+		BaseTool: NewBaseTool(
+			"begin_write",
+			"Start writing a large file in chunks, for content too long for one write_file call. Returns a write_id; send the content with append_chunk (index 0, 1, 2...) and finish with commit_write. The file is only replaced at commit.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *BeginWriteTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	createDirs := true
+	if cd, ok := args["create_dirs"].(bool); ok {
+		createDirs = cd
+	}
+	mode, setMode, err := parseFileMode(args["mode"])
+	if err != nil {
+		return map[string]interface{}{"path": path, "error": err.Error(), "success": false}, nil
+	}
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+	if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
+		return map[string]interface{}{"path": path, "error": "path is a directory", "success": false}, nil
+	}
+	if !createDirs {
+		if _, err := os.Stat(filepath.Dir(fullPath)); err != nil {
+			return map[string]interface{}{"path": path, "error": fmt.Sprintf("parent directory: %v", err), "success": false}, nil
+		}
+	}
+
+	w, err := globalChunkedWrites.begin(chunkSessionID(ctx), path, fullPath, createDirs, mode, setMode)
+	if err != nil {
+		return map[string]interface{}{"path": path, "error": err.Error(), "success": false}, nil
+	}
+	return map[string]interface{}{
+		"path":     path,
+		"write_id": w.id,
+		"next":     "append_chunk with index 0",
+		"success":  true,
+	}, nil
+}
+
+// AppendChunkTool adds a chunk to a pending write
+type AppendChunkTool struct {
+	BaseTool
+}
+
+// NewAppendChunkTool creates an append_chunk tool
+func NewAppendChunkTool() *AppendChunkTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"write_id": map[string]interface{}{
+				"type":        "string",
+				"description": "The write_id from begin_write",
+			},
+			"index": map[string]interface{}{
+				"type":        "integer",
+				"description": "Position of the chunk, from 0. Resending a chunk with the same index and content is ignored.",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "The next piece of the file, exactly (chunks are joined without separators)",
+			},
+		},
+		"required": []string{"write_id", "index", "content"},
+	}
+
+	return &AppendChunkTool{
+		BaseTool: NewBaseTool(
+			"append_chunk",
+			"Append the next chunk of a file started with begin_write. Chunks are joined as-is, so end a chunk at a line break and start the next on the following line.",
+			params,
+		),
+	}
+}
+
+func (t *AppendChunkTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	id, _ := args["write_id"].(string)
+	content, ok := args["content"].(string)
+	if id == "" || !ok {
+		return nil, fmt.Errorf("write_id and content parameters are required")
+	}
+	indexArg, ok := args["index"].(float64)
+	if !ok || indexArg < 0 {
+		return nil, fmt.Errorf("index parameter is required")
+	}
+	index := int(indexArg)
+
+	w, err := globalChunkedWrites.get(chunkSessionID(ctx), id)
+	if err != nil {
+		return map[string]interface{}{"write_id": id, "error": err.Error(), "success": false}, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hash := contentHash([]byte(content))
+	result := map[string]interface{}{"write_id": id, "index": index}
+	switch {
+	case index < len(w.hashes) && w.hashes[index] == hash:
+		result["duplicate"] = true
+	case index < len(w.hashes):
+		result["error"] = fmt.Sprintf("chunk %d was already received with different content; chunks can't be replaced, begin_write again to start over", index)
+		result["success"] = false
+		return result, nil
+	case index > len(w.hashes):
+		result["error"] = fmt.Sprintf("expected chunk %d, got %d: send the missing chunks first", len(w.hashes), index)
+		result["success"] = false
+		return result, nil
+	case w.size+len(content) > chunkedWriteMaxBytes:
+		result["error"] = fmt.Sprintf("the file would exceed %d MB", chunkedWriteMaxBytes>>20)
+		result["success"] = false
+		return result, nil
+	default:
+		f, err := os.OpenFile(w.staging, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("open staging file: %w", err)
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("write staging file: %w", err)
+		}
+		w.hashes = append(w.hashes, hash)
+		w.size += len(content)
+	}
+	result["chunks"] = len(w.hashes)
+	result["size"] = w.size
+	result["success"] = true
+	return result, nil
+}
+
+// CommitWriteTool assembles a pending write into its file
+type CommitWriteTool struct {
+	BaseTool
+}
+
+// NewCommitWriteTool creates a commit_write tool
+func NewCommitWriteTool() *CommitWriteTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"write_id": map[string]interface{}{
+				"type":        "string",
+				"description": "The write_id from begin_write",
+			},
+			"chunks": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of chunks you sent; the commit fails if fewer arrived",
+			},
+			"discard": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Abandon the write instead, leaving the file untouched",
+			},
+			"skip_syntax_check": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write even if the result fails syntax validation (default: false)",
+			},
+		},
+		"required": []string{"write_id"},
+	}
+
+	return &CommitWriteTool{
+		BaseTool: NewBaseTool(
+			"commit_write",
+			"Finish a chunked write: checks the syntax of the assembled file and atomically replaces the target with it. After a failed check the write stays pending: discard it, or commit with skip_syntax_check if the error is expected.",
+			params,
+		),
+	}
+}
+
+func (t *CommitWriteTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	id, _ := args["write_id"].(string)
+	if id == "" {
+		return nil, fmt.Errorf("write_id parameter is required")
+	}
+	w, err := globalChunkedWrites.get(chunkSessionID(ctx), id)
+	if err != nil {
+		return map[string]interface{}{"write_id": id, "error": err.Error(), "success": false}, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if discard, _ := args["discard"].(bool); discard {
+		globalChunkedWrites.finish(w)
+		return map[string]interface{}{"write_id": id, "path": w.path, "action": "discarded", "success": true}, nil
+	}
+	if want, ok := args["chunks"].(float64); ok && int(want) != len(w.hashes) {
+		return map[string]interface{}{
+			"write_id": id,
+			"path":     w.path,
+			"error":    fmt.Sprintf("%d chunks expected, %d received: send chunks %d to %d first", int(want), len(w.hashes), len(w.hashes), int(want)-1),
+			"success":  false,
+		}, nil
+	}
+
+	content, err := os.ReadFile(w.staging)
+	if err != nil {
+		return nil, fmt.Errorf("read staging file: %w", err)
+	}
+	// Keep the line endings, BOM and encoding of the file being replaced
+	format := defaultFileFormat
+	if existing, err := os.ReadFile(w.fullPath); err == nil {
+		if _, format, err = decodeText(existing); err != nil {
+			result := formatErrorResult(w.path, err)
+			result["write_id"] = id
+			return result, nil
+		}
+	}
+	if skip, _ := args["skip_syntax_check"].(bool); !skip {
+		if err := validateSyntax(w.fullPath, content); err != nil {
+			result := syntaxErrorResult(w.path, err)
+			result["write_id"] = id
+			return result, nil
+		}
+	}
+	if w.createDirs {
+		if err := os.MkdirAll(filepath.Dir(w.fullPath), 0755); err != nil {
+			return map[string]interface{}{"path": w.path, "error": fmt.Sprintf("failed to create directories: %v", err), "success": false}, nil
+		}
+	}
+
+	// The new file gets the mode asked for, or the one it replaces
+	existed := false
+	mode, setMode := w.mode, w.setMode
+	if info, err := os.Stat(w.fullPath); err == nil {
+		existed = true
+		if !setMode {
+			mode, setMode = info.Mode().Perm(), true
+		}
+	}
+	data := content
+	if !format.plain() {
+		data = format.encode(string(content))
+		if err := os.WriteFile(w.staging, data, 0600); err != nil {
+			return nil, fmt.Errorf("write staging file: %w", err)
+		}
+	}
+	if err := replaceFile(w.fullPath, w.staging, mode, setMode); err != nil {
+		return map[string]interface{}{"path": w.path, "error": err.Error(), "success": false}, nil
+	}
+	globalChunkedWrites.finish(w)
+	recordFileRead(ctx, w.fullPath, data)
+
+	action := "created"
+	if existed {
+		action = "overwritten"
+	}
+	result := map[string]interface{}{
+		"path":    w.path,
+		"action":  action,
+		"size":    len(data),
+		"chunks":  len(w.hashes),
+		"format":  format.String(),
+		"success": true,
+	}
+	if info, err := os.Stat(w.fullPath); err == nil {
+		result["mode"] = formatFileMode(info.Mode())
+	}
+	applyPostWriteHooks(ctx, w.fullPath, result)
+	return result, nil
+}
+
+// replaceFile copies src next to target and renames it over target, so
+// readers see the old file or the new one, never a partial write. The file
+// gets mode if set, newFileMode narrowed by the umask otherwise.
+func replaceFile(target, src string, mode os.FileMode, setMode bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := createTempFile(filepath.Dir(target), "."+filepath.Base(target)+".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, in)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && setMode {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
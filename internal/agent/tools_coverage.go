@@ -0,0 +1,370 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TEST COVERAGE TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// coverageTimeout bounds a coverage test run
+const coverageTimeout = 10 * time.Minute
+
+// coverBlock is one block from a Go cover profile
+type coverBlock struct {
+	startLine, startCol int
+	endLine, endCol     int
+	stmts               int
+	count               int
+}
+
+// fileCoverage aggregates blocks for one source file
+type fileCoverage struct {
+	file    string // Import-path style name from the profile
+	path    string // Resolved path on disk (may be empty)
+	blocks  []coverBlock
+	stmts   int
+	covered int
+}
+
+func (f *fileCoverage) percent() float64 {
+	if f.stmts == 0 {
+		return 100
+	}
+	return float64(f.covered) * 100 / float64(f.stmts)
+}
+
+// parseCoverProfile parses the output of go test -coverprofile
+func parseCoverProfile(path string) (map[string]*fileCoverage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	files := make(map[string]*fileCoverage)
+	// Blocks can repeat across packages when tests of one package cover another; keep the max count
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") || line == "" {
+			continue
+		}
+		// name.go:12.34,15.2 3 1
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			continue
+		}
+		name := line[:colon]
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) != 3 {
+			continue
+		}
+		var b coverBlock
+		if _, err := fmt.Sscanf(fields[0], "%d.%d,%d.%d", &b.startLine, &b.startCol, &b.endLine, &b.endCol); err != nil {
+			continue
+		}
+		b.stmts, _ = strconv.Atoi(fields[1])
+		b.count, _ = strconv.Atoi(fields[2])
+
+		key := name + ":" + fields[0]
+		if idx, ok := seen[key]; ok {
+			fc := files[name]
+			if b.count > 0 && fc.blocks[idx].count == 0 {
+				fc.covered += b.stmts
+			}
+			if b.count > fc.blocks[idx].count {
+				fc.blocks[idx].count = b.count
+			}
+			continue
+		}
+
+		fc, ok := files[name]
+		if !ok {
+			fc = &fileCoverage{file: name}
+			files[name] = fc
+		}
+		seen[key] = len(fc.blocks)
+		fc.blocks = append(fc.blocks, b)
+		fc.stmts += b.stmts
+		if b.count > 0 {
+			fc.covered += b.stmts
+		}
+	}
+	return files, scanner.Err()
+}
+
+// uncoveredRanges merges zero-count blocks into line ranges like "12-15"
+func (f *fileCoverage) uncoveredRanges() []string {
+	var blocks []coverBlock
+	for _, b := range f.blocks {
+		if b.count == 0 && b.stmts > 0 {
+			blocks = append(blocks, b)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].startLine < blocks[j].startLine })
+
+	var ranges []string
+	start, end := -1, -1
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		if start == end {
+			ranges = append(ranges, strconv.Itoa(start))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, end))
+		}
+	}
+	for _, b := range blocks {
+		if start >= 0 && b.startLine <= end+1 {
+			if b.endLine > end {
+				end = b.endLine
+			}
+			continue
+		}
+		flush()
+		start, end = b.startLine, b.endLine
+	}
+	flush()
+	return ranges
+}
+
+// functionCoverage computes per-function coverage by mapping blocks onto function bodies
+func (f *fileCoverage) functionCoverage() []map[string]interface{} {
+	if f.path == "" {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, f.path, nil, 0)
+	if err != nil {
+		return nil
+	}
+
+	var funcs []map[string]interface{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start := fset.Position(fn.Pos()).Line
+		end := fset.Position(fn.End()).Line
+		stmts, covered := 0, 0
+		for _, b := range f.blocks {
+			if b.startLine >= start && b.endLine <= end {
+				stmts += b.stmts
+				if b.count > 0 {
+					covered += b.stmts
+				}
+			}
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = fmt.Sprintf("(%s).%s", exprString(fn.Recv.List[0].Type), name)
+		}
+		pct := 100.0
+		if stmts > 0 {
+			pct = float64(covered) * 100 / float64(stmts)
+		}
+		funcs = append(funcs, map[string]interface{}{
+			"function": name,
+			"line":     start,
+			"coverage": roundPercent(pct),
+		})
+	}
+	return funcs
+}
+
+// exprString renders a receiver type expression like *Foo or Foo[T]
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return exprString(e.X)
+	case *ast.IndexListExpr:
+		return exprString(e.X)
+	default:
+		return "?"
+	}
+}
+
+// roundPercent rounds a percentage to one decimal place
+func roundPercent(p float64) float64 {
+	return float64(int(p*10+0.5)) / 10
+}
+
+// CoverageTool runs tests with coverage and reports per-file and per-function results
+type CoverageTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewCoverageTool creates a new coverage tool
+func NewCoverageTool(workingDir string) *CoverageTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"packages": map[string]interface{}{
+				"type":        "string",
+				"description": "Package patterns to test, separated by spaces (default: ./...)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to run in (default: working directory)",
+			},
+			"file_filter": map[string]interface{}{
+				"type":        "string",
+				"description": "Only report files whose path contains this substring (e.g. internal/agent)",
+			},
+			"functions": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include per-function coverage (default: true)",
+			},
+		},
+	}
+
+	return &CoverageTool{
+		BaseTool: NewBaseTool(
+			"coverage",
+			"Run go test with -coverprofile and report coverage per file and per function, including uncovered line ranges. Files are sorted least-covered first.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *CoverageTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	packages := "./..."
+	if p, ok := args["packages"].(string); ok && p != "" {
+		packages = p
+	}
+	patterns := strings.Fields(packages)
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "-") {
+			return nil, fmt.Errorf("packages must be package patterns, not flags: %s", p)
+		}
+	}
+	dir := BaseDir(ctx, t.workingDir)
+	if p, ok := args["path"].(string); ok && p != "" {
+		dir = ResolveAbsPath(ctx, t.workingDir, p)
+	}
+	filter, _ := args["file_filter"].(string)
+	includeFuncs := true
+	if f, ok := args["functions"].(bool); ok {
+		includeFuncs = f
+	}
+
+	profile, err := os.CreateTemp("", "zen-claw-cover-*.out")
+	if err != nil {
+		return nil, fmt.Errorf("create profile: %w", err)
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	runCtx, cancel := context.WithTimeout(ctx, coverageTimeout)
+	defer cancel()
+	cmdArgs := []string{"test", "-coverprofile=" + profile.Name(), "-covermode=set"}
+	cmd := exec.CommandContext(runCtx, "go", append(cmdArgs, patterns...)...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	output, runErr := cmd.CombinedOutput()
+
+	files, err := parseCoverProfile(profile.Name())
+	if err != nil || len(files) == 0 {
+		result := map[string]interface{}{
+			"packages":    packages,
+			"error":       "no coverage profile produced",
+			"test_output": truncateOutput(string(output), 8000),
+			"success":     false,
+		}
+		if runErr != nil {
+			result["error"] = fmt.Sprintf("go test failed: %v", runErr)
+		}
+		return result, nil
+	}
+
+	// Map import-path file names back to disk using the module root
+	var mod *goModule
+	if dir != "" {
+		mod, _ = findGoModule(dir)
+	} else if wd, err := os.Getwd(); err == nil {
+		mod, _ = findGoModule(wd)
+	}
+
+	var list []*fileCoverage
+	totalStmts, totalCovered := 0, 0
+	for name, fc := range files {
+		if mod != nil && strings.HasPrefix(name, mod.path+"/") {
+			fc.path = filepath.Join(mod.root, filepath.FromSlash(strings.TrimPrefix(name, mod.path+"/")))
+		}
+		totalStmts += fc.stmts
+		totalCovered += fc.covered
+		if filter != "" && !strings.Contains(name, filter) {
+			continue
+		}
+		list = append(list, fc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].percent() < list[j].percent() })
+
+	var fileResults []map[string]interface{}
+	filteredStmts, filteredCovered := 0, 0
+	for _, fc := range list {
+		filteredStmts += fc.stmts
+		filteredCovered += fc.covered
+		entry := map[string]interface{}{
+			"file":       fc.file,
+			"coverage":   roundPercent(fc.percent()),
+			"statements": fc.stmts,
+			"covered":    fc.covered,
+		}
+		if ranges := fc.uncoveredRanges(); len(ranges) > 0 {
+			entry["uncovered_lines"] = strings.Join(ranges, ",")
+		}
+		if includeFuncs {
+			entry["functions"] = fc.functionCoverage()
+		}
+		fileResults = append(fileResults, entry)
+	}
+
+	total := 0.0
+	if totalStmts > 0 {
+		total = float64(totalCovered) * 100 / float64(totalStmts)
+	}
+	result := map[string]interface{}{
+		"packages":       packages,
+		"total_coverage": roundPercent(total),
+		"files":          fileResults,
+		"file_count":     len(fileResults),
+		"tests_passed":   runErr == nil,
+		"success":        true,
+	}
+	if filter != "" {
+		pct := 0.0
+		if filteredStmts > 0 {
+			pct = float64(filteredCovered) * 100 / float64(filteredStmts)
+		}
+		result["filtered_coverage"] = roundPercent(pct)
+	}
+	if runErr != nil {
+		result["test_output"] = truncateOutput(string(output), 8000)
+	}
+	return result, nil
+}
@@ -0,0 +1,414 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// DEPENDENCY INSPECTION TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// depsTimeout bounds go list / go mod graph / govulncheck runs
+const depsTimeout = 5 * time.Minute
+
+// maxDependencyChains caps how many "why" chains are returned
+const maxDependencyChains = 10
+
+// goModuleInfo is the subset of `go list -m -json` output we report
+type goModuleInfo struct {
+	Path     string        `json:"Path"`
+	Version  string        `json:"Version,omitempty"`
+	Main     bool          `json:"Main,omitempty"`
+	Indirect bool          `json:"Indirect,omitempty"`
+	Replace  *goModuleInfo `json:"Replace,omitempty"`
+	Update   *goModuleInfo `json:"Update,omitempty"`
+}
+
+// GoDepsTool inspects Go module dependencies and known vulnerabilities
+type GoDepsTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewGoDepsTool creates a new Go dependency inspection tool
+func NewGoDepsTool(workingDir string) *GoDepsTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "Action: 'list' (all modules), 'why' (dependency chains pulling in a module), 'graph' (direct edges of a module), 'vulns' (govulncheck)",
+			},
+			"module": map[string]interface{}{
+				"type":        "string",
+				"description": "Module path (required for why/graph; optional filter for list)",
+			},
+			"updates": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For list: include available updates (slower, needs network)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Module directory (default: working directory)",
+			},
+		},
+		"required": []string{"action"},
+	}
+
+	return &GoDepsTool{
+		BaseTool: NewBaseTool(
+			"go_deps",
+			"Inspect Go module dependencies with structured output. Actions: list (modules and versions), why (which dependency chains pull in a module), graph (what a module requires and who requires it), vulns (known CVEs via govulncheck).",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GoDepsTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	action, ok := args["action"].(string)
+	if !ok || action == "" {
+		return nil, fmt.Errorf("action parameter is required")
+	}
+	dir := BaseDir(ctx, t.workingDir)
+	if p, ok := args["path"].(string); ok && p != "" {
+		dir = ResolveAbsPath(ctx, t.workingDir, p)
+	}
+	module, _ := args["module"].(string)
+
+	runCtx, cancel := context.WithTimeout(ctx, depsTimeout)
+	defer cancel()
+
+	switch action {
+	case "list":
+		updates, _ := args["updates"].(bool)
+		return t.list(runCtx, dir, module, updates), nil
+	case "why", "graph":
+		if module == "" {
+			return map[string]interface{}{
+				"error":   fmt.Sprintf("module is required for %s action", action),
+				"success": false,
+			}, nil
+		}
+		if action == "why" {
+			return t.why(runCtx, dir, module), nil
+		}
+		return t.graph(runCtx, dir, module), nil
+	case "vulns":
+		return t.vulns(runCtx, dir), nil
+	default:
+		return map[string]interface{}{
+			"error":   fmt.Sprintf("unknown action: %s (use list, why, graph, vulns)", action),
+			"success": false,
+		}, nil
+	}
+}
+
+// runGo runs a go subcommand in dir and returns stdout
+func runGo(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// list reports modules from `go list -m -json all`
+func (t *GoDepsTool) list(ctx context.Context, dir, filter string, updates bool) map[string]interface{} {
+	goArgs := []string{"list", "-m", "-json"}
+	if updates {
+		goArgs = append(goArgs, "-u")
+	}
+	out, err := runGo(ctx, dir, append(goArgs, "all")...)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}
+	}
+
+	var modules []map[string]interface{}
+	direct, indirect := 0, 0
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m goModuleInfo
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return map[string]interface{}{"error": fmt.Sprintf("parse go list output: %v", err), "success": false}
+		}
+		if m.Main {
+			continue
+		}
+		if m.Indirect {
+			indirect++
+		} else {
+			direct++
+		}
+		if filter != "" && !strings.Contains(m.Path, filter) {
+			continue
+		}
+		entry := map[string]interface{}{
+			"path":     m.Path,
+			"version":  m.Version,
+			"indirect": m.Indirect,
+		}
+		if m.Replace != nil {
+			entry["replace"] = strings.TrimSpace(m.Replace.Path + " " + m.Replace.Version)
+		}
+		if m.Update != nil {
+			entry["update"] = m.Update.Version
+		}
+		modules = append(modules, entry)
+	}
+
+	return map[string]interface{}{
+		"modules":  modules,
+		"count":    len(modules),
+		"direct":   direct,
+		"indirect": indirect,
+		"success":  true,
+	}
+}
+
+// modGraph is the parsed `go mod graph` output
+type modGraph struct {
+	root     string
+	requires map[string][]string // node -> required nodes
+}
+
+// loadModGraph runs and parses `go mod graph`
+func loadModGraph(ctx context.Context, dir string) (*modGraph, error) {
+	out, err := runGo(ctx, dir, "mod", "graph")
+	if err != nil {
+		return nil, err
+	}
+	g := &modGraph{requires: make(map[string][]string)}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if g.root == "" {
+			g.root = fields[0]
+		}
+		g.requires[fields[0]] = append(g.requires[fields[0]], fields[1])
+	}
+	return g, nil
+}
+
+// modulePathOf strips the @version suffix from a graph node
+func modulePathOf(node string) string {
+	if i := strings.LastIndex(node, "@"); i > 0 {
+		return node[:i]
+	}
+	return node
+}
+
+// chainsTo returns shortest dependency chains from the main module to any version of module
+func (g *modGraph) chainsTo(module string, limit int) [][]string {
+	type item struct {
+		node string
+		path []string
+	}
+	queue := []item{{g.root, []string{g.root}}}
+	visited := map[string]bool{g.root: true}
+	var chains [][]string
+
+	for len(queue) > 0 && len(chains) < limit {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range g.requires[cur.node] {
+			path := append(append([]string(nil), cur.path...), next)
+			if modulePathOf(next) == module {
+				chains = append(chains, path)
+				if len(chains) >= limit {
+					break
+				}
+				continue
+			}
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, item{next, path})
+			}
+		}
+	}
+	return chains
+}
+
+// why explains which dependency chains pull in module
+func (t *GoDepsTool) why(ctx context.Context, dir, module string) map[string]interface{} {
+	g, err := loadModGraph(ctx, dir)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}
+	}
+
+	chains := g.chainsTo(module, maxDependencyChains)
+	var rendered []string
+	for _, c := range chains {
+		rendered = append(rendered, strings.Join(c, " → "))
+	}
+
+	result := map[string]interface{}{
+		"module":   module,
+		"chains":   rendered,
+		"required": len(chains) > 0,
+		"success":  true,
+	}
+	// Package-level explanation (only reports modules whose packages are actually imported)
+	if out, err := runGo(ctx, dir, "mod", "why", "-m", module); err == nil {
+		result["go_mod_why"] = strings.TrimSpace(string(out))
+	}
+	return result
+}
+
+// graph reports the direct edges of a module in the graph
+func (t *GoDepsTool) graph(ctx context.Context, dir, module string) map[string]interface{} {
+	g, err := loadModGraph(ctx, dir)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}
+	}
+
+	requires := make(map[string]bool)
+	requiredBy := make(map[string]bool)
+	for from, tos := range g.requires {
+		for _, to := range tos {
+			if modulePathOf(from) == module {
+				requires[to] = true
+			}
+			if modulePathOf(to) == module {
+				requiredBy[from] = true
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"module":      module,
+		"requires":    sortedKeys(requires),
+		"required_by": sortedKeys(requiredBy),
+		"success":     true,
+	}
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// govulncheckMessage is one message from `govulncheck -json`
+type govulncheckMessage struct {
+	OSV *struct {
+		ID      string   `json:"id"`
+		Summary string   `json:"summary"`
+		Aliases []string `json:"aliases"`
+	} `json:"osv,omitempty"`
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Version  string `json:"version"`
+			Package  string `json:"package"`
+			Function string `json:"function"`
+		} `json:"trace"`
+	} `json:"finding,omitempty"`
+}
+
+// vulns runs govulncheck and summarizes findings per vulnerability
+func (t *GoDepsTool) vulns(ctx context.Context, dir string) map[string]interface{} {
+	if _, err := exec.LookPath("govulncheck"); err != nil {
+		return map[string]interface{}{
+			"error":      "govulncheck not installed",
+			"error_code": types.ErrUnavailable,
+			"hint":       "Install with: go install golang.org/x/vuln/cmd/govulncheck@latest",
+			"success":    false,
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "govulncheck", "-json", "./...")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return map[string]interface{}{
+			"error":   fmt.Sprintf("govulncheck failed: %v: %s", err, strings.TrimSpace(stderr.String())),
+			"success": false,
+		}
+	}
+
+	type vuln struct {
+		ID           string   `json:"id"`
+		Summary      string   `json:"summary"`
+		Aliases      []string `json:"aliases,omitempty"`
+		Module       string   `json:"module"`
+		Version      string   `json:"version"`
+		FixedVersion string   `json:"fixed_version,omitempty"`
+		Called       bool     `json:"called"` // Vulnerable function is reachable from your code
+	}
+	summaries := make(map[string]govulncheckMessage)
+	found := make(map[string]*vuln)
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var msg govulncheckMessage
+		if err := dec.Decode(&msg); err != nil {
+			break
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg
+		}
+		if f := msg.Finding; f != nil && len(f.Trace) > 0 {
+			v, ok := found[f.OSV]
+			if !ok {
+				v = &vuln{ID: f.OSV, Module: f.Trace[0].Module, Version: f.Trace[0].Version, FixedVersion: f.FixedVersion}
+				found[f.OSV] = v
+			}
+			if f.Trace[0].Function != "" {
+				v.Called = true
+			}
+		}
+	}
+
+	var list []*vuln
+	called := 0
+	for id, v := range found {
+		if s, ok := summaries[id]; ok {
+			v.Summary = s.OSV.Summary
+			v.Aliases = s.OSV.Aliases
+		}
+		if v.Called {
+			called++
+		}
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Called != list[j].Called {
+			return list[i].Called
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return map[string]interface{}{
+		"vulnerabilities": list,
+		"count":           len(list),
+		"called":          called,
+		"success":         true,
+	}
+}
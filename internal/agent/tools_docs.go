@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/rag"
+	"github.com/neves/zen-claw/internal/wiki"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// DOCS TOOLS (Confluence, Notion)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// docs_search and docs_fetch read the wikis of docs in the gateway config.
+// Fetched pages are stored in the project's codebase index (zen-claw index)
+// as documents, so later code_search and get_context calls find the spec
+// next to the code without fetching it again.
+
+// numericID matches a bare Confluence page ID
+var numericID = regexp.MustCompile(`^\d+$`)
+
+// docsSourceParam is the source parameter shared by the tools
+var docsSourceParam = map[string]interface{}{
+	"type":        "string",
+	"enum":        []string{"confluence", "notion"},
+	"description": "Wiki to use (default: told from the page URL; search: all configured)",
+}
+
+// NewDocsTools creates the docs tools for the configured wikis (source name
+// to client)
+func NewDocsTools(sources map[string]wiki.Source) []Tool {
+	return []Tool{NewDocsSearchTool(sources), NewDocsFetchTool("", sources)}
+}
+
+// docsSource picks the wiki for a page reference
+func docsSource(sources map[string]wiki.Source, name, ref string) (string, wiki.Source, error) {
+	if len(sources) == 0 {
+		return "", nil, fmt.Errorf("no wikis configured (docs.confluence or docs.notion in the gateway config)")
+	}
+	if name == "" {
+		switch {
+		case strings.Contains(ref, "notion.so") || strings.Contains(ref, "notion.site"):
+			name = "notion"
+		case strings.Contains(ref, "/pages/") || strings.Contains(ref, "pageId=") || numericID.MatchString(ref):
+			name = "confluence"
+		case len(sources) == 1:
+			for only := range sources {
+				name = only
+			}
+		default:
+			return "", nil, fmt.Errorf("can't tell the wiki of %q: pass source", ref)
+		}
+	}
+	source, ok := sources[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%s is not configured (docs.%s in the gateway config)", name, name)
+	}
+	return name, source, nil
+}
+
+// DocsSearchTool searches Confluence and Notion pages
+type DocsSearchTool struct {
+	BaseTool
+	sources map[string]wiki.Source
+}
+
+// NewDocsSearchTool creates a new wiki search tool
+func NewDocsSearchTool(sources map[string]wiki.Source) *DocsSearchTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Free text (Notion matches page titles), or CQL on Confluence",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum pages per wiki (default 10, max 50)",
+			},
+			"source": docsSourceParam,
+		},
+		"required": []string{"query"},
+	}
+	return &DocsSearchTool{
+		BaseTool: NewBaseTool("docs_search", "Search Confluence and Notion pages (specs, designs, ADRs). Returns page IDs, titles and URLs to read with docs_fetch.", params),
+		sources:  sources,
+	}
+}
+
+func (t *DocsSearchTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+	limit := 10
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), 50)
+	}
+	names := make([]string, 0, len(t.sources))
+	if name, _ := args["source"].(string); name != "" {
+		if _, ok := t.sources[name]; !ok {
+			return nil, fmt.Errorf("%s is not configured (docs.%s in the gateway config)", name, name)
+		}
+		names = append(names, name)
+	} else {
+		for name := range t.sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no wikis configured (docs.confluence or docs.notion in the gateway config)")
+	}
+
+	var results []map[string]interface{}
+	var errs []string
+	for _, name := range names {
+		pages, err := t.sources[name].Search(ctx, query, limit)
+		if err != nil {
+			if len(names) == 1 {
+				return nil, err
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		for _, p := range pages {
+			results = append(results, map[string]interface{}{
+				"source":  name,
+				"id":      p.ID,
+				"title":   p.Title,
+				"url":     p.URL,
+				"space":   p.Space,
+				"updated": p.Updated,
+			})
+		}
+	}
+	result := map[string]interface{}{
+		"pages":   results,
+		"count":   len(results),
+		"success": true,
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	return result, nil
+}
+
+// DocsFetchTool reads a Confluence or Notion page
+type DocsFetchTool struct {
+	BaseTool
+	workingDir string
+	sources    map[string]wiki.Source
+}
+
+// NewDocsFetchTool creates a new wiki page tool
+func NewDocsFetchTool(workingDir string, sources map[string]wiki.Source) *DocsFetchTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"page": map[string]interface{}{
+				"type":        "string",
+				"description": "Page URL, or its ID (Confluence: numeric; Notion: 32 hex digits)",
+			},
+			"source": docsSourceParam,
+			"index": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Store the page in the project's codebase index for code_search/get_context (default true)",
+			},
+		},
+		"required": []string{"page"},
+	}
+	return &DocsFetchTool{
+		BaseTool:   NewBaseTool("docs_fetch", "Read a Confluence or Notion page as markdown, by URL or ID, e.g. the spec or acceptance criteria of a task. The page is also added to the project's code index.", params),
+		workingDir: workingDir,
+		sources:    sources,
+	}
+}
+
+func (t *DocsFetchTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	ref, _ := args["page"].(string)
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("page parameter is required")
+	}
+	sourceName, _ := args["source"].(string)
+	name, source, err := docsSource(t.sources, sourceName, ref)
+	if err != nil {
+		return nil, err
+	}
+	page, err := source.Fetch(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	content := page.Body
+	if page.Format == "html" {
+		content = htmlToMarkdown(content)
+	}
+
+	result := map[string]interface{}{
+		"source":  name,
+		"id":      page.ID,
+		"title":   page.Title,
+		"url":     page.URL,
+		"updated": page.Updated,
+		"content": truncateWithRef(ctx, content, MaxToolOutputBytes),
+		"success": true,
+	}
+	if index, ok := args["index"].(bool); !ok || index {
+		path := name + ":" + page.ID
+		if err := t.indexPage(ctx, rag.Document{Path: path, Title: page.Title, URL: page.URL, Content: content}); err != nil {
+			result["index_error"] = err.Error()
+		} else {
+			result["indexed_as"] = path
+		}
+	}
+	return result, nil
+}
+
+// indexPage stores a page in the index of the session's project (the
+// index code_search uses)
+func (t *DocsFetchTool) indexPage(ctx context.Context, doc rag.Document) error {
+	root := BaseDir(ctx, t.workingDir)
+	projectName := filepath.Base(root)
+	if projectName == "" || projectName == "." {
+		cwd, _ := os.Getwd()
+		projectName = filepath.Base(cwd)
+	}
+	indexer, err := rag.NewIndexer(&rag.IndexerConfig{
+		DBPath:  rag.DefaultIndexDBPath(projectName),
+		RootDir: root,
+	})
+	if err != nil {
+		return fmt.Errorf("open index: %w", err)
+	}
+	defer indexer.Close()
+	return indexer.IndexDocument(doc)
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// MultiEditTool applies several string replacements to one file atomically
+type MultiEditTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewMultiEditTool creates a new multi edit tool
+func NewMultiEditTool(workingDir string) *MultiEditTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File path to edit",
+			},
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Replacements applied in order. Each old_string must be unique in the file (as modified by previous edits) unless replace_all is true.",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"old_string": map[string]interface{}{
+							"type":        "string",
+							"description": "The exact string to find and replace",
+						},
+						"new_string": map[string]interface{}{
+							"type":        "string",
+							"description": "The replacement string",
+						},
+						"replace_all": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Replace all occurrences (default: false)",
+						},
+					},
+					"required": []string{"old_string", "new_string"},
+				},
+			},
+			"skip_syntax_check": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write even if the result fails syntax validation (default: false)",
+			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Use each new_string exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
+		},
+		"required": []string{"path", "edits"},
+	}
+
+	return &MultiEditTool{
+		BaseTool: NewBaseTool(
+			"multi_edit",
+			"Apply several string replacements to one file in a single write. All edits are validated first; if any fails, the file is left untouched. Prefer this over repeated edit_file calls on the same file.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+// fileEdit is a single replacement in a multi_edit call
+type fileEdit struct {
+	oldString  string
+	newString  string
+	replaceAll bool
+}
+
+// parseFileEdits converts the raw edits argument into fileEdit values
+func parseFileEdits(raw interface{}) ([]fileEdit, error) {
+	items, ok := raw.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("edits parameter is required and must be a non-empty array")
+	}
+
+	edits := make([]fileEdit, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("edit %d: must be an object", i+1)
+		}
+		oldString, ok := obj["old_string"].(string)
+		if !ok || oldString == "" {
+			return nil, fmt.Errorf("edit %d: old_string is required", i+1)
+		}
+		newString, ok := obj["new_string"].(string)
+		if !ok {
+			return nil, fmt.Errorf("edit %d: new_string is required", i+1)
+		}
+		replaceAll, _ := obj["replace_all"].(bool)
+		edits = append(edits, fileEdit{oldString: oldString, newString: newString, replaceAll: replaceAll})
+	}
+	return edits, nil
+}
+
+// applyFileEdits applies edits in order to content, failing on the first edit that doesn't match
+func applyFileEdits(content string, edits []fileEdit) (string, int, error) {
+	total := 0
+	for i, edit := range edits {
+		count := strings.Count(content, edit.oldString)
+		if count == 0 {
+			return "", 0, fmt.Errorf("edit %d: old_string not found in file", i+1)
+		}
+		if !edit.replaceAll && count > 1 {
+			return "", 0, fmt.Errorf("edit %d: old_string found %d times in file, must be unique (or use replace_all: true)", i+1, count)
+		}
+		if edit.replaceAll {
+			content = strings.ReplaceAll(content, edit.oldString, edit.newString)
+			total += count
+		} else {
+			content = strings.Replace(content, edit.oldString, edit.newString, 1)
+			total++
+		}
+	}
+	return content, total, nil
+}
+
+func (t *MultiEditTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	path, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	edits, err := parseFileEdits(args["edits"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return map[string]interface{}{
+			"path":    path,
+			"error":   fmt.Sprintf("failed to read file: %v", err),
+			"success": false,
+		}, nil
+	}
+
+	if checkFileConflict(ctx, fullPath, content) {
+		return map[string]interface{}{
+			"path":       path,
+			"error":      "file changed on disk since it was last read; re-read it with read_file before editing",
+			"conflict":   true,
+			"error_code": types.ErrConflict,
+			"success":    false,
+		}, nil
+	}
+
+	// Edit the text; the file keeps its line endings, BOM and encoding
+	text, format, err := decodeText(content)
+	if err != nil {
+		return formatErrorResult(path, err), nil
+	}
+	for i := range edits {
+		edits[i].oldString = format.normalize(edits[i].oldString)
+		edits[i].newString = format.normalize(edits[i].newString)
+	}
+
+	// Validate and apply all edits in memory; nothing is written unless every edit succeeds
+	newContent, replacements, err := applyFileEdits(text, edits)
+	if err != nil {
+		return map[string]interface{}{
+			"path":    path,
+			"error":   err.Error(),
+			"success": false,
+			"hint":    "No changes were written. Fix the failing edit and retry the whole batch.",
+		}, nil
+	}
+
+	// Refuse to write code that doesn't parse unless explicitly overridden
+	if skip, _ := args["skip_syntax_check"].(bool); !skip {
+		if err := validateSyntax(fullPath, []byte(newContent)); err != nil {
+			return syntaxErrorResult(path, err), nil
+		}
+	}
+
+	data := format.encode(newContent)
+	if err := os.WriteFile(fullPath, data, newFileMode); err != nil {
+		// Best-effort rollback in case of a partial write
+		os.WriteFile(fullPath, content, newFileMode)
+		return map[string]interface{}{
+			"path":    path,
+			"error":   fmt.Sprintf("failed to write file: %v", err),
+			"success": false,
+		}, nil
+	}
+
+	recordFileRead(ctx, fullPath, data)
+
+	result := map[string]interface{}{
+		"path":         path,
+		"edits":        len(edits),
+		"replacements": replacements,
+		"format":       format.String(),
+		"success":      true,
+	}
+	applyPostWriteHooks(ctx, fullPath, result)
+
+	return result, nil
+}
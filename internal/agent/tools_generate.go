@@ -0,0 +1,409 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/jsonrepair"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// GENERATE-LIKE TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// generate_like writes a new file modeled on an existing one. The exemplar
+// becomes a template: its names are mapped to the new ones (user → order,
+// User → Order...), its top-level lines (package clause, declarations,
+// signatures, closing braces) are kept and its indented bodies become
+// numbered slots. The model fills only the slots, so the structure,
+// naming and boilerplate of the new file can't drift from the exemplar's.
+
+// generateMaxTokens bounds the model's answer
+const generateMaxTokens = 8000
+
+// GenerateLikeTool generates a file from an exemplar and a spec
+type GenerateLikeTool struct {
+	BaseTool
+	workingDir string
+}
+
+// templatePart is a run of template lines: fixed, or a slot to fill
+type templatePart struct {
+	slot  int    // 0 for fixed lines
+	lines string // The (renamed) exemplar lines, newline-terminated
+}
+
+// NewGenerateLikeTool creates a generate_like tool
+func NewGenerateLikeTool(workingDir string) *GenerateLikeTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"exemplar": map[string]interface{}{
+				"type":        "string",
+				"description": "Existing file to model the new one on, e.g. internal/api/user_handler.go",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File to create, e.g. internal/api/order_handler.go",
+			},
+			"spec": map[string]interface{}{
+				"type":        "string",
+				"description": "What the new file does differently: entity, fields, endpoints, behavior",
+			},
+			"names": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Names to map from the exemplar to the new file, e.g. {\"user\": \"order\"}; case variants (User, USER, userID) follow. Default: derived from the file and directory names.",
+			},
+			"preview": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the generated content without writing it",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace path if it exists (default: false)",
+			},
+		},
+		"required": []string{"exemplar", "path", "spec"},
+	}
+
+	return &GenerateLikeTool{
+		BaseTool: NewBaseTool(
+			"generate_like",
+			"Create a file modeled on an existing exemplar (a handler, test, migration...): the exemplar's names are mapped and its structure kept, and the model fills only the parts that vary, following the spec. Prefer it over write_file for files that should look like existing ones.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GenerateLikeTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	exemplar, _ := args["exemplar"].(string)
+	path, _ := args["path"].(string)
+	spec, _ := args["spec"].(string)
+	if exemplar == "" || path == "" || strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("exemplar, path and spec parameters are required")
+	}
+	caller, model := ModelFromContext(ctx)
+	if caller == nil {
+		return map[string]interface{}{"error": "generate_like needs a model: run it from an agent session", "success": false}, nil
+	}
+	exemplarPath := ResolvePath(ctx, t.workingDir, exemplar)
+	fullPath := ResolvePath(ctx, t.workingDir, path)
+	source, err := os.ReadFile(exemplarPath)
+	if err != nil {
+		return map[string]interface{}{"exemplar": exemplar, "error": err.Error(), "success": false}, nil
+	}
+	if overwrite, _ := args["overwrite"].(bool); !overwrite {
+		if _, err := os.Stat(fullPath); err == nil {
+			return map[string]interface{}{"path": path, "error": "path exists: pass overwrite: true to replace it", "success": false}, nil
+		}
+	}
+
+	names := map[string]string{}
+	if given, ok := args["names"].(map[string]interface{}); ok {
+		for old, repl := range given {
+			if s, ok := repl.(string); ok && old != "" {
+				names[old] = s
+			}
+		}
+	}
+	if len(names) == 0 {
+		names = deriveNames(exemplarPath, fullPath)
+	}
+	renamed := renameReplacer(names).Replace(string(source))
+	parts := extractTemplate(renamed)
+
+	prompt := generatePrompt(exemplar, path, spec, string(source), names, parts)
+	messages := []ai.Message{{Role: "user", Content: prompt}}
+	var content string
+	var unfilled []int
+	for attempt := 0; attempt < 2; attempt++ {
+		genCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		resp, err := caller.Chat(genCtx, ai.ChatRequest{
+			Model:       model,
+			Messages:    messages,
+			Temperature: 0.2,
+			MaxTokens:   generateMaxTokens,
+		})
+		cancel()
+		if err != nil {
+			return map[string]interface{}{"path": path, "error": fmt.Sprintf("model call failed: %v", err), "success": false}, nil
+		}
+		var fill struct {
+			Slots map[string]string `json:"slots"`
+			Extra string            `json:"extra"`
+		}
+		if _, err := jsonrepair.Unmarshal(resp.Content, &fill); err != nil {
+			return map[string]interface{}{"path": path, "error": fmt.Sprintf("the model's answer is not the expected JSON: %v", err), "success": false}, nil
+		}
+		content, unfilled = fillTemplate(parts, fill.Slots, fill.Extra)
+
+		err = validateSyntax(fullPath, []byte(content))
+		if err == nil {
+			break
+		}
+		if attempt == 1 {
+			result := syntaxErrorResult(path, err)
+			result["content"] = truncateWithRef(ctx, content, MaxToolOutputBytes)
+			result["hint"] = "Fix the content and write it with write_file"
+			return result, nil
+		}
+		messages = append(messages,
+			ai.Message{Role: "assistant", Content: resp.Content},
+			ai.Message{Role: "user", Content: fmt.Sprintf("The assembled file doesn't parse: %v\nAnswer again with the corrected slots, same JSON format.", err)})
+	}
+
+	slots := 0
+	for _, part := range parts {
+		if part.slot > 0 {
+			slots++
+		}
+	}
+	result := map[string]interface{}{
+		"path":     path,
+		"exemplar": exemplar,
+		"names":    names,
+		"slots":    slots,
+		"size":     len(content),
+	}
+	if len(unfilled) > 0 {
+		result["unfilled_slots"] = unfilled // Kept as in the exemplar: check them
+	}
+	if preview, _ := args["preview"].(bool); preview {
+		result["content"] = truncateWithRef(ctx, content, MaxToolOutputBytes)
+		result["not_written"] = "Preview only: call again without preview to write it"
+		result["success"] = true
+		return result, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return map[string]interface{}{"path": path, "error": fmt.Sprintf("failed to create directories: %v", err), "success": false}, nil
+	}
+	if err := os.WriteFile(fullPath, []byte(content), newFileMode); err != nil {
+		return map[string]interface{}{"path": path, "error": err.Error(), "success": false}, nil
+	}
+	recordFileRead(ctx, fullPath, []byte(content))
+	result["action"] = "created"
+	result["success"] = true
+	applyPostWriteHooks(ctx, fullPath, result)
+	return result, nil
+}
+
+// deriveNames maps the part of the exemplar's file name (and directory
+// name) that differs from the new file's: user_handler.go → order_handler.go
+// gives user → order
+func deriveNames(exemplar, target string) map[string]string {
+	names := map[string]string{}
+	stem := func(p string) string { return strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)) }
+	pairs := [][2]string{{stem(exemplar), stem(target)}, {filepath.Base(filepath.Dir(exemplar)), filepath.Base(filepath.Dir(target))}}
+	for _, pair := range pairs {
+		old, repl := differingPart(pair[0], pair[1])
+		if old != "" && repl != "" && old != repl {
+			names[old] = repl
+		}
+	}
+	return names
+}
+
+// differingPart strips the common prefix and suffix of a and b, at word
+// separators (_ - .), and returns what's left of each
+func differingPart(a, b string) (string, string) {
+	isSep := func(r byte) bool { return r == '_' || r == '-' || r == '.' }
+	prefix := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if isSep(a[i]) {
+			prefix = i + 1
+		}
+	}
+	suffix := 0
+	for i := 1; i <= len(a)-prefix && i <= len(b)-prefix && a[len(a)-i] == b[len(b)-i]; i++ {
+		if isSep(a[len(a)-i]) {
+			suffix = i
+		}
+	}
+	return a[prefix : len(a)-suffix], b[prefix : len(b)-suffix]
+}
+
+// renameReplacer replaces each name and its case variants (user, User,
+// USER; user_item, userItem, UserItem), longest names first
+func renameReplacer(names map[string]string) *strings.Replacer {
+	variants := map[string]string{}
+	for old, repl := range names {
+		variants[old] = repl
+		variants[upperFirst(old)] = upperFirst(repl)
+		variants[lowerFirst(old)] = lowerFirst(repl)
+		variants[strings.ToUpper(old)] = strings.ToUpper(repl)
+		variants[strings.ToLower(old)] = strings.ToLower(repl)
+		if camel, camelRepl := camelCase(old), camelCase(repl); camel != old {
+			variants[camel] = camelRepl
+			variants[upperFirst(camel)] = upperFirst(camelRepl)
+			variants[strings.ReplaceAll(strings.ToUpper(old), "-", "_")] = strings.ReplaceAll(strings.ToUpper(repl), "-", "_")
+		}
+	}
+	olds := make([]string, 0, len(variants))
+	for old := range variants {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+	var pairs []string
+	for _, old := range olds {
+		pairs = append(pairs, old, variants[old])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// camelCase joins snake_case or kebab-case words: user_item → userItem
+func camelCase(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' })
+	for i := 1; i < len(words); i++ {
+		words[i] = upperFirst(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// extractTemplate splits content into fixed top-level lines and slots for
+// the indented runs (blank lines inside a run belong to it). Content
+// without indented lines is a single slot.
+func extractTemplate(content string) []templatePart {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	indented := func(line string) bool {
+		return strings.TrimSpace(line) != "" && unicode.IsSpace(rune(line[0]))
+	}
+	body := make([]bool, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			body[i] = indented(line)
+			continue
+		}
+		// A blank line is in a body when the lines around it are
+		prev, next := false, false
+		for j := i - 1; j >= 0; j-- {
+			if strings.TrimSpace(lines[j]) != "" {
+				prev = indented(lines[j])
+				break
+			}
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) != "" {
+				next = indented(lines[j])
+				break
+			}
+		}
+		body[i] = prev && next
+	}
+
+	var parts []templatePart
+	slot := 0
+	for i, line := range lines {
+		if len(parts) == 0 || body[i] != body[i-1] {
+			part := templatePart{}
+			if body[i] {
+				slot++
+				part.slot = slot
+			}
+			parts = append(parts, part)
+		}
+		parts[len(parts)-1].lines += line
+	}
+	if slot == 0 {
+		return []templatePart{{slot: 1, lines: content}}
+	}
+	return parts
+}
+
+// fillTemplate assembles the file from the template and the model's slots;
+// slots it didn't answer keep the exemplar's lines and are returned
+func fillTemplate(parts []templatePart, slots map[string]string, extra string) (string, []int) {
+	var b strings.Builder
+	var unfilled []int
+	for _, part := range parts {
+		if part.slot == 0 {
+			b.WriteString(part.lines)
+			continue
+		}
+		fill, ok := slots[strconv.Itoa(part.slot)]
+		if !ok {
+			unfilled = append(unfilled, part.slot)
+			b.WriteString(part.lines)
+			continue
+		}
+		if fill != "" && !strings.HasSuffix(fill, "\n") {
+			fill += "\n"
+		}
+		b.WriteString(fill)
+	}
+	if strings.TrimSpace(extra) != "" {
+		b.WriteString("\n" + strings.TrimRight(extra, "\n") + "\n")
+	}
+	return b.String(), unfilled
+}
+
+// generatePrompt asks the model to fill the template's slots
+func generatePrompt(exemplar, path, spec, source string, names map[string]string, parts []templatePart) string {
+	var template strings.Builder
+	for _, part := range parts {
+		if part.slot == 0 {
+			template.WriteString(part.lines)
+			continue
+		}
+		fmt.Fprintf(&template, "⟦SLOT %d⟧\n%s⟦END %d⟧\n", part.slot, part.lines, part.slot)
+	}
+	var mapped []string
+	for old, repl := range names {
+		mapped = append(mapped, old+" → "+repl)
+	}
+	sort.Strings(mapped)
+	mapping := "none"
+	if len(mapped) > 0 {
+		mapping = strings.Join(mapped, ", ")
+	}
+
+	return fmt.Sprintf(`Create %s, modeled on the existing file %s.
+
+Spec of the new file:
+%s
+
+The exemplar, %s:
+~~~~
+%s~~~~
+
+Template of the new file. The names are already mapped (%s). The lines outside the slots are fixed. Each slot ⟦SLOT n⟧ ... ⟦END n⟧ holds the exemplar's lines for that place; replace them with what the new file needs there, following the exemplar's style, error handling and conventions, adapted to the spec. Keep the indentation. Change only what the spec requires.
+~~~~
+%s~~~~
+
+Answer with JSON only:
+{"slots": {"1": "lines of slot 1", "2": "..."}, "extra": ""}
+Give every slot, "" to drop its lines. "extra" is appended at the end of the file, for top-level code the template has no place for (usually "").
+`, path, exemplar, spec, exemplar, source, mapping, template.String())
+}
@@ -48,9 +48,7 @@ func (t *GitStatusTool) Execute(ctx context.Context, args map[string]interface{}
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -85,9 +83,7 @@ func (t *GitStatusTool) Execute(ctx context.Context, args map[string]interface{}
 
 	// Get branch info
 	branchCmd := exec.CommandContext(ctx, "git", "branch", "--show-current")
-	if t.workingDir != "" {
-		branchCmd.Dir = t.workingDir
-	}
+	branchCmd.Dir = BaseDir(ctx, t.workingDir)
 	branchOut, _ := branchCmd.Output()
 	branch := strings.TrimSpace(string(branchOut))
 
@@ -167,9 +163,7 @@ func (t *GitDiffTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -267,9 +261,7 @@ func (t *GitCommitTool) Execute(ctx context.Context, args map[string]interface{}
 			}
 		}
 		addCmd := exec.CommandContext(ctx, "git", addArgs...)
-		if t.workingDir != "" {
-			addCmd.Dir = t.workingDir
-		}
+		addCmd.Dir = BaseDir(ctx, t.workingDir)
 		if out, err := addCmd.CombinedOutput(); err != nil {
 			return map[string]interface{}{
 				"error":   fmt.Sprintf("git add failed: %v", err),
@@ -287,9 +279,7 @@ func (t *GitCommitTool) Execute(ctx context.Context, args map[string]interface{}
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -302,9 +292,7 @@ func (t *GitCommitTool) Execute(ctx context.Context, args map[string]interface{}
 
 	// Get commit hash
 	hashCmd := exec.CommandContext(ctx, "git", "rev-parse", "--short", "HEAD")
-	if t.workingDir != "" {
-		hashCmd.Dir = t.workingDir
-	}
+	hashCmd.Dir = BaseDir(ctx, t.workingDir)
 	hashOut, _ := hashCmd.Output()
 	hash := strings.TrimSpace(string(hashOut))
 
@@ -371,9 +359,7 @@ func (t *GitPushTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -451,9 +437,7 @@ func (t *GitLogTool) Execute(ctx context.Context, args map[string]interface{}) (
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -551,9 +535,7 @@ func (t *GitAddTool) Execute(ctx context.Context, args map[string]interface{}) (
 	}
 
 	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
-	if t.workingDir != "" {
-		cmd.Dir = t.workingDir
-	}
+	cmd.Dir = BaseDir(ctx, t.workingDir)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
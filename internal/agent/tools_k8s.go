@@ -0,0 +1,255 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// KUBERNETES TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// The k8s tool reads cluster state through kubectl: objects, descriptions,
+// pod logs, events and resource usage. It builds the kubectl command itself
+// from a fixed set of read verbs, so it can't change the cluster; the
+// session environment (e.g. KUBECONFIG) is passed to kubectl.
+
+// k8sTimeout bounds one kubectl run
+const k8sTimeout = 2 * time.Minute
+
+// k8sMaxTailLines caps the log lines one call returns
+const k8sMaxTailLines = 2000
+
+// k8sName matches resource kinds, names, namespaces, containers and contexts
+var k8sName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/:-]*$`)
+
+// k8sSelector matches label and field selectors (no shell or flag syntax)
+var k8sSelector = regexp.MustCompile(`^[A-Za-z0-9._/=!,()\- ]+$`)
+
+// K8sTool reads Kubernetes cluster state with kubectl
+type K8sTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewK8sTool creates a read-only Kubernetes tool
+func NewK8sTool(workingDir string) *K8sTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "describe", "logs", "events", "top"},
+				"description": "get (list objects), describe (details and recent events of objects), logs (pod/container logs), events (namespace events, newest last), top (pod CPU/memory)",
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "Resource kind for get/describe, e.g. pods, deployments, nodes, svc (default pods)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Object name (pod name for logs; deploy/<name> also works for logs)",
+			},
+			"namespace": map[string]interface{}{
+				"type":        "string",
+				"description": "Namespace (default: the context's); \"all\" for every namespace (get, events, top)",
+			},
+			"selector": map[string]interface{}{
+				"type":        "string",
+				"description": "Label selector, e.g. app=api,tier!=cache (get, describe, logs, top)",
+			},
+			"field_selector": map[string]interface{}{
+				"type":        "string",
+				"description": "Field selector, e.g. status.phase!=Running or type=Warning for events",
+			},
+			"container": map[string]interface{}{
+				"type":        "string",
+				"description": "Container for logs (default: the pod's only/default container)",
+			},
+			"previous": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Logs of the previous, crashed container instance",
+			},
+			"tail": map[string]interface{}{
+				"type":        "integer",
+				"description": "Log lines from the end (default 200, max 2000)",
+			},
+			"since": map[string]interface{}{
+				"type":        "string",
+				"description": "Only logs newer than this, e.g. 15m, 2h",
+			},
+			"output": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"wide", "yaml", "json"},
+				"description": "Output format for get (default: table)",
+			},
+			"context": map[string]interface{}{
+				"type":        "string",
+				"description": "kubeconfig context (default: the current context)",
+			},
+		},
+		"required": []string{"action"},
+	}
+
+	return &K8sTool{
+		BaseTool: NewBaseTool(
+			"k8s",
+			"Read Kubernetes cluster state with kubectl (read-only): get objects, describe them, pod logs (including the previous crashed container), namespace events and pod resource usage. Cannot modify the cluster.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *K8sTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	kubectlArgs, err := k8sArgs(args)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return map[string]interface{}{"error": "kubectl is not installed on the gateway host", "success": false}, nil
+	}
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, k8sTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, "kubectl", kubectlArgs...)
+	cmd.Dir = BaseDir(ctx, t.workingDir)
+	env.apply(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	runErr := cmd.Run()
+
+	result := map[string]interface{}{
+		"command": "kubectl " + strings.Join(kubectlArgs, " "),
+		"output":  truncateWithRef(ctx, env.redact(stdout.String()), MaxToolOutputBytes),
+		"success": runErr == nil,
+	}
+	if runErr != nil {
+		msg := strings.TrimSpace(env.redact(stderr.String()))
+		if msg == "" {
+			msg = runErr.Error()
+		}
+		result["error"] = msg
+	}
+	return result, nil
+}
+
+// k8sArgs builds the kubectl arguments for a call, refusing values that
+// could smuggle in flags or other verbs
+func k8sArgs(args map[string]interface{}) ([]string, error) {
+	str := func(key string) (string, error) {
+		v, _ := args[key].(string)
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return "", nil
+		}
+		re := k8sName
+		if key == "selector" || key == "field_selector" {
+			re = k8sSelector
+		}
+		if !re.MatchString(v) {
+			return "", fmt.Errorf("invalid %s %q", key, v)
+		}
+		return v, nil
+	}
+	values := make(map[string]string)
+	for _, key := range []string{"action", "kind", "name", "namespace", "selector", "field_selector", "container", "since", "output", "context"} {
+		v, err := str(key)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = v
+	}
+
+	action := values["action"]
+	var out []string
+	switch action {
+	case "get", "describe":
+		kind := values["kind"]
+		if kind == "" {
+			kind = "pods"
+		}
+		out = []string{action, kind}
+		if values["name"] != "" {
+			out = append(out, values["name"])
+		}
+		if action == "get" {
+			switch output := values["output"]; output {
+			case "", "wide":
+				if output != "" {
+					out = append(out, "-o", output)
+				}
+			case "yaml", "json":
+				// Secret values would end up in the transcript and at the provider
+				if strings.HasPrefix(strings.ToLower(kind), "secret") {
+					return nil, fmt.Errorf("secret contents are not shown; use describe for their keys and sizes")
+				}
+				out = append(out, "-o", output)
+			default:
+				return nil, fmt.Errorf("invalid output %q (use wide, yaml or json)", output)
+			}
+		}
+	case "logs":
+		if values["name"] == "" && values["selector"] == "" {
+			return nil, fmt.Errorf("logs needs a pod name or a selector")
+		}
+		out = []string{"logs"}
+		if values["name"] != "" {
+			out = append(out, values["name"])
+		}
+		if values["container"] != "" {
+			out = append(out, "-c", values["container"])
+		} else if values["selector"] != "" {
+			out = append(out, "--all-containers", "--prefix")
+		}
+		if previous, _ := args["previous"].(bool); previous {
+			out = append(out, "--previous")
+		}
+		tail := 200
+		if n, ok := args["tail"].(float64); ok && n > 0 {
+			tail = min(int(n), k8sMaxTailLines)
+		}
+		out = append(out, "--tail", strconv.Itoa(tail))
+		if values["since"] != "" {
+			out = append(out, "--since", values["since"])
+		}
+	case "events":
+		out = []string{"get", "events", "--sort-by", ".lastTimestamp"}
+	case "top":
+		out = []string{"top", "pods"}
+		if values["name"] != "" {
+			out = append(out, values["name"])
+		}
+	case "":
+		return nil, fmt.Errorf("action is required")
+	default:
+		return nil, fmt.Errorf("unknown action %q (use get, describe, logs, events or top)", action)
+	}
+
+	switch ns := values["namespace"]; {
+	case ns == "all" && action != "logs" && action != "describe":
+		out = append(out, "--all-namespaces")
+	case ns != "" && ns != "all":
+		out = append(out, "-n", ns)
+	}
+	if values["selector"] != "" && action != "events" {
+		out = append(out, "-l", values["selector"])
+	}
+	if values["field_selector"] != "" && (action == "get" || action == "events") {
+		out = append(out, "--field-selector", values["field_selector"])
+	}
+	if values["context"] != "" {
+		out = append(out, "--context", values["context"])
+	}
+	return out, nil
+}
@@ -97,28 +97,25 @@ func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]interface{
 
 	for _, op := range ops {
 		// Resolve path
-		fullPath := op.Path
-		if t.workingDir != "" && !filepath.IsAbs(op.Path) {
-			fullPath = filepath.Join(t.workingDir, op.Path)
-		}
+		fullPath := ResolvePath(ctx, t.workingDir, op.Path)
 
 		switch op.Type {
 		case "add":
-			result := t.applyAdd(fullPath, op)
+			result := t.applyAdd(ctx, fullPath, op)
 			results = append(results, result)
 			if !result["success"].(bool) {
 				errors = append(errors, fmt.Sprintf("%s: %v", op.Path, result["error"]))
 			}
 
 		case "update":
-			result := t.applyUpdate(fullPath, op)
+			result := t.applyUpdate(ctx, fullPath, op)
 			results = append(results, result)
 			if !result["success"].(bool) {
 				errors = append(errors, fmt.Sprintf("%s: %v", op.Path, result["error"]))
 			}
 
 		case "delete":
-			result := t.applyDelete(fullPath, op)
+			result := t.applyDelete(ctx, fullPath, op)
 			results = append(results, result)
 			if !result["success"].(bool) {
 				errors = append(errors, fmt.Sprintf("%s: %v", op.Path, result["error"]))
@@ -140,7 +137,7 @@ func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]interface{
 	return response, nil
 }
 
-func (t *ApplyPatchTool) applyAdd(fullPath string, op PatchOperation) map[string]interface{} {
+func (t *ApplyPatchTool) applyAdd(ctx context.Context, fullPath string, op PatchOperation) map[string]interface{} {
 	// Create parent directories
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -153,7 +150,7 @@ func (t *ApplyPatchTool) applyAdd(fullPath string, op PatchOperation) map[string
 	}
 
 	// Write file
-	if err := os.WriteFile(fullPath, []byte(op.Content), 0644); err != nil {
+	if err := os.WriteFile(fullPath, []byte(op.Content), newFileMode); err != nil {
 		return map[string]interface{}{
 			"path":    op.Path,
 			"action":  "add",
@@ -161,6 +158,7 @@ func (t *ApplyPatchTool) applyAdd(fullPath string, op PatchOperation) map[string
 			"success": false,
 		}
 	}
+	recordFileRead(ctx, fullPath, []byte(op.Content))
 
 	return map[string]interface{}{
 		"path":    op.Path,
@@ -169,7 +167,7 @@ func (t *ApplyPatchTool) applyAdd(fullPath string, op PatchOperation) map[string
 	}
 }
 
-func (t *ApplyPatchTool) applyUpdate(fullPath string, op PatchOperation) map[string]interface{} {
+func (t *ApplyPatchTool) applyUpdate(ctx context.Context, fullPath string, op PatchOperation) map[string]interface{} {
 	// Read existing file
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -207,14 +205,10 @@ func (t *ApplyPatchTool) applyUpdate(fullPath string, op PatchOperation) map[str
 	// Handle rename
 	targetPath := fullPath
 	if op.NewPath != "" {
-		newFullPath := op.NewPath
-		if t.workingDir != "" && !filepath.IsAbs(op.NewPath) {
-			newFullPath = filepath.Join(t.workingDir, op.NewPath)
-		}
-		targetPath = newFullPath
+		targetPath = ResolvePath(ctx, t.workingDir, op.NewPath)
 
 		// Create new directory if needed
-		if err := os.MkdirAll(filepath.Dir(newFullPath), 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 			return map[string]interface{}{
 				"path":    op.Path,
 				"action":  "update",
@@ -227,8 +221,12 @@ func (t *ApplyPatchTool) applyUpdate(fullPath string, op PatchOperation) map[str
 		defer os.Remove(fullPath)
 	}
 
-	// Write file
-	if err := os.WriteFile(targetPath, []byte(contentStr), 0644); err != nil {
+	// Write file (a moved file keeps its mode)
+	err = os.WriteFile(targetPath, []byte(contentStr), newFileMode)
+	if info, statErr := os.Stat(fullPath); err == nil && statErr == nil && targetPath != fullPath {
+		err = os.Chmod(targetPath, info.Mode().Perm())
+	}
+	if err != nil {
 		return map[string]interface{}{
 			"path":    op.Path,
 			"action":  "update",
@@ -236,6 +234,10 @@ func (t *ApplyPatchTool) applyUpdate(fullPath string, op PatchOperation) map[str
 			"success": false,
 		}
 	}
+	recordFileRead(ctx, targetPath, []byte(contentStr))
+	if targetPath != fullPath {
+		forgetFile(ctx, fullPath)
+	}
 
 	result := map[string]interface{}{
 		"path":          op.Path,
@@ -251,7 +253,7 @@ func (t *ApplyPatchTool) applyUpdate(fullPath string, op PatchOperation) map[str
 	return result
 }
 
-func (t *ApplyPatchTool) applyDelete(fullPath string, op PatchOperation) map[string]interface{} {
+func (t *ApplyPatchTool) applyDelete(ctx context.Context, fullPath string, op PatchOperation) map[string]interface{} {
 	if err := os.Remove(fullPath); err != nil {
 		if os.IsNotExist(err) {
 			return map[string]interface{}{
@@ -268,6 +270,7 @@ func (t *ApplyPatchTool) applyDelete(fullPath string, op PatchOperation) map[str
 			"success": false,
 		}
 	}
+	forgetFile(ctx, fullPath)
 
 	return map[string]interface{}{
 		"path":    op.Path,
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -31,6 +30,10 @@ func NewPreviewWriteTool(workingDir string) *PreviewWriteTool {
 				"type":        "string",
 				"description": "Content that would be written",
 			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Preview content exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -56,25 +59,17 @@ func (t *PreviewWriteTool) Execute(ctx context.Context, args map[string]interfac
 		return nil, fmt.Errorf("content parameter is required")
 	}
 
-	// Resolve path
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~") {
-		fullPath = filepath.Join(t.workingDir, path)
-	}
-
-	if strings.HasPrefix(fullPath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			fullPath = filepath.Join(home, fullPath[1:])
-		}
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
 
 	// Read existing content
 	existingContent := ""
 	existed := false
 	if data, err := os.ReadFile(fullPath); err == nil {
-		existingContent = string(data)
 		existed = true
+		if existingContent, _, err = decodeText(data); err != nil {
+			existingContent = string(data)
+		}
 	}
 
 	// Generate diff
@@ -128,6 +123,10 @@ func NewPreviewEditTool(workingDir string) *PreviewEditTool {
 				"type":        "boolean",
 				"description": "Preview replacing all occurrences",
 			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Use new_string exactly as given, without fence stripping or whitespace cleanup (default: false)",
+			},
 		},
 		"required": []string{"path", "old_string", "new_string"},
 	}
@@ -163,18 +162,8 @@ func (t *PreviewEditTool) Execute(ctx context.Context, args map[string]interface
 		replaceAll = ra
 	}
 
-	// Resolve path
-	fullPath := path
-	if t.workingDir != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~") {
-		fullPath = filepath.Join(t.workingDir, path)
-	}
-
-	if strings.HasPrefix(fullPath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			fullPath = filepath.Join(home, fullPath[1:])
-		}
-	}
+	// Resolve path relative to the session/tool working directory
+	fullPath := ResolvePath(ctx, t.workingDir, path)
 
 	// Read file
 	content, err := os.ReadFile(fullPath)
@@ -186,7 +175,16 @@ func (t *PreviewEditTool) Execute(ctx context.Context, args map[string]interface
 		}, nil
 	}
 
-	contentStr := string(content)
+	// Compare text as edit_file does, whatever the file's line endings and encoding
+	contentStr, format, err := decodeText(content)
+	if err != nil {
+		return map[string]interface{}{
+			"path":    path,
+			"error":   err.Error(),
+			"preview": true,
+		}, nil
+	}
+	oldString, newString = format.normalize(oldString), format.normalize(newString)
 
 	// Check occurrences
 	count := strings.Count(contentStr, oldString)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -45,8 +46,9 @@ func GetProcessManager() *ProcessManager {
 	return globalProcessManager
 }
 
-// Start starts a background process
-func (pm *ProcessManager) Start(ctx context.Context, command, workingDir string) (*BackgroundProcess, error) {
+// Start starts a background process. env is appended to the gateway environment and
+// redact (optional) scrubs each captured output line, e.g. of injected secret values.
+func (pm *ProcessManager) Start(ctx context.Context, command, workingDir string, env []string, redact func(string) string) (*BackgroundProcess, error) {
 	pm.mu.Lock()
 	pm.nextID++
 	id := fmt.Sprintf("proc-%d", pm.nextID)
@@ -56,6 +58,12 @@ func (pm *ProcessManager) Start(ctx context.Context, command, workingDir string)
 	if workingDir != "" {
 		cmd.Dir = workingDir
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if redact == nil {
+		redact = func(line string) string { return line }
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -81,12 +89,13 @@ func (pm *ProcessManager) Start(ctx context.Context, command, workingDir string)
 
 	// Capture output in background
 	go func() {
+		defer recoverGoroutine("process output")
 		multi := io.MultiReader(stdout, stderr)
 		scanner := bufio.NewScanner(multi)
 		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 		for scanner.Scan() {
 			proc.mu.Lock()
-			proc.Output.WriteString(scanner.Text() + "\n")
+			proc.Output.WriteString(redact(scanner.Text()) + "\n")
 			proc.mu.Unlock()
 		}
 	}()
@@ -224,7 +233,15 @@ func (t *ProcessTool) Execute(ctx context.Context, args map[string]interface{})
 			}, nil
 		}
 
-		proc, err := pm.Start(ctx, command, t.workingDir)
+		env, err := resolveSessionEnv(ctx)
+		if err != nil {
+			return map[string]interface{}{
+				"error":   err.Error(),
+				"success": false,
+			}, nil
+		}
+
+		proc, err := pm.Start(ctx, limitCommand(command, effectiveLimits(ctx)), BaseDir(ctx, t.workingDir), env.vars, env.redact)
 		if err != nil {
 			return map[string]interface{}{
 				"error":   fmt.Sprintf("failed to start: %v", err),
@@ -232,6 +249,11 @@ func (t *ProcessTool) Execute(ctx context.Context, args map[string]interface{})
 			}, nil
 		}
 
+		// Report the command as given, not the limit-wrapped script
+		proc.mu.Lock()
+		proc.Command = command
+		proc.mu.Unlock()
+
 		return map[string]interface{}{
 			"id":         proc.ID,
 			"command":    proc.Command,
@@ -56,7 +56,8 @@ func (t *CodeSearchTool) Execute(ctx context.Context, args map[string]interface{
 	}
 
 	// Determine project name from working directory
-	projectName := filepath.Base(t.workingDir)
+	root := BaseDir(ctx, t.workingDir)
+	projectName := filepath.Base(root)
 	if projectName == "" || projectName == "." {
 		cwd, _ := os.Getwd()
 		projectName = filepath.Base(cwd)
@@ -68,13 +69,13 @@ func (t *CodeSearchTool) Execute(ctx context.Context, args map[string]interface{
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		return map[string]interface{}{
 			"error":   "No index found",
-			"message": fmt.Sprintf("Run 'zen-claw index build %s' to create an index", t.workingDir),
+			"message": fmt.Sprintf("Run 'zen-claw index build %s' to create an index", root),
 		}, nil
 	}
 
 	indexer, err := rag.NewIndexer(&rag.IndexerConfig{
 		DBPath:  dbPath,
-		RootDir: t.workingDir,
+		RootDir: root,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("open index: %w", err)
@@ -147,7 +148,8 @@ func (t *FindSymbolTool) Execute(ctx context.Context, args map[string]interface{
 	}
 
 	// Search for the symbol
-	projectName := filepath.Base(t.workingDir)
+	root := BaseDir(ctx, t.workingDir)
+	projectName := filepath.Base(root)
 	if projectName == "" || projectName == "." {
 		cwd, _ := os.Getwd()
 		projectName = filepath.Base(cwd)
@@ -158,13 +160,13 @@ func (t *FindSymbolTool) Execute(ctx context.Context, args map[string]interface{
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		return map[string]interface{}{
 			"error":   "No index found",
-			"message": fmt.Sprintf("Run 'zen-claw index build %s' to create an index", t.workingDir),
+			"message": fmt.Sprintf("Run 'zen-claw index build %s' to create an index", root),
 		}, nil
 	}
 
 	indexer, err := rag.NewIndexer(&rag.IndexerConfig{
 		DBPath:  dbPath,
-		RootDir: t.workingDir,
+		RootDir: root,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("open index: %w", err)
@@ -241,7 +243,8 @@ func (t *ContextTool) Execute(ctx context.Context, args map[string]interface{})
 		return nil, fmt.Errorf("query is required")
 	}
 
-	projectName := filepath.Base(t.workingDir)
+	root := BaseDir(ctx, t.workingDir)
+	projectName := filepath.Base(root)
 	if projectName == "" || projectName == "." {
 		cwd, _ := os.Getwd()
 		projectName = filepath.Base(cwd)
@@ -258,7 +261,7 @@ func (t *ContextTool) Execute(ctx context.Context, args map[string]interface{})
 
 	indexer, err := rag.NewIndexer(&rag.IndexerConfig{
 		DBPath:  dbPath,
-		RootDir: t.workingDir,
+		RootDir: root,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("open index: %w", err)
@@ -283,10 +286,20 @@ func (t *ContextTool) Execute(ctx context.Context, args map[string]interface{})
 	var filePaths []string
 
 	for _, r := range results {
-		filePath := filepath.Join(t.workingDir, r.Path)
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			continue
+		var content []byte
+		if r.Language == "doc" {
+			// Confluence/Notion page stored by docs_fetch
+			doc, err := indexer.Document(r.Path)
+			if err != nil || doc == nil {
+				continue
+			}
+			content = []byte(fmt.Sprintf("%s (%s)\n\n%s", doc.Title, doc.URL, doc.Content))
+		} else {
+			filePath := filepath.Join(root, r.Path)
+			var err error
+			if content, err = os.ReadFile(filePath); err != nil {
+				continue
+			}
 		}
 
 		// Truncate large files
@@ -0,0 +1,963 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// GO REFACTORING TOOLS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// goBuildTimeout bounds compile verification after a refactor
+const goBuildTimeout = 5 * time.Minute
+
+// majorVersionElem matches import path elements like "v2" whose package name differs
+var majorVersionElem = regexp.MustCompile(`^v[0-9]+$`)
+
+// goModule describes the module containing a directory
+type goModule struct {
+	root string // Directory containing go.mod
+	path string // Module path from the module directive
+}
+
+// findGoModule walks up from dir to the nearest go.mod
+func findGoModule(dir string) (*goModule, error) {
+	current := dir
+	for {
+		data, err := os.ReadFile(filepath.Join(current, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if strings.HasPrefix(line, "module ") {
+					modPath := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+					return &goModule{root: current, path: modPath}, nil
+				}
+			}
+			return nil, fmt.Errorf("no module directive in %s", filepath.Join(current, "go.mod"))
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, fmt.Errorf("no go.mod found above %s", dir)
+		}
+		current = parent
+	}
+}
+
+// importPath returns the import path of a package directory inside the module
+func (m *goModule) importPath(dir string) string {
+	rel, err := filepath.Rel(m.root, dir)
+	if err != nil || rel == "." {
+		return m.path
+	}
+	return m.path + "/" + filepath.ToSlash(rel)
+}
+
+// packageNameInDir returns the package name declared by non-test files in dir
+func packageNameInDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil {
+			return file.Name.Name
+		}
+	}
+	return ""
+}
+
+// usedSelectorNames returns identifiers used as the X of a selector expression (package qualifiers)
+func usedSelectorNames(node ast.Node) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// addImport inserts an import into Go source (textually) and reformats it
+func addImport(src []byte, importPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range file.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p == importPath {
+			return src, nil
+		}
+	}
+
+	quoted := strconv.Quote(importPath)
+	var out []byte
+	var importDecl *ast.GenDecl
+	for _, decl := range file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			importDecl = gd
+			break
+		}
+	}
+
+	switch {
+	case importDecl == nil:
+		// No imports: add a declaration after the package clause
+		offset := fset.Position(file.Name.End()).Offset
+		out = append(out, src[:offset]...)
+		out = append(out, []byte("\n\nimport "+quoted+"\n")...)
+		out = append(out, src[offset:]...)
+	case importDecl.Lparen.IsValid():
+		offset := fset.Position(importDecl.Rparen).Offset
+		out = append(out, src[:offset]...)
+		out = append(out, []byte("\t"+quoted+"\n")...)
+		out = append(out, src[offset:]...)
+	default:
+		// Single import without parens: turn it into a group
+		start := fset.Position(importDecl.Pos()).Offset
+		end := fset.Position(importDecl.End()).Offset
+		spec := string(src[fset.Position(importDecl.Specs[0].Pos()).Offset:end])
+		out = append(out, src[:start]...)
+		out = append(out, []byte("import (\n\t"+spec+"\n\t"+quoted+"\n)")...)
+		out = append(out, src[end:]...)
+	}
+	return format.Source(out)
+}
+
+// removeUnusedImports drops imports whose local name is never used as a qualifier, then reformats
+func removeUnusedImports(src []byte) ([]byte, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	used := usedSelectorNames(file)
+
+	type span struct{ start, end int }
+	var spans []span
+	var removed []string
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		unusedInDecl := 0
+		var declSpans []span
+		for _, s := range gd.Specs {
+			imp := s.(*ast.ImportSpec)
+			p, _ := strconv.Unquote(imp.Path.Value)
+			name := filepath.Base(p)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			// Blank, dot, and cgo imports are always kept; versioned paths (foo/v2) may not match the base name
+			if name == "_" || name == "." || p == "C" || majorVersionElem.MatchString(name) || used[name] {
+				continue
+			}
+			// Package names may differ from the last path element (e.g. go-yaml -> yaml); be conservative
+			if imp.Name == nil && strings.ContainsAny(name, "-.") {
+				continue
+			}
+			unusedInDecl++
+			removed = append(removed, p)
+			declSpans = append(declSpans, span{fset.Position(imp.Pos()).Offset, fset.Position(imp.End()).Offset})
+		}
+		if unusedInDecl > 0 && unusedInDecl == len(gd.Specs) {
+			spans = append(spans, span{fset.Position(gd.Pos()).Offset, fset.Position(gd.End()).Offset})
+		} else {
+			spans = append(spans, declSpans...)
+		}
+	}
+	if len(spans) == 0 {
+		out, err := format.Source(src)
+		return out, nil, err
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	out := append([]byte(nil), src...)
+	for _, s := range spans {
+		out = append(out[:s.start], out[s.end:]...)
+	}
+	formatted, err := format.Source(out)
+	return formatted, removed, err
+}
+
+// goChangeSet accumulates file rewrites so they can be previewed, applied, and rolled back together
+type goChangeSet struct {
+	root     string
+	original map[string][]byte // nil value = file did not exist
+	updated  map[string][]byte
+}
+
+func newGoChangeSet(root string) *goChangeSet {
+	return &goChangeSet{
+		root:     root,
+		original: make(map[string][]byte),
+		updated:  make(map[string][]byte),
+	}
+}
+
+// set records new content for a file
+func (c *goChangeSet) set(path string, original, updated []byte) {
+	if _, ok := c.original[path]; !ok {
+		c.original[path] = original
+	}
+	c.updated[path] = updated
+}
+
+// paths returns the absolute paths of the changed files
+func (c *goChangeSet) paths() []string {
+	var paths []string
+	for path, data := range c.updated {
+		if !bytes.Equal(c.original[path], data) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// files returns the changed files relative to the module root
+func (c *goChangeSet) files() []string {
+	var files []string
+	for _, path := range c.paths() {
+		rel, _ := filepath.Rel(c.root, path)
+		files = append(files, rel)
+	}
+	return files
+}
+
+// diff returns a unified diff of all changes
+func (c *goChangeSet) diff() string {
+	var sb strings.Builder
+	for _, rel := range c.files() {
+		path := filepath.Join(c.root, rel)
+		sb.WriteString(generateUnifiedDiff(rel, string(c.original[path]), string(c.updated[path])))
+		sb.WriteString("\n")
+	}
+	return truncateOutput(sb.String(), MaxToolOutputBytes)
+}
+
+// apply writes all changes to disk
+func (c *goChangeSet) apply(ctx context.Context) error {
+	for path, data := range c.updated {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		recordFileRead(ctx, path, data)
+	}
+	return nil
+}
+
+// rollback restores the original contents (removing files that were created)
+func (c *goChangeSet) rollback(ctx context.Context) {
+	for path, data := range c.original {
+		if data == nil {
+			os.Remove(path)
+			forgetFile(ctx, path)
+			continue
+		}
+		if os.WriteFile(path, data, 0644) == nil {
+			recordFileRead(ctx, path, data)
+		}
+	}
+}
+
+// finish previews or applies the change set, verifying compilation and rolling back on failure
+func (c *goChangeSet) finish(ctx context.Context, apply bool, result map[string]interface{}) map[string]interface{} {
+	files := c.files()
+	result["files"] = files
+	result["diff"] = c.diff()
+	if len(files) == 0 {
+		result["success"] = false
+		result["error"] = "no changes produced"
+		return result
+	}
+	if !apply {
+		result["preview"] = true
+		result["success"] = true
+		result["hint"] = "Preview only. Call again with apply: true to write changes (compilation is verified)."
+		return result
+	}
+
+	if err := c.apply(ctx); err != nil {
+		c.rollback(ctx)
+		result["success"] = false
+		result["error"] = fmt.Sprintf("failed to write changes: %v", err)
+		return result
+	}
+
+	buildCtx, cancel := context.WithTimeout(ctx, goBuildTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(buildCtx, "go", "build", "./...")
+	cmd.Dir = c.root
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		c.rollback(ctx)
+		result["success"] = false
+		result["error"] = "compilation failed after refactor; all changes were rolled back"
+		result["build_output"] = truncateOutput(string(output), 8000)
+		return result
+	}
+
+	result["success"] = true
+	result["applied"] = true
+	result["compiled"] = true
+	return result
+}
+
+// goPackagesMode loads the syntax and type information the refactoring tools resolve names with
+const goPackagesMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax
+
+// loadGoPackages type-checks every package of the module, test variants included
+func loadGoPackages(ctx context.Context, mod *goModule) ([]*packages.Package, *token.FileSet, error) {
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    goPackagesMode,
+		Dir:     mod.root,
+		Fset:    fset,
+		Tests:   true,
+	}, "./...")
+	if err != nil {
+		return nil, nil, fmt.Errorf("load packages: %w", err)
+	}
+	return pkgs, fset, nil
+}
+
+// lookupGoObject finds a package-level object declared in dir. A package
+// with type errors is refused: its references can't all be resolved.
+func lookupGoObject(pkgs []*packages.Package, fset *token.FileSet, dir, name string) (*packages.Package, types.Object, error) {
+	for _, p := range pkgs {
+		if p.Types == nil || len(p.GoFiles) == 0 || filepath.Dir(p.GoFiles[0]) != dir || strings.HasSuffix(p.Name, "_test") {
+			continue
+		}
+		if len(p.Errors) > 0 {
+			return nil, nil, fmt.Errorf("package %s has errors; fix them first: %v", p.PkgPath, p.Errors[0])
+		}
+		if obj := p.Types.Scope().Lookup(name); obj != nil {
+			return p, obj, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s is not a package-level identifier in %s", name, dir)
+}
+
+// goObjectKey identifies a declaration by its position: the test variants of
+// a package each have their own types.Object for the same source
+type goObjectKey struct {
+	file         string
+	line, column int
+}
+
+func goKeyOf(fset *token.FileSet, obj types.Object) goObjectKey {
+	pos := fset.Position(obj.Pos())
+	return goObjectKey{pos.Filename, pos.Line, pos.Column}
+}
+
+// goRef is an identifier referring to a declaration
+type goRef struct {
+	offset    int // Of the identifier
+	qualifier int // Of pkg in pkg.Name; -1 when unqualified
+}
+
+// goFileRefs are the references found in one file
+type goFileRefs struct {
+	pkgName string
+	refs    map[int]goRef // By offset
+}
+
+// goReferences finds the identifiers in the module's files that declare or
+// refer to the object at key, by type information rather than by name: a
+// local, field or method of the same name is not a reference.
+func goReferences(pkgs []*packages.Package, fset *token.FileSet, root string, key goObjectKey) map[string]*goFileRefs {
+	found := make(map[string]*goFileRefs)
+	matches := func(info *types.Info, id *ast.Ident) bool {
+		obj := info.Defs[id]
+		if obj == nil {
+			obj = info.Uses[id]
+		}
+		return obj != nil && obj.Pkg() != nil && goKeyOf(fset, obj) == key
+	}
+	add := func(file *ast.File, id *ast.Ident, qualifier int) {
+		pos := fset.Position(id.Pos())
+		if rel, err := filepath.Rel(root, pos.Filename); err != nil || strings.HasPrefix(rel, "..") {
+			return // Generated (cgo) or outside the module
+		}
+		fr := found[pos.Filename]
+		if fr == nil {
+			fr = &goFileRefs{pkgName: file.Name.Name, refs: make(map[int]goRef)}
+			found[pos.Filename] = fr
+		}
+		fr.refs[pos.Offset] = goRef{offset: pos.Offset, qualifier: qualifier}
+	}
+
+	for _, p := range pkgs {
+		if p.TypesInfo == nil {
+			continue
+		}
+		for _, file := range p.Syntax {
+			qualified := make(map[*ast.Ident]bool)
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.SelectorExpr:
+					x, ok := n.X.(*ast.Ident)
+					if !ok {
+						break
+					}
+					if _, isPkg := p.TypesInfo.Uses[x].(*types.PkgName); isPkg && matches(p.TypesInfo, n.Sel) {
+						add(file, n.Sel, fset.Position(x.Pos()).Offset)
+						qualified[n.Sel] = true
+					}
+				case *ast.Ident:
+					if !qualified[n] && matches(p.TypesInfo, n) {
+						add(file, n, -1)
+					}
+				}
+				return true
+			})
+		}
+	}
+	return found
+}
+
+// ignoredFilesMentioning lists the module files left out by build constraints
+// that contain name: they weren't type-checked, so references there are missed
+func ignoredFilesMentioning(pkgs []*packages.Package, root, name string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, p := range pkgs {
+		for _, path := range p.IgnoredFiles {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			if data, err := os.ReadFile(path); err == nil && bytes.Contains(data, []byte(name)) {
+				rel, _ := filepath.Rel(root, path)
+				files = append(files, rel)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// textEdit replaces src[start:end] with text
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyTextEdits applies non-overlapping edits to src
+func applyTextEdits(src []byte, edits []textEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte(nil), src...)
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	return out
+}
+
+// GoRenameTool renames a package-level identifier across the module
+type GoRenameTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewGoRenameTool creates a Go rename tool
+func NewGoRenameTool(workingDir string) *GoRenameTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"package_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory of the package declaring the identifier",
+			},
+			"old_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Current package-level identifier (func, type, var, or const)",
+			},
+			"new_name": map[string]interface{}{
+				"type":        "string",
+				"description": "New identifier name",
+			},
+			"apply": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write changes and verify compilation (default: false = preview diff only)",
+			},
+		},
+		"required": []string{"package_dir", "old_name", "new_name"},
+	}
+
+	return &GoRenameTool{
+		BaseTool: NewBaseTool(
+			"go_rename",
+			"Rename a package-level Go identifier and update every reference across the module (including qualified uses in other packages). Previews a diff by default; with apply: true writes and verifies the module still compiles, rolling back on failure.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GoRenameTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	pkgDirArg, ok := args["package_dir"].(string)
+	if !ok {
+		return nil, fmt.Errorf("package_dir parameter is required")
+	}
+	oldName, ok := args["old_name"].(string)
+	if !ok || oldName == "" {
+		return nil, fmt.Errorf("old_name parameter is required")
+	}
+	newName, ok := args["new_name"].(string)
+	if !ok || !token.IsIdentifier(newName) {
+		return nil, fmt.Errorf("new_name must be a valid Go identifier")
+	}
+	apply, _ := args["apply"].(bool)
+
+	changes, result, err := t.plan(ctx, pkgDirArg, oldName, newName)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	return changes.finish(ctx, apply, result), nil
+}
+
+// PlannedPaths returns the files an applied rename would change
+func (t *GoRenameTool) PlannedPaths(ctx context.Context, args map[string]interface{}) ([]string, error) {
+	pkgDirArg, _ := args["package_dir"].(string)
+	oldName, _ := args["old_name"].(string)
+	newName, _ := args["new_name"].(string)
+	if args["apply"] != true || oldName == "" || !token.IsIdentifier(newName) {
+		return nil, nil // Preview, or refused by Execute
+	}
+	changes, _, err := t.plan(ctx, pkgDirArg, oldName, newName)
+	if err != nil {
+		return nil, err
+	}
+	return changes.paths(), nil
+}
+
+// plan computes the rename without writing anything
+func (t *GoRenameTool) plan(ctx context.Context, pkgDirArg, oldName, newName string) (*goChangeSet, map[string]interface{}, error) {
+	pkgDir := ResolveAbsPath(ctx, t.workingDir, pkgDirArg)
+	mod, err := findGoModule(pkgDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgs, fset, err := loadGoPackages(ctx, mod)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg, obj, err := lookupGoObject(pkgs, fset, pkgDir, oldName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing := pkg.Types.Scope().Lookup(newName); existing != nil {
+		return nil, nil, fmt.Errorf("%s is already declared in package %s (%s)", newName, pkg.Name, filepath.Base(fset.Position(existing.Pos()).Filename))
+	}
+
+	// Only identifiers that resolve to the declaration are renamed
+	changes := newGoChangeSet(mod.root)
+	for path, fr := range goReferences(pkgs, fset, mod.root, goKeyOf(fset, obj)) {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var edits []textEdit
+		for _, ref := range fr.refs {
+			edits = append(edits, textEdit{ref.offset, ref.offset + len(oldName), newName})
+		}
+		out := applyTextEdits(src, edits)
+		if formatted, err := format.Source(out); err == nil {
+			out = formatted // Realigns columns the new length shifted
+		}
+		changes.set(path, src, out)
+	}
+
+	result := map[string]interface{}{
+		"package":  pkg.PkgPath,
+		"old_name": oldName,
+		"new_name": newName,
+	}
+	if skipped := ignoredFilesMentioning(pkgs, mod.root, oldName); len(skipped) > 0 {
+		result["unchecked_files"] = skipped
+		result["note"] = "These files are excluded by build constraints and were not type-checked; update their references by hand"
+	}
+	return changes, result, nil
+}
+
+// GoMoveFuncTool moves a top-level function to another package
+type GoMoveFuncTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewGoMoveFuncTool creates a Go move function tool
+func NewGoMoveFuncTool(workingDir string) *GoMoveFuncTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"source_file": map[string]interface{}{
+				"type":        "string",
+				"description": "Go file containing the function",
+			},
+			"func_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Top-level function to move (must be exported to be usable from the old package)",
+			},
+			"target_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory of the destination package (must be in the same module)",
+			},
+			"target_file": map[string]interface{}{
+				"type":        "string",
+				"description": "Destination file name inside target_dir (default: same name as source file)",
+			},
+			"apply": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write changes and verify compilation (default: false = preview diff only)",
+			},
+		},
+		"required": []string{"source_file", "func_name", "target_dir"},
+	}
+
+	return &GoMoveFuncTool{
+		BaseTool: NewBaseTool(
+			"go_move_func",
+			"Move a top-level Go function to another package in the module, updating imports and qualified references. Previews by default; with apply: true writes and verifies compilation, rolling back on failure.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GoMoveFuncTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	sourceArg, ok := args["source_file"].(string)
+	if !ok {
+		return nil, fmt.Errorf("source_file parameter is required")
+	}
+	funcName, ok := args["func_name"].(string)
+	if !ok || funcName == "" {
+		return nil, fmt.Errorf("func_name parameter is required")
+	}
+	targetArg, ok := args["target_dir"].(string)
+	if !ok {
+		return nil, fmt.Errorf("target_dir parameter is required")
+	}
+	targetFile, _ := args["target_file"].(string)
+	apply, _ := args["apply"].(bool)
+
+	changes, result, err := t.plan(ctx, sourceArg, funcName, targetArg, targetFile)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	return changes.finish(ctx, apply, result), nil
+}
+
+// PlannedPaths returns the files an applied move would change or create
+func (t *GoMoveFuncTool) PlannedPaths(ctx context.Context, args map[string]interface{}) ([]string, error) {
+	sourceArg, _ := args["source_file"].(string)
+	funcName, _ := args["func_name"].(string)
+	targetArg, _ := args["target_dir"].(string)
+	targetFile, _ := args["target_file"].(string)
+	if args["apply"] != true || sourceArg == "" || funcName == "" || targetArg == "" {
+		return nil, nil // Preview, or refused by Execute
+	}
+	changes, _, err := t.plan(ctx, sourceArg, funcName, targetArg, targetFile)
+	if err != nil {
+		return nil, err
+	}
+	return changes.paths(), nil
+}
+
+// plan computes the move without writing anything
+func (t *GoMoveFuncTool) plan(ctx context.Context, sourceArg, funcName, targetArg, targetFile string) (*goChangeSet, map[string]interface{}, error) {
+	sourcePath := ResolveAbsPath(ctx, t.workingDir, sourceArg)
+	targetDir := ResolveAbsPath(ctx, t.workingDir, targetArg)
+	sourceDir := filepath.Dir(sourcePath)
+	if sourceDir == targetDir {
+		return nil, nil, fmt.Errorf("source and target are the same package")
+	}
+
+	mod, err := findGoModule(sourceDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rel, err := filepath.Rel(mod.root, targetDir); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, nil, fmt.Errorf("target_dir must be inside module %s", mod.path)
+	}
+	dstPkgPath := mod.importPath(targetDir)
+	dstPkgName := packageNameInDir(targetDir)
+	if dstPkgName == "" {
+		dstPkgName = filepath.Base(targetDir)
+	}
+
+	pkgs, fset, err := loadGoPackages(ctx, mod)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg, obj, err := lookupGoObject(pkgs, fset, sourceDir, funcName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, isFunc := obj.(*types.Func); !isFunc || fset.Position(obj.Pos()).Filename != sourcePath {
+		return nil, nil, fmt.Errorf("top-level function %s not found in %s", funcName, sourceArg)
+	}
+
+	// Locate the declaration in the type-checked syntax
+	var fn *ast.FuncDecl
+	for _, file := range pkg.Syntax {
+		if fset.Position(file.Pos()).Filename != sourcePath {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*ast.FuncDecl); ok && d.Recv == nil && d.Name.Name == funcName {
+				fn = d
+			}
+		}
+	}
+	if fn == nil {
+		return nil, nil, fmt.Errorf("top-level function %s not found in %s", funcName, sourceArg)
+	}
+	src, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := fn.Pos()
+	if fn.Doc != nil {
+		start = fn.Doc.Pos()
+	}
+	startOff := fset.Position(start).Offset
+	endOff := fset.Position(fn.End()).Offset
+
+	// The function's text: packages it uses need importing at the target,
+	// and references to the target package lose their qualifier
+	var neededImports []string
+	var funcEdits []textEdit
+	ast.Inspect(fn, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if pkgName, ok := pkg.TypesInfo.Uses[x].(*types.PkgName); ok {
+			if path := pkgName.Imported().Path(); path == dstPkgPath {
+				xOff := fset.Position(x.Pos()).Offset - startOff
+				funcEdits = append(funcEdits, textEdit{xOff, fset.Position(sel.Sel.Pos()).Offset - startOff, ""})
+			} else {
+				neededImports = append(neededImports, path)
+			}
+		}
+		return true
+	})
+	funcText := string(applyTextEdits(src[startOff:endOff], funcEdits))
+
+	// References across the module: the source package calls dst.Func, the
+	// target package calls Func, other importers switch qualifiers
+	updated := make(map[string][]byte)
+	original := make(map[string][]byte)
+	needsDstImport := make(map[string]bool)
+	for path, fr := range goReferences(pkgs, fset, mod.root, goKeyOf(fset, obj)) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		inTarget := filepath.Dir(path) == targetDir && fr.pkgName == dstPkgName
+		var edits []textEdit
+		for _, ref := range fr.refs {
+			switch {
+			case path == sourcePath && ref.offset >= startOff && ref.offset < endOff:
+				// The declaration itself and recursive calls move along
+			case ref.qualifier < 0:
+				edits = append(edits, textEdit{ref.offset, ref.offset, dstPkgName + "."})
+				needsDstImport[path] = true
+			case inTarget:
+				edits = append(edits, textEdit{ref.qualifier, ref.offset, ""})
+			default:
+				edits = append(edits, textEdit{ref.qualifier, ref.offset, dstPkgName + "."})
+				needsDstImport[path] = true
+			}
+		}
+		if path == sourcePath {
+			edits = append(edits, textEdit{startOff, endOff, ""})
+		}
+		original[path] = content
+		updated[path] = applyTextEdits(content, edits)
+	}
+
+	// Append to the target file
+	targetName := filepath.Base(sourcePath)
+	if targetFile != "" {
+		targetName = filepath.Base(targetFile)
+	}
+	targetPath := filepath.Join(targetDir, targetName)
+	targetSrc, ok := updated[targetPath]
+	if !ok {
+		if existing, err := os.ReadFile(targetPath); err == nil {
+			original[targetPath] = existing
+			targetSrc = existing
+		} else {
+			original[targetPath] = nil
+			targetSrc = []byte(fmt.Sprintf("package %s\n", dstPkgName))
+		}
+	}
+	targetSrc = append(append(append(targetSrc, '\n'), funcText...), '\n')
+	for _, imp := range neededImports {
+		if targetSrc, err = addImport(targetSrc, imp); err != nil {
+			return nil, nil, fmt.Errorf("add import %s to %s: %v", imp, targetName, err)
+		}
+	}
+	updated[targetPath] = targetSrc
+
+	changes := newGoChangeSet(mod.root)
+	for path, content := range updated {
+		var err error
+		if needsDstImport[path] {
+			if content, err = addImport(content, dstPkgPath); err != nil {
+				return nil, nil, fmt.Errorf("add import to %s: %v", path, err)
+			}
+		}
+		if content, _, err = removeUnusedImports(content); err != nil {
+			return nil, nil, fmt.Errorf("clean imports in %s: %v", path, err)
+		}
+		changes.set(path, original[path], content)
+	}
+
+	result := map[string]interface{}{
+		"function": funcName,
+		"from":     pkg.PkgPath,
+		"to":       dstPkgPath,
+	}
+	if skipped := ignoredFilesMentioning(pkgs, mod.root, funcName); len(skipped) > 0 {
+		result["unchecked_files"] = skipped
+		result["note"] = "These files are excluded by build constraints and were not type-checked; update their references by hand"
+	}
+	return changes, result, nil
+}
+
+// GoOrganizeImportsTool removes unused imports and sorts import blocks
+type GoOrganizeImportsTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewGoOrganizeImportsTool creates a Go organize imports tool
+func NewGoOrganizeImportsTool(workingDir string) *GoOrganizeImportsTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Go file to organize",
+			},
+			"apply": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Write changes and verify compilation (default: false = preview diff only)",
+			},
+		},
+		"required": []string{"path"},
+	}
+
+	return &GoOrganizeImportsTool{
+		BaseTool: NewBaseTool(
+			"go_organize_imports",
+			"Organize imports in a Go file: uses goimports when installed (adds missing imports), otherwise removes unused imports and sorts. Previews by default.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *GoOrganizeImportsTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	pathArg, ok := args["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	apply, _ := args["apply"].(bool)
+
+	path := ResolveAbsPath(ctx, t.workingDir, pathArg)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+
+	var updated []byte
+	var removed []string
+	tool := "builtin"
+	if _, err := exec.LookPath("goimports"); err == nil {
+		cmd := exec.CommandContext(ctx, "goimports")
+		cmd.Dir = filepath.Dir(path)
+		cmd.Stdin = bytes.NewReader(original)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if out, err := cmd.Output(); err == nil {
+			updated = out
+			tool = "goimports"
+		}
+	}
+	if updated == nil {
+		updated, removed, err = removeUnusedImports(original)
+		if err != nil {
+			return map[string]interface{}{"error": fmt.Sprintf("parse %s: %v", pathArg, err), "success": false}, nil
+		}
+	}
+
+	if bytes.Equal(original, updated) {
+		return map[string]interface{}{
+			"path":    pathArg,
+			"changed": false,
+			"success": true,
+		}, nil
+	}
+
+	root := filepath.Dir(path)
+	if mod, err := findGoModule(root); err == nil {
+		root = mod.root
+	} else if apply {
+		// Outside a module there is nothing to compile; just write
+		if err := os.WriteFile(path, updated, 0644); err != nil {
+			return map[string]interface{}{"error": err.Error(), "success": false}, nil
+		}
+		recordFileRead(ctx, path, updated)
+		return map[string]interface{}{"path": pathArg, "changed": true, "removed": removed, "tool": tool, "success": true}, nil
+	}
+
+	changes := newGoChangeSet(root)
+	changes.set(path, original, updated)
+	return changes.finish(ctx, apply, map[string]interface{}{
+		"path":    pathArg,
+		"changed": true,
+		"removed": removed,
+		"tool":    tool,
+	}), nil
+}
@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// RUNBOOK SEARCH TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Runbooks are markdown (or text) files in the configured directories. They
+// are searched section by section (a section runs from one heading to the
+// next): sections score by how many of the query's words they contain,
+// words in the heading or file name counting more. Files are read on every
+// search, so edited runbooks are found right away.
+
+// runbookMaxFileSize skips files too large to be runbooks
+const runbookMaxFileSize = 1 << 20
+
+// runbookSectionMax caps the text returned per section
+const runbookSectionMax = 3000
+
+// runbookSection is a heading and the text under it
+type runbookSection struct {
+	path    string
+	heading string
+	text    string
+	score   int
+}
+
+// RunbookSearchTool searches the operations runbooks
+type RunbookSearchTool struct {
+	BaseTool
+	dirs []string
+}
+
+// NewRunbookSearchTool creates a runbook search over dirs (~ is expanded)
+func NewRunbookSearchTool(dirs []string) *RunbookSearchTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to look for: alert name, error message, component, symptom",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Sections to return (default 5)",
+			},
+		},
+		"required": []string{"query"},
+	}
+
+	expanded := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+			home, _ := os.UserHomeDir()
+			dir = filepath.Join(home, rest)
+		}
+		expanded = append(expanded, dir)
+	}
+
+	return &RunbookSearchTool{
+		BaseTool: NewBaseTool(
+			"runbook_search",
+			"Search the team's operations runbooks (markdown) for procedures matching an alert, error or component. Returns the best matching sections with their file and heading.",
+			params,
+		),
+		dirs: expanded,
+	}
+}
+
+func (t *RunbookSearchTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	words := runbookWords(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+	if len(t.dirs) == 0 {
+		return map[string]interface{}{
+			"error":   "no runbooks configured (incident.runbooks in the gateway config)",
+			"success": false,
+		}, nil
+	}
+	limit := 5
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), 20)
+	}
+
+	var matches []runbookSection
+	for _, dir := range t.dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || ctx.Err() != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if strings.HasPrefix(d.Name(), ".") && path != dir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".md", ".markdown", ".txt":
+			default:
+				return nil
+			}
+			if info, err := d.Info(); err != nil || info.Size() > runbookMaxFileSize {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, path)
+			for _, section := range runbookSections(filepath.Join(filepath.Base(dir), rel), string(data)) {
+				if section.score = scoreRunbookSection(section, words); section.score > 0 {
+					matches = append(matches, section)
+				}
+			}
+			return nil
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]map[string]interface{}, 0, len(matches))
+	for _, m := range matches {
+		text := m.text
+		if len(text) > runbookSectionMax {
+			text = text[:runbookSectionMax] + "\n... [section truncated]"
+		}
+		results = append(results, map[string]interface{}{
+			"file":    m.path,
+			"heading": m.heading,
+			"text":    text,
+		})
+	}
+	return map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+		"success": true,
+	}, nil
+}
+
+// runbookSections splits a document at its markdown headings
+func runbookSections(path, content string) []runbookSection {
+	var sections []runbookSection
+	current := runbookSection{path: path}
+	var body strings.Builder
+	flush := func() {
+		current.text = strings.TrimSpace(body.String())
+		if current.text != "" || current.heading != "" {
+			sections = append(sections, current)
+		}
+		body.Reset()
+	}
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			flush()
+			current = runbookSection{path: path, heading: strings.TrimSpace(strings.TrimLeft(line, "#"))}
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// scoreRunbookSection counts the query words a section contains; words in
+// its heading or file name count three times
+func scoreRunbookSection(s runbookSection, words []string) int {
+	title := strings.ToLower(s.heading + " " + s.path)
+	text := strings.ToLower(s.text)
+	score := 0
+	for _, w := range words {
+		if strings.Contains(title, w) {
+			score += 3
+		}
+		if strings.Contains(text, w) {
+			score++
+		}
+	}
+	return score
+}
+
+// runbookWords splits a query into lowercase words of 3+ characters
+func runbookWords(query string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	}) {
+		if len(w) >= 3 && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
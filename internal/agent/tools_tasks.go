@@ -0,0 +1,267 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROJECT TASKS TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// projectTask is one runnable task discovered in a project file
+type projectTask struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Run         string `json:"run"` // Command line that runs the task
+}
+
+// taskSource is a task file and the tasks it defines
+type taskSource struct {
+	File  string        `json:"file"`
+	Tasks []projectTask `json:"tasks"`
+}
+
+var (
+	// target: deps ## description
+	makeTargetRe = regexp.MustCompile(`^([A-Za-z0-9_][A-Za-z0-9_./%-]*(?:\s+[A-Za-z0-9_][A-Za-z0-9_./%-]*)*)\s*:([^=]|$)`)
+	// recipe arg1 arg2="x": deps
+	justRecipeRe = regexp.MustCompile(`^@?([A-Za-z0-9_][A-Za-z0-9_-]*)(\s[^:]*)?:([^=]|$)`)
+)
+
+// parseMakefile extracts targets with descriptions from "## desc" suffixes or preceding comments
+func parseMakefile(content string) []projectTask {
+	var tasks []projectTask
+	seen := make(map[string]bool)
+	comment := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		if strings.HasPrefix(line, "\t") || strings.TrimSpace(line) == "" {
+			comment = ""
+			continue
+		}
+		m := makeTargetRe.FindStringSubmatch(line)
+		if m == nil {
+			comment = ""
+			continue
+		}
+		desc := comment
+		if i := strings.Index(line, "##"); i >= 0 {
+			desc = strings.TrimSpace(line[i+2:])
+		}
+		for _, name := range strings.Fields(m[1]) {
+			// Skip special targets (.PHONY), pattern rules and file targets
+			if strings.HasPrefix(name, ".") || strings.Contains(name, "%") || strings.Contains(name, "/") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			tasks = append(tasks, projectTask{Name: name, Description: desc, Run: "make " + name})
+		}
+		comment = ""
+	}
+	return tasks
+}
+
+// parseJustfile extracts recipes with their doc comments
+func parseJustfile(content string) []projectTask {
+	var tasks []projectTask
+	comment := ""
+	private := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		if strings.HasPrefix(line, "[") { // Attributes like [private] or [no-cd]
+			private = private || strings.HasPrefix(line, "[private")
+			continue
+		}
+		m := justRecipeRe.FindStringSubmatch(line)
+		if m != nil && !strings.HasPrefix(m[1], "_") && !private && !isJustDirective(line) {
+			tasks = append(tasks, projectTask{Name: m[1], Description: comment, Run: "just " + m[1]})
+		}
+		comment, private = "", false
+	}
+	return tasks
+}
+
+// isJustDirective reports whether a justfile line is a setting or statement rather than a recipe
+func isJustDirective(line string) bool {
+	for _, kw := range []string{"set ", "alias ", "export ", "import ", "mod "} {
+		if strings.HasPrefix(line, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTaskfile extracts tasks from a go-task Taskfile
+func parseTaskfile(content []byte) ([]projectTask, error) {
+	var doc struct {
+		Tasks map[string]yaml.Node `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	var tasks []projectTask
+	for name, node := range doc.Tasks {
+		var def struct {
+			Desc     string `yaml:"desc"`
+			Summary  string `yaml:"summary"`
+			Internal bool   `yaml:"internal"`
+		}
+		if node.Kind == yaml.MappingNode {
+			node.Decode(&def)
+		}
+		if def.Internal {
+			continue
+		}
+		desc := def.Desc
+		if desc == "" {
+			desc = strings.SplitN(strings.TrimSpace(def.Summary), "\n", 2)[0]
+		}
+		tasks = append(tasks, projectTask{Name: name, Description: desc, Run: "task " + name})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// parsePackageScripts extracts npm scripts; the description is the script command itself
+func parsePackageScripts(content []byte, runner string) ([]projectTask, error) {
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+
+	var tasks []projectTask
+	for name, script := range pkg.Scripts {
+		tasks = append(tasks, projectTask{Name: name, Description: script, Run: runner + " run " + name})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// nodeRunner picks the package manager from the lockfile present in dir
+func nodeRunner(dir string) string {
+	for _, lock := range []struct{ file, runner string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun"},
+		{"bun.lock", "bun"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			return lock.runner
+		}
+	}
+	return "npm"
+}
+
+// ProjectTasksTool lists the project's canonical commands from its task files
+type ProjectTasksTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewProjectTasksTool creates a new project tasks tool
+func NewProjectTasksTool(workingDir string) *ProjectTasksTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Project directory (default: working directory)",
+			},
+		},
+	}
+
+	return &ProjectTasksTool{
+		BaseTool: NewBaseTool(
+			"project_tasks",
+			"List the project's defined tasks (Makefile targets, Taskfile tasks, package.json scripts, justfile recipes) with descriptions and the exact command to run each. Use this before building, testing or linting so you run the project's canonical commands instead of guessing.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *ProjectTasksTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	dir := BaseDir(ctx, t.workingDir)
+	if p, ok := args["path"].(string); ok && p != "" {
+		dir = ResolveAbsPath(ctx, t.workingDir, p)
+	}
+	if dir == "" {
+		dir = "."
+	}
+
+	var sources []taskSource
+	var parseErrors []string
+	total := 0
+	add := func(file string, tasks []projectTask, err error) {
+		if err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("%s: %v", file, err))
+			return
+		}
+		if len(tasks) > 0 {
+			sources = append(sources, taskSource{File: file, Tasks: tasks})
+			total += len(tasks)
+		}
+	}
+
+	for _, name := range []string{"Makefile", "makefile", "GNUmakefile"} {
+		if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			add(name, parseMakefile(string(content)), nil)
+			break
+		}
+	}
+	for _, name := range []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"} {
+		if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			tasks, err := parseTaskfile(content)
+			add(name, tasks, err)
+			break
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		tasks, err := parsePackageScripts(content, nodeRunner(dir))
+		add("package.json", tasks, err)
+	}
+	for _, name := range []string{"justfile", "Justfile", ".justfile"} {
+		if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			add(name, parseJustfile(string(content)), nil)
+			break
+		}
+	}
+
+	result := map[string]interface{}{
+		"sources": sources,
+		"count":   total,
+		"success": true,
+	}
+	if len(parseErrors) > 0 {
+		result["parse_errors"] = parseErrors
+	}
+	if total == 0 {
+		result["message"] = "No Makefile, Taskfile, package.json scripts or justfile found"
+	}
+	return result, nil
+}
@@ -0,0 +1,503 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TERRAFORM PLAN TOOL
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// terraform_plan runs `terraform plan` on a copy of the project, so init
+// and plan never write into the checkout (.terraform, lock file, plan file),
+// and it never takes the state lock. The JSON plan is summarized per
+// resource with a risk level: destroying or replacing stateful resources
+// (databases, buckets, disks, keys...) is high risk. Saved plans (binary or
+// `terraform show -json` output) can be summarized without running plan.
+
+// terraformTimeout bounds init plus plan
+const terraformTimeout = 15 * time.Minute
+
+// terraformMaxCopy caps the bytes copied into the sandbox
+const terraformMaxCopy = 200 << 20
+
+// terraformSkipDirs are not copied into the sandbox
+var terraformSkipDirs = map[string]bool{".git": true, ".terraform": true, "node_modules": true}
+
+// terraformVarName matches -var names and -target addresses
+var terraformVarName = regexp.MustCompile(`^[A-Za-z0-9_.\[\]"\-/]+$`)
+
+// statefulResourceTypes hold data: destroying them loses it
+var statefulResourceTypes = map[string]bool{
+	"aws_db_instance": true, "aws_rds_cluster": true, "aws_rds_cluster_instance": true,
+	"aws_dynamodb_table": true, "aws_s3_bucket": true, "aws_efs_file_system": true,
+	"aws_ebs_volume": true, "aws_elasticache_cluster": true, "aws_elasticache_replication_group": true,
+	"aws_redshift_cluster": true, "aws_docdb_cluster": true, "aws_neptune_cluster": true,
+	"aws_kms_key": true, "aws_msk_cluster": true, "aws_opensearch_domain": true,
+	"aws_elasticsearch_domain": true, "aws_secretsmanager_secret": true, "aws_route53_zone": true,
+	"aws_kinesis_stream": true, "aws_sqs_queue": true, "aws_ecr_repository": true,
+	"google_sql_database_instance": true, "google_sql_database": true, "google_storage_bucket": true,
+	"google_bigquery_dataset": true, "google_bigquery_table": true, "google_spanner_instance": true,
+	"google_spanner_database": true, "google_compute_disk": true, "google_kms_crypto_key": true,
+	"google_bigtable_instance": true, "google_redis_instance": true, "google_filestore_instance": true,
+	"google_dns_managed_zone": true,
+	"azurerm_storage_account": true, "azurerm_mssql_database": true, "azurerm_sql_database": true,
+	"azurerm_postgresql_server": true, "azurerm_postgresql_flexible_server": true,
+	"azurerm_mysql_flexible_server": true, "azurerm_cosmosdb_account": true, "azurerm_key_vault": true,
+	"azurerm_managed_disk": true, "azurerm_redis_cache": true, "azurerm_dns_zone": true,
+	"kubernetes_persistent_volume": true, "kubernetes_persistent_volume_claim": true, "kubernetes_namespace": true,
+}
+
+// accessControlMarkers in a resource type mean it grants or restricts access
+var accessControlMarkers = []string{"iam", "security_group", "firewall", "network_acl", "policy", "role_binding"}
+
+// tfPlan is the part of `terraform show -json` output the summary uses
+type tfPlan struct {
+	TerraformVersion string             `json:"terraform_version"`
+	ResourceChanges  []tfResourceChange `json:"resource_changes"`
+	ResourceDrift    []tfResourceChange `json:"resource_drift"`
+	OutputChanges    map[string]struct {
+		Actions []string `json:"actions"`
+	} `json:"output_changes"`
+}
+
+// tfResourceChange is one planned change of a resource instance
+type tfResourceChange struct {
+	Address      string `json:"address"`
+	Mode         string `json:"mode"` // managed or data
+	Type         string `json:"type"`
+	ActionReason string `json:"action_reason"`
+	Change       struct {
+		Actions      []string               `json:"actions"`
+		Before       map[string]interface{} `json:"before"`
+		After        map[string]interface{} `json:"after"`
+		AfterUnknown map[string]interface{} `json:"after_unknown"`
+		ReplacePaths [][]interface{}        `json:"replace_paths"`
+	} `json:"change"`
+}
+
+// TerraformPlanTool runs terraform plan in a sandbox and summarizes it
+type TerraformPlanTool struct {
+	BaseTool
+	workingDir string
+}
+
+// NewTerraformPlanTool creates a new Terraform plan review tool
+func NewTerraformPlanTool(workingDir string) *TerraformPlanTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Terraform root module directory (default: working directory)",
+			},
+			"var_files": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "-var-file files, relative to path",
+			},
+			"vars": map[string]interface{}{
+				"type":        "object",
+				"description": "-var values, name to value",
+			},
+			"targets": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "-target resource addresses",
+			},
+			"destroy": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Plan a destroy",
+			},
+			"refresh": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Refresh state against the real infrastructure first (default true)",
+			},
+			"plan_file": map[string]interface{}{
+				"type":        "string",
+				"description": "Summarize a saved plan (binary, or terraform show -json output) instead of running plan",
+			},
+		},
+	}
+
+	return &TerraformPlanTool{
+		BaseTool: NewBaseTool(
+			"terraform_plan",
+			"Run terraform plan on a sandboxed copy of the project (no lock taken, nothing written to the checkout or applied) and summarize the changes per resource with a risk level. Flags destroying or replacing stateful resources (databases, buckets, disks, keys), access-control changes and disabled deletion protection.",
+			params,
+		),
+		workingDir: workingDir,
+	}
+}
+
+func (t *TerraformPlanTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	dir := ResolveAbsPath(ctx, t.workingDir, ".")
+	if p, ok := args["path"].(string); ok && p != "" {
+		dir = ResolveAbsPath(ctx, t.workingDir, p)
+	}
+
+	if planFile, _ := args["plan_file"].(string); planFile != "" {
+		planFile = ResolveAbsPath(ctx, t.workingDir, planFile)
+		data, err := os.ReadFile(planFile)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error(), "success": false}, nil
+		}
+		if !json.Valid(data) {
+			// Binary plan: terraform renders it, which needs the initialized module
+			out, errMsg := t.terraform(ctx, dir, "show", "-json", "-no-color", planFile)
+			if errMsg != "" {
+				return map[string]interface{}{"error": "terraform show failed: " + errMsg, "success": false}, nil
+			}
+			data = out
+		}
+		return summarizeTerraformPlan(data)
+	}
+
+	planArgs, err := terraformPlanArgs(args)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		return map[string]interface{}{"error": "terraform is not installed on the gateway host", "success": false}, nil
+	}
+
+	// The sandbox copies the project root so ../modules sources still resolve
+	root := BaseDir(ctx, t.workingDir)
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || root == "" || strings.HasPrefix(rel, "..") {
+		root, rel = dir, "."
+	}
+	sandbox, err := os.MkdirTemp("", "zen-claw-terraform-*")
+	if err != nil {
+		return nil, fmt.Errorf("create sandbox: %w", err)
+	}
+	defer os.RemoveAll(sandbox)
+	if err := copyTerraformTree(root, sandbox); err != nil {
+		return map[string]interface{}{"error": err.Error(), "success": false}, nil
+	}
+	workDir := filepath.Join(sandbox, rel)
+
+	runCtx, cancel := context.WithTimeout(ctx, terraformTimeout)
+	defer cancel()
+	if _, errMsg := t.terraform(runCtx, workDir, "init", "-input=false", "-no-color"); errMsg != "" {
+		return map[string]interface{}{"error": "terraform init failed: " + errMsg, "success": false}, nil
+	}
+	planArgs = append(planArgs, "-out="+filepath.Join(sandbox, "zen-claw.tfplan"))
+	if out, errMsg := t.terraform(runCtx, workDir, planArgs...); errMsg != "" {
+		return map[string]interface{}{
+			"error":       "terraform plan failed: " + errMsg,
+			"plan_output": truncateOutput(string(out), 8000),
+			"success":     false,
+		}, nil
+	}
+	data, errMsg := t.terraform(runCtx, workDir, "show", "-json", "-no-color", filepath.Join(sandbox, "zen-claw.tfplan"))
+	if errMsg != "" {
+		return map[string]interface{}{"error": "terraform show failed: " + errMsg, "success": false}, nil
+	}
+	return summarizeTerraformPlan(data)
+}
+
+// terraform runs one terraform command with the session environment. The
+// error message is the redacted stderr (empty on success).
+func (t *TerraformPlanTool) terraform(ctx context.Context, dir string, args ...string) ([]byte, string) {
+	env, err := resolveSessionEnv(ctx)
+	if err != nil {
+		return nil, err.Error()
+	}
+	cmd := exec.CommandContext(ctx, "terraform", args...)
+	cmd.Dir = dir
+	env.apply(cmd)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "TF_IN_AUTOMATION=1", "TF_INPUT=0")
+	if os.Getenv("TF_PLUGIN_CACHE_DIR") == "" {
+		// Every sandbox runs init: share downloaded providers between them
+		if cache, err := os.UserCacheDir(); err == nil {
+			cache = filepath.Join(cache, "zen-claw", "terraform-plugins")
+			if os.MkdirAll(cache, 0o755) == nil {
+				cmd.Env = append(cmd.Env, "TF_PLUGIN_CACHE_DIR="+cache)
+			}
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(env.redact(stderr.String()))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return []byte(env.redact(stdout.String())), truncateOutput(msg, 4000)
+	}
+	return stdout.Bytes(), ""
+}
+
+// terraformPlanArgs builds the plan arguments for a call
+func terraformPlanArgs(args map[string]interface{}) ([]string, error) {
+	out := []string{"plan", "-input=false", "-no-color", "-lock=false"}
+	if destroy, _ := args["destroy"].(bool); destroy {
+		out = append(out, "-destroy")
+	}
+	if refresh, ok := args["refresh"].(bool); ok && !refresh {
+		out = append(out, "-refresh=false")
+	}
+	for _, f := range stringList(args["var_files"]) {
+		if strings.HasPrefix(f, "-") {
+			return nil, fmt.Errorf("invalid var file %q", f)
+		}
+		out = append(out, "-var-file="+f)
+	}
+	if vars, ok := args["vars"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			if !terraformVarName.MatchString(name) {
+				return nil, fmt.Errorf("invalid variable name %q", name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, ok := vars[name].(string)
+			if !ok {
+				data, _ := json.Marshal(vars[name])
+				value = string(data)
+			}
+			out = append(out, "-var="+name+"="+value)
+		}
+	}
+	for _, target := range stringList(args["targets"]) {
+		if !terraformVarName.MatchString(target) {
+			return nil, fmt.Errorf("invalid target %q", target)
+		}
+		out = append(out, "-target="+target)
+	}
+	return out, nil
+}
+
+// stringList reads a JSON array of strings from a tool argument
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// copyTerraformTree copies the project into the sandbox, skipping VCS and
+// provider directories
+func copyTerraformTree(src, dst string) error {
+	var total int64
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			if terraformSkipDirs[d.Name()] && path != src {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if total += info.Size(); total > terraformMaxCopy {
+			return fmt.Errorf("project is larger than %d MB; point path at the Terraform directory", terraformMaxCopy>>20)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// summarizeTerraformPlan turns `terraform show -json` output into the
+// tool result: counts per action and the changes, riskiest first
+func summarizeTerraformPlan(data []byte) (interface{}, error) {
+	var plan tfPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf("not a JSON plan: %v", err), "success": false}, nil
+	}
+
+	counts := map[string]int{"create": 0, "update": 0, "delete": 0, "replace": 0, "read": 0, "no-op": 0}
+	riskOrder := map[string]int{"high": 0, "medium": 1, "low": 2}
+	var changes []map[string]interface{}
+	var dangerous []string
+	for _, rc := range plan.ResourceChanges {
+		action := terraformAction(rc.Change.Actions)
+		counts[action]++
+		if action == "no-op" || (action == "read" && rc.Mode == "data") {
+			continue
+		}
+		risk, reasons := terraformRisk(rc, action)
+		change := map[string]interface{}{
+			"address": rc.Address,
+			"action":  action,
+			"risk":    risk,
+		}
+		if len(reasons) > 0 {
+			change["reasons"] = reasons
+		}
+		if rc.ActionReason != "" {
+			change["action_reason"] = rc.ActionReason
+		}
+		if action == "update" || action == "replace" {
+			if attrs := changedAttributes(rc); len(attrs) > 0 {
+				change["changed_attributes"] = attrs
+			}
+		}
+		if risk == "high" {
+			dangerous = append(dangerous, fmt.Sprintf("%s: %s", rc.Address, strings.Join(reasons, "; ")))
+		}
+		changes = append(changes, change)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		ri, rj := riskOrder[changes[i]["risk"].(string)], riskOrder[changes[j]["risk"].(string)]
+		if ri != rj {
+			return ri < rj
+		}
+		return changes[i]["address"].(string) < changes[j]["address"].(string)
+	})
+
+	var outputs []string
+	for name, oc := range plan.OutputChanges {
+		if action := terraformAction(oc.Actions); action != "no-op" {
+			outputs = append(outputs, name+" ("+action+")")
+		}
+	}
+	sort.Strings(outputs)
+
+	result := map[string]interface{}{
+		"summary":   counts,
+		"changes":   changes,
+		"dangerous": dangerous,
+		"success":   true,
+	}
+	if plan.TerraformVersion != "" {
+		result["terraform_version"] = plan.TerraformVersion
+	}
+	if len(outputs) > 0 {
+		result["output_changes"] = outputs
+	}
+	if len(plan.ResourceDrift) > 0 {
+		result["drifted_resources"] = len(plan.ResourceDrift)
+	}
+	return result, nil
+}
+
+// terraformAction names a plan's action list: ["delete","create"] and
+// ["create","delete"] are replacements
+func terraformAction(actions []string) string {
+	switch {
+	case len(actions) == 2:
+		return "replace"
+	case len(actions) == 1:
+		return actions[0]
+	}
+	return "no-op"
+}
+
+// terraformRisk rates a change: high when data or protection can be lost,
+// medium for other destruction and access-control changes
+func terraformRisk(rc tfResourceChange, action string) (string, []string) {
+	stateful := statefulResourceTypes[rc.Type]
+	var reasons []string
+	risk := "low"
+	raise := func(level, reason string) {
+		if level == "high" || risk == "low" {
+			risk = level
+		}
+		reasons = append(reasons, reason)
+	}
+
+	switch action {
+	case "delete", "replace":
+		verb := "destroyed"
+		if action == "replace" {
+			verb = "destroyed and recreated"
+		}
+		if stateful {
+			raise("high", fmt.Sprintf("stateful %s is %s; its data is lost", rc.Type, verb))
+		} else {
+			raise("medium", fmt.Sprintf("%s is %s", rc.Type, verb))
+		}
+	case "update":
+		before, after := rc.Change.Before, rc.Change.After
+		if before["deletion_protection"] == true && after["deletion_protection"] == false {
+			raise("high", "deletion protection is turned off")
+		}
+		if after["force_destroy"] == true && before["force_destroy"] != true {
+			raise("medium", "force_destroy is turned on")
+		}
+		if stateful && after["skip_final_snapshot"] == true && before["skip_final_snapshot"] != true {
+			raise("medium", "final snapshot is skipped on destroy")
+		}
+	}
+	if action != "read" {
+		for _, marker := range accessControlMarkers {
+			if strings.Contains(rc.Type, marker) {
+				raise("medium", "access control changes")
+				break
+			}
+		}
+	}
+	return risk, reasons
+}
+
+// changedAttributes lists the top-level attributes an update changes;
+// values only known after apply count as changed
+func changedAttributes(rc tfResourceChange) []string {
+	var attrs []string
+	for key, after := range rc.Change.After {
+		if before, ok := rc.Change.Before[key]; !ok || !jsonEqual(before, after) {
+			attrs = append(attrs, key)
+		}
+	}
+	for key := range rc.Change.AfterUnknown {
+		if _, ok := rc.Change.After[key]; !ok {
+			attrs = append(attrs, key)
+		}
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
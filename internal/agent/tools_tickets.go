@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/tickets"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TICKET TOOLS (Jira, Linear)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// The ticket tools work on the workspaces of tickets.workspaces in the
+// gateway config. A call names its workspace, or the ticket key's prefix
+// picks it (LIN-423 goes to the workspace whose projects include LIN).
+
+// ticketWorkspaceParam is the workspace parameter shared by the tools
+var ticketWorkspaceParam = map[string]interface{}{
+	"type":        "string",
+	"description": "Workspace name from the config (default: chosen by the ticket key's prefix, else the default workspace)",
+}
+
+// NewTicketTools creates the ticket tools for the configured workspaces
+func NewTicketTools(workspaces *tickets.Workspaces) []Tool {
+	return []Tool{
+		NewTicketSearchTool(workspaces),
+		NewTicketGetTool(workspaces),
+		NewTicketCommentTool(workspaces),
+		NewTicketTransitionTool(workspaces),
+	}
+}
+
+// TicketSearchTool searches tickets
+type TicketSearchTool struct {
+	BaseTool
+	workspaces *tickets.Workspaces
+}
+
+// NewTicketSearchTool creates a new ticket search tool
+func NewTicketSearchTool(workspaces *tickets.Workspaces) *TicketSearchTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Free text, or JQL on Jira (e.g. project = OPS AND status = \"In Progress\")",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum tickets (default 10, max 50)",
+			},
+			"workspace": ticketWorkspaceParam,
+		},
+		"required": []string{"query"},
+	}
+	return &TicketSearchTool{
+		BaseTool:   NewBaseTool("ticket_search", "Search Jira or Linear tickets by text (or JQL on Jira). Returns key, title, status and assignee.", params),
+		workspaces: workspaces,
+	}
+}
+
+func (t *TicketSearchTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+	workspace, _ := args["workspace"].(string)
+	ws, err := t.workspaces.Resolve(workspace, "")
+	if err != nil {
+		return nil, err
+	}
+	limit := 10
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), 50)
+	}
+	found, err := ws.Tracker.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]map[string]interface{}, 0, len(found))
+	for _, ticket := range found {
+		results = append(results, map[string]interface{}{
+			"key":      ticket.Key,
+			"title":    ticket.Title,
+			"status":   ticket.Status,
+			"assignee": ticket.Assignee,
+			"url":      ticket.URL,
+		})
+	}
+	return map[string]interface{}{
+		"workspace": ws.Name,
+		"tickets":   results,
+		"count":     len(results),
+		"success":   true,
+	}, nil
+}
+
+// TicketGetTool reads a ticket
+type TicketGetTool struct {
+	BaseTool
+	workspaces *tickets.Workspaces
+}
+
+// NewTicketGetTool creates a new ticket read tool
+func NewTicketGetTool(workspaces *tickets.Workspaces) *TicketGetTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Ticket key, e.g. LIN-423 or OPS-12",
+			},
+			"workspace": ticketWorkspaceParam,
+		},
+		"required": []string{"key"},
+	}
+	return &TicketGetTool{
+		BaseTool:   NewBaseTool("ticket_get", "Read a Jira or Linear ticket: title, description (acceptance criteria), status, labels and comments.", params),
+		workspaces: workspaces,
+	}
+}
+
+func (t *TicketGetTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	key, _ := args["key"].(string)
+	workspace, _ := args["workspace"].(string)
+	ws, err := t.workspaces.Resolve(workspace, key)
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := ws.Tracker.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"workspace": ws.Name,
+		"ticket":    ticket,
+		"success":   true,
+	}, nil
+}
+
+// TicketCommentTool comments on a ticket
+type TicketCommentTool struct {
+	BaseTool
+	workspaces *tickets.Workspaces
+}
+
+// NewTicketCommentTool creates a new ticket comment tool
+func NewTicketCommentTool(workspaces *tickets.Workspaces) *TicketCommentTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Ticket key, e.g. LIN-423 or OPS-12",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Comment text (markdown on Linear, wiki markup on Jira)",
+			},
+			"workspace": ticketWorkspaceParam,
+		},
+		"required": []string{"key", "body"},
+	}
+	return &TicketCommentTool{
+		BaseTool:   NewBaseTool("ticket_comment", "Add a comment to a Jira or Linear ticket, e.g. a summary of the change made for it.", params),
+		workspaces: workspaces,
+	}
+}
+
+func (t *TicketCommentTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	key, _ := args["key"].(string)
+	body, _ := args["body"].(string)
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("body parameter is required")
+	}
+	workspace, _ := args["workspace"].(string)
+	ws, err := t.workspaces.Resolve(workspace, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.Tracker.Comment(ctx, key, body); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"workspace": ws.Name,
+		"key":       key,
+		"success":   true,
+	}, nil
+}
+
+// TicketTransitionTool moves a ticket to another status
+type TicketTransitionTool struct {
+	BaseTool
+	workspaces *tickets.Workspaces
+}
+
+// NewTicketTransitionTool creates a new ticket status tool
+func NewTicketTransitionTool(workspaces *tickets.Workspaces) *TicketTransitionTool {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Ticket key, e.g. LIN-423 or OPS-12",
+			},
+			"status": map[string]interface{}{
+				"type":        "string",
+				"description": "Target status as named in the workflow, e.g. In Review (the error lists the available ones)",
+			},
+			"workspace": ticketWorkspaceParam,
+		},
+		"required": []string{"key", "status"},
+	}
+	return &TicketTransitionTool{
+		BaseTool:   NewBaseTool("ticket_transition", "Move a Jira or Linear ticket to another workflow status, e.g. In Review or Done.", params),
+		workspaces: workspaces,
+	}
+}
+
+func (t *TicketTransitionTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	key, _ := args["key"].(string)
+	status, _ := args["status"].(string)
+	if strings.TrimSpace(status) == "" {
+		return nil, fmt.Errorf("status parameter is required")
+	}
+	workspace, _ := args["workspace"].(string)
+	ws, err := t.workspaces.Resolve(workspace, key)
+	if err != nil {
+		return nil, err
+	}
+	now, err := ws.Tracker.Transition(ctx, key, status)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"workspace": ws.Name,
+		"key":       key,
+		"status":    now,
+		"success":   true,
+	}, nil
+}
@@ -181,13 +181,8 @@ func NewWebFetchTool() *WebFetchTool {
 			params,
 		),
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 5 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+			Timeout:       30 * time.Second,
+			CheckRedirect: checkRedirect, // Redirects may not leave the egress policy
 		},
 	}
 }
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TRANSCRIPT INTEGRITY
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Providers require every tool call of an assistant message to be answered by
+// a tool message right after it, and reject tool messages that answer no call.
+// A crash, a cancel or an older version can leave a history that breaks this,
+// and every later request in the session fails. CheckTranscript finds such
+// problems; RepairTranscript fixes them: results are moved back after their
+// call, calls without one get an error result, and tool messages answering
+// nothing are dropped.
+
+// Kinds of transcript issues
+const (
+	IssueMissingResult   = "missing_result"   // A tool call has no result
+	IssueMisplacedResult = "misplaced_result" // A result is not right after its call
+	IssueOrphanedResult  = "orphaned_result"  // A tool message answers no call
+	IssueDuplicateResult = "duplicate_result" // A call has more than one result
+)
+
+// TranscriptIssue is an inconsistency in a session's history
+type TranscriptIssue struct {
+	Kind       string `json:"kind"`
+	Index      int    `json:"index"` // Message index in the checked history
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Tool       string `json:"tool,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// CheckTranscript returns the issues in a history (none if it is consistent)
+func CheckTranscript(messages []ai.Message) []TranscriptIssue {
+	_, _, issues := repairTranscript(messages, "")
+	return issues
+}
+
+// RepairTranscript fixes the session's history and returns what was wrong.
+// reason says why missing results were lost (e.g. "the run crashed").
+func (s *Session) RepairTranscript(reason string) []TranscriptIssue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repaired, newIndex, issues := repairTranscript(s.messages, reason)
+	if len(issues) == 0 {
+		return nil
+	}
+	s.messages = repaired
+	// Feedback points at assistant messages, which are never dropped
+	for i, fb := range s.feedback {
+		if fb.MessageIndex >= 0 && fb.MessageIndex < len(newIndex) {
+			s.feedback[i].MessageIndex = newIndex[fb.MessageIndex]
+		}
+	}
+	s.updatedAt = time.Now()
+	return issues
+}
+
+// repairTranscript returns the repaired history, the new index of each
+// message (-1 if dropped), and the issues found. Call IDs are only unique
+// within an assistant message (text-parsed calls are call_1, call_2, ... on
+// every step), so a result belongs to the latest call with its ID before it.
+func repairTranscript(messages []ai.Message, reason string) ([]ai.Message, []int, []TranscriptIssue) {
+	if reason == "" {
+		reason = "the run stopped before the tool finished"
+	}
+
+	// owner[j] is the assistant message whose call tool message j answers;
+	// results lists the tool messages answering each call
+	type callKey struct {
+		owner int
+		id    string
+	}
+	owner := make([]int, len(messages))
+	results := make(map[callKey][]int)
+	latest := make(map[string]int) // Call ID -> latest assistant message calling it
+	for i, msg := range messages {
+		owner[i] = -1
+		switch msg.Role {
+		case "assistant":
+			for _, call := range msg.ToolCalls {
+				latest[call.ID] = i
+			}
+		case "tool":
+			if a, ok := latest[msg.ToolCallID]; ok {
+				owner[i] = a
+				key := callKey{a, msg.ToolCallID}
+				results[key] = append(results[key], i)
+			}
+		}
+	}
+
+	var issues []TranscriptIssue
+	repaired := make([]ai.Message, 0, len(messages))
+	newIndex := make([]int, len(messages))
+	placed := make(map[int]bool) // Tool messages already put after their call
+	for i, msg := range messages {
+		if msg.Role == "tool" {
+			if placed[i] {
+				continue
+			}
+			newIndex[i] = -1
+			issue := TranscriptIssue{Kind: IssueOrphanedResult, Index: i, ToolCallID: msg.ToolCallID,
+				Detail: "tool result answers no tool call; dropped"}
+			if owner[i] >= 0 {
+				issue.Kind, issue.Detail = IssueDuplicateResult, fmt.Sprintf("tool call (message %d) already has a result; dropped", owner[i])
+			}
+			issues = append(issues, issue)
+			continue
+		}
+
+		newIndex[i] = len(repaired)
+		repaired = append(repaired, msg)
+		if msg.Role != "assistant" || len(msg.ToolCalls) == 0 {
+			continue
+		}
+
+		// The results of this message's calls follow it, in call order
+		end := i + 1
+		for end < len(messages) && messages[end].Role == "tool" {
+			end++
+		}
+		answered := make(map[string]bool)
+		for _, call := range msg.ToolCalls {
+			if answered[call.ID] {
+				continue
+			}
+			answered[call.ID] = true
+			found := -1
+			if js := results[callKey{i, call.ID}]; len(js) > 0 {
+				found = js[0]
+			}
+			if found < 0 {
+				errorJSON, _ := json.Marshal(map[string]interface{}{
+					"error":      fmt.Sprintf("%s result lost: %s", call.Name, reason),
+					"error_code": types.ErrInternal,
+				})
+				repaired = append(repaired, ai.Message{Role: "tool", Content: string(errorJSON), ToolCallID: call.ID})
+				issues = append(issues, TranscriptIssue{Kind: IssueMissingResult, Index: i, ToolCallID: call.ID, Tool: call.Name,
+					Detail: "tool call has no result; added an error result"})
+				continue
+			}
+			if found >= end {
+				issues = append(issues, TranscriptIssue{Kind: IssueMisplacedResult, Index: found, ToolCallID: call.ID, Tool: call.Name,
+					Detail: fmt.Sprintf("result is not right after its call (message %d); moved", i)})
+			}
+			placed[found] = true
+			newIndex[found] = len(repaired)
+			repaired = append(repaired, messages[found])
+		}
+	}
+	return repaired, newIndex, issues
+}
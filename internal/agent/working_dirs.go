@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// WORKING DIRECTORY ROOTS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// The gateway's limits.working_dirs keep sessions inside a set of roots.
+// Every way a session gets a working directory is checked against them: the
+// request's working_dir, the directory of a resumed session, the gateway's own
+// directory for a session without one, and a cd through exec.
+
+// WorkingDirRoots holds the roots session working dirs must be inside (per gateway instance)
+type WorkingDirRoots struct {
+	names []string // As configured, for errors
+	roots []string // Absolute, symlinks resolved
+	mu    sync.RWMutex
+}
+
+var globalWorkingDirRoots = &WorkingDirRoots{}
+
+// GetWorkingDirRoots returns the global working dir roots
+func GetWorkingDirRoots() *WorkingDirRoots {
+	return globalWorkingDirRoots
+}
+
+// NewWorkingDirRoots creates roots from configured directories
+func NewWorkingDirRoots(dirs []string) *WorkingDirRoots {
+	r := &WorkingDirRoots{}
+	r.Set(dirs)
+	return r
+}
+
+// Set replaces the roots (empty = any directory)
+func (r *WorkingDirRoots) Set(dirs []string) {
+	var roots []string
+	for _, dir := range dirs {
+		if abs, err := resolveDir(dir); err == nil {
+			roots = append(roots, abs)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = slices.Clone(dirs)
+	r.roots = roots
+}
+
+// Check returns an error unless dir is inside one of the roots (or none are set)
+func (r *WorkingDirRoots) Check(dir string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.names) == 0 {
+		return nil
+	}
+	abs, err := resolveDir(dir)
+	if err != nil {
+		return types.Errorf(types.ErrInvalidArgument, "invalid working dir %q: %v", dir, err)
+	}
+	for _, root := range r.roots {
+		if rel, err := filepath.Rel(root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return types.Errorf(types.ErrPermissionDenied, "working dir %q is outside the allowed roots (%s)", dir, strings.Join(r.names, ", "))
+}
+
+// resolveDir returns dir as an absolute path with ~, $VARS and symlinks
+// resolved (symlinks only as far as the path exists)
+func resolveDir(dir string) (string, error) {
+	abs, err := filepath.Abs(ExpandPath(dir))
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
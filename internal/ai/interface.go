@@ -1,6 +1,9 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Message represents a single message in the conversation
 type Message struct {
@@ -9,6 +12,8 @@ type Message struct {
 	ToolCalls  []ToolCall             `json:"tool_calls,omitempty"`
 	ToolCallID string                 `json:"tool_call_id,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Pinned     bool                   `json:"pinned,omitempty"` // Never trimmed from the context window
+	Time       time.Time              `json:"-"`                // When it was added to the session (not sent to providers)
 }
 
 // ToolCall represents a tool call in a message
@@ -42,11 +47,16 @@ type ChatRequest struct {
 	Model                   string        `json:"model,omitempty"`
 	Temperature             float64       `json:"temperature,omitempty"`
 	MaxTokens               int           `json:"max_tokens,omitempty"`
-	ContextLimit            int           `json:"context_limit,omitempty"`
+	TopP                    float64       `json:"top_p,omitempty"`
+	ContextLimit            int           `json:"context_limit,omitempty"`  // Token cap on history sent (0 = model's window)
 	Thinking                bool          `json:"thinking,omitempty"`       // Legacy: simple on/off
 	ThinkingLevel           ThinkingLevel `json:"thinking_level,omitempty"` // New: off/low/medium/high
 	QwenLargeContextEnabled bool          `json:"qwen_large_context_enabled,omitempty"`
 	Stream                  bool          `json:"stream,omitempty"` // Enable streaming
+
+	// ResponseSchema asks for a JSON answer matching this schema; providers with
+	// native structured output enforce it, others rely on the prompt
+	ResponseSchema map[string]interface{} `json:"response_schema,omitempty"`
 }
 
 // ChatResponse represents a chat response from an AI provider
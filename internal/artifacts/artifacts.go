@@ -0,0 +1,320 @@
+// Package artifacts uploads files to object storage (Amazon S3, Google
+// Cloud Storage and S3-compatible stores such as MinIO or R2) and returns
+// a URL to them, so results can leave the gateway host.
+//
+// Requests are signed with AWS Signature Version 4, which GCS accepts on
+// its XML API with HMAC keys.
+package artifacts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// DefaultGCSEndpoint is the XML API of Cloud Storage
+const DefaultGCSEndpoint = "https://storage.googleapis.com"
+
+// MaxExpiry is the longest lifetime of a presigned URL SigV4 allows
+const MaxExpiry = 7 * 24 * time.Hour
+
+// unsignedPayload is the payload hash of presigned URLs
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Store is a bucket artifacts are uploaded to
+type Store struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com, https://storage.googleapis.com
+	PathStyle       bool   // Address objects as Endpoint/Bucket/key instead of Bucket.host/key
+	Region          string
+	Bucket          string
+	Prefix          string // Prepended to object keys
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string        // Temporary credentials (AWS_SESSION_TOKEN)
+	PublicURL       string        // Base URL objects are served from; returned instead of a presigned URL
+	Expiry          time.Duration // Presigned URL lifetime
+	HTTP            *http.Client
+
+	now func() time.Time
+}
+
+// Config describes a store; see the artifacts section of the gateway config
+type Config struct {
+	Provider        string // s3 (default) or gcs
+	Bucket          string
+	Region          string
+	Endpoint        string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PublicURL       string
+	Expiry          time.Duration
+}
+
+// New creates a store, filling in the provider's endpoint and region
+func New(cfg Config) (*Store, error) {
+	if cfg.Bucket == "" {
+		return nil, types.Errorf(types.ErrInvalidArgument, "artifacts: bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, types.Errorf(types.ErrInvalidArgument, "artifacts: no credentials for bucket %s", cfg.Bucket)
+	}
+	s := &Store{
+		Region:          cfg.Region,
+		Bucket:          cfg.Bucket,
+		Prefix:          cfg.Prefix,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		SessionToken:    cfg.SessionToken,
+		PublicURL:       strings.TrimSuffix(cfg.PublicURL, "/"),
+		Expiry:          cfg.Expiry,
+		HTTP:            &http.Client{Timeout: 10 * time.Minute},
+	}
+	switch cfg.Provider {
+	case "", "s3":
+		if s.Region == "" {
+			s.Region = "us-east-1"
+		}
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+		// Bucket names with dots break TLS on virtual-hosted names
+		s.PathStyle = strings.Contains(s.Bucket, ".")
+	case "gcs":
+		if s.Region == "" {
+			s.Region = "auto"
+		}
+		s.Endpoint = DefaultGCSEndpoint
+		s.PathStyle = true
+	default:
+		return nil, types.Errorf(types.ErrInvalidArgument, "artifacts: unknown provider %q (use s3 or gcs)", cfg.Provider)
+	}
+	if cfg.Endpoint != "" {
+		s.Endpoint = cfg.Endpoint
+		s.PathStyle = true
+	}
+	s.Endpoint = strings.TrimSuffix(s.Endpoint, "/")
+	if s.Expiry <= 0 {
+		s.Expiry = 24 * time.Hour
+	}
+	s.Expiry = min(s.Expiry, MaxExpiry)
+	return s, nil
+}
+
+// Object is an uploaded artifact
+type Object struct {
+	Key     string    `json:"key"`
+	URL     string    `json:"url"`
+	Size    int64     `json:"size"`
+	Expires time.Time `json:"expires,omitzero"` // Zero for public URLs
+}
+
+// Put uploads body under Prefix+key and returns its URL. The body is read
+// twice: once to hash it, once to send it.
+func (s *Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) (*Object, error) {
+	key = s.Prefix + key
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return nil, err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), io.NopCloser(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, hex.EncodeToString(h.Sum(nil)), s.clock())
+
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return nil, types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, statusError(s.Bucket, resp, strings.TrimSpace(string(msg)))
+	}
+
+	obj := &Object{Key: key, Size: size}
+	if s.PublicURL != "" {
+		obj.URL = s.PublicURL + "/" + uriEncode(key, false)
+		return obj, nil
+	}
+	now := s.clock()
+	obj.URL = s.presign(key, now, s.Expiry)
+	obj.Expires = now.Add(s.Expiry).UTC()
+	return obj, nil
+}
+
+// statusError maps an HTTP error status of the store to an error code
+func statusError(bucket string, resp *http.Response, detail string) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return types.Errorf(types.ErrPermissionDenied, "bucket %s: %s: %s", bucket, resp.Status, detail)
+	case http.StatusNotFound:
+		return types.Errorf(types.ErrNotFound, "bucket %s not found: %s", bucket, detail)
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return types.Errorf(types.ErrRateLimited, "bucket %s: %s", bucket, resp.Status)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return types.Errorf(types.ErrInvalidArgument, "bucket %s: %s", bucket, detail)
+	}
+	return types.Errorf(types.ErrUnavailable, "bucket %s: %s: %s", bucket, resp.Status, detail)
+}
+
+func (s *Store) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// objectURL is the URL of an object, path-style or virtual-hosted
+func (s *Store) objectURL(key string) string {
+	if s.PathStyle {
+		return s.Endpoint + "/" + s.Bucket + "/" + uriEncode(key, false)
+	}
+	scheme, host, _ := strings.Cut(s.Endpoint, "://")
+	return scheme + "://" + s.Bucket + "." + host + "/" + uriEncode(key, false)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SIGNATURE VERSION 4
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// sign adds the Authorization header to a request whose body hashes to
+// payloadHash
+func (s *Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	request := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonical.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := s.scope(stamp)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signed, s.signature(stamp, request)))
+}
+
+// presign returns a GET URL of an object valid for expiry
+func (s *Store) presign(key string, now time.Time, expiry time.Duration) string {
+	u, _ := url.Parse(s.objectURL(key))
+	stamp := now.UTC().Format("20060102T150405Z")
+	q := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.AccessKeyID + "/" + s.scope(stamp)},
+		"X-Amz-Date":          {stamp},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if s.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	request := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery = canonicalQuery(q) + "&X-Amz-Signature=" + s.signature(stamp, request)
+	return u.String()
+}
+
+// scope is the credential scope of a request made at stamp
+func (s *Store) scope(stamp string) string {
+	return stamp[:8] + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request
+func (s *Store) signature(stamp, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + s.scope(stamp) + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), stamp[:8])
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 wants
+func canonicalQuery(q url.Values) string {
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), q[name]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters (and /
+// unless encodeSlash)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -2,10 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/neves/zen-claw/internal/i18n"
+	"github.com/neves/zen-claw/internal/privacy"
+	"github.com/neves/zen-claw/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,6 +19,7 @@ type Config struct {
 	Gateway          GatewayConfig          `yaml:"gateway"`
 	Agent            AgentConfig            `yaml:"agent"`
 	Providers        ProvidersConfig        `yaml:"providers"`
+	Encryption       EncryptionConfig       `yaml:"encryption,omitempty"` // Key of encrypted provider API keys (zen-claw config encrypt-keys)
 	Default          DefaultConfig          `yaml:"default"`
 	Workspace        WorkspaceConfig        `yaml:"workspace"`
 	Sessions         SessionsConfig         `yaml:"sessions"`
@@ -24,6 +31,337 @@ type Config struct {
 	MCP              MCPConfig              `yaml:"mcp"`
 	Routing          RoutingConfig          `yaml:"routing"`
 	CostOptimization CostOptimizationConfig `yaml:"cost_optimization"`
+	Tools            ToolsConfig            `yaml:"tools"`
+	Recording        RecordingConfig        `yaml:"recording"`
+	ModelParams      ModelParamsConfig      `yaml:"model_params"`
+	Update           UpdateConfig           `yaml:"update"`
+	Privacy          PrivacyConfig          `yaml:"privacy"`
+	Usage            UsageConfig            `yaml:"usage"`
+	Hooks            map[string]HookConfig  `yaml:"hooks"`          // Inbound webhooks (POST /hooks/{name}) that start agent tasks
+	GitHub           GitHubConfig           `yaml:"github"`         // GitHub integration behind hooks of kind github
+	Incident         IncidentConfig         `yaml:"incident"`       // Runbooks and cluster defaults of zen-claw incident
+	Tickets          TicketsConfig          `yaml:"tickets"`        // Jira and Linear workspaces of the ticket tools
+	Docs             DocsConfig             `yaml:"docs"`           // Confluence and Notion read by the docs tools
+	Artifacts        ArtifactsConfig        `yaml:"artifacts"`      // Bucket upload_artifact uploads to
+	Aliases          map[string]AliasConfig `yaml:"aliases"`        // zen-claw <alias>: agent runs with preset provider, model, tools and prompt
+	ModelProfiles    map[string]string      `yaml:"model_profiles"` // Model -> prompting profile, overriding ModelProfileInfo
+}
+
+// RecordingConfig configures the opt-in prompt/response recorder used to build
+// fine-tuning and eval datasets from real usage
+type RecordingConfig struct {
+	Enabled   bool     `yaml:"enabled"`   // Record agent runs (default false)
+	Dir       string   `yaml:"dir"`       // Output directory (default ~/.zen/zen-claw/data/recordings)
+	Providers []string `yaml:"providers"` // Only record these providers (empty = all)
+}
+
+// UpdateConfig configures zen-claw update and the new-release hint
+type UpdateConfig struct {
+	Channel      string `yaml:"channel"`       // "stable" (default) or "beta" (includes pre-releases)
+	DisableCheck bool   `yaml:"disable_check"` // Don't look for new releases in the background
+	PublicKey    string `yaml:"public_key"`    // Base64 ed25519 key release checksums must be signed with (default: the key built in)
+
+	// SkipSignature installs releases verified by checksum alone, for
+	// builds without an embedded public key
+	SkipSignature bool `yaml:"skip_signature"`
+}
+
+// PrivacyConfig scrubs personal data and internal hostnames from prompts
+// before they are sent to providers (the answers get the values back)
+type PrivacyConfig struct {
+	Scrub           bool            `yaml:"scrub"`            // Scrub prompts (default false)
+	Kinds           []string        `yaml:"kinds"`            // email, phone, credit_card, hostname (empty = all)
+	InternalDomains []string        `yaml:"internal_domains"` // Domains whose hosts are internal, besides .internal, .local, .corp, .lan
+	Projects        map[string]bool `yaml:"projects"`         // Per-workspace override of scrub, by project (owner/repo)
+}
+
+// ScrubProject reports whether prompts of a project's sessions are scrubbed
+func (p PrivacyConfig) ScrubProject(project string) bool {
+	for name, on := range p.Projects {
+		if strings.EqualFold(name, project) {
+			return on
+		}
+	}
+	return p.Scrub
+}
+
+// UsageConfig configures what the gateway does with the model usage it records
+type UsageConfig struct {
+	Push UsagePushConfig `yaml:"push"` // Periodic push to a cost-tracking endpoint
+}
+
+// UsagePushConfig sends recorded usage somewhere on an interval: new usage
+// records to a webhook (json, csv), or totals per provider/model/project to
+// a Prometheus pushgateway (prometheus)
+type UsagePushConfig struct {
+	URL          string            `yaml:"url"`           // Webhook or pushgateway URL (empty = off)
+	Format       string            `yaml:"format"`        // "json" (default), "csv" or "prometheus"
+	IntervalMins int               `yaml:"interval_mins"` // How often to push (default 60)
+	Headers      map[string]string `yaml:"headers"`       // Extra request headers (e.g. Authorization)
+	Job          string            `yaml:"job"`           // Pushgateway job name (default zen_claw)
+}
+
+// GetFormat returns the push format (json unless set)
+func (p UsagePushConfig) GetFormat() string {
+	if p.Format == "" {
+		return "json"
+	}
+	return strings.ToLower(p.Format)
+}
+
+// HookConfig turns requests to POST /hooks/{name} into agent tasks. The
+// template renders the task from the request: .Name, .Event (from
+// X-GitHub-Event, X-Gitlab-Event or X-Event-Key, else the payload's
+// event.event_type or webhookEvent), .Payload (the JSON body) and .Headers.
+// A template that renders nothing skips the request.
+//
+// A hook of kind "github" is a GitHub webhook (or GitHub App) endpoint
+// instead: its events are handled by the GitHub integration (see
+// GitHubConfig) and it needs no template.
+type HookConfig struct {
+	Kind       string   `yaml:"kind"`        // "" (task from the template) or "github"
+	Template   string   `yaml:"template"`    // Go text/template producing the task
+	Secret     string   `yaml:"secret"`      // Checks X-Hub-Signature-256 (GitHub), else an X-Hook-Token header equal to it
+	Events     []string `yaml:"events"`      // Only these events (empty = all)
+	Session    string   `yaml:"session"`     // Session the tasks run in, one after another (default hook-{name})
+	WorkingDir string   `yaml:"working_dir"` // Working directory of the tasks (default ".")
+	Provider   string   `yaml:"provider"`    // Default: default.provider
+	Model      string   `yaml:"model"`       // Default: the provider's model
+	MaxSteps   int      `yaml:"max_steps"`   // Default: agent.max_steps
+	Tags       []string `yaml:"tags"`        // Added to the session
+
+	// Insecure accepts requests to a hook without a secret, unchecked; it
+	// is required to leave the secret empty
+	Insecure bool `yaml:"insecure"`
+}
+
+// GitHubConfig configures the GitHub integration: pull requests of the
+// listed repos are reviewed when opened or pushed to, and the review is
+// posted back with inline comments; new issues are triaged (classified,
+// summarized and labeled). Events reach it through a hook of kind github,
+// whose secret checks their signature.
+type GitHubConfig struct {
+	Token         string                      `yaml:"token"`          // Token the integration acts as (GITHUB_TOKEN overrides)
+	APIURL        string                      `yaml:"api_url"`        // Default https://api.github.com (Enterprise: https://host/api/v3)
+	MaxConcurrent int                         `yaml:"max_concurrent"` // Reviews and triages running at once (default 2)
+	Repos         map[string]GitHubRepoConfig `yaml:"repos"`          // owner/repo -> settings; other repos are ignored
+}
+
+// GitHubRepoConfig configures the integration for one repository
+type GitHubRepoConfig struct {
+	Review       bool     `yaml:"review"`       // Review PRs on opened, reopened, ready_for_review and synchronize
+	Drafts       bool     `yaml:"drafts"`       // Review draft PRs too
+	Branches     []string `yaml:"branches"`     // Only PRs into these base branches (empty = all)
+	WorkingDir   string   `yaml:"working_dir"`  // Checkout the agent may read for context (empty = the diff or issue only)
+	Provider     string   `yaml:"provider"`     // Default: default.provider
+	Model        string   `yaml:"model"`        // Default: the provider's model
+	Instructions string   `yaml:"instructions"` // Added to the review prompt (house rules, focus areas)
+	MaxDiffKB    int      `yaml:"max_diff_kb"`  // Larger diffs are cut (default 200)
+
+	Triage              bool    `yaml:"triage"`                // Triage issues when opened
+	TriageMinConfidence float64 `yaml:"triage_min_confidence"` // Labels are applied at or above this confidence, else only proposed (default 0.8)
+}
+
+// GetTriageMinConfidence returns the confidence triage labels are applied at
+func (r GitHubRepoConfig) GetTriageMinConfidence() float64 {
+	if r.TriageMinConfidence <= 0 {
+		return 0.8
+	}
+	return r.TriageMinConfidence
+}
+
+// GetToken returns the GitHub token, GITHUB_TOKEN first
+func (g GitHubConfig) GetToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return g.Token
+}
+
+// GetMaxConcurrent returns how many reviews and triages may run at once
+func (g GitHubConfig) GetMaxConcurrent() int {
+	if g.MaxConcurrent <= 0 {
+		return 2
+	}
+	return g.MaxConcurrent
+}
+
+// IncidentConfig configures incident investigations (zen-claw incident)
+type IncidentConfig struct {
+	Runbooks  []string `yaml:"runbooks"`  // Directories of markdown runbooks searched by runbook_search
+	Namespace string   `yaml:"namespace"` // Namespace to start from (default: the kube context's)
+	Context   string   `yaml:"context"`   // kubeconfig context (default: the current one)
+}
+
+// TicketsConfig configures the ticket tools (ticket_search, ticket_get,
+// ticket_comment, ticket_transition)
+type TicketsConfig struct {
+	Default    string                           `yaml:"default"`    // Workspace for keys no workspace's projects claim
+	Workspaces map[string]TicketWorkspaceConfig `yaml:"workspaces"` // Name -> tracker
+}
+
+// TicketWorkspaceConfig is one Jira site or Linear workspace
+type TicketWorkspaceConfig struct {
+	Kind     string   `yaml:"kind"`      // jira or linear
+	URL      string   `yaml:"url"`       // Jira site, e.g. https://acme.atlassian.net
+	Email    string   `yaml:"email"`     // Jira Cloud account the API token belongs to (empty: token is a personal access token)
+	Token    string   `yaml:"token"`     // API token (Jira) or API key (Linear)
+	TokenEnv string   `yaml:"token_env"` // Env var holding the token, used before token
+	Projects []string `yaml:"projects"`  // Key prefixes of this workspace (OPS, LIN), used to route keys
+}
+
+// GetToken returns the workspace token, token_env first
+func (w TicketWorkspaceConfig) GetToken() string {
+	if w.TokenEnv != "" {
+		if token := os.Getenv(w.TokenEnv); token != "" {
+			return token
+		}
+	}
+	return w.Token
+}
+
+// DocsConfig configures the docs tools (docs_search, docs_fetch); a wiki
+// is used when its token is set
+type DocsConfig struct {
+	Confluence ConfluenceConfig `yaml:"confluence"`
+	Notion     NotionConfig     `yaml:"notion"`
+}
+
+// ConfluenceConfig is a Confluence site
+type ConfluenceConfig struct {
+	URL      string `yaml:"url"`       // Base URL: https://acme.atlassian.net/wiki (Cloud) or https://confluence.acme.com
+	Email    string `yaml:"email"`     // Cloud account the API token belongs to (empty: token is a personal access token)
+	Token    string `yaml:"token"`     // API token
+	TokenEnv string `yaml:"token_env"` // Env var holding the token, used before token
+}
+
+// GetToken returns the Confluence token, token_env first
+func (c ConfluenceConfig) GetToken() string {
+	if c.TokenEnv != "" {
+		if token := os.Getenv(c.TokenEnv); token != "" {
+			return token
+		}
+	}
+	return c.Token
+}
+
+// NotionConfig is a Notion integration; pages must be shared with it
+type NotionConfig struct {
+	Token    string `yaml:"token"`     // Internal integration secret
+	TokenEnv string `yaml:"token_env"` // Env var holding the token, used before token (NOTION_TOKEN when unset)
+}
+
+// GetToken returns the Notion token: token_env (default NOTION_TOKEN), else token
+func (n NotionConfig) GetToken() string {
+	env := n.TokenEnv
+	if env == "" {
+		env = "NOTION_TOKEN"
+	}
+	if token := os.Getenv(env); token != "" {
+		return token
+	}
+	return n.Token
+}
+
+// AliasConfig is a command alias: zen-claw <name> [task] runs the agent
+// with these settings. With a prompt, the task is the saved prompt rendered
+// with vars (and --var), followed by the task argument if given.
+type AliasConfig struct {
+	Description string            `yaml:"description"` // Shown in zen-claw --help
+	Provider    string            `yaml:"provider"`    // Default: default.provider
+	Model       string            `yaml:"model"`       // Default: the provider's model
+	Tools       string            `yaml:"tools"`       // "all" (default) or "read-only"
+	Prompt      string            `yaml:"prompt"`      // Saved prompt (zen-claw prompt) the task starts from
+	Vars        map[string]string `yaml:"vars"`        // Prompt variables (--var overrides)
+	MaxSteps    int               `yaml:"max_steps"`   // Default 100
+	Session     string            `yaml:"session"`     // Named session (default: fresh context)
+	Tags        []string          `yaml:"tags"`        // Added to the session
+}
+
+// aliasName is a valid alias: one command-line word
+var aliasName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ReadOnly reports whether the alias runs with the read-only toolset
+func (a AliasConfig) ReadOnly() bool {
+	return a.Tools == "read-only" || a.Tools == "readonly"
+}
+
+// ArtifactsConfig configures upload_artifact: the object storage bucket
+// generated reports, coverage and patches are uploaded to
+type ArtifactsConfig struct {
+	Provider        string `yaml:"provider"`          // s3 (default; also MinIO, R2) or gcs (HMAC keys)
+	Bucket          string `yaml:"bucket"`            // Bucket name (empty = no upload_artifact)
+	Region          string `yaml:"region"`            // Default us-east-1 (s3), auto (gcs)
+	Endpoint        string `yaml:"endpoint"`          // S3-compatible endpoint, e.g. http://minio:9000 (default: the provider's)
+	Prefix          string `yaml:"prefix"`            // Prepended to object keys (default zen-claw/)
+	AccessKeyID     string `yaml:"access_key_id"`     // AWS_ACCESS_KEY_ID (s3) or GCS_HMAC_ACCESS_KEY_ID (gcs) overrides
+	SecretAccessKey string `yaml:"secret_access_key"` // AWS_SECRET_ACCESS_KEY (s3) or GCS_HMAC_SECRET (gcs) overrides
+	PublicURL       string `yaml:"public_url"`        // Base URL the bucket is served from; returned instead of a presigned URL
+	URLExpiryHours  int    `yaml:"url_expiry_hours"`  // Presigned URL lifetime (default 24, max 168)
+	MaxSizeMB       int    `yaml:"max_size_mb"`       // Largest upload (default 100)
+}
+
+// GetCredentials returns the access key, secret and session token: the
+// provider's env vars first, else the config
+func (a ArtifactsConfig) GetCredentials() (id, secret, session string) {
+	idEnv, secretEnv := "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"
+	if a.Provider == "gcs" {
+		idEnv, secretEnv = "GCS_HMAC_ACCESS_KEY_ID", "GCS_HMAC_SECRET"
+	}
+	id, secret = os.Getenv(idEnv), os.Getenv(secretEnv)
+	if id == "" || secret == "" {
+		return a.AccessKeyID, a.SecretAccessKey, ""
+	}
+	if a.Provider != "gcs" {
+		session = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	return id, secret, session
+}
+
+// GetPrefix returns the key prefix (zen-claw/ unless set)
+func (a ArtifactsConfig) GetPrefix() string {
+	if a.Prefix == "" {
+		return "zen-claw/"
+	}
+	return a.Prefix
+}
+
+// ToolsConfig configures agent tool behavior
+type ToolsConfig struct {
+	// PostWriteHooks maps file extension (".go") to commands run after write_file/edit_file.
+	// {file} is replaced by the written file path. Unset uses built-in defaults (gofmt, prettier).
+	PostWriteHooks map[string][]string `yaml:"post_write_hooks"`
+	// DisablePostWriteHooks turns off all post-write hooks
+	DisablePostWriteHooks bool `yaml:"disable_post_write_hooks"`
+	// DisablePostProcess turns off cleanups of write/edit content before it is
+	// written: strip_fences, trailing_whitespace, newlines (all on by default)
+	DisablePostProcess []string `yaml:"disable_post_process"`
+	// Limits bounds CPU, memory, processes and file size of exec/process commands.
+	// Sessions may tighten these per request but never loosen them.
+	Limits types.ResourceLimits `yaml:"limits"`
+	// AuditLog appends a JSON line per tool call (tool, arguments, duration, error)
+	// to this file. Empty disables the audit log.
+	AuditLog string `yaml:"audit_log"`
+	// ProtectedPaths are globs tools may never modify (unset: .git/**, go.sum,
+	// secrets/**, *.pem, *.key; an empty list protects nothing)
+	ProtectedPaths []string `yaml:"protected_paths"`
+	// ExecApproval is which shell commands run without an operator: "all"
+	// (default) or "read-only" (mutating, network-egress and destructive
+	// commands are refused until approved for the session)
+	ExecApproval string `yaml:"exec_approval"`
+	// Egress limits which hosts web tools and shell commands may reach
+	Egress EgressConfig `yaml:"egress"`
+	// SecretRefs are the keyring:/op:// references a session's env may
+	// resolve: exact refs, or prefixes ending in "/" (keyring:myapp/,
+	// op://dev/). Unset refuses every reference; literals always work.
+	SecretRefs []string `yaml:"secret_refs"`
+}
+
+// EgressConfig is the network egress policy for tools. Patterns are hosts
+// (github.com), wildcards (*.github.com), IPs or CIDR ranges, or "*".
+type EgressConfig struct {
+	Allow []string `yaml:"allow"` // Only these hosts (empty = any host not denied)
+	Deny  []string `yaml:"deny"`  // Never these hosts (wins over allow)
 }
 
 // PluginsConfig configures the plugin system
@@ -59,24 +397,65 @@ type WebSearchConfig struct {
 }
 
 type SessionsConfig struct {
-	MaxSessions int    `yaml:"max_sessions"` // Maximum concurrent sessions (default 5)
-	DBPath      string `yaml:"db_path"`      // Path to session database (default ~/.zen/zen-claw/data/sessions.db)
+	MaxSessions int             `yaml:"max_sessions"` // Maximum concurrent sessions (default 5)
+	DBPath      string          `yaml:"db_path"`      // Path to session database (default ~/.zen/zen-claw/data/sessions.db)
+	Retention   RetentionConfig `yaml:"retention"`    // Automatic cleanup of stale sessions
+}
+
+// RetentionConfig bounds how long and how much session history is kept. The
+// gateway deletes unused sessions past these limits; sessions tagged "pinned"
+// and sessions listed in Keep are never deleted.
+type RetentionConfig struct {
+	MaxAgeDays   int      `yaml:"max_age_days"`  // Delete sessions unused for this many days (0 = no limit)
+	MaxTotalMB   int      `yaml:"max_total_mb"`  // Delete least recently used sessions while transcripts exceed this (0 = no limit)
+	IntervalMins int      `yaml:"interval_mins"` // How often the gateway enforces the policy (default 60)
+	DryRun       bool     `yaml:"dry_run"`       // Only log what would be deleted
+	Keep         []string `yaml:"keep"`          // Session names never deleted
+}
+
+// Enabled reports whether any retention limit is set
+func (r RetentionConfig) Enabled() bool {
+	return r.MaxAgeDays > 0 || r.MaxTotalMB > 0
 }
 
 // AgentConfig configures agent execution
 type AgentConfig struct {
-	MaxSteps           int `yaml:"max_steps"`            // Maximum tool execution steps (default 100)
-	MaxSubagents       int `yaml:"max_subagents"`        // Maximum concurrent subagents (default 4)
-	SubagentMaxSteps   int `yaml:"subagent_max_steps"`   // Max steps per subagent (default 50)
+	MaxSteps         int              `yaml:"max_steps"`          // Maximum tool execution steps (default 100)
+	MaxSubagents     int              `yaml:"max_subagents"`      // Maximum concurrent subagents (default 4)
+	SubagentMaxSteps int              `yaml:"subagent_max_steps"` // Max steps per subagent (default 50)
+	ProbeModels      *bool            `yaml:"probe_models"`       // Probe unknown models' capabilities on first use (default true)
+	Review           types.SelfReview `yaml:"review"`             // Review changes before finishing a task (off by default)
+	DirtyTree        string           `yaml:"dirty_tree"`         // Uncommitted changes the agent didn't make: warn (default), stash or off
 }
 
 // ConsensusConfig configures the consensus engine
 type ConsensusConfig struct {
-	Workers     []WorkerConfig `yaml:"workers"`      // Worker definitions for parallel calls
-	Arbiter     []string       `yaml:"arbiter"`      // Arbiter preference order (first available used)
-	MinWorkers  int            `yaml:"min_workers"`  // Minimum workers required (default 2)
-	MaxTokens   int            `yaml:"max_tokens"`   // Default max tokens per worker (default 4000)
-	Temperature float64        `yaml:"temperature"`  // Default temperature (default 0.7)
+	Workers     []WorkerConfig `yaml:"workers"`     // Worker definitions for parallel calls
+	Arbiter     []string       `yaml:"arbiter"`     // Arbiter preference order (first available used)
+	MinWorkers  int            `yaml:"min_workers"` // Minimum workers required (default 2)
+	MaxTokens   int            `yaml:"max_tokens"`  // Default max tokens per worker (default 4000)
+	Temperature float64        `yaml:"temperature"` // Default temperature (default 0.7)
+
+	// Cost controls: a run stops calling workers once its budget is spent
+	BudgetUSD    float64 `yaml:"budget_usd"`    // Estimated spend per run (0 = no limit)
+	BudgetTokens int     `yaml:"budget_tokens"` // Estimated tokens per run (0 = no limit)
+
+	// Tiering: cheap draft workers answer first, the workers refine the best drafts
+	DraftWorkers   []WorkerConfig `yaml:"draft_workers"`    // Draft tier (empty = no tiering)
+	DraftMaxTokens int            `yaml:"draft_max_tokens"` // Max tokens per draft (default 1500)
+	Finalists      int            `yaml:"finalists"`        // Drafts passed to the refine tier (default 2)
+
+	// Judging: an LLM judge scores the workers before synthesis
+	Judge        bool                        `yaml:"judge"`         // Judge every run (default false; --judge per run)
+	JudgePreset  string                      `yaml:"judge_preset"`  // Weighted criteria preset (code-quality, security, api-design, or custom)
+	JudgePresets map[string][]JudgeCriterion `yaml:"judge_presets"` // Custom presets; a built-in name replaces it
+}
+
+// JudgeCriterion is a weighted criterion of a judge preset
+type JudgeCriterion struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Weight      float64 `yaml:"weight"` // Relative; normalized over the preset
 }
 
 // WorkerConfig defines a consensus worker
@@ -122,6 +501,7 @@ type RoutingConfig struct {
 	ContextTiers   ContextTiersConfig `yaml:"context_tiers"`   // Context size tiers
 	PremiumBudget  float64            `yaml:"premium_budget"`  // Daily budget for premium models (USD)
 	RequireConfirm bool               `yaml:"require_confirm"` // Require confirmation for premium tier
+	ProjectBudgets map[string]float64 `yaml:"project_budgets"` // Daily spend cap per project (USD, keyed by project e.g. owner/repo)
 }
 
 // CostOptimizationConfig configures token-saving features
@@ -135,6 +515,9 @@ type CostOptimizationConfig struct {
 	MaxToolResultTokens int                       `yaml:"max_tool_result_tokens"` // Default max tokens per tool result
 	ToolRules           map[string]ToolRuleConfig `yaml:"tool_rules"`             // Per-tool pruning rules
 
+	// Measurement: tokens before/after each pass on a sample of requests (reported in /stats)
+	MeasureSamplePercent int `yaml:"measure_sample_percent"` // Share of requests measured, 0-100 (default 0 = off)
+
 	// Semantic cache settings
 	SemanticCacheEnabled    bool `yaml:"semantic_cache_enabled"`     // Enable semantic caching (default true)
 	SemanticCacheMinOverlap int  `yaml:"semantic_cache_min_overlap"` // Min keyword overlap (default 3)
@@ -170,6 +553,62 @@ var ProviderContextInfo = map[string]int{
 	"claude":   200000,  // Claude Sonnet: 200K
 }
 
+// ModelContextInfo holds context window sizes (tokens) for models that differ
+// from their provider's default in ProviderContextInfo
+var ModelContextInfo = map[string]int{
+	"deepseek-chat":                  128000,
+	"deepseek-reasoner":              128000,
+	"qwen3-coder-30b-a3b-instruct":   262144,
+	"qwen3-coder-480b-a35b-instruct": 262144,
+	"qwen-plus":                      131072,
+	"qwen-max":                       32768,
+	"kimi-k2-5":                      256000,
+	"glm-4.7":                        200000,
+	"minimax-M2.1":                   204800,
+	"gpt-4o":                         128000,
+	"gpt-4o-mini":                    128000,
+	"gpt-4.1":                        1047576,
+	"claude-3-haiku":                 200000,
+	"claude-3-sonnet":                200000,
+}
+
+// ModelProfileInfo names the prompting profile of models that need one other
+// than "chat" (see agent.PromptProfiles)
+var ModelProfileInfo = map[string]string{
+	"deepseek-reasoner":      "reasoning",
+	"deepseek-r1":            "reasoning-text", // No function calling
+	"kimi-k2-thinking":       "reasoning",
+	"kimi-k2-thinking-turbo": "reasoning",
+	"qwq-plus":               "reasoning",
+	"qwq-32b":                "reasoning",
+}
+
+// KnownModel reports whether the model registry knows the model: it has a
+// context window, a prompting profile or a configured profile
+func (c *Config) KnownModel(model string) bool {
+	_, hasWindow := ModelContextInfo[model]
+	_, hasProfile := ModelProfileInfo[model]
+	_, configured := c.ModelProfiles[model]
+	return hasWindow || hasProfile || configured
+}
+
+// GetModelProfile returns the prompting profile for a model: configured in
+// model_profiles, known in ModelProfileInfo, "reasoning" for other
+// reasoner/thinking models, else "chat"
+func (c *Config) GetModelProfile(model string) string {
+	if profile, ok := c.ModelProfiles[model]; ok {
+		return profile
+	}
+	if profile, ok := ModelProfileInfo[model]; ok {
+		return profile
+	}
+	lower := strings.ToLower(model)
+	if strings.Contains(lower, "reasoner") || strings.Contains(lower, "thinking") {
+		return "reasoning"
+	}
+	return "chat"
+}
+
 type ProvidersConfig struct {
 	Kimi      *ProviderConfig `yaml:"kimi,omitempty"`
 	OpenAI    *ProviderConfig `yaml:"openai,omitempty"`
@@ -178,6 +617,38 @@ type ProvidersConfig struct {
 	Minimax   *ProviderConfig `yaml:"minimax,omitempty"`
 	Qwen      *ProviderConfig `yaml:"qwen,omitempty"`
 	Anthropic *ProviderConfig `yaml:"anthropic,omitempty"`
+	Mock      *MockConfig     `yaml:"mock,omitempty"`
+}
+
+// MockConfig configures the mock provider, which replays model answers from
+// fixture files (no API key; for tests and CI)
+type MockConfig struct {
+	Fixtures string `yaml:"fixtures"` // Fixture directory
+	Record   string `yaml:"record"`   // Provider that answers requests without a fixture, recorded as new fixtures ("" = replay only)
+	Model    string `yaml:"model"`    // Model name in requests (default "mock")
+}
+
+// GetMock returns the mock provider's settings; ZEN_CLAW_MOCK_FIXTURES and
+// ZEN_CLAW_MOCK_RECORD override the config (nil = mock not configured)
+func (c *Config) GetMock() *MockConfig {
+	var mock MockConfig
+	if c.Providers.Mock != nil {
+		mock = *c.Providers.Mock
+	}
+	if dir := os.Getenv("ZEN_CLAW_MOCK_FIXTURES"); dir != "" {
+		mock.Fixtures = dir
+	}
+	if record := os.Getenv("ZEN_CLAW_MOCK_RECORD"); record != "" {
+		mock.Record = record
+	}
+	if mock.Fixtures == "" {
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(mock.Fixtures, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		mock.Fixtures = filepath.Join(home, rest)
+	}
+	return &mock
 }
 
 type ProviderConfig struct {
@@ -190,55 +661,170 @@ type DefaultConfig struct {
 	Provider string `yaml:"provider"`
 	Model    string `yaml:"model"`
 	Thinking bool   `yaml:"thinking"`
+	Language string `yaml:"language"` // Language tag for answers, e.g. pt-BR (default en)
 }
 
 type WorkspaceConfig struct {
 	Path string `yaml:"path"`
 }
 
+// ModelParamsConfig sets sampling defaults (temperature, max_tokens, top_p)
+// for model calls. The most specific layer wins: models, then providers, then
+// default; a request's own params beat all of them.
+type ModelParamsConfig struct {
+	Default   types.ModelParams            `yaml:"default"`
+	Providers map[string]types.ModelParams `yaml:"providers"` // By provider name
+	Models    map[string]types.ModelParams `yaml:"models"`    // By model name
+}
+
+// GetModelParams returns the configured params for a provider's model (zero
+// fields: nothing configured, callers fall back to their built-in defaults)
+func (c *Config) GetModelParams(provider, model string) types.ModelParams {
+	params := c.ModelParams.Default
+	params = params.Merge(c.ModelParams.Providers[provider])
+	return params.Merge(c.ModelParams.Models[model])
+}
+
 // GatewayConfig defines gateway server settings
 type GatewayConfig struct {
-	Host string `yaml:"host"` // Listen address (default: "")
-	Port int    `yaml:"port"` // Listen port (default: 8080)
+	Host   string              `yaml:"host"`   // Listen address (default: "" = all interfaces)
+	Port   int                 `yaml:"port"`   // Listen port (default: 8080)
+	Socket string              `yaml:"socket"` // Unix socket path; when set the gateway listens only there
+	Limits RequestLimitsConfig `yaml:"limits"` // Caps on what clients may request
+
+	// Requests per client; also changeable at runtime through /preferences
+	// by requests carrying OperatorToken
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// OperatorToken must be sent as X-Operator-Token to change the rate
+	// limits at runtime (empty = only the config sets them)
+	OperatorToken string `yaml:"operator_token"`
+
+	// How long a repeated Idempotency-Key on /chat returns the original
+	// result (default: 60)
+	IdempotencyTTLMins int `yaml:"idempotency_ttl_mins"`
+
+	// Reverse proxies (IPs or CIDR ranges) whose X-Forwarded-For identifies
+	// the client for per-client limits; from anywhere else it is ignored
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// RequestLimitsConfig caps client requests; violations are rejected with a
+// 4xx before reaching the agent. Zero values mean "no limit".
+type RequestLimitsConfig struct {
+	MaxBodyKB            int      `yaml:"max_body_kb"`             // Request body / WebSocket message size (default: 1024)
+	MaxSteps             int      `yaml:"max_steps"`               // Highest max_steps a client may ask for
+	WorkingDirs          []string `yaml:"working_dirs"`            // Roots working_dir must be inside (empty = any)
+	MaxSessionsPerClient int      `yaml:"max_sessions_per_client"` // Sessions one client may run at once
+}
+
+// RateLimitConfig limits how fast clients may send requests. A request with
+// an API key listed in Keys (X-API-Key header or Authorization: Bearer) is
+// limited per key at its tier's limits; everything else per address at the
+// default limits.
+type RateLimitConfig struct {
+	RequestsPerSecond float64                  `yaml:"requests_per_second" json:"requests_per_second"` // Per client (default 10)
+	Burst             int                      `yaml:"burst" json:"burst"`                             // Requests allowed at once (default 20)
+	Tiers             map[string]RateLimitTier `yaml:"tiers" json:"tiers,omitempty"`                   // Named limits for API keys
+	Keys              map[string]string        `yaml:"keys" json:"-"`                                  // API key -> tier
+}
+
+// RateLimitTier is a named rate limit API keys can be assigned to
+type RateLimitTier struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst"`
+}
+
+// Validate checks the limits are non-negative and every key's tier exists
+func (r RateLimitConfig) Validate() error {
+	if r.RequestsPerSecond < 0 || r.Burst < 0 {
+		return fmt.Errorf("requests_per_second and burst must be >= 0")
+	}
+	for name, tier := range r.Tiers {
+		if tier.RequestsPerSecond <= 0 || tier.Burst <= 0 {
+			return fmt.Errorf("tier %q: requests_per_second and burst must be > 0", name)
+		}
+	}
+	for _, tier := range r.Keys {
+		if _, ok := r.Tiers[tier]; !ok {
+			return fmt.Errorf("a key is assigned to unknown tier %q", tier)
+		}
+	}
+	return nil
+}
+
+// defaultMaxBodyKB bounds request bodies when max_body_kb is unset
+const defaultMaxBodyKB = 1024
+
+// GetMaxBodyBytes returns the request body size limit in bytes
+func (l RequestLimitsConfig) GetMaxBodyBytes() int64 {
+	if l.MaxBodyKB > 0 {
+		return int64(l.MaxBodyKB) * 1024
+	}
+	return defaultMaxBodyKB * 1024
 }
 
 // GetAddr returns the full listen address
 func (g *GatewayConfig) GetAddr() string {
+	if g.Socket != "" {
+		return g.GetSocket()
+	}
 	port := g.Port
 	if port == 0 {
 		port = 8080
 	}
-	return fmt.Sprintf("%s:%d", g.Host, port)
+	return net.JoinHostPort(g.Host, strconv.Itoa(port))
+}
+
+// GetSocket returns the unix socket path, ~ expanded ("" = listen on TCP)
+func (g *GatewayConfig) GetSocket() string {
+	if rest, ok := strings.CutPrefix(g.Socket, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return g.Socket
 }
 
-// GetURL returns the full HTTP URL for the gateway
+// clientHost returns the host clients connect to (localhost when the
+// gateway listens on all interfaces)
+func (g *GatewayConfig) clientHost() string {
+	if g.Host == "" || g.Host == "0.0.0.0" || g.Host == "::" {
+		return "localhost"
+	}
+	return g.Host
+}
+
+// GetURL returns the full HTTP URL for the gateway (unix:///path for a socket)
 func (g *GatewayConfig) GetURL() string {
+	if g.Socket != "" {
+		return "unix://" + g.GetSocket()
+	}
 	port := g.Port
 	if port == 0 {
 		port = 8080
 	}
-	host := g.Host
-	if host == "" {
-		host = "localhost"
-	}
-	return fmt.Sprintf("http://%s:%d", host, port)
+	return "http://" + net.JoinHostPort(g.clientHost(), strconv.Itoa(port))
 }
 
-// GetWSURL returns the WebSocket URL for the gateway
+// GetWSURL returns the WebSocket URL for the gateway (unix:///path for a
+// socket; the /ws path is implied)
 func (g *GatewayConfig) GetWSURL() string {
+	if g.Socket != "" {
+		return "unix://" + g.GetSocket()
+	}
 	port := g.Port
 	if port == 0 {
 		port = 8080
 	}
-	host := g.Host
-	if host == "" {
-		host = "localhost"
-	}
-	return fmt.Sprintf("ws://%s:%d/ws", host, port)
+	return "ws://" + net.JoinHostPort(g.clientHost(), strconv.Itoa(port)) + "/ws"
 }
 
-// DefaultConfigPath returns the default config path
+// DefaultConfigPath returns the default config path (ZEN_CLAW_CONFIG when it
+// names a file)
 func DefaultConfigPath() string {
+	if path := os.Getenv(EnvConfig); path != "" && !EnvOnly() {
+		return path
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "./zen-claw.yaml"
@@ -246,31 +832,38 @@ func DefaultConfigPath() string {
 	return filepath.Join(home, ".zen", "zen-claw", "config.yaml")
 }
 
-// LoadConfig loads configuration from file
+// LoadConfig loads configuration from file, with the ZEN_CLAW_* environment
+// variables applied on top (see env.go)
 func LoadConfig(path string) (*Config, error) {
-	if path == "" {
-		path = DefaultConfigPath()
-	}
-
-	// Check if config exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// Return default config
-		return NewDefaultConfig(), nil
-	}
+	config := NewDefaultConfig()
+	if path != "" || !EnvOnly() {
+		if path == "" {
+			path = DefaultConfigPath()
+		}
 
-	// Read config file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("read config file: %w", err)
+		// Read config file
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			// Keep the default config
+		case err != nil:
+			return nil, fmt.Errorf("read config file: %w", err)
+		default:
+			// Parse YAML
+			config = &Config{}
+			if err := yaml.Unmarshal(data, config); err != nil {
+				return nil, fmt.Errorf("parse config YAML: %w", err)
+			}
+			if err := config.decryptKeys(); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// Parse YAML
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parse config YAML: %w", err)
+	if err := config.ApplyEnv(); err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
 	}
-
-	return &config, nil
+	return config, nil
 }
 
 // SaveConfig saves configuration to file
@@ -332,9 +925,9 @@ func NewDefaultConfig() *Config {
 				{Provider: "minimax", Model: "minimax-M2.1"},
 			},
 			Arbiter:     []string{"kimi", "qwen", "deepseek"},
-			MinWorkers:  2,      // Minimum workers required
-			MaxTokens:   4000,   // Default max tokens per worker
-			Temperature: 0.7,   // Default temperature
+			MinWorkers:  2,    // Minimum workers required
+			MaxTokens:   4000, // Default max tokens per worker
+			Temperature: 0.7,  // Default temperature
 		},
 		Factory: FactoryConfig{
 			Specialists: map[string]SpecialistConfig{
@@ -425,6 +1018,7 @@ func (c *Config) Validate() error {
 		validProviders := map[string]bool{
 			"deepseek": true, "qwen": true, "glm": true,
 			"minimax": true, "openai": true, "kimi": true, "anthropic": true,
+			"mock": c.GetMock() != nil,
 		}
 		if !validProviders[strings.ToLower(c.Default.Provider)] {
 			errs = append(errs, ValidationError{
@@ -434,6 +1028,136 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Default.Language != "" && i18n.Normalize(c.Default.Language) == "" {
+		errs = append(errs, ValidationError{
+			Field:   "default.language",
+			Message: fmt.Sprintf("invalid language tag %q (e.g. en, pt-BR)", c.Default.Language),
+		})
+	}
+
+	if ch := c.Update.Channel; ch != "" && ch != "stable" && ch != "beta" {
+		errs = append(errs, ValidationError{
+			Field:   "update.channel",
+			Message: fmt.Sprintf("unknown channel %q (use stable or beta)", ch),
+		})
+	}
+
+	if f := c.Usage.Push.GetFormat(); f != "json" && f != "csv" && f != "prometheus" {
+		errs = append(errs, ValidationError{
+			Field:   "usage.push.format",
+			Message: fmt.Sprintf("unknown format %q (use json, csv or prometheus)", c.Usage.Push.Format),
+		})
+	}
+	if c.Usage.Push.IntervalMins < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "usage.push.interval_mins",
+			Message: "must be >= 0",
+		})
+	}
+
+	for name, hook := range c.Hooks {
+		if hook.Kind != "" && hook.Kind != "github" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("hooks[%s].kind", name),
+				Message: fmt.Sprintf("unknown kind %q (use github, or leave empty for a template)", hook.Kind),
+			})
+		}
+		if hook.Kind == "" && strings.TrimSpace(hook.Template) == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("hooks[%s].template", name),
+				Message: "is required",
+			})
+		}
+		if hook.Secret == "" && !hook.Insecure {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("hooks[%s].secret", name),
+				Message: "is required (or set insecure: true to accept unauthenticated requests)",
+			})
+		}
+		if strings.ContainsAny(name, "/?#") || name == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("hooks[%s]", name),
+				Message: "name must be a single path segment",
+			})
+		}
+	}
+
+	for name, ws := range c.Tickets.Workspaces {
+		switch ws.Kind {
+		case "jira":
+			if ws.URL == "" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("tickets.workspaces[%s].url", name),
+					Message: "is required for jira",
+				})
+			}
+		case "linear":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("tickets.workspaces[%s].kind", name),
+				Message: fmt.Sprintf("unknown kind %q (use jira or linear)", ws.Kind),
+			})
+		}
+	}
+	if c.Docs.Confluence.GetToken() != "" && c.Docs.Confluence.URL == "" {
+		errs = append(errs, ValidationError{
+			Field:   "docs.confluence.url",
+			Message: "is required with a token",
+		})
+	}
+
+	for name, alias := range c.Aliases {
+		if !aliasName.MatchString(name) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("aliases[%s]", name),
+				Message: "name must be a single word of letters, digits, - and _",
+			})
+		}
+		switch alias.Tools {
+		case "", "all", "read-only", "readonly":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("aliases[%s].tools", name),
+				Message: fmt.Sprintf("unknown toolset %q (use all or read-only)", alias.Tools),
+			})
+		}
+	}
+
+	if a := c.Artifacts; a.Bucket != "" {
+		if a.Provider != "" && a.Provider != "s3" && a.Provider != "gcs" {
+			errs = append(errs, ValidationError{
+				Field:   "artifacts.provider",
+				Message: fmt.Sprintf("unknown provider %q (use s3 or gcs)", a.Provider),
+			})
+		}
+		if a.URLExpiryHours < 0 || a.URLExpiryHours > 168 {
+			errs = append(errs, ValidationError{
+				Field:   "artifacts.url_expiry_hours",
+				Message: "must be between 1 and 168 (7 days)",
+			})
+		}
+	}
+
+	if key := c.Encryption.Key; key != "" {
+		switch kind, _, _ := strings.Cut(key, ":"); kind {
+		case "keyring", "age", "awskms", "env":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   "encryption.key",
+				Message: fmt.Sprintf("unknown key source %q (use keyring:<service>/<account>, age:<identity file>, awskms:<key id> or env)", key),
+			})
+		}
+	}
+
+	if d := c.Tickets.Default; d != "" {
+		if _, ok := c.Tickets.Workspaces[d]; !ok {
+			errs = append(errs, ValidationError{
+				Field:   "tickets.default",
+				Message: fmt.Sprintf("unknown workspace %q", d),
+			})
+		}
+	}
+
 	// Validate sessions config
 	if c.Sessions.MaxSessions < 0 {
 		errs = append(errs, ValidationError{
@@ -441,6 +1165,93 @@ func (c *Config) Validate() error {
 			Message: "must be >= 0",
 		})
 	}
+	if c.Sessions.Retention.MaxAgeDays < 0 || c.Sessions.Retention.MaxTotalMB < 0 || c.Sessions.Retention.IntervalMins < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "sessions.retention",
+			Message: "max_age_days, max_total_mb and interval_mins must be >= 0",
+		})
+	}
+
+	if c.Gateway.Port < 0 || c.Gateway.Port > 65535 {
+		errs = append(errs, ValidationError{
+			Field:   "gateway.port",
+			Message: "must be between 0 and 65535",
+		})
+	}
+
+	if err := c.Gateway.RateLimit.Validate(); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   "gateway.rate_limit",
+			Message: err.Error(),
+		})
+	}
+
+	if c.Gateway.IdempotencyTTLMins < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "gateway.idempotency_ttl_mins",
+			Message: "must be >= 0",
+		})
+	}
+
+	if l := c.Gateway.Limits; l.MaxBodyKB < 0 || l.MaxSteps < 0 || l.MaxSessionsPerClient < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "gateway.limits",
+			Message: "max_body_kb, max_steps and max_sessions_per_client must be >= 0",
+		})
+	}
+
+	if d := c.Agent.DirtyTree; d != "" && d != "warn" && d != "stash" && d != "off" {
+		errs = append(errs, ValidationError{
+			Field:   "agent.dirty_tree",
+			Message: fmt.Sprintf("unknown policy %q (use warn, stash or off)", d),
+		})
+	}
+
+	if _, err := privacy.New(c.Privacy.Kinds, c.Privacy.InternalDomains); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   "privacy.kinds",
+			Message: err.Error(),
+		})
+	}
+
+	if a := c.Tools.ExecApproval; a != "" && a != "all" && a != "read-only" {
+		errs = append(errs, ValidationError{
+			Field:   "tools.exec_approval",
+			Message: fmt.Sprintf("unknown policy %q (use all or read-only)", a),
+		})
+	}
+	for _, name := range c.Tools.DisablePostProcess {
+		if name != "strip_fences" && name != "trailing_whitespace" && name != "newlines" {
+			errs = append(errs, ValidationError{
+				Field:   "tools.disable_post_process",
+				Message: fmt.Sprintf("unknown post-processor %q (use strip_fences, trailing_whitespace or newlines)", name),
+			})
+		}
+	}
+
+	// Validate model params
+	checkParams := func(field string, p types.ModelParams) {
+		if p.Temperature < 0 || p.Temperature > 2 || p.TopP < 0 || p.TopP > 1 || p.MaxTokens < 0 {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "temperature must be 0-2, top_p 0-1 and max_tokens >= 0",
+			})
+		}
+	}
+	checkParams("model_params.default", c.ModelParams.Default)
+	for name, p := range c.ModelParams.Providers {
+		checkParams(fmt.Sprintf("model_params.providers[%s]", name), p)
+	}
+	for name, p := range c.ModelParams.Models {
+		checkParams(fmt.Sprintf("model_params.models[%s]", name), p)
+	}
+
+	if c.CostOptimization.MeasureSamplePercent < 0 || c.CostOptimization.MeasureSamplePercent > 100 {
+		errs = append(errs, ValidationError{
+			Field:   "cost_optimization.measure_sample_percent",
+			Message: "must be between 0 and 100",
+		})
+	}
 
 	// Validate consensus workers
 	for i, w := range c.Consensus.Workers {
@@ -473,6 +1284,14 @@ func (c *Config) Validate() error {
 			Message: "must be >= 0",
 		})
 	}
+	for project, budget := range c.Routing.ProjectBudgets {
+		if budget <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("routing.project_budgets[%s]", project),
+				Message: "must be > 0",
+			})
+		}
+	}
 
 	// Validate MCP servers
 	for i, s := range c.MCP.Servers {
@@ -557,6 +1376,16 @@ func (c *Config) GetPluginDir() string {
 	return filepath.Join(home, ".zen", "zen-claw", "plugins")
 }
 
+// GetRecordingDir returns the recordings directory
+func (c *Config) GetRecordingDir() string {
+	if c.Recording.Dir != "" {
+		return c.Recording.Dir
+	}
+	// Default: ~/.zen/zen-claw/data/recordings
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "data", "recordings")
+}
+
 // GetAPIKey returns the API key for a provider from config or environment
 func (c *Config) GetAPIKey(provider string) string {
 	// First check environment variables
@@ -648,11 +1477,25 @@ func (c *Config) GetModel(provider string) string {
 			return c.Providers.Anthropic.Model
 		}
 		return "claude-sonnet-4-20250514" // Claude Sonnet 4: 200K context, prompt caching
+	case "mock":
+		if c.Providers.Mock != nil && c.Providers.Mock.Model != "" {
+			return c.Providers.Mock.Model
+		}
+		return "mock"
 	default:
 		return c.Default.Model
 	}
 }
 
+// GetPostWriteHooks returns configured post-write hooks, or nil to keep the built-in defaults.
+// Returns an empty map when hooks are disabled.
+func (c *Config) GetPostWriteHooks() map[string][]string {
+	if c.Tools.DisablePostWriteHooks {
+		return map[string][]string{}
+	}
+	return c.Tools.PostWriteHooks
+}
+
 // GetMCPServers returns enabled MCP server configs
 func (c *Config) GetMCPServers() []MCPServerConfig {
 	var enabled []MCPServerConfig
@@ -729,6 +1572,15 @@ func GetProviderContextLimit(provider string) int {
 	return 128000 // Default assumption
 }
 
+// GetModelContextLimit returns the context window of a provider's model,
+// falling back to the provider's limit for unknown models
+func GetModelContextLimit(provider, model string) int {
+	if limit, ok := ModelContextInfo[model]; ok {
+		return limit
+	}
+	return GetProviderContextLimit(provider)
+}
+
 // CanProviderHandleContext returns whether a provider can handle the given context size
 func CanProviderHandleContext(provider string, tokenCount int) bool {
 	limit := GetProviderContextLimit(provider)
@@ -743,6 +1595,11 @@ func (c *Config) GetMaxSteps() int {
 	return 100 // Default
 }
 
+// ProbeModelsEnabled reports whether unknown models are probed on first use
+func (c *Config) ProbeModelsEnabled() bool {
+	return c.Agent.ProbeModels == nil || *c.Agent.ProbeModels
+}
+
 // GetMaxSubagents returns the max concurrent subagents
 func (c *Config) GetMaxSubagents() int {
 	if c.Agent.MaxSubagents > 0 {
@@ -775,6 +1632,22 @@ func (c *Config) GetConsensusMaxTokens() int {
 	return 4000 // Default
 }
 
+// GetConsensusDraftMaxTokens returns max tokens for draft tier workers
+func (c *Config) GetConsensusDraftMaxTokens() int {
+	if c.Consensus.DraftMaxTokens > 0 {
+		return c.Consensus.DraftMaxTokens
+	}
+	return 1500 // Default
+}
+
+// GetConsensusFinalists returns how many drafts the refine tier gets
+func (c *Config) GetConsensusFinalists() int {
+	if c.Consensus.Finalists > 0 {
+		return c.Consensus.Finalists
+	}
+	return 2 // Default
+}
+
 // GetConsensusTemperature returns default temperature for consensus
 func (c *Config) GetConsensusTemperature() float64 {
 	if c.Consensus.Temperature > 0 {
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"encoding/base64"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
 )
 
 func TestDefaultConfigPath(t *testing.T) {
@@ -182,6 +186,78 @@ func TestGetModel(t *testing.T) {
 	}
 }
 
+func TestGetModelParams(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.ModelParams = ModelParamsConfig{
+		Default:   types.ModelParams{Temperature: 0.7, MaxTokens: 4000},
+		Providers: map[string]types.ModelParams{"deepseek": {Temperature: 0.5, TopP: 0.9}},
+		Models:    map[string]types.ModelParams{"deepseek-reasoner": {MaxTokens: 16000}},
+	}
+
+	tests := []struct {
+		provider, model string
+		want            types.ModelParams
+	}{
+		{"openai", "gpt-4o", types.ModelParams{Temperature: 0.7, MaxTokens: 4000}},
+		{"deepseek", "deepseek-chat", types.ModelParams{Temperature: 0.5, MaxTokens: 4000, TopP: 0.9}},
+		{"deepseek", "deepseek-reasoner", types.ModelParams{Temperature: 0.5, MaxTokens: 16000, TopP: 0.9}},
+	}
+	for _, tt := range tests {
+		if got := cfg.GetModelParams(tt.provider, tt.model); got != tt.want {
+			t.Errorf("GetModelParams(%s, %s) = %+v, want %+v", tt.provider, tt.model, got, tt.want)
+		}
+	}
+
+	// Request overrides win over every layer
+	got := cfg.GetModelParams("deepseek", "deepseek-reasoner").Merge(types.ModelParams{Temperature: 0.1})
+	if got.Temperature != 0.1 || got.MaxTokens != 16000 {
+		t.Errorf("with override = %+v", got)
+	}
+
+	cfg.ModelParams.Models["bad"] = types.ModelParams{TopP: 1.5}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for top_p > 1")
+	}
+}
+
+func TestGetModelProfile(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.ModelProfiles = map[string]string{"my-finetune": "reasoning-text"}
+
+	tests := map[string]string{
+		"deepseek-reasoner": "reasoning",
+		"deepseek-r1":       "reasoning-text",
+		"glm-4-thinking":    "reasoning",
+		"gpt-4o":            "chat",
+		"my-finetune":       "reasoning-text",
+	}
+	for model, want := range tests {
+		if got := cfg.GetModelProfile(model); got != want {
+			t.Errorf("GetModelProfile(%s) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestScrubProject(t *testing.T) {
+	p := PrivacyConfig{Scrub: true, Projects: map[string]bool{"acme/public-site": false, "acme/billing": true}}
+	tests := map[string]bool{
+		"":                 true,
+		"acme/api":         true,
+		"ACME/Public-Site": false,
+		"acme/billing":     true,
+	}
+	for project, want := range tests {
+		if got := p.ScrubProject(project); got != want {
+			t.Errorf("ScrubProject(%q) = %v, want %v", project, got, want)
+		}
+	}
+
+	p.Scrub = false
+	if p.ScrubProject("acme/api") || !p.ScrubProject("acme/billing") {
+		t.Error("with scrub off, only projects turned on should be scrubbed")
+	}
+}
+
 func TestGetMaxSessions(t *testing.T) {
 	t.Run("returns configured value", func(t *testing.T) {
 		cfg := NewDefaultConfig()
@@ -201,6 +277,31 @@ func TestGetMaxSessions(t *testing.T) {
 	})
 }
 
+func TestGatewayAddresses(t *testing.T) {
+	tests := []struct {
+		gateway          GatewayConfig
+		addr, url, wsURL string
+	}{
+		{GatewayConfig{}, ":8080", "http://localhost:8080", "ws://localhost:8080/ws"},
+		{GatewayConfig{Host: "127.0.0.1", Port: 9000}, "127.0.0.1:9000", "http://127.0.0.1:9000", "ws://127.0.0.1:9000/ws"},
+		{GatewayConfig{Host: "0.0.0.0", Port: 9000}, "0.0.0.0:9000", "http://localhost:9000", "ws://localhost:9000/ws"},
+		{GatewayConfig{Host: "::1"}, "[::1]:8080", "http://[::1]:8080", "ws://[::1]:8080/ws"},
+		{GatewayConfig{Port: 9000, Socket: "/run/zc.sock"}, "/run/zc.sock", "unix:///run/zc.sock", "unix:///run/zc.sock"},
+	}
+	for _, tt := range tests {
+		g := tt.gateway
+		if got := g.GetAddr(); got != tt.addr {
+			t.Errorf("%+v: GetAddr() = %q, want %q", g, got, tt.addr)
+		}
+		if got := g.GetURL(); got != tt.url {
+			t.Errorf("%+v: GetURL() = %q, want %q", g, got, tt.url)
+		}
+		if got := g.GetWSURL(); got != tt.wsURL {
+			t.Errorf("%+v: GetWSURL() = %q, want %q", g, got, tt.wsURL)
+		}
+	}
+}
+
 func TestContextTier(t *testing.T) {
 	cfg := NewDefaultConfig()
 
@@ -288,6 +389,38 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("unknown usage push format", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.Usage.Push.Format = "xml"
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() expected error for unknown usage.push.format")
+		}
+	})
+
+	t.Run("alias", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.Aliases = map[string]AliasConfig{"review-go": {Provider: "qwen", Tools: "read-only"}}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+		cfg.Aliases = map[string]AliasConfig{"review go": {}, "fix": {Tools: "write-only"}}
+		if verrs, ok := cfg.Validate().(ValidationErrors); !ok || len(verrs) != 2 {
+			t.Errorf("Validate() = %v, want errors for the name and the toolset", verrs)
+		}
+	})
+
+	t.Run("hook without secret", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.Hooks = map[string]HookConfig{"ci": {Template: "Check CI"}}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "hooks[ci].secret") {
+			t.Errorf("Validate() = %v, want an error for the missing secret", err)
+		}
+		cfg.Hooks = map[string]HookConfig{"ci": {Template: "Check CI", Insecure: true}}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil for an insecure hook", err)
+		}
+	})
+
 	t.Run("multiple errors", func(t *testing.T) {
 		cfg := NewDefaultConfig()
 		cfg.Default.Provider = ""
@@ -309,3 +442,121 @@ func TestValidate(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && (s[0:len(substr)] == substr || contains(s[1:], substr)))
 }
+
+func TestLoadRepoConfig(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "services", "api")
+	os.MkdirAll(sub, 0755)
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+
+	// Above the git root it isn't looked for
+	os.WriteFile(filepath.Join(filepath.Dir(root), RepoConfigFile), []byte("tools: read-only\n"), 0644)
+	defer os.Remove(filepath.Join(filepath.Dir(root), RepoConfigFile))
+	if repo, err := LoadRepoConfig(sub); repo != nil || err != nil {
+		t.Fatalf("LoadRepoConfig without a file = %+v, %v", repo, err)
+	}
+
+	os.WriteFile(filepath.Join(root, RepoConfigFile), []byte("model: deepseek-reasoner\ntools: read-only\nmax_steps: 20\nprotected_paths: [\"/migrations/**\"]\ninstructions: Run make test before finishing.\n"), 0644)
+	repo, err := LoadRepoConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Root != root || repo.Model != "deepseek-reasoner" || !repo.ReadOnly() || repo.MaxSteps != 20 || len(repo.ProtectedPaths) != 1 {
+		t.Errorf("LoadRepoConfig = %+v", repo)
+	}
+
+	os.WriteFile(filepath.Join(root, RepoConfigFile), []byte("tools: everything\nbudget_usd: -1\n"), 0644)
+	if _, err := LoadRepoConfig(root); err == nil {
+		t.Error("invalid repo config accepted")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("default:\n  provider: qwen\ngateway:\n  port: 7000\n"), 0644)
+
+	t.Setenv("ZEN_CLAW_GATEWAY_PORT", "9090")
+	t.Setenv("ZEN_CLAW_KIMI_API_KEY", "sk-kimi")
+	t.Setenv("KIMI_API_KEY", "")
+	t.Setenv("ZEN_CLAW_PROJECT_BUDGETS", "acme/api=5, acme/web=2.5")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Default.Provider != "qwen" || cfg.Gateway.Port != 9090 || cfg.GetAPIKey("kimi") != "sk-kimi" || cfg.Routing.ProjectBudgets["acme/web"] != 2.5 {
+		t.Errorf("file + env = provider %s, port %d, kimi key %q, budgets %v", cfg.Default.Provider, cfg.Gateway.Port, cfg.GetAPIKey("kimi"), cfg.Routing.ProjectBudgets)
+	}
+
+	// Environment only: the file named by ZEN_CLAW_CONFIG isn't read
+	t.Setenv(EnvConfig, "env")
+	t.Setenv("ZEN_CLAW_DEFAULT_PROVIDER", "kimi")
+	cfg, err = LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Default.Provider != "kimi" || cfg.Gateway.Port != 9090 || cfg.Agent.MaxSteps != 100 {
+		t.Errorf("env only = provider %s, port %d, max steps %d", cfg.Default.Provider, cfg.Gateway.Port, cfg.Agent.MaxSteps)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("env only config invalid: %v", err)
+	}
+
+	t.Setenv(EnvConfig, path)
+	if DefaultConfigPath() != path {
+		t.Errorf("DefaultConfigPath = %s, want ZEN_CLAW_CONFIG", DefaultConfigPath())
+	}
+
+	for name, value := range map[string]string{"ZEN_CLAW_GATEWAY_PORT": "http", "ZEN_CLAW_PROJECT_BUDGETS": "acme/api"} {
+		t.Setenv(name, value)
+		if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s=%s: %v", name, value, err)
+		}
+		t.Setenv(name, "")
+	}
+}
+
+func TestEncryptKeys(t *testing.T) {
+	key, _ := NewDataKey()
+	sealed, err := Encrypt(key, "sk-secret")
+	if err != nil || !IsEncrypted(sealed) || strings.Contains(sealed, "sk-secret") {
+		t.Fatalf("Encrypt = %q, %v", sealed, err)
+	}
+	if got, err := Decrypt(key, sealed); err != nil || got != "sk-secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	other, _ := NewDataKey()
+	if _, err := Decrypt(other, sealed); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("# keep me\ndefault:\n  provider: kimi\nproviders:\n  kimi:\n    api_key: sk-kimi # the key\n  qwen:\n    api_key: ${QWEN_API_KEY}\n"), 0644)
+	t.Setenv(EnvConfigKey, base64.StdEncoding.EncodeToString(key))
+	t.Setenv("KIMI_API_KEY", "")
+	n, envKey, err := EncryptKeys(path, "env")
+	if err != nil || n != 1 || envKey != "" {
+		t.Fatalf("EncryptKeys = %d, %q, %v", n, envKey, err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-kimi") || !strings.Contains(string(data), "# keep me") || !strings.Contains(string(data), "${QWEN_API_KEY}") {
+		t.Errorf("encrypted file:\n%s", data)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil || cfg.GetAPIKey("kimi") != "sk-kimi" || cfg.Encryption.Key != "env" {
+		t.Fatalf("LoadConfig = %+v, %v", cfg, err)
+	}
+	cfg.Providers.Qwen.APIKey = "sk-qwen-0123456789"
+	cfg.MaskKeys()
+	if cfg.Providers.Kimi.APIKey != "…" || cfg.Providers.Qwen.APIKey != "sk-q…6789" {
+		t.Errorf("masked keys = %q, %q", cfg.Providers.Kimi.APIKey, cfg.Providers.Qwen.APIKey)
+	}
+	if _, _, err := EncryptKeys(path, "keyring:zen-claw/other"); err == nil {
+		t.Error("switched key source of encrypted keys")
+	}
+
+	t.Setenv(EnvConfigKey, base64.StdEncoding.EncodeToString(other))
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "providers.kimi.api_key") {
+		t.Errorf("LoadConfig with the wrong key: %v", err)
+	}
+}
@@ -0,0 +1,449 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ENCRYPTED API KEYS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Provider API keys can be stored encrypted (zen-claw config encrypt-keys):
+//
+//	encryption:
+//	  key: keyring:zen-claw/config-key
+//	providers:
+//	  kimi:
+//	    api_key: enc:v1:9Xk2...
+//
+// Values are sealed with AES-256-GCM under a random data key, which lives in
+// the OS keyring or is itself encrypted (wrapped_key) to an age identity or a
+// KMS key. LoadConfig decrypts them; ZEN_CLAW_CONFIG_KEY (the base64 data
+// key) takes the place of the key source, e.g. in a container.
+
+// EnvConfigKey is the variable holding the base64 data key
+const EnvConfigKey = "ZEN_CLAW_CONFIG_KEY"
+
+// encryptedPrefix marks an encrypted value (base64 of nonce + ciphertext follows)
+const encryptedPrefix = "enc:v1:"
+
+// keyCommandTimeout bounds a keyring, age or KMS call
+const keyCommandTimeout = 30 * time.Second
+
+// EncryptionConfig says where the data key of encrypted values comes from
+type EncryptionConfig struct {
+	Key        string `yaml:"key"`                   // keyring:<service>/<account>, age:<identity file>, awskms:<key id> or env (ZEN_CLAW_CONFIG_KEY)
+	WrappedKey string `yaml:"wrapped_key,omitempty"` // The data key encrypted to the age identity or KMS key
+}
+
+// DefaultKeySource is where encrypt-keys keeps a new data key
+const DefaultKeySource = "keyring:zen-claw/config-key"
+
+// IsEncrypted reports whether a config value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// NewDataKey returns a random 256-bit data key
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt seals a value with the data key
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt
+func Decrypt(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("wrong key or tampered value")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// dataKeys caches data keys per source, so a process asks the keyring or
+// KMS once
+var dataKeys sync.Map
+
+// DataKey returns the data key: ZEN_CLAW_CONFIG_KEY, else the one from the
+// key source
+func (e EncryptionConfig) DataKey() ([]byte, error) {
+	if encoded := os.Getenv(EnvConfigKey); encoded != "" {
+		return decodeDataKey(encoded, EnvConfigKey)
+	}
+	cacheKey := e.Key + "\x00" + e.WrappedKey
+	if key, ok := dataKeys.Load(cacheKey); ok {
+		return key.([]byte), nil
+	}
+
+	kind, ref, _ := strings.Cut(e.Key, ":")
+	var key []byte
+	var err error
+	switch kind {
+	case "":
+		return nil, fmt.Errorf("encryption.key not set")
+	case "env":
+		return nil, fmt.Errorf("%s not set", EnvConfigKey)
+	case "keyring":
+		var service, account string
+		if service, account, err = keyringRef(ref); err != nil {
+			return nil, err
+		}
+		var out []byte
+		if runtime.GOOS == "darwin" {
+			out, err = keyCommand(nil, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+		} else {
+			out, err = keyCommand(nil, "secret-tool", "lookup", "service", service, "account", account)
+		}
+		if err == nil {
+			key, err = decodeDataKey(string(out), e.Key)
+		}
+	case "age":
+		var out []byte
+		out, err = keyCommand([]byte(e.WrappedKey), "age", "--decrypt", "-i", expandHome(ref))
+		if err == nil {
+			key, err = decodeDataKey(string(out), e.Key)
+		}
+	case "awskms":
+		var wrapped, out []byte
+		if wrapped, err = base64.StdEncoding.DecodeString(e.WrappedKey); err != nil {
+			return nil, fmt.Errorf("encryption.wrapped_key: %w", err)
+		}
+		out, err = keyCommand(wrapped, "aws", "kms", "decrypt", "--key-id", ref,
+			"--ciphertext-blob", "fileb:///dev/stdin", "--query", "Plaintext", "--output", "text")
+		if err == nil {
+			key, err = decodeDataKey(string(out), e.Key)
+		}
+	default:
+		return nil, fmt.Errorf("unknown encryption.key %q (use keyring:<service>/<account>, age:<identity file>, awskms:<key id> or env)", e.Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s: data key is %d bytes, want 32", e.Key, len(key))
+	}
+	dataKeys.Store(cacheKey, key)
+	return key, nil
+}
+
+// Seal stores a new data key at the key source and returns the settings to
+// save (with the wrapped key for age and KMS)
+func (e EncryptionConfig) Seal(key []byte) (EncryptionConfig, error) {
+	encoded := base64.StdEncoding.EncodeToString(key)
+	kind, ref, _ := strings.Cut(e.Key, ":")
+	switch kind {
+	case "env":
+		// The caller hands the key to the user for ZEN_CLAW_CONFIG_KEY
+	case "keyring":
+		service, account, err := keyringRef(ref)
+		if err != nil {
+			return e, err
+		}
+		if runtime.GOOS == "darwin" {
+			err = keychainStore(service, account, encoded)
+		} else {
+			_, err = keyCommand([]byte(encoded), "secret-tool", "store", "--label", "zen-claw config key", "service", service, "account", account)
+		}
+		if err != nil {
+			return e, err
+		}
+	case "age":
+		recipient, err := keyCommand(nil, "age-keygen", "-y", expandHome(ref))
+		if err != nil {
+			return e, err
+		}
+		wrapped, err := keyCommand([]byte(encoded), "age", "--encrypt", "--armor", "-r", strings.TrimSpace(string(recipient)))
+		if err != nil {
+			return e, err
+		}
+		e.WrappedKey = string(wrapped)
+	case "awskms":
+		wrapped, err := keyCommand(key, "aws", "kms", "encrypt", "--key-id", ref,
+			"--plaintext", "fileb:///dev/stdin", "--query", "CiphertextBlob", "--output", "text")
+		if err != nil {
+			return e, err
+		}
+		e.WrappedKey = strings.TrimSpace(string(wrapped))
+	default:
+		return e, fmt.Errorf("unknown key source %q (use keyring:<service>/<account>, age:<identity file>, awskms:<key id> or env)", e.Key)
+	}
+	dataKeys.Store(e.Key+"\x00"+e.WrappedKey, key)
+	return e, nil
+}
+
+// keychainStore adds or updates a macOS Keychain password. security -i
+// reads the command from stdin, so the key never shows in the process list
+// the way add-generic-password -w <key> would. Its exit status doesn't
+// reflect the command's, so the key is read back to check.
+func keychainStore(service, account, encoded string) error {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	line := fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -w %s\n", quote(service), quote(account), encoded)
+	if _, err := keyCommand([]byte(line), "security", "-i"); err != nil {
+		return err
+	}
+	stored, err := keyCommand(nil, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		return err
+	}
+	if string(stored) != encoded {
+		return fmt.Errorf("security: the key was not stored in the keychain")
+	}
+	return nil
+}
+
+// keyringRef splits <service>/<account>
+func keyringRef(ref string) (service, account string, err error) {
+	service, account, ok := strings.Cut(ref, "/")
+	if !ok || service == "" || account == "" {
+		return "", "", fmt.Errorf("invalid keyring reference %q (want keyring:<service>/<account>)", ref)
+	}
+	return service, account, nil
+}
+
+func decodeDataKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s: not a base64 256-bit key", source)
+	}
+	return key, nil
+}
+
+// keyCommand runs a keyring, age or KMS command with stdin and returns its
+// output
+func keyCommand(stdin []byte, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Never include stdout: it may hold the key
+		return nil, fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimRight(out, "\r\n"), nil
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
+// providerConfigs returns the configured providers by name
+func (c *Config) providerConfigs() map[string]*ProviderConfig {
+	all := map[string]*ProviderConfig{
+		"kimi": c.Providers.Kimi, "openai": c.Providers.OpenAI, "deepseek": c.Providers.DeepSeek, "glm": c.Providers.GLM,
+		"minimax": c.Providers.Minimax, "qwen": c.Providers.Qwen, "anthropic": c.Providers.Anthropic,
+	}
+	for name, p := range all {
+		if p == nil {
+			delete(all, name)
+		}
+	}
+	return all
+}
+
+// MaskKeys replaces the provider API keys and the gateway's operator token
+// with enough of them to recognize, for display
+func (c *Config) MaskKeys() {
+	for _, p := range c.providerConfigs() {
+		p.APIKey = maskSecret(p.APIKey)
+	}
+	c.Gateway.OperatorToken = maskSecret(c.Gateway.OperatorToken)
+}
+
+// maskSecret keeps the first and last 4 characters of longer secrets
+func maskSecret(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) < 12:
+		return "…"
+	}
+	return secret[:4] + "…" + secret[len(secret)-4:]
+}
+
+// decryptKeys replaces encrypted provider API keys with their values
+func (c *Config) decryptKeys() error {
+	var key []byte
+	for name, p := range c.providerConfigs() {
+		if !IsEncrypted(p.APIKey) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = c.Encryption.DataKey(); err != nil {
+				return fmt.Errorf("decrypt API keys: %w", err)
+			}
+		}
+		value, err := Decrypt(key, p.APIKey)
+		if err != nil {
+			return fmt.Errorf("decrypt providers.%s.api_key: %w", name, err)
+		}
+		p.APIKey = value
+	}
+	return nil
+}
+
+// EncryptKeys encrypts the plaintext provider API keys in a config file,
+// keeping the rest of the file as it is, and returns how many it encrypted.
+// The first run creates the data key at source (DefaultKeySource if empty);
+// later runs reuse the file's. For the env source the new data key is
+// returned to be given to ZEN_CLAW_CONFIG_KEY.
+func EncryptKeys(path, source string) (encrypted int, newEnvKey string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, "", fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return 0, "", fmt.Errorf("%s: not a config mapping", path)
+	}
+	root := doc.Content[0]
+
+	var enc EncryptionConfig
+	encNode := mappingValue(root, "encryption")
+	if encNode != nil {
+		if err := encNode.Decode(&enc); err != nil {
+			return 0, "", fmt.Errorf("encryption: %w", err)
+		}
+	}
+	var key []byte
+	switch {
+	case enc.Key != "":
+		if source != "" && source != enc.Key {
+			return 0, "", fmt.Errorf("the keys are already encrypted with %s", enc.Key)
+		}
+		if key, err = enc.DataKey(); err != nil {
+			return 0, "", err
+		}
+	default:
+		enc.Key = source
+		if enc.Key == "" {
+			enc.Key = DefaultKeySource
+		}
+		if enc.Key == "env" && os.Getenv(EnvConfigKey) != "" {
+			key, err = decodeDataKey(os.Getenv(EnvConfigKey), EnvConfigKey)
+		} else {
+			key, err = NewDataKey()
+			if enc.Key == "env" {
+				newEnvKey = base64.StdEncoding.EncodeToString(key)
+			}
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if enc, err = enc.Seal(key); err != nil {
+			return 0, "", err
+		}
+	}
+
+	if providers := mappingValue(root, "providers"); providers != nil && providers.Kind == yaml.MappingNode {
+		for i := 1; i < len(providers.Content); i += 2 {
+			apiKey := mappingValue(providers.Content[i], "api_key")
+			if apiKey == nil || apiKey.Value == "" || IsEncrypted(apiKey.Value) || strings.HasPrefix(apiKey.Value, "${") {
+				continue
+			}
+			if apiKey.Value, err = Encrypt(key, apiKey.Value); err != nil {
+				return 0, "", err
+			}
+			apiKey.Style = 0
+			encrypted++
+		}
+	}
+
+	// Record the key source
+	var settings yaml.Node
+	if err := settings.Encode(enc); err != nil {
+		return 0, "", err
+	}
+	if encNode != nil {
+		*encNode = settings
+	} else {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "encryption"}, &settings)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return 0, "", err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0600); err != nil {
+		return 0, "", err
+	}
+	return encrypted, newEnvKey, os.Rename(tmp, path)
+}
+
+// mappingValue returns the value of key in a mapping node (nil if absent)
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
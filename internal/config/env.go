@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// ENVIRONMENT
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ZEN_CLAW_* variables override the config file's settings, so a container
+// is configured without one. With ZEN_CLAW_CONFIG=env the file is ignored
+// altogether: the defaults plus these variables are the whole config (an
+// explicit --config path is still read). Unparsable values fail loading.
+
+// EnvConfig is the variable naming the config file, or "env" for
+// environment-only configuration
+const EnvConfig = "ZEN_CLAW_CONFIG"
+
+// EnvVar is a ZEN_CLAW_* variable that sets a config field
+type EnvVar struct {
+	Name        string // e.g. ZEN_CLAW_GATEWAY_PORT
+	Field       string // Config field it sets, e.g. gateway.port
+	Description string
+	Secret      bool // Not shown by zen-claw config env
+	apply       func(c *Config, value string) error
+}
+
+// EnvProviders are the providers configurable through ZEN_CLAW_<PROVIDER>_*
+var EnvProviders = []string{"deepseek", "kimi", "qwen", "glm", "minimax", "openai", "anthropic"}
+
+// EnvVars lists the variables that set config fields
+var EnvVars = buildEnvVars()
+
+func buildEnvVars() []EnvVar {
+	vars := []EnvVar{
+		{Name: EnvConfig, Description: `Config file path, or "env" to ignore the file`, apply: func(*Config, string) error { return nil }},
+		{Name: EnvConfigKey, Field: "encryption.key", Description: "Base64 data key of encrypted API keys (instead of encryption.key)", Secret: true,
+			apply: func(*Config, string) error { return nil }},
+		{Name: "ZEN_CLAW_DEFAULT_PROVIDER", Field: "default.provider", Description: "Provider used when a request names none",
+			apply: func(c *Config, v string) error { c.Default.Provider = v; return nil }},
+		{Name: "ZEN_CLAW_DEFAULT_MODEL", Field: "default.model", Description: "Model of the default provider",
+			apply: func(c *Config, v string) error { c.Default.Model = v; return nil }},
+		{Name: "ZEN_CLAW_LANGUAGE", Field: "default.language", Description: "Language tag for answers, e.g. pt-BR",
+			apply: func(c *Config, v string) error { c.Default.Language = v; return nil }},
+	}
+	for _, name := range EnvProviders {
+		name := name
+		prefix := "ZEN_CLAW_" + strings.ToUpper(name)
+		vars = append(vars,
+			EnvVar{Name: prefix + "_API_KEY", Field: "providers." + name + ".api_key", Description: name + " API key (" + strings.ToUpper(name) + "_API_KEY still wins)", Secret: true,
+				apply: func(c *Config, v string) error { c.provider(name).APIKey = v; return nil }},
+			EnvVar{Name: prefix + "_MODEL", Field: "providers." + name + ".model", Description: name + " model",
+				apply: func(c *Config, v string) error { c.provider(name).Model = v; return nil }},
+			EnvVar{Name: prefix + "_BASE_URL", Field: "providers." + name + ".base_url", Description: name + " API base URL",
+				apply: func(c *Config, v string) error { c.provider(name).BaseURL = v; return nil }},
+		)
+	}
+	return append(vars,
+		EnvVar{Name: "ZEN_CLAW_GATEWAY_HOST", Field: "gateway.host", Description: "Listen address (empty = all interfaces)",
+			apply: func(c *Config, v string) error { c.Gateway.Host = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_GATEWAY_PORT", Field: "gateway.port", Description: "Listen port",
+			apply: func(c *Config, v string) error { return envInt(v, &c.Gateway.Port) }},
+		EnvVar{Name: "ZEN_CLAW_GATEWAY_SOCKET", Field: "gateway.socket", Description: "Listen on this unix socket instead of TCP",
+			apply: func(c *Config, v string) error { c.Gateway.Socket = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_OPERATOR_TOKEN", Field: "gateway.operator_token", Description: "Token (X-Operator-Token) allowed to change rate limits at runtime", Secret: true,
+			apply: func(c *Config, v string) error { c.Gateway.OperatorToken = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_MAX_STEPS", Field: "agent.max_steps", Description: "Tool steps per turn",
+			apply: func(c *Config, v string) error { return envInt(v, &c.Agent.MaxSteps) }},
+		EnvVar{Name: "ZEN_CLAW_WORKSPACE", Field: "workspace.path", Description: "Workspace directory",
+			apply: func(c *Config, v string) error { c.Workspace.Path = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_SESSION_DB", Field: "sessions.db_path", Description: "Session database (put it on a volume)",
+			apply: func(c *Config, v string) error { c.Sessions.DBPath = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_MAX_SESSIONS", Field: "sessions.max_sessions", Description: "Concurrent sessions",
+			apply: func(c *Config, v string) error { return envInt(v, &c.Sessions.MaxSessions) }},
+		EnvVar{Name: "ZEN_CLAW_PREMIUM_BUDGET", Field: "routing.premium_budget", Description: "Daily budget for premium models (USD)",
+			apply: func(c *Config, v string) error { return envFloat(v, &c.Routing.PremiumBudget) }},
+		EnvVar{Name: "ZEN_CLAW_PROJECT_BUDGETS", Field: "routing.project_budgets", Description: "Daily budget per project, e.g. acme/api=5,acme/web=2 (USD)",
+			apply: func(c *Config, v string) error { return envBudgets(v, &c.Routing.ProjectBudgets) }},
+		EnvVar{Name: "ZEN_CLAW_CONSENSUS_BUDGET_USD", Field: "consensus.budget_usd", Description: "Estimated spend per consensus run",
+			apply: func(c *Config, v string) error { return envFloat(v, &c.Consensus.BudgetUSD) }},
+		EnvVar{Name: "ZEN_CLAW_CONSENSUS_BUDGET_TOKENS", Field: "consensus.budget_tokens", Description: "Estimated tokens per consensus run",
+			apply: func(c *Config, v string) error { return envInt(v, &c.Consensus.BudgetTokens) }},
+		EnvVar{Name: "ZEN_CLAW_FACTORY_MAX_COST", Field: "factory.guardrails.max_cost_total", Description: "Spend limit of a factory project (USD)",
+			apply: func(c *Config, v string) error { return envFloat(v, &c.Factory.Guardrails.MaxCostTotal) }},
+		EnvVar{Name: "ZEN_CLAW_BRAVE_API_KEY", Field: "web.search.api_key", Description: "Brave Search API key (BRAVE_API_KEY still wins)", Secret: true,
+			apply: func(c *Config, v string) error { c.Web.Search.APIKey = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_MOCK_FIXTURES", Field: "providers.mock.fixtures", Description: "Fixture directory of the mock provider",
+			apply: func(c *Config, v string) error { c.mock().Fixtures = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_MOCK_RECORD", Field: "providers.mock.record", Description: "Provider recording mock fixtures",
+			apply: func(c *Config, v string) error { c.mock().Record = v; return nil }},
+		EnvVar{Name: "ZEN_CLAW_NO_UPDATE_CHECK", Field: "update.disable_check", Description: "Set to anything to skip the new-release check",
+			apply: func(c *Config, v string) error { c.Update.DisableCheck = true; return nil }},
+	)
+}
+
+// EnvOnly reports whether the config comes from the environment alone
+// (ZEN_CLAW_CONFIG=env)
+func EnvOnly() bool {
+	return strings.EqualFold(os.Getenv(EnvConfig), "env")
+}
+
+// ApplyEnv sets the config fields of the ZEN_CLAW_* variables that are set;
+// values that don't parse are returned as ValidationErrors
+func (c *Config) ApplyEnv() error {
+	var errs ValidationErrors
+	for _, v := range EnvVars {
+		value, ok := os.LookupEnv(v.Name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := v.apply(c, value); err != nil {
+			errs = append(errs, ValidationError{Field: v.Name, Message: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// provider returns a provider's config, creating it
+func (c *Config) provider(name string) *ProviderConfig {
+	slot := map[string]**ProviderConfig{
+		"kimi": &c.Providers.Kimi, "openai": &c.Providers.OpenAI, "deepseek": &c.Providers.DeepSeek, "glm": &c.Providers.GLM,
+		"minimax": &c.Providers.Minimax, "qwen": &c.Providers.Qwen, "anthropic": &c.Providers.Anthropic,
+	}[name]
+	if *slot == nil {
+		*slot = &ProviderConfig{}
+	}
+	return *slot
+}
+
+// mock returns the mock provider's config, creating it
+func (c *Config) mock() *MockConfig {
+	if c.Providers.Mock == nil {
+		c.Providers.Mock = &MockConfig{}
+	}
+	return c.Providers.Mock
+}
+
+func envInt(value string, dst *int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("not an integer: %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func envFloat(value string, dst *float64) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("not a number: %q", value)
+	}
+	*dst = f
+	return nil
+}
+
+// envBudgets parses project=usd pairs separated by commas
+func envBudgets(value string, dst *map[string]float64) error {
+	budgets := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		project, usd, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(project) == "" {
+			return fmt.Errorf("want project=usd pairs, got %q", pair)
+		}
+		var f float64
+		if err := envFloat(usd, &f); err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimSpace(project), err)
+		}
+		budgets[strings.TrimSpace(project)] = f
+	}
+	*dst = budgets
+	return nil
+}
+
+// SetEnvVars returns the names of the ZEN_CLAW_* variables that are set,
+// including unknown ones (likely typos)
+func SetEnvVars() (known, unknown []string) {
+	names := make(map[string]bool, len(EnvVars))
+	for _, v := range EnvVars {
+		names[v.Name] = true
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "ZEN_CLAW_") || value == "" {
+			continue
+		}
+		if names[name] {
+			known = append(known, name)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(known)
+	sort.Strings(unknown)
+	return known, unknown
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfigFile is the repo-local config file name
+const RepoConfigFile = ".zenclaw.yaml"
+
+// RepoConfig is a repo-local .zenclaw.yaml. It applies to runs whose
+// working dir is inside the repo and is merged with the user config:
+//
+//   - provider/model: the request's (--provider/--model, /model) win, then
+//     the repo's, then default.provider and its model
+//   - tools: "read-only" makes runs read-only; a repo can't lift a
+//     request's read-only
+//   - max_steps: caps the steps of a turn (the lower of the request's and
+//     the repo's)
+//   - budget_usd: daily budget of the project; routing.project_budgets wins
+//     when lower
+//   - protected_paths: protected in addition to tools.protected_paths
+//   - instructions: added to the system prompt
+//
+// The file comes with the repo, so it can only tighten the user's limits.
+type RepoConfig struct {
+	Provider       string   `yaml:"provider"`        // Default provider for the repo
+	Model          string   `yaml:"model"`           // Default model for the repo
+	Tools          string   `yaml:"tools"`           // "all" (default) or "read-only"
+	MaxSteps       int      `yaml:"max_steps"`       // Steps per turn (0 = the request's)
+	BudgetUSD      float64  `yaml:"budget_usd"`      // Estimated spend per day for the project (0 = no budget)
+	ProtectedPaths []string `yaml:"protected_paths"` // Globs tools may not modify, relative to the repo root
+	Instructions   string   `yaml:"instructions"`    // Project instructions (conventions, commands, no-go areas)
+
+	Path string `yaml:"-"` // The file it was read from
+	Root string `yaml:"-"` // Directory holding the file
+}
+
+// ReadOnly reports whether the repo asks for the read-only toolset
+func (r *RepoConfig) ReadOnly() bool {
+	return r.Tools == "read-only" || r.Tools == "readonly"
+}
+
+// FindRepoConfig returns the path of the .zenclaw.yaml applying to dir: the
+// nearest one in dir or a parent, not looking past the git root ("" if none)
+func FindRepoConfig(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, RepoConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadRepoConfig reads the .zenclaw.yaml applying to dir (nil, nil if there
+// is none)
+func LoadRepoConfig(dir string) (*RepoConfig, error) {
+	path := FindRepoConfig(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var repo RepoConfig
+	if err := yaml.Unmarshal(data, &repo); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	repo.Path, repo.Root = path, filepath.Dir(path)
+	if err := repo.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &repo, nil
+}
+
+// Validate checks the repo config for errors
+func (r *RepoConfig) Validate() error {
+	var errs ValidationErrors
+	switch r.Tools {
+	case "", "all", "read-only", "readonly":
+	default:
+		errs = append(errs, ValidationError{Field: "tools", Message: fmt.Sprintf("unknown toolset %q (use all or read-only)", r.Tools)})
+	}
+	if r.MaxSteps < 0 {
+		errs = append(errs, ValidationError{Field: "max_steps", Message: "must not be negative"})
+	}
+	if r.BudgetUSD < 0 {
+		errs = append(errs, ValidationError{Field: "budget_usd", Message: "must not be negative"})
+	}
+	for _, p := range r.ProtectedPaths {
+		if strings.TrimSpace(p) == "" {
+			errs = append(errs, ValidationError{Field: "protected_paths", Message: "empty pattern"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/neves/zen-claw/internal/shellrisk"
 )
 
 // Level defines the confirmation requirement level
@@ -30,6 +32,7 @@ type Operation struct {
 type Confirmer struct {
 	level          Level
 	alwaysYes      bool      // Auto-approve all (for non-interactive)
+	readOnlyYes    bool      // Auto-approve read-only commands only
 	dangerPatterns []string  // Patterns that trigger confirmation
 	callback       ConfirmCallback
 }
@@ -68,6 +71,12 @@ func (c *Confirmer) SetAlwaysYes(yes bool) {
 	c.alwaysYes = yes
 }
 
+// SetAutoApproveReadOnly auto-approves exec operations classified read-only;
+// every other command still follows the level
+func (c *Confirmer) SetAutoApproveReadOnly(yes bool) {
+	c.readOnlyYes = yes
+}
+
 // SetCallback sets a custom confirmation callback
 func (c *Confirmer) SetCallback(cb ConfirmCallback) {
 	c.callback = cb
@@ -84,6 +93,10 @@ func (c *Confirmer) ShouldConfirm(op Operation) bool {
 		return false
 	}
 
+	if c.readOnlyYes && op.Type == "exec" && op.Details["class"] == string(shellrisk.ReadOnly) {
+		return false
+	}
+
 	if c.level == LevelAll {
 		// Confirm all write operations
 		switch op.Type {
@@ -217,23 +230,14 @@ func ClassifyRisk(op Operation) string {
 		}
 	}
 
-	// High risk patterns
-	highRiskPatterns := []string{
-		"rm -rf", "rm -r",
-		"git push --force", "git push -f",
-		"git reset --hard",
-		"drop database", "DROP DATABASE",
-		"sudo",
-	}
-
-	for _, pattern := range highRiskPatterns {
-		if strings.Contains(strings.ToLower(content), strings.ToLower(pattern)) {
+	// Everything else by what the parsed command does
+	if op.Type == "exec" {
+		switch shellrisk.Classify(content).Class {
+		case shellrisk.Destructive:
 			return "high"
+		case shellrisk.ReadOnly:
+			return "low"
 		}
-	}
-
-	// Medium risk: any exec command
-	if op.Type == "exec" {
 		return "medium"
 	}
 
@@ -245,14 +249,19 @@ func ClassifyRisk(op Operation) string {
 
 // ExecOp creates an exec operation
 func ExecOp(command, workDir string) Operation {
+	analysis := shellrisk.Classify(command)
 	op := Operation{
 		Type:        "exec",
 		Description: fmt.Sprintf("Execute command: %s", truncate(command, 60)),
 		Details: map[string]interface{}{
 			"command":     command,
 			"working_dir": workDir,
+			"class":       string(analysis.Class),
 		},
 	}
+	if len(analysis.Reasons) > 0 {
+		op.Details["reasons"] = strings.Join(analysis.Reasons, "; ")
+	}
 	op.Risk = ClassifyRisk(op)
 	return op
 }
@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// COST CONTROLS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Without limits every worker answers at full MaxTokens. A run can have a
+// budget (estimated USD and/or tokens): workers share a context that is
+// canceled as soon as their share of the budget is spent, and the rest is
+// kept for the arbiter. With draft workers configured, cheap models write
+// short drafts first and only the finalists are refined by the big workers.
+
+const (
+	// draftBudgetShare is the share of the budget the draft tier may use
+	draftBudgetShare = 0.25
+	// workerBudgetShare is the share drafts and workers may use together;
+	// the remainder is kept for the arbiter
+	workerBudgetShare = 0.75
+)
+
+// Spend is what a consensus run used, estimated from prompt and answer sizes
+type Spend struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Tokens returns input plus output tokens
+func (s Spend) Tokens() int {
+	return s.InputTokens + s.OutputTokens
+}
+
+// budget tracks a run's spend against its limits (zero limits: unlimited)
+type budget struct {
+	maxUSD    float64
+	maxTokens int
+
+	mu    sync.Mutex
+	spent Spend
+}
+
+// charge records a call's estimated usage and returns its cost
+func (b *budget) charge(provider, model, prompt, response string) float64 {
+	in, out := estimateTokens(prompt), estimateTokens(response)
+	usd := cost.CalculateUSD(provider, model, in, out)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent.InputTokens += in
+	b.spent.OutputTokens += out
+	b.spent.CostUSD += usd
+	return usd
+}
+
+// exceeded reports whether the spend reached share of a limit
+func (b *budget) exceeded(share float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return (b.maxUSD > 0 && b.spent.CostUSD >= b.maxUSD*share) ||
+		(b.maxTokens > 0 && float64(b.spent.Tokens()) >= float64(b.maxTokens)*share)
+}
+
+// total returns the spend so far
+func (b *budget) total() Spend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// phase returns a context for a group of worker calls that is canceled once
+// the spend reaches share of the budget, and a function to call after each
+// call is charged
+func (b *budget) phase(ctx context.Context, share float64) (context.Context, func(), context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	check := func() {
+		if b.exceeded(share) {
+			cancel(types.Errorf(types.ErrBudgetExceeded, "consensus budget used up (%s)", b.describe()))
+		}
+	}
+	return ctx, check, cancel
+}
+
+// describe renders the limits for messages
+func (b *budget) describe() string {
+	var parts []string
+	if b.maxUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.4f", b.maxUSD))
+	}
+	if b.maxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", b.maxTokens))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// estimateTokens estimates tokens from text length (~4 chars per token)
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// pickFinalists returns the best successful drafts: workers with the higher
+// arbiter average first, then the more thorough answer
+func (e *Engine) pickFinalists(drafts []WorkerResult, n int) []WorkerResult {
+	var ok []WorkerResult
+	for _, d := range drafts {
+		if d.Error == nil && d.Response != "" {
+			ok = append(ok, d)
+		}
+	}
+	e.statsMu.RLock()
+	avg := func(w Worker) float64 {
+		if s, found := e.stats[w.Provider+"/"+w.Model]; found {
+			return s.AvgScore
+		}
+		return 0
+	}
+	sort.SliceStable(ok, func(i, j int) bool {
+		if si, sj := avg(ok[i].Worker), avg(ok[j].Worker); si != sj {
+			return si > sj
+		}
+		return len(ok[i].Response) > len(ok[j].Response)
+	})
+	e.statsMu.RUnlock()
+	if len(ok) > n {
+		ok = ok[:n]
+	}
+	return ok
+}
+
+// buildRefinePrompt asks a worker to improve on the finalist drafts
+func (e *Engine) buildRefinePrompt(req ConsensusRequest, finalists []WorkerResult) string {
+	var drafts strings.Builder
+	for i, d := range finalists {
+		fmt.Fprintf(&drafts, "\n=== DRAFT %d ===\n%s\n", i+1, d.Response)
+	}
+	return e.buildWorkerPrompt(req) + `
+
+DRAFT PROPOSALS (written quickly by other team members):
+` + drafts.String() + `
+Use the drafts as a starting point: keep what is right, fix what is wrong or
+missing, and write your own complete specification. Don't just merge them.`
+}
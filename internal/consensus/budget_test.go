@@ -0,0 +1,50 @@
+package consensus
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestBudget(t *testing.T) {
+	b := &budget{maxTokens: 1000}
+	ctx, check, cancel := b.phase(context.Background(), workerBudgetShare)
+	defer cancel(nil)
+
+	b.charge("deepseek", "deepseek-chat", strings.Repeat("x", 1600), strings.Repeat("y", 800)) // 600 tokens
+	check()
+	if ctx.Err() != nil {
+		t.Fatal("canceled below the workers' share")
+	}
+	b.charge("deepseek", "deepseek-chat", strings.Repeat("x", 400), strings.Repeat("y", 400)) // 800 tokens
+	check()
+	if types.CodeOf(context.Cause(ctx)) != types.ErrBudgetExceeded {
+		t.Errorf("cause = %v, want a budget error", context.Cause(ctx))
+	}
+	if spend := b.total(); spend.Tokens() != 800 || spend.CostUSD <= 0 {
+		t.Errorf("spend = %+v", spend)
+	}
+	if b.exceeded(1) {
+		t.Error("the arbiter's reserve counts as spent")
+	}
+
+	if (&budget{}).exceeded(0) {
+		t.Error("a budget without limits is never exceeded")
+	}
+}
+
+func TestPickFinalists(t *testing.T) {
+	e := &Engine{stats: map[string]*WorkerStats{"qwen/q": {AvgScore: 8}}}
+	drafts := []WorkerResult{
+		{Worker: Worker{Provider: "deepseek", Model: "d"}, Response: "a longer draft"},
+		{Worker: Worker{Provider: "minimax", Model: "m"}, Error: context.Canceled},
+		{Worker: Worker{Provider: "qwen", Model: "q"}, Response: "short"},
+		{Worker: Worker{Provider: "glm", Model: "g"}, Response: "mid"},
+	}
+	got := e.pickFinalists(drafts, 2)
+	if len(got) != 2 || got[0].Worker.Provider != "qwen" || got[1].Worker.Provider != "deepseek" {
+		t.Errorf("finalists = %+v", got)
+	}
+}
@@ -9,21 +9,24 @@ package consensus
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/jsonrepair"
 	"github.com/neves/zen-claw/internal/judge"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/roles"
+	"github.com/neves/zen-claw/internal/types"
 )
 
+// maxScoreReprompts caps follow-up calls asking the arbiter for a valid scores block
+const maxScoreReprompts = 2
+
 // Worker represents a single AI worker in the consensus pool
 type Worker struct {
 	Provider string // e.g., "deepseek", "qwen", "minimax"
@@ -36,8 +39,10 @@ type WorkerResult struct {
 	Response string
 	Duration time.Duration
 	Error    error
-	Score    int    // 1-10 score assigned by arbiter
-	Feedback string // Brief feedback from arbiter
+	Score    int     // 1-10 score assigned by arbiter
+	Feedback string  // Brief feedback from arbiter
+	Tier     string  // "draft" for the draft tier, "" for workers
+	CostUSD  float64 // Estimated cost of the call
 }
 
 // ConsensusRequest represents a request for consensus
@@ -45,10 +50,14 @@ type ConsensusRequest struct {
 	Prompt      string   // The original request/idea
 	Role        string   // The expert role for ALL workers AND arbiter (e.g., "security_architect")
 	Workers     []Worker // Optional custom workers (defaults used if empty)
-	MaxTokens   int      // Max tokens per worker response (default 4000)
-	Temperature float64  // Temperature for worker responses (default 0.7)
+	MaxTokens   int      // Max tokens per worker response (default: model_params, else consensus.max_tokens)
+	Temperature float64  // Temperature for worker responses (default: model_params, else consensus.temperature)
 	UseJudge    bool     // Use LLM judge to evaluate responses before synthesis
 	JudgeCriteria []string // Custom criteria for judge evaluation (optional)
+	JudgePreset  string   // Weighted criteria preset for the judge (default: consensus.judge_preset)
+	BudgetUSD    float64  // Estimated spend limit for the run (default: consensus.budget_usd)
+	BudgetTokens int      // Estimated token limit for the run (default: consensus.budget_tokens)
+	DraftWorkers []Worker // Draft tier (default: consensus.draft_workers; empty = no tiering)
 }
 
 // ConsensusResult holds the synthesized result
@@ -61,6 +70,9 @@ type ConsensusResult struct {
 	ArbiterDuration time.Duration  // Time for arbiter synthesis
 	Role            string         // The role used for this consensus
 	JudgeResult     *judge.Result  // Optional: LLM judge evaluation result
+	Drafts          []WorkerResult // Draft tier responses, when tiering was used
+	Spend           Spend          // Estimated usage of all calls
+	BudgetExceeded  bool           // Workers were canceled because the budget was used up
 }
 
 // WorkerStats tracks long-term worker performance
@@ -71,7 +83,9 @@ type WorkerStats struct {
 	TotalScore int       `json:"total_score"`
 	AvgScore   float64   `json:"avg_score"`
 	LastUsed   time.Time `json:"last_used"`
-	BestRoles  []string  `json:"best_roles"` // Roles where this worker excelled
+	BestRoles  []string  `json:"best_roles"`           // Roles where this worker excelled
+	RatedGood  int       `json:"rated_good,omitempty"` // User 👍 on this provider/model's answers
+	RatedBad   int       `json:"rated_bad,omitempty"`  // User 👎
 }
 
 // Engine manages multi-model consensus
@@ -81,7 +95,8 @@ type Engine struct {
 	statsFile string
 	stats     map[string]*WorkerStats // provider/model -> stats
 	statsMu   sync.RWMutex
-	useJudge  bool // Whether to use LLM judge for response evaluation
+	useJudge  bool            // Whether to use LLM judge for response evaluation
+	roles     *roles.Registry // nil = built-in roles only
 }
 
 // NewEngine creates a new consensus engine
@@ -89,9 +104,10 @@ func NewEngine(cfg *config.Config) *Engine {
 	e := &Engine{
 		cfg:       cfg,
 		factory:   providers.NewFactory(cfg),
-		statsFile: filepath.Join(os.TempDir(), "zen-claw-consensus-stats.json"),
+		statsFile: defaultStatsFile(),
 		stats:     make(map[string]*WorkerStats),
-		useJudge:  false, // Disabled by default
+		useJudge:  cfg.Consensus.Judge, // consensus.judge, disabled by default
+		roles:     roles.Load(roles.DefaultDir()),
 	}
 	e.loadStats()
 	return e
@@ -156,23 +172,39 @@ func (e *Engine) Generate(ctx context.Context, req ConsensusRequest) (*Consensus
 		req.Role = "senior_software_engineer"
 	}
 
-	log.Printf("[Consensus] Starting with %d workers, role: %s", len(workers), req.Role)
-
-	// Set defaults
-	if req.MaxTokens == 0 {
-		req.MaxTokens = 4000
-	}
-	if req.Temperature == 0 {
-		req.Temperature = 0.7
+	// Fail before calling anyone on a misspelled judge preset
+	if preset := e.judgePreset(req); preset != "" && (req.UseJudge || e.useJudge) {
+		if _, err := judge.LookupPreset(preset, e.judgePresets()); err != nil {
+			return nil, types.Errorf(types.ErrInvalidArgument, "%v", err)
+		}
 	}
 
+	log.Printf("[Consensus] Starting with %d workers, role: %s", len(workers), req.Role)
+
 	// Build worker prompt - ALL workers get the SAME prompt with the SAME role
 	workerPrompt := e.buildWorkerPrompt(req)
+	b := e.newBudget(req)
+	overrides := types.ModelParams{MaxTokens: req.MaxTokens, Temperature: req.Temperature}
+	workerStart := time.Now()
+
+	// Phase 0 (tiering): cheap drafts; the workers refine the best ones
+	var drafts []WorkerResult
+	if draftWorkers := e.draftWorkers(req); len(draftWorkers) > 0 {
+		log.Printf("[Consensus] Drafting with %d workers", len(draftWorkers))
+		draftParams := types.ModelParams{MaxTokens: e.cfg.GetConsensusDraftMaxTokens(), Temperature: req.Temperature}
+		drafts = e.callWorkersParallel(ctx, draftWorkers, workerPrompt, draftParams, b, draftBudgetShare)
+		for i := range drafts {
+			drafts[i].Tier = "draft"
+		}
+		if finalists := e.pickFinalists(drafts, e.cfg.GetConsensusFinalists()); len(finalists) > 0 {
+			workerPrompt = e.buildRefinePrompt(req, finalists)
+		}
+	}
 
 	// Phase 1: Parallel worker calls (all with same role and prompt)
-	workerStart := time.Now()
-	results := e.callWorkersParallel(ctx, workers, workerPrompt, req.MaxTokens, req.Temperature)
+	results := e.callWorkersParallel(ctx, workers, workerPrompt, overrides, b, workerBudgetShare)
 	workerDuration := time.Since(workerStart)
+	budgetExceeded := b.exceeded(workerBudgetShare)
 
 	// Check if we got enough valid responses
 	validCount := 0
@@ -183,13 +215,20 @@ func (e *Engine) Generate(ctx context.Context, req ConsensusRequest) (*Consensus
 	}
 
 	if validCount < 2 {
-		return &ConsensusResult{
+		result := &ConsensusResult{
 			Blueprint:      "",
 			WorkerResults:  results,
 			TotalDuration:  time.Since(start),
 			WorkerDuration: workerDuration,
 			Role:           req.Role,
-		}, fmt.Errorf("only %d valid worker responses (need at least 2)", validCount)
+			Drafts:         drafts,
+			Spend:          b.total(),
+			BudgetExceeded: budgetExceeded,
+		}
+		if budgetExceeded {
+			return result, types.Errorf(types.ErrBudgetExceeded, "only %d valid worker responses (need at least 2) before the budget (%s) was used up", validCount, b.describe())
+		}
+		return result, fmt.Errorf("only %d valid worker responses (need at least 2)", validCount)
 	}
 
 	// Optional Phase 1.5: LLM Judge evaluation
@@ -207,7 +246,7 @@ func (e *Engine) Generate(ctx context.Context, req ConsensusRequest) (*Consensus
 	// Arbiter gets: original question + all worker outputs
 	// Arbiter also scores each worker (optionally informed by judge)
 	arbiterStart := time.Now()
-	blueprint, arbiterModel, scoredResults, err := e.synthesizeWithArbiter(ctx, req, results, judgeResult)
+	blueprint, arbiterModel, scoredResults, err := e.synthesizeWithArbiter(ctx, req, results, judgeResult, b)
 	arbiterDuration := time.Since(arbiterStart)
 
 	if err != nil {
@@ -219,15 +258,20 @@ func (e *Engine) Generate(ctx context.Context, req ConsensusRequest) (*Consensus
 			ArbiterDuration: arbiterDuration,
 			Role:            req.Role,
 			JudgeResult:     judgeResult,
+			Drafts:          drafts,
+			Spend:           b.total(),
+			BudgetExceeded:  budgetExceeded,
 		}, fmt.Errorf("arbiter synthesis failed: %w", err)
 	}
 
 	// Update worker stats with scores
 	e.updateWorkerStats(scoredResults, req.Role)
 
-	log.Printf("[Consensus] Complete: %d workers in %v, arbiter in %v, total %v",
+	spend := b.total()
+	log.Printf("[Consensus] Complete: %d workers in %v, arbiter in %v, total %v, ~$%.4f (%d tokens)",
 		len(workers), workerDuration.Round(time.Millisecond),
-		arbiterDuration.Round(time.Millisecond), time.Since(start).Round(time.Millisecond))
+		arbiterDuration.Round(time.Millisecond), time.Since(start).Round(time.Millisecond),
+		spend.CostUSD, spend.Tokens())
 
 	return &ConsensusResult{
 		Blueprint:       blueprint,
@@ -238,9 +282,43 @@ func (e *Engine) Generate(ctx context.Context, req ConsensusRequest) (*Consensus
 		ArbiterDuration: arbiterDuration,
 		Role:            req.Role,
 		JudgeResult:     judgeResult,
+		Drafts:          drafts,
+		Spend:           spend,
+		BudgetExceeded:  budgetExceeded,
 	}, nil
 }
 
+// newBudget returns the run's budget: the request's limits, else the config's
+func (e *Engine) newBudget(req ConsensusRequest) *budget {
+	b := &budget{maxUSD: req.BudgetUSD, maxTokens: req.BudgetTokens}
+	if b.maxUSD == 0 {
+		b.maxUSD = e.cfg.Consensus.BudgetUSD
+	}
+	if b.maxTokens == 0 {
+		b.maxTokens = e.cfg.Consensus.BudgetTokens
+	}
+	return b
+}
+
+// draftWorkers returns the draft tier: the request's, else the configured
+// ones with an API key
+func (e *Engine) draftWorkers(req ConsensusRequest) []Worker {
+	if len(req.DraftWorkers) > 0 {
+		return req.DraftWorkers
+	}
+	available := make(map[string]bool)
+	for _, p := range e.factory.ListAvailableProviders() {
+		available[p] = true
+	}
+	var workers []Worker
+	for _, w := range e.cfg.Consensus.DraftWorkers {
+		if available[w.Provider] {
+			workers = append(workers, Worker{Provider: w.Provider, Model: w.Model})
+		}
+	}
+	return workers
+}
+
 // evaluateWithJudge uses an LLM judge to evaluate worker responses
 func (e *Engine) evaluateWithJudge(ctx context.Context, req ConsensusRequest, results []WorkerResult) *judge.Result {
 	// Convert worker results to judge responses
@@ -282,6 +360,7 @@ func (e *Engine) evaluateWithJudge(ctx context.Context, req ConsensusRequest, re
 
 	// Create judge
 	j := judge.NewJudge(judgeProvider, judgeName, e.cfg.GetModel(judgeName))
+	j.SetPresets(e.judgePresets())
 
 	// Run judgment
 	judgeReq := judge.Request{
@@ -289,6 +368,7 @@ func (e *Engine) evaluateWithJudge(ctx context.Context, req ConsensusRequest, re
 		Task:      req.Prompt,
 		Context:   fmt.Sprintf("Role: %s", req.Role),
 		Criteria:  req.JudgeCriteria,
+		Preset:    e.judgePreset(req),
 	}
 
 	result, err := j.Judge(ctx, judgeReq)
@@ -300,6 +380,25 @@ func (e *Engine) evaluateWithJudge(ctx context.Context, req ConsensusRequest, re
 	return result
 }
 
+// judgePreset returns the request's judge preset, else the config's
+func (e *Engine) judgePreset(req ConsensusRequest) string {
+	if req.JudgePreset != "" {
+		return req.JudgePreset
+	}
+	return e.cfg.Consensus.JudgePreset
+}
+
+// judgePresets converts the configured custom judge presets
+func (e *Engine) judgePresets() map[string][]judge.Criterion {
+	presets := make(map[string][]judge.Criterion)
+	for name, criteria := range e.cfg.Consensus.JudgePresets {
+		for _, c := range criteria {
+			presets[name] = append(presets[name], judge.Criterion{Name: c.Name, Description: c.Description, Weight: c.Weight})
+		}
+	}
+	return presets
+}
+
 // truncateString truncates a string to maxLen characters
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -310,7 +409,7 @@ func truncateString(s string, maxLen int) string {
 
 // buildWorkerPrompt constructs the prompt - same for ALL workers
 func (e *Engine) buildWorkerPrompt(req ConsensusRequest) string {
-	roleDescription := getRoleDescription(req.Role)
+	roleDescription := e.roles.Lookup(req.Role).Expertise
 
 	return fmt.Sprintf(`You are a %s.
 
@@ -329,65 +428,20 @@ INSTRUCTIONS:
 Format your response as a clear technical specification.`, req.Role, roleDescription, req.Prompt)
 }
 
-// getRoleDescription returns a description for the given role
-func getRoleDescription(role string) string {
-	descriptions := map[string]string{
-		"security_architect": `Your expertise includes:
-- Zero-trust architecture and defense in depth
-- Authentication, authorization, and access control
-- Encryption, key management, and secure communications
-- Threat modeling and vulnerability assessment
-- Compliance frameworks (SOC2, GDPR, HIPAA)
-- Secure coding practices and security testing`,
-
-		"software_architect": `Your expertise includes:
-- System design and component organization
-- Scalability, reliability, and performance patterns
-- Microservices and distributed systems
-- Data flow and integration patterns
-- Technology selection and trade-off analysis`,
-
-		"api_designer": `Your expertise includes:
-- RESTful API design and OpenAPI specifications
-- gRPC and protocol buffer design
-- API versioning and backward compatibility
-- Error handling and response schemas
-- Rate limiting and API security`,
-
-		"database_architect": `Your expertise includes:
-- Schema design and normalization
-- Query optimization and indexing strategies
-- Data modeling for different access patterns
-- Replication, sharding, and consistency models
-- Database selection (SQL vs NoSQL)`,
-
-		"devops_engineer": `Your expertise includes:
-- CI/CD pipeline design and automation
-- Container orchestration (Kubernetes)
-- Infrastructure as Code (Terraform, Helm)
-- Monitoring, logging, and observability
-- Deployment strategies and rollback procedures`,
-
-		"frontend_architect": `Your expertise includes:
-- Component architecture and state management
-- Performance optimization and lazy loading
-- Accessibility and responsive design
-- Testing strategies (unit, integration, E2E)
-- Build tooling and bundler configuration`,
-	}
-
-	if desc, ok := descriptions[role]; ok {
-		return desc
-	}
-
-	// Default for unknown roles
-	return fmt.Sprintf("You have deep expertise in %s. Apply your specialized knowledge to provide the best possible recommendation.", strings.ReplaceAll(role, "_", " "))
+// modelParams layers sampling settings for one call: the call's defaults, then
+// the params configured for the model, then the request's overrides
+func (e *Engine) modelParams(provider, model string, defaults, overrides types.ModelParams) types.ModelParams {
+	return defaults.Merge(e.cfg.GetModelParams(provider, model)).Merge(overrides)
 }
 
-// callWorkersParallel calls all workers in parallel with the SAME prompt
-func (e *Engine) callWorkersParallel(ctx context.Context, workers []Worker, prompt string, maxTokens int, temperature float64) []WorkerResult {
+// callWorkersParallel calls all workers in parallel with the SAME prompt.
+// Workers still running when the spend reaches share of the budget are canceled.
+func (e *Engine) callWorkersParallel(ctx context.Context, workers []Worker, prompt string, overrides types.ModelParams, b *budget, share float64) []WorkerResult {
+	defaults := types.ModelParams{MaxTokens: e.cfg.GetConsensusMaxTokens(), Temperature: e.cfg.GetConsensusTemperature()}
 	results := make([]WorkerResult, len(workers))
 	var wg sync.WaitGroup
+	ctx, checkBudget, cancel := b.phase(ctx, share)
+	defer cancel(nil)
 
 	for i, worker := range workers {
 		wg.Add(1)
@@ -408,16 +462,21 @@ func (e *Engine) callWorkersParallel(ctx context.Context, workers []Worker, prom
 
 			// ALL workers get the SAME prompt (no role prefix - role is in the prompt)
 			// CLEAN CONTEXT: only the user message, no system message or history
+			params := e.modelParams(w.Provider, w.Model, defaults, overrides)
 			resp, err := provider.Chat(ctx, ai.ChatRequest{
 				Model: w.Model,
 				Messages: []ai.Message{
 					{Role: "user", Content: prompt},
 				},
-				MaxTokens:   maxTokens,
-				Temperature: temperature,
+				MaxTokens:   params.MaxTokens,
+				Temperature: params.Temperature,
+				TopP:        params.TopP,
 			})
 
 			if err != nil {
+				if cause := context.Cause(ctx); types.CodeOf(cause) == types.ErrBudgetExceeded {
+					err = cause
+				}
 				results[idx] = WorkerResult{
 					Worker:   w,
 					Error:    err,
@@ -431,7 +490,9 @@ func (e *Engine) callWorkersParallel(ctx context.Context, workers []Worker, prom
 				Worker:   w,
 				Response: resp.Content,
 				Duration: time.Since(start),
+				CostUSD:  b.charge(w.Provider, w.Model, prompt, resp.Content),
 			}
+			checkBudget()
 			log.Printf("[Consensus] Worker %s/%s completed in %v (%d chars)",
 				w.Provider, w.Model, time.Since(start).Round(time.Millisecond), len(resp.Content))
 		}(i, worker)
@@ -444,7 +505,7 @@ func (e *Engine) callWorkersParallel(ctx context.Context, workers []Worker, prom
 // synthesizeWithArbiter uses an arbiter model to synthesize and score worker responses
 // CLEAN CONTEXT: arbiter gets only the original question + worker outputs, no history
 // If judgeResult is provided, the arbiter is informed of the judge's evaluation
-func (e *Engine) synthesizeWithArbiter(ctx context.Context, req ConsensusRequest, results []WorkerResult, judgeResult *judge.Result) (string, string, []WorkerResult, error) {
+func (e *Engine) synthesizeWithArbiter(ctx context.Context, req ConsensusRequest, results []WorkerResult, judgeResult *judge.Result, b *budget) (string, string, []WorkerResult, error) {
 	// Choose arbiter based on config preference order
 	arbiterOrder := e.cfg.GetArbiterOrder()
 	var arbiter ai.Provider
@@ -477,7 +538,8 @@ func (e *Engine) synthesizeWithArbiter(ctx context.Context, req ConsensusRequest
 	}
 
 	// Arbiter prompt - SAME ROLE as workers, CLEAN CONTEXT
-	roleDescription := getRoleDescription(req.Role)
+	role := e.roles.Lookup(req.Role)
+	roleDescription := role.Expertise
 	
 	// Include judge evaluation if available
 	judgeSection := ""
@@ -514,7 +576,7 @@ YOUR RESPONSIBILITIES:
    - Maintains your expert perspective as a %s
 
 2. SCORE EACH WORKER: At the END of your response, include a JSON block with scores:
-   - Rate each worker 1-10 based on: accuracy, completeness, practicality, insight
+   - Rate each worker 1-10 based on: %s
    - Provide brief feedback (1 sentence) for each
 
 OUTPUT FORMAT:
@@ -529,26 +591,53 @@ OUTPUT FORMAT:
 }
 `+"```",
 		req.Role, roleDescription, req.Prompt, judgeSection, workerResponses.String(), req.Role,
-		buildScoreTemplate(workerIDs))
+		strings.Join(role.EvaluationCriteria(), ", "), buildScoreTemplate(workerIDs))
 
 	log.Printf("[Consensus] Arbiter %s (role: %s) synthesizing %d responses...", arbiterName, req.Role, len(results))
 
 	// Call arbiter with CLEAN CONTEXT - only this one message, no history
+	arbiterModel := e.cfg.GetModel(arbiterName)
+	params := e.modelParams(arbiterName, arbiterModel, types.ModelParams{MaxTokens: 8000, Temperature: 0.5}, types.ModelParams{})
 	resp, err := arbiter.Chat(ctx, ai.ChatRequest{
-		Model: e.cfg.GetModel(arbiterName),
+		Model: arbiterModel,
 		Messages: []ai.Message{
 			{Role: "user", Content: arbiterPrompt},
 		},
-		MaxTokens:   8000,
-		Temperature: 0.5,
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
 	})
 
 	if err != nil {
 		return "", arbiterName, results, fmt.Errorf("arbiter call failed: %w", err)
 	}
+	b.charge(arbiterName, arbiterModel, arbiterPrompt, resp.Content)
 
 	// Parse response and extract scores
-	blueprint, scoredResults := parseArbiterResponse(resp.Content, results)
+	blueprint, scoredResults, scored := parseArbiterResponse(resp.Content, results)
+
+	// Scores missing or unparseable: ask for just the scores block, with a cap
+	// (unless the budget is used up: the blueprint matters more than the scores)
+	for attempt := 1; !scored && len(workerIDs) > 0 && attempt <= maxScoreReprompts && !b.exceeded(1); attempt++ {
+		log.Printf("[Consensus] Arbiter scores missing or invalid, reprompting (%d/%d)", attempt, maxScoreReprompts)
+		retry, err := arbiter.Chat(ctx, ai.ChatRequest{
+			Model: arbiterModel,
+			Messages: []ai.Message{
+				{Role: "user", Content: arbiterPrompt},
+				{Role: "assistant", Content: resp.Content},
+				{Role: "user", Content: "Your ---SCORES--- JSON block was missing or invalid. Reply with ONLY the corrected JSON object in the requested format, nothing else."},
+			},
+			MaxTokens:   1000,
+			Temperature: 0.2,
+			TopP:        params.TopP,
+		})
+		if err != nil {
+			log.Printf("[Consensus] Scores reprompt failed: %v", err)
+			break
+		}
+		b.charge(arbiterName, arbiterModel, arbiterPrompt+resp.Content, retry.Content)
+		scored = applyScores(retry.Content, scoredResults)
+	}
 
 	return blueprint, arbiterName, scoredResults, nil
 }
@@ -562,50 +651,61 @@ func buildScoreTemplate(workerIDs []string) string {
 	return strings.Join(lines, ",\n")
 }
 
-// parseArbiterResponse extracts blueprint and scores from arbiter response
-func parseArbiterResponse(response string, results []WorkerResult) (string, []WorkerResult) {
+// parseArbiterResponse extracts blueprint and scores from arbiter response.
+// scored reports whether a scores block was found and parsed.
+func parseArbiterResponse(response string, results []WorkerResult) (blueprint string, scoredResults []WorkerResult, scored bool) {
 	// Split at ---SCORES--- marker
 	parts := strings.Split(response, "---SCORES---")
-	blueprint := strings.TrimSpace(parts[0])
+	blueprint = strings.TrimSpace(parts[0])
 
 	// Try to parse scores if present
 	if len(parts) > 1 {
-		scoresSection := parts[1]
+		scored = applyScores(parts[1], results)
+	}
 
-		// Find JSON block
-		jsonStart := strings.Index(scoresSection, "{")
-		jsonEnd := strings.LastIndex(scoresSection, "}")
+	return blueprint, results, scored
+}
 
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := scoresSection[jsonStart : jsonEnd+1]
+// applyScores parses a scores JSON block (repairing truncated or sloppy JSON)
+// and sets Score/Feedback on the matching results
+func applyScores(section string, results []WorkerResult) bool {
+	jsonStart := strings.Index(section, "{")
+	if jsonStart < 0 {
+		return false
+	}
 
-			var scoreData struct {
-				Scores []struct {
-					Worker   string `json:"worker"`
-					Score    int    `json:"score"`
-					Feedback string `json:"feedback"`
-				} `json:"scores"`
-			}
+	var scoreData struct {
+		Scores []struct {
+			Worker   string `json:"worker"`
+			Score    int    `json:"score"`
+			Feedback string `json:"feedback"`
+		} `json:"scores"`
+	}
 
-			if err := json.Unmarshal([]byte(jsonStr), &scoreData); err == nil {
-				// Match scores to results
-				for i := range results {
-					workerID := fmt.Sprintf("WORKER_%d_%s", i+1, strings.ToUpper(results[i].Worker.Provider))
-					for _, s := range scoreData.Scores {
-						if strings.Contains(s.Worker, workerID) || strings.Contains(workerID, s.Worker) {
-							results[i].Score = s.Score
-							results[i].Feedback = s.Feedback
-							break
-						}
-					}
-				}
-			} else {
-				log.Printf("[Consensus] Failed to parse scores JSON: %v", err)
+	repaired, err := jsonrepair.Unmarshal(section[jsonStart:], &scoreData)
+	if err != nil {
+		log.Printf("[Consensus] Failed to parse scores JSON: %v", err)
+		return false
+	}
+	if repaired {
+		log.Printf("[Consensus] Repaired malformed scores JSON")
+	}
+	if len(scoreData.Scores) == 0 {
+		return false
+	}
+
+	// Match scores to results
+	for i := range results {
+		workerID := fmt.Sprintf("WORKER_%d_%s", i+1, strings.ToUpper(results[i].Worker.Provider))
+		for _, s := range scoreData.Scores {
+			if strings.Contains(s.Worker, workerID) || strings.Contains(workerID, s.Worker) {
+				results[i].Score = s.Score
+				results[i].Feedback = s.Feedback
+				break
 			}
 		}
 	}
-
-	return blueprint, results
+	return true
 }
 
 // updateWorkerStats updates long-term worker statistics
@@ -613,34 +713,44 @@ func (e *Engine) updateWorkerStats(results []WorkerResult, role string) {
 	e.statsMu.Lock()
 	defer e.statsMu.Unlock()
 
-	for _, r := range results {
-		if r.Error != nil || r.Score == 0 {
-			continue
-		}
+	apply := func(all map[string]*WorkerStats) {
+		for _, r := range results {
+			if r.Error != nil || r.Score == 0 {
+				continue
+			}
 
-		key := fmt.Sprintf("%s/%s", r.Worker.Provider, r.Worker.Model)
-		stats, exists := e.stats[key]
-		if !exists {
-			stats = &WorkerStats{
-				Provider:  r.Worker.Provider,
-				Model:     r.Worker.Model,
-				BestRoles: []string{},
+			key := fmt.Sprintf("%s/%s", r.Worker.Provider, r.Worker.Model)
+			stats, exists := all[key]
+			if !exists {
+				stats = &WorkerStats{
+					Provider:  r.Worker.Provider,
+					Model:     r.Worker.Model,
+					BestRoles: []string{},
+				}
+				all[key] = stats
 			}
-			e.stats[key] = stats
-		}
 
-		stats.TotalTasks++
-		stats.TotalScore += r.Score
-		stats.AvgScore = float64(stats.TotalScore) / float64(stats.TotalTasks)
-		stats.LastUsed = time.Now()
+			stats.TotalTasks++
+			stats.TotalScore += r.Score
+			stats.AvgScore = float64(stats.TotalScore) / float64(stats.TotalTasks)
+			stats.LastUsed = time.Now()
 
-		// Track best roles (score >= 8)
-		if r.Score >= 8 && !contains(stats.BestRoles, role) {
-			stats.BestRoles = append(stats.BestRoles, role)
+			// Track best roles (score >= 8)
+			if r.Score >= 8 && !contains(stats.BestRoles, role) {
+				stats.BestRoles = append(stats.BestRoles, role)
+			}
 		}
 	}
 
-	e.saveStats()
+	// Applied to the file's current contents, so ratings recorded meanwhile
+	// by other processes are kept
+	stats, err := updateStatsFile(e.statsFile, apply)
+	if err != nil {
+		log.Printf("[Consensus] Could not save worker stats: %v", err)
+		apply(e.stats)
+		return
+	}
+	e.stats = stats
 }
 
 // GetWorkerStats returns current worker statistics
@@ -659,26 +769,11 @@ func (e *Engine) GetWorkerStats() map[string]*WorkerStats {
 
 // loadStats loads worker statistics from disk
 func (e *Engine) loadStats() {
-	data, err := os.ReadFile(e.statsFile)
-	if err != nil {
-		return
-	}
-
-	var stats map[string]*WorkerStats
-	if err := json.Unmarshal(data, &stats); err == nil {
+	if stats, err := readStatsFile(e.statsFile); err == nil {
 		e.stats = stats
 	}
 }
 
-// saveStats saves worker statistics to disk
-func (e *Engine) saveStats() {
-	data, err := json.MarshalIndent(e.stats, "", "  ")
-	if err != nil {
-		return
-	}
-	os.WriteFile(e.statsFile, data, 0644)
-}
-
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -0,0 +1,211 @@
+package consensus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/jsonrepair"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// BLUEPRINT EXECUTION
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// A blueprint can be handed to an agent session as its plan. The blueprint is
+// split into numbered sections; the agent implements them and ends with a
+// compliance block saying what it did for each, which becomes the report.
+
+// complianceMarker separates the agent's answer from its compliance block
+const complianceMarker = "---COMPLIANCE---"
+
+// Compliance statuses
+const (
+	StatusImplemented = "implemented"
+	StatusPartial     = "partial"
+	StatusSkipped     = "skipped"
+	StatusUnreported  = "unreported" // The agent said nothing about the section
+)
+
+// Section is one part of a blueprint
+type Section struct {
+	ID    string // S1, S2, ...
+	Title string
+	Body  string
+}
+
+// SectionCompliance is what the agent did for a section
+type SectionCompliance struct {
+	Section Section
+	Status  string
+	Notes   string // Files changed, or why it was skipped
+}
+
+// ComplianceReport summarizes how much of a blueprint was implemented
+type ComplianceReport struct {
+	Sections []SectionCompliance
+}
+
+// Count returns how many sections have the status
+func (r ComplianceReport) Count(status string) int {
+	n := 0
+	for _, s := range r.Sections {
+		if s.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Complete reports whether every section was implemented
+func (r ComplianceReport) Complete() bool {
+	return len(r.Sections) > 0 && r.Count(StatusImplemented) == len(r.Sections)
+}
+
+var headingPattern = regexp.MustCompile(`^(#{1,4})\s+(.+?)\s*#*$`)
+
+// SplitSections splits a blueprint at its top-level markdown headings (the
+// shallowest level used more than once). A blueprint without them is one
+// section.
+func SplitSections(blueprint string) []Section {
+	lines := strings.Split(blueprint, "\n")
+	levels := make(map[int]int)
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil && !inFence {
+			levels[len(m[1])]++
+		}
+	}
+	level := 0
+	for l := 1; l <= 4; l++ {
+		if levels[l] > 1 {
+			level = l
+			break
+		}
+	}
+	if level == 0 {
+		return []Section{{ID: "S1", Title: "Blueprint", Body: strings.TrimSpace(blueprint)}}
+	}
+
+	var sections []Section
+	var preamble []string
+	current := -1
+	inFence = false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil && !inFence && len(m[1]) == level {
+			sections = append(sections, Section{ID: fmt.Sprintf("S%d", len(sections)+1), Title: m[2]})
+			current = len(sections) - 1
+			continue
+		}
+		if current < 0 {
+			preamble = append(preamble, line)
+			continue
+		}
+		sections[current].Body += line + "\n"
+	}
+	for i := range sections {
+		sections[i].Body = strings.TrimSpace(sections[i].Body)
+	}
+	// Text before the first heading is context for the first section
+	if intro := strings.TrimSpace(strings.Join(preamble, "\n")); intro != "" {
+		sections[0].Body = strings.TrimSpace(intro + "\n\n" + sections[0].Body)
+	}
+	return sections
+}
+
+// ExecutionPrompt asks an agent to implement the blueprint as its plan
+func ExecutionPrompt(task string, sections []Section) string {
+	var sb strings.Builder
+	sb.WriteString("Implement the following blueprint in the working directory. It was produced by a team of experts for this task:\n\n")
+	sb.WriteString(task)
+	sb.WriteString("\n\nBLUEPRINT (follow it as your plan; sections are numbered):\n")
+	for _, s := range sections {
+		fmt.Fprintf(&sb, "\n## [%s] %s\n%s\n", s.ID, s.Title, s.Body)
+	}
+	sb.WriteString(`
+INSTRUCTIONS:
+1. Work through the sections in order. Read the existing code before changing it.
+2. Implement what applies to this codebase; skip sections that don't apply and say why.
+3. Verify your changes (build, tests) where possible.
+4. End your final answer with the compliance block described below.
+
+`)
+	sb.WriteString(complianceInstructions(sections))
+	return sb.String()
+}
+
+// CompliancePrompt asks for the compliance block alone, when the agent's
+// answer lacked it
+func CompliancePrompt(sections []Section) string {
+	return "Report what you implemented for each blueprint section. Don't make further changes.\n\n" + complianceInstructions(sections)
+}
+
+func complianceInstructions(sections []Section) string {
+	var entries []string
+	for _, s := range sections {
+		entries = append(entries, fmt.Sprintf(`    {"section": "%s", "status": "implemented|partial|skipped", "notes": "files changed, or why not"}`, s.ID))
+	}
+	return "COMPLIANCE BLOCK FORMAT:\n" + complianceMarker + "\n```json\n{\n  \"sections\": [\n" + strings.Join(entries, ",\n") + "\n  ]\n}\n```"
+}
+
+// ParseCompliance reads the compliance block of the agent's answer. found is
+// false when the answer has none; sections it does not mention are
+// reported as unreported.
+func ParseCompliance(answer string, sections []Section) (report ComplianceReport, found bool) {
+	block := answer
+	if _, after, ok := strings.Cut(answer, complianceMarker); ok {
+		block = after
+	}
+	var data struct {
+		Sections []struct {
+			Section string `json:"section"`
+			Status  string `json:"status"`
+			Notes   string `json:"notes"`
+		} `json:"sections"`
+	}
+	if start := strings.Index(block, "{"); start >= 0 {
+		if _, err := jsonrepair.Unmarshal(block[start:], &data); err == nil {
+			found = len(data.Sections) > 0
+		}
+	}
+
+	for _, s := range sections {
+		entry := SectionCompliance{Section: s, Status: StatusUnreported}
+		for _, d := range data.Sections {
+			if strings.EqualFold(strings.Trim(d.Section, "[] "), s.ID) {
+				entry.Status = normalizeStatus(d.Status)
+				entry.Notes = d.Notes
+				break
+			}
+		}
+		report.Sections = append(report.Sections, entry)
+	}
+	return report, found
+}
+
+// StripCompliance returns the answer without its compliance block
+func StripCompliance(answer string) string {
+	before, _, _ := strings.Cut(answer, complianceMarker)
+	return strings.TrimSpace(before)
+}
+
+func normalizeStatus(status string) string {
+	switch s := strings.ToLower(strings.TrimSpace(status)); s {
+	case StatusImplemented, StatusPartial, StatusSkipped:
+		return s
+	case "done", "complete", "completed":
+		return StatusImplemented
+	case "partially implemented", "in progress":
+		return StatusPartial
+	case "":
+		return StatusUnreported
+	default:
+		return StatusSkipped
+	}
+}
@@ -0,0 +1,48 @@
+package consensus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlueprintCompliance(t *testing.T) {
+	blueprint := "Overview of the design.\n\n## Storage\nUse SQLite.\n\n### Schema\nOne table.\n\n## API\n```\n## not a heading\n```\nAdd /limits.\n\n## Rollout\nFeature flag."
+	sections := SplitSections(blueprint)
+	if len(sections) != 3 {
+		t.Fatalf("got %d sections, want 3: %+v", len(sections), sections)
+	}
+	if s := sections[0]; s.ID != "S1" || s.Title != "Storage" || !strings.HasPrefix(s.Body, "Overview") || !strings.Contains(s.Body, "### Schema") {
+		t.Errorf("first section = %+v", s)
+	}
+	if !strings.Contains(sections[1].Body, "## not a heading") {
+		t.Errorf("fenced heading split the API section: %+v", sections[1])
+	}
+	if prompt := ExecutionPrompt("rate limiting", sections); !strings.Contains(prompt, "[S3] Rollout") || !strings.Contains(prompt, complianceMarker) {
+		t.Errorf("prompt lacks sections or the compliance format:\n%s", prompt)
+	}
+
+	answer := "Done, see below.\n" + complianceMarker + "\n```json\n{\"sections\": [" +
+		"{\"section\": \"S1\", \"status\": \"implemented\", \"notes\": \"store.go\"}," +
+		"{\"section\": \"[S2]\", \"status\": \"Done\"}]}\n```"
+	report, found := ParseCompliance(answer, sections)
+	if !found {
+		t.Fatal("compliance block not found")
+	}
+	got := []string{report.Sections[0].Status, report.Sections[1].Status, report.Sections[2].Status}
+	if got[0] != StatusImplemented || got[1] != StatusImplemented || got[2] != StatusUnreported {
+		t.Errorf("statuses = %v", got)
+	}
+	if report.Complete() || report.Count(StatusImplemented) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	if StripCompliance(answer) != "Done, see below." {
+		t.Errorf("stripped answer = %q", StripCompliance(answer))
+	}
+
+	if _, found := ParseCompliance("All done.", sections); found {
+		t.Error("found a compliance block in a plain answer")
+	}
+	if one := SplitSections("Just do it."); len(one) != 1 || one[0].Body != "Just do it." {
+		t.Errorf("headingless blueprint = %+v", one)
+	}
+}
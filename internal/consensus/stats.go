@@ -0,0 +1,122 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// statsLockWait bounds how long a writer waits for another process's stats
+// update; a lock file older than this was left by a writer that died
+const statsLockWait = 10 * time.Second
+
+// defaultStatsFile is where worker statistics persist between runs
+func defaultStatsFile() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "data", "consensus-stats.json")
+}
+
+// readStatsFile reads persisted worker statistics; a missing file is empty
+func readStatsFile(path string) (map[string]*WorkerStats, error) {
+	stats := make(map[string]*WorkerStats)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return stats, nil
+}
+
+// updateStatsFile applies update to the statistics on disk and writes them
+// back. Consensus runs and the gateway's feedback write the same file from
+// different processes, so the read-modify-write holds a lock file and the
+// new contents replace the old with a rename.
+func updateStatsFile(path string, update func(stats map[string]*WorkerStats)) (map[string]*WorkerStats, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	unlock, err := lockStatsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stats, err := readStatsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	update(stats)
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return stats, nil
+}
+
+// lockStatsFile takes path's lock file, waiting up to statsLockWait for the
+// current holder
+func lockStatsFile(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(statsLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, serr := os.Stat(lock); serr == nil && time.Since(info.ModTime()) > statsLockWait {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another process", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// RecordFeedback adds a user rating (+1/-1) of a provider/model answer to the
+// persisted worker statistics
+func RecordFeedback(provider, model string, rating int) error {
+	_, err := updateStatsFile(defaultStatsFile(), func(stats map[string]*WorkerStats) {
+		key := fmt.Sprintf("%s/%s", provider, model)
+		s, exists := stats[key]
+		if !exists {
+			s = &WorkerStats{Provider: provider, Model: model, BestRoles: []string{}}
+			stats[key] = s
+		}
+		if rating > 0 {
+			s.RatedGood++
+		} else if rating < 0 {
+			s.RatedBad++
+		}
+	})
+	return err
+}
@@ -0,0 +1,47 @@
+package consensus
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStatsFileUpdates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Concurrent ratings are all counted
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rating := 1
+			if i%4 == 0 {
+				rating = -1
+			}
+			if err := RecordFeedback("qwen", "q", rating); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// A consensus run merges its scores into the file instead of overwriting it
+	e := &Engine{statsFile: defaultStatsFile(), stats: make(map[string]*WorkerStats)}
+	e.updateWorkerStats([]WorkerResult{{Worker: Worker{Provider: "qwen", Model: "q"}, Score: 9}}, "architect")
+
+	stats, err := readStatsFile(defaultStatsFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := stats["qwen/q"]
+	if q == nil || q.RatedGood != 15 || q.RatedBad != 5 || q.TotalTasks != 1 || q.BestRoles[0] != "architect" {
+		t.Errorf("stats = %+v", q)
+	}
+	if e.stats["qwen/q"].RatedGood != 15 {
+		t.Errorf("engine stats not refreshed from the file: %+v", e.stats["qwen/q"])
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(defaultStatsFile()), "*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
@@ -97,6 +97,28 @@ func (u *Usage) Record(provider, model string, inputTokens, outputTokens int) {
 	pu.Cost += cost
 }
 
+// Restore adds usage recorded before a restart: calls to a provider/model
+// and their summed tokens
+func (u *Usage) Restore(provider, model string, calls, inputTokens, outputTokens int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cost := Calculate(provider, model, inputTokens, outputTokens)
+	u.InputTokens += inputTokens
+	u.OutputTokens += outputTokens
+	u.TotalCost += cost
+
+	key := provider + ":" + model
+	if u.ByProvider[key] == nil {
+		u.ByProvider[key] = &ProviderUsage{Provider: provider, Model: model}
+	}
+	pu := u.ByProvider[key]
+	pu.InputTokens += inputTokens
+	pu.OutputTokens += outputTokens
+	pu.Calls += calls
+	pu.Cost += cost
+}
+
 // Calculate returns cost in cents * 100 for given tokens
 func Calculate(provider, model string, inputTokens, outputTokens int) int {
 	key := provider + ":" + model
@@ -122,6 +144,17 @@ func Calculate(provider, model string, inputTokens, outputTokens int) int {
 	return costIn + costOut
 }
 
+// CalculateUSD returns the cost in dollars for given tokens, without the
+// per-call rounding of Calculate (for budgets that add up many small calls)
+func CalculateUSD(provider, model string, inputTokens, outputTokens int) float64 {
+	pricing, ok := providerCosts[provider+":"+model]
+	if !ok {
+		pricing = Pricing{InputPerMillion: 100, OutputPerMillion: 300}
+	}
+	cents := float64(inputTokens*pricing.InputPerMillion+outputTokens*pricing.OutputPerMillion) / 1000000
+	return cents / 100
+}
+
 // FormatCost formats cost in cents * 100 to human readable
 func FormatCost(cost int) string {
 	cents := float64(cost) / 100.0
@@ -17,6 +17,7 @@ import (
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/roles"
 )
 
 // Specialist represents an AI specialist for a specific domain
@@ -89,6 +90,8 @@ type PhaseResult struct {
 	Duration         time.Duration
 	EstimatedCost    float64
 	ValidationPassed bool
+	Provider         string // Specialist that ran the phase
+	Model            string
 }
 
 // FactoryState represents the persistent state of a factory run
@@ -122,6 +125,7 @@ type Factory struct {
 	stateFile       string
 	updates         chan MacroUpdate
 	mu              sync.RWMutex
+	roles           *roles.Registry // A role named like a phase's domain replaces the specialist's prompt
 }
 
 // NewFactory creates a new software factory
@@ -132,6 +136,7 @@ func NewFactory(cfg *config.Config) *Factory {
 		specialists:     loadSpecialistsFromConfig(cfg),
 		guardrails:      loadGuardrailsFromConfig(cfg),
 		updates:         make(chan MacroUpdate, 100),
+		roles:           roles.Load(roles.DefaultDir()),
 	}
 }
 
@@ -327,6 +332,8 @@ func (f *Factory) executePhase(ctx context.Context, phase Phase) (PhaseResult, e
 			Status:   StatusFailed,
 			Errors:   []string{err.Error()},
 			Duration: time.Since(start),
+			Provider: specialist.Provider,
+			Model:    specialist.Model,
 		}, err
 	}
 
@@ -341,7 +348,7 @@ func (f *Factory) executePhase(ctx context.Context, phase Phase) (PhaseResult, e
 	resp, err := provider.Chat(phaseCtx, ai.ChatRequest{
 		Model: specialist.Model,
 		Messages: []ai.Message{
-			{Role: "system", Content: f.getSpecialistSystemPrompt(specialist.Domain)},
+			{Role: "system", Content: f.phaseSystemPrompt(phase, specialist)},
 			{Role: "user", Content: prompt},
 		},
 		MaxTokens:   4000,
@@ -354,6 +361,8 @@ func (f *Factory) executePhase(ctx context.Context, phase Phase) (PhaseResult, e
 			Status:   StatusFailed,
 			Errors:   []string{err.Error()},
 			Duration: time.Since(start),
+			Provider: specialist.Provider,
+			Model:    specialist.Model,
 		}, err
 	}
 
@@ -364,6 +373,8 @@ func (f *Factory) executePhase(ctx context.Context, phase Phase) (PhaseResult, e
 		Duration:         time.Since(start),
 		EstimatedCost:    0.01, // Rough estimate, would need token counting
 		ValidationPassed: true,
+		Provider:         specialist.Provider,
+		Model:            specialist.Model,
 	}, nil
 }
 
@@ -412,6 +423,15 @@ Provide complete, production-ready output.`)
 	return sb.String()
 }
 
+// phaseSystemPrompt returns the system prompt for a phase: the role named
+// like the phase's domain in the role library, else the specialist's
+func (f *Factory) phaseSystemPrompt(phase Phase, specialist Specialist) string {
+	if role, ok := f.roles.Get(phase.Domain); ok {
+		return fmt.Sprintf("You are a %s.\n\n%s", strings.ReplaceAll(role.Name, "_", " "), role.Expertise)
+	}
+	return f.getSpecialistSystemPrompt(specialist.Domain)
+}
+
 // getSpecialistSystemPrompt returns the system prompt for a specialist
 func (f *Factory) getSpecialistSystemPrompt(domain string) string {
 	switch domain {
@@ -530,6 +550,7 @@ Be specific about what went wrong and how to fix it.`, phase.Name, phase.Task, o
 		Status:           StatusCompleted,
 		Output:           resp.Content,
 		ValidationPassed: true,
+		Provider:         f.specialists["coordinator"].Provider,
 	}, nil
 }
 
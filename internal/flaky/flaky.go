@@ -0,0 +1,489 @@
+// Package flaky finds nondeterministic Go tests: it reruns packages many
+// times in parallel git worktrees and compares the outcomes of each test.
+package flaky
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of a detection
+const (
+	DefaultRuns    = 10
+	DefaultTimeout = 10 * time.Minute
+)
+
+// excerptLines is the output kept of a failure
+const excerptLines = 25
+
+// excerptsMax caps the distinct failure excerpts kept per test
+const excerptsMax = 3
+
+// Options configure a detection
+type Options struct {
+	Dir      string        // Module directory (inside a git repo for parallel runs)
+	Packages []string      // Package patterns (default ./...)
+	Runs     int           // Times every package is run (default 10)
+	Parallel int           // Worktrees running at once (default min(4, CPUs))
+	Run      string        // -run regexp
+	Race     bool          // -race
+	Shuffle  bool          // -shuffle=on: a different test order each run
+	Timeout  time.Duration // -timeout of one run (default 10m)
+	Env      []string      // Added to the environment of go test
+
+	Progress func(RunResult) // Called as runs finish (from several goroutines, one at a time)
+}
+
+// RunResult is one run of go test
+type RunResult struct {
+	Index   int      `json:"index"`
+	Passed  bool     `json:"passed"`
+	Seconds float64  `json:"seconds"`
+	Seed    int64    `json:"seed,omitempty"`   // -shuffle seed
+	Failed  []string `json:"failed,omitempty"` // Package.Test of the failures
+	Error   string   `json:"error,omitempty"`  // go test didn't run (not a test failure)
+}
+
+// TestStats is the outcome of a test over the runs
+type TestStats struct {
+	Package     string   `json:"package"`
+	Test        string   `json:"test"` // "" for failures outside any test (build errors, panics in init, timeouts)
+	Runs        int      `json:"runs"`
+	Passes      int      `json:"passes"`
+	Failures    int      `json:"failures"`
+	Skips       int      `json:"skips"`
+	FailureRate float64  `json:"failure_rate"`
+	MinSeconds  float64  `json:"min_seconds"`
+	MaxSeconds  float64  `json:"max_seconds"`
+	Excerpts    []string `json:"excerpts,omitempty"` // Distinct failure outputs
+	Seeds       []int64  `json:"seeds,omitempty"`    // Shuffle seeds of failing runs
+}
+
+// Name is the test's package-qualified name
+func (s *TestStats) Name() string {
+	if s.Test == "" {
+		return s.Package + " (package)"
+	}
+	return s.Package + "." + s.Test
+}
+
+// Report is the result of a detection
+type Report struct {
+	Packages []string     `json:"packages"`
+	Runs     int          `json:"runs"`
+	Parallel int          `json:"parallel"`
+	Options  string       `json:"options"` // go test flags used
+	Tests    int          `json:"tests"`   // Distinct tests seen
+	Flaky    []*TestStats `json:"flaky"`   // Passed in some runs and failed in others
+	Failing  []*TestStats `json:"failing"` // Failed in every run
+	Results  []RunResult  `json:"results"`
+	Seconds  float64      `json:"seconds"`
+}
+
+// testEvent is a line of go test -json
+type testEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Output  string  `json:"Output"`
+	Elapsed float64 `json:"Elapsed"`
+}
+
+// testKey identifies a test (Test "" for the package itself)
+type testKey struct {
+	Package, Test string
+}
+
+func (k testKey) String() string {
+	return k.Package + "." + k.Test
+}
+
+// outcome is a test's result in one run
+type outcome struct {
+	action  string // pass, fail or skip
+	elapsed float64
+	output  []string
+}
+
+var seedRe = regexp.MustCompile(`-test\.shuffle (\d+)`)
+
+// Detect runs the packages opts.Runs times and reports the tests whose
+// outcome changed between runs. Runs are spread over opts.Parallel
+// worktrees of the repo at HEAD with the uncommitted changes applied, so
+// they don't share build output or files the tests write; outside a git
+// repo they run one at a time in opts.Dir.
+func Detect(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Runs <= 0 {
+		opts.Runs = DefaultRuns
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = min(4, runtime.NumCPU())
+	}
+	opts.Parallel = min(opts.Parallel, opts.Runs)
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if len(opts.Packages) == 0 {
+		opts.Packages = []string{"./..."}
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	dirs := []string{dir}
+	if root := gitRoot(ctx, dir); root != "" {
+		rel, _ := filepath.Rel(root, dir)
+		dirs = nil
+		for i := 0; i < opts.Parallel; i++ {
+			tree, cleanup, err := newWorktree(ctx, root)
+			if err != nil {
+				return nil, err
+			}
+			defer cleanup()
+			dirs = append(dirs, filepath.Join(tree, rel))
+		}
+	} else {
+		opts.Parallel = 1
+	}
+
+	flags := testFlags(opts)
+	runs := make([]map[testKey]*outcome, opts.Runs)
+	results := make([]RunResult, opts.Runs)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	for _, d := range dirs {
+		wg.Add(1)
+		go func(d string) {
+			defer wg.Done()
+			for i := range next {
+				results[i], runs[i] = runOnce(ctx, d, flags, opts)
+				results[i].Index = i + 1
+				if opts.Progress != nil {
+					progressMu.Lock()
+					opts.Progress(results[i])
+					progressMu.Unlock()
+				}
+			}
+		}(d)
+	}
+	for i := 0; i < opts.Runs && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	report := aggregate(runs, results)
+	report.Packages = opts.Packages
+	report.Runs = opts.Runs
+	report.Parallel = opts.Parallel
+	report.Options = strings.Join(flags, " ")
+	report.Seconds = time.Since(start).Round(time.Second).Seconds()
+	return report, nil
+}
+
+// testFlags are the go test arguments of a run
+func testFlags(opts Options) []string {
+	flags := []string{"test", "-json", "-count=1", "-timeout=" + opts.Timeout.String()}
+	if opts.Run != "" {
+		flags = append(flags, "-run="+opts.Run)
+	}
+	if opts.Race {
+		flags = append(flags, "-race")
+	}
+	if opts.Shuffle {
+		flags = append(flags, "-shuffle=on")
+	}
+	return append(flags, opts.Packages...)
+}
+
+// runOnce runs go test in dir and collects the outcome of every test
+func runOnce(ctx context.Context, dir string, flags []string, opts Options) (RunResult, map[testKey]*outcome) {
+	started := time.Now()
+	cmd := exec.CommandContext(ctx, "go", flags...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), opts.Env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return RunResult{Error: err.Error()}, nil
+	}
+	if err := cmd.Start(); err != nil {
+		return RunResult{Error: err.Error()}, nil
+	}
+	outcomes, seed := parseTestJSON(stdout)
+	err = cmd.Wait()
+
+	result := RunResult{Passed: err == nil, Seconds: time.Since(started).Round(100 * time.Millisecond).Seconds(), Seed: seed}
+	for key, o := range outcomes {
+		if o.action == "fail" {
+			result.Failed = append(result.Failed, key.String())
+		}
+	}
+	sort.Strings(result.Failed)
+	if err != nil && len(outcomes) == 0 {
+		// go test failed before running anything (bad pattern, no go.mod)
+		result.Error = strings.TrimSpace(stderr.String())
+		if result.Error == "" {
+			result.Error = err.Error()
+		}
+	}
+	return result, outcomes
+}
+
+// parseTestJSON reads go test -json output: the outcome of each test
+// (package-level failures under Test "") and the shuffle seed
+func parseTestJSON(r io.Reader) (map[testKey]*outcome, int64) {
+	outcomes := make(map[testKey]*outcome)
+	pending := make(map[testKey][]string) // Output of tests still running
+	var seed int64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for scanner.Scan() {
+		var ev testEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		key := testKey{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output":
+			if m := seedRe.FindStringSubmatch(ev.Output); m != nil && seed == 0 {
+				seed, _ = strconv.ParseInt(m[1], 10, 64)
+			}
+			lines := append(pending[key], strings.TrimRight(ev.Output, "\n"))
+			if len(lines) > excerptLines {
+				lines = lines[len(lines)-excerptLines:]
+			}
+			pending[key] = lines
+		case "pass", "fail", "skip":
+			if ev.Test == "" && ev.Action == "skip" {
+				continue // No test files
+			}
+			outcomes[key] = &outcome{action: ev.Action, elapsed: ev.Elapsed, output: pending[key]}
+			delete(pending, key)
+		}
+	}
+
+	// A package failure is only kept when no test failure explains it: a
+	// build error, a panic or a timeout
+	failedTests := make(map[string]bool)
+	for key, o := range outcomes {
+		if key.Test != "" && o.action == "fail" {
+			failedTests[key.Package] = true
+		}
+	}
+	for key := range outcomes {
+		if key.Test == "" && failedTests[key.Package] {
+			delete(outcomes, key)
+		}
+	}
+	return outcomes, seed
+}
+
+// aggregate compares the runs' outcomes per test
+func aggregate(runs []map[testKey]*outcome, results []RunResult) *Report {
+	stats := make(map[testKey]*TestStats)
+	parents := make(map[testKey]bool)
+	for i, run := range runs {
+		for key, o := range run {
+			if parent, _, ok := strings.Cut(key.Test, "/"); ok {
+				parents[testKey{key.Package, parent}] = true
+			}
+			s := stats[key]
+			if s == nil {
+				s = &TestStats{Package: key.Package, Test: key.Test, MinSeconds: o.elapsed, MaxSeconds: o.elapsed}
+				stats[key] = s
+			}
+			s.Runs++
+			s.MinSeconds = min(s.MinSeconds, o.elapsed)
+			s.MaxSeconds = max(s.MaxSeconds, o.elapsed)
+			switch o.action {
+			case "pass":
+				s.Passes++
+			case "skip":
+				s.Skips++
+			case "fail":
+				s.Failures++
+				excerpt := strings.TrimSpace(strings.Join(o.output, "\n"))
+				if len(s.Excerpts) < excerptsMax && !contains(s.Excerpts, excerpt) {
+					s.Excerpts = append(s.Excerpts, excerpt)
+				}
+				if seed := results[i].Seed; seed != 0 {
+					s.Seeds = append(s.Seeds, seed)
+				}
+			}
+		}
+	}
+
+	ran := 0
+	for _, r := range results {
+		if r.Error == "" {
+			ran++
+		}
+	}
+	report := &Report{Results: results}
+	for key, s := range stats {
+		// A parent fails with its subtests: the subtests say which
+		if parents[key] {
+			continue
+		}
+		if key.Test != "" {
+			report.Tests++
+		}
+		// A test missing from a run died with its package (panic, timeout)
+		if key.Test != "" && s.Runs < ran {
+			s.Failures += ran - s.Runs
+			s.Runs = ran
+		}
+		if s.Runs > 0 {
+			s.FailureRate = float64(s.Failures) / float64(s.Runs)
+		}
+		switch {
+		case s.Failures > 0 && s.Passes > 0:
+			report.Flaky = append(report.Flaky, s)
+		case s.Failures > 0 && s.Failures == s.Runs:
+			report.Failing = append(report.Failing, s)
+		}
+	}
+	for _, list := range [][]*TestStats{report.Flaky, report.Failing} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].FailureRate != list[j].FailureRate {
+				return list[i].FailureRate > list[j].FailureRate
+			}
+			return list[i].Name() < list[j].Name()
+		})
+	}
+	return report
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the report
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Flaky test report\n\n")
+	fmt.Fprintf(&b, "`go %s` run %d times (%d in parallel) in %.0fs: %d tests, %d flaky, %d always failing.\n",
+		r.Options, r.Runs, r.Parallel, r.Seconds, r.Tests, len(r.Flaky), len(r.Failing))
+	failedRuns := 0
+	for _, res := range r.Results {
+		if res.Error != "" {
+			fmt.Fprintf(&b, "\n⚠️ Run %d didn't run the tests: %s\n", res.Index, res.Error)
+		}
+		if !res.Passed {
+			failedRuns++
+		}
+	}
+	fmt.Fprintf(&b, "%d of %d runs failed.\n", failedRuns, r.Runs)
+
+	section := func(title string, list []*TestStats) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n", title)
+		for _, s := range list {
+			fmt.Fprintf(&b, "\n### %s\n\nFailed %d of %d runs (%.0f%%), took %.2fs–%.2fs.\n",
+				s.Name(), s.Failures, s.Runs, s.FailureRate*100, s.MinSeconds, s.MaxSeconds)
+			if len(s.Seeds) > 0 {
+				seeds := make([]string, len(s.Seeds))
+				for i, seed := range s.Seeds {
+					seeds[i] = strconv.FormatInt(seed, 10)
+				}
+				fmt.Fprintf(&b, "Failing shuffle seeds (rerun with -shuffle=<seed>): %s\n", strings.Join(seeds, ", "))
+			}
+			for _, ex := range s.Excerpts {
+				fmt.Fprintf(&b, "\n```\n%s\n```\n", ex)
+			}
+		}
+	}
+	section("Flaky tests", r.Flaky)
+	section("Always failing", r.Failing)
+	return b.String()
+}
+
+// gitRoot returns the top of the git repo holding dir ("" outside one)
+func gitRoot(ctx context.Context, dir string) string {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newWorktree checks out HEAD in a temporary worktree of root and copies
+// the uncommitted changes (tracked and untracked) into it
+func newWorktree(ctx context.Context, root string) (string, func(), error) {
+	tree, err := os.MkdirTemp("", "zen-claw-flaky-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		exec.Command("git", "-C", root, "worktree", "remove", "--force", tree).Run()
+		os.RemoveAll(tree)
+		exec.Command("git", "-C", root, "worktree", "prune").Run()
+	}
+	git := func(dir string, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+	if _, err := git(root, "worktree", "add", "--detach", "--quiet", tree, "HEAD"); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	patch, err := git(root, "diff", "HEAD", "--binary")
+	if err == nil && len(patch) > 0 {
+		cmd := exec.CommandContext(ctx, "git", "-C", tree, "apply", "--whitespace=nowarn", "-")
+		cmd.Stdin = bytes.NewReader(patch)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("apply uncommitted changes: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	untracked, _ := git(root, "ls-files", "--others", "--exclude-standard", "-z")
+	for _, name := range strings.Split(string(untracked), "\x00") {
+		if name == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		dst := filepath.Join(tree, name)
+		os.MkdirAll(filepath.Dir(dst), 0755)
+		os.WriteFile(dst, data, 0644)
+	}
+	return tree, cleanup, nil
+}
@@ -0,0 +1,147 @@
+package flaky
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	run1 := `{"Action":"run","Package":"example.com/app/store","Test":"TestOpen"}
+{"Action":"output","Package":"example.com/app/store","Test":"TestOpen","Output":"-test.shuffle 1700000000\n"}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestOpen","Elapsed":0.01}
+{"Action":"output","Package":"example.com/app/store","Test":"TestCache","Output":"    cache_test.go:12: got 2 entries, want 1\n"}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestCache","Elapsed":0.2}
+{"Action":"output","Package":"example.com/app/store","Test":"TestTable/empty","Output":"    table_test.go:30: timeout\n"}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestTable/empty","Elapsed":1}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTable/full","Elapsed":0}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestTable","Elapsed":1}
+{"Action":"fail","Package":"example.com/app/store","Elapsed":1.3}
+{"Action":"pass","Package":"example.com/app/api","Test":"TestServe","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/app/api","Elapsed":0.1}
+`
+	run2 := `{"Action":"pass","Package":"example.com/app/store","Test":"TestOpen","Elapsed":0.02}
+{"Action":"fail","Package":"example.com/app/store","Test":"TestCache","Elapsed":0.3}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTable/empty","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTable/full","Elapsed":0}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTable","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/api","Output":"panic: test timed out after 10m0s\n"}
+{"Action":"fail","Package":"example.com/app/api","Elapsed":600}
+`
+	var runs []map[testKey]*outcome
+	var results []RunResult
+	for _, out := range []string{run1, run2} {
+		outcomes, seed := parseTestJSON(strings.NewReader(out))
+		runs = append(runs, outcomes)
+		results = append(results, RunResult{Seed: seed})
+	}
+	if results[0].Seed != 1700000000 {
+		t.Errorf("seed = %d", results[0].Seed)
+	}
+	if _, ok := runs[0][testKey{"example.com/app/store", ""}]; ok {
+		t.Error("package failure kept although tests explain it")
+	}
+
+	report := aggregate(runs, results)
+	var flaky, failing []string
+	for _, s := range report.Flaky {
+		flaky = append(flaky, s.Name())
+	}
+	for _, s := range report.Failing {
+		failing = append(failing, s.Name())
+	}
+	// TestServe died with its package in run 2 (timeout)
+	want := "example.com/app/api (package),example.com/app/api.TestServe,example.com/app/store.TestTable/empty"
+	if strings.Join(flaky, ",") != want {
+		t.Errorf("flaky = %v, want %s", flaky, want)
+	}
+	if strings.Join(failing, ",") != "example.com/app/store.TestCache" {
+		t.Errorf("failing = %v", failing)
+	}
+	// TestOpen, TestCache, TestTable/empty, TestTable/full, TestServe (the parent TestTable isn't counted)
+	if report.Tests != 5 {
+		t.Errorf("tests = %d, want 5", report.Tests)
+	}
+	if s := report.Flaky[2]; s.Failures != 1 || s.Runs != 2 || s.FailureRate != 0.5 || len(s.Excerpts) != 1 || s.Seeds[0] != 1700000000 {
+		t.Errorf("TestTable/empty = %+v", s)
+	}
+	if md := report.Markdown(); !strings.Contains(md, "### example.com/app/store.TestTable/empty") || !strings.Contains(md, "table_test.go:30: timeout") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, bin := range []string{"git", "go"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skip(bin + " not installed")
+		}
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.email=t@example.com", "-c", "user.name=t"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/flaky\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "stable_test.go"), []byte("package flaky\n\nimport \"testing\"\n\nfunc TestStable(t *testing.T) {}\n"), 0644)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-qm", "init")
+
+	// Uncommitted: every other run fails, whichever worktree it runs in
+	os.WriteFile(filepath.Join(dir, "flaky_test.go"), []byte(`package flaky
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	for n := 1; ; n++ {
+		f, err := os.OpenFile(filepath.Join(os.Getenv("FLAKY_COUNTER"), fmt.Sprint(n)), os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		if n%2 == 1 {
+			t.Fatalf("run %d failed", n)
+		}
+		return
+	}
+}
+`), 0644)
+
+	var seen int
+	report, err := Detect(context.Background(), Options{
+		Dir:      dir,
+		Runs:     4,
+		Parallel: 2,
+		Env:      []string{"FLAKY_COUNTER=" + t.TempDir()},
+		Progress: func(RunResult) { seen++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 4 || report.Tests != 2 || report.Parallel != 2 {
+		t.Errorf("%d progress calls, %d tests, %d parallel", seen, report.Tests, report.Parallel)
+	}
+	if len(report.Flaky) != 1 || report.Flaky[0].Name() != "example.com/flaky.TestCounter" || report.Flaky[0].Failures != 2 {
+		t.Fatalf("flaky = %+v\n%s", report.Flaky, report.Markdown())
+	}
+	if !strings.Contains(report.Flaky[0].Excerpts[0], "failed") {
+		t.Errorf("excerpt = %q", report.Flaky[0].Excerpts[0])
+	}
+
+	// The worktrees are removed
+	out, _ := exec.Command("git", "-C", dir, "worktree", "list").Output()
+	if strings.Count(string(out), "\n") != 1 {
+		t.Errorf("worktrees left behind:\n%s", out)
+	}
+}
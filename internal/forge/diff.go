@@ -0,0 +1,47 @@
+package forge
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DiffLines returns, per file, the lines of the new version a unified diff
+// shows (added and context lines). Inline review comments can only go on
+// these lines; GitHub rejects a review with a comment anywhere else.
+func DiffLines(diff string) map[string]map[int]bool {
+	files := make(map[string]map[int]bool)
+	var lines map[int]bool
+	line := 0
+	prev := ""
+	for _, text := range strings.Split(diff, "\n") {
+		header := strings.HasPrefix(prev, "--- ")
+		prev = text
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			lines = nil
+		case header && strings.HasPrefix(text, "+++ "):
+			path := strings.TrimPrefix(text, "+++ ")
+			if path == "/dev/null" {
+				lines = nil // Deleted file
+				continue
+			}
+			path = strings.TrimPrefix(path, "b/")
+			lines = make(map[int]bool)
+			files[path] = lines
+		case strings.HasPrefix(text, "@@ "):
+			// @@ -a,b +c,d @@: the new side starts at line c
+			line = 0
+			if fields := strings.Fields(text); len(fields) >= 3 {
+				start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+				line, _ = strconv.Atoi(start)
+			}
+		case lines == nil || line == 0:
+		case strings.HasPrefix(text, "+"), strings.HasPrefix(text, " "):
+			lines[line] = true
+			line++
+		case strings.HasPrefix(text, "-"), strings.HasPrefix(text, `\`):
+			// Removed line or "\ No newline at end of file": no new line
+		}
+	}
+	return files
+}
@@ -0,0 +1,56 @@
+package forge
+
+import "testing"
+
+func TestDiffLines(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,4 +10,5 @@ func main() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ 	fmt.Println(a, b)
+@@ -40,2 +41,2 @@ func helper() {
+-	return nil
++	return err
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/new.md b/new.md
+new file mode 100644
+--- /dev/null
++++ b/new.md
+@@ -0,0 +1,2 @@
++# Title
++++ not a header
+\ No newline at end of file
+`
+	files := DiffLines(diff)
+
+	want := map[string][]int{
+		"main.go": {10, 11, 12, 13, 41},
+		"new.md":  {1, 2},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("files = %v", files)
+	}
+	for path, lines := range want {
+		if len(files[path]) != len(lines) {
+			t.Errorf("%s: lines = %v, want %v", path, files[path], lines)
+		}
+		for _, l := range lines {
+			if !files[path][l] {
+				t.Errorf("%s: line %d missing", path, l)
+			}
+		}
+	}
+	if files["main.go"][14] || files["main.go"][40] {
+		t.Error("lines outside the hunks reported")
+	}
+}
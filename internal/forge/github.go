@@ -0,0 +1,221 @@
+// Package forge talks to code forges for the gateway's integrations: it
+// reads pull requests and issues on GitHub and writes reviews, comments and
+// labels back.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// DefaultGitHubAPI is the API of github.com (GitHub Enterprise serves it
+// under https://<host>/api/v3)
+const DefaultGitHubAPI = "https://api.github.com"
+
+// maxDiffSize caps a pull request diff read from the API
+const maxDiffSize = 10 << 20
+
+// PullRequest is the part of a GitHub pull request the integrations use
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft"`
+	URL    string `json:"html_url"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// ReviewComment is an inline comment of a review, on a line of the new
+// version of a file
+type ReviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Side string `json:"side,omitempty"` // RIGHT (default): the line in the PR's version
+	Body string `json:"body"`
+}
+
+// Review is a pull request review to post
+type Review struct {
+	CommitID string          `json:"commit_id,omitempty"` // Head the review is for (a newer push makes it outdated)
+	Body     string          `json:"body"`
+	Event    string          `json:"event"` // COMMENT, APPROVE or REQUEST_CHANGES
+	Comments []ReviewComment `json:"comments,omitempty"`
+}
+
+// Label is a repository label
+type Label struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Issue is the part of a GitHub issue the integrations use
+type Issue struct {
+	Number int     `json:"number"`
+	Title  string  `json:"title"`
+	Body   string  `json:"body"`
+	State  string  `json:"state"`
+	URL    string  `json:"html_url"`
+	Labels []Label `json:"labels"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	PullRequest *struct{} `json:"pull_request,omitempty"` // Set when the issue is a pull request
+}
+
+// GitHub is a client of the GitHub REST API
+type GitHub struct {
+	APIURL string // API base URL (default DefaultGitHubAPI)
+	Token  string // Token of the account or app installation acting
+	HTTP   *http.Client
+}
+
+// NewGitHub creates a client for an API ("" = DefaultGitHubAPI)
+func NewGitHub(apiURL, token string) *GitHub {
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPI
+	}
+	return &GitHub{
+		APIURL: strings.TrimSuffix(apiURL, "/"),
+		Token:  token,
+		HTTP:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+// PullRequest returns a pull request of repo (owner/name)
+func (g *GitHub) PullRequest(ctx context.Context, repo string, number int) (*PullRequest, error) {
+	var pr PullRequest
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", repo, number), "", nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// PullRequestDiff returns the unified diff of a pull request
+func (g *GitHub) PullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	var diff bytes.Buffer
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", repo, number), "application/vnd.github.v3.diff", nil, &diff); err != nil {
+		return "", err
+	}
+	return diff.String(), nil
+}
+
+// CreateReview posts a review on a pull request
+func (g *GitHub) CreateReview(ctx context.Context, repo string, number int, review Review) error {
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/pulls/%d/reviews", repo, number), "", review, nil)
+}
+
+// Issue returns an issue of repo (pull requests are issues too)
+func (g *GitHub) Issue(ctx context.Context, repo string, number int) (*Issue, error) {
+	var issue Issue
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", repo, number), "", nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// Labels returns the labels defined in repo (the first 100)
+func (g *GitHub) Labels(ctx context.Context, repo string) ([]Label, error) {
+	var labels []Label
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/labels?per_page=100", repo), "", nil, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// AddLabels adds labels to an issue or pull request
+func (g *GitHub) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/labels", repo, number), "", map[string][]string{"labels": labels}, nil)
+}
+
+// CreateComment comments on an issue or pull request
+func (g *GitHub) CreateComment(ctx context.Context, repo string, number int, body string) error {
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number), "", map[string]string{"body": body}, nil)
+}
+
+// do makes one API request. in is sent as JSON; the response is decoded
+// into out as JSON, or copied when out is a *bytes.Buffer.
+func (g *GitHub) do(ctx context.Context, method, path, accept string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.APIURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "zen-claw")
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	resp, err := g.HTTP.Do(req)
+	if err != nil {
+		return types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		detail := strings.TrimSpace(string(msg))
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return types.Errorf(types.ErrNotFound, "%s %s: not found", method, path)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				return types.Errorf(types.ErrRateLimited, "GitHub rate limit exceeded (%s)", resp.Status)
+			}
+			return types.Errorf(types.ErrPermissionDenied, "%s %s: %s: %s", method, path, resp.Status, detail)
+		case http.StatusTooManyRequests:
+			return types.Errorf(types.ErrRateLimited, "GitHub rate limit exceeded (%s)", resp.Status)
+		case http.StatusUnprocessableEntity:
+			return types.Errorf(types.ErrInvalidArgument, "%s %s: %s", method, path, detail)
+		}
+		return types.Errorf(types.ErrUnavailable, "%s %s: %s: %s", method, path, resp.Status, detail)
+	}
+	if out == nil {
+		return nil
+	}
+	if buf, ok := out.(*bytes.Buffer); ok {
+		n, err := buf.ReadFrom(io.LimitReader(resp.Body, maxDiffSize+1))
+		if err != nil {
+			return err
+		}
+		if n > maxDiffSize {
+			return types.Errorf(types.ErrBudgetExceeded, "%s %s: response larger than %d bytes", method, path, maxDiffSize)
+		}
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestGitHubClient(t *testing.T) {
+	var added []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/api/issues/7":
+			w.Write([]byte(`{"number": 7, "title": "Crash", "labels": [{"name": "bug"}], "user": {"login": "ana"}}`))
+		case "POST /repos/acme/api/issues/7/labels":
+			var body struct{ Labels []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			added = body.Labels
+			w.Write([]byte(`[]`))
+		case "GET /repos/acme/api/pulls/8":
+			if r.Header.Get("Accept") == "application/vnd.github.v3.diff" {
+				w.Write([]byte("diff --git a/x b/x\n"))
+				return
+			}
+			w.Write([]byte(`{"number": 8, "head": {"sha": "abc"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	gh := NewGitHub(srv.URL+"/", "t0ken")
+	ctx := context.Background()
+	issue, err := gh.Issue(ctx, "acme/api", 7)
+	if err != nil || issue.Title != "Crash" || issue.User.Login != "ana" || len(issue.Labels) != 1 || issue.PullRequest != nil {
+		t.Fatalf("Issue = %+v, %v", issue, err)
+	}
+	if err := gh.AddLabels(ctx, "acme/api", 7, []string{"area/cli"}); err != nil || len(added) != 1 {
+		t.Errorf("AddLabels: %v, sent %v", err, added)
+	}
+	if diff, err := gh.PullRequestDiff(ctx, "acme/api", 8); err != nil || diff != "diff --git a/x b/x\n" {
+		t.Errorf("PullRequestDiff = %q, %v", diff, err)
+	}
+	if _, err := gh.Issue(ctx, "acme/api", 9); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("missing issue: %v", err)
+	}
+	gh.Token = "wrong"
+	if _, err := gh.PullRequest(ctx, "acme/api", 8); types.CodeOf(err) != types.ErrPermissionDenied {
+		t.Errorf("bad token: %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -11,8 +15,14 @@ import (
 	"github.com/neves/zen-claw/internal/agent"
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/i18n"
+	"github.com/neves/zen-claw/internal/lsp"
 	"github.com/neves/zen-claw/internal/mcp"
 	"github.com/neves/zen-claw/internal/plugins"
+	"github.com/neves/zen-claw/internal/privacy"
+	"github.com/neves/zen-claw/internal/recorder"
+	"github.com/neves/zen-claw/internal/shellrisk"
 	"github.com/neves/zen-claw/internal/types"
 )
 
@@ -22,9 +32,40 @@ type GatewayAICaller struct {
 	provider      string
 	model         string
 	thinkingLevel ai.ThinkingLevel
+	project       string                    // Session's project, for per-project budgets
+	sessionID     string                    // For the usage log
+	budgets       *projectBudgets           // nil = no budget tracking
+	usage         *usageLog                 // nil = usage is not persisted
+	scrubber      *privacy.Scrubber         // nil = prompts are sent as they are
+	onRedact      func(*privacy.Redactions) // Reports what a call scrubbed
+}
+
+// checkBudget fails before a call once the project's daily budget is used up
+func (c *GatewayAICaller) checkBudget() error {
+	if c.budgets == nil {
+		return nil
+	}
+	return c.budgets.check(c.project)
+}
+
+// recordSpend adds the call's estimated cost to the project's daily spend
+// and the usage log
+func (c *GatewayAICaller) recordSpend(req ai.ChatRequest, resp *ai.ChatResponse) {
+	if resp == nil {
+		return
+	}
+	inputTokens, outputTokens := EstimateTokens(req.Messages), len(resp.Content)/4
+	if c.budgets != nil {
+		c.budgets.add(c.project, c.provider, req.Model, inputTokens, outputTokens)
+	}
+	c.usage.record(usageRecord(c.provider, req.Model, c.project, c.sessionID, inputTokens, outputTokens))
 }
 
 func (c *GatewayAICaller) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
+
 	// Use provider/model from caller if specified, otherwise use defaults
 	preferredProvider := c.provider
 
@@ -39,10 +80,19 @@ func (c *GatewayAICaller) Chat(ctx context.Context, req ai.ChatRequest) (*ai.Cha
 		req.Thinking = c.thinkingLevel != ai.ThinkingOff
 	}
 
-	return c.aiRouter.Chat(ctx, req, preferredProvider)
+	redactions := c.scrub(&req)
+	resp, err := c.aiRouter.Chat(ctx, req, preferredProvider)
+	c.recordSpend(req, resp)
+	if redactions != nil {
+		redactions.RestoreResponse(resp)
+	}
+	return resp, err
 }
 
 func (c *GatewayAICaller) ChatStream(ctx context.Context, req ai.ChatRequest, callback ai.StreamCallback) (*ai.ChatResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
 	preferredProvider := c.provider
 	if c.model != "" {
 		req.Model = c.model
@@ -51,7 +101,45 @@ func (c *GatewayAICaller) ChatStream(ctx context.Context, req ai.ChatRequest, ca
 		req.ThinkingLevel = c.thinkingLevel
 		req.Thinking = c.thinkingLevel != ai.ThinkingOff
 	}
-	return c.aiRouter.ChatStream(ctx, req, preferredProvider, callback)
+	redactions := c.scrub(&req)
+	if redactions != nil && callback != nil {
+		// Best effort: a placeholder split across tokens streams as is
+		stream := callback
+		callback = func(token string) { stream(redactions.Restore(token)) }
+	}
+	resp, err := c.aiRouter.ChatStream(ctx, req, preferredProvider, callback)
+	c.recordSpend(req, resp)
+	if redactions != nil {
+		redactions.RestoreResponse(resp)
+	}
+	return resp, err
+}
+
+// costTicker prices the model calls reported in progress events (only the
+// gateway knows prices) and stamps each event with the request's running
+// total, for clients' cost tickers
+type costTicker struct {
+	provider, model string
+
+	mu    sync.Mutex
+	total *types.Usage // nil until the first model call
+}
+
+func (t *costTicker) observe(event *types.ProgressEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if event.Usage != nil {
+		usage := *event.Usage
+		usage.CostUSD = cost.CalculateUSD(t.provider, t.model, usage.InputTokens, usage.OutputTokens)
+		event.Usage = &usage
+		total := usage
+		if t.total != nil {
+			total = t.total.Add(usage)
+		}
+		t.total = &total
+	}
+	event.Total = t.total
 }
 
 // AgentService manages agent sessions and tool execution via gateway
@@ -63,6 +151,20 @@ type AgentService struct {
 	fallbackSessions map[string]*agent.Session
 	fallbackMu       sync.RWMutex
 	mcpClient        *mcp.Client
+	lspManager       *lsp.Manager
+	recorder         *recorder.Recorder // nil unless recording is enabled
+	budgets          *projectBudgets    // Daily spend per project (routing.project_budgets)
+	janitor          *sessionJanitor    // nil unless sessions.retention is set
+	usagePusher      *usagePusher       // nil unless usage.push.url is set
+	toolMetrics      *agent.ToolMetrics // Per-tool call counts and latency
+	auditLog         *os.File           // nil unless tools.audit_log is set
+	runs             *runRegistry       // In-flight requests by session, for cancelling
+	turns            *sessionTurns      // One turn at a time per session
+	runHistory       *runHistory        // Consensus, fabric and factory runs reported by the CLI
+	probe            *capabilityProbe   // Capabilities of models unknown to the registry
+	scrubber         *privacy.Scrubber  // nil unless privacy.scrub is on for some workspace
+	usage            *usageLog          // Model call usage, persisted with the sessions
+	explainCache     *agent.ResultCache // Tool results shared by explain runs (zen-claw ask)
 }
 
 // NewAgentService creates a new agent service for the gateway
@@ -80,6 +182,36 @@ func NewAgentService(cfg *config.Config) *AgentService {
 		sessionStore = nil
 	}
 
+	// Configure formatters/linters run after file writes
+	if hooks := cfg.GetPostWriteHooks(); hooks != nil {
+		agent.GetPostWriteHooks().Set(hooks)
+	}
+
+	// Resource limits for exec/process commands
+	agent.GetToolLimits().Set(cfg.Tools.Limits)
+
+	// Paths no tool may modify
+	if cfg.Tools.ProtectedPaths != nil {
+		agent.GetProtectedPaths().Set(cfg.Tools.ProtectedPaths)
+	}
+
+	// Which shell commands run without an operator's approval
+	if err := agent.GetExecApproval().Set(cfg.Tools.ExecApproval); err != nil {
+		log.Printf("Warning: %v, running all commands", err)
+	}
+
+	// Hosts tools may reach
+	agent.GetEgressPolicy().Set(cfg.Tools.Egress.Allow, cfg.Tools.Egress.Deny)
+
+	// Secret references session env may resolve
+	agent.GetSecretRefPolicy().Set(cfg.Tools.SecretRefs)
+
+	// Roots session working dirs must be inside (cd included)
+	agent.GetWorkingDirRoots().Set(cfg.Gateway.Limits.WorkingDirs)
+
+	// Cleanups of write/edit content (fences, trailing whitespace, newlines)
+	agent.GetPostProcess().Set(cfg.Tools.DisablePostProcess)
+
 	// Create tools (working directory will be set per session)
 	// Full toolset for code generation and editing
 	tools := []agent.Tool{
@@ -88,7 +220,11 @@ func NewAgentService(cfg *config.Config) *AgentService {
 		agent.NewReadFileTool(""),    // Read files
 		agent.NewWriteFileTool(""),   // Create/overwrite files
 		agent.NewEditFileTool(""),    // String replacement (like Cursor's StrReplace)
+		agent.NewMultiEditTool(""),   // Several replacements in one atomic write
 		agent.NewAppendFileTool(""),  // Append to files
+		agent.NewBeginWriteTool(""),  // Chunked write of a large file...
+		agent.NewAppendChunkTool(),   // ...one chunk at a time...
+		agent.NewCommitWriteTool(),   // ...replaced atomically at commit
 		agent.NewListDirTool(""),     // List directories
 		agent.NewSearchFilesTool(""), // Grep-like search
 		agent.NewSystemInfoTool(),    // System info
@@ -99,6 +235,7 @@ func NewAgentService(cfg *config.Config) *AgentService {
 		agent.NewGitCommitTool(""), // git commit
 		agent.NewGitPushTool(""),   // git push
 		agent.NewGitLogTool(""),    // git log
+		agent.NewGitBisectTool(""), // Find the first bad commit with a test command
 		// Preview (diff before write)
 		agent.NewPreviewWriteTool(""), // Preview write changes
 		agent.NewPreviewEditTool(""),  // Preview edit changes
@@ -109,11 +246,46 @@ func NewAgentService(cfg *config.Config) *AgentService {
 		agent.NewProcessTool(""), // Background process management
 		// Multi-file patches
 		agent.NewApplyPatchTool(""), // Apply structured patches
+		// Generation from exemplars
+		agent.NewGenerateLikeTool(""), // New file modeled on an existing one
 		// RAG tools (requires index: zen-claw index build)
 		agent.NewCodeSearchTool(""), // Search indexed codebase
 		agent.NewFindSymbolTool(""), // Find symbol definitions
 		agent.NewContextTool(""),    // Get relevant context
+		// Go refactoring (AST-based, compile-verified)
+		agent.NewGoRenameTool(""),          // Rename identifier across module
+		agent.NewGoMoveFuncTool(""),        // Move function between packages
+		agent.NewGoOrganizeImportsTool(""), // Remove unused / sort imports
+		// Project analysis
+		agent.NewCoverageTool(""),      // Per-file/function test coverage
+		agent.NewGoBenchTool(""),       // Benchmarks compared with a baseline
+		agent.NewPprofTool(""),         // Top functions / annotated source of a profile
+		agent.NewGoDepsTool(""),        // Module list, dependency chains, govulncheck
+		agent.NewProjectTasksTool(""),  // Makefile/Taskfile/package.json/justfile tasks
+		agent.NewTerraformPlanTool(""), // Sandboxed terraform plan with risk summary
+		// Operations (read-only)
+		agent.NewK8sTool(""),                              // kubectl get/describe/logs/events/top
+		agent.NewRunbookSearchTool(cfg.Incident.Runbooks), // Search the runbooks
+		// Large outputs trimmed from context
+		agent.NewExpandResultTool(), // Re-read a stored tool output by reference
+	}
+
+	// Ticket tools (Jira/Linear), when workspaces are configured
+	if len(cfg.Tickets.Workspaces) > 0 {
+		tools = append(tools, agent.NewTicketTools(ticketWorkspaces(cfg.Tickets))...)
+	}
+	// Docs tools (Confluence/Notion), when a wiki is configured
+	if sources := docsSources(cfg.Docs); len(sources) > 0 {
+		tools = append(tools, agent.NewDocsTools(sources)...)
 	}
+	// Artifact upload (S3/GCS), when a bucket is configured
+	if store := artifactStore(cfg.Artifacts); store != nil {
+		tools = append(tools, agent.NewUploadArtifactTool("", store, int64(cfg.Artifacts.MaxSizeMB)<<20))
+	}
+
+	// Language server tools (gopls/tsserver started lazily on first use)
+	lspManager := lsp.NewManager()
+	tools = append(tools, lspManager.GetTools("")...)
 
 	// Load plugins from ~/.zen/zen-claw/plugins/
 	pluginLoader := plugins.NewLoader(cfg.GetPluginDir())
@@ -156,14 +328,58 @@ func NewAgentService(cfg *config.Config) *AgentService {
 		}
 	}
 
-	return &AgentService{
+	// Seed routing heuristics with persisted ratings
+	if sessionStore != nil {
+		if totals, err := sessionStore.FeedbackTotals(); err == nil {
+			aiRouter.feedback.seed(totals)
+		}
+	}
+
+	// Opt-in dataset recording
+	var rec *recorder.Recorder
+	if cfg.Recording.Enabled {
+		rec, err = recorder.New(cfg.GetRecordingDir(), cfg.Recording.Providers)
+		if err != nil {
+			log.Printf("Warning: Recording disabled: %v", err)
+		} else {
+			log.Printf("[Recorder] Recording runs to %s", rec.Dir())
+		}
+	}
+
+	// Tool call audit log
+	var auditLog *os.File
+	if cfg.Tools.AuditLog != "" {
+		auditLog, err = os.OpenFile(agent.ExpandPath(cfg.Tools.AuditLog), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Printf("Warning: Tool audit log disabled: %v", err)
+			auditLog = nil
+		}
+	}
+
+	s := &AgentService{
 		config:           cfg,
 		aiRouter:         aiRouter,
 		tools:            tools,
 		sessionStore:     sessionStore,
 		fallbackSessions: make(map[string]*agent.Session),
 		mcpClient:        mcpClient,
+		lspManager:       lspManager,
+		recorder:         rec,
+		budgets:          newProjectBudgets(cfg.Routing.ProjectBudgets),
+		janitor:          newSessionJanitor(sessionStore, cfg.Sessions.Retention),
+		usagePusher:      newUsagePusher(sessionStore, cfg.Usage.Push),
+		toolMetrics:      agent.NewToolMetrics(),
+		auditLog:         auditLog,
+		runs:             newRunRegistry(),
+		turns:            newSessionTurns(),
+		runHistory:       newRunHistory(sessionStore),
+		probe:            newCapabilityProbe(sessionStore),
+		scrubber:         newScrubber(cfg.Privacy),
+		usage:            &usageLog{store: sessionStore},
+		explainCache:     agent.NewResultCache(0, 0),
 	}
+	s.restoreUsage()
+	return s
 }
 
 // ChatRequest is an alias to the shared type
@@ -172,14 +388,19 @@ type ChatRequest = types.ChatRequest
 // ChatResponse represents a chat response from the agent service
 // Uses typed SessionInfo internally (converts to map for JSON wire format)
 type ChatResponse struct {
-	SessionID   string             `json:"session_id"`
-	Result      string             `json:"result"`
-	SessionInfo agent.SessionStats `json:"session_info"`
-	Error       string             `json:"error,omitempty"`
+	SessionID   string               `json:"session_id"`
+	Result      string               `json:"result"`
+	SessionInfo agent.SessionStats   `json:"session_info"`
+	Error       string               `json:"error,omitempty"`
+	ErrorCode   types.ErrorCode      `json:"error_code,omitempty"`
+	Output      json.RawMessage      `json:"output,omitempty"`     // Set when response_schema was given
+	RecordID    string               `json:"record_id,omitempty"`  // Dataset record, when recording is enabled
+	StepLimit   *types.StepLimit     `json:"step_limit,omitempty"` // Set when the run stopped at max_steps
+	Review      *types.ReviewOutcome `json:"review,omitempty"`     // Set when the changes were self-reviewed
 }
 
 // ProgressCallback is a function called for each progress event
-type ProgressCallback func(event map[string]interface{})
+type ProgressCallback = types.ProgressCallback
 
 // Chat handles a chat request using the agent service (no progress)
 func (s *AgentService) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
@@ -188,32 +409,114 @@ func (s *AgentService) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 
 // ChatWithProgress handles a chat request with progress callback for streaming
 func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, progressCb ProgressCallback) (*ChatResponse, error) {
+	if req.Language != "" && req.Language != "default" && i18n.Normalize(req.Language) == "" {
+		return nil, types.Errorf(types.ErrInvalidArgument, "invalid language %q (use a tag like en or pt-BR)", req.Language)
+	}
+	switch req.DirtyTree {
+	case "", agent.DirtyTreeWarn, agent.DirtyTreeStash, agent.DirtyTreeOff:
+	default:
+		return nil, types.Errorf(types.ErrInvalidArgument, "invalid dirty_tree %q (use warn, stash or off)", req.DirtyTree)
+	}
+
+	// Turns in a session run one at a time; a new session can't be busy
+	if req.SessionID != "" {
+		release, err := s.turns.acquire(ctx, req.SessionID, req.Queue, func() {
+			if progressCb != nil {
+				progressCb(types.ProgressEvent{
+					Version:   types.ProgressSchemaVersion,
+					Type:      "queued",
+					SessionID: req.SessionID,
+					Message:   fmt.Sprintf("Waiting for the running turn in session '%s' to finish", req.SessionID),
+				})
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
 	// Get or create session
 	session, resumed := s.getOrCreateSessionWithInfo(req.SessionID)
 
 	// Notify if session was resumed with existing context
 	if resumed && progressCb != nil {
 		msgCount := len(session.GetMessages())
-		progressCb(map[string]interface{}{
-			"type":          "session_resumed",
-			"session_id":    req.SessionID,
-			"message_count": msgCount,
-			"message":       fmt.Sprintf("Resumed session '%s' with %d messages (context restored)", req.SessionID, msgCount),
+		progressCb(types.ProgressEvent{
+			Version:   types.ProgressSchemaVersion,
+			Type:      "session_resumed",
+			SessionID: req.SessionID,
+			Message:   fmt.Sprintf("Resumed session '%s' with %d messages (context restored)", req.SessionID, msgCount),
+			Data:      map[string]interface{}{"message_count": msgCount},
 		})
 	}
 
-	// Set working directory if provided
+	// Set working directory if provided. Whatever the session ends up in (a
+	// resumed session's directory, or the gateway's without one) must be
+	// inside limits.working_dirs.
 	if req.WorkingDir != "" {
 		session.SetWorkingDir(req.WorkingDir)
 	}
+	workingDir := session.GetWorkingDir()
+	if workingDir == "" {
+		workingDir = "."
+	}
+	if err := agent.GetWorkingDirRoots().Check(workingDir); err != nil {
+		return nil, err
+	}
+
+	// Merge client-provided environment (secret refs are resolved per command, not here)
+	if len(req.Env) > 0 {
+		session.SetEnv(req.Env)
+	}
+	if req.Limits != nil {
+		session.SetResourceLimits(req.Limits)
+	}
+
+	// Labels and project (derived from the git remote unless given)
+	session.AddTags(req.Tags...)
+	if req.Project != "" {
+		session.SetProject(req.Project)
+	} else if session.GetProject() == "" {
+		session.SetProject(projectFromDir(session.GetWorkingDir()))
+	}
+
+	// The .zenclaw.yaml of the repo the working dir is in (precedence: see
+	// config.RepoConfig)
+	repoCfg, err := config.LoadRepoConfig(session.GetWorkingDir())
+	if err != nil {
+		return nil, types.Errorf(types.ErrInvalidArgument, "%v", err)
+	}
+	if repoCfg != nil && s.budgets != nil {
+		s.budgets.setRepoBudget(session.GetProject(), repoCfg.BudgetUSD)
+	}
 
-	// Determine provider and model
-	providerName := req.Provider
-	modelName := req.Model
+	// Provider, model, thinking level and language given in a request become
+	// the session's, so any client resuming it keeps using them
+	if req.Provider != "" || req.Model != "" {
+		providerName := req.Provider
+		// If model is specified but provider isn't, try to infer provider from model name
+		if providerName == "" {
+			providerName = s.inferProviderFromModel(req.Model)
+		}
+		session.SetModel(providerName, req.Model)
+	}
+	if req.ThinkingLevel != "" {
+		session.SetThinkingLevel(req.ThinkingLevel)
+	}
+	if req.Language == "default" {
+		session.SetLanguage("")
+	} else if req.Language != "" {
+		session.SetLanguage(i18n.Normalize(req.Language))
+	}
 
-	// If model is specified but provider isn't, try to infer provider from model name
-	if modelName != "" && providerName == "" {
-		providerName = s.inferProviderFromModel(modelName)
+	// Determine provider and model: the session's, else the repo's
+	providerName, modelName := session.GetModel()
+	if providerName == "" && modelName == "" && repoCfg != nil {
+		providerName, modelName = repoCfg.Provider, repoCfg.Model
+		if providerName == "" && modelName != "" {
+			providerName = s.inferProviderFromModel(modelName)
+		}
 	}
 
 	// If provider still not determined, use default
@@ -228,11 +531,17 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 
 	// Emit initial progress
 	if progressCb != nil {
-		progressCb(map[string]interface{}{
-			"type":     "start",
-			"provider": providerName,
-			"model":    modelName,
-			"message":  fmt.Sprintf("Starting with %s/%s", providerName, modelName),
+		message := fmt.Sprintf("Starting with %s/%s", providerName, modelName)
+		if repoCfg != nil {
+			message += " (repo config " + repoCfg.Path + ")"
+		}
+		progressCb(types.ProgressEvent{
+			Version:   types.ProgressSchemaVersion,
+			Type:      "start",
+			Provider:  providerName,
+			Model:     modelName,
+			SessionID: session.ID,
+			Message:   message,
 		})
 	}
 
@@ -241,36 +550,113 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 	if maxSteps == 0 {
 		maxSteps = s.config.GetMaxSteps()
 	}
+	if repoCfg != nil && repoCfg.MaxSteps > 0 && repoCfg.MaxSteps < maxSteps {
+		maxSteps = repoCfg.MaxSteps
+	}
 
 	// Create AI caller for gateway
 	aiCaller := &GatewayAICaller{
 		aiRouter:      s.aiRouter,
 		provider:      providerName,
 		model:         modelName,
-		thinkingLevel: ai.ThinkingLevel(req.ThinkingLevel),
+		thinkingLevel: ai.ThinkingLevel(session.GetThinkingLevel()),
+		project:       session.GetProject(),
+		sessionID:     session.ID,
+		budgets:       s.budgets,
+		usage:         s.usage,
+	}
+	// Personal data stays out of prompts where the workspace asks for it
+	if scrubber := s.scrubberFor(session.GetProject()); scrubber != nil {
+		aiCaller.scrubber = scrubber
+		aiCaller.onRedact = redactionReporter(session.ID, providerName, progressCb)
 	}
 
 	// Create agent with progress callback
-	agentInstance := agent.NewAgent(aiCaller, s.tools, maxSteps)
+	tools := s.tools
+	switch {
+	case req.Explain:
+		tools = agent.ExplainTools(tools)
+	case req.ReadOnly || repoCfg != nil && repoCfg.ReadOnly():
+		tools = agent.ReadOnlyTools(tools)
+	}
+	agentInstance := agent.NewAgent(aiCaller, tools, maxSteps)
+
+	// Cross-cutting tool concerns: audit, metrics, per-run cache of read-only calls
+	if s.auditLog != nil {
+		agentInstance.Use(agent.AuditMiddleware(s.auditLog))
+	}
+	agentInstance.Use(s.toolMetrics.Middleware(), agent.ProtectedPathsMiddleware(), agent.ExecApprovalMiddleware(), agent.EgressMiddleware(), agent.PostProcessMiddleware(), agent.CacheMiddleware())
+	if req.Explain {
+		agentInstance.Use(s.explainCache.Middleware())
+	}
+	if repoCfg != nil {
+		if len(repoCfg.ProtectedPaths) > 0 {
+			agentInstance.Use(agent.NewProtectedPaths(repoCfg.ProtectedPaths).Middleware(repoCfg.Root))
+		}
+		agentInstance.SetInstructions(repoCfg.Instructions)
+	}
+
+	// Prompting and sampling suited to the model family; configured params
+	// beat the profile's, the request's beat both
+	profile := agent.ProfileFor(s.config.GetModelProfile(modelName))
+	if caps, ok := s.modelCapabilities(ctx, providerName, modelName, progressCb); ok {
+		profile = profile.Adapt(caps)
+	}
+	agentInstance.SetPromptProfile(profile)
+	params := profile.Params.Merge(s.config.GetModelParams(providerName, modelName))
+	if req.Params != nil {
+		params = params.Merge(*req.Params)
+	}
+	agentInstance.SetModelParams(params)
+
+	// Answers in the session's language (code and tool calls stay in English)
+	language := session.GetLanguage()
+	if language == "" {
+		language = s.config.Default.Language
+	}
+	agentInstance.SetLanguage(language)
+
+	// Size listing/search results to what the model's context can still take
+	agentInstance.SetContextWindow(contextBudget(providerName, ai.ChatRequest{
+		Model:                   modelName,
+		MaxTokens:               params.MaxTokens,
+		ContextLimit:            session.GetContextLimit(),
+		QwenLargeContextEnabled: session.GetQwenLargeContextEnabled(),
+	}))
 
 	// Set progress callback on agent if provided
 	if progressCb != nil {
+		ticker := &costTicker{provider: providerName, model: modelName}
 		agentInstance.SetProgressCallback(func(event agent.ProgressEvent) {
-			progressCb(map[string]interface{}{
-				"type":    event.Type,
-				"step":    event.Step,
-				"message": event.Message,
-				"data":    event.Data,
-			})
+			ticker.observe(&event)
+			progressCb(event)
 		})
 	}
 
+	// Structured output: final answer must match the caller's schema
+	if req.ResponseSchema != nil {
+		agentInstance.SetResponseSchema(req.ResponseSchema)
+	}
+
+	// Self-review of the changes before the run is finalized
+	if review := s.selfReview(req.Review); review.Enabled {
+		agentInstance.SetSelfReview(s.reviewer(review, aiCaller))
+	}
+
+	// The user's uncommitted work: warn the model, or stash it for the run
+	dirtyTree := req.DirtyTree
+	if dirtyTree == "" {
+		dirtyTree = s.config.Agent.DirtyTree
+	}
+	agentInstance.SetDirtyTree(dirtyTree)
+
 	// Set stream callback for token-by-token streaming
 	if req.Stream && progressCb != nil {
 		agentInstance.SetStreamCallback(func(token string) {
-			progressCb(map[string]interface{}{
-				"type":    "token",
-				"message": token,
+			progressCb(types.ProgressEvent{
+				Version: types.ProgressSchemaVersion,
+				Type:    "token",
+				Message: token,
 			})
 		})
 	}
@@ -281,7 +667,11 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 	// We create a new context with a generous timeout for large tasks.
 	// This is similar to how Cursor handles large tasks - they run in background
 	// and are not tied to the HTTP request lifecycle.
-	agentCtx, agentCancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	// It can be canceled through the session (CancelSession).
+	runCtx, cancelRun := context.WithCancelCause(context.Background())
+	defer cancelRun(nil)
+	defer s.runs.start(session.ID, cancelRun)()
+	agentCtx, agentCancel := context.WithTimeout(runCtx, 30*time.Minute)
 	defer agentCancel()
 
 	// Also monitor HTTP context for client disconnection (graceful abort)
@@ -289,7 +679,13 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 	go func() {
 		select {
 		case <-ctx.Done():
-			// HTTP client disconnected - but we let the current step finish
+			// A caller canceling on purpose (CANCELED cause, e.g. an
+			// abandoned stream) stops the run; a disconnected HTTP client
+			// doesn't
+			if cause := context.Cause(ctx); types.CodeOf(cause) == types.ErrCanceled && !errors.Is(cause, context.Canceled) {
+				cancelRun(cause)
+				return
+			}
 			log.Printf("[AgentService] HTTP context cancelled, agent will complete current step")
 		case <-done:
 			// Agent finished normally
@@ -299,30 +695,36 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 
 	// Run agent with detached context
 	startTime := time.Now()
+	priorMessages := len(session.GetMessages())
 	updatedSession, result, err := agentInstance.Run(agentCtx, session, req.UserInput)
 	duration := time.Since(startTime)
+	recordID := s.recordRun(updatedSession, priorMessages, providerName, modelName, result, err)
+	updatedSession.SetLastExchange(agent.Exchange{Provider: providerName, Model: modelName, RecordID: recordID})
+	if req.Pin && len(updatedSession.GetMessages()) > priorMessages {
+		if _, err := updatedSession.PinMessage(priorMessages, true); err != nil {
+			log.Printf("[AgentService] Failed to pin message: %v", err)
+		}
+	}
 
+	// Canceled runs keep the work done so far, as do crashed ones (the
+	// agent repaired the session to be continued)
+	if code := types.CodeOf(err); code == types.ErrCanceled || code == types.ErrInternal && !updatedSession.RecoveredAt().IsZero() {
+		s.saveSession(updatedSession)
+	}
 	if err != nil {
 		return &ChatResponse{
 			SessionID:   updatedSession.ID,
 			Result:      "",
 			SessionInfo: updatedSession.GetStats(),
 			Error:       err.Error(),
+			ErrorCode:   types.CodeOf(err),
+			RecordID:    recordID,
 		}, nil // Return error in response, not as Go error
 	}
 
-	// Save session - only persist explicitly named sessions
-	// Auto-generated sessions (session_*) stay in memory only (like Cursor)
-	if isNamedSession(updatedSession.ID) && s.sessionStore != nil {
-		if err := s.sessionStore.SaveSession(updatedSession); err != nil {
-			log.Printf("Warning: Failed to save session %s: %v", updatedSession.ID, err)
-		}
-	} else {
-		// Keep in memory for conversation continuity within this run
-		s.fallbackMu.Lock()
-		s.fallbackSessions[updatedSession.ID] = updatedSession
-		s.fallbackMu.Unlock()
-	}
+	s.ensureTitle(updatedSession, providerName, modelName)
+
+	s.saveSession(updatedSession)
 
 	// Get session stats
 	stats := updatedSession.GetStats()
@@ -330,11 +732,165 @@ func (s *AgentService) ChatWithProgress(ctx context.Context, req ChatRequest, pr
 	log.Printf("[AgentService] Session %s: %d messages, %v duration",
 		stats.SessionID, stats.MessageCount, duration.Round(time.Millisecond))
 
-	return &ChatResponse{
+	resp := &ChatResponse{
 		SessionID:   stats.SessionID,
 		Result:      result,
 		SessionInfo: stats,
-	}, nil
+		RecordID:    recordID,
+	}
+	if req.ResponseSchema != nil {
+		resp.Output = json.RawMessage(result)
+	}
+	if agentInstance.StepLimitReached() {
+		resp.StepLimit = &types.StepLimit{Steps: maxSteps, Extend: types.ExtendSteps(maxSteps)}
+	}
+	resp.Review = agentInstance.ReviewOutcome()
+	return resp, nil
+}
+
+// selfReview returns the review settings for a request: the request's,
+// with unset fields taken from agent.review, or agent.review itself
+func (s *AgentService) selfReview(override *types.SelfReview) types.SelfReview {
+	review := s.config.Agent.Review
+	if override == nil {
+		return review
+	}
+	merged := *override
+	if merged.Provider == "" && merged.Model == "" {
+		merged.Provider, merged.Model = review.Provider, review.Model
+	}
+	if merged.VerifyCommand == "" {
+		merged.VerifyCommand = review.VerifyCommand
+	}
+	if merged.MaxRounds == 0 {
+		merged.MaxRounds = review.MaxRounds
+	}
+	return merged
+}
+
+// reviewer sets up the agent's review pass; a reviewer with its own
+// provider or model gets its own caller (the session's pins the model)
+func (s *AgentService) reviewer(review types.SelfReview, caller *GatewayAICaller) *agent.SelfReview {
+	r := &agent.SelfReview{
+		VerifyCommand: review.VerifyCommand,
+		MaxRounds:     review.MaxRounds,
+	}
+	if review.Provider == "" && review.Model == "" {
+		return r
+	}
+	reviewCaller := *caller
+	if review.Provider != "" {
+		reviewCaller.provider = review.Provider
+		reviewCaller.model = s.config.GetModel(review.Provider)
+	}
+	if review.Model != "" {
+		reviewCaller.model = review.Model
+	}
+	r.Caller, r.Model = &reviewCaller, reviewCaller.model
+	return r
+}
+
+// saveSession stores a session after a run. Only explicitly named sessions
+// are persisted; auto-generated ones (session_*) stay in memory only (like
+// Cursor).
+func (s *AgentService) saveSession(session *agent.Session) {
+	if isNamedSession(session.ID) && s.sessionStore != nil {
+		if err := s.sessionStore.SaveSession(session); err != nil {
+			log.Printf("Warning: Failed to save session %s: %v", session.ID, err)
+		}
+		return
+	}
+	// Keep in memory for conversation continuity within this run
+	s.fallbackMu.Lock()
+	s.fallbackSessions[session.ID] = session
+	s.fallbackMu.Unlock()
+}
+
+// SessionBusy reports whether a turn is running in the session
+func (s *AgentService) SessionBusy(sessionID string) bool {
+	return s.turns.busy(sessionID)
+}
+
+// CancelSession cancels the session's in-flight requests; they stop at the
+// agent's next safe point and keep the work done so far
+func (s *AgentService) CancelSession(sessionID string) (int, error) {
+	n := s.runs.cancel(sessionID, types.Errorf(types.ErrCanceled, "canceled by the client"))
+	if n == 0 {
+		return 0, types.Errorf(types.ErrNotFound, "no request running for session %s", sessionID)
+	}
+	log.Printf("[AgentService] Canceled %d request(s) of session %s", n, sessionID)
+	return n, nil
+}
+
+// AllowProtected lets (or stops letting) tools in a session modify paths
+// matching protected patterns, and returns the protected and allowed patterns
+func (s *AgentService) AllowProtected(sessionID string, allow, revoke []string) (protected, allowed []string, err error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	protected = agent.GetProtectedPaths().Patterns()
+	for _, p := range allow {
+		if p != "*" && !slices.Contains(protected, p) {
+			return nil, nil, types.Errorf(types.ErrInvalidArgument, "%q is not a protected pattern (protected: %s)", p, strings.Join(protected, ", "))
+		}
+	}
+	session.AllowProtected(allow, revoke)
+	if len(allow) > 0 {
+		log.Printf("[AgentService] Session %s allowed to modify protected paths: %s", sessionID, strings.Join(allow, ", "))
+	}
+	return protected, session.ProtectedAllowed(), nil
+}
+
+// ApproveExec lets (or stops letting) a session run shell commands of the
+// given classes under the read-only exec approval policy, and returns the
+// policy and the approved classes
+func (s *AgentService) ApproveExec(sessionID string, allow, revoke []string) (policy string, approved []string, err error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, c := range allow {
+		if c != "*" && shellrisk.Class(c).Severity() < 0 {
+			return "", nil, types.Errorf(types.ErrInvalidArgument, "%q is not a command class (use mutating, network-egress, destructive or *)", c)
+		}
+	}
+	session.ApproveExec(allow, revoke)
+	if len(allow) > 0 {
+		log.Printf("[AgentService] Session %s approved %s commands", sessionID, strings.Join(allow, ", "))
+	}
+	return agent.GetExecApproval().Policy(), session.ExecApproved(), nil
+}
+
+// recordRun writes the run to the dataset recorder (if enabled) and returns the record ID.
+// priorMessages is the message count before the run, so the request context and the
+// run's own tool trace can be told apart.
+func (s *AgentService) recordRun(session *agent.Session, priorMessages int, provider, model, result string, runErr error) string {
+	if !s.recorder.Enabled(provider) {
+		return ""
+	}
+	messages := session.GetMessages()
+	if len(messages) <= priorMessages {
+		return "" // Slash command or nothing reached the model
+	}
+
+	rec := recorder.Record{
+		SessionID: session.ID,
+		Provider:  provider,
+		Model:     model,
+		Messages:  messages[:priorMessages+1],
+		Response:  result,
+		ToolTrace: recorder.TraceFromMessages(messages[priorMessages+1:]),
+	}
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	id, err := s.recorder.Record(rec)
+	if err != nil {
+		log.Printf("[Recorder] Warning: failed to record run: %v", err)
+		return ""
+	}
+	return id
 }
 
 // GetSession returns a session by ID
@@ -429,17 +985,27 @@ AVAILABLE TOOLS:
 - read_file: Read file contents
 - write_file: Create or overwrite files
 - edit_file: Make precise string replacements in files
+- multi_edit: Apply several replacements to one file atomically
 - append_file: Append content to files
-- list_dir: List directory contents
+- begin_write / append_chunk / commit_write: Write a file too large for one write_file call in chunks
+- generate_like: Create a file modeled on an existing one (handler, test, migration), filling only the parts that differ
+- list_dir: List directory contents (recursive: true, format: "tree" for a project overview in one call)
 - search_files: Search for patterns in files (grep-like)
 - system_info: Get system information
+- find_definition / find_references / rename_symbol / diagnostics: Precise code navigation via language server
+- project_tasks: List the project's Makefile/Taskfile/package.json/justfile tasks
+- go_bench / pprof: Run Go benchmarks against a baseline (git ref or saved run); read CPU/memory profiles
+- expand_result: Re-read a large tool output shortened in context (lines marked [full output: expand_result ref=...])
 
 WORKFLOW:
 1. For simple questions: Answer directly
 2. For code tasks: Use tools to read, analyze, then write/edit
 3. Be efficient - don't over-explore
 
-When editing files, use edit_file with unique string matches. For new files, use write_file.`,
+When editing files, use edit_file with unique string matches. For new files, use write_file; for files over ~500 lines, use begin_write and chunks of a few hundred lines.
+To build, test or lint, check project_tasks first and run the project's own commands.
+For performance work, benchmark against a baseline with go_bench and put the regression/improvement deltas in your final answer.
+Calls in one response can run in parallel: add "depends_on": [] to a call that needs nothing else from the response, or "depends_on": [1, 2] (positions of earlier calls in the response) when it needs their results or effects.`,
 	})
 
 	// Only persist named sessions, not auto-generated ones
@@ -574,6 +1140,21 @@ func (s *AgentService) GetCacheStats() (hits, misses, size int, hitRate float64)
 	return s.aiRouter.GetCacheStats()
 }
 
+// GetOptimizerStats returns measured cost optimizer savings (nil when off)
+func (s *AgentService) GetOptimizerStats() *OptimizerStats {
+	return s.aiRouter.GetOptimizerStats()
+}
+
+// GetToolStats returns per-tool call counts and latency
+func (s *AgentService) GetToolStats() []agent.ToolStats {
+	return s.toolMetrics.Snapshot()
+}
+
+// GetPanicCounts returns recovered agent panics by where they happened
+func (s *AgentService) GetPanicCounts() map[string]int64 {
+	return agent.PanicCounts()
+}
+
 // GetCircuitStats returns circuit breaker statistics
 func (s *AgentService) GetCircuitStats() map[string]map[string]interface{} {
 	return s.aiRouter.GetCircuitStats()
@@ -597,7 +1178,19 @@ func (s *AgentService) GetMCPToolCount() int {
 
 // Close cleans up resources
 func (s *AgentService) Close() {
+	if s.janitor != nil {
+		s.janitor.Close()
+	}
+	if s.usagePusher != nil {
+		s.usagePusher.Close()
+	}
+	if s.auditLog != nil {
+		s.auditLog.Close()
+	}
 	if s.mcpClient != nil {
 		s.mcpClient.Close()
 	}
+	if s.lspManager != nil {
+		s.lspManager.Close()
+	}
 }
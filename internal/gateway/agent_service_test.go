@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestCostTicker(t *testing.T) {
+	ticker := &costTicker{provider: "deepseek", model: "deepseek-chat"}
+
+	step := types.ProgressEvent{Type: "step", Step: 1}
+	ticker.observe(&step)
+	if step.Total != nil {
+		t.Errorf("total before any model call = %+v, want nil", step.Total)
+	}
+
+	var events []types.ProgressEvent
+	for _, usage := range []types.Usage{{InputTokens: 10000, OutputTokens: 500}, {InputTokens: 12000, OutputTokens: 300}} {
+		event := types.ProgressEvent{Type: "ai_response", Usage: &usage}
+		ticker.observe(&event)
+		events = append(events, event)
+	}
+
+	want := cost.CalculateUSD("deepseek", "deepseek-chat", 10000, 500)
+	if got := events[0].Usage.CostUSD; got != want {
+		t.Errorf("first call cost = %v, want %v", got, want)
+	}
+	if total := events[1].Total; total.InputTokens != 22000 || total.OutputTokens != 800 || total.CostUSD <= want {
+		t.Errorf("running total = %+v", total)
+	}
+	if events[0].Total.InputTokens != 10000 {
+		t.Errorf("earlier event's total changed to %+v", events[0].Total)
+	}
+
+	tool := types.ProgressEvent{Type: "tool_call"}
+	ticker.observe(&tool)
+	if tool.Total == nil || tool.Total.InputTokens != 22000 {
+		t.Errorf("events between model calls should carry the total, got %+v", tool.Total)
+	}
+	if s := tool.Total.String(); !strings.HasPrefix(s, "≈$") || !strings.HasSuffix(s, ", 22.8K tokens") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestRunRegistryCancel(t *testing.T) {
+	runs := newRunRegistry()
+	ctx1, cancel1 := context.WithCancelCause(context.Background())
+	ctx2, cancel2 := context.WithCancelCause(context.Background())
+	done1 := runs.start("s1", cancel1)
+	runs.start("s1", cancel2)
+
+	cause := types.Errorf(types.ErrCanceled, "canceled by the client")
+	if n := runs.cancel("s2", cause); n != 0 {
+		t.Errorf("cancel(other session) = %d, want 0", n)
+	}
+	if n := runs.cancel("s1", cause); n != 2 {
+		t.Errorf("cancel(s1) = %d, want 2", n)
+	}
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		if context.Cause(ctx) != cause {
+			t.Errorf("cause = %v, want %v", context.Cause(ctx), cause)
+		}
+	}
+
+	done1()
+	if n := runs.cancel("s1", cause); n != 1 {
+		t.Errorf("cancel(s1) after one finished = %d, want 1", n)
+	}
+}
+
+// chatFunc is a provider answering with a function
+type chatFunc func(req ai.ChatRequest) (*ai.ChatResponse, error)
+
+func (f chatFunc) Name() string        { return "func" }
+func (f chatFunc) SupportsTools() bool { return true }
+func (f chatFunc) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	return f(req)
+}
+func (f chatFunc) ChatStream(ctx context.Context, req ai.ChatRequest, cb ai.StreamCallback) (*ai.ChatResponse, error) {
+	return f(req)
+}
+
+func TestCapabilityProbe(t *testing.T) {
+	// A model without function calling that ignores system prompts
+	var calls atomic.Int32
+	provider := chatFunc(func(req ai.ChatRequest) (*ai.ChatResponse, error) {
+		calls.Add(1)
+		switch {
+		case len(req.Tools) > 0:
+			return &ai.ChatResponse{Content: "I would call echo."}, nil
+		case req.ResponseSchema != nil:
+			return &ai.ChatResponse{Content: `{"ok": true}`}, nil
+		}
+		return &ai.ChatResponse{Content: "Hello! I'm fine."}, nil
+	})
+
+	probe := newCapabilityProbe(nil)
+	caps, ok := probe.capabilities(context.Background(), provider, "openai", "my-finetune")
+	if !ok || caps.Tools || !caps.JSONMode || caps.SystemPrompt || caps.ProbedAt.IsZero() {
+		t.Fatalf("capabilities = %+v, %v", caps, ok)
+	}
+	if _, ok := probe.capabilities(context.Background(), provider, "openai", "my-finetune"); !ok || calls.Load() != 3 {
+		t.Errorf("second lookup probed again: %d calls", calls.Load())
+	}
+	if got := probe.snapshot(); len(got) != 1 || got[0].Model != "my-finetune" {
+		t.Errorf("snapshot = %+v", got)
+	}
+
+	// Nothing is learned (or cached) from an unreachable provider
+	down := chatFunc(func(req ai.ChatRequest) (*ai.ChatResponse, error) {
+		return nil, errors.New("connection refused")
+	})
+	if _, ok := probe.capabilities(context.Background(), down, "openai", "other"); ok || probe.known("openai", "other") {
+		t.Error("failed probe was cached")
+	}
+}
@@ -39,6 +39,9 @@ type AIRouter struct {
 	// Cost optimization
 	optimizer *CostOptimizer
 	dedup     *RequestDeduplicator
+
+	// User ratings (demote providers users dislike in fallback chains)
+	feedback *feedbackTracker
 }
 
 // NewAIRouter creates a new AI router
@@ -76,6 +79,16 @@ func NewAIRouter(cfg *config.Config) *AIRouter {
 		log.Printf("Loaded AI provider: %s", name)
 	}
 
+	// The mock provider replays fixtures and needs no API key
+	if cfg.GetMock() != nil {
+		if provider, err := factory.CreateProvider("mock"); err != nil {
+			log.Printf("Warning: Failed to load provider mock: %v", err)
+		} else {
+			providersMap["mock"] = provider
+			log.Printf("Loaded AI provider: mock (fixtures in %s)", cfg.GetMock().Fixtures)
+		}
+	}
+
 	if len(providersMap) == 0 {
 		log.Printf("Warning: No AI providers loaded!")
 	}
@@ -101,6 +114,7 @@ func NewAIRouter(cfg *config.Config) *AIRouter {
 		usage:         cost.NewUsage(),
 		optimizer:     NewCostOptimizerWithConfig(&cfg.CostOptimization),
 		dedup:         NewRequestDeduplicator(time.Duration(cfg.GetDedupWindowSeconds()) * time.Second),
+		feedback:      newFeedbackTracker(),
 	}
 }
 
@@ -171,9 +185,10 @@ func (r *AIRouter) Chat(ctx context.Context, req ai.ChatRequest, preferredProvid
 
 		// Try this provider with circuit breaker + retry
 		var resp *ai.ChatResponse
+		providerReq := r.fitContext(req, providerName)
 		err := cb.Call(ctx, func() error {
 			var callErr error
-			resp, callErr = r.callWithRetry(ctx, provider, providerName, req)
+			resp, callErr = r.callWithRetry(ctx, provider, providerName, providerReq)
 			return callErr
 		})
 
@@ -279,6 +294,11 @@ func (r *AIRouter) GetCacheStats() (hits, misses, size int, hitRate float64) {
 	return r.cache.Stats()
 }
 
+// GetOptimizerStats returns measured cost optimizer savings (nil when off)
+func (r *AIRouter) GetOptimizerStats() *OptimizerStats {
+	return r.optimizer.Stats()
+}
+
 // GetCircuitStats returns circuit breaker statistics
 func (r *AIRouter) GetCircuitStats() map[string]map[string]interface{} {
 	return r.circuits.AllStats()
@@ -308,16 +328,17 @@ func (r *AIRouter) ChatStream(ctx context.Context, req ai.ChatRequest, preferred
 		log.Printf("[AIRouter] Streaming from provider: %s", providerName)
 
 		var resp *ai.ChatResponse
+		providerReq := r.fitContext(req, providerName)
 		err := cb.Call(ctx, func() error {
 			var callErr error
 			// Use provider's streaming method
 			if streamProvider, ok := provider.(interface {
 				ChatStream(context.Context, ai.ChatRequest, ai.StreamCallback) (*ai.ChatResponse, error)
 			}); ok {
-				resp, callErr = streamProvider.ChatStream(ctx, req, callback)
+				resp, callErr = streamProvider.ChatStream(ctx, providerReq, callback)
 			} else {
 				// Fallback to non-streaming
-				resp, callErr = provider.Chat(ctx, req)
+				resp, callErr = provider.Chat(ctx, providerReq)
 				if callErr == nil && callback != nil && resp.Content != "" {
 					callback(resp.Content)
 				}
@@ -391,7 +412,7 @@ func (r *AIRouter) getProviderChain(preferred string) []string {
 		}
 	}
 
-	return chain
+	return r.feedback.demoteDisliked(chain)
 }
 
 // getProviderChainForContext returns context-aware provider chain
@@ -451,7 +472,7 @@ func (r *AIRouter) getProviderChainForContext(preferred string, estimatedTokens
 		return r.getProviderChain("")
 	}
 
-	return chain
+	return r.feedback.demoteDisliked(chain)
 }
 
 // EstimateTokens estimates token count from messages
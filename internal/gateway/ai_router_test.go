@@ -2,11 +2,14 @@ package gateway
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // mockProvider implements ai.Provider for testing
@@ -94,7 +97,7 @@ func TestAIRouterCircuitStats(t *testing.T) {
 }
 
 func TestSemanticCache(t *testing.T) {
-	sc := NewSemanticCache(1*time.Hour, 100)
+	sc := NewSemanticCache(1*time.Hour, 100, 0)
 
 	t.Run("stores and retrieves similar queries", func(t *testing.T) {
 		// Use a query with enough keywords to be cached
@@ -193,3 +196,171 @@ func TestKeywordOverlap(t *testing.T) {
 		t.Errorf("keywordOverlap = %d, want 0", overlap2)
 	}
 }
+
+func TestFeedbackDemotesDislikedProviders(t *testing.T) {
+	tracker := newFeedbackTracker()
+	chain := []string{"deepseek", "qwen", "kimi"}
+
+	// Too few ratings: order unchanged
+	tracker.add("deepseek", "deepseek-chat", -1)
+	if got := tracker.demoteDisliked(chain); got[0] != "deepseek" {
+		t.Errorf("Expected no demotion below %d ratings, got %v", minFeedbackForRouting, got)
+	}
+
+	for i := 0; i < minFeedbackForRouting; i++ {
+		tracker.add("deepseek", "deepseek-reasoner", -1)
+		tracker.add("qwen", "qwen-plus", 1)
+	}
+	got := tracker.demoteDisliked(chain)
+	want := []string{"qwen", "kimi", "deepseek"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("demoteDisliked() = %v, want %v", got, want)
+		}
+	}
+
+	if r, err := ParseRating("👍"); err != nil || r != 1 {
+		t.Errorf("ParseRating(👍) = %d, %v", r, err)
+	}
+	if _, err := ParseRating("meh"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for unknown rating, got %v", err)
+	}
+}
+
+func TestTrimToBudget(t *testing.T) {
+	filler := strings.Repeat("word ", 400) // ~500 tokens
+	messages := []ai.Message{
+		{Role: "system", Content: "You are a coding agent."},
+		{Role: "user", Content: "Requirement: must keep the v1 API", Pinned: true},
+		{Role: "user", Content: "Decision: use postgres. " + filler},
+		{Role: "assistant", ToolCalls: []ai.ToolCall{{ID: "1", Name: "read_file", Args: map[string]interface{}{"path": "a.go"}}}},
+		{Role: "tool", ToolCallID: "1", Content: filler},
+		{Role: "assistant", Content: filler},
+		{Role: "user", Content: "Now add the endpoint"},
+	}
+
+	// Under budget: unchanged
+	if got, dropped := trimToBudget(messages, 100000); dropped != 0 || len(got) != len(messages) {
+		t.Fatalf("Expected no trimming under budget, dropped %d", dropped)
+	}
+
+	got, dropped := trimToBudget(messages, 1000)
+	if dropped != 3 {
+		t.Fatalf("dropped = %d, want 3 (oldest user turn and the tool call with its result)", dropped)
+	}
+	if got[0].Content != messages[0].Content {
+		t.Error("Expected system prompt first")
+	}
+	if got[1].Role != "system" || !strings.Contains(got[1].Content, "use postgres") {
+		t.Errorf("Expected summary of dropped messages second, got %q", got[1].Content)
+	}
+	if !got[2].Pinned {
+		t.Error("Expected pinned message to be kept")
+	}
+	for _, msg := range got {
+		if msg.Role == "tool" {
+			t.Error("Tool result kept without its tool call")
+		}
+	}
+	if last := got[len(got)-1]; last.Content != "Now add the endpoint" {
+		t.Errorf("Expected newest message last, got %q", last.Content)
+	}
+
+	// Qwen without large context is capped; /context-limit caps further
+	req := ai.ChatRequest{Model: "qwen3-coder-30b-a3b-instruct"}
+	if budget := contextBudget("qwen", req); budget > qwenSafeContext {
+		t.Errorf("qwen budget = %d, want <= %d", budget, qwenSafeContext)
+	}
+	req.QwenLargeContextEnabled = true
+	if budget := contextBudget("qwen", req); budget < 200000 {
+		t.Errorf("qwen large-context budget = %d, want >= 200000", budget)
+	}
+	req.ContextLimit = 8000
+	if budget := contextBudget("qwen", req); budget != 8000 {
+		t.Errorf("budget with /context-limit = %d, want 8000", budget)
+	}
+}
+
+func TestCostOptimizerKeepsPinned(t *testing.T) {
+	o := NewCostOptimizer()
+	o.MaxHistoryTurns = 6
+	o.MaxHistoryMessages = 4
+
+	requirement := "Requirement:   keep   the **v1** API"
+	messages := []ai.Message{{Role: "system", Content: "You are a coding agent."}}
+	messages = append(messages, ai.Message{Role: "user", Content: requirement, Pinned: true})
+	for i := 0; i < 8; i++ {
+		messages = append(messages, ai.Message{Role: "user", Content: fmt.Sprintf("question %d", i)})
+	}
+
+	optimized := o.OptimizeRequest(ai.ChatRequest{Messages: messages})
+	found := false
+	for _, msg := range optimized.Messages {
+		if msg.Pinned {
+			found = true
+			if msg.Content != requirement {
+				t.Errorf("Pinned message was modified: %q", msg.Content)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Pinned message was dropped")
+	}
+	if len(optimized.Messages) >= len(messages) {
+		t.Errorf("Expected history to be trimmed, got %d messages", len(optimized.Messages))
+	}
+}
+
+func TestCompressPromptKeepsCode(t *testing.T) {
+	o := NewCostOptimizer()
+
+	code := "```go\nfunc   ParseURL(s string)  error {\n\tif s == \"\" {\n\n\n\t\treturn ErrEmpty\n\t}\n}\n```"
+	input := "In order to fix   the bug, call `HTTPClient.Do`   with \"In Order To\".\n\n\n\n" + code + "\nDue to the fact that IDs are case-sensitive, keep `UserID`."
+	got := o.CompressPrompt(input)
+
+	want := "To fix the bug, call `HTTPClient.Do` with \"In Order To\".\n\n" + code + "\nBecause IDs are case-sensitive, keep `UserID`."
+	if got != want {
+		t.Errorf("CompressPrompt() =\n%s\nwant\n%s", got, want)
+	}
+
+	// An unclosed fence protects the rest of the message
+	unclosed := "Output:\n```\nA    B\n\n\n\nin order to"
+	if got := o.CompressPrompt(unclosed); got != unclosed {
+		t.Errorf("CompressPrompt() changed an unclosed code block: %q", got)
+	}
+}
+
+func TestCostOptimizerMeasurement(t *testing.T) {
+	o := NewCostOptimizer()
+	if o.Stats() != nil {
+		t.Error("Stats() should be nil when measurement is off")
+	}
+	o.MeasureSampleRate = 1
+
+	output := "exec: go test ./...\n" + strings.Repeat("ok  \tpkg/module\t0.01s\n", 2000)
+	messages := []ai.Message{
+		{Role: "system", Content: "You are a coding agent."},
+		{Role: "user", Content: "Run   the tests in order to check"},
+		{Role: "tool", Content: output},
+		{Role: "tool", Content: output},
+	}
+	o.OptimizeRequest(ai.ChatRequest{Messages: messages})
+
+	stats := o.Stats()
+	if stats == nil || stats.Sampled != 1 {
+		t.Fatalf("Stats() = %+v, want one sample", stats)
+	}
+	saved := map[string]int64{}
+	for _, p := range stats.Passes {
+		saved[p.Pass] = p.TokensSaved
+	}
+	if saved[passToolPruning] <= 0 || saved[passCompression] <= 0 {
+		t.Errorf("expected savings from pruning and compression, got %v", saved)
+	}
+	if saved[passTurnLimit]+saved[passCompression]+saved[passToolPruning]+saved[passSummarization] != stats.TokensBefore-stats.TokensAfter {
+		t.Errorf("pass savings %v don't add up to %d", saved, stats.TokensBefore-stats.TokensAfter)
+	}
+	if len(stats.Tools) != 1 || stats.Tools[0].Tool != "exec" || stats.Tools[0].Truncated != 1 || stats.Tools[0].MaxTokens != 4000 {
+		t.Errorf("Tools = %+v, want one truncated exec result under the 4000-token rule", stats.Tools)
+	}
+}
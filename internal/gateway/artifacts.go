@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"log"
+	"time"
+
+	"github.com/neves/zen-claw/internal/artifacts"
+	"github.com/neves/zen-claw/internal/config"
+)
+
+// artifactStore creates the bucket of upload_artifact, or nil when none is
+// configured or it lacks credentials
+func artifactStore(cfg config.ArtifactsConfig) *artifacts.Store {
+	if cfg.Bucket == "" {
+		return nil
+	}
+	id, secret, session := cfg.GetCredentials()
+	store, err := artifacts.New(artifacts.Config{
+		Provider:        cfg.Provider,
+		Bucket:          cfg.Bucket,
+		Region:          cfg.Region,
+		Endpoint:        cfg.Endpoint,
+		Prefix:          cfg.GetPrefix(),
+		AccessKeyID:     id,
+		SecretAccessKey: secret,
+		SessionToken:    session,
+		PublicURL:       cfg.PublicURL,
+		Expiry:          time.Duration(cfg.URLExpiryHours) * time.Hour,
+	})
+	if err != nil {
+		log.Printf("Warning: upload_artifact disabled: %v", err)
+		return nil
+	}
+	return store
+}
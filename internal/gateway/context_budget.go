@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/config"
+)
+
+const (
+	// qwenSafeContext bounds Qwen requests unless /qwen-large-context is on:
+	// long contexts make its API slow enough to hit client timeouts
+	qwenSafeContext = 32000
+
+	// defaultOutputReserve is kept free for the answer when MaxTokens is unset
+	defaultOutputReserve = 4000
+)
+
+// contextBudget returns how many tokens of messages a request may send to a
+// provider's model: the model's window minus room for tools and the answer,
+// capped by the session's /context-limit
+func contextBudget(providerName string, req ai.ChatRequest) int {
+	window := config.GetModelContextLimit(providerName, req.Model)
+	if providerName == "qwen" && !req.QwenLargeContextEnabled && window > qwenSafeContext {
+		window = qwenSafeContext
+	}
+
+	reserve := req.MaxTokens
+	if reserve <= 0 {
+		reserve = defaultOutputReserve
+	}
+	if len(req.Tools) > 0 {
+		if data, err := json.Marshal(req.Tools); err == nil {
+			reserve += len(data) / 4
+		}
+	}
+
+	budget := (window - reserve) * 9 / 10 // Margin for estimation error
+	if req.ContextLimit > 0 && req.ContextLimit < budget {
+		budget = req.ContextLimit
+	}
+	return budget
+}
+
+// fitContext trims a request's history to the target model's budget
+func (r *AIRouter) fitContext(req ai.ChatRequest, providerName string) ai.ChatRequest {
+	budget := contextBudget(providerName, req)
+	messages, dropped := trimToBudget(req.Messages, budget)
+	if dropped > 0 {
+		log.Printf("[AIRouter] Context for %s: summarized %d oldest messages to fit %d tokens", providerName, dropped, budget)
+		req.Messages = messages
+	}
+	return req
+}
+
+// messageTokens estimates a message's tokens, including tool call arguments
+func messageTokens(msg ai.Message) int {
+	tokens := estimateTokens(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		if data, err := json.Marshal(tc.Args); err == nil {
+			tokens += (len(tc.Name) + len(data)) / 4
+		}
+	}
+	return tokens + 4 // Role and framing
+}
+
+// trimToBudget drops the oldest messages until the rest fit in budget tokens
+// and puts a summary of what was dropped in their place. Leading system
+// messages, pinned messages and the newest turn are always kept; an assistant
+// tool call and its results are kept or dropped together. Returns the
+// messages and how many were dropped.
+func trimToBudget(messages []ai.Message, budget int) ([]ai.Message, int) {
+	total := 0
+	for _, msg := range messages {
+		total += messageTokens(msg)
+	}
+	if budget <= 0 || total <= budget {
+		return messages, 0
+	}
+
+	// Leading system prompt(s)
+	head := 0
+	for head < len(messages) && messages[head].Role == "system" {
+		head++
+	}
+
+	// Group the rest into units that must stay together
+	type unit struct {
+		start, end int // messages[start:end]
+		tokens     int
+		pinned     bool
+	}
+	var units []unit
+	for i := head; i < len(messages); {
+		u := unit{start: i, end: i + 1}
+		if messages[i].Role == "assistant" && len(messages[i].ToolCalls) > 0 {
+			for u.end < len(messages) && messages[u.end].Role == "tool" {
+				u.end++
+			}
+		}
+		for _, msg := range messages[u.start:u.end] {
+			u.tokens += messageTokens(msg)
+			u.pinned = u.pinned || msg.Pinned
+		}
+		units = append(units, u)
+		i = u.end
+	}
+	if len(units) == 0 {
+		return messages, 0
+	}
+
+	// Fixed cost: system prompt, pinned units, the newest unit, the summary
+	summaryBudget := budget / 10
+	used := summaryBudget
+	for _, msg := range messages[:head] {
+		used += messageTokens(msg)
+	}
+	keep := make([]bool, len(units))
+	keep[len(units)-1] = true
+	for i, u := range units {
+		if keep[i] || u.pinned {
+			keep[i] = true
+			used += u.tokens
+		}
+	}
+
+	// Fill the rest newest first; stop at the first unit that doesn't fit so
+	// the kept history stays contiguous
+	for i := len(units) - 2; i >= 0; i-- {
+		if keep[i] {
+			continue
+		}
+		if used+units[i].tokens > budget {
+			break
+		}
+		keep[i] = true
+		used += units[i].tokens
+	}
+
+	var dropped []ai.Message
+	var kept []ai.Message
+	for i, u := range units {
+		if keep[i] {
+			kept = append(kept, messages[u.start:u.end]...)
+		} else {
+			dropped = append(dropped, messages[u.start:u.end]...)
+		}
+	}
+	if len(dropped) == 0 {
+		return messages, 0
+	}
+
+	result := make([]ai.Message, 0, head+1+len(kept))
+	result = append(result, messages[:head]...)
+	result = append(result, ai.Message{
+		Role:    "system",
+		Content: "[Previous conversation summary]\n" + summarizeDropped(dropped, summaryBudget),
+	})
+	result = append(result, kept...)
+	return result, len(dropped)
+}
+
+// summarizeDropped condenses trimmed messages to at most maxTokens, one
+// short line per preserved item or topic
+func summarizeDropped(dropped []ai.Message, maxTokens int) string {
+	summary := createHistorySummary(dropped)
+	if flush := createMemoryFlush(dropped); flush != "" {
+		summary = flush + "\n\n" + summary
+	}
+
+	maxChars := maxTokens * 4
+	var sb strings.Builder
+	for _, line := range strings.Split(summary, "\n") {
+		line = truncateRunes(line, 200)
+		if sb.Len()+len(line)+1 > maxChars {
+			sb.WriteString("...")
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
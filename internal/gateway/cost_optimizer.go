@@ -2,10 +2,13 @@ package gateway
 
 import (
 	"fmt"
+	"math/rand"
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/neves/zen-claw/internal/agent"
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
 )
@@ -31,6 +34,10 @@ type CostOptimizer struct {
 	ToolRules map[string]ToolPruneRule
 	// Keep last N assistant messages unmodified
 	KeepLastAssistants int
+	// Share of requests (0-1) measured pass by pass for /stats
+	MeasureSampleRate float64
+
+	measurements *optimizerMeasurements
 }
 
 // NewCostOptimizer creates a cost optimizer with sensible defaults
@@ -86,6 +93,7 @@ func NewCostOptimizerWithConfig(cfg *config.CostOptimizationConfig) *CostOptimiz
 		if cfg.MaxToolResultTokens > 0 {
 			o.MaxToolResultTokens = cfg.MaxToolResultTokens
 		}
+		o.MeasureSampleRate = float64(cfg.MeasureSamplePercent) / 100
 
 		// Apply tool-specific rules from config
 		for toolName, rule := range cfg.ToolRules {
@@ -97,6 +105,7 @@ func NewCostOptimizerWithConfig(cfg *config.CostOptimizationConfig) *CostOptimiz
 		}
 	}
 
+	o.measurements = newOptimizerMeasurements()
 	return o
 }
 
@@ -104,23 +113,42 @@ func NewCostOptimizerWithConfig(cfg *config.CostOptimizationConfig) *CostOptimiz
 func (o *CostOptimizer) OptimizeRequest(req ai.ChatRequest) ai.ChatRequest {
 	optimized := req
 
+	// Sampled requests record tokens before and after each pass
+	var sample *optimizationSample
+	if o.MeasureSampleRate > 0 && o.measurements != nil && rand.Float64() < o.MeasureSampleRate {
+		sample = newOptimizationSample(optimized.Messages)
+	}
+
 	// 1. Hard limit on turns (drop oldest)
 	if o.MaxHistoryTurns > 0 && len(optimized.Messages) > o.MaxHistoryTurns {
 		optimized.Messages = o.EnforceHistoryTurnLimit(optimized.Messages)
 	}
+	sample.pass(passTurnLimit, optimized.Messages)
 
-	// 2. Compress prompts (whitespace, redundancy)
-	for i := range optimized.Messages {
-		optimized.Messages[i].Content = o.CompressPrompt(optimized.Messages[i].Content)
+	// 2. Compress prompts (whitespace, redundancy); pinned messages stay verbatim
+	compressed := make([]ai.Message, len(optimized.Messages))
+	copy(compressed, optimized.Messages)
+	for i := range compressed {
+		if !compressed[i].Pinned {
+			compressed[i].Content = o.CompressPrompt(compressed[i].Content)
+		}
 	}
+	optimized.Messages = compressed
+	sample.pass(passCompression, optimized.Messages)
 
 	// 3. Prune tool results with tool-specific rules
-	optimized.Messages = o.PruneToolResults(optimized.Messages)
+	optimized.Messages = o.pruneToolResults(optimized.Messages, sample.toolSavings())
+	sample.pass(passToolPruning, optimized.Messages)
 
 	// 4. Summarize old history if still too long
 	if len(optimized.Messages) > o.MaxHistoryMessages {
 		optimized.Messages = o.SummarizeHistory(optimized.Messages)
 	}
+	sample.pass(passSummarization, optimized.Messages)
+
+	if sample != nil {
+		o.measurements.record(sample)
+	}
 
 	// 5. Set smart output limits based on task
 	if optimized.MaxTokens == 0 {
@@ -130,7 +158,7 @@ func (o *CostOptimizer) OptimizeRequest(req ai.ChatRequest) ai.ChatRequest {
 	return optimized
 }
 
-// EnforceHistoryTurnLimit drops oldest messages beyond limit
+// EnforceHistoryTurnLimit drops oldest messages beyond limit (pinned messages are kept)
 func (o *CostOptimizer) EnforceHistoryTurnLimit(messages []ai.Message) []ai.Message {
 	if len(messages) <= o.MaxHistoryTurns {
 		return messages
@@ -155,12 +183,24 @@ func (o *CostOptimizer) EnforceHistoryTurnLimit(messages []ai.Message) []ai.Mess
 	}
 
 	if len(history) > keepCount {
-		dropped := len(history) - keepCount
-		history = history[dropped:]
+		toDrop := len(history) - keepCount
+		dropped := 0
+		var kept []ai.Message
+		for _, msg := range history {
+			if dropped < toDrop && !msg.Pinned {
+				dropped++
+				continue
+			}
+			kept = append(kept, msg)
+		}
+		history = kept
 
 		// Add note about dropped messages
-		if len(history) > 0 {
-			history[0].Content = fmt.Sprintf("[%d earlier messages dropped]\n\n%s", dropped, history[0].Content)
+		for i := range history {
+			if !history[i].Pinned {
+				history[i].Content = fmt.Sprintf("[%d earlier messages dropped]\n\n%s", dropped, history[i].Content)
+				break
+			}
 		}
 	}
 
@@ -176,6 +216,12 @@ func (o *CostOptimizer) EnforceHistoryTurnLimit(messages []ai.Message) []ai.Mess
 
 // PruneToolResults applies tool-specific pruning rules
 func (o *CostOptimizer) PruneToolResults(messages []ai.Message) []ai.Message {
+	return o.pruneToolResults(messages, nil)
+}
+
+// pruneToolResults is PruneToolResults that, when savings is non-nil, adds
+// the tokens cut from each tool's results to it
+func (o *CostOptimizer) pruneToolResults(messages []ai.Message, savings map[string]*toolSaving) []ai.Message {
 	result := make([]ai.Message, len(messages))
 	copy(result, messages)
 
@@ -185,6 +231,9 @@ func (o *CostOptimizer) PruneToolResults(messages []ai.Message) []ai.Message {
 	// Process from newest to oldest
 	for i := len(result) - 1; i >= 0; i-- {
 		msg := &result[i]
+		if msg.Pinned {
+			continue
+		}
 
 		// Skip last N assistant messages
 		if msg.Role == "assistant" {
@@ -225,23 +274,52 @@ func (o *CostOptimizer) PruneToolResults(messages []ai.Message) []ai.Message {
 		}
 
 		if estimateTokens(msg.Content) > maxTokens {
+			original := msg.Content
 			if rule.Aggressive {
 				msg.Content = truncateAggressive(msg.Content, maxTokens)
 			} else {
 				msg.Content = truncateWithContext(msg.Content, maxTokens)
 			}
+			// Keep the pointer to the stored full output so the model can
+			// expand what was cut
+			if ref := agent.ResultRef(original); ref != "" && agent.ResultRef(msg.Content) != ref {
+				msg.Content = agent.ResultRefNote(ref) + "\n" + msg.Content
+			}
+			if savings != nil {
+				if savings[toolName] == nil {
+					savings[toolName] = &toolSaving{}
+				}
+				savings[toolName].results++
+				savings[toolName].tokens += estimateTokens(original) - estimateTokens(msg.Content)
+			}
 		}
 	}
 
 	return result
 }
 
-// CompressPrompt reduces token count without losing meaning
+// CompressPrompt reduces token count without losing meaning. Only prose is
+// rewritten: fenced code blocks, inline code and string literals are kept
+// byte for byte, and prose keeps its original casing.
 func (o *CostOptimizer) CompressPrompt(content string) string {
 	if content == "" {
 		return content
 	}
 
+	// Swap verbatim segments for placeholders so the passes below only see prose
+	var prose strings.Builder
+	var restore []string
+	for i, seg := range splitPromptSegments(content) {
+		if !seg.verbatim {
+			prose.WriteString(seg.text)
+			continue
+		}
+		placeholder := fmt.Sprintf("\uE000%d\uE001", i)
+		prose.WriteString(placeholder)
+		restore = append(restore, placeholder, seg.text)
+	}
+	content = prose.String()
+
 	// 1. Normalize whitespace (multiple spaces/newlines → single)
 	content = normalizeWhitespace(content)
 
@@ -251,9 +329,124 @@ func (o *CostOptimizer) CompressPrompt(content string) string {
 	// 3. Compress common verbose patterns
 	content = compressVerbosePatterns(content)
 
+	if len(restore) > 0 {
+		content = strings.NewReplacer(restore...).Replace(content)
+	}
 	return strings.TrimSpace(content)
 }
 
+// promptSegment is a run of prose, or of text that must not be rewritten
+type promptSegment struct {
+	text     string
+	verbatim bool // Fenced code block, inline code or string literal
+}
+
+// splitPromptSegments splits content into prose and verbatim segments:
+// ``` / ~~~ fenced blocks (to the closing fence, or the end when unclosed),
+// `inline code` and "string literals" (single line, backslash escapes)
+func splitPromptSegments(content string) []promptSegment {
+	var segs []promptSegment
+	var prose strings.Builder
+	flush := func() {
+		if prose.Len() > 0 {
+			segs = append(segs, promptSegment{text: prose.String()})
+			prose.Reset()
+		}
+	}
+
+	lineStart := true // Only indentation so far on this line
+	for i := 0; i < len(content); {
+		// Fenced block: fence at the start of a line, through the closing fence line
+		if lineStart {
+			if fence := fenceAt(content[i:]); fence != "" {
+				end := len(content)
+				if lineEnd := strings.IndexByte(content[i:], '\n'); lineEnd >= 0 {
+					end = closingFence(content, i+lineEnd+1, fence)
+				}
+				flush()
+				segs = append(segs, promptSegment{text: content[i:end], verbatim: true})
+				i = end
+				continue
+			}
+		}
+
+		c := content[i]
+		if c == '`' || c == '"' {
+			if end := literalEnd(content, i); end > 0 {
+				flush()
+				segs = append(segs, promptSegment{text: content[i:end], verbatim: true})
+				i = end
+				lineStart = false
+				continue
+			}
+		}
+
+		prose.WriteByte(c)
+		switch c {
+		case '\n':
+			lineStart = true
+		case ' ', '\t':
+		default:
+			lineStart = false
+		}
+		i++
+	}
+	flush()
+	return segs
+}
+
+// fenceAt returns the code fence (``` or ~~~, any length ≥ 3) that s starts with
+func fenceAt(s string) string {
+	s = strings.TrimLeft(s, " \t")
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(s) && s[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return s[:n]
+		}
+	}
+	return ""
+}
+
+// closingFence returns the offset just past the line closing a fenced block
+// whose content starts at from (len(content) when the block is unclosed)
+func closingFence(content string, from int, fence string) int {
+	for i := from; i < len(content); {
+		lineEnd := strings.IndexByte(content[i:], '\n')
+		next := len(content)
+		if lineEnd >= 0 {
+			next = i + lineEnd + 1
+		}
+		line := strings.TrimSpace(content[i:next])
+		if strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == "" {
+			return next
+		}
+		i = next
+	}
+	return len(content)
+}
+
+// literalEnd returns the offset just past the inline code span or string
+// literal opening at content[i], or 0 when it isn't closed on the same line
+func literalEnd(content string, i int) int {
+	quote := content[i]
+	for j := i + 1; j < len(content); j++ {
+		switch content[j] {
+		case '\n':
+			return 0
+		case '\\':
+			if quote == '"' {
+				j++ // Skip escaped character
+			}
+		case quote:
+			return j + 1
+		}
+	}
+	return 0
+}
+
 // TruncateToolResults limits tool output size (legacy - use PruneToolResults)
 func (o *CostOptimizer) TruncateToolResults(messages []ai.Message) []ai.Message {
 	return o.PruneToolResults(messages)
@@ -333,7 +526,8 @@ func truncateAggressive(content string, maxTokens int) string {
 	return fmt.Sprintf("%s\n\n... [%d chars truncated - use specific tools to see more]", truncated, removed)
 }
 
-// SummarizeHistory keeps recent messages, summarizes old ones
+// SummarizeHistory keeps recent messages, summarizes old ones. Pinned old
+// messages are kept verbatim after the summary.
 func (o *CostOptimizer) SummarizeHistory(messages []ai.Message) []ai.Message {
 	if len(messages) <= o.MaxHistoryMessages {
 		return messages
@@ -359,7 +553,17 @@ func (o *CostOptimizer) SummarizeHistory(messages []ai.Message) []ai.Message {
 		return messages
 	}
 
-	oldMessages := history[:splitPoint]
+	var oldMessages, pinnedMessages []ai.Message
+	for _, msg := range history[:splitPoint] {
+		if msg.Pinned {
+			pinnedMessages = append(pinnedMessages, msg)
+		} else {
+			oldMessages = append(oldMessages, msg)
+		}
+	}
+	if len(oldMessages) == 0 {
+		return messages
+	}
 	recentMessages := history[splitPoint:]
 
 	// Create memory flush - extract important items before dropping
@@ -383,6 +587,7 @@ func (o *CostOptimizer) SummarizeHistory(messages []ai.Message) []ai.Message {
 		Role:    "system",
 		Content: "[Previous conversation summary]\n" + fullSummary,
 	})
+	result = append(result, pinnedMessages...)
 	result = append(result, recentMessages...)
 
 	return result
@@ -513,20 +718,18 @@ func (o *CostOptimizer) EstimateOutputTokens(messages []ai.Message) int {
 // === Helper functions ===
 
 func normalizeWhitespace(s string) string {
-	// Replace multiple spaces with single space
-	spaceRe := regexp.MustCompile(`[ \t]+`)
-	s = spaceRe.ReplaceAllString(s, " ")
-
-	// Replace 3+ newlines with 2
-	newlineRe := regexp.MustCompile(`\n{3,}`)
-	s = newlineRe.ReplaceAllString(s, "\n\n")
-
-	// Trim each line
+	// Collapse runs of spaces inside lines; leading indentation is structure
+	// (lists, quoted output) and is kept
 	lines := strings.Split(s, "\n")
 	for i, line := range lines {
-		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+		body := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(body)]
+		lines[i] = indent + multiSpaceRe.ReplaceAllString(strings.TrimRightFunc(body, unicode.IsSpace), " ")
 	}
-	return strings.Join(lines, "\n")
+	s = strings.Join(lines, "\n")
+
+	// Replace 3+ newlines with 2
+	return multiNewlineRe.ReplaceAllString(s, "\n\n")
 }
 
 func simplifyMarkdown(s string) string {
@@ -539,34 +742,47 @@ func simplifyMarkdown(s string) string {
 	return s
 }
 
+var (
+	multiSpaceRe   = regexp.MustCompile(`[ \t]+`)
+	multiNewlineRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// verbosePhrases are common verbose phrases that can be shortened
+var verbosePhrases = []struct {
+	re      *regexp.Regexp
+	concise string
+}{
+	{regexp.MustCompile(`(?i)\bin order to\b`), "to"},
+	{regexp.MustCompile(`(?i)\bdue to the fact that\b`), "because"},
+	{regexp.MustCompile(`(?i)\bat this point in time\b`), "now"},
+	{regexp.MustCompile(`(?i)\bin the event that\b`), "if"},
+	{regexp.MustCompile(`(?i)\bfor the purpose of\b`), "for"},
+	{regexp.MustCompile(`(?i)\bwith regard to\b`), "regarding"},
+	{regexp.MustCompile(`(?i)\bin accordance with\b`), "per"},
+	{regexp.MustCompile(`(?i)\bin the process of\b`), "while"},
+	{regexp.MustCompile(`(?i)\bon a daily basis\b`), "daily"},
+	{regexp.MustCompile(`(?i)\bat the present time\b`), "now"},
+	{regexp.MustCompile(`(?i)\bin the near future\b`), "soon"},
+	{regexp.MustCompile(`(?i)\ba large number of\b`), "many"},
+	{regexp.MustCompile(`(?i)\ba small number of\b`), "few"},
+	{regexp.MustCompile(`(?i)\bthe vast majority of\b`), "most"},
+	{regexp.MustCompile(`(?i)\bin spite of the fact\b`), "although"},
+	{regexp.MustCompile(`(?i)\bit is important to note\b`), "note:"},
+	{regexp.MustCompile(`(?i)\bplease note that\b`), "note:"},
+}
+
+// compressVerbosePatterns shortens verbose phrases, keeping the case of the
+// phrase's first letter (and leaving the rest of the text untouched)
 func compressVerbosePatterns(s string) string {
-	// Common verbose phrases that can be shortened
-	replacements := map[string]string{
-		"in order to":             "to",
-		"due to the fact that":    "because",
-		"at this point in time":   "now",
-		"in the event that":       "if",
-		"for the purpose of":      "for",
-		"with regard to":          "regarding",
-		"in accordance with":      "per",
-		"in the process of":       "while",
-		"on a daily basis":        "daily",
-		"at the present time":     "now",
-		"in the near future":      "soon",
-		"a large number of":       "many",
-		"a small number of":       "few",
-		"the vast majority of":    "most",
-		"in spite of the fact":    "although",
-		"it is important to note": "note:",
-		"please note that":        "note:",
-	}
-
-	result := s
-	for verbose, concise := range replacements {
-		result = strings.ReplaceAll(strings.ToLower(result), verbose, concise)
+	for _, p := range verbosePhrases {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if r, _ := utf8.DecodeRuneInString(match); unicode.IsUpper(r) {
+				return strings.ToUpper(p.concise[:1]) + p.concise[1:]
+			}
+			return p.concise
+		})
 	}
-
-	return result
+	return s
 }
 
 func isLikelyToolResult(content string) bool {
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/wiki"
+)
+
+// docsSources creates the wikis of the docs tools: those with a token
+func docsSources(cfg config.DocsConfig) map[string]wiki.Source {
+	sources := make(map[string]wiki.Source)
+	if token := cfg.Confluence.GetToken(); token != "" && cfg.Confluence.URL != "" {
+		sources["confluence"] = wiki.NewConfluence(cfg.Confluence.URL, cfg.Confluence.Email, token)
+	}
+	if token := cfg.Notion.GetToken(); token != "" {
+		sources["notion"] = wiki.NewNotion(token)
+	}
+	return sources
+}
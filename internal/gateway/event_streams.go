@@ -0,0 +1,251 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// Streamed requests (SSE and WebSocket) publish their events to an
+// eventStream instead of writing to the connection. The connection follows
+// the stream and sends a heartbeat while the agent is quiet, so proxies keep
+// it open and clients notice a dead one. A client that lost the connection
+// resumes from the last event ID it saw ("<stream>:<seq>"): the agent keeps
+// running, and buffered events are replayed before live ones.
+
+const (
+	// heartbeatInterval is how long a stream may be quiet before a heartbeat
+	heartbeatInterval = 15 * time.Second
+
+	// streamBufferSize is how many recent events a stream keeps for resuming
+	streamBufferSize = 2000
+
+	// streamRetention keeps finished streams resumable for a client that
+	// lost the connection just before the end
+	streamRetention = 5 * time.Minute
+
+	// streamAbandonAfter cancels a request nobody followed for this long
+	streamAbandonAfter = 2 * time.Minute
+)
+
+// streamEvent is one published event
+type streamEvent struct {
+	seq   int    // 1-based; 0 for notices that are not buffered
+	data  []byte // JSON: a progress event, or the final event
+	final bool
+}
+
+// eventStream buffers the events of one streamed request
+type eventStream struct {
+	id string
+
+	mu         sync.Mutex
+	events     []streamEvent // The last streamBufferSize events
+	last       int           // Sequence of the last published event
+	done       bool
+	finishedAt time.Time
+	changed    chan struct{} // Closed (and replaced) on each publish
+	followers  int
+	cancel     context.CancelCauseFunc // Cancels the request; nil when not attached
+}
+
+// attach returns the context to run the request in: it outlives the
+// connection that started it, and is canceled once no client followed the
+// stream for streamAbandonAfter
+func (s *eventStream) attach(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	return ctx
+}
+
+// join and leave count the clients following the stream
+func (s *eventStream) join() {
+	s.mu.Lock()
+	s.followers++
+	s.mu.Unlock()
+}
+
+func (s *eventStream) leave() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.followers--; s.followers > 0 || s.done {
+		return
+	}
+	time.AfterFunc(streamAbandonAfter, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.followers == 0 && !s.done && s.cancel != nil {
+			s.cancel(types.Errorf(types.ErrCanceled, "abandoned: no client followed the stream for %s", streamAbandonAfter))
+		}
+	})
+}
+
+// publish appends an event; the final one ends the stream
+func (s *eventStream) publish(event interface{}, final bool) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.last++
+	s.events = append(s.events, streamEvent{seq: s.last, data: data, final: final})
+	if len(s.events) > streamBufferSize {
+		s.events = append([]streamEvent(nil), s.events[len(s.events)-streamBufferSize:]...)
+	}
+	if final {
+		s.done = true
+		s.finishedAt = time.Now()
+		if s.cancel != nil {
+			s.cancel(nil)
+		}
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// after returns the buffered events after seq, a channel closed on the next
+// publish, and how many events after seq are no longer buffered
+func (s *eventStream) after(seq int) (events []streamEvent, changed <-chan struct{}, dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) > 0 && s.events[0].seq > seq+1 {
+		dropped = s.events[0].seq - seq - 1
+	}
+	for _, ev := range s.events {
+		if ev.seq > seq {
+			events = append(events, ev)
+		}
+	}
+	return events, s.changed, dropped
+}
+
+// eventID returns the ID clients resume from
+func (s *eventStream) eventID(seq int) string {
+	return fmt.Sprintf("%s:%d", s.id, seq)
+}
+
+// follow calls send for each event after seq, and heartbeat when the stream
+// was quiet for heartbeatInterval, until the final event was sent, stop is
+// closed, or a callback fails
+func (s *eventStream) follow(seq int, stop <-chan struct{}, send func(streamEvent) error, heartbeat func() error) error {
+	s.join()
+	defer s.leave()
+	timer := time.NewTimer(heartbeatInterval)
+	defer timer.Stop()
+
+	for {
+		events, changed, dropped := s.after(seq)
+		if dropped > 0 {
+			notice, _ := json.Marshal(types.ProgressEvent{
+				Version: types.ProgressSchemaVersion,
+				Type:    "events_dropped",
+				Message: fmt.Sprintf("%d events were no longer buffered and are not replayed", dropped),
+			})
+			if err := send(streamEvent{data: notice}); err != nil {
+				return err
+			}
+		}
+		for _, ev := range events {
+			if err := send(ev); err != nil {
+				return err
+			}
+			seq = ev.seq
+			if ev.final {
+				return nil
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(heartbeatInterval)
+		select {
+		case <-changed:
+		case <-timer.C:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// heartbeatEvent is sent while a stream is quiet
+func heartbeatEvent() []byte {
+	data, _ := json.Marshal(types.ProgressEvent{Version: types.ProgressSchemaVersion, Type: "heartbeat"})
+	return data
+}
+
+// streamHub holds the streams of in-flight and recently finished requests
+type streamHub struct {
+	mu      sync.Mutex
+	streams map[string]*eventStream
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{streams: make(map[string]*eventStream)}
+}
+
+// create starts a stream, dropping finished ones past their retention
+func (h *streamHub) create() *eventStream {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	stream := &eventStream{id: hex.EncodeToString(buf), changed: make(chan struct{})}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, s := range h.streams {
+		s.mu.Lock()
+		expired := s.done && time.Since(s.finishedAt) > streamRetention
+		s.mu.Unlock()
+		if expired {
+			delete(h.streams, id)
+		}
+	}
+	h.streams[stream.id] = stream
+	return stream
+}
+
+// get returns a stream by ID (nil when unknown or expired)
+func (h *streamHub) get(id string) *eventStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams[id]
+}
+
+// lookup finds the stream of an event ID and the sequence to resume after
+func (h *streamHub) lookup(eventID string) (*eventStream, int, error) {
+	id, seqStr, _ := strings.Cut(strings.TrimSpace(eventID), ":")
+	seq, err := strconv.Atoi(seqStr)
+	if id == "" || err != nil || seq < 0 {
+		return nil, 0, types.Errorf(types.ErrInvalidArgument, "invalid event ID %q (want <stream>:<seq>)", eventID)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stream, ok := h.streams[id]
+	if !ok {
+		return nil, 0, types.Errorf(types.ErrNotFound, "stream %s not found or expired", id)
+	}
+	return stream, seq, nil
+}
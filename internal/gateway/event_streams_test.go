@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestEventStreamResume(t *testing.T) {
+	hub := newStreamHub()
+	stream := hub.create()
+	for i := 1; i <= 3; i++ {
+		stream.publish(types.ProgressEvent{Type: "step", Step: i}, false)
+	}
+
+	// Resuming after the second event replays the third, then follows live
+	found, seq, err := hub.lookup(stream.eventID(2))
+	if err != nil || found != stream || seq != 2 {
+		t.Fatalf("lookup = %v, %d, %v", found, seq, err)
+	}
+	var got []int
+	done := make(chan error)
+	go func() {
+		done <- found.follow(seq, nil, func(ev streamEvent) error {
+			got = append(got, ev.seq)
+			return nil
+		}, func() error { return nil })
+	}()
+	stream.publish(map[string]string{"type": "done"}, true)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow did not return after the final event")
+	}
+	if len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("followed events = %v, want [3 4]", got)
+	}
+
+	// Publishing after the final event is ignored
+	stream.publish(types.ProgressEvent{Type: "step"}, false)
+	if events, _, _ := stream.after(0); len(events) != 4 {
+		t.Errorf("buffered %d events after the end, want 4", len(events))
+	}
+
+	for _, id := range []string{"", "nocolon", stream.id + ":x", stream.id + ":-1"} {
+		if _, _, err := hub.lookup(id); types.CodeOf(err) != types.ErrInvalidArgument {
+			t.Errorf("lookup(%q) code = %v, want invalid_argument", id, types.CodeOf(err))
+		}
+	}
+	if _, _, err := hub.lookup("unknown:1"); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("lookup(unknown) code = %v, want not_found", types.CodeOf(err))
+	}
+}
+
+func TestEventStreamDropped(t *testing.T) {
+	stream := newStreamHub().create()
+	for i := 0; i < streamBufferSize+10; i++ {
+		stream.publish(types.ProgressEvent{Type: "step", Step: i}, false)
+	}
+	stream.publish(map[string]string{"type": "done"}, true)
+
+	var notices, events int
+	stream.follow(5, nil, func(ev streamEvent) error {
+		if ev.seq == 0 {
+			var notice types.ProgressEvent
+			json.Unmarshal(ev.data, &notice)
+			if notice.Type != "events_dropped" {
+				t.Errorf("notice type = %q", notice.Type)
+			}
+			notices++
+			return nil
+		}
+		events++
+		return nil
+	}, func() error { return nil })
+
+	if notices != 1 || events != streamBufferSize {
+		t.Errorf("got %d notices and %d events, want 1 and %d", notices, events, streamBufferSize)
+	}
+}
+
+func TestEventStreamAttach(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	stream := newStreamHub().create()
+	ctx := stream.attach(parent)
+
+	// The request outlives the connection that started it...
+	cancelParent()
+	if ctx.Err() != nil {
+		t.Fatal("request canceled with its connection")
+	}
+	// ...and ends with the stream
+	stream.publish(map[string]string{"type": "done"}, true)
+	if ctx.Err() == nil {
+		t.Error("request context still live after the final event")
+	}
+}
@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/consensus"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// Routing only reacts to providers with enough ratings, and only demotes
+// (never drops) those users clearly dislike
+const (
+	minFeedbackForRouting = 5
+	dislikedApproval      = 0.4
+)
+
+// FeedbackCounts aggregates user ratings for a provider/model
+type FeedbackCounts struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Good     int    `json:"good"`
+	Bad      int    `json:"bad"`
+}
+
+// Approval returns the share of good ratings (0.5 when unrated)
+func (c FeedbackCounts) Approval() float64 {
+	if c.Good+c.Bad == 0 {
+		return 0.5
+	}
+	return float64(c.Good) / float64(c.Good+c.Bad)
+}
+
+// feedbackTracker aggregates ratings for stats and routing heuristics
+type feedbackTracker struct {
+	mu     sync.RWMutex
+	counts map[string]*FeedbackCounts // provider/model -> counts
+}
+
+func newFeedbackTracker() *feedbackTracker {
+	return &feedbackTracker{counts: make(map[string]*FeedbackCounts)}
+}
+
+// seed loads persisted totals
+func (t *feedbackTracker) seed(totals map[string]*FeedbackCounts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, c := range totals {
+		t.counts[k] = c
+	}
+}
+
+// add records one rating
+func (t *feedbackTracker) add(provider, model string, rating int) {
+	if provider == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := provider + "/" + model
+	c, ok := t.counts[key]
+	if !ok {
+		c = &FeedbackCounts{Provider: provider, Model: model}
+		t.counts[key] = c
+	}
+	if rating > 0 {
+		c.Good++
+	} else if rating < 0 {
+		c.Bad++
+	}
+}
+
+// snapshot returns all counts sorted by provider/model
+func (t *feedbackTracker) snapshot() []FeedbackCounts {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	list := make([]FeedbackCounts, 0, len(t.counts))
+	for _, c := range t.counts {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Provider != list[j].Provider {
+			return list[i].Provider < list[j].Provider
+		}
+		return list[i].Model < list[j].Model
+	})
+	return list
+}
+
+// providerTotals sums counts across a provider's models
+func (t *feedbackTracker) providerTotals(provider string) FeedbackCounts {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	total := FeedbackCounts{Provider: provider}
+	for _, c := range t.counts {
+		if c.Provider == provider {
+			total.Good += c.Good
+			total.Bad += c.Bad
+		}
+	}
+	return total
+}
+
+// demoteDisliked moves providers users rate poorly to the end of a fallback
+// chain, keeping the relative order otherwise
+func (t *feedbackTracker) demoteDisliked(chain []string) []string {
+	var liked, disliked []string
+	for _, p := range chain {
+		c := t.providerTotals(p)
+		if c.Good+c.Bad >= minFeedbackForRouting && c.Approval() < dislikedApproval {
+			disliked = append(disliked, p)
+		} else {
+			liked = append(liked, p)
+		}
+	}
+	if len(disliked) > 0 && len(liked) > 0 {
+		log.Printf("[AIRouter] Demoting poorly rated providers: %v", disliked)
+	}
+	return append(liked, disliked...)
+}
+
+// ParseRating converts "good"/"bad" (and common synonyms) to +1/-1
+func ParseRating(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "good", "+1", "1", "up", "👍", "yes":
+		return 1, nil
+	case "bad", "-1", "down", "👎", "no":
+		return -1, nil
+	}
+	return 0, types.Errorf(types.ErrInvalidArgument, "invalid rating %q (use good or bad)", s)
+}
+
+// RateExchange attaches a rating to the latest answer in a session and feeds it
+// into the dataset recorder, routing heuristics and consensus worker stats
+func (s *AgentService) RateExchange(sessionID string, rating int, comment string) (agent.Feedback, error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return agent.Feedback{}, err
+	}
+
+	fb, err := session.RateLastExchange(rating, comment)
+	if err != nil {
+		return fb, err
+	}
+
+	if isNamedSession(sessionID) && s.sessionStore != nil {
+		if err := s.sessionStore.SaveSession(session); err != nil {
+			log.Printf("Warning: Failed to save feedback for session %s: %v", sessionID, err)
+		}
+	}
+	if err := s.recorder.Rate(fb.RecordID, rating); err != nil {
+		log.Printf("[Recorder] Warning: failed to record rating: %v", err)
+	}
+	s.aiRouter.feedback.add(fb.Provider, fb.Model, rating)
+	if fb.Provider != "" {
+		if err := consensus.RecordFeedback(fb.Provider, fb.Model, rating); err != nil {
+			log.Printf("[Consensus] Warning: failed to record feedback: %v", err)
+		}
+	}
+
+	log.Printf("[AgentService] Session %s rated %+d (%s/%s)", sessionID, rating, fb.Provider, fb.Model)
+	return fb, nil
+}
+
+// GetFeedbackStats returns rating counts per provider/model
+func (s *AgentService) GetFeedbackStats() []FeedbackCounts {
+	return s.aiRouter.feedback.snapshot()
+}
+
+// feedbackSummary renders a one-line summary for chat replies
+func feedbackSummary(fb agent.Feedback) string {
+	verdict := "👍 good"
+	if fb.Rating < 0 {
+		verdict = "👎 bad"
+	}
+	if fb.Provider == "" {
+		return fmt.Sprintf("Rated last answer %s", verdict)
+	}
+	return fmt.Sprintf("Rated last answer %s (%s/%s)", verdict, fb.Provider, fb.Model)
+}
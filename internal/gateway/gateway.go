@@ -15,6 +15,7 @@ import (
 	"github.com/neves/zen-claw/internal/ai"
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/providers"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // Gateway represents the HTTP gateway server
@@ -23,15 +24,15 @@ type Gateway struct {
 	server  *http.Server
 	mu      sync.RWMutex
 	running bool
-	pidFile string
+	runDir  string
 }
 
 // NewGateway creates a new gateway instance
 func NewGateway(cfg *config.Config) *Gateway {
 	// Create gateway
 	gw := &Gateway{
-		config:  cfg,
-		pidFile: "/tmp/zen-claw-gateway.pid",
+		config: cfg,
+		runDir: DefaultRunDir(),
 	}
 
 	// Setup HTTP server
@@ -58,15 +59,21 @@ func (g *Gateway) Start() error {
 	g.running = true
 	g.mu.Unlock()
 
-	// Write PID file
-	if err := g.writePID(); err != nil {
-		return fmt.Errorf("failed to write PID file: %w", err)
+	// Record this gateway
+	if err := acquireRunState(g.runDir, g.server.Addr, g.config.Gateway.GetURL()); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+
+	listener, err := listen(&g.config.Gateway)
+	if err != nil {
+		releaseRunState(g.runDir)
+		return fmt.Errorf("failed to listen on %s: %w", g.server.Addr, err)
 	}
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Starting Zen Claw gateway on %s", g.server.Addr)
-		if err := g.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Starting Zen Claw gateway on %s", g.config.Gateway.GetURL())
+		if err := g.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Printf("Server error: %v", err)
 		}
 	}()
@@ -94,8 +101,7 @@ func (g *Gateway) Stop() error {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
 
-	// Remove PID file
-	os.Remove(g.pidFile)
+	releaseRunState(g.runDir)
 
 	log.Println("Gateway stopped")
 	return nil
@@ -140,25 +146,7 @@ func (g *Gateway) waitForShutdown() {
 	defer cancel()
 
 	g.server.Shutdown(ctx)
-	os.Remove(g.pidFile)
-}
-
-// writePID writes the process ID to file
-func (g *Gateway) writePID() error {
-	pid := os.Getpid()
-	return os.WriteFile(g.pidFile, []byte(fmt.Sprintf("%d", pid)), 0644)
-}
-
-// readPID reads the process ID from file
-func (g *Gateway) readPID() (int, error) {
-	data, err := os.ReadFile(g.pidFile)
-	if err != nil {
-		return 0, err
-	}
-
-	var pid int
-	_, err = fmt.Sscanf(string(data), "%d", &pid)
-	return pid, err
+	releaseRunState(g.runDir)
 }
 
 // HTTP handlers
@@ -169,14 +157,14 @@ func (g *Gateway) healthHandler(w http.ResponseWriter, r *http.Request) {
 
 func (g *Gateway) chatHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
 	// Parse request
 	message := r.FormValue("message")
 	if message == "" {
-		http.Error(w, "Message is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Message is required")
 		return
 	}
 
@@ -211,7 +199,8 @@ func (g *Gateway) chatHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := aiProvider.Chat(context.Background(), req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("AI processing failed: %v", err), http.StatusInternalServerError)
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, fmt.Sprintf("AI processing failed: %v", err))
 		return
 	}
 
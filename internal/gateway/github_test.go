@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/forge"
+	"github.com/neves/zen-claw/internal/ratelimit"
+)
+
+func TestGitHubHookSkips(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.GitHub.Repos = map[string]config.GitHubRepoConfig{
+		"acme/api":  {Review: true, Branches: []string{"main"}},
+		"acme/docs": {Review: false},
+	}
+	s := &Server{
+		config:      cfg,
+		metrics:     &Metrics{},
+		rateLimiter: ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+		hooks:       newWebhooks(map[string]config.HookConfig{"github": {Kind: "github", Insecure: true}}),
+		github:      newGitHubIntegration(cfg.GitHub),
+	}
+	defer s.rateLimiter.Close()
+
+	tests := []struct {
+		event, payload, reason string
+	}{
+		{"ping", `{"zen": "Design for failure."}`, "ping"},
+		{"push", `{}`, `event "push" not handled`},
+		{"pull_request", `{"action": "opened", "repository": {"full_name": "acme/docs"}}`, "reviews are not enabled for acme/docs"},
+		{"pull_request", `{"action": "closed", "repository": {"full_name": "acme/api"}}`, `action "closed" not reviewed`},
+		{"pull_request", `{"action": "opened", "pull_request": {"draft": true}, "repository": {"full_name": "acme/api"}}`, "pull request is a draft"},
+		{"pull_request", `{"action": "synchronize", "pull_request": {"base": {"ref": "dev"}}, "repository": {"full_name": "acme/api"}}`, "base branch dev not reviewed"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/hooks/github", strings.NewReader(tt.payload))
+		req.Header.Set("X-GitHub-Event", tt.event)
+		rec := httptest.NewRecorder()
+		s.hooksHandler(rec, req)
+		if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"skipped"`) || !strings.Contains(rec.Body.String(), jsonEscape(tt.reason)) {
+			t.Errorf("%s %s: got %d %s, want skipped: %s", tt.event, tt.payload, rec.Code, rec.Body.String(), tt.reason)
+		}
+	}
+}
+
+// jsonEscape quotes s the way encoding/json does inside a string
+func jsonEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+func TestBuildPRReview(t *testing.T) {
+	lines := map[string]map[int]bool{"main.go": {10: true, 11: true}}
+	review := buildPRReview(prReview{
+		Summary: "One bug.",
+		Comments: []forge.ReviewComment{
+			{Path: "main.go", Line: 11, Body: "err is ignored"},
+			{Path: "b/main.go", Line: 10, Body: "off by one"},
+			{Path: "main.go", Line: 90, Body: "caller must check err too"},
+			{Path: "main.go", Line: 10, Body: " "},
+		},
+	}, lines)
+
+	if review.Event != "COMMENT" || len(review.Comments) != 2 {
+		t.Fatalf("review = %+v", review)
+	}
+	for _, c := range review.Comments {
+		if c.Path != "main.go" || c.Side != "RIGHT" {
+			t.Errorf("inline comment = %+v", c)
+		}
+	}
+	if !strings.Contains(review.Body, "`main.go:90`: caller must check err too") || !strings.HasPrefix(review.Body, "One bug.") {
+		t.Errorf("body = %q", review.Body)
+	}
+}
+
+func TestCutDiff(t *testing.T) {
+	diff := "line one\nline two\nline three\n"
+	if got := cutDiff(diff, 100); got != "line one\nline two\nline three" {
+		t.Errorf("short diff changed: %q", got)
+	}
+	if got := cutDiff(diff, 12); !strings.HasPrefix(got, "line one\n[diff cut: ") {
+		t.Errorf("cut = %q", got)
+	}
+}
+
+func TestTriageHelpers(t *testing.T) {
+	repoLabels := []forge.Label{{Name: "bug"}, {Name: "area/gateway"}, {Name: "enhancement"}}
+	got := knownLabels([]string{"Bug", "area/gateway", "priority/p0", "bug"}, repoLabels)
+	if strings.Join(got, ",") != "bug,area/gateway" {
+		t.Errorf("knownLabels = %v", got)
+	}
+
+	comment := triageComment(&IssueTriage{Kind: "bug", Area: "internal/gateway", Confidence: 0.6, Summary: "Crash on empty hooks.", Labels: got})
+	if !strings.Contains(comment, "bug in `internal/gateway` (confidence 60%)") || !strings.Contains(comment, "Suggested labels: bug, area/gateway") {
+		t.Errorf("comment = %q", comment)
+	}
+	comment = triageComment(&IssueTriage{Kind: "bug", Confidence: 0.9, Labels: got, Applied: []string{"bug"}})
+	if !strings.Contains(comment, "Labeled: bug") || strings.Contains(comment, "Suggested") {
+		t.Errorf("comment = %q", comment)
+	}
+
+	dir := t.TempDir()
+	for _, f := range []string{"main.go", "internal/gateway/server.go", "internal/gateway/hooks.go", "internal/gateway/sub/x.go", ".git/HEAD", "cmd/root.go"} {
+		path := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, nil, 0o644)
+	}
+	if got := repoMap(dir); got != "cmd/ (1)\ninternal/gateway/ (3)" {
+		t.Errorf("repoMap = %q", got)
+	}
+}
+
+func TestGitHubIssueSkips(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.GitHub.Repos = map[string]config.GitHubRepoConfig{"acme/api": {Review: true}}
+	s := &Server{
+		config:      cfg,
+		metrics:     &Metrics{},
+		rateLimiter: ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+		hooks:       newWebhooks(map[string]config.HookConfig{"github": {Kind: "github", Insecure: true}}),
+		github:      newGitHubIntegration(cfg.GitHub),
+	}
+	defer s.rateLimiter.Close()
+
+	req := httptest.NewRequest("POST", "/hooks/github", strings.NewReader(`{"action": "opened", "issue": {"number": 7}, "repository": {"full_name": "acme/api"}}`))
+	req.Header.Set("X-GitHub-Event", "issues")
+	rec := httptest.NewRecorder()
+	s.hooksHandler(rec, req)
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), "triage is not enabled for acme/api") {
+		t.Errorf("got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,395 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// WEBHOOK TRIGGERS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Each entry of the hooks config is an endpoint, POST /hooks/{name}. A
+// request that passes the hook's secret and event filter is rendered through
+// its template into a task, which runs in the hook's session in the
+// background; tasks of one hook run one after another. The request is
+// answered 202 right away.
+
+// hookDeliveriesKept bounds the deliveries remembered per hook
+const hookDeliveriesKept = 20
+
+// hookDeliveryIDsKept bounds the delivery IDs remembered per hook to refuse
+// replays
+const hookDeliveryIDsKept = 1000
+
+// hookDeliveryHeaders carry the forge's unique ID of a delivery
+var hookDeliveryHeaders = []string{"X-GitHub-Delivery", "X-Gitlab-Event-UUID"}
+
+// HookDelivery is one request to a hook and what came of it
+type HookDelivery struct {
+	At        time.Time `json:"at"`
+	Event     string    `json:"event,omitempty"`
+	Status    string    `json:"status"` // skipped, running, done, failed
+	SessionID string    `json:"session_id,omitempty"`
+	Task      string    `json:"task,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration,omitempty"`
+}
+
+// hookData is what a hook's template renders
+type hookData struct {
+	Name    string
+	Event   string
+	Payload interface{}
+	Headers map[string]string
+}
+
+// webhook is a configured hook with its compiled template
+type webhook struct {
+	name     string
+	cfg      config.HookConfig
+	tmpl     *template.Template
+	parseErr error
+
+	mu         sync.Mutex
+	deliveries []*HookDelivery // Most recent last
+	seen       map[string]bool // Delivery IDs received, the last hookDeliveryIDsKept
+	seenOrder  []string        // Oldest first
+}
+
+// hookFuncs are the functions templates may use besides the built-ins
+var hookFuncs = template.FuncMap{
+	// json renders a value as JSON
+	"json": func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+	// truncate shortens a string to n characters
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n]) + "…"
+		}
+		return s
+	},
+}
+
+// newWebhooks compiles the configured hooks. A hook whose template doesn't
+// parse is kept and answers every request with the parse error; one without
+// a secret isn't registered unless it is insecure.
+func newWebhooks(cfgs map[string]config.HookConfig) map[string]*webhook {
+	hooks := make(map[string]*webhook, len(cfgs))
+	for name, cfg := range cfgs {
+		if cfg.Secret == "" && !cfg.Insecure {
+			log.Printf("Warning: hook %s has no secret and is not registered (set insecure: true to accept unauthenticated requests)", name)
+			continue
+		}
+		h := &webhook{name: name, cfg: cfg, seen: make(map[string]bool)}
+		h.tmpl, h.parseErr = template.New(name).Funcs(hookFuncs).Option("missingkey=zero").Parse(cfg.Template)
+		if h.parseErr != nil {
+			log.Printf("Warning: hook %s has an invalid template: %v", name, h.parseErr)
+		}
+		if cfg.Secret == "" {
+			log.Printf("Warning: hook %s has no secret and accepts unauthenticated requests (insecure: true)", name)
+		}
+		hooks[name] = h
+	}
+	return hooks
+}
+
+// sessionID returns the session the hook's tasks run in
+func (h *webhook) sessionID() string {
+	if h.cfg.Session != "" {
+		return h.cfg.Session
+	}
+	return "hook-" + h.name
+}
+
+// verify checks a request against the hook's secret: GitHub's HMAC
+// signature when present, else the X-Hook-Token header. Without a secret,
+// only an insecure hook accepts requests.
+func (h *webhook) verify(r *http.Request, body []byte) bool {
+	if h.cfg.Secret == "" {
+		return h.cfg.Insecure
+	}
+	if sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(h.cfg.Secret))
+		mac.Write(body)
+		want := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(sig), []byte(want))
+	}
+	token := r.Header.Get("X-Hook-Token")
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.Secret)) == 1
+}
+
+// replayed remembers the request's delivery ID, reporting whether it was
+// received before (false for requests without one)
+func (h *webhook) replayed(r *http.Request) (id string, seen bool) {
+	for _, header := range hookDeliveryHeaders {
+		if id = r.Header.Get(header); id != "" {
+			break
+		}
+	}
+	if id == "" {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seen[id] {
+		return id, true
+	}
+	h.seen[id] = true
+	h.seenOrder = append(h.seenOrder, id)
+	if len(h.seenOrder) > hookDeliveryIDsKept {
+		delete(h.seen, h.seenOrder[0])
+		h.seenOrder = h.seenOrder[1:]
+	}
+	return id, false
+}
+
+// hookEvent names the event a request carries: the forge's event header, or
+// the event type in a PagerDuty (event.event_type) or Jira (webhookEvent)
+// payload
+func hookEvent(r *http.Request, payload interface{}) string {
+	for _, header := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Event-Key"} {
+		if event := r.Header.Get(header); event != "" {
+			return event
+		}
+	}
+	if m, ok := payload.(map[string]interface{}); ok {
+		if event, ok := m["event"].(map[string]interface{}); ok {
+			if t, ok := event["event_type"].(string); ok {
+				return t
+			}
+		}
+		if t, ok := m["webhookEvent"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
+
+// render produces the task for a request ("" = skip it)
+func (h *webhook) render(data hookData) (string, error) {
+	var buf bytes.Buffer
+	if err := h.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// record remembers a delivery, dropping the oldest past hookDeliveriesKept
+func (h *webhook) record(d *HookDelivery) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deliveries = append(h.deliveries, d)
+	if len(h.deliveries) > hookDeliveriesKept {
+		h.deliveries = h.deliveries[len(h.deliveries)-hookDeliveriesKept:]
+	}
+}
+
+// update changes a delivery under the hook's lock
+func (h *webhook) update(d *HookDelivery, change func(*HookDelivery)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	change(d)
+}
+
+// finish records how a delivery's task ended
+func (h *webhook) finish(d *HookDelivery, start time.Time, err error) {
+	h.update(d, func(d *HookDelivery) {
+		d.Duration = time.Since(start).Round(time.Millisecond).String()
+		d.Status = "done"
+		if err != nil {
+			d.Status = "failed"
+			d.Error = err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("[Hooks] %s: task failed: %v", h.name, err)
+	} else {
+		log.Printf("[Hooks] %s: task done in session %s", h.name, d.SessionID)
+	}
+}
+
+// recent returns copies of the hook's deliveries, most recent first
+func (h *webhook) recent() []HookDelivery {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]HookDelivery, 0, len(h.deliveries))
+	for i := len(h.deliveries) - 1; i >= 0; i-- {
+		out = append(out, *h.deliveries[i])
+	}
+	return out
+}
+
+// hooksHandler lists the hooks (GET /hooks) and receives their requests
+// (POST /hooks/{name})
+func (s *Server) hooksHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hooks"), "/")
+
+	if name == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
+			return
+		}
+		names := make([]string, 0, len(s.hooks))
+		for n := range s.hooks {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		list := make([]map[string]interface{}, 0, len(names))
+		for _, n := range names {
+			h := s.hooks[n]
+			entry := map[string]interface{}{
+				"name":       n,
+				"events":     h.cfg.Events,
+				"deliveries": h.recent(),
+			}
+			if h.cfg.Kind != "" {
+				entry["kind"] = h.cfg.Kind // Sessions per delivery
+			} else {
+				entry["session_id"] = h.sessionID()
+			}
+			if h.parseErr != nil {
+				entry["error"] = h.parseErr.Error()
+			}
+			list = append(list, entry)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"hooks": list})
+		return
+	}
+
+	h, ok := s.hooks[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, types.ErrNotFound, "Unknown hook: "+name)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
+		return
+	}
+	atomic.AddInt64(&s.metrics.RequestsTotal, 1)
+	if !s.allowRequest(r) {
+		writeError(w, http.StatusTooManyRequests, types.ErrRateLimited, "Rate limit exceeded")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if !h.verify(r, body) {
+		writeError(w, http.StatusUnauthorized, types.ErrPermissionDenied, "Invalid hook signature or token")
+		return
+	}
+	if h.parseErr != nil {
+		writeError(w, http.StatusInternalServerError, types.ErrInternal, "Hook template: "+h.parseErr.Error())
+		return
+	}
+
+	// Non-JSON bodies reach the template as a string
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		payload = string(body)
+	}
+	headers := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		headers[k] = r.Header.Get(k)
+	}
+	data := hookData{Name: name, Event: hookEvent(r, payload), Payload: payload, Headers: headers}
+	delivery := &HookDelivery{At: time.Now(), Event: data.Event}
+
+	respond := func(status int) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(delivery)
+	}
+
+	if id, seen := h.replayed(r); seen {
+		delivery.Status = "skipped"
+		delivery.Error = fmt.Sprintf("delivery %s was already received", id)
+		h.record(delivery)
+		respond(http.StatusOK)
+		return
+	}
+	if len(h.cfg.Events) > 0 && !slices.Contains(h.cfg.Events, data.Event) {
+		delivery.Status = "skipped"
+		delivery.Error = fmt.Sprintf("event %q not handled", data.Event)
+		h.record(delivery)
+		respond(http.StatusOK)
+		return
+	}
+	if h.cfg.Kind == "github" {
+		s.githubHook(h, data.Event, body, delivery, respond)
+		return
+	}
+	task, err := h.render(data)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, types.ErrInvalidArgument, "Hook template: "+err.Error())
+		return
+	}
+	if task == "" {
+		delivery.Status = "skipped"
+		h.record(delivery)
+		respond(http.StatusOK)
+		return
+	}
+
+	delivery.Status = "running"
+	delivery.SessionID = h.sessionID()
+	delivery.Task = task
+	h.record(delivery)
+	respond(http.StatusAccepted)
+
+	go s.runHookTask(h, delivery, task)
+}
+
+// runHookTask runs a hook's task in its session, after any task of the
+// session still running
+func (s *Server) runHookTask(h *webhook, delivery *HookDelivery, task string) {
+	start := time.Now()
+	finish := func(err error) { h.finish(delivery, start, err) }
+	// Outside the handler, RecoveryMiddleware can't catch a panic
+	defer func() {
+		if r := recover(); r != nil {
+			finish(panicError("hook "+h.name, r))
+		}
+	}()
+
+	workingDir := h.cfg.WorkingDir
+	if workingDir == "" {
+		workingDir = "."
+	}
+	_, err := s.agentService.Chat(context.Background(), ChatRequest{
+		SessionID:  delivery.SessionID,
+		UserInput:  task,
+		WorkingDir: workingDir,
+		Provider:   h.cfg.Provider,
+		Model:      h.cfg.Model,
+		MaxSteps:   h.cfg.MaxSteps,
+		Tags:       append([]string{"hook"}, h.cfg.Tags...),
+		Queue:      true,
+	})
+	finish(err)
+}
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/ratelimit"
+)
+
+func TestWebhookVerify(t *testing.T) {
+	h := newWebhooks(map[string]config.HookConfig{
+		"github": {Template: "x", Secret: "s3cret"},
+	})["github"]
+	body := []byte(`{"ref":"refs/heads/main"}`)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	req := httptest.NewRequest("POST", "/hooks/github", nil)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	if !h.verify(req, body) {
+		t.Error("valid GitHub signature rejected")
+	}
+	if h.verify(req, []byte(`{"ref":"refs/heads/evil"}`)) {
+		t.Error("signature accepted for another body")
+	}
+
+	req = httptest.NewRequest("POST", "/hooks/github", nil)
+	req.Header.Set("X-Hook-Token", "s3cret")
+	if !h.verify(req, body) {
+		t.Error("valid token rejected")
+	}
+	req.Header.Set("X-Hook-Token", "wrong")
+	if h.verify(req, body) {
+		t.Error("wrong token accepted")
+	}
+	if h.verify(httptest.NewRequest("POST", "/hooks/github", nil), body) {
+		t.Error("request without signature or token accepted")
+	}
+
+	// Without a secret, only an insecure hook is registered
+	hooks := newWebhooks(map[string]config.HookConfig{"open": {Template: "x"}, "insecure": {Template: "x", Insecure: true}})
+	if hooks["open"] != nil {
+		t.Error("hook without a secret was registered")
+	}
+	if (&webhook{cfg: config.HookConfig{Template: "x"}}).verify(httptest.NewRequest("POST", "/hooks/open", nil), body) {
+		t.Error("hook without a secret accepted a request")
+	}
+	if !hooks["insecure"].verify(httptest.NewRequest("POST", "/hooks/insecure", nil), body) {
+		t.Error("insecure hook rejected a request")
+	}
+}
+
+func TestWebhookRender(t *testing.T) {
+	h := newWebhooks(map[string]config.HookConfig{
+		"github": {Template: `{{if eq .Payload.ref "refs/heads/main"}}Review {{len .Payload.commits}} commits ({{.Event}}){{end}}`, Insecure: true},
+	})["github"]
+
+	req := httptest.NewRequest("POST", "/hooks/github", nil)
+	req.Header.Set("X-GitHub-Event", "push")
+	payload := map[string]interface{}{"ref": "refs/heads/main", "commits": []interface{}{1, 2}}
+	task, err := h.render(hookData{Name: "github", Event: hookEvent(req, payload), Payload: payload})
+	if err != nil || task != "Review 2 commits (push)" {
+		t.Errorf("render = %q, %v", task, err)
+	}
+
+	// Nothing rendered skips the request
+	payload["ref"] = "refs/heads/dev"
+	if task, _ := h.render(hookData{Payload: payload}); task != "" {
+		t.Errorf("render = %q, want empty", task)
+	}
+
+	// Events from PagerDuty and Jira payloads
+	plain := httptest.NewRequest("POST", "/hooks/x", nil)
+	pd := map[string]interface{}{"event": map[string]interface{}{"event_type": "incident.triggered"}}
+	if got := hookEvent(plain, pd); got != "incident.triggered" {
+		t.Errorf("pagerduty event = %q", got)
+	}
+	if got := hookEvent(plain, map[string]interface{}{"webhookEvent": "jira:issue_created"}); got != "jira:issue_created" {
+		t.Errorf("jira event = %q", got)
+	}
+
+	bad := newWebhooks(map[string]config.HookConfig{"bad": {Template: "{{.Payload", Insecure: true}})["bad"]
+	if bad.parseErr == nil {
+		t.Error("expected parse error for an invalid template")
+	}
+}
+
+func TestWebhookSkipsUnhandledEvents(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	s := &Server{
+		config:      cfg,
+		metrics:     &Metrics{},
+		rateLimiter: ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+		hooks: newWebhooks(map[string]config.HookConfig{
+			"github": {Template: "Review", Events: []string{"pull_request"}, Insecure: true},
+		}),
+	}
+	defer s.rateLimiter.Close()
+	req := httptest.NewRequest("POST", "/hooks/github", strings.NewReader(`{}`))
+	req.Header.Set("X-GitHub-Event", "push")
+	rec := httptest.NewRecorder()
+	s.hooksHandler(rec, req)
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"skipped"`) {
+		t.Fatalf("got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A repeated delivery ID is skipped before its event is looked at
+	for i, want := range []string{`event \"push\" not handled`, "delivery 72d3162e was already received"} {
+		req := httptest.NewRequest("POST", "/hooks/github", strings.NewReader(`{}`))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-GitHub-Delivery", "72d3162e")
+		rec := httptest.NewRecorder()
+		s.hooksHandler(rec, req)
+		if rec.Code != 200 || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("delivery %d: got %d %s, want %s", i, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.hooksHandler(rec, httptest.NewRequest("POST", "/hooks/missing", nil))
+	if rec.Code != 404 {
+		t.Errorf("unknown hook: got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.hooksHandler(rec, httptest.NewRequest("GET", "/hooks", nil))
+	if !strings.Contains(rec.Body.String(), `"status":"skipped"`) {
+		t.Errorf("GET /hooks = %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// A chat request may carry an Idempotency-Key header, so a client retrying
+// after a network error doesn't run the agent twice. The first request with
+// a key runs; repeats from the same client get its result instead: /chat the
+// same response (waiting for it if the first is still running), /chat/stream
+// the same stream (replayed while retained, then only its final event).
+// Failures are replayed too, once the request got past admission. Keys are
+// kept for gateway.idempotency_ttl_mins after the request finished. A
+// request rejected before it ran (invalid, busy session, over a limit)
+// doesn't keep its key, so the retry runs.
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayHeader marks a response replayed for a repeated key
+	idempotentReplayHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLen bounds the keys clients may send
+	maxIdempotencyKeyLen = 255
+
+	// defaultIdempotencyTTL applies when idempotency_ttl_mins is unset
+	defaultIdempotencyTTL = time.Hour
+)
+
+// idempotentRequest is the first request sent with a key
+type idempotentRequest struct {
+	fingerprint string        // Hash of the endpoint and request body
+	ready       chan struct{} // Closed once the result is set, or the request abandoned
+
+	resp      *ChatResponse // /chat: the response
+	err       error         // /chat: the error it failed with instead
+	streamID  string        // /chat/stream: the request's event stream
+	final     []byte        // /chat/stream: its final event, once finished
+	abandoned bool          // Rejected before running; the key was released
+	finished  time.Time     // Zero while running
+}
+
+// idempotencyStore holds the requests of recently used keys
+type idempotencyStore struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	requests map[string]*idempotentRequest // By client and key
+}
+
+func newIdempotencyStore(ttlMins int) *idempotencyStore {
+	ttl := time.Duration(ttlMins) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &idempotencyStore{ttl: ttl, requests: make(map[string]*idempotentRequest)}
+}
+
+// begin claims a client's key for a request. The key's first request gets
+// first = true and must be finished or released; a repeat gets the first
+// request instead. Reusing a key for a different request is an error.
+func (st *idempotencyStore) begin(client, key string, req ChatRequest, endpoint string) (entry *idempotentRequest, first bool, err error) {
+	if len(key) > maxIdempotencyKeyLen {
+		return nil, false, types.Errorf(types.ErrInvalidArgument, "%s is longer than %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen)
+	}
+	fingerprint := requestFingerprint(endpoint, req)
+	id := client + "\x00" + key
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for k, r := range st.requests {
+		if !r.finished.IsZero() && time.Since(r.finished) > st.ttl {
+			delete(st.requests, k)
+		}
+	}
+	if r, ok := st.requests[id]; ok {
+		if r.fingerprint != fingerprint {
+			return nil, false, types.Errorf(types.ErrInvalidArgument, "%s %q was already used for a different request", idempotencyKeyHeader, key)
+		}
+		return r, false, nil
+	}
+	entry = &idempotentRequest{fingerprint: fingerprint, ready: make(chan struct{})}
+	st.requests[id] = entry
+	return entry, true, nil
+}
+
+// finish records the response of a /chat request, or the error it failed
+// with (no-op once it has a result)
+func (st *idempotencyStore) finish(entry *idempotentRequest, resp *ChatResponse, err error) {
+	if entry == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	select {
+	case <-entry.ready:
+		return
+	default:
+	}
+	entry.resp, entry.err = resp, err
+	entry.finished = time.Now()
+	close(entry.ready)
+}
+
+// started records the event stream of a /chat/stream request; repeats
+// follow it from then on
+func (st *idempotencyStore) started(entry *idempotentRequest, streamID string) {
+	if entry == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	entry.streamID = streamID
+	close(entry.ready)
+}
+
+// finishStream records the final event of a /chat/stream request, which is
+// replayed once its stream expired
+func (st *idempotencyStore) finishStream(entry *idempotentRequest, final []byte) {
+	if entry == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	entry.final = final
+	entry.finished = time.Now()
+}
+
+// release gives up the key of a request that didn't get to run (no-op once
+// it finished or started streaming), so a retry runs it
+func (st *idempotencyStore) release(client, key string, entry *idempotentRequest) {
+	if entry == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	select {
+	case <-entry.ready:
+		return
+	default:
+	}
+	entry.abandoned = true
+	close(entry.ready)
+	st.forgetLocked(client, key, entry)
+}
+
+// forget gives up the key of a streamed request that failed before the
+// agent ran; clients following its stream still get the error
+func (st *idempotencyStore) forget(client, key string, entry *idempotentRequest) {
+	if entry == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.forgetLocked(client, key, entry)
+}
+
+func (st *idempotencyStore) forgetLocked(client, key string, entry *idempotentRequest) {
+	if st.requests[client+"\x00"+key] == entry {
+		delete(st.requests, client+"\x00"+key)
+	}
+}
+
+// wait blocks until the request has a result. It returns false when the
+// request was abandoned, so the caller should claim the key again.
+func (st *idempotencyStore) wait(ctx context.Context, entry *idempotentRequest) (bool, error) {
+	select {
+	case <-entry.ready:
+	case <-ctx.Done():
+		return false, types.Errorf(types.ErrCanceled, "canceled while waiting for the original request: %v", ctx.Err())
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return !entry.abandoned, nil
+}
+
+// result returns what a repeat replays
+func (st *idempotencyStore) result(entry *idempotentRequest) (resp *ChatResponse, streamID string, final []byte, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return entry.resp, entry.streamID, entry.final, entry.err
+}
+
+// rejectedBeforeRun reports whether the agent service refused a request
+// without running it (busy session, or canceled while queued for it), so
+// its key is released rather than its error replayed
+func rejectedBeforeRun(err error) bool {
+	code := types.CodeOf(err)
+	return code == types.ErrConflict || code == types.ErrCanceled
+}
+
+// requestFingerprint hashes a request, so a key reused for another one is
+// caught
+func requestFingerprint(endpoint string, req ChatRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(append([]byte(endpoint+"\n"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimIdempotencyKey claims the request's Idempotency-Key (a nil entry
+// without one). For a repeat it returns the first request once that has a
+// result, with replay = true.
+func (s *Server) claimIdempotencyKey(r *http.Request, req ChatRequest) (entry *idempotentRequest, replay bool, err error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, false, nil
+	}
+	for {
+		entry, first, err := s.idempotency.begin(s.clientAddr(r), key, req, r.URL.Path)
+		if err != nil || first {
+			return entry, false, err
+		}
+		ok, err := s.idempotency.wait(r.Context(), entry)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			log.Printf("[Gateway] Replaying %s for %s %q", r.URL.Path, idempotencyKeyHeader, key)
+			return entry, true, nil
+		}
+		// The first request was rejected before running: try to run this one
+	}
+}
+
+// replayChat answers a repeated /chat request with the first one's response
+// or error
+func (s *Server) replayChat(w http.ResponseWriter, entry *idempotentRequest) {
+	resp, _, _, err := s.idempotency.result(entry)
+	w.Header().Set(idempotentReplayHeader, "true")
+	if err != nil {
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, fmt.Sprintf("Agent service error: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// replayStream answers a repeated /chat/stream request with the first one's
+// stream while it is retained, and with its final event after that
+func (s *Server) replayStream(w http.ResponseWriter, r *http.Request, entry *idempotentRequest) {
+	_, streamID, final, _ := s.idempotency.result(entry)
+	stream := s.streams.get(streamID)
+	if stream == nil {
+		if final == nil {
+			writeError(w, http.StatusNotFound, types.ErrNotFound, fmt.Sprintf("stream %s of the original request expired", streamID))
+			return
+		}
+		stream = s.streams.create()
+		stream.publish(json.RawMessage(final), true)
+	}
+	w.Header().Set(idempotentReplayHeader, "true")
+	s.serveStream(w, r, stream, 0)
+}
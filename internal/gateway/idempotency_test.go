@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	st := newIdempotencyStore(0)
+	req := ChatRequest{UserInput: "deploy", WorkingDir: "."}
+
+	first, isFirst, err := st.begin("10.0.0.1", "k1", req, "/chat")
+	if err != nil || !isFirst {
+		t.Fatalf("begin = %v, %v", isFirst, err)
+	}
+
+	// A repeat waits for the first request's response
+	repeat, isFirst, err := st.begin("10.0.0.1", "k1", req, "/chat")
+	if err != nil || isFirst || repeat != first {
+		t.Fatalf("repeat begin = %v, %v", isFirst, err)
+	}
+	waited := make(chan bool)
+	go func() {
+		ok, _ := st.wait(context.Background(), repeat)
+		waited <- ok
+	}()
+	st.finish(first, &ChatResponse{Result: "deployed"}, nil)
+	select {
+	case ok := <-waited:
+		if resp, _, _, _ := st.result(repeat); !ok || resp.Result != "deployed" {
+			t.Errorf("repeat got %v, %+v", ok, resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after finish")
+	}
+	st.release("10.0.0.1", "k1", first) // No-op once finished
+	st.finish(first, nil, types.Errorf(types.ErrInternal, "panic"))
+	if resp, _, _, err := st.result(first); resp == nil || err != nil {
+		t.Errorf("a second finish replaced the result: %+v, %v", resp, err)
+	}
+	if _, isFirst, _ := st.begin("10.0.0.1", "k1", req, "/chat"); isFirst {
+		t.Error("release dropped a finished request's key")
+	}
+
+	// Keys are per client, and tied to the request
+	if _, isFirst, _ := st.begin("10.0.0.2", "k1", req, "/chat"); !isFirst {
+		t.Error("another client's key was shared")
+	}
+	other := req
+	other.UserInput = "rollback"
+	if _, _, err := st.begin("10.0.0.1", "k1", other, "/chat"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("reused key for another request: code = %v, want invalid_argument", types.CodeOf(err))
+	}
+	if _, _, err := st.begin("10.0.0.1", "k1", req, "/chat/stream"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("reused key on another endpoint: code = %v, want invalid_argument", types.CodeOf(err))
+	}
+
+	// A request rejected before running releases its key
+	rejected, _, _ := st.begin("10.0.0.1", "k2", req, "/chat")
+	st.release("10.0.0.1", "k2", rejected)
+	if ok, err := st.wait(context.Background(), rejected); ok || err != nil {
+		t.Errorf("wait on a released request = %v, %v, want false", ok, err)
+	}
+	if _, isFirst, _ := st.begin("10.0.0.1", "k2", req, "/chat"); !isFirst {
+		t.Error("a released key was not claimable again")
+	}
+
+	// A request that failed once admitted replays its error
+	failed, _, _ := st.begin("10.0.0.1", "k3", req, "/chat")
+	st.finish(failed, nil, types.Errorf(types.ErrTimeout, "deadline exceeded"))
+	repeat, isFirst, _ = st.begin("10.0.0.1", "k3", req, "/chat")
+	if ok, _ := st.wait(context.Background(), repeat); isFirst || !ok {
+		t.Errorf("repeat of a failed request: first = %v, ready = %v", isFirst, ok)
+	}
+	if _, _, _, err := st.result(repeat); types.CodeOf(err) != types.ErrTimeout {
+		t.Errorf("replayed error = %v, want TIMEOUT", err)
+	}
+
+	// Finished requests expire after the TTL
+	st.mu.Lock()
+	first.finished = time.Now().Add(-2 * defaultIdempotencyTTL)
+	st.mu.Unlock()
+	if _, isFirst, _ := st.begin("10.0.0.1", "k1", other, "/chat"); !isFirst {
+		t.Error("an expired key was not claimable again")
+	}
+}
+
+func TestIdempotencyStream(t *testing.T) {
+	st := newIdempotencyStore(5)
+	req := ChatRequest{UserInput: "deploy"}
+	entry, _, _ := st.begin("c", "k", req, "/chat/stream")
+	st.started(entry, "stream1")
+	st.release("c", "k", entry) // No-op once streaming
+
+	repeat, isFirst, _ := st.begin("c", "k", req, "/chat/stream")
+	if ok, _ := st.wait(context.Background(), repeat); isFirst || !ok {
+		t.Fatalf("repeat of a streaming request: first = %v, ready = %v", isFirst, ok)
+	}
+	st.finishStream(entry, []byte(`{"type":"done"}`))
+	if _, streamID, final, _ := st.result(repeat); streamID != "stream1" || string(final) != `{"type":"done"}` {
+		t.Errorf("result = %q, %s", streamID, final)
+	}
+
+	// A stream that failed before the agent ran gives its key up
+	st.forget("c", "k", entry)
+	if _, isFirst, _ := st.begin("c", "k", req, "/chat/stream"); !isFirst {
+		t.Error("a forgotten key was not claimable again")
+	}
+}
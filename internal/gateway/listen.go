@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/neves/zen-claw/internal/config"
+)
+
+// listen opens the gateway's listener: the unix socket when one is
+// configured (owner-only, so only this user can reach the gateway),
+// otherwise host:port over TCP.
+//
+// A leftover socket from a crashed gateway is removed first; callers hold
+// the run state by then, so it can't belong to a live gateway.
+func listen(cfg *config.GatewayConfig) (net.Listener, error) {
+	socket := cfg.GetSocket()
+	if socket == "" {
+		return net.Listen("tcp", cfg.GetAddr())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		os.Remove(socket)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
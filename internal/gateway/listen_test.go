@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/config"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "run", "gateway.sock")
+	cfg := &config.GatewayConfig{Socket: socket}
+
+	// A socket left behind by a crashed gateway is replaced
+	os.MkdirAll(filepath.Dir(socket), 0700)
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listen(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	info, err := os.Stat(socket)
+	if err != nil || info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket = %v, %v; want an owner-only socket", info, err)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// A regular file at the path is not removed
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(file, []byte("keep"), 0600)
+	if _, err := listen(&config.GatewayConfig{Socket: file}); err == nil {
+		t.Error("listen over a regular file succeeded")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep" {
+		t.Error("regular file was replaced")
+	}
+}
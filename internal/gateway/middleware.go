@@ -3,7 +3,11 @@ package gateway
 import (
 	"log"
 	"net/http"
+	"runtime/debug"
+	"sync/atomic"
 	"time"
+
+	"github.com/neves/zen-claw/internal/types"
 )
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -60,12 +64,24 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestPanics counts panics recovered in handlers and the goroutines
+// running requests (agent panics are counted by the agent package)
+var requestPanics atomic.Int64
+
+// panicError logs a recovered panic with its stack, counts it, and returns
+// it as an INTERNAL error for the client
+func panicError(where string, value interface{}) error {
+	requestPanics.Add(1)
+	log.Printf("[HTTP] PANIC in %s: %v\n%s", where, value, debug.Stack())
+	return types.Errorf(types.ErrInternal, "internal error: %v", value)
+}
+
 // RecoveryMiddleware recovers from panics and returns 500
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("[HTTP] PANIC: %v - %s %s", err, r.Method, r.URL.Path)
+				panicError(r.Method+" "+r.URL.Path, err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -73,6 +89,18 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// BodyLimitMiddleware caps request bodies at maxBytes
+func BodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Chain applies multiple middleware in order
 func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for i := len(middlewares) - 1; i >= 0; i-- {
@@ -1,9 +1,15 @@
 package gateway
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 func TestResponseWriter(t *testing.T) {
@@ -135,3 +141,106 @@ func TestChain(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeError(recorder, http.StatusNotFound, types.ErrNotFound, "Session not found")
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	var body types.ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if body.ErrorCode != types.ErrNotFound || body.Error != "Session not found" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	t.Run("body size", func(t *testing.T) {
+		handler := BodyLimitMiddleware(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req ChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeDecodeError(w, err)
+			}
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/chat", strings.NewReader(`{"user_input": "far more than sixteen bytes"}`)))
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want 413", rec.Code)
+		}
+	})
+
+	root := t.TempDir()
+	limits := newRequestLimits(config.RequestLimitsConfig{MaxSteps: 50, WorkingDirs: []string{root}, MaxSessionsPerClient: 2})
+
+	t.Run("max steps and working dir", func(t *testing.T) {
+		tests := []struct {
+			req  ChatRequest
+			want types.ErrorCode
+		}{
+			{ChatRequest{MaxSteps: 50, WorkingDir: root}, ""},
+			{ChatRequest{WorkingDir: filepath.Join(root, "sub", "dir")}, ""},
+			{ChatRequest{MaxSteps: 51, WorkingDir: root}, types.ErrInvalidArgument},
+			{ChatRequest{WorkingDir: filepath.Join(root, "..")}, types.ErrPermissionDenied},
+			{ChatRequest{WorkingDir: root + "-other"}, types.ErrPermissionDenied},
+		}
+		for _, tt := range tests {
+			if err := limits.check(tt.req); types.CodeOf(err) != tt.want {
+				t.Errorf("check(%+v) = %v, want %q", tt.req, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("trusted proxies", func(t *testing.T) {
+		s := &Server{trustedProxies: parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "bogus"})}
+		tests := []struct {
+			remote, forwarded, want string
+		}{
+			{"203.0.113.5:4321", "198.51.100.7", "203.0.113.5"}, // Not a proxy: header ignored
+			{"10.1.2.3:80", "", "10.1.2.3"},
+			{"10.1.2.3:80", "198.51.100.7", "198.51.100.7"},
+			{"192.0.2.1:80", "6.6.6.6, 198.51.100.7, 10.9.9.9", "198.51.100.7"}, // Client-set hops are skipped
+		}
+		for _, tt := range tests {
+			r := httptest.NewRequest("POST", "/chat", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := s.clientAddr(r); got != tt.want {
+				t.Errorf("clientAddr(%s, %q) = %q, want %q", tt.remote, tt.forwarded, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("concurrent sessions", func(t *testing.T) {
+		releaseA, err := limits.acquire("10.0.0.1", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseNew, err := limits.acquire("10.0.0.1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Same session again is fine, a third session is not, other clients are
+		releaseA2, err := limits.acquire("10.0.0.1", "a")
+		if err != nil {
+			t.Fatalf("second request to a running session: %v", err)
+		}
+		if _, err := limits.acquire("10.0.0.1", "b"); types.CodeOf(err) != types.ErrRateLimited {
+			t.Errorf("third session: err = %v, want RATE_LIMITED", err)
+		}
+		if _, err := limits.acquire("10.0.0.2", "b"); err != nil {
+			t.Errorf("other client: %v", err)
+		}
+		releaseA()
+		releaseA2()
+		releaseNew()
+		if _, err := limits.acquire("10.0.0.1", "b"); err != nil {
+			t.Errorf("after release: %v", err)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// The model registry (config.ModelContextInfo, ModelProfileInfo) only knows
+// the models zen-claw ships with. The first time a session uses a model it
+// knows nothing about, the gateway sends it three tiny requests - does it
+// call a tool, answer in JSON mode, follow the system prompt? - and picks the
+// interaction protocol from the answers (see agent.PromptProfile.Adapt).
+// Results are kept in the session database, so each model is probed once.
+
+// probeTimeout bounds each probe request
+const probeTimeout = 30 * time.Second
+
+// probeWord is what the system prompt probe asks the model to answer
+const probeWord = "PINEAPPLE"
+
+// capabilityProbe probes models and caches the results
+type capabilityProbe struct {
+	mu       sync.Mutex
+	results  map[string]types.ModelCapabilities // provider/model -> capabilities
+	inflight map[string]chan struct{}           // Closed when a running probe finishes
+	store    *SessionStore                      // nil = not persisted
+}
+
+func newCapabilityProbe(store *SessionStore) *capabilityProbe {
+	p := &capabilityProbe{
+		results:  make(map[string]types.ModelCapabilities),
+		inflight: make(map[string]chan struct{}),
+		store:    store,
+	}
+	if store != nil {
+		if saved, err := store.ModelCapabilities(); err == nil {
+			for _, caps := range saved {
+				p.results[caps.Provider+"/"+caps.Model] = caps
+			}
+		}
+	}
+	return p
+}
+
+// capabilities returns the model's probed capabilities, probing it first if
+// needed; false when the probe failed (the provider is unreachable). Only
+// one probe per model runs at a time; concurrent callers wait for it.
+func (p *capabilityProbe) capabilities(ctx context.Context, provider ai.Provider, providerName, model string) (types.ModelCapabilities, bool) {
+	key := providerName + "/" + model
+	for {
+		p.mu.Lock()
+		if caps, ok := p.results[key]; ok {
+			p.mu.Unlock()
+			return caps, true
+		}
+		wait, running := p.inflight[key]
+		if !running {
+			done := make(chan struct{})
+			p.inflight[key] = done
+			p.mu.Unlock()
+
+			caps, ok := probeModel(ctx, provider, providerName, model)
+			p.mu.Lock()
+			if ok {
+				p.results[key] = caps
+			}
+			delete(p.inflight, key)
+			p.mu.Unlock()
+			close(done)
+			if ok && p.store != nil {
+				if err := p.store.SaveModelCapabilities(caps); err != nil {
+					log.Printf("[Probe] Failed to save capabilities of %s: %v", key, err)
+				}
+			}
+			return caps, ok
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-wait:
+			p.mu.Lock()
+			caps, ok := p.results[key]
+			p.mu.Unlock()
+			if ok {
+				return caps, true
+			}
+			// The other probe failed: try again
+		case <-ctx.Done():
+			return types.ModelCapabilities{}, false
+		}
+	}
+}
+
+// known reports whether the model was probed
+func (p *capabilityProbe) known(providerName, model string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.results[providerName+"/"+model]
+	return ok
+}
+
+// snapshot returns all known capabilities sorted by provider/model
+func (p *capabilityProbe) snapshot() []types.ModelCapabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]types.ModelCapabilities, 0, len(p.results))
+	for _, caps := range p.results {
+		out = append(out, caps)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Provider+"/"+out[i].Model < out[j].Provider+"/"+out[j].Model
+	})
+	return out
+}
+
+// probeModel runs the three probes concurrently. A probe that errors counts
+// as unsupported, unless all of them error: then the provider is likely down
+// and nothing is learned.
+func probeModel(ctx context.Context, provider ai.Provider, providerName, model string) (types.ModelCapabilities, bool) {
+	caps := types.ModelCapabilities{Provider: providerName, Model: model, ProbedAt: time.Now().UTC()}
+	probes := []struct {
+		result *bool
+		run    func(context.Context, ai.Provider, string) (bool, error)
+	}{
+		{&caps.Tools, probeTools},
+		{&caps.JSONMode, probeJSONMode},
+		{&caps.SystemPrompt, probeSystemPrompt},
+	}
+
+	errs := make([]error, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			*probe.result, errs[i] = probe.run(probeCtx, provider, model)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(probes) {
+		log.Printf("[Probe] %s/%s: all probes failed (%v), will retry on next use", providerName, model, errs[0])
+		return caps, false
+	}
+	log.Printf("[Probe] %s/%s: tools=%t json_mode=%t system_prompt=%t", providerName, model, caps.Tools, caps.JSONMode, caps.SystemPrompt)
+	return caps, true
+}
+
+// probeTools asks for a call to a single tool
+func probeTools(ctx context.Context, provider ai.Provider, model string) (bool, error) {
+	resp, err := provider.Chat(ctx, ai.ChatRequest{
+		Model:     model,
+		MaxTokens: 100,
+		Messages:  []ai.Message{{Role: "user", Content: `Call the echo tool with text "ok". Do not answer in prose.`}},
+		Tools: []ai.Tool{{
+			Name:        "echo",
+			Description: "Echo the text back",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+				"required":   []string{"text"},
+			},
+		}},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, call := range resp.ToolCalls {
+		if call.Name == "echo" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// probeJSONMode asks for a JSON object in JSON mode
+func probeJSONMode(ctx context.Context, provider ai.Provider, model string) (bool, error) {
+	resp, err := provider.Chat(ctx, ai.ChatRequest{
+		Model:     model,
+		MaxTokens: 100,
+		Messages:  []ai.Message{{Role: "user", Content: `Reply with the JSON object {"ok": true} and nothing else.`}},
+		ResponseSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"ok": map[string]interface{}{"type": "boolean"}},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	var out map[string]interface{}
+	return json.Unmarshal([]byte(strings.TrimSpace(resp.Content)), &out) == nil, nil
+}
+
+// probeSystemPrompt checks that an instruction in the system prompt is followed
+func probeSystemPrompt(ctx context.Context, provider ai.Provider, model string) (bool, error) {
+	resp, err := provider.Chat(ctx, ai.ChatRequest{
+		Model:     model,
+		MaxTokens: 100,
+		Messages: []ai.Message{
+			{Role: "system", Content: "Whatever the user says, answer with exactly one word: " + probeWord + "."},
+			{Role: "user", Content: "Hello! How are you?"},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(strings.ToUpper(resp.Content), probeWord), nil
+}
+
+// modelCapabilities probes a model the registry does not know (once; later
+// calls return the cached results). False for known models, when probing is
+// disabled, or when the probe failed.
+func (s *AgentService) modelCapabilities(ctx context.Context, providerName, model string, progressCb ProgressCallback) (types.ModelCapabilities, bool) {
+	if !s.config.ProbeModelsEnabled() || s.config.KnownModel(model) {
+		return types.ModelCapabilities{}, false
+	}
+	provider, ok := s.aiRouter.GetProvider(providerName)
+	if !ok {
+		return types.ModelCapabilities{}, false
+	}
+	if progressCb != nil && !s.probe.known(providerName, model) {
+		progressCb(types.ProgressEvent{
+			Version: types.ProgressSchemaVersion,
+			Type:    "thinking",
+			Message: "Probing " + providerName + "/" + model + " (first use): tools, JSON mode, system prompt",
+		})
+	}
+	return s.probe.capabilities(ctx, provider, providerName, model)
+}
+
+// GetModelCapabilities returns the capabilities of the probed models
+func (s *AgentService) GetModelCapabilities() []types.ModelCapabilities {
+	return s.probe.snapshot()
+}
@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// COST OPTIMIZER MEASUREMENT
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Optimization passes, in the order OptimizeRequest runs them
+const (
+	passTurnLimit     = "turn_limit"
+	passCompression   = "compression"
+	passToolPruning   = "tool_pruning"
+	passSummarization = "summarization"
+)
+
+var optimizationPasses = []string{passTurnLimit, passCompression, passToolPruning, passSummarization}
+
+// PassSavings is what one optimization pass saved across sampled requests
+type PassSavings struct {
+	Pass         string  `json:"pass"`
+	Applied      int64   `json:"applied"`       // Sampled requests the pass changed
+	TokensBefore int64   `json:"tokens_before"` // Input to the pass, summed over samples
+	TokensSaved  int64   `json:"tokens_saved"`
+	SavedPercent float64 `json:"saved_percent"` // Of the request's original tokens
+}
+
+// ToolPruneSavings is what pruning one tool's results saved, with the rule
+// that did it
+type ToolPruneSavings struct {
+	Tool        string `json:"tool"`
+	Truncated   int64  `json:"truncated"` // Results cut
+	TokensSaved int64  `json:"tokens_saved"`
+	MaxTokens   int    `json:"max_tokens"`
+	KeepRecent  int    `json:"keep_recent"`
+	Aggressive  bool   `json:"aggressive,omitempty"`
+}
+
+// OptimizerStats reports realized savings per technique on sampled requests
+type OptimizerStats struct {
+	SampleRate   float64            `json:"sample_rate"`
+	Sampled      int64              `json:"sampled_requests"`
+	TokensBefore int64              `json:"tokens_before"` // Before any pass
+	TokensAfter  int64              `json:"tokens_after"`  // After all passes
+	Passes       []PassSavings      `json:"passes"`
+	Tools        []ToolPruneSavings `json:"tools,omitempty"` // Most saved first
+}
+
+// toolSaving accumulates one tool's pruning in a sample
+type toolSaving struct {
+	results int
+	tokens  int
+}
+
+// optimizationSample measures one request as it goes through the passes.
+// Methods are no-ops on a nil sample (request not sampled).
+type optimizationSample struct {
+	original int
+	last     int
+	passes   map[string]int // Tokens saved per pass
+	tools    map[string]*toolSaving
+}
+
+func newOptimizationSample(messages []ai.Message) *optimizationSample {
+	tokens := agent.MessagesTokens(messages)
+	return &optimizationSample{
+		original: tokens,
+		last:     tokens,
+		passes:   make(map[string]int),
+		tools:    make(map[string]*toolSaving),
+	}
+}
+
+// pass records the messages as they are after the named pass
+func (s *optimizationSample) pass(name string, messages []ai.Message) {
+	if s == nil {
+		return
+	}
+	tokens := agent.MessagesTokens(messages)
+	s.passes[name] = s.last - tokens
+	s.last = tokens
+}
+
+// toolSavings is the map pruning adds per-tool savings to (nil when not sampled)
+func (s *optimizationSample) toolSavings() map[string]*toolSaving {
+	if s == nil {
+		return nil
+	}
+	return s.tools
+}
+
+// passTotals accumulates one pass over all samples
+type passTotals struct {
+	applied int64
+	before  int64
+	saved   int64
+}
+
+// optimizerMeasurements accumulates samples
+type optimizerMeasurements struct {
+	mu      sync.Mutex
+	sampled int64
+	before  int64
+	after   int64
+	passes  map[string]*passTotals
+	tools   map[string]*passTotals // applied = results truncated
+}
+
+func newOptimizerMeasurements() *optimizerMeasurements {
+	return &optimizerMeasurements{
+		passes: make(map[string]*passTotals),
+		tools:  make(map[string]*passTotals),
+	}
+}
+
+func (m *optimizerMeasurements) record(s *optimizationSample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sampled++
+	m.before += int64(s.original)
+	m.after += int64(s.last)
+
+	before := s.original
+	for _, name := range optimizationPasses {
+		t := m.passes[name]
+		if t == nil {
+			t = &passTotals{}
+			m.passes[name] = t
+		}
+		saved := s.passes[name]
+		t.before += int64(before)
+		t.saved += int64(saved)
+		if saved != 0 {
+			t.applied++
+		}
+		before -= saved
+	}
+	for tool, saving := range s.tools {
+		t := m.tools[tool]
+		if t == nil {
+			t = &passTotals{}
+			m.tools[tool] = t
+		}
+		t.applied += int64(saving.results)
+		t.saved += int64(saving.tokens)
+	}
+}
+
+// Stats returns savings measured so far (nil when measurement is off)
+func (o *CostOptimizer) Stats() *OptimizerStats {
+	if o.MeasureSampleRate <= 0 || o.measurements == nil {
+		return nil
+	}
+	m := o.measurements
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &OptimizerStats{
+		SampleRate:   o.MeasureSampleRate,
+		Sampled:      m.sampled,
+		TokensBefore: m.before,
+		TokensAfter:  m.after,
+		Passes:       []PassSavings{},
+	}
+	for _, name := range optimizationPasses {
+		p := PassSavings{Pass: name}
+		if t := m.passes[name]; t != nil {
+			p.Applied, p.TokensBefore, p.TokensSaved = t.applied, t.before, t.saved
+			if m.before > 0 {
+				p.SavedPercent = float64(t.saved) * 100 / float64(m.before)
+			}
+		}
+		stats.Passes = append(stats.Passes, p)
+	}
+	for tool, t := range m.tools {
+		rule, ok := o.ToolRules[tool]
+		if !ok || rule.MaxTokens == 0 {
+			rule.MaxTokens = o.MaxToolResultTokens
+		}
+		stats.Tools = append(stats.Tools, ToolPruneSavings{
+			Tool:        tool,
+			Truncated:   t.applied,
+			TokensSaved: t.saved,
+			MaxTokens:   rule.MaxTokens,
+			KeepRecent:  rule.KeepRecent,
+			Aggressive:  rule.Aggressive,
+		})
+	}
+	sort.Slice(stats.Tools, func(i, j int) bool { return stats.Tools[i].TokensSaved > stats.Tools[j].TokensSaved })
+	return stats
+}
@@ -0,0 +1,32 @@
+package gateway
+
+import "github.com/neves/zen-claw/internal/agent"
+
+// PinMessage pins or unpins a session message (-1 = latest user message) and
+// saves the session
+func (s *AgentService) PinMessage(sessionID string, index int, pinned bool) (agent.PinnedMessage, error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return agent.PinnedMessage{}, err
+	}
+
+	msg, err := session.PinMessage(index, pinned)
+	if err != nil {
+		return agent.PinnedMessage{}, err
+	}
+	if isNamedSession(sessionID) && s.sessionStore != nil {
+		if err := s.sessionStore.SaveSession(session); err != nil {
+			return agent.PinnedMessage{}, err
+		}
+	}
+	return msg, nil
+}
+
+// GetPinnedMessages returns a session's pinned messages
+func (s *AgentService) GetPinnedMessages(sessionID string) ([]agent.PinnedMessage, error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetPinnedMessages(), nil
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/privacy"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// newScrubber builds the prompt scrubber when privacy.scrub is on for any
+// workspace (nil otherwise)
+func newScrubber(cfg config.PrivacyConfig) *privacy.Scrubber {
+	enabled := cfg.Scrub
+	for _, on := range cfg.Projects {
+		enabled = enabled || on
+	}
+	if !enabled {
+		return nil
+	}
+	scrubber, err := privacy.New(cfg.Kinds, cfg.InternalDomains)
+	if err != nil {
+		log.Printf("Warning: privacy.kinds: %v, scrubbing every kind", err)
+		scrubber, _ = privacy.New(nil, cfg.InternalDomains)
+	}
+	return scrubber
+}
+
+// scrubberFor returns the scrubber for a session's project (nil = don't scrub)
+func (s *AgentService) scrubberFor(project string) *privacy.Scrubber {
+	if s.scrubber == nil || !s.config.Privacy.ScrubProject(project) {
+		return nil
+	}
+	return s.scrubber
+}
+
+// scrub replaces personal data in the request's messages; the returned
+// record puts it back in the answer (nil when not scrubbing)
+func (c *GatewayAICaller) scrub(req *ai.ChatRequest) *privacy.Redactions {
+	if c.scrubber == nil {
+		return nil
+	}
+	r := privacy.NewRedactions()
+	req.Messages = c.scrubber.Messages(req.Messages, r)
+	if r.Total() > 0 && c.onRedact != nil {
+		c.onRedact(r)
+	}
+	return r
+}
+
+// redactionReporter tells the client what was scrubbed, when it changes
+// (every call sends the whole history, so most calls repeat the last report)
+func redactionReporter(sessionID, provider string, progressCb ProgressCallback) func(*privacy.Redactions) {
+	var mu sync.Mutex
+	last := ""
+	return func(r *privacy.Redactions) {
+		summary := r.Summary()
+		mu.Lock()
+		repeated := summary == last
+		last = summary
+		mu.Unlock()
+		if repeated {
+			return
+		}
+		log.Printf("[Privacy] Session %s: redacted %s before calling %s", sessionID, summary, provider)
+		if progressCb != nil {
+			progressCb(types.ProgressEvent{
+				Version:   types.ProgressSchemaVersion,
+				Type:      "redacted",
+				SessionID: sessionID,
+				Message:   fmt.Sprintf("Redacted from the prompt: %s", summary),
+				Data:      map[string]interface{}{"counts": r.Counts},
+			})
+		}
+	}
+}
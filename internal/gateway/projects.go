@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/cost"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROJECTS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// projectFromDir derives a project name from a working directory: owner/repo
+// from the origin remote, else the repository's directory name. Empty when
+// dir is not inside a git repository.
+func projectFromDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if out, err := exec.Command("git", "-C", dir, "config", "--get", "remote.origin.url").Output(); err == nil {
+		if project := projectFromRemote(strings.TrimSpace(string(out))); project != "" {
+			return project
+		}
+	}
+	if out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output(); err == nil {
+		return filepath.Base(strings.TrimSpace(string(out)))
+	}
+	return ""
+}
+
+// projectFromRemote turns a git remote URL into owner/repo
+// (git@github.com:owner/repo.git, https://host/owner/repo, ssh://git@host/group/sub/repo)
+func projectFromRemote(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if i := strings.Index(remote, "://"); i >= 0 {
+		remote = remote[i+3:]
+		if j := strings.IndexByte(remote, '/'); j >= 0 {
+			remote = remote[j+1:] // Drop host
+		} else {
+			return ""
+		}
+	} else if i := strings.IndexByte(remote, ':'); i >= 0 {
+		remote = remote[i+1:] // scp-like syntax: host:path
+	}
+	parts := strings.Split(strings.Trim(remote, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.ToLower(parts[len(parts)-2] + "/" + parts[len(parts)-1])
+}
+
+// SessionFilter selects sessions by tag and/or project (empty fields match all)
+type SessionFilter struct {
+	Tag     string `json:"tag,omitempty"`
+	Project string `json:"project,omitempty"`
+}
+
+// Matches reports whether session stats pass the filter
+func (f SessionFilter) Matches(stats agent.SessionStats) bool {
+	if f.Project != "" && !strings.EqualFold(stats.Project, f.Project) {
+		return false
+	}
+	if f.Tag != "" {
+		tag := agent.NormalizeTag(f.Tag)
+		for _, t := range stats.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PROJECT BUDGETS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ProjectSpend is a project's estimated spend today against its budget
+type ProjectSpend struct {
+	Project  string  `json:"project"`
+	SpentUSD float64 `json:"spent_usd"`
+	Budget   float64 `json:"budget_usd,omitempty"` // 0 = no budget
+}
+
+// projectBudgets tracks estimated daily spend per project and enforces
+// routing.project_budgets
+type projectBudgets struct {
+	mu     sync.Mutex
+	limits map[string]float64 // project -> USD per day
+	repo   map[string]float64 // project -> USD per day from its .zenclaw.yaml
+	day    string             // Spend below is for this day (YYYY-MM-DD)
+	spent  map[string]float64
+}
+
+func newProjectBudgets(limits map[string]float64) *projectBudgets {
+	normalized := make(map[string]float64, len(limits))
+	for project, usd := range limits {
+		normalized[strings.ToLower(project)] = usd
+	}
+	return &projectBudgets{limits: normalized, repo: make(map[string]float64), spent: make(map[string]float64)}
+}
+
+// setRepoBudget sets the daily budget a project's .zenclaw.yaml asks for (0
+// removes it)
+func (b *projectBudgets) setRepoBudget(project string, usd float64) {
+	if project == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if usd > 0 {
+		b.repo[strings.ToLower(project)] = usd
+	} else {
+		delete(b.repo, strings.ToLower(project))
+	}
+}
+
+// limit returns a project's daily budget: the lower of the configured and
+// the repo's (caller holds mu)
+func (b *projectBudgets) limit(project string) (float64, bool) {
+	limit, ok := b.limits[project]
+	if repo, set := b.repo[project]; set && (!ok || repo < limit) {
+		return repo, true
+	}
+	return limit, ok
+}
+
+// rollover resets spend at midnight (caller holds mu)
+func (b *projectBudgets) rollover() {
+	if today := time.Now().Format("2006-01-02"); today != b.day {
+		b.day = today
+		b.spent = make(map[string]float64)
+	}
+}
+
+// check fails with BUDGET_EXCEEDED when the project has used up today's budget
+func (b *projectBudgets) check(project string) error {
+	if project == "" {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	project = strings.ToLower(project)
+	limit, ok := b.limit(project)
+	if ok && b.spent[project] >= limit {
+		return types.Errorf(types.ErrBudgetExceeded, "daily budget for project %s exhausted ($%.2f of $%.2f); raise routing.project_budgets (or budget_usd in the repo's .zenclaw.yaml) or wait until tomorrow",
+			project, b.spent[project], limit)
+	}
+	return nil
+}
+
+// add records the estimated cost of a call
+func (b *projectBudgets) add(project, provider, model string, inputTokens, outputTokens int) {
+	if project == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	b.spent[strings.ToLower(project)] += cost.CalculateUSD(provider, model, inputTokens, outputTokens)
+}
+
+// snapshot returns today's spend for projects with spend or a budget
+func (b *projectBudgets) snapshot() []ProjectSpend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	seen := make(map[string]bool)
+	var list []ProjectSpend
+	for project, usd := range b.spent {
+		seen[project] = true
+		limit, _ := b.limit(project)
+		list = append(list, ProjectSpend{Project: project, SpentUSD: usd, Budget: limit})
+	}
+	for _, limits := range []map[string]float64{b.limits, b.repo} {
+		for project := range limits {
+			if !seen[project] {
+				seen[project] = true
+				limit, _ := b.limit(project)
+				list = append(list, ProjectSpend{Project: project, Budget: limit})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Project < list[j].Project })
+	return list
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SESSION TAGS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// findSession looks a session up in the store and in unsaved in-memory sessions
+func (s *AgentService) findSession(sessionID string) (*agent.Session, error) {
+	if session, ok := s.GetSession(sessionID); ok {
+		return session, nil
+	}
+	s.fallbackMu.RLock()
+	session, ok := s.fallbackSessions[sessionID]
+	s.fallbackMu.RUnlock()
+	if !ok {
+		return nil, types.Errorf(types.ErrNotFound, "session not found: %s", sessionID)
+	}
+	return session, nil
+}
+
+// TagSession adds and removes a session's tags and optionally sets its project
+func (s *AgentService) TagSession(sessionID string, add, remove []string, project string) (agent.SessionStats, error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return agent.SessionStats{}, err
+	}
+
+	session.AddTags(add...)
+	session.RemoveTags(remove...)
+	if project != "" {
+		session.SetProject(project)
+	}
+
+	if isNamedSession(sessionID) && s.sessionStore != nil {
+		if err := s.sessionStore.SaveSession(session); err != nil {
+			return agent.SessionStats{}, err
+		}
+	}
+	return session.GetStats(), nil
+}
+
+// GetProjectSpend returns today's estimated spend per project
+func (s *AgentService) GetProjectSpend() []ProjectSpend {
+	if s.budgets == nil {
+		return nil
+	}
+	return s.budgets.snapshot()
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestProjectFromRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:Kube-Zen/zen-claw.git", "kube-zen/zen-claw"},
+		{"https://github.com/kube-zen/zen-claw", "kube-zen/zen-claw"},
+		{"https://gitlab.com/group/sub/repo.git/", "sub/repo"},
+		{"ssh://git@host:2222/owner/repo.git", "owner/repo"},
+		{"repo", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := projectFromRemote(tt.remote); got != tt.want {
+			t.Errorf("projectFromRemote(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestSessionFilter(t *testing.T) {
+	session := agent.NewSession("tagged")
+	session.AddTags("Backend", "#infra", "backend")
+	session.SetProject("kube-zen/zen-claw")
+	stats := session.GetStats()
+
+	if len(stats.Tags) != 2 || stats.Tags[0] != "backend" || stats.Tags[1] != "infra" {
+		t.Fatalf("Expected normalized, deduplicated tags, got %v", stats.Tags)
+	}
+
+	tests := []struct {
+		filter SessionFilter
+		want   bool
+	}{
+		{SessionFilter{}, true},
+		{SessionFilter{Tag: "infra"}, true},
+		{SessionFilter{Tag: "#INFRA"}, true},
+		{SessionFilter{Tag: "frontend"}, false},
+		{SessionFilter{Project: "Kube-Zen/zen-claw"}, true},
+		{SessionFilter{Project: "other/repo", Tag: "infra"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(stats); got != tt.want {
+			t.Errorf("%+v.Matches() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	session.RemoveTags("BACKEND")
+	if tags := session.GetTags(); len(tags) != 1 || tags[0] != "infra" {
+		t.Errorf("Expected [infra] after removal, got %v", tags)
+	}
+}
+
+func TestProjectBudgets(t *testing.T) {
+	budgets := newProjectBudgets(map[string]float64{"Kube-Zen/zen-claw": 0.01})
+
+	if err := budgets.check("kube-zen/zen-claw"); err != nil {
+		t.Fatalf("Expected budget available, got %v", err)
+	}
+
+	// Unknown pricing falls back to $1/$3 per M: 10K in + 1K out = $0.013
+	budgets.add("kube-zen/zen-claw", "test", "model", 10000, 1000)
+	err := budgets.check("kube-zen/zen-claw")
+	if types.CodeOf(err) != types.ErrBudgetExceeded {
+		t.Errorf("Expected BUDGET_EXCEEDED, got %v", err)
+	}
+
+	// Projects without a budget and sessions without a project are never blocked
+	budgets.add("other/repo", "test", "model", 1000000, 1000000)
+	if err := budgets.check("other/repo"); err != nil {
+		t.Errorf("Expected no budget for other/repo, got %v", err)
+	}
+	if err := budgets.check(""); err != nil {
+		t.Errorf("Expected no budget without project, got %v", err)
+	}
+
+	spend := budgets.snapshot()
+	if len(spend) != 2 || spend[0].Project != "kube-zen/zen-claw" || spend[0].Budget != 0.01 {
+		t.Errorf("Unexpected snapshot: %+v", spend)
+	}
+
+	// A repo's .zenclaw.yaml budget applies unless the configured one is lower
+	budgets.setRepoBudget("other/repo", 0.5)
+	if err := budgets.check("other/repo"); types.CodeOf(err) != types.ErrBudgetExceeded {
+		t.Errorf("Expected the repo budget to apply, got %v", err)
+	}
+	budgets.setRepoBudget("kube-zen/zen-claw", 100)
+	if err := budgets.check("kube-zen/zen-claw"); types.CodeOf(err) != types.ErrBudgetExceeded {
+		t.Errorf("Expected the lower configured budget to apply, got %v", err)
+	}
+	budgets.setRepoBudget("other/repo", 0)
+	if err := budgets.check("other/repo"); err != nil {
+		t.Errorf("Expected the repo budget removed, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/ratelimit"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// RATE LIMITS
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Requests are limited per client: per API key for the keys listed in
+// gateway.rate_limit.keys (at their tier's limits), per address otherwise.
+// Operators change the limits, tiers and keys through /preferences
+// (/prefs ratelimit in the CLI) with the gateway.operator_token as
+// X-Operator-Token; without one configured only the config sets them. The
+// change applies at once and lasts until the gateway restarts.
+
+// operatorTokenHeader carries gateway.operator_token
+const operatorTokenHeader = "X-Operator-Token"
+
+// rateLimiterConfig converts gateway.rate_limit to limiter settings (zero
+// limits fall back to the limiter's defaults)
+func rateLimiterConfig(cfg config.RateLimitConfig) ratelimit.Config {
+	rl := ratelimit.DefaultConfig()
+	rl.RequestsPerSecond = cfg.RequestsPerSecond
+	rl.BurstSize = cfg.Burst
+	rl.Tiers = make(map[string]ratelimit.Tier, len(cfg.Tiers))
+	for name, tier := range cfg.Tiers {
+		rl.Tiers[name] = ratelimit.Tier{RequestsPerSecond: tier.RequestsPerSecond, BurstSize: tier.Burst}
+	}
+	rl.Keys = maps.Clone(cfg.Keys)
+	return rl
+}
+
+// requestAPIKey returns the API key a request was sent with (X-API-Key, or
+// an Authorization bearer token)
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// allowRequest applies the rate limit to a request, counting rejections
+func (s *Server) allowRequest(r *http.Request) bool {
+	if s.rateLimiter.AllowKey(s.getClientID(r), requestAPIKey(r)) {
+		return true
+	}
+	atomic.AddInt64(&s.metrics.RateLimitHits, 1)
+	return false
+}
+
+// operatorRequest reports whether a request carries the operator token
+func (s *Server) operatorRequest(r *http.Request) bool {
+	token := s.config.Gateway.OperatorToken
+	got := r.Header.Get(operatorTokenHeader)
+	return token != "" && got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// maskKey shows enough of an API key to recognize it
+func maskKey(key string) string {
+	if len(key) < 12 {
+		return "…"
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
+
+// rateLimitPrefs returns the limits in effect for /preferences; keys are
+// masked
+func (s *Server) rateLimitPrefs() map[string]interface{} {
+	rl := s.rateLimiter.Config()
+	keys := make(map[string]string, len(rl.Keys))
+	for key, tier := range rl.Keys {
+		keys[maskKey(key)] = tier
+	}
+	return map[string]interface{}{
+		"requests_per_second": rl.RequestsPerSecond,
+		"burst":               rl.BurstSize,
+		"tiers":               rl.Tiers,
+		"keys":                keys,
+	}
+}
+
+// rateLimitUpdate is a partial change to the rate limits: a null tier
+// removes it, a key assigned to "" is removed
+type rateLimitUpdate struct {
+	RequestsPerSecond *float64                         `json:"requests_per_second"`
+	Burst             *int                             `json:"burst"`
+	Tiers             map[string]*config.RateLimitTier `json:"tiers"`
+	Keys              map[string]string                `json:"keys"`
+}
+
+// updateRateLimit applies a "rate_limit" preference update. Nothing changes
+// when the result is invalid.
+func (s *Server) updateRateLimit(raw interface{}) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	var update rateLimitUpdate
+	if err := json.Unmarshal(data, &update); err != nil {
+		return err
+	}
+
+	s.rateLimitMu.Lock()
+	defer s.rateLimitMu.Unlock()
+
+	next := s.config.Gateway.RateLimit
+	next.Tiers = maps.Clone(next.Tiers)
+	next.Keys = maps.Clone(next.Keys)
+	if update.RequestsPerSecond != nil {
+		next.RequestsPerSecond = *update.RequestsPerSecond
+	}
+	if update.Burst != nil {
+		next.Burst = *update.Burst
+	}
+	for name, tier := range update.Tiers {
+		if next.Tiers == nil {
+			next.Tiers = make(map[string]config.RateLimitTier)
+		}
+		if tier == nil {
+			delete(next.Tiers, name)
+		} else {
+			next.Tiers[name] = *tier
+		}
+	}
+	for key, tier := range update.Keys {
+		if next.Keys == nil {
+			next.Keys = make(map[string]string)
+		}
+		if tier == "" {
+			delete(next.Keys, key)
+		} else {
+			next.Keys[key] = tier
+		}
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	s.config.Gateway.RateLimit = next
+	s.rateLimiter.Update(rateLimiterConfig(next))
+	return nil
+}
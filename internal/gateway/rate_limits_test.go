@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/ratelimit"
+)
+
+func TestUpdateRateLimit(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	s := &Server{
+		config:      cfg,
+		metrics:     &Metrics{},
+		rateLimiter: ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+	}
+	defer s.rateLimiter.Close()
+
+	err := s.updateRateLimit(map[string]interface{}{
+		"burst": 2,
+		"tiers": map[string]interface{}{"ci": map[string]interface{}{"requests_per_second": 0.001, "burst": 4}},
+		"keys":  map[string]interface{}{"sk-ci-0123456789": "ci"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl := cfg.Gateway.RateLimit; rl.Burst != 2 || rl.Keys["sk-ci-0123456789"] != "ci" {
+		t.Errorf("config = %+v", rl)
+	}
+	prefs := s.rateLimitPrefs()
+	if keys := prefs["keys"].(map[string]string); keys["sk-c…6789"] != "ci" {
+		t.Errorf("keys = %v, want masked", keys)
+	}
+
+	req := httptest.NewRequest("POST", "/chat", nil)
+	req.Header.Set("Authorization", "Bearer sk-ci-0123456789")
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if s.allowRequest(req) {
+			allowed++
+		}
+	}
+	if allowed != 4 || s.metrics.RateLimitHits != 6 {
+		t.Errorf("allowed %d (hits %d), want the ci tier's burst of 4", allowed, s.metrics.RateLimitHits)
+	}
+
+	// Invalid changes are rejected whole
+	if err := s.updateRateLimit(map[string]interface{}{"burst": 50, "keys": map[string]interface{}{"k": "missing"}}); err == nil {
+		t.Error("expected error for a key assigned to an unknown tier")
+	}
+	if cfg.Gateway.RateLimit.Burst != 2 {
+		t.Errorf("burst = %d, want unchanged 2", cfg.Gateway.RateLimit.Burst)
+	}
+}
+
+func TestPreferencesRateLimitNeedsOperatorToken(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	s := &Server{
+		config:      cfg,
+		metrics:     &Metrics{},
+		rateLimiter: ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+	}
+	defer s.rateLimiter.Close()
+	burst := cfg.Gateway.RateLimit.Burst
+
+	post := func(token string) int {
+		req := httptest.NewRequest("POST", "/preferences", strings.NewReader(`{"rate_limit": {"burst": 5}}`))
+		if token != "" {
+			req.Header.Set(operatorTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		s.preferencesHandler(rec, req)
+		return rec.Code
+	}
+
+	// No operator_token configured: runtime changes are off
+	if code := post("anything"); code != http.StatusForbidden {
+		t.Errorf("without operator_token: status %d, want 403", code)
+	}
+
+	cfg.Gateway.OperatorToken = "op-secret-token"
+	if code := post(""); code != http.StatusForbidden {
+		t.Errorf("missing token: status %d, want 403", code)
+	}
+	if code := post("wrong"); code != http.StatusForbidden {
+		t.Errorf("wrong token: status %d, want 403", code)
+	}
+	if cfg.Gateway.RateLimit.Burst != burst {
+		t.Fatalf("burst = %d, want unchanged %d", cfg.Gateway.RateLimit.Burst, burst)
+	}
+	if code := post("op-secret-token"); code != http.StatusOK {
+		t.Errorf("operator token: status %d, want 200", code)
+	}
+	if cfg.Gateway.RateLimit.Burst != 5 {
+		t.Errorf("burst = %d, want 5", cfg.Gateway.RateLimit.Burst)
+	}
+
+	// Reads stay open
+	rec := httptest.NewRecorder()
+	s.preferencesHandler(rec, httptest.NewRequest("GET", "/preferences/rate_limit", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET: status %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// requestLimits enforces gateway.limits on chat requests before they reach
+// the agent
+type requestLimits struct {
+	cfg   config.RequestLimitsConfig
+	roots *agent.WorkingDirRoots
+
+	mu      sync.Mutex
+	active  map[string]map[string]int // Client -> session key -> in-flight requests
+	pending int                       // Keys for requests starting a new session
+}
+
+func newRequestLimits(cfg config.RequestLimitsConfig) *requestLimits {
+	return &requestLimits{cfg: cfg, roots: agent.NewWorkingDirRoots(cfg.WorkingDirs), active: make(map[string]map[string]int)}
+}
+
+// check rejects requests exceeding the max_steps cap or working outside the
+// allowed roots. The agent service checks the directory a session ends up in
+// (resumed, or the gateway's when the request has none).
+func (l *requestLimits) check(req ChatRequest) error {
+	if l.cfg.MaxSteps > 0 && req.MaxSteps > l.cfg.MaxSteps {
+		return types.Errorf(types.ErrInvalidArgument, "max_steps %d exceeds the gateway limit of %d", req.MaxSteps, l.cfg.MaxSteps)
+	}
+	if req.WorkingDir != "" {
+		return l.roots.Check(req.WorkingDir)
+	}
+	return nil
+}
+
+// acquire counts a request against the client's concurrent sessions; call
+// release when it finishes. Requests to a session already running for the
+// client don't count twice; each request without a session ID is a new one.
+func (l *requestLimits) acquire(client, sessionID string) (release func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := sessionID
+	if key == "" {
+		l.pending++
+		key = fmt.Sprintf("\x00new-%d", l.pending)
+	}
+	sessions := l.active[client]
+	if limit := l.cfg.MaxSessionsPerClient; limit > 0 && sessions[key] == 0 && len(sessions) >= limit {
+		return nil, types.Errorf(types.ErrRateLimited, "too many concurrent sessions: client %s already runs %d (limit %d)", client, len(sessions), limit)
+	}
+	if sessions == nil {
+		sessions = make(map[string]int)
+		l.active[client] = sessions
+	}
+	sessions[key]++
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if sessions[key]--; sessions[key] <= 0 {
+			delete(sessions, key)
+		}
+		if len(sessions) == 0 {
+			delete(l.active, client)
+		}
+	}, nil
+}
+
+// admit runs check and acquire for one chat request
+func (l *requestLimits) admit(client string, req ChatRequest) (release func(), err error) {
+	if err := l.check(req); err != nil {
+		return nil, err
+	}
+	return l.acquire(client, req.SessionID)
+}
+
+// clientAddr identifies the client for per-client limits: the remote IP
+// without the port or, for requests through one of gateway.trusted_proxies,
+// the nearest X-Forwarded-For address that isn't a trusted proxy
+func (s *Server) clientAddr(r *http.Request) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded == "" || !s.trustedProxy(addr) {
+		return addr
+	}
+	// Each proxy appends the address it got the request from: walk back
+	// until one wasn't added by a trusted proxy
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr = strings.TrimSpace(hops[i])
+		if !s.trustedProxy(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// trustedProxy reports whether addr is in gateway.trusted_proxies
+func (s *Server) trustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses gateway.trusted_proxies (IPs or CIDR ranges),
+// skipping invalid entries with a warning
+func parseTrustedProxies(proxies []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, p := range proxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			ip, ipErr := netip.ParseAddr(p)
+			if ipErr != nil {
+				log.Printf("Warning: ignoring trusted proxy %q: not an IP or CIDR range", p)
+				continue
+			}
+			ip = ip.Unmap()
+			prefix = netip.PrefixFrom(ip, ip.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// writeDecodeError reports a request body that could not be decoded: 413
+// when it exceeded the size limit, 400 otherwise
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, types.ErrInvalidArgument, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Invalid JSON")
+}
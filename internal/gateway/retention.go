@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/config"
+)
+
+// pinnedTag protects a session from retention cleanup (/tag pinned)
+const pinnedTag = "pinned"
+
+// RetentionPolicy limits how much session history the store keeps
+type RetentionPolicy struct {
+	MaxAge        time.Duration // Delete sessions unused for longer (0 = no limit)
+	MaxTotalBytes int64         // Delete least recently used sessions while transcripts exceed this (0 = no limit)
+	Keep          []string      // Session IDs never deleted
+}
+
+// RetentionPolicyFromConfig converts sessions.retention to a policy
+func RetentionPolicyFromConfig(cfg config.RetentionConfig) RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:        time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		MaxTotalBytes: int64(cfg.MaxTotalMB) << 20,
+		Keep:          cfg.Keep,
+	}
+}
+
+// RetentionCandidate is a session deleted (or, in a dry run, due for deletion)
+type RetentionCandidate struct {
+	SessionID string    `json:"session_id"`
+	Title     string    `json:"title,omitempty"`
+	Reason    string    `json:"reason"`
+	LastUsed  time.Time `json:"last_used"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// RetentionReport summarizes one enforcement pass
+type RetentionReport struct {
+	DryRun     bool                 `json:"dry_run"`
+	RanAt      time.Time            `json:"ran_at"`
+	Sessions   int                  `json:"sessions"`    // Sessions before the pass
+	Protected  int                  `json:"protected"`   // Pinned, named, kept or active sessions skipped
+	TotalBytes int64                `json:"total_bytes"` // Transcript size before the pass
+	FreedBytes int64                `json:"freed_bytes"`
+	Deleted    []RetentionCandidate `json:"deleted"`
+}
+
+// sessionSizes returns the stored size of each session's transcript and tool
+// outputs in bytes
+func (s *SessionStore) sessionSizes() (map[string]int64, error) {
+	rows, err := s.db.Query(`
+		SELECT session_id, SUM(size) FROM (
+			SELECT session_id, LENGTH(content) + COALESCE(LENGTH(tool_calls), 0) AS size FROM messages
+			UNION ALL
+			SELECT session_id, LENGTH(content) AS size FROM tool_results
+		)
+		GROUP BY session_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var id string
+		var size int64
+		if err := rows.Scan(&id, &size); err != nil {
+			continue
+		}
+		sizes[id] = size
+	}
+	return sizes, rows.Err()
+}
+
+// ApplyRetention deletes sessions past the policy's limits, least recently
+// used first. Active, pinned, named and kept sessions are never deleted. With dryRun
+// the report lists what would be deleted and nothing changes.
+func (s *SessionStore) ApplyRetention(policy RetentionPolicy, dryRun bool) (*RetentionReport, error) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sizes, err := s.sessionSizes()
+	if err != nil {
+		return nil, fmt.Errorf("measure sessions: %w", err)
+	}
+
+	keep := make(map[string]bool, len(policy.Keep))
+	for _, id := range policy.Keep {
+		keep[id] = true
+	}
+
+	report := &RetentionReport{DryRun: dryRun, RanAt: time.Now(), Sessions: len(s.sessions)}
+	var eligible []*SessionInfo
+	for id, info := range s.sessions {
+		report.TotalBytes += sizes[id]
+		if keep[id] || info.State == SessionStateActive || info.Session.HasTag(pinnedTag) || namedForRetention(info.Session) {
+			report.Protected++
+			continue
+		}
+		eligible = append(eligible, info)
+	}
+	sort.Slice(eligible, func(i, j int) bool { return eligible[i].LastUsed.Before(eligible[j].LastUsed) })
+
+	cutoff := report.RanAt.Add(-policy.MaxAge)
+	remaining := report.TotalBytes
+	for _, info := range eligible {
+		var reason string
+		switch {
+		case policy.MaxAge > 0 && info.LastUsed.Before(cutoff):
+			reason = fmt.Sprintf("unused for %s", formatAge(report.RanAt.Sub(info.LastUsed)))
+		case policy.MaxTotalBytes > 0 && remaining > policy.MaxTotalBytes:
+			reason = fmt.Sprintf("store over %d MB", policy.MaxTotalBytes>>20)
+		default:
+			continue
+		}
+
+		id := info.Session.ID
+		candidate := RetentionCandidate{
+			SessionID: id,
+			Title:     info.Session.GetTitle(),
+			Reason:    reason,
+			LastUsed:  info.LastUsed,
+			Bytes:     sizes[id],
+		}
+		if !dryRun {
+			if err := s.deleteSessionLocked(id); err != nil {
+				log.Printf("[SessionStore] Retention failed to delete %s: %v", id, err)
+				continue
+			}
+		}
+		remaining -= candidate.Bytes
+		report.FreedBytes += candidate.Bytes
+		report.Deleted = append(report.Deleted, candidate)
+	}
+
+	if !dryRun && len(report.Deleted) > 0 {
+		_, _ = s.db.Exec("VACUUM")
+	}
+	return report, nil
+}
+
+// namedForRetention reports whether a session was named, by its ID (not
+// auto-generated) or a title: someone meant to come back to it
+func namedForRetention(session *agent.Session) bool {
+	return isNamedSession(session.ID) || session.GetTitle() != ""
+}
+
+// formatAge renders a duration in days (or hours when under a day)
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SESSION JANITOR
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// sessionJanitor enforces sessions.retention in the background
+type sessionJanitor struct {
+	store    *SessionStore
+	policy   RetentionPolicy
+	dryRun   bool
+	interval time.Duration
+	stop     chan struct{}
+
+	mu   sync.Mutex
+	last *RetentionReport
+}
+
+// newSessionJanitor starts enforcing the retention policy. Returns nil when
+// no limit is configured.
+func newSessionJanitor(store *SessionStore, cfg config.RetentionConfig) *sessionJanitor {
+	if store == nil || !cfg.Enabled() {
+		return nil
+	}
+	interval := time.Duration(cfg.IntervalMins) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	j := &sessionJanitor{
+		store:    store,
+		policy:   RetentionPolicyFromConfig(cfg),
+		dryRun:   cfg.DryRun,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	go j.run()
+	return j
+}
+
+func (j *sessionJanitor) run() {
+	j.sweep()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.sweep()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// sweep runs one enforcement pass and logs the outcome
+func (j *sessionJanitor) sweep() {
+	report, err := j.store.ApplyRetention(j.policy, j.dryRun)
+	if err != nil {
+		log.Printf("[Retention] %v", err)
+		return
+	}
+	j.mu.Lock()
+	j.last = report
+	j.mu.Unlock()
+
+	verb := "Deleted"
+	if report.DryRun {
+		verb = "Dry run: would delete"
+	}
+	for _, c := range report.Deleted {
+		log.Printf("[Retention] %s session %s (%s, %d bytes)", verb, c.SessionID, c.Reason, c.Bytes)
+	}
+	if len(report.Deleted) > 0 {
+		log.Printf("[Retention] %s %d of %d sessions, %d bytes", verb, len(report.Deleted), report.Sessions, report.FreedBytes)
+	}
+}
+
+// lastReport returns the most recent pass (nil before the first one)
+func (j *sessionJanitor) lastReport() *RetentionReport {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// Close stops the janitor
+func (j *sessionJanitor) Close() {
+	close(j.stop)
+}
+
+// GetRetentionReport returns the janitor's latest pass (nil when retention is off)
+func (s *AgentService) GetRetentionReport() *RetentionReport {
+	if s.janitor == nil {
+		return nil
+	}
+	return s.janitor.lastReport()
+}
@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// Consensus, fabric and factory runs call the providers straight from the
+// CLI, so the gateway never sees them. When they finish the CLI posts a
+// types.RunRecord to /runs; the gateway keeps it in the session database and
+// includes it in /stats next to agent usage.
+
+// maxRunHistory is how many runs are kept in memory (all are persisted)
+const maxRunHistory = 1000
+
+// runHistory keeps the reported runs
+type runHistory struct {
+	mu    sync.RWMutex
+	runs  []types.RunRecord // Oldest first
+	store *SessionStore     // nil = not persisted
+}
+
+func newRunHistory(store *SessionStore) *runHistory {
+	h := &runHistory{store: store}
+	if store != nil {
+		if saved, err := store.Runs(maxRunHistory); err == nil {
+			h.runs = saved
+		}
+	}
+	return h
+}
+
+// record validates and stores a run, filling in its ID
+func (h *runHistory) record(run types.RunRecord) (types.RunRecord, error) {
+	switch run.Kind {
+	case types.RunConsensus, types.RunFabric, types.RunFactory:
+	default:
+		return run, types.Errorf(types.ErrInvalidArgument, "unknown run kind %q (want consensus, fabric or factory)", run.Kind)
+	}
+	if run.Status == "" {
+		run.Status = types.RunCompleted
+	}
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now().Add(-time.Duration(run.DurationMs) * time.Millisecond)
+	}
+	if run.ID == "" {
+		run.ID = fmt.Sprintf("run_%d", time.Now().UnixNano())
+	}
+
+	h.mu.Lock()
+	h.runs = append(h.runs, run)
+	if len(h.runs) > maxRunHistory {
+		h.runs = h.runs[len(h.runs)-maxRunHistory:]
+	}
+	h.mu.Unlock()
+
+	if h.store != nil {
+		if err := h.store.SaveRun(run); err != nil {
+			log.Printf("[Runs] Failed to persist run %s: %v", run.ID, err)
+		}
+	}
+	return run, nil
+}
+
+// list returns the most recent runs, newest first, optionally of one kind
+// (limit <= 0: all)
+func (h *runHistory) list(kind string, limit int) []types.RunRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	out := []types.RunRecord{}
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		if kind != "" && !strings.EqualFold(h.runs[i].Kind, kind) {
+			continue
+		}
+		out = append(out, h.runs[i])
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// get returns a run by ID
+func (h *runHistory) get(id string) (types.RunRecord, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, run := range h.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return types.RunRecord{}, false
+}
+
+// RunKindStats aggregates the runs of one kind
+type RunKindStats struct {
+	Runs          int     `json:"runs"`
+	Failed        int     `json:"failed"`
+	CostUSD       float64 `json:"cost_usd"`
+	AvgDurationMs int64   `json:"avg_duration_ms"`
+}
+
+// RunProviderStats aggregates one provider's calls across runs
+type RunProviderStats struct {
+	Provider string  `json:"provider"`
+	Calls    int     `json:"calls"`
+	Errors   int     `json:"errors"`
+	CostUSD  float64 `json:"cost_usd"`
+	AvgScore float64 `json:"avg_score,omitempty"` // Over consensus calls the arbiter scored
+}
+
+// RunSummary is the runs section of /stats
+type RunSummary struct {
+	Total     int                     `json:"total"`
+	CostUSD   float64                 `json:"cost_usd"`
+	ByKind    map[string]RunKindStats `json:"by_kind"`
+	Providers []RunProviderStats      `json:"providers"`
+}
+
+// summary aggregates the runs in memory
+func (h *runHistory) summary() RunSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s := RunSummary{ByKind: make(map[string]RunKindStats), Providers: []RunProviderStats{}}
+	durations := make(map[string]int64)
+	providers := make(map[string]*RunProviderStats)
+	scores := make(map[string][2]int) // provider -> score sum, scored calls
+	for _, run := range h.runs {
+		s.Total++
+		s.CostUSD += run.CostUSD
+		k := s.ByKind[run.Kind]
+		k.Runs++
+		if run.Status == types.RunFailed {
+			k.Failed++
+		}
+		k.CostUSD += run.CostUSD
+		durations[run.Kind] += run.DurationMs
+		s.ByKind[run.Kind] = k
+
+		for _, call := range run.Calls {
+			p := providers[call.Provider]
+			if p == nil {
+				p = &RunProviderStats{Provider: call.Provider}
+				providers[call.Provider] = p
+			}
+			p.Calls++
+			if call.Error != "" {
+				p.Errors++
+			}
+			p.CostUSD += call.CostUSD
+			if call.Score > 0 {
+				sc := scores[call.Provider]
+				scores[call.Provider] = [2]int{sc[0] + call.Score, sc[1] + 1}
+			}
+		}
+	}
+	for kind, k := range s.ByKind {
+		k.AvgDurationMs = durations[kind] / int64(k.Runs)
+		s.ByKind[kind] = k
+	}
+	for name, p := range providers {
+		if sc := scores[name]; sc[1] > 0 {
+			p.AvgScore = float64(sc[0]) / float64(sc[1])
+		}
+		s.Providers = append(s.Providers, *p)
+	}
+	sort.Slice(s.Providers, func(i, j int) bool {
+		if s.Providers[i].Calls != s.Providers[j].Calls {
+			return s.Providers[i].Calls > s.Providers[j].Calls
+		}
+		return s.Providers[i].Provider < s.Providers[j].Provider
+	})
+	return s
+}
+
+// RecordRun stores a run reported by the CLI
+func (s *AgentService) RecordRun(run types.RunRecord) (types.RunRecord, error) {
+	return s.runHistory.record(run)
+}
+
+// ListRuns returns recent runs, newest first
+func (s *AgentService) ListRuns(kind string, limit int) []types.RunRecord {
+	return s.runHistory.list(kind, limit)
+}
+
+// GetRun returns a run by ID
+func (s *AgentService) GetRun(id string) (types.RunRecord, bool) {
+	return s.runHistory.get(id)
+}
+
+// GetRunSummary returns the runs section of /stats
+func (s *AgentService) GetRunSummary() RunSummary {
+	return s.runHistory.summary()
+}
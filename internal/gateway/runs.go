@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// runRegistry tracks the in-flight chat requests of each session, so a
+// client can cancel them (POST /sessions/{id}/cancel or a WebSocket cancel)
+type runRegistry struct {
+	mu   sync.Mutex
+	next int
+	runs map[string]map[int]context.CancelCauseFunc // Session ID -> run -> cancel
+}
+
+func newRunRegistry() *runRegistry {
+	return &runRegistry{runs: make(map[string]map[int]context.CancelCauseFunc)}
+}
+
+// start registers a run of the session; call done when it finishes
+func (r *runRegistry) start(sessionID string, cancel context.CancelCauseFunc) (done func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	id := r.next
+	if r.runs[sessionID] == nil {
+		r.runs[sessionID] = make(map[int]context.CancelCauseFunc)
+	}
+	r.runs[sessionID][id] = cancel
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.runs[sessionID], id)
+		if len(r.runs[sessionID]) == 0 {
+			delete(r.runs, sessionID)
+		}
+	}
+}
+
+// cancel cancels the session's runs with the cause and returns how many
+// were running
+func (r *runRegistry) cancel(sessionID string, cause error) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.runs[sessionID] {
+		cancel(cause)
+	}
+	return len(r.runs[sessionID])
+}
+
+// sessionTurns lets one turn at a time run in a session. Two concurrent
+// turns would interleave their messages and split tool calls from their
+// results, leaving a history providers reject.
+type sessionTurns struct {
+	mu   sync.Mutex
+	held map[string]chan struct{} // Session ID -> closed when the turn ends
+}
+
+func newSessionTurns() *sessionTurns {
+	return &sessionTurns{held: make(map[string]chan struct{})}
+}
+
+// busy reports whether a turn is running in the session
+func (t *sessionTurns) busy(sessionID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.held[sessionID]
+	return ok
+}
+
+// acquire starts a turn in the session; call release when it ends. While
+// another turn runs it fails with CONFLICT, or with wait, waits for it (until
+// ctx ends). queued is called once when the turn has to wait.
+func (t *sessionTurns) acquire(ctx context.Context, sessionID string, wait bool, queued func()) (release func(), err error) {
+	for {
+		t.mu.Lock()
+		running, ok := t.held[sessionID]
+		if !ok {
+			done := make(chan struct{})
+			t.held[sessionID] = done
+			t.mu.Unlock()
+			return func() {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				delete(t.held, sessionID)
+				close(done)
+			}, nil
+		}
+		t.mu.Unlock()
+
+		if !wait {
+			return nil, errSessionBusy(sessionID)
+		}
+		if queued != nil {
+			queued()
+			queued = nil
+		}
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return nil, types.Errorf(types.ErrCanceled, "gave up waiting for the running turn of session %s: %v", sessionID, context.Cause(ctx))
+		}
+	}
+}
+
+// errSessionBusy refuses a turn in a session that is running one
+func errSessionBusy(sessionID string) error {
+	return types.Errorf(types.ErrConflict,
+		"session %s is already running a turn. Wait for it to finish, cancel it (POST /sessions/%s/cancel), or send with \"queue\": true to run after it",
+		sessionID, sessionID)
+}
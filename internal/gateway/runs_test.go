@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestSessionTurns(t *testing.T) {
+	turns := newSessionTurns()
+	ctx := context.Background()
+
+	release, err := turns.acquire(ctx, "s1", false, nil)
+	if err != nil || !turns.busy("s1") {
+		t.Fatalf("first turn: %v", err)
+	}
+	if _, err := turns.acquire(ctx, "s1", false, nil); types.CodeOf(err) != types.ErrConflict {
+		t.Errorf("concurrent turn: err = %v, want CONFLICT", err)
+	}
+	other, err := turns.acquire(ctx, "s2", false, nil)
+	if err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+	other()
+
+	// A queued turn starts when the running one ends
+	queued := make(chan struct{})
+	started := make(chan func())
+	go func() {
+		next, err := turns.acquire(ctx, "s1", true, func() { close(queued) })
+		if err != nil {
+			t.Error(err)
+		}
+		started <- next
+	}()
+	<-queued
+	select {
+	case <-started:
+		t.Fatal("queued turn started while another was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case next := <-started:
+		if !turns.busy("s1") {
+			t.Error("queued turn is not holding the session")
+		}
+		next()
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued turn did not start")
+	}
+	if turns.busy("s1") {
+		t.Error("session still busy after its turns ended")
+	}
+
+	// Waiting gives up with the request
+	release, _ = turns.acquire(ctx, "s1", false, nil)
+	defer release()
+	waitCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := turns.acquire(waitCtx, "s1", true, nil); types.CodeOf(err) != types.ErrCanceled {
+		t.Errorf("canceled wait: err = %v, want CANCELED", err)
+	}
+}
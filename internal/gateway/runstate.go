@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// The running gateway records itself in ~/.zen/zen-claw/run/gateway.json.
+// The file doubles as a lock (created exclusively), and is only trusted
+// while its PID is alive and still a zen-claw process: after a crash, or
+// once the PID is reused by something else, it is stale and removed by
+// whoever finds it.
+
+const runStateFile = "gateway.json"
+
+// DiscoverURL returns the URL of the gateway running on this machine, or ""
+// when none is recorded (clients then fall back to the configured address)
+func DiscoverURL() string {
+	state, err := ReadRunState(DefaultRunDir())
+	if err != nil || state == nil {
+		return ""
+	}
+	return state.URL
+}
+
+// DefaultRunDir returns the directory for runtime state
+func DefaultRunDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "run")
+}
+
+// RunState describes a running gateway
+type RunState struct {
+	PID        int       `json:"pid"`
+	Addr       string    `json:"addr"`
+	URL        string    `json:"url"` // Where clients connect (http://host:port or unix:///path)
+	StartedAt  time.Time `json:"started_at"`
+	Executable string    `json:"executable,omitempty"`
+}
+
+// ReadRunState returns the gateway recorded in dir, or nil when none is
+// running. A stale file (dead PID, or not a zen-claw process) is removed.
+func ReadRunState(dir string) (*RunState, error) {
+	path := filepath.Join(dir, runStateFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state RunState
+	if err := json.Unmarshal(data, &state); err != nil || !state.alive() {
+		os.Remove(path)
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// acquireRunState records this process as the running gateway. It fails
+// with ALREADY_EXISTS while another live gateway holds the file.
+func acquireRunState(dir, addr, url string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	exe, _ := os.Executable()
+	data, _ := json.MarshalIndent(RunState{PID: os.Getpid(), Addr: addr, URL: url, StartedAt: time.Now(), Executable: exe}, "", "  ")
+
+	path := filepath.Join(dir, runStateFile)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			// Taken: by a live gateway, or stale (ReadRunState removes it)
+			running, err := ReadRunState(dir)
+			if err != nil {
+				return err
+			}
+			if running != nil {
+				return types.Errorf(types.ErrAlreadyExists, "gateway already running (PID %d, %s)", running.PID, running.Addr)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	return fmt.Errorf("could not acquire %s", path)
+}
+
+// releaseRunState removes the run state if this process holds it
+func releaseRunState(dir string) {
+	path := filepath.Join(dir, runStateFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var state RunState
+	if json.Unmarshal(data, &state) == nil && state.PID == os.Getpid() {
+		os.Remove(path)
+	}
+}
+
+// alive reports whether the PID runs and is a zen-claw process
+func (s *RunState) alive() bool {
+	if s.PID <= 0 {
+		return false
+	}
+	if s.PID == os.Getpid() {
+		return true
+	}
+	process, err := os.FindProcess(s.PID)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks existence; EPERM means it exists but isn't ours
+	if err := process.Signal(syscall.Signal(0)); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	return isZenClawProcess(s.PID, s.Executable)
+}
+
+// isZenClawProcess checks that pid runs the zen-claw binary, so a PID
+// reused by another program is not mistaken for the gateway
+func isZenClawProcess(pid int, executable string) bool {
+	matches := func(exe string) bool {
+		return exe != "" && (exe == executable || strings.Contains(filepath.Base(exe), "zen-claw") ||
+			executable != "" && filepath.Base(exe) == filepath.Base(executable))
+	}
+	// Linux: the binary behind the PID (" (deleted)" after an upgrade)
+	if exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+		return matches(strings.TrimSuffix(exe, " (deleted)"))
+	}
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return false
+	}
+	return matches(strings.TrimSpace(string(out)))
+}
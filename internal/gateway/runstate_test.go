@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestRunState(t *testing.T) {
+	dir := t.TempDir()
+	if err := acquireRunState(dir, ":9999", "http://localhost:9999"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := ReadRunState(dir)
+	if err != nil || state == nil || state.PID != os.Getpid() || state.Addr != ":9999" || state.URL != "http://localhost:9999" {
+		t.Fatalf("state = %+v, %v", state, err)
+	}
+	if err := acquireRunState(dir, ":9999", "http://localhost:9999"); types.CodeOf(err) != types.ErrAlreadyExists {
+		t.Errorf("second acquire = %v, want ALREADY_EXISTS", err)
+	}
+	releaseRunState(dir)
+	if state, _ := ReadRunState(dir); state != nil {
+		t.Errorf("state after release = %+v", state)
+	}
+}
+
+func TestRunStateStale(t *testing.T) {
+	// A process that is alive but not zen-claw, and one that has exited
+	sleeper := exec.Command("sleep", "10")
+	if err := sleeper.Start(); err != nil {
+		t.Skip("no sleep binary")
+	}
+	defer sleeper.Process.Kill()
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip("no true binary")
+	}
+
+	for name, pid := range map[string]int{"other program": sleeper.Process.Pid, "dead": exited.Process.Pid} {
+		dir := t.TempDir()
+		data, _ := json.Marshal(RunState{PID: pid, Addr: ":8080", Executable: "/usr/local/bin/zen-claw"})
+		path := filepath.Join(dir, runStateFile)
+		os.WriteFile(path, data, 0600)
+
+		if state, err := ReadRunState(dir); state != nil || err != nil {
+			t.Errorf("%s: stale state read as running: %+v, %v", name, state, err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s: stale file not removed", name)
+		}
+		os.WriteFile(path, data, 0600)
+		if err := acquireRunState(dir, ":8080", "http://localhost:8080"); err != nil {
+			t.Errorf("%s: acquire over stale state: %v", name, err)
+		}
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,6 +21,8 @@ import (
 
 	"github.com/neves/zen-claw/internal/config"
 	"github.com/neves/zen-claw/internal/ratelimit"
+	"github.com/neves/zen-claw/internal/types"
+	"github.com/neves/zen-claw/internal/version"
 )
 
 // Server represents the Zen Claw gateway server
@@ -24,12 +31,20 @@ type Server struct {
 	server          *http.Server
 	mu              sync.RWMutex
 	running         bool
-	pidFile         string
+	runDir          string // Runtime state (see runstate.go)
 	agentService    *AgentService
 	rateLimiter     *ratelimit.Limiter
+	rateLimitMu     sync.Mutex // Serializes rate limit changes from /preferences
+	limits          *requestLimits
+	trustedProxies  []netip.Prefix    // Whose X-Forwarded-For is honored
+	streams         *streamHub        // Events of streamed requests, for resuming
+	idempotency     *idempotencyStore // Results of requests sent with an Idempotency-Key
 	metrics         *Metrics
 	activeRequests  int64
 	shutdownTimeout time.Duration
+	stopCounters    chan struct{} // Stops persisting the counters
+	hooks           map[string]*webhook
+	github          *githubIntegration
 }
 
 // Metrics tracks server metrics
@@ -48,9 +63,15 @@ type Metrics struct {
 func NewServer(cfg *config.Config) *Server {
 	srv := &Server{
 		config:          cfg,
-		pidFile:         "/tmp/zen-claw-gateway.pid",
+		runDir:          DefaultRunDir(),
 		agentService:    NewAgentService(cfg),
-		rateLimiter:     ratelimit.NewLimiter(ratelimit.DefaultConfig()),
+		rateLimiter:     ratelimit.NewLimiter(rateLimiterConfig(cfg.Gateway.RateLimit)),
+		limits:          newRequestLimits(cfg.Gateway.Limits),
+		trustedProxies:  parseTrustedProxies(cfg.Gateway.TrustedProxies),
+		streams:         newStreamHub(),
+		idempotency:     newIdempotencyStore(cfg.Gateway.IdempotencyTTLMins),
+		hooks:           newWebhooks(cfg.Hooks),
+		github:          newGitHubIntegration(cfg.GitHub),
 		metrics:         &Metrics{StartTime: time.Now()},
 		shutdownTimeout: 30 * time.Second, // Allow in-flight requests to complete
 	}
@@ -58,7 +79,7 @@ func NewServer(cfg *config.Config) *Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", srv.healthHandler)
 	mux.HandleFunc("/chat", srv.chatHandler)
-	mux.HandleFunc("/chat/stream", srv.streamChatHandler) // SSE streaming endpoint
+	mux.HandleFunc("/chat/stream", srv.streamChatHandler) // SSE streaming endpoint (GET resumes)
 	mux.HandleFunc("/ws", srv.wsHandler)                  // WebSocket endpoint
 	mux.HandleFunc("/sessions", srv.sessionsHandler)
 	mux.HandleFunc("/sessions/", srv.sessionHandler)
@@ -66,16 +87,24 @@ func NewServer(cfg *config.Config) *Server {
 	mux.HandleFunc("/preferences/", srv.preferencesHandler)
 	mux.HandleFunc("/stats", srv.statsHandler)     // Usage and cache stats
 	mux.HandleFunc("/metrics", srv.metricsHandler) // Prometheus-style metrics
+	mux.HandleFunc("/runs", srv.runsHandler)       // Consensus, fabric and factory runs
+	mux.HandleFunc("/runs/", srv.runsHandler)
+	mux.HandleFunc("/hooks", srv.hooksHandler) // Inbound webhooks that start agent tasks
+	mux.HandleFunc("/hooks/", srv.hooksHandler)
+	mux.HandleFunc("/github/triage", srv.githubTriageHandler) // Triage a GitHub issue on demand
 	mux.HandleFunc("/", srv.defaultHandler)
 
-	// Apply middleware: recovery -> logging -> handler
-	handler := Chain(mux, RecoveryMiddleware, LoggingMiddleware)
+	// Apply middleware: recovery -> logging -> body limit -> handler
+	handler := Chain(mux, RecoveryMiddleware, LoggingMiddleware, BodyLimitMiddleware(cfg.Gateway.Limits.GetMaxBodyBytes()))
 
 	srv.server = &http.Server{
 		Addr:    cfg.Gateway.GetAddr(),
 		Handler: handler,
 	}
 
+	// Counters continue from the last run
+	srv.restoreCounters()
+
 	return srv
 }
 
@@ -89,16 +118,39 @@ func (s *Server) Start() error {
 	s.running = true
 	s.mu.Unlock()
 
-	// Write PID file
-	if err := s.writePID(); err != nil {
-		log.Printf("Warning: Failed to write PID file: %v", err)
+	notRunning := func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
 	}
 
+	// Record this gateway (with the URL clients discover it by); refuses
+	// to start while another one runs
+	if err := acquireRunState(s.runDir, s.server.Addr, s.config.Gateway.GetURL()); err != nil {
+		if types.CodeOf(err) == types.ErrAlreadyExists {
+			notRunning()
+			return err
+		}
+		log.Printf("Warning: Failed to write run state: %v", err)
+	}
+
+	listener, err := listen(&s.config.Gateway)
+	if err != nil {
+		notRunning()
+		releaseRunState(s.runDir)
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+
+	s.mu.Lock()
+	s.stopCounters = make(chan struct{})
+	go s.persistCounters(s.stopCounters)
+	s.mu.Unlock()
+
 	// Start server in goroutine
 	serverErr := make(chan error, 1)
 	go func() {
-		log.Printf("Starting Zen Claw gateway on %s", s.server.Addr)
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Starting Zen Claw gateway on %s", s.config.Gateway.GetURL())
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
 	}()
@@ -113,6 +165,8 @@ func (s *Server) Start() error {
 		s.Stop()
 		return nil
 	case err := <-serverErr:
+		s.stopPersistingCounters()
+		releaseRunState(s.runDir)
 		return fmt.Errorf("server failed: %w", err)
 	}
 }
@@ -144,6 +198,8 @@ func (s *Server) Stop() error {
 		log.Printf("HTTP shutdown error: %v", err)
 	}
 
+	s.stopPersistingCounters()
+
 	// Close agent service (cleanup MCP client, etc.)
 	log.Println("Closing agent service...")
 	s.agentService.Close()
@@ -151,8 +207,7 @@ func (s *Server) Stop() error {
 	// Close rate limiter
 	s.rateLimiter.Close()
 
-	// Remove PID file
-	os.Remove(s.pidFile)
+	releaseRunState(s.runDir)
 
 	log.Println("Gateway stopped gracefully")
 	return nil
@@ -195,16 +250,10 @@ func (s *Server) Status() string {
 
 // waitForShutdown is now integrated into Start() method
 
-// writePID writes the PID to file
-func (s *Server) writePID() error {
-	pid := os.Getpid()
-	return os.WriteFile(s.pidFile, []byte(fmt.Sprintf("%d\n", pid)), 0644)
-}
-
 // healthHandler handles health checks
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
@@ -213,7 +262,7 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 		"status":          "healthy",
 		"timestamp":       time.Now().Format(time.RFC3339),
 		"gateway":         "zen-claw",
-		"version":         "0.1.0",
+		"version":         version.Version,
 		"active_requests": s.ActiveRequests(),
 		"rate_limit":      s.rateLimiter.Stats(),
 	})
@@ -222,7 +271,7 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 // statsHandler returns usage and cache statistics
 func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
@@ -237,7 +286,14 @@ func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
 			"size":     size,
 			"hit_rate": hitRate,
 		},
-		"circuits": s.agentService.GetCircuitStats(),
+		"circuits":  s.agentService.GetCircuitStats(),
+		"feedback":  s.agentService.GetFeedbackStats(),
+		"projects":  s.agentService.GetProjectSpend(),
+		"retention": s.agentService.GetRetentionReport(),
+		"optimizer": s.agentService.GetOptimizerStats(),
+		"tools":     s.agentService.GetToolStats(),
+		"models":    s.agentService.GetModelCapabilities(),
+		"runs":      s.agentService.GetRunSummary(),
 		"mcp": map[string]interface{}{
 			"servers": s.agentService.GetMCPServers(),
 			"tools":   s.agentService.GetMCPToolCount(),
@@ -246,10 +302,62 @@ func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// runsHandler lists runs (GET /runs?kind=&limit=), returns one
+// (GET /runs/{id}) or records one reported by the CLI (POST /runs)
+func (s *Server) runsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/runs"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id != "":
+		run, ok := s.agentService.GetRun(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, types.ErrNotFound, "Run not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
+
+	case r.Method == http.MethodGet:
+		limit := 50
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Invalid limit: "+v)
+				return
+			}
+			limit = n
+		}
+		runs := s.agentService.ListRuns(r.URL.Query().Get("kind"), limit)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"runs":  runs,
+			"count": len(runs),
+		})
+
+	case r.Method == http.MethodPost && id == "":
+		var run types.RunRecord
+		if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+			writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Invalid JSON: "+err.Error())
+			return
+		}
+		run, err := s.agentService.RecordRun(run)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": run.ID, "status": "recorded"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
+	}
+}
+
 // metricsHandler returns Prometheus-style metrics
 func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
@@ -294,16 +402,22 @@ func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Fprintf(w, "# HELP zenclaw_cache_hit_rate Cache hit rate\n")
 	fmt.Fprintf(w, "# TYPE zenclaw_cache_hit_rate gauge\n")
-	fmt.Fprintf(w, "zenclaw_cache_hit_rate %.4f\n", hitRate)
+	fmt.Fprintf(w, "zenclaw_cache_hit_rate %.4f\n\n", hitRate)
+
+	// Recovered panics: each one failed a tool call, a run or a request
+	// instead of the gateway
+	panics := s.agentService.GetPanicCounts()
+	panics["request"] = requestPanics.Load()
+	fmt.Fprintf(w, "# HELP zenclaw_panics_total Recovered panics by where they happened\n")
+	fmt.Fprintf(w, "# TYPE zenclaw_panics_total counter\n")
+	for _, where := range slices.Sorted(maps.Keys(panics)) {
+		fmt.Fprintf(w, "zenclaw_panics_total{where=%q} %d\n", where, panics[where])
+	}
 }
 
 // getClientID extracts client identifier from request (IP + User-Agent hash)
-func getClientID(r *http.Request) string {
-	// Use X-Forwarded-For if behind proxy, otherwise RemoteAddr
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.RemoteAddr
-	}
+func (s *Server) getClientID(r *http.Request) string {
+	ip := s.clientAddr(r)
 	// Include session ID if provided for more granular limiting
 	sessionID := r.Header.Get("X-Session-ID")
 	if sessionID != "" {
@@ -312,6 +426,13 @@ func getClientID(r *http.Request) string {
 	return ip
 }
 
+// writeError writes a JSON error body with a machine-readable error code
+func writeError(w http.ResponseWriter, status int, code types.ErrorCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(types.ErrorResponse{Error: message, ErrorCode: code})
+}
+
 // chatHandler handles chat requests
 func (s *Server) chatHandler(w http.ResponseWriter, r *http.Request) {
 	s.trackRequest()
@@ -320,27 +441,25 @@ func (s *Server) chatHandler(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&s.metrics.RequestsChat, 1)
 
 	// Rate limit check
-	clientID := getClientID(r)
-	if !s.rateLimiter.Allow(clientID) {
-		atomic.AddInt64(&s.metrics.RateLimitHits, 1)
-		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRequest(r) {
+		writeError(w, http.StatusTooManyRequests, types.ErrRateLimited, "Rate limit exceeded")
 		return
 	}
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
 	// Parse request
 	var req ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
 	if req.UserInput == "" {
-		http.Error(w, "user_input is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "user_input is required")
 		return
 	}
 
@@ -349,13 +468,45 @@ func (s *Server) chatHandler(w http.ResponseWriter, r *http.Request) {
 		req.WorkingDir = "."
 	}
 
+	// A retry with the same Idempotency-Key gets the first request's result
+	entry, replay, err := s.claimIdempotencyKey(r, req)
+	if err != nil {
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, err.Error())
+		return
+	}
+	if replay {
+		s.replayChat(w, entry)
+		return
+	}
+	client, key := s.clientAddr(r), r.Header.Get(idempotencyKeyHeader)
+
+	release, err := s.limits.admit(client, req)
+	if err != nil {
+		s.idempotency.release(client, key, entry)
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, err.Error())
+		return
+	}
+	defer release()
+	// From here the agent may run, so a retry replays the outcome; this
+	// stands in for one lost to a panic
+	defer s.idempotency.finish(entry, nil, types.Errorf(types.ErrInternal, "the original request failed"))
+
 	// Process with agent service
 	ctx := r.Context()
 	resp, err := s.agentService.Chat(ctx, req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Agent service error: %v", err), http.StatusInternalServerError)
+		if rejectedBeforeRun(err) {
+			s.idempotency.release(client, key, entry)
+		} else {
+			s.idempotency.finish(entry, nil, err)
+		}
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, fmt.Sprintf("Agent service error: %v", err))
 		return
 	}
+	s.idempotency.finish(entry, resp, nil)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -365,18 +516,25 @@ func (s *Server) chatHandler(w http.ResponseWriter, r *http.Request) {
 // sessionsHandler lists all sessions with state info
 func (s *Server) sessionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
 	// Get sessions with state from agent service
 	sessions := s.agentService.ListSessionsWithState()
+	filter := SessionFilter{Tag: r.URL.Query().Get("tag"), Project: r.URL.Query().Get("project")}
 
 	// Convert to response format
 	sessionList := make([]map[string]interface{}, 0, len(sessions))
 	for _, entry := range sessions {
+		if !filter.Matches(entry.Stats) {
+			continue
+		}
 		sessionList = append(sessionList, map[string]interface{}{
 			"id":                 entry.Stats.SessionID,
+			"title":              entry.Stats.Title,
+			"tags":               entry.Stats.Tags,
+			"project":            entry.Stats.Project,
 			"created_at":         entry.Stats.CreatedAt.Format(time.RFC3339),
 			"updated_at":         entry.Stats.UpdatedAt.Format(time.RFC3339),
 			"message_count":      entry.Stats.MessageCount,
@@ -403,11 +561,11 @@ func (s *Server) sessionsHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) sessionHandler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path[len("/sessions/"):]
 	if path == "" {
-		http.Error(w, "Session ID required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Session ID required")
 		return
 	}
 
-	// Parse path for actions: /sessions/{id}/background, /sessions/{id}/activate
+	// Parse path for actions: /sessions/{id}/cancel, /sessions/{id}/background, ...
 	parts := splitPath(path)
 	sessionID := parts[0]
 	action := ""
@@ -426,14 +584,22 @@ func (s *Server) sessionHandler(w http.ResponseWriter, r *http.Request) {
 		// Get session from agent service
 		session, exists := s.agentService.GetSession(sessionID)
 		if !exists {
-			http.Error(w, "Session not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, types.ErrNotFound, "Session not found")
+			return
+		}
+
+		query, err := ParseMessageQuery(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, err.Error())
 			return
 		}
 
 		stats := session.GetStats()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		result := map[string]interface{}{
 			"id":                 stats.SessionID,
+			"title":              stats.Title,
+			"tags":               stats.Tags,
+			"project":            stats.Project,
 			"created_at":         stats.CreatedAt.Format(time.RFC3339),
 			"updated_at":         stats.UpdatedAt.Format(time.RFC3339),
 			"message_count":      stats.MessageCount,
@@ -441,8 +607,16 @@ func (s *Server) sessionHandler(w http.ResponseWriter, r *http.Request) {
 			"assistant_messages": stats.AssistantMessages,
 			"tool_messages":      stats.ToolMessages,
 			"working_dir":        stats.WorkingDir,
-			"messages":           session.GetMessages(),
-		})
+		}
+		if !query.Summary {
+			messages, next := PageMessages(session.GetMessages(), query)
+			result["messages"] = messages
+			if next > 0 {
+				result["next_offset"] = next
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
 
 	case http.MethodDelete:
 		// Delete session via agent service
@@ -455,21 +629,35 @@ func (s *Server) sessionHandler(w http.ResponseWriter, r *http.Request) {
 		})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 	}
 }
 
-// handleSessionAction handles session actions (background, activate)
+// handleSessionAction handles session actions (cancel, background, activate, feedback, tags, pins, protected, exec-approvals, doctor)
 func (s *Server) handleSessionAction(w http.ResponseWriter, r *http.Request, sessionID, action string) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
 	switch action {
+	case "cancel":
+		n, err := s.agentService.CancelSession(sessionID)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":        sessionID,
+			"cancelled": n,
+			"status":    "ok",
+		})
+
 	case "background":
 		if err := s.agentService.BackgroundSession(sessionID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.CodeOf(err), err.Error())
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -487,7 +675,7 @@ func (s *Server) handleSessionAction(w http.ResponseWriter, r *http.Request, ses
 		json.NewDecoder(r.Body).Decode(&req) // Ignore error, clientID is optional
 
 		if err := s.agentService.ActivateSession(sessionID, req.ClientID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.CodeOf(err), err.Error())
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -497,8 +685,156 @@ func (s *Server) handleSessionAction(w http.ResponseWriter, r *http.Request, ses
 			"status": "ok",
 		})
 
+	case "feedback":
+		var req struct {
+			Rating  string `json:"rating"` // good or bad
+			Comment string `json:"comment,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		rating, err := ParseRating(req.Rating)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, types.CodeOf(err), err.Error())
+			return
+		}
+		fb, err := s.agentService.RateExchange(sessionID, rating, req.Comment)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       sessionID,
+			"feedback": fb,
+			"message":  feedbackSummary(fb),
+			"status":   "ok",
+		})
+
+	case "tags":
+		var req struct {
+			Add     []string `json:"add,omitempty"`
+			Remove  []string `json:"remove,omitempty"`
+			Project string   `json:"project,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		stats, err := s.agentService.TagSession(sessionID, req.Add, req.Remove, req.Project)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      sessionID,
+			"tags":    stats.Tags,
+			"project": stats.Project,
+			"status":  "ok",
+		})
+
+	case "pins":
+		if r.Method == http.MethodPost {
+			req := struct {
+				Index *int `json:"index,omitempty"` // Default: latest user message
+				Unpin bool `json:"unpin,omitempty"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeDecodeError(w, err)
+				return
+			}
+			index := -1
+			if req.Index != nil {
+				index = *req.Index
+			}
+			if _, err := s.agentService.PinMessage(sessionID, index, !req.Unpin); err != nil {
+				code := types.CodeOf(err)
+				writeError(w, code.HTTPStatus(), code, err.Error())
+				return
+			}
+		}
+		pinned, err := s.agentService.GetPinnedMessages(sessionID)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     sessionID,
+			"pinned": pinned,
+			"status": "ok",
+		})
+
+	case "protected":
+		var req struct {
+			Allow  []string `json:"allow,omitempty"`
+			Revoke []string `json:"revoke,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeDecodeError(w, err)
+			return
+		}
+		protected, allowed, err := s.agentService.AllowProtected(sessionID, req.Allow, req.Revoke)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":        sessionID,
+			"protected": protected,
+			"allowed":   allowed,
+			"status":    "ok",
+		})
+
+	case "exec-approvals":
+		var req struct {
+			Allow  []string `json:"allow,omitempty"`
+			Revoke []string `json:"revoke,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeDecodeError(w, err)
+			return
+		}
+		policy, approved, err := s.agentService.ApproveExec(sessionID, req.Allow, req.Revoke)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       sessionID,
+			"policy":   policy,
+			"approved": approved,
+			"status":   "ok",
+		})
+
+	case "doctor":
+		var req struct {
+			Repair bool `json:"repair,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeDecodeError(w, err)
+			return
+		}
+		report, err := s.agentService.DoctorSession(sessionID, req.Repair)
+		if err != nil {
+			code := types.CodeOf(err)
+			writeError(w, code.HTTPStatus(), code, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+
 	default:
-		http.Error(w, "Unknown action: "+action, http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Unknown action: "+action)
 	}
 }
 
@@ -541,27 +877,29 @@ func (s *Server) streamChatHandler(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&s.metrics.RequestsStream, 1)
 
 	// Rate limit check
-	clientID := getClientID(r)
-	if !s.rateLimiter.Allow(clientID) {
-		atomic.AddInt64(&s.metrics.RateLimitHits, 1)
-		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	if !s.allowRequest(r) {
+		writeError(w, http.StatusTooManyRequests, types.ErrRateLimited, "Rate limit exceeded")
 		return
 	}
 
+	if r.Method == http.MethodGet {
+		s.resumeStream(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 		return
 	}
 
 	// Parse request
 	var req ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
 	if req.UserInput == "" {
-		http.Error(w, "user_input is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "user_input is required")
 		return
 	}
 
@@ -570,65 +908,166 @@ func (s *Server) streamChatHandler(w http.ResponseWriter, r *http.Request) {
 		req.WorkingDir = "."
 	}
 
-	// Set up SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	// A retry with the same Idempotency-Key follows the first request's stream
+	client, key := s.clientAddr(r), r.Header.Get(idempotencyKeyHeader)
+	entry, replay, err := s.claimIdempotencyKey(r, req)
+	if err != nil {
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, err.Error())
+		return
+	}
+	if replay {
+		s.replayStream(w, r, entry)
+		return
+	}
 
-	// Get flusher for streaming
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+	release, err := s.limits.admit(client, req)
+	if err != nil {
+		s.idempotency.release(client, key, entry)
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, err.Error())
 		return
 	}
 
-	// Create event channel
-	eventChan := make(chan map[string]interface{}, 100)
+	if _, ok := w.(http.Flusher); !ok {
+		release()
+		s.idempotency.release(client, key, entry)
+		writeError(w, http.StatusInternalServerError, types.ErrUnavailable, "Streaming not supported")
+		return
+	}
+	// Refuse a busy session while an HTTP status can still say so; the
+	// agent service enforces it again (a CONFLICT error event) if a turn
+	// started in between
+	if req.SessionID != "" && !req.Queue && s.agentService.SessionBusy(req.SessionID) {
+		release()
+		s.idempotency.release(client, key, entry)
+		writeError(w, http.StatusConflict, types.ErrConflict, errSessionBusy(req.SessionID).Error())
+		return
+	}
 
-	// Process with agent service (with progress callback)
-	ctx := r.Context()
+	// Process with agent service, publishing events to a stream the
+	// connection follows. The agent outlives a dropped connection (the client
+	// may resume), so it holds the session slot until done.
+	stream := s.streams.create()
+	ctx := stream.attach(r.Context())
+	s.idempotency.started(entry, stream.id)
 	go func() {
-		defer close(eventChan)
-		resp, err := s.agentService.ChatWithProgress(ctx, req, func(event map[string]interface{}) {
-			select {
-			case eventChan <- event:
-			case <-ctx.Done():
-				return
+		defer release()
+		publishFinal := func(event map[string]interface{}) {
+			data, _ := json.Marshal(event)
+			stream.publish(json.RawMessage(data), true)
+			s.idempotency.finishStream(entry, data)
+		}
+		publishError := func(err error) {
+			publishFinal(map[string]interface{}{
+				"v":          types.ProgressSchemaVersion,
+				"type":       "error",
+				"message":    err.Error(),
+				"error_code": types.CodeOf(err),
+			})
+		}
+		// Outside the handler, RecoveryMiddleware can't catch a panic
+		defer func() {
+			if r := recover(); r != nil {
+				publishError(panicError("chat stream", r))
 			}
+		}()
+		resp, err := s.agentService.ChatWithProgress(ctx, req, func(event types.ProgressEvent) {
+			stream.publish(event, false)
 		})
 		if err != nil {
-			eventChan <- map[string]interface{}{
-				"type":    "error",
-				"message": err.Error(),
+			// Its error is replayed, unless the session was busy: then a
+			// retry with the key may run it
+			publishError(err)
+			if rejectedBeforeRun(err) {
+				s.idempotency.forget(client, key, entry)
 			}
 			return
 		}
 		// Send final result
-		eventChan <- map[string]interface{}{
+		done := map[string]interface{}{
+			"v":            types.ProgressSchemaVersion,
 			"type":         "done",
 			"session_id":   resp.SessionID,
 			"result":       resp.Result,
 			"session_info": resp.SessionInfo,
 		}
+		if resp.Error != "" {
+			done["error"] = resp.Error
+			done["error_code"] = resp.ErrorCode
+		}
+		if resp.Output != nil {
+			done["output"] = resp.Output
+		}
+		if resp.RecordID != "" {
+			done["record_id"] = resp.RecordID
+		}
+		if resp.StepLimit != nil {
+			done["step_limit"] = resp.StepLimit
+		}
+		if resp.Review != nil {
+			done["review"] = resp.Review
+		}
+		publishFinal(done)
 	}()
 
-	// Stream events to client
-	for event := range eventChan {
-		data, err := json.Marshal(event)
-		if err != nil {
-			continue
-		}
-		fmt.Fprintf(w, "data: %s\n\n", data)
-		flusher.Flush()
+	s.serveStream(w, r, stream, 0)
+}
 
-		// Check if client disconnected
-		select {
-		case <-ctx.Done():
-			return
-		default:
+// resumeStream continues a stream after the event in the Last-Event-ID
+// header (or the last_event_id query parameter)
+func (s *Server) resumeStream(w http.ResponseWriter, r *http.Request) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID == "" {
+		writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Last-Event-ID is required to resume a stream")
+		return
+	}
+	stream, seq, err := s.streams.lookup(lastID)
+	if err != nil {
+		code := types.CodeOf(err)
+		writeError(w, code.HTTPStatus(), code, err.Error())
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		writeError(w, http.StatusInternalServerError, types.ErrUnavailable, "Streaming not supported")
+		return
+	}
+	s.serveStream(w, r, stream, seq)
+}
+
+// serveStream writes the stream's events after seq as SSE, with heartbeats
+// while it is quiet, until the final event or the client disconnects
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, stream *eventStream, seq int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("X-Stream-ID", stream.id)
+	flusher := w.(http.Flusher)
+
+	write := func(id string, data []byte) error {
+		if id != "" {
+			if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
 		}
+		flusher.Flush()
+		return nil
 	}
+	stream.follow(seq, r.Context().Done(), func(ev streamEvent) error {
+		if ev.seq == 0 {
+			return write("", ev.data)
+		}
+		return write(stream.eventID(ev.seq), ev.data)
+	}, func() error {
+		return write("", heartbeatEvent())
+	})
 }
 
 // preferencesHandler handles AI preferences viewing and modification
@@ -656,6 +1095,7 @@ func (s *Server) preferencesHandler(w http.ResponseWriter, r *http.Request) {
 				"provider": s.config.Default.Provider,
 				"model":    s.config.Default.Model,
 			}
+			prefs["rate_limit"] = s.rateLimitPrefs()
 		case "fallback":
 			prefs["fallback_order"] = s.config.GetFallbackOrder()
 		case "consensus":
@@ -664,8 +1104,10 @@ func (s *Server) preferencesHandler(w http.ResponseWriter, r *http.Request) {
 		case "factory":
 			prefs["specialists"] = s.config.Factory.Specialists
 			prefs["guardrails"] = s.config.Factory.Guardrails
+		case "rate_limit":
+			prefs = s.rateLimitPrefs()
 		default:
-			http.Error(w, "Unknown preference: "+path, http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Unknown preference: "+path)
 			return
 		}
 
@@ -676,10 +1118,22 @@ func (s *Server) preferencesHandler(w http.ResponseWriter, r *http.Request) {
 		// Update preferences
 		var update map[string]interface{}
 		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Invalid JSON: "+err.Error())
 			return
 		}
 
+		// Rate limits first: an invalid change rejects the whole update
+		if rl, ok := update["rate_limit"]; ok {
+			if !s.operatorRequest(r) {
+				writeError(w, http.StatusForbidden, types.ErrPermissionDenied, "Changing rate_limit needs the gateway's operator_token in "+operatorTokenHeader)
+				return
+			}
+			if err := s.updateRateLimit(rl); err != nil {
+				writeError(w, http.StatusBadRequest, types.ErrInvalidArgument, "Invalid rate_limit: "+err.Error())
+				return
+			}
+		}
+
 		// Update fallback order
 		if fo, ok := update["fallback_order"].([]interface{}); ok {
 			order := make([]string, len(fo))
@@ -712,7 +1166,7 @@ func (s *Server) preferencesHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, types.ErrInvalidArgument, "Method not allowed")
 	}
 }
 
@@ -734,4 +1188,9 @@ func (s *Server) defaultHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "  POST /sessions/{id}/activate    - Activate a session\n")
 	fmt.Fprintf(w, "  GET  /preferences               - View AI preferences\n")
 	fmt.Fprintf(w, "  POST /preferences               - Update AI preferences\n")
+	fmt.Fprintf(w, "  GET  /runs                      - Consensus, fabric and factory runs\n")
+	fmt.Fprintf(w, "  GET  /runs/{id}                 - Get run details\n")
+	fmt.Fprintf(w, "  GET  /hooks                     - Webhooks and their recent deliveries\n")
+	fmt.Fprintf(w, "  POST /hooks/{name}              - Start the hook's agent task from a webhook\n")
+	fmt.Fprintf(w, "  POST /github/triage             - Classify, summarize and label a GitHub issue\n")
 }
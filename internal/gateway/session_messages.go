@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// SessionMessage is a message as GET /sessions/{id} returns it: with its
+// position in the history, so a page can be followed by the next one, and
+// the time it was added
+type SessionMessage struct {
+	Index int       `json:"index"`
+	Time  time.Time `json:"time,omitzero"`
+	ai.Message
+	ContentBytes int `json:"content_bytes,omitempty"` // Size of a tool result left out (tool_bodies=false)
+}
+
+// MessageQuery selects the messages of GET /sessions/{id}. Long sessions
+// hold megabytes of tool results; clients page through them, poll for
+// what's new with since, or leave the bodies out.
+type MessageQuery struct {
+	Offset     int       // First message index; negative counts from the end
+	Limit      int       // At most this many messages (0 = all)
+	Since      time.Time // Only messages added after this
+	Summary    bool      // No messages, only the session's details
+	ToolBodies bool      // Include the content of tool results
+}
+
+// ParseMessageQuery reads offset, limit, since (RFC 3339), summary and
+// tool_bodies from a query string
+func ParseMessageQuery(values url.Values) (MessageQuery, error) {
+	q := MessageQuery{ToolBodies: true}
+	var err error
+	if v := values.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("invalid offset: %s", v)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+	if v := values.Get("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return q, fmt.Errorf("invalid since (use RFC 3339, e.g. 2026-03-01T12:00:00Z): %s", v)
+		}
+	}
+	for name, dst := range map[string]*bool{"summary": &q.Summary, "tool_bodies": &q.ToolBodies} {
+		if v := values.Get(name); v != "" {
+			if *dst, err = strconv.ParseBool(v); err != nil {
+				return q, fmt.Errorf("invalid %s: %s", name, v)
+			}
+		}
+	}
+	return q, nil
+}
+
+// PageMessages returns the messages q selects, and the offset of the next
+// page (0 when this is the last one)
+func PageMessages(messages []ai.Message, q MessageQuery) ([]SessionMessage, int) {
+	start := q.Offset
+	if start < 0 {
+		start = max(len(messages)+start, 0)
+	}
+	page := []SessionMessage{}
+	for i := start; i < len(messages); i++ {
+		msg := messages[i]
+		if !q.Since.IsZero() && !msg.Time.After(q.Since) {
+			continue
+		}
+		if q.Limit > 0 && len(page) == q.Limit {
+			return page, i
+		}
+		item := SessionMessage{Index: i, Time: msg.Time, Message: msg}
+		if !q.ToolBodies && msg.Role == "tool" {
+			item.ContentBytes = len(msg.Content)
+			item.Content = ""
+		}
+		page = append(page, item)
+	}
+	return page, 0
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+func TestPageMessages(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var messages []ai.Message
+	for i, role := range []string{"system", "user", "assistant", "tool", "assistant", "user"} {
+		messages = append(messages, ai.Message{Role: role, Content: "body " + role, Time: start.Add(time.Duration(i) * time.Minute)})
+	}
+	page := func(query string) ([]SessionMessage, int) {
+		t.Helper()
+		values, _ := url.ParseQuery(query)
+		q, err := ParseMessageQuery(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return PageMessages(messages, q)
+	}
+	indexes := func(page []SessionMessage) []int {
+		var out []int
+		for _, m := range page {
+			out = append(out, m.Index)
+		}
+		return out
+	}
+
+	if got, next := page("offset=1&limit=2"); len(got) != 2 || got[0].Index != 1 || next != 3 {
+		t.Errorf("offset=1&limit=2: %v, next %d", indexes(got), next)
+	}
+	if got, next := page("offset=-2"); len(got) != 2 || got[0].Index != 4 || next != 0 {
+		t.Errorf("offset=-2: %v, next %d", indexes(got), next)
+	}
+	if got, _ := page("since=2026-03-01T12:03:00Z"); len(got) != 2 || got[0].Index != 4 {
+		t.Errorf("since: %v", indexes(got))
+	}
+	got, _ := page("tool_bodies=false")
+	if tool := got[3]; tool.Content != "" || tool.ContentBytes != len("body tool") || got[2].Content == "" {
+		t.Errorf("tool_bodies=false: %+v", got[2:4])
+	}
+
+	for _, bad := range []string{"limit=-1", "offset=x", "since=yesterday", "summary=maybe"} {
+		values, _ := url.ParseQuery(bad)
+		if _, err := ParseMessageQuery(values); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+}
+
+func TestMessageTimesPersist(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	session, _ := store.CreateSession("timed")
+	session.AddMessage(ai.Message{Role: "user", Content: "hi", Time: at})
+	session.AddMessage(ai.Message{Role: "assistant", Content: "hello"})
+	if err := store.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Close()
+	restored, _ := reloaded.GetSession("timed")
+	messages := restored.GetMessages()
+	if len(messages) != 2 || !messages[0].Time.Equal(at) || messages[1].Time.IsZero() {
+		t.Errorf("message times after reload: %+v", messages)
+	}
+}
@@ -0,0 +1,243 @@
+package gateway
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// titleSeq is the seq value of the title row in the search index
+const titleSeq = -1
+
+// SearchResult is a session matching a search query
+type SearchResult struct {
+	SessionID string    `json:"session_id"`
+	Title     string    `json:"title,omitempty"`
+	Snippet   string    `json:"snippet,omitempty"` // Matching excerpt, terms in [brackets]
+	Matches   int       `json:"matches"`           // Matching messages (title counts as one)
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// indexSession replaces a session's rows in the full-text index
+func (s *SessionStore) indexSession(tx *sql.Tx, sessionID, title string, messages []ai.Message) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	if _, err := tx.Exec("DELETE FROM session_search WHERE session_id = ?", sessionID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO session_search (session_id, seq, content) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if title != "" {
+		if _, err := stmt.Exec(sessionID, titleSeq, title); err != nil {
+			return err
+		}
+	}
+	for i, msg := range messages {
+		if !searchable(msg) {
+			continue
+		}
+		if _, err := stmt.Exec(sessionID, i, msg.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSearchIndex indexes sessions saved before full-text search existed
+func (s *SessionStore) backfillSearchIndex() {
+	if !s.ftsEnabled || len(s.sessions) == 0 {
+		return
+	}
+	var rows int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM session_search").Scan(&rows); err != nil || rows > 0 {
+		return
+	}
+
+	for id, info := range s.sessions {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return
+		}
+		if err := s.indexSession(tx, id, info.Session.GetTitle(), info.Session.GetMessages()); err != nil {
+			tx.Rollback()
+			log.Printf("[SessionStore] Failed to index session %s: %v", id, err)
+			continue
+		}
+		tx.Commit()
+	}
+	log.Printf("[SessionStore] Indexed %d sessions for search", len(s.sessions))
+}
+
+// Search finds sessions whose title or transcript contains all query terms,
+// best matches first (full-text) or most recently used first (fallback)
+func (s *SessionStore) Search(query string, limit int) ([]SearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, types.Errorf(types.ErrInvalidArgument, "search query is empty")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var results []SearchResult
+	var err error
+	if s.ftsEnabled {
+		results, err = s.searchFTS(terms)
+	} else {
+		results = s.searchMemory(terms)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchFTS queries the FTS5 index; each term is quoted so user input can't
+// inject query syntax
+func (s *SessionStore) searchFTS(terms []string) ([]SearchResult, error) {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+
+	rows, err := s.db.Query(`
+		SELECT session_id, seq, snippet(session_search, 2, '[', ']', '…', 16)
+		FROM session_search
+		WHERE session_search MATCH ?
+		ORDER BY rank
+	`, strings.Join(quoted, " "))
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	var results []SearchResult
+	index := make(map[string]int)
+	for rows.Next() {
+		var id, snippet string
+		var seq int
+		if err := rows.Scan(&id, &seq, &snippet); err != nil {
+			continue
+		}
+		info, ok := s.sessions[id]
+		if !ok {
+			continue // Stale index row
+		}
+		i, seen := index[id]
+		if !seen {
+			stats := info.Session.GetStats()
+			i = len(results)
+			index[id] = i
+			results = append(results, SearchResult{SessionID: id, Title: stats.Title, UpdatedAt: info.LastUsed})
+		}
+		results[i].Matches++
+		if results[i].Snippet == "" && seq != titleSeq {
+			results[i].Snippet = oneLine(snippet)
+		}
+	}
+	return results, rows.Err()
+}
+
+// searchMemory matches terms case-insensitively against sessions in memory
+// (used when SQLite lacks FTS5)
+func (s *SessionStore) searchMemory(terms []string) []SearchResult {
+	for i, t := range terms {
+		terms[i] = strings.ToLower(t)
+	}
+
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	var results []SearchResult
+	for id, info := range s.sessions {
+		result := SearchResult{SessionID: id, Title: info.Session.GetTitle(), UpdatedAt: info.LastUsed}
+		if containsAll(strings.ToLower(result.Title), terms) {
+			result.Matches++
+		}
+		for _, msg := range info.Session.GetMessages() {
+			if !searchable(msg) || !containsAll(strings.ToLower(msg.Content), terms) {
+				continue
+			}
+			result.Matches++
+			if result.Snippet == "" {
+				result.Snippet = excerpt(msg.Content, terms[0], 60)
+			}
+		}
+		if result.Matches > 0 {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+	return results
+}
+
+// searchable reports whether a message belongs in the search index
+// (the visible conversation, not tool output or system prompts)
+func searchable(msg ai.Message) bool {
+	return (msg.Role == "user" || msg.Role == "assistant") && strings.TrimSpace(msg.Content) != ""
+}
+
+func containsAll(text string, terms []string) bool {
+	for _, t := range terms {
+		if !strings.Contains(text, t) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// excerpt returns up to width characters either side of the first occurrence
+// of term (lowercase), with the match in [brackets]
+func excerpt(text, term string, width int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	pos := strings.Index(string(lower), term)
+	if pos < 0 || len(lower) != len(runes) {
+		return oneLine(truncateRunes(text, 2*width))
+	}
+	start := len([]rune(string(lower)[:pos]))
+	end := start + len([]rune(term))
+
+	from, to := start-width, end+width
+	prefix, suffix := "…", "…"
+	if from <= 0 {
+		from, prefix = 0, ""
+	}
+	if to >= len(runes) {
+		to, suffix = len(runes), ""
+	}
+	return oneLine(prefix + string(runes[from:start]) + "[" + string(runes[start:end]) + "]" + string(runes[end:to]) + suffix)
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// oneLine collapses whitespace so snippets print on a single line
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -7,11 +7,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/neves/zen-claw/internal/agent"
 	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -40,6 +42,8 @@ type SessionStore struct {
 	sessions    map[string]*SessionInfo // In-memory cache
 	sessionsMu  sync.RWMutex
 	maxSessions int
+	ftsEnabled  bool                               // SQLite built with FTS5 (-tags sqlite_fts5)
+	repairs     map[string][]agent.TranscriptIssue // Transcript issues fixed when loading, by session
 }
 
 // SessionStoreConfig configuration for session store
@@ -89,6 +93,8 @@ func NewSessionStore(cfg *SessionStoreConfig) (*SessionStore, error) {
 		dbPath:      cfg.DBPath,
 		sessions:    make(map[string]*SessionInfo),
 		maxSessions: cfg.MaxSessions,
+		ftsEnabled:  createSearchIndex(db),
+		repairs:     make(map[string][]agent.TranscriptIssue),
 	}
 
 	// Load existing sessions into memory
@@ -96,6 +102,7 @@ func NewSessionStore(cfg *SessionStoreConfig) (*SessionStore, error) {
 		db.Close()
 		return nil, fmt.Errorf("load sessions: %w", err)
 	}
+	store.backfillSearchIndex()
 
 	log.Printf("[SessionStore] Initialized with SQLite at %s", cfg.DBPath)
 	return store, nil
@@ -108,7 +115,14 @@ func createTables(db *sql.DB) error {
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
 		working_dir TEXT,
-		message_count INTEGER DEFAULT 0
+		message_count INTEGER DEFAULT 0,
+		title TEXT,
+		tags TEXT,
+		project TEXT,
+		provider TEXT,
+		model TEXT,
+		thinking_level TEXT,
+		language TEXT
 	);
 
 	CREATE TABLE IF NOT EXISTS messages (
@@ -119,15 +133,116 @@ func createTables(db *sql.DB) error {
 		content TEXT NOT NULL,
 		tool_calls TEXT,
 		tool_call_id TEXT,
+		pinned INTEGER DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
 		UNIQUE(session_id, seq)
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_messages_session ON messages(session_id, seq);
+
+	CREATE TABLE IF NOT EXISTS feedback (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		message_index INTEGER NOT NULL,
+		rating INTEGER NOT NULL,
+		comment TEXT,
+		provider TEXT,
+		model TEXT,
+		record_id TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_feedback_session ON feedback(session_id);
+
+	CREATE TABLE IF NOT EXISTS tool_results (
+		session_id TEXT NOT NULL,
+		ref TEXT NOT NULL,
+		content TEXT NOT NULL,
+		PRIMARY KEY (session_id, ref)
+	);
+
+	CREATE TABLE IF NOT EXISTS model_capabilities (
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		tools INTEGER NOT NULL,
+		json_mode INTEGER NOT NULL,
+		system_prompt INTEGER NOT NULL,
+		probed_at DATETIME NOT NULL,
+		PRIMARY KEY (provider, model)
+	);
+
+	CREATE TABLE IF NOT EXISTS runs (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		data TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_runs_started ON runs(started_at);
+
+	CREATE TABLE IF NOT EXISTS usage (
+		at INTEGER NOT NULL,
+		day TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		project TEXT NOT NULL,
+		session_id TEXT NOT NULL,
+		input_tokens INTEGER NOT NULL,
+		output_tokens INTEGER NOT NULL,
+		cost_usd REAL NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_usage_at ON usage(at);
+
+	CREATE TABLE IF NOT EXISTS counters (
+		name TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);
 	`
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the first release
+	for _, column := range []string{"title", "tags", "project", "provider", "model", "thinking_level", "language"} {
+		var exists bool
+		if err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('sessions') WHERE name = ?", column).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := db.Exec("ALTER TABLE sessions ADD COLUMN " + column + " TEXT"); err != nil {
+				return err
+			}
+		}
+	}
+	var hasPinned bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('messages') WHERE name = 'pinned'").Scan(&hasPinned); err != nil {
+		return err
+	}
+	if !hasPinned {
+		if _, err := db.Exec("ALTER TABLE messages ADD COLUMN pinned INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSearchIndex creates the FTS5 index over titles and transcripts.
+// Returns false when SQLite was built without FTS5; search then scans sessions in memory.
+func createSearchIndex(db *sql.DB) bool {
+	_, err := db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS session_search USING fts5(
+			session_id UNINDEXED,
+			seq UNINDEXED,
+			content,
+			tokenize = 'porter unicode61'
+		)
+	`)
+	if err != nil {
+		log.Printf("[SessionStore] Full-text search unavailable (%v); build with -tags sqlite_fts5 to enable it", err)
+		return false
+	}
+	return true
 }
 
 // Close closes the database connection
@@ -190,14 +305,23 @@ func (s *SessionStore) SaveSession(session *agent.Session) error {
 	messages := session.GetMessages()
 
 	// Upsert session
+	tagsJSON, _ := json.Marshal(stats.Tags)
 	_, err = tx.Exec(`
-		INSERT INTO sessions (id, created_at, updated_at, working_dir, message_count)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO sessions (id, created_at, updated_at, working_dir, message_count, title, tags, project, provider, model, thinking_level, language)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			updated_at = excluded.updated_at,
 			working_dir = excluded.working_dir,
-			message_count = excluded.message_count
-	`, session.ID, stats.CreatedAt, now, stats.WorkingDir, len(messages))
+			message_count = excluded.message_count,
+			title = excluded.title,
+			tags = excluded.tags,
+			project = excluded.project,
+			provider = excluded.provider,
+			model = excluded.model,
+			thinking_level = excluded.thinking_level,
+			language = excluded.language
+	`, session.ID, stats.CreatedAt, now, stats.WorkingDir, len(messages), stats.Title, string(tagsJSON), stats.Project,
+		stats.Provider, stats.Model, stats.ThinkingLevel, stats.Language)
 	if err != nil {
 		return fmt.Errorf("save session: %w", err)
 	}
@@ -209,8 +333,8 @@ func (s *SessionStore) SaveSession(session *agent.Session) error {
 	}
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO messages (session_id, seq, role, content, tool_calls, tool_call_id)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO messages (session_id, seq, role, content, tool_calls, tool_call_id, pinned, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("prepare insert: %w", err)
@@ -222,12 +346,42 @@ func (s *SessionStore) SaveSession(session *agent.Session) error {
 		if len(msg.ToolCalls) > 0 {
 			toolCallsJSON, _ = json.Marshal(msg.ToolCalls)
 		}
-		_, err = stmt.Exec(session.ID, i, msg.Role, msg.Content, toolCallsJSON, msg.ToolCallID)
+		at := msg.Time
+		if at.IsZero() {
+			at = now
+		}
+		_, err = stmt.Exec(session.ID, i, msg.Role, msg.Content, toolCallsJSON, msg.ToolCallID, msg.Pinned, at)
 		if err != nil {
 			return fmt.Errorf("insert message %d: %w", i, err)
 		}
 	}
 
+	// Feedback is replaced the same way
+	if _, err := tx.Exec("DELETE FROM feedback WHERE session_id = ?", session.ID); err != nil {
+		return fmt.Errorf("delete feedback: %w", err)
+	}
+	for _, fb := range session.GetFeedback() {
+		_, err = tx.Exec(`
+			INSERT INTO feedback (session_id, message_index, rating, comment, provider, model, record_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, session.ID, fb.MessageIndex, fb.Rating, fb.Comment, fb.Provider, fb.Model, fb.RecordID, fb.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert feedback: %w", err)
+		}
+	}
+
+	// Stored tool outputs never change once referenced: only insert new ones
+	for ref, content := range session.GetResults() {
+		_, err = tx.Exec("INSERT OR IGNORE INTO tool_results (session_id, ref, content) VALUES (?, ?, ?)", session.ID, ref, content)
+		if err != nil {
+			return fmt.Errorf("insert tool result %s: %w", ref, err)
+		}
+	}
+
+	if err := s.indexSession(tx, session.ID, stats.Title, messages); err != nil {
+		return fmt.Errorf("index session: %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit: %w", err)
 	}
@@ -252,16 +406,43 @@ func (s *SessionStore) DeleteSession(sessionID string) bool {
 	s.sessionsMu.Lock()
 	defer s.sessionsMu.Unlock()
 
-	// Delete from DB (cascade deletes messages)
-	_, err := s.db.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
-	if err != nil {
+	if err := s.deleteSessionLocked(sessionID); err != nil {
 		log.Printf("[SessionStore] Delete error: %v", err)
 		return false
 	}
+	return true
+}
+
+// deleteSessionLocked removes a session and its rows (caller holds sessionsMu).
+// Child rows are deleted explicitly: foreign keys aren't enforced on this connection.
+func (s *SessionStore) deleteSessionLocked(sessionID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"DELETE FROM messages WHERE session_id = ?",
+		"DELETE FROM feedback WHERE session_id = ?",
+		"DELETE FROM tool_results WHERE session_id = ?",
+		"DELETE FROM sessions WHERE id = ?",
+	} {
+		if _, err := tx.Exec(query, sessionID); err != nil {
+			return err
+		}
+	}
+	if s.ftsEnabled {
+		if _, err := tx.Exec("DELETE FROM session_search WHERE session_id = ?", sessionID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
-	// Remove from memory
 	delete(s.sessions, sessionID)
-	return true
+	return nil
 }
 
 // ListSessions returns all session stats
@@ -416,7 +597,8 @@ func (s *SessionStore) CreateSession(sessionID string) (*agent.Session, error) {
 // loadSessions loads all sessions from SQLite into memory
 func (s *SessionStore) loadSessions() error {
 	rows, err := s.db.Query(`
-		SELECT id, created_at, updated_at, working_dir 
+		SELECT id, created_at, updated_at, working_dir, COALESCE(title, ''), COALESCE(tags, ''), COALESCE(project, ''),
+			COALESCE(provider, ''), COALESCE(model, ''), COALESCE(thinking_level, ''), COALESCE(language, '')
 		FROM sessions 
 		ORDER BY updated_at DESC
 	`)
@@ -425,10 +607,11 @@ func (s *SessionStore) loadSessions() error {
 	}
 	defer rows.Close()
 
+	var repaired []*agent.Session
 	for rows.Next() {
-		var id, workingDir string
+		var id, workingDir, title, tagsJSON, project, provider, model, thinkingLevel, language string
 		var createdAt, updatedAt time.Time
-		if err := rows.Scan(&id, &createdAt, &updatedAt, &workingDir); err != nil {
+		if err := rows.Scan(&id, &createdAt, &updatedAt, &workingDir, &title, &tagsJSON, &project, &provider, &model, &thinkingLevel, &language); err != nil {
 			continue
 		}
 
@@ -437,9 +620,19 @@ func (s *SessionStore) loadSessions() error {
 		if workingDir != "" {
 			session.SetWorkingDir(workingDir)
 		}
+		session.SetTitle(title)
+		session.SetProject(project)
+		session.SetModel(provider, model)
+		session.SetThinkingLevel(thinkingLevel)
+		session.SetLanguage(language)
+		if tagsJSON != "" {
+			var tags []string
+			json.Unmarshal([]byte(tagsJSON), &tags)
+			session.AddTags(tags...)
+		}
 
 		msgRows, err := s.db.Query(`
-			SELECT role, content, tool_calls, tool_call_id
+			SELECT role, content, tool_calls, tool_call_id, COALESCE(pinned, 0), created_at
 			FROM messages
 			WHERE session_id = ?
 			ORDER BY seq
@@ -452,14 +645,18 @@ func (s *SessionStore) loadSessions() error {
 			var role, content string
 			var toolCallsJSON sql.NullString
 			var toolCallID sql.NullString
+			var pinned bool
+			var createdAt sql.NullTime
 
-			if err := msgRows.Scan(&role, &content, &toolCallsJSON, &toolCallID); err != nil {
+			if err := msgRows.Scan(&role, &content, &toolCallsJSON, &toolCallID, &pinned, &createdAt); err != nil {
 				continue
 			}
 
 			msg := ai.Message{
 				Role:    role,
 				Content: content,
+				Pinned:  pinned,
+				Time:    createdAt.Time,
 			}
 			if toolCallID.Valid {
 				msg.ToolCallID = toolCallID.String
@@ -471,6 +668,44 @@ func (s *SessionStore) loadSessions() error {
 		}
 		msgRows.Close()
 
+		fbRows, err := s.db.Query(`
+			SELECT message_index, rating, comment, provider, model, record_id, created_at
+			FROM feedback
+			WHERE session_id = ?
+			ORDER BY id
+		`, id)
+		if err == nil {
+			for fbRows.Next() {
+				var fb agent.Feedback
+				var comment, provider, model, recordID sql.NullString
+				if err := fbRows.Scan(&fb.MessageIndex, &fb.Rating, &comment, &provider, &model, &recordID, &fb.CreatedAt); err != nil {
+					continue
+				}
+				fb.Comment, fb.Provider, fb.Model, fb.RecordID = comment.String, provider.String, model.String, recordID.String
+				session.AddFeedback(fb)
+			}
+			fbRows.Close()
+		}
+
+		resultRows, err := s.db.Query("SELECT ref, content FROM tool_results WHERE session_id = ?", id)
+		if err == nil {
+			for resultRows.Next() {
+				var ref, content string
+				if err := resultRows.Scan(&ref, &content); err != nil {
+					continue
+				}
+				session.SetResult(ref, content)
+			}
+			resultRows.Close()
+		}
+
+		// A history providers would reject makes the session unusable
+		if issues := session.RepairTranscript(""); len(issues) > 0 {
+			log.Printf("[SessionStore] Repaired %d transcript issue(s) in session '%s' (see: zen-claw session doctor %s)", len(issues), id, id)
+			s.repairs[id] = issues
+			repaired = append(repaired, session)
+		}
+
 		msgCount := len(session.GetMessages())
 		s.sessions[id] = &SessionInfo{
 			Session:  session,
@@ -480,10 +715,250 @@ func (s *SessionStore) loadSessions() error {
 		log.Printf("[SessionStore] Loaded session '%s' with %d messages", id, msgCount)
 	}
 
+	for _, session := range repaired {
+		if err := s.SaveSession(session); err != nil {
+			log.Printf("[SessionStore] Warning: failed to save repaired session '%s': %v", session.ID, err)
+		}
+	}
+
 	log.Printf("[SessionStore] Loaded %d sessions from %s", len(s.sessions), s.dbPath)
 	return nil
 }
 
+// LoadRepairs returns the transcript issues fixed when the session was loaded
+func (s *SessionStore) LoadRepairs(sessionID string) []agent.TranscriptIssue {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+	return s.repairs[sessionID]
+}
+
+// FeedbackTotals returns persisted good/bad rating counts per provider/model
+func (s *SessionStore) FeedbackTotals() (map[string]*FeedbackCounts, error) {
+	rows, err := s.db.Query(`
+		SELECT provider, model, SUM(rating > 0), SUM(rating < 0)
+		FROM feedback
+		WHERE provider != ''
+		GROUP BY provider, model
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]*FeedbackCounts)
+	for rows.Next() {
+		var provider, model string
+		var good, bad int
+		if err := rows.Scan(&provider, &model, &good, &bad); err != nil {
+			continue
+		}
+		totals[provider+"/"+model] = &FeedbackCounts{Provider: provider, Model: model, Good: good, Bad: bad}
+	}
+	return totals, rows.Err()
+}
+
+// ModelCapabilities returns the persisted results of model probes
+func (s *SessionStore) ModelCapabilities() ([]types.ModelCapabilities, error) {
+	rows, err := s.db.Query("SELECT provider, model, tools, json_mode, system_prompt, probed_at FROM model_capabilities")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []types.ModelCapabilities
+	for rows.Next() {
+		var caps types.ModelCapabilities
+		if err := rows.Scan(&caps.Provider, &caps.Model, &caps.Tools, &caps.JSONMode, &caps.SystemPrompt, &caps.ProbedAt); err != nil {
+			continue
+		}
+		out = append(out, caps)
+	}
+	return out, rows.Err()
+}
+
+// SaveModelCapabilities persists a model probe's results
+func (s *SessionStore) SaveModelCapabilities(caps types.ModelCapabilities) error {
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO model_capabilities (provider, model, tools, json_mode, system_prompt, probed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, caps.Provider, caps.Model, caps.Tools, caps.JSONMode, caps.SystemPrompt, caps.ProbedAt)
+	return err
+}
+
+// Runs returns the most recent reported runs, oldest first
+func (s *SessionStore) Runs(limit int) ([]types.RunRecord, error) {
+	rows, err := s.db.Query("SELECT data FROM (SELECT data, started_at FROM runs ORDER BY started_at DESC LIMIT ?) ORDER BY started_at", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []types.RunRecord
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			continue
+		}
+		var run types.RunRecord
+		if json.Unmarshal([]byte(data), &run) == nil {
+			out = append(out, run)
+		}
+	}
+	return out, rows.Err()
+}
+
+// SaveRun persists a reported run
+func (s *SessionStore) SaveRun(run types.RunRecord) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT OR REPLACE INTO runs (id, kind, started_at, data) VALUES (?, ?, ?, ?)",
+		run.ID, run.Kind, run.StartedAt, string(data))
+	return err
+}
+
+// SaveUsage persists a model call's usage
+func (s *SessionStore) SaveUsage(rec UsageRecord) error {
+	_, err := s.db.Exec(`
+		INSERT INTO usage (at, day, provider, model, project, session_id, input_tokens, output_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.At.Unix(), rec.At.Format("2006-01-02"), rec.Provider, rec.Model, rec.Project, rec.SessionID,
+		rec.InputTokens, rec.OutputTokens, rec.CostUSD)
+	return err
+}
+
+// UsageReport sums the usage recorded since a time, in total and by
+// provider/model, project and day
+func (s *SessionStore) UsageReport(since time.Time) (*UsageReport, error) {
+	report := &UsageReport{Since: since}
+	totals, err := s.usageRows("'', '', '', ''", since, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Total = totals[0]
+	}
+	if report.ByModel, err = s.usageRows("provider, model, '', ''", since, true); err != nil {
+		return nil, err
+	}
+	if report.ByProject, err = s.usageRows("'', '', project, ''", since, true); err != nil {
+		return nil, err
+	}
+	if report.ByDay, err = s.usageRows("'', '', '', day", since, true); err != nil {
+		return nil, err
+	}
+	sort.Slice(report.ByDay, func(i, j int) bool { return report.ByDay[i].Day < report.ByDay[j].Day })
+	return report, nil
+}
+
+// UsageTotals sums all recorded usage by provider, model and project
+func (s *SessionStore) UsageTotals() ([]UsageRow, error) {
+	return s.usageRows("provider, model, project, ''", time.Time{}, true)
+}
+
+// usageRows sums usage since a time, grouped by the selected columns
+// (provider, model, project, day; empty strings for the others), most
+// expensive first
+func (s *SessionStore) usageRows(columns string, since time.Time, grouped bool) ([]UsageRow, error) {
+	query := `SELECT ` + columns + `, COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM usage WHERE at >= ?`
+	if grouped {
+		query += " GROUP BY 1, 2, 3, 4 ORDER BY 8 DESC, 1, 2, 3, 4"
+	}
+	rows, err := s.db.Query(query, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []UsageRow
+	for rows.Next() {
+		var row UsageRow
+		if err := rows.Scan(&row.Provider, &row.Model, &row.Project, &row.Day, &row.Calls, &row.InputTokens, &row.OutputTokens, &row.CostUSD); err != nil {
+			continue
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// SessionUsage sums the usage recorded for a session
+func (s *SessionStore) SessionUsage(sessionID string) (UsageRow, error) {
+	var row UsageRow
+	err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM usage WHERE session_id = ?
+	`, sessionID).Scan(&row.Calls, &row.InputTokens, &row.OutputTokens, &row.CostUSD)
+	return row, err
+}
+
+// UsageRecords returns the usage records since a time and after a record ID,
+// oldest first (limit 0 = all)
+func (s *SessionStore) UsageRecords(since time.Time, afterID int64, limit int) ([]UsageRecord, error) {
+	query := `
+		SELECT rowid, at, provider, model, project, session_id, input_tokens, output_tokens, cost_usd
+		FROM usage WHERE at >= ? AND rowid > ? ORDER BY rowid`
+	args := []any{since.Unix(), afterID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []UsageRecord
+	for rows.Next() {
+		var rec UsageRecord
+		var at int64
+		if err := rows.Scan(&rec.ID, &at, &rec.Provider, &rec.Model, &rec.Project, &rec.SessionID,
+			&rec.InputTokens, &rec.OutputTokens, &rec.CostUSD); err != nil {
+			continue
+		}
+		rec.At = time.Unix(at, 0)
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Counters returns the persisted gateway counters
+func (s *SessionStore) Counters() (map[string]int64, error) {
+	rows, err := s.db.Query("SELECT name, value FROM counters")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counters := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			continue
+		}
+		counters[name] = value
+	}
+	return counters, rows.Err()
+}
+
+// SaveCounters persists gateway counters
+func (s *SessionStore) SaveCounters(counters map[string]int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for name, value := range counters {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO counters (name, value) VALUES (?, ?)", name, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // CleanAllSessions deletes all sessions (for CLI clean command)
 func (s *SessionStore) CleanAllSessions() (int, error) {
 	s.sessionsMu.Lock()
@@ -496,10 +971,21 @@ func (s *SessionStore) CleanAllSessions() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	_, err = s.db.Exec("DELETE FROM feedback")
+	if err != nil {
+		return 0, err
+	}
+	_, err = s.db.Exec("DELETE FROM tool_results")
+	if err != nil {
+		return 0, err
+	}
 	_, err = s.db.Exec("DELETE FROM sessions")
 	if err != nil {
 		return 0, err
 	}
+	if s.ftsEnabled {
+		_, _ = s.db.Exec("DELETE FROM session_search")
+	}
 
 	// Vacuum to reclaim space
 	_, _ = s.db.Exec("VACUUM")
@@ -528,9 +1014,7 @@ func (s *SessionStore) CleanOldSessions(olderThan time.Duration) (int, error) {
 
 	// Delete from DB
 	for _, id := range toDelete {
-		_, err := s.db.Exec("DELETE FROM sessions WHERE id = ?", id)
-		if err == nil {
-			delete(s.sessions, id)
+		if err := s.deleteSessionLocked(id); err == nil {
 			count++
 		}
 	}
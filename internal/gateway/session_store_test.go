@@ -3,10 +3,13 @@ package gateway
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/neves/zen-claw/internal/agent"
 	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/types"
 )
 
 func setupTestStore(t *testing.T) (*SessionStore, func()) {
@@ -304,6 +307,177 @@ func TestCleanOldSessions(t *testing.T) {
 	}
 }
 
+func TestApplyRetention(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Idle auto-generated sessions last used 40, 20 and 1 days ago, the oldest
+	// pinned; a named and a titled session as old as the oldest
+	const (
+		pinned = "session_20250101_000000"
+		stale  = "session_20250102_000000"
+		recent = "session_20250103_000000"
+		named  = "release-notes"
+		titled = "session_20250104_000000"
+	)
+	now := time.Now()
+	for id, age := range map[string]int{pinned: 40, stale: 20, recent: 1, named: 40, titled: 40} {
+		session, _ := store.CreateSession(id)
+		session.AddMessage(ai.Message{Role: "user", Content: strings.Repeat("x", 1000)})
+		switch id {
+		case pinned:
+			session.AddTags(pinnedTag)
+		case titled:
+			session.SetTitle("Fix the login redirect")
+		}
+		if err := store.SaveSession(session); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+		info, _ := store.GetSessionInfo(id)
+		info.State = SessionStateIdle
+		info.LastUsed = now.Add(-time.Duration(age) * 24 * time.Hour)
+	}
+
+	deletedIDs := func(report *RetentionReport) []string {
+		var ids []string
+		for _, c := range report.Deleted {
+			ids = append(ids, c.SessionID)
+		}
+		return ids
+	}
+
+	// Dry run reports without deleting
+	report, err := store.ApplyRetention(RetentionPolicy{MaxAge: 7 * 24 * time.Hour}, true)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if got := deletedIDs(report); len(got) != 1 || got[0] != stale {
+		t.Errorf("dry run deleted = %v, want [%s]", got, stale)
+	}
+	if report.Protected != 3 || report.TotalBytes < 5000 {
+		t.Errorf("protected = %d, total = %d; want 3 and >= 5000", report.Protected, report.TotalBytes)
+	}
+	if _, found := store.GetSession(stale); !found {
+		t.Error("dry run deleted a session")
+	}
+
+	// Kept sessions survive; size limit removes least recently used first
+	report, err = store.ApplyRetention(RetentionPolicy{MaxTotalBytes: 4500, Keep: []string{recent}}, false)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if got := deletedIDs(report); len(got) != 1 || got[0] != stale {
+		t.Errorf("deleted = %v, want [%s]", got, stale)
+	}
+	if _, found := store.GetSession(stale); found {
+		t.Error("expected stale session to be deleted")
+	}
+	for _, id := range []string{pinned, recent, named, titled} {
+		if _, found := store.GetSession(id); !found {
+			t.Errorf("expected %s to be kept", id)
+		}
+	}
+}
+
+func TestPinnedMessages(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	session, _ := store.CreateSession("pins")
+	if _, err := session.PinMessage(-1, true); err == nil {
+		t.Error("Expected error pinning in an empty session")
+	}
+	session.AddMessage(ai.Message{Role: "user", Content: "Schema: users(id, email)"})
+	session.AddMessage(ai.Message{Role: "assistant", Content: "Noted."})
+
+	pinned, err := session.PinMessage(-1, true)
+	if err != nil || pinned.Index != 0 {
+		t.Fatalf("PinMessage(-1) = %+v, %v; want index 0", pinned, err)
+	}
+	if _, err := session.PinMessage(5, true); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+	if err := store.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	// Pins survive a reload
+	reloaded, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer reloaded.Close()
+	restored, _ := reloaded.GetSession("pins")
+	if got := restored.GetPinnedMessages(); len(got) != 1 || got[0].Content != "Schema: users(id, email)" {
+		t.Errorf("GetPinnedMessages() after reload = %+v", got)
+	}
+	if restored.GetStats().PinnedMessages != 1 {
+		t.Errorf("PinnedMessages = %d, want 1", restored.GetStats().PinnedMessages)
+	}
+}
+
+func TestToolResultsPersist(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	session, _ := store.CreateSession("results")
+	ref := session.StoreResult("full build log")
+	if err := store.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if err := store.SaveSession(session); err != nil {
+		t.Fatalf("second SaveSession failed: %v", err)
+	}
+
+	reloaded, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer reloaded.Close()
+	restored, _ := reloaded.GetSession("results")
+	if got, ok := restored.GetResult(ref); !ok || got != "full build log" {
+		t.Errorf("GetResult(%s) after reload = %q, %v", ref, got, ok)
+	}
+
+	if !reloaded.DeleteSession("results") {
+		t.Fatal("DeleteSession failed")
+	}
+	var rows int
+	reloaded.db.QueryRow("SELECT COUNT(*) FROM tool_results").Scan(&rows)
+	if rows != 0 {
+		t.Errorf("%d tool_results rows left after delete", rows)
+	}
+}
+
+func TestSessionModelPersists(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	session, _ := store.CreateSession("switched")
+	session.SetModel("qwen", "qwen-max")
+	session.SetThinkingLevel("high")
+	session.SetLanguage("pt-BR")
+	if err := store.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	reloaded, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer reloaded.Close()
+	restored, _ := reloaded.GetSession("switched")
+	if provider, model := restored.GetModel(); provider != "qwen" || model != "qwen-max" {
+		t.Errorf("GetModel after reload = %s/%s, want qwen/qwen-max", provider, model)
+	}
+	if level := restored.GetThinkingLevel(); level != "high" {
+		t.Errorf("GetThinkingLevel after reload = %q, want high", level)
+	}
+	if lang := restored.GetLanguage(); lang != "pt-BR" {
+		t.Errorf("GetLanguage after reload = %q, want pt-BR", lang)
+	}
+}
+
 func TestDefaultSessionDBPath(t *testing.T) {
 	path := DefaultSessionDBPath()
 
@@ -319,3 +493,275 @@ func TestDefaultSessionDBPath(t *testing.T) {
 		t.Errorf("Expected filename 'sessions.db', got %q", filepath.Base(path))
 	}
 }
+
+func TestSessionFeedback(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	session, err := store.CreateSession("feedback-test")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	if _, err := session.RateLastExchange(1, ""); err == nil {
+		t.Error("Expected error rating a session without answers")
+	}
+
+	session.AddMessage(ai.Message{Role: "user", Content: "Hello"})
+	session.AddMessage(ai.Message{Role: "assistant", Content: "Hi there!"})
+	session.SetLastExchange(agent.Exchange{Provider: "deepseek", Model: "deepseek-chat"})
+
+	session.RateLastExchange(1, "")
+	fb, err := session.RateLastExchange(-1, "too vague") // Replaces the first rating
+	if err != nil {
+		t.Fatalf("RateLastExchange failed: %v", err)
+	}
+	if fb.MessageIndex != 1 || fb.Provider != "deepseek" {
+		t.Errorf("Unexpected feedback: %+v", fb)
+	}
+	if err := store.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	// Reopen the database to check persistence
+	reopened, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer reopened.Close()
+
+	retrieved, found := reopened.GetSession("feedback-test")
+	if !found {
+		t.Fatal("Expected to find saved session")
+	}
+	feedback := retrieved.GetFeedback()
+	if len(feedback) != 1 || feedback[0].Rating != -1 || feedback[0].Comment != "too vague" {
+		t.Errorf("Unexpected persisted feedback: %+v", feedback)
+	}
+
+	totals, err := reopened.FeedbackTotals()
+	if err != nil {
+		t.Fatalf("FeedbackTotals failed: %v", err)
+	}
+	if c := totals["deepseek/deepseek-chat"]; c == nil || c.Bad != 1 || c.Good != 0 {
+		t.Errorf("Unexpected totals: %+v", c)
+	}
+}
+
+func TestSessionSearch(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	alpha, _ := store.CreateSession("alpha")
+	alpha.SetTitle("Fix circuit breaker flapping")
+	alpha.AddMessage(ai.Message{Role: "user", Content: "The circuit breaker opens on every timeout"})
+	alpha.AddMessage(ai.Message{Role: "tool", Content: "postgres migration log"}) // Tool output is not indexed
+	alpha.AddMessage(ai.Message{Role: "assistant", Content: "Raise the failure threshold."})
+	if err := store.SaveSession(alpha); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	beta, _ := store.CreateSession("beta")
+	beta.AddMessage(ai.Message{Role: "user", Content: "Plan the Postgres migration"})
+	if err := store.SaveSession(beta); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	results, err := store.Search("circuit breaker", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].SessionID != "alpha" || results[0].Title != "Fix circuit breaker flapping" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if results[0].Matches != 2 || !strings.Contains(results[0].Snippet, "[") {
+		t.Errorf("Expected title and message matches with highlighted snippet, got %+v", results[0])
+	}
+
+	results, _ = store.Search("postgres migration", 10)
+	if len(results) != 1 || results[0].SessionID != "beta" {
+		t.Errorf("Expected only beta (tool output not searchable), got %+v", results)
+	}
+
+	if _, err := store.Search("  ", 10); err == nil {
+		t.Error("Expected error for empty query")
+	}
+
+	// Titles, tags and project survive a reload
+	alpha.AddTags("backend", "infra")
+	alpha.SetProject("kube-zen/zen-claw")
+	if err := store.SaveSession(alpha); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	reopened, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer reopened.Close()
+	s, _ := reopened.GetSession("alpha")
+	if s == nil || s.GetTitle() != "Fix circuit breaker flapping" {
+		t.Fatal("Expected title to persist")
+	}
+	if !s.HasTag("infra") || s.GetProject() != "kube-zen/zen-claw" {
+		t.Errorf("Expected tags and project to persist, got %v %q", s.GetTags(), s.GetProject())
+	}
+}
+
+func TestHeuristicTitle(t *testing.T) {
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{"fix the login bug\nhere is the stack trace...", "fix the login bug"},
+		{`  "Why does the build fail?"  `, "Why does the build fail?"},
+		{"Refactor the session store so that transcripts are searchable across every session we keep", "Refactor the session store so that transcripts are…"},
+	}
+	for _, tt := range tests {
+		if got := heuristicTitle(tt.question); got != tt.want {
+			t.Errorf("heuristicTitle(%q) = %q, want %q", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	h := newRunHistory(store)
+	if _, err := h.record(types.RunRecord{Kind: "chat"}); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("unknown kind: err = %v", err)
+	}
+	start := time.Now().Add(-time.Minute)
+	consensusRun, err := h.record(types.RunRecord{
+		Kind: types.RunConsensus, Task: "design", StartedAt: start, DurationMs: 4000, CostUSD: 0.03,
+		Calls: []types.RunCall{
+			{Provider: "deepseek", Role: "worker", CostUSD: 0.01, Score: 8},
+			{Provider: "qwen", Role: "worker", CostUSD: 0.01, Score: 6},
+			{Provider: "deepseek", Role: "arbiter", CostUSD: 0.01},
+		},
+	})
+	if err != nil || consensusRun.ID == "" || consensusRun.Status != types.RunCompleted {
+		t.Fatalf("record = %+v, %v", consensusRun, err)
+	}
+	h.record(types.RunRecord{
+		Kind: types.RunFabric, Status: types.RunFailed, StartedAt: start.Add(time.Second), DurationMs: 1000,
+		Calls: []types.RunCall{{Provider: "qwen", Role: "coordinator", Error: "timeout"}},
+	})
+
+	// Reloaded from the database
+	h = newRunHistory(store)
+	if runs := h.list("", 0); len(runs) != 2 || runs[0].Kind != types.RunFabric {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if runs := h.list(types.RunConsensus, 10); len(runs) != 1 || len(runs[0].Calls) != 3 {
+		t.Errorf("consensus runs = %+v", runs)
+	}
+	if _, ok := h.get(consensusRun.ID); !ok {
+		t.Error("run not found by ID")
+	}
+
+	s := h.summary()
+	if s.Total != 2 || s.ByKind[types.RunFabric].Failed != 1 || s.ByKind[types.RunConsensus].AvgDurationMs != 4000 {
+		t.Errorf("summary = %+v", s)
+	}
+	if p := s.Providers[0]; p.Provider != "deepseek" || p.Calls != 2 || p.AvgScore != 8 {
+		t.Errorf("top provider = %+v", p)
+	}
+	if p := s.Providers[1]; p.Provider != "qwen" || p.Errors != 1 || p.AvgScore != 6 {
+		t.Errorf("second provider = %+v", p)
+	}
+}
+
+func TestLoadRepairsTranscript(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A run that crashed between the call and its result
+	session := agent.NewSession("crashed")
+	session.AddMessage(ai.Message{Role: "user", Content: "read a.txt"})
+	session.AddMessage(ai.Message{Role: "assistant", ToolCalls: []ai.ToolCall{{ID: "call_1", Name: "read_file"}}})
+	session.AddMessage(ai.Message{Role: "user", Content: "hello?"})
+	if err := store.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewSessionStore(&SessionStoreConfig{DBPath: store.GetDBPath(), MaxSessions: 3})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer reopened.Close()
+	if repairs := reopened.LoadRepairs("crashed"); len(repairs) != 1 || repairs[0].Kind != agent.IssueMissingResult {
+		t.Errorf("repairs = %+v", repairs)
+	}
+	s, _ := reopened.GetSession("crashed")
+	if issues := agent.CheckTranscript(s.GetMessages()); len(issues) != 0 {
+		t.Errorf("loaded session still has issues: %+v", issues)
+	}
+	if messages := s.GetMessages(); len(messages) != 4 || messages[2].Role != "tool" {
+		t.Errorf("messages = %+v", messages)
+	}
+}
+
+func TestUsagePersists(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	for _, rec := range []UsageRecord{
+		{At: now.Add(-30 * 24 * time.Hour), Provider: "openai", Model: "gpt-4o", Project: "acme/api", InputTokens: 1000, OutputTokens: 100, CostUSD: 1},
+		{At: now.Add(-24 * time.Hour), Provider: "deepseek", Model: "deepseek-chat", Project: "acme/api", InputTokens: 2000, OutputTokens: 200, CostUSD: 0.5},
+		{At: now, Provider: "deepseek", Model: "deepseek-chat", Project: "acme/web", InputTokens: 500, OutputTokens: 50, CostUSD: 0.25},
+		{At: now, Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 10, CostUSD: 0.1},
+	} {
+		if err := store.SaveUsage(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := store.UsageReport(now.Add(-7 * 24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.Calls != 3 || report.Total.InputTokens != 2600 {
+		t.Errorf("total = %+v, want 3 calls and 2600 input tokens (the old call excluded)", report.Total)
+	}
+	if len(report.ByModel) != 2 || report.ByModel[0].Model != "deepseek-chat" || report.ByModel[0].Calls != 2 {
+		t.Errorf("by model = %+v, want deepseek-chat (2 calls) first", report.ByModel)
+	}
+	if len(report.ByProject) != 3 || report.ByProject[0].Project != "acme/api" {
+		t.Errorf("by project = %+v", report.ByProject)
+	}
+	if len(report.ByDay) != 2 || report.ByDay[1].Day != now.Format("2006-01-02") {
+		t.Errorf("by day = %+v", report.ByDay)
+	}
+
+	if err := store.SaveUsage(UsageRecord{At: now, Provider: "openai", Model: "gpt-4o", SessionID: "s1", InputTokens: 10, OutputTokens: 1, CostUSD: 0.02}); err != nil {
+		t.Fatal(err)
+	}
+	if usage, err := store.SessionUsage("s1"); err != nil || usage.Calls != 1 || usage.CostUSD != 0.02 {
+		t.Errorf("session usage = %+v, %v", usage, err)
+	}
+
+	// Counters survive a restart
+	m := &Metrics{RequestsTotal: 5, RateLimitHits: 1}
+	if err := store.SaveCounters(m.counters()); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Counters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := &Metrics{RequestsTotal: 2}
+	restarted.restore(saved)
+	if restarted.RequestsTotal != 7 || restarted.RateLimitHits != 1 {
+		t.Errorf("restored metrics = %+v", restarted)
+	}
+
+	// Today's project spend keeps counting against budgets
+	budgets := newProjectBudgets(map[string]float64{"acme/web": 1})
+	today, _ := store.UsageReport(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	budgets.restore(today.ByProject)
+	if spend := budgets.snapshot(); len(spend) != 1 || spend[0].SpentUSD != 0.25 {
+		t.Errorf("restored spend = %+v", spend)
+	}
+}
@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"github.com/neves/zen-claw/internal/config"
+	"github.com/neves/zen-claw/internal/tickets"
+)
+
+// ticketWorkspaces creates the trackers of the ticket tools
+func ticketWorkspaces(cfg config.TicketsConfig) *tickets.Workspaces {
+	var list []*tickets.Workspace
+	for name, wc := range cfg.Workspaces {
+		ws := &tickets.Workspace{Name: name, Kind: wc.Kind, Projects: wc.Projects}
+		switch wc.Kind {
+		case "jira":
+			ws.Tracker = tickets.NewJira(wc.URL, wc.Email, wc.GetToken())
+		case "linear":
+			linear := tickets.NewLinear(wc.GetToken())
+			if wc.URL != "" {
+				linear.APIURL = wc.URL
+			}
+			ws.Tracker = linear
+		default:
+			continue // Rejected by config validation
+		}
+		list = append(list, ws)
+	}
+	return tickets.NewWorkspaces(cfg.Default, list...)
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// maxTitleLen caps session titles (in runes)
+const maxTitleLen = 60
+
+// ensureTitle gives a session a title after its first exchange. A title built
+// from the first question is set right away; persisted sessions then get a
+// better one from the model in the background.
+func (s *AgentService) ensureTitle(session *agent.Session, providerName, modelName string) {
+	if session.GetTitle() != "" {
+		return
+	}
+	question, answer := firstExchange(session.GetMessages())
+	if question == "" {
+		return
+	}
+	session.SetTitle(heuristicTitle(question))
+
+	if !isNamedSession(session.ID) || s.sessionStore == nil {
+		return // Unsaved sessions aren't searchable, don't spend a request
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		caller := &GatewayAICaller{
+			aiRouter:  s.aiRouter,
+			provider:  providerName,
+			project:   session.GetProject(),
+			sessionID: session.ID,
+			budgets:   s.budgets,
+			usage:     s.usage,
+			scrubber:  s.scrubberFor(session.GetProject()),
+		}
+		resp, err := caller.Chat(ctx, ai.ChatRequest{
+			Model: modelName,
+			Messages: []ai.Message{
+				{Role: "system", Content: "Write a title of at most 6 words for this conversation. Reply with the title only - no quotes, no trailing punctuation."},
+				{Role: "user", Content: "Question:\n" + truncateRunes(question, 1500) + "\n\nAnswer:\n" + truncateRunes(answer, 1500)},
+			},
+			Temperature: 0.3,
+			MaxTokens:   30,
+		})
+		if err != nil {
+			log.Printf("[AgentService] Title generation failed for %s: %v", session.ID, err)
+			return
+		}
+		title := cleanTitle(resp.Content)
+		if title == "" {
+			return
+		}
+		session.SetTitle(title)
+		if err := s.sessionStore.SaveSession(session); err != nil {
+			log.Printf("Warning: Failed to save title for session %s: %v", session.ID, err)
+		}
+	}()
+}
+
+// firstExchange returns the first user message and the first final answer
+func firstExchange(messages []ai.Message) (question, answer string) {
+	for _, m := range messages {
+		switch {
+		case m.Role == "user" && question == "":
+			question = m.Content
+		case m.Role == "assistant" && question != "" && len(m.ToolCalls) == 0 && m.Content != "":
+			return question, m.Content
+		}
+	}
+	return question, ""
+}
+
+// heuristicTitle shortens the first line of a question to a title
+func heuristicTitle(question string) string {
+	line := strings.TrimSpace(question)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return cleanTitle(line)
+}
+
+// cleanTitle normalizes a title: one line, no quotes or trailing punctuation,
+// at most maxTitleLen runes cut at a word boundary
+func cleanTitle(title string) string {
+	title = oneLine(title)
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(title, " \"'`*#.:")
+	runes := []rune(title)
+	if len(runes) <= maxTitleLen {
+		return title
+	}
+	cut := string(runes[:maxTitleLen])
+	if i := strings.LastIndexByte(cut, ' '); i > maxTitleLen/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:-") + "…"
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"log"
+
+	"github.com/neves/zen-claw/internal/agent"
+)
+
+// DoctorReport is the result of checking a session's transcript
+type DoctorReport struct {
+	ID             string                  `json:"id"`
+	Issues         []agent.TranscriptIssue `json:"issues"`
+	Repaired       bool                    `json:"repaired"`                   // Issues were fixed and the session saved
+	RepairedOnLoad []agent.TranscriptIssue `json:"repaired_on_load,omitempty"` // Fixed when the gateway loaded the session
+}
+
+// DoctorSession checks a session's history for tool calls and results
+// providers would reject, and with repair fixes and saves it. A session
+// running a turn can't be checked (CONFLICT): its latest calls are still
+// waiting for their results.
+func (s *AgentService) DoctorSession(sessionID string, repair bool) (*DoctorReport, error) {
+	session, err := s.findSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	release, err := s.turns.acquire(context.Background(), sessionID, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	report := &DoctorReport{ID: sessionID}
+	if s.sessionStore != nil {
+		report.RepairedOnLoad = s.sessionStore.LoadRepairs(sessionID)
+	}
+	if !repair {
+		report.Issues = agent.CheckTranscript(session.GetMessages())
+	} else if report.Issues = session.RepairTranscript(""); len(report.Issues) > 0 {
+		log.Printf("[AgentService] Repaired %d transcript issue(s) in session %s", len(report.Issues), sessionID)
+		if isNamedSession(sessionID) && s.sessionStore != nil {
+			if err := s.sessionStore.SaveSession(session); err != nil {
+				return nil, err
+			}
+		}
+		report.Repaired = true
+	}
+	if report.Issues == nil {
+		report.Issues = []agent.TranscriptIssue{}
+	}
+	return report, nil
+}
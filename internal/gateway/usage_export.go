@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/config"
+)
+
+// Usage leaves the gateway two ways: `zen-claw usage export` writes the
+// records as CSV or JSON, and usage.push sends them on an interval, either
+// the records added since the last push (json, csv: a webhook) or running
+// totals per provider/model/project (prometheus: a pushgateway).
+
+// usagePushBatch bounds the records sent in one webhook request
+const usagePushBatch = 1000
+
+// usagePushedCounter persists the ID of the last record pushed to a webhook
+const usagePushedCounter = "usage_pushed_id"
+
+// usageCSVHeader is the header row of exported usage
+var usageCSVHeader = []string{"at", "provider", "model", "project", "session_id", "input_tokens", "output_tokens", "cost_usd"}
+
+// WriteUsageCSV writes usage records as CSV with a header row
+func WriteUsageCSV(w io.Writer, records []UsageRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(usageCSVHeader); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := cw.Write([]string{
+			rec.At.UTC().Format(time.RFC3339),
+			rec.Provider,
+			rec.Model,
+			rec.Project,
+			rec.SessionID,
+			strconv.Itoa(rec.InputTokens),
+			strconv.Itoa(rec.OutputTokens),
+			strconv.FormatFloat(rec.CostUSD, 'f', 6, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteUsagePrometheus writes usage totals in the Prometheus text format,
+// labeled by provider, model and project
+func WriteUsagePrometheus(w io.Writer, rows []UsageRow) {
+	metrics := []struct {
+		name, help string
+		value      func(UsageRow) string
+	}{
+		{"zenclaw_usage_calls_total", "Model calls", func(r UsageRow) string { return strconv.FormatInt(r.Calls, 10) }},
+		{"zenclaw_usage_input_tokens_total", "Estimated input tokens", func(r UsageRow) string { return strconv.FormatInt(r.InputTokens, 10) }},
+		{"zenclaw_usage_output_tokens_total", "Estimated output tokens", func(r UsageRow) string { return strconv.FormatInt(r.OutputTokens, 10) }},
+		{"zenclaw_usage_cost_usd_total", "Estimated cost in USD", func(r UsageRow) string { return strconv.FormatFloat(r.CostUSD, 'f', 6, 64) }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", m.name)
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s{provider=%q,model=%q,project=%q} %s\n", m.name, row.Provider, row.Model, row.Project, m.value(row))
+		}
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// USAGE PUSHER
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// usagePusher sends recorded usage to usage.push.url in the background
+type usagePusher struct {
+	store    *SessionStore
+	cfg      config.UsagePushConfig
+	client   *http.Client
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// newUsagePusher starts pushing usage. Returns nil when no URL is configured.
+func newUsagePusher(store *SessionStore, cfg config.UsagePushConfig) *usagePusher {
+	if store == nil || cfg.URL == "" {
+		return nil
+	}
+	interval := time.Duration(cfg.IntervalMins) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	p := &usagePusher{
+		store:    store,
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *usagePusher) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.push(context.Background()); err != nil {
+				log.Printf("[Usage] Push to %s failed: %v", p.cfg.URL, err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// push sends the usage due: new records to a webhook (the last one sent is
+// remembered, so nothing is lost to a failed push or a restart), or all
+// totals to a pushgateway
+func (p *usagePusher) push(ctx context.Context) error {
+	if p.cfg.GetFormat() == "prometheus" {
+		rows, err := p.store.UsageTotals()
+		if err != nil {
+			return err
+		}
+		var body bytes.Buffer
+		WriteUsagePrometheus(&body, rows)
+		return p.send(ctx, http.MethodPut, p.pushgatewayURL(), "text/plain; version=0.0.4", body.Bytes())
+	}
+
+	counters, err := p.store.Counters()
+	if err != nil {
+		return err
+	}
+	lastID := counters[usagePushedCounter]
+	for {
+		records, err := p.store.UsageRecords(time.Time{}, lastID, usagePushBatch)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		var body bytes.Buffer
+		contentType := "application/json"
+		if p.cfg.GetFormat() == "csv" {
+			contentType = "text/csv"
+			err = WriteUsageCSV(&body, records)
+		} else {
+			err = json.NewEncoder(&body).Encode(map[string]any{"records": records})
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.send(ctx, http.MethodPost, p.cfg.URL, contentType, body.Bytes()); err != nil {
+			return err
+		}
+
+		lastID = records[len(records)-1].ID
+		if err := p.store.SaveCounters(map[string]int64{usagePushedCounter: lastID}); err != nil {
+			return err
+		}
+		if len(records) < usagePushBatch {
+			return nil
+		}
+	}
+}
+
+// pushgatewayURL returns the URL the totals are PUT to: the configured one
+// when it already names a job, else {url}/metrics/job/{job}
+func (p *usagePusher) pushgatewayURL() string {
+	if strings.Contains(p.cfg.URL, "/metrics/job/") {
+		return p.cfg.URL
+	}
+	job := p.cfg.Job
+	if job == "" {
+		job = "zen_claw"
+	}
+	return strings.TrimSuffix(p.cfg.URL, "/") + "/metrics/job/" + url.PathEscape(job)
+}
+
+// send makes one push request; any non-2xx status is an error
+func (p *usagePusher) send(ctx context.Context, method, target, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range p.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Close stops the pusher
+func (p *usagePusher) Close() {
+	close(p.stop)
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neves/zen-claw/internal/config"
+)
+
+func TestWriteUsageCSV(t *testing.T) {
+	var b strings.Builder
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := WriteUsageCSV(&b, []UsageRecord{
+		{At: at, Provider: "openai", Model: "gpt-4o", Project: "acme/api", SessionID: "s1", InputTokens: 1000, OutputTokens: 100, CostUSD: 0.0035},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "at,provider,model,project,session_id,input_tokens,output_tokens,cost_usd\n" +
+		"2026-03-01T12:00:00Z,openai,gpt-4o,acme/api,s1,1000,100,0.003500\n"
+	if b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+}
+
+func TestUsagePusher(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	var mu sync.Mutex
+	var received []UsageRecord
+	var methods, paths []string
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, r.Method)
+		paths = append(paths, r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Content-Type") == "application/json" {
+			var payload struct {
+				Records []UsageRecord `json:"records"`
+			}
+			json.Unmarshal(body, &payload)
+			received = append(received, payload.Records...)
+		}
+	}))
+	defer srv.Close()
+
+	save := func(project string) {
+		if err := store.SaveUsage(UsageRecord{At: time.Now(), Provider: "deepseek", Model: "deepseek-chat", Project: project, InputTokens: 100, OutputTokens: 10, CostUSD: 0.01}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Webhook: each push sends only the records added since the last one
+	p := &usagePusher{store: store, cfg: config.UsagePushConfig{URL: srv.URL}, client: srv.Client()}
+	save("acme/api")
+	save("acme/web")
+	if err := p.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	save("acme/api")
+	if err := p.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 3 || received[2].Project != "acme/api" {
+		t.Errorf("received = %+v, want the 3 records once each", received)
+	}
+	if len(methods) != 2 {
+		t.Errorf("requests = %d, want 2 (nothing new on the third push)", len(methods))
+	}
+
+	// Pushgateway: totals by provider/model/project
+	p = &usagePusher{store: store, cfg: config.UsagePushConfig{URL: srv.URL, Format: "prometheus"}, client: srv.Client()}
+	if err := p.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	last := len(methods) - 1
+	if methods[last] != http.MethodPut || paths[last] != "/metrics/job/zen_claw" {
+		t.Errorf("pushgateway request = %s %s", methods[last], paths[last])
+	}
+	if !strings.Contains(bodies[last], `zenclaw_usage_calls_total{provider="deepseek",model="deepseek-chat",project="acme/api"} 2`) {
+		t.Errorf("pushgateway body = %s", bodies[last])
+	}
+}
@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/neves/zen-claw/internal/cost"
+)
+
+// Every model call's estimated usage is kept in the session database, and so
+// are the gateway's request counters. A restarted gateway picks up its usage
+// summary, today's project spend (budgets keep holding) and its /metrics
+// counters where it left them, and `zen-claw usage report` breaks cost down
+// over any period.
+
+// countersSaveInterval is how often the request counters are persisted
+// (and on shutdown)
+const countersSaveInterval = time.Minute
+
+// UsageRecord is one model call's estimated usage
+type UsageRecord struct {
+	ID           int64     `json:"id,omitempty"` // Set when read back from the store
+	At           time.Time `json:"at"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Project      string    `json:"project,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+}
+
+// UsageRow is usage summed over a group of calls; the fields not grouped by
+// are empty
+type UsageRow struct {
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Project      string  `json:"project,omitempty"`
+	Day          string  `json:"day,omitempty"` // YYYY-MM-DD, local time
+	Calls        int64   `json:"calls"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// UsageReport is the usage recorded since a time, most expensive first
+// (days in order)
+type UsageReport struct {
+	Since     time.Time  `json:"since"`
+	Total     UsageRow   `json:"total"`
+	ByModel   []UsageRow `json:"by_model"`
+	ByProject []UsageRow `json:"by_project"`
+	ByDay     []UsageRow `json:"by_day"`
+}
+
+// usageLog persists the usage of model calls
+type usageLog struct {
+	store *SessionStore
+}
+
+// record saves a call's usage (nil log: not persisted)
+func (l *usageLog) record(rec UsageRecord) {
+	if l == nil || l.store == nil {
+		return
+	}
+	if err := l.store.SaveUsage(rec); err != nil {
+		log.Printf("[Usage] Failed to persist usage: %v", err)
+	}
+}
+
+// SessionUsage returns the usage recorded for a session (zero without a
+// session store)
+func (s *AgentService) SessionUsage(sessionID string) (UsageRow, error) {
+	if s.sessionStore == nil {
+		return UsageRow{}, nil
+	}
+	return s.sessionStore.SessionUsage(sessionID)
+}
+
+// restoreUsage seeds the usage summary and today's project spend from the
+// usage recorded before the gateway started
+func (s *AgentService) restoreUsage() {
+	if s.sessionStore == nil {
+		return
+	}
+	all, err := s.sessionStore.UsageReport(time.Time{})
+	if err != nil {
+		log.Printf("Warning: Failed to load usage history: %v", err)
+		return
+	}
+	for _, row := range all.ByModel {
+		s.aiRouter.restoreUsage(row)
+	}
+
+	now := time.Now()
+	today, err := s.sessionStore.UsageReport(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		log.Printf("Warning: Failed to load today's usage: %v", err)
+		return
+	}
+	s.budgets.restore(today.ByProject)
+	if all.Total.Calls > 0 {
+		log.Printf("[Usage] Restored %d recorded model calls ($%.4f, $%.4f today)", all.Total.Calls, all.Total.CostUSD, today.Total.CostUSD)
+	}
+}
+
+// restoreUsage adds calls made before a restart to the usage summary
+func (r *AIRouter) restoreUsage(row UsageRow) {
+	r.usageMu.Lock()
+	defer r.usageMu.Unlock()
+	r.usage.Restore(row.Provider, row.Model, int(row.Calls), int(row.InputTokens), int(row.OutputTokens))
+}
+
+// restore sets today's spend from calls made before a restart
+func (b *projectBudgets) restore(rows []UsageRow) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	for _, row := range rows {
+		if row.Project != "" {
+			b.spent[strings.ToLower(row.Project)] += row.CostUSD
+		}
+	}
+}
+
+// counters returns the request counters by name
+func (m *Metrics) counters() map[string]int64 {
+	return map[string]int64{
+		"requests_total":  atomic.LoadInt64(&m.RequestsTotal),
+		"requests_chat":   atomic.LoadInt64(&m.RequestsChat),
+		"requests_stream": atomic.LoadInt64(&m.RequestsStream),
+		"requests_ws":     atomic.LoadInt64(&m.RequestsWS),
+		"errors_4xx":      atomic.LoadInt64(&m.Errors4xx),
+		"errors_5xx":      atomic.LoadInt64(&m.Errors5xx),
+		"rate_limit_hits": atomic.LoadInt64(&m.RateLimitHits),
+	}
+}
+
+// restore adds counts saved by an earlier run of the gateway
+func (m *Metrics) restore(saved map[string]int64) {
+	for name, counter := range map[string]*int64{
+		"requests_total":  &m.RequestsTotal,
+		"requests_chat":   &m.RequestsChat,
+		"requests_stream": &m.RequestsStream,
+		"requests_ws":     &m.RequestsWS,
+		"errors_4xx":      &m.Errors4xx,
+		"errors_5xx":      &m.Errors5xx,
+		"rate_limit_hits": &m.RateLimitHits,
+	} {
+		atomic.AddInt64(counter, saved[name])
+	}
+}
+
+// restoreCounters loads the request counters saved by an earlier run
+func (s *Server) restoreCounters() {
+	store := s.agentService.sessionStore
+	if store == nil {
+		return
+	}
+	saved, err := store.Counters()
+	if err != nil {
+		log.Printf("Warning: Failed to load gateway counters: %v", err)
+		return
+	}
+	s.metrics.restore(saved)
+}
+
+// saveCounters persists the request counters
+func (s *Server) saveCounters() {
+	store := s.agentService.sessionStore
+	if store == nil {
+		return
+	}
+	if err := store.SaveCounters(s.metrics.counters()); err != nil {
+		log.Printf("Warning: Failed to save gateway counters: %v", err)
+	}
+}
+
+// persistCounters saves the request counters every countersSaveInterval
+// until stop is closed
+func (s *Server) persistCounters(stop <-chan struct{}) {
+	ticker := time.NewTicker(countersSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.saveCounters()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// stopPersistingCounters stops the periodic save and saves the counters one
+// last time
+func (s *Server) stopPersistingCounters() {
+	s.mu.Lock()
+	stop := s.stopCounters
+	s.stopCounters = nil
+	s.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	s.saveCounters()
+}
+
+// usageRecord prices a call for the usage log
+func usageRecord(provider, model, project, sessionID string, inputTokens, outputTokens int) UsageRecord {
+	return UsageRecord{
+		At:           time.Now(),
+		Provider:     provider,
+		Model:        model,
+		Project:      project,
+		SessionID:    sessionID,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CostUSD:      cost.CalculateUSD(provider, model, inputTokens, outputTokens),
+	}
+}
@@ -3,6 +3,8 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
@@ -10,6 +12,9 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/types"
+	"github.com/neves/zen-claw/internal/version"
 )
 
 var upgrader = websocket.Upgrader{
@@ -22,9 +27,10 @@ var upgrader = websocket.Upgrader{
 
 // WSMessage represents a WebSocket message
 type WSMessage struct {
-	Type string          `json:"type"`
-	ID   string          `json:"id,omitempty"`   // Message ID for request/response matching
-	Data json.RawMessage `json:"data,omitempty"` // Payload
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`       // Message ID for request/response matching
+	EventID string          `json:"event_id,omitempty"` // Chat stream position, for resuming after a reconnect
+	Data    json.RawMessage `json:"data,omitempty"`     // Payload
 }
 
 // WSChatRequest is the chat request sent over WebSocket
@@ -35,17 +41,27 @@ type WSChatRequest struct {
 	Provider   string `json:"provider,omitempty"`
 	Model      string `json:"model,omitempty"`
 	MaxSteps   int    `json:"max_steps,omitempty"`
+	Language   string `json:"language,omitempty"` // Answer language, e.g. pt-BR ("default" resets)
+
+	Env    map[string]string  `json:"env,omitempty"`    // Session environment (values may be secret refs)
+	Params *types.ModelParams `json:"params,omitempty"` // Sampling overrides (temperature, max_tokens, top_p)
+
+	Tags    []string `json:"tags,omitempty"`    // Labels added to the session
+	Project string   `json:"project,omitempty"` // Overrides the project derived from the git remote
+	Pin     bool     `json:"pin,omitempty"`     // Keep this message in context verbatim
+	Queue   bool     `json:"queue,omitempty"`   // Wait for the session's running turn instead of failing
 }
 
 // WSClient represents a connected WebSocket client
 type WSClient struct {
-	conn         *websocket.Conn
-	server       *Server
-	send         chan []byte
-	done         chan struct{}
-	mu           sync.Mutex
-	cancelFunc   context.CancelFunc // Cancel current task
-	currentMsgID string             // ID of current task
+	conn           *websocket.Conn
+	server         *Server
+	clientAddr     string // For per-client request limits
+	send           chan []byte
+	done           chan struct{}
+	mu             sync.Mutex
+	currentMsgID   string // ID of current task
+	currentSession string // Session of the current task, for cancelling it
 }
 
 // NewWSClient creates a new WebSocket client handler
@@ -66,7 +82,7 @@ func (c *WSClient) Run() {
 	// Send welcome message
 	c.sendMessage(WSMessage{
 		Type: "connected",
-		Data: json.RawMessage(`{"message":"Connected to Zen Claw WebSocket","version":"0.1.0"}`),
+		Data: json.RawMessage(fmt.Sprintf(`{"message":"Connected to Zen Claw WebSocket","version":%q}`, version.Version)),
 	})
 
 	// Read messages
@@ -80,7 +96,7 @@ func (c *WSClient) readPump() {
 		close(c.done)
 	}()
 
-	c.conn.SetReadLimit(512 * 1024) // 512KB max message size
+	c.conn.SetReadLimit(c.server.config.Gateway.Limits.GetMaxBodyBytes())
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Minute))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Minute))
@@ -138,7 +154,7 @@ func (c *WSClient) writePump() {
 func (c *WSClient) handleMessage(message []byte) {
 	var msg WSMessage
 	if err := json.Unmarshal(message, &msg); err != nil {
-		c.sendError("", "Invalid JSON: "+err.Error())
+		c.sendError("", types.ErrInvalidArgument, "Invalid JSON: "+err.Error())
 		return
 	}
 
@@ -146,6 +162,9 @@ func (c *WSClient) handleMessage(message []byte) {
 	case "chat":
 		c.handleChat(msg)
 
+	case "resume":
+		c.handleResume(msg)
+
 	case "cancel":
 		c.handleCancel(msg)
 
@@ -159,7 +178,7 @@ func (c *WSClient) handleMessage(message []byte) {
 		c.handleSession(msg)
 
 	default:
-		c.sendError(msg.ID, "Unknown message type: "+msg.Type)
+		c.sendError(msg.ID, types.ErrInvalidArgument, "Unknown message type: "+msg.Type)
 	}
 }
 
@@ -167,12 +186,12 @@ func (c *WSClient) handleMessage(message []byte) {
 func (c *WSClient) handleChat(msg WSMessage) {
 	var req WSChatRequest
 	if err := json.Unmarshal(msg.Data, &req); err != nil {
-		c.sendError(msg.ID, "Invalid chat request: "+err.Error())
+		c.sendError(msg.ID, types.ErrInvalidArgument, "Invalid chat request: "+err.Error())
 		return
 	}
 
 	if req.UserInput == "" {
-		c.sendError(msg.ID, "user_input is required")
+		c.sendError(msg.ID, types.ErrInvalidArgument, "user_input is required")
 		return
 	}
 
@@ -180,16 +199,6 @@ func (c *WSClient) handleChat(msg WSMessage) {
 		req.WorkingDir = "."
 	}
 
-	// Cancel any existing task
-	c.mu.Lock()
-	if c.cancelFunc != nil {
-		c.cancelFunc()
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	c.cancelFunc = cancel
-	c.currentMsgID = msg.ID
-	c.mu.Unlock()
-
 	// Convert to gateway ChatRequest
 	chatReq := ChatRequest{
 		SessionID:  req.SessionID,
@@ -198,85 +207,212 @@ func (c *WSClient) handleChat(msg WSMessage) {
 		Provider:   req.Provider,
 		Model:      req.Model,
 		MaxSteps:   req.MaxSteps,
+		Language:   req.Language,
+		Env:        req.Env,
+		Params:     req.Params,
+		Tags:       req.Tags,
+		Project:    req.Project,
+		Pin:        req.Pin,
+		Queue:      req.Queue,
+	}
+	release, err := c.server.limits.admit(c.clientAddr, chatReq)
+	if err != nil {
+		c.sendError(msg.ID, types.CodeOf(err), err.Error())
+		return
+	}
+
+	// Cancel any existing task
+	c.mu.Lock()
+	if c.currentSession != "" {
+		c.server.agentService.CancelSession(c.currentSession)
+		// The replaced turn holds the session until it stops
+		if c.currentSession == chatReq.SessionID {
+			chatReq.Queue = true
+		}
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	c.currentMsgID = msg.ID
+	c.currentSession = chatReq.SessionID // New sessions get their ID from the start event
+	c.mu.Unlock()
 
-	// Run in goroutine
+	// Run in goroutine, publishing to a stream this connection follows (and
+	// a new one may resume after a reconnect)
+	stream := c.server.streams.create()
 	go func() {
+		defer release()
 		defer func() {
 			c.mu.Lock()
 			if c.currentMsgID == msg.ID {
-				c.cancelFunc = nil
 				c.currentMsgID = ""
+				c.currentSession = ""
 			}
 			c.mu.Unlock()
 			cancel()
 		}()
 
+		// Outside the handler, RecoveryMiddleware can't catch a panic
+		defer func() {
+			if r := recover(); r != nil {
+				err := panicError("websocket chat", r)
+				stream.publish(errorMessage(msg.ID, types.CodeOf(err), err.Error()), true)
+			}
+		}()
+
 		// Send progress events via WebSocket
-		resp, err := c.server.agentService.ChatWithProgress(ctx, chatReq, func(event map[string]interface{}) {
-			// Add message ID to event
-			eventWithID := make(map[string]interface{})
-			for k, v := range event {
-				eventWithID[k] = v
+		resp, err := c.server.agentService.ChatWithProgress(ctx, chatReq, func(event types.ProgressEvent) {
+			if event.Type == "start" {
+				c.mu.Lock()
+				if c.currentMsgID == msg.ID {
+					c.currentSession = event.SessionID
+				}
+				c.mu.Unlock()
 			}
-			eventWithID["id"] = msg.ID
 
-			eventJSON, _ := json.Marshal(eventWithID)
-			c.sendMessage(WSMessage{
+			// Add message ID to event
+			eventJSON, _ := json.Marshal(struct {
+				types.ProgressEvent
+				ID string `json:"id"`
+			}{event, msg.ID})
+			stream.publish(WSMessage{
 				Type: "progress",
 				ID:   msg.ID,
 				Data: eventJSON,
-			})
+			}, false)
 		})
 
 		if err != nil {
-			c.sendError(msg.ID, err.Error())
+			stream.publish(errorMessage(msg.ID, types.CodeOf(err), err.Error()), true)
+			return
+		}
+
+		if resp.ErrorCode == types.ErrCanceled {
+			cancelled, _ := json.Marshal(map[string]interface{}{
+				"message":      resp.Error,
+				"session_id":   resp.SessionID,
+				"session_info": resp.SessionInfo,
+			})
+			stream.publish(WSMessage{Type: "cancelled", ID: msg.ID, Data: cancelled}, true)
 			return
 		}
 
 		if resp.Error != "" {
-			c.sendError(msg.ID, resp.Error)
+			stream.publish(errorMessage(msg.ID, resp.ErrorCode, resp.Error), true)
 			return
 		}
 
 		// Send final result
-		resultData, _ := json.Marshal(map[string]interface{}{
+		result := map[string]interface{}{
 			"session_id":   resp.SessionID,
 			"result":       resp.Result,
 			"session_info": resp.SessionInfo,
-		})
+		}
+		if resp.Output != nil {
+			result["output"] = resp.Output
+		}
+		if resp.RecordID != "" {
+			result["record_id"] = resp.RecordID
+		}
+		if resp.StepLimit != nil {
+			result["step_limit"] = resp.StepLimit
+		}
+		if resp.Review != nil {
+			result["review"] = resp.Review
+		}
+		resultData, _ := json.Marshal(result)
 
-		c.sendMessage(WSMessage{
+		stream.publish(WSMessage{
 			Type: "result",
 			ID:   msg.ID,
 			Data: resultData,
-		})
+		}, true)
 	}()
+
+	go c.followStream(stream, 0)
+}
+
+// handleResume replays a chat stream after the event in last_event_id, then
+// follows it live; used after reconnecting
+func (c *WSClient) handleResume(msg WSMessage) {
+	var req struct {
+		LastEventID string `json:"last_event_id"`
+	}
+	if err := json.Unmarshal(msg.Data, &req); err != nil || req.LastEventID == "" {
+		c.sendError(msg.ID, types.ErrInvalidArgument, "last_event_id is required")
+		return
+	}
+	stream, seq, err := c.server.streams.lookup(req.LastEventID)
+	if err != nil {
+		c.sendError(msg.ID, types.CodeOf(err), err.Error())
+		return
+	}
+	go c.followStream(stream, seq)
 }
 
-// handleCancel cancels the current task
+// followStream sends a chat stream's messages after seq, with heartbeats
+// while it is quiet, until the final one or the connection closes
+func (c *WSClient) followStream(stream *eventStream, seq int) {
+	stream.follow(seq, c.done, func(ev streamEvent) error {
+		if ev.seq == 0 { // Notice, not a published message
+			return c.deliver(WSMessage{Type: "progress", Data: ev.data})
+		}
+		var msg WSMessage
+		if err := json.Unmarshal(ev.data, &msg); err != nil {
+			return err
+		}
+		msg.EventID = stream.eventID(ev.seq)
+		return c.deliver(msg)
+	}, func() error {
+		return c.deliver(WSMessage{Type: "heartbeat", Data: heartbeatEvent()})
+	})
+}
+
+// handleCancel cancels the current task, or the requests of the session in
+// data.session_id; the task ends with a "cancelled" message once the agent
+// reaches a safe point
 func (c *WSClient) handleCancel(msg WSMessage) {
-	c.mu.Lock()
-	if c.cancelFunc != nil {
-		c.cancelFunc()
-		c.sendMessage(WSMessage{
-			Type: "cancelled",
-			ID:   c.currentMsgID,
-			Data: json.RawMessage(`{"message":"Task cancelled"}`),
-		})
-	} else {
+	var req struct {
+		SessionID string `json:"session_id"`
+	}
+	if len(msg.Data) > 0 {
+		json.Unmarshal(msg.Data, &req)
+	}
+	if req.SessionID == "" {
+		c.mu.Lock()
+		req.SessionID = c.currentSession
+		c.mu.Unlock()
+	}
+
+	if req.SessionID == "" {
 		c.sendMessage(WSMessage{
 			Type: "info",
 			ID:   msg.ID,
 			Data: json.RawMessage(`{"message":"No task to cancel"}`),
 		})
+		return
 	}
-	c.mu.Unlock()
+	if _, err := c.server.agentService.CancelSession(req.SessionID); err != nil {
+		c.sendError(msg.ID, types.CodeOf(err), err.Error())
+		return
+	}
+	c.sendMessage(WSMessage{
+		Type: "info",
+		ID:   msg.ID,
+		Data: json.RawMessage(`{"message":"Cancelling: the task stops at the next safe point"}`),
+	})
 }
 
 // handleSessions lists all sessions
 func (c *WSClient) handleSessions(msg WSMessage) {
-	sessions := c.server.agentService.ListSessions()
+	var filter SessionFilter
+	if len(msg.Data) > 0 {
+		json.Unmarshal(msg.Data, &filter) // Optional {"tag": ..., "project": ...}
+	}
+	var sessions []agent.SessionStats
+	for _, stats := range c.server.agentService.ListSessions() {
+		if filter.Matches(stats) {
+			sessions = append(sessions, stats)
+		}
+	}
 
 	sessionsJSON, _ := json.Marshal(map[string]interface{}{
 		"sessions": sessions,
@@ -290,15 +426,17 @@ func (c *WSClient) handleSessions(msg WSMessage) {
 	})
 }
 
-// handleSession gets or deletes a session
+// handleSession gets, deletes or rates a session, or reports its usage
 func (c *WSClient) handleSession(msg WSMessage) {
 	var req struct {
 		SessionID string `json:"session_id"`
-		Action    string `json:"action"` // "get" or "delete"
+		Action    string `json:"action"`            // "get", "delete", "feedback" or "usage"
+		Rating    string `json:"rating,omitempty"`  // feedback: good or bad
+		Comment   string `json:"comment,omitempty"` // feedback: optional reason
 	}
 
 	if err := json.Unmarshal(msg.Data, &req); err != nil {
-		c.sendError(msg.ID, "Invalid session request: "+err.Error())
+		c.sendError(msg.ID, types.ErrInvalidArgument, "Invalid session request: "+err.Error())
 		return
 	}
 
@@ -306,7 +444,7 @@ func (c *WSClient) handleSession(msg WSMessage) {
 	case "get", "":
 		session, exists := c.server.agentService.GetSession(req.SessionID)
 		if !exists {
-			c.sendError(msg.ID, "Session not found: "+req.SessionID)
+			c.sendError(msg.ID, types.ErrNotFound, "Session not found: "+req.SessionID)
 			return
 		}
 		stats := session.GetStats()
@@ -329,8 +467,46 @@ func (c *WSClient) handleSession(msg WSMessage) {
 			Data: resultJSON,
 		})
 
+	case "feedback":
+		rating, err := ParseRating(req.Rating)
+		if err != nil {
+			c.sendError(msg.ID, types.CodeOf(err), err.Error())
+			return
+		}
+		fb, err := c.server.agentService.RateExchange(req.SessionID, rating, req.Comment)
+		if err != nil {
+			c.sendError(msg.ID, types.CodeOf(err), err.Error())
+			return
+		}
+		resultJSON, _ := json.Marshal(map[string]interface{}{
+			"id":       req.SessionID,
+			"feedback": fb,
+			"message":  feedbackSummary(fb),
+		})
+		c.sendMessage(WSMessage{
+			Type: "feedback",
+			ID:   msg.ID,
+			Data: resultJSON,
+		})
+
+	case "usage":
+		usage, err := c.server.agentService.SessionUsage(req.SessionID)
+		if err != nil {
+			c.sendError(msg.ID, types.ErrInternal, err.Error())
+			return
+		}
+		resultJSON, _ := json.Marshal(map[string]interface{}{
+			"id":    req.SessionID,
+			"usage": usage,
+		})
+		c.sendMessage(WSMessage{
+			Type: "usage",
+			ID:   msg.ID,
+			Data: resultJSON,
+		})
+
 	default:
-		c.sendError(msg.ID, "Unknown action: "+req.Action)
+		c.sendError(msg.ID, types.ErrInvalidArgument, "Unknown action: "+req.Action)
 	}
 }
 
@@ -349,16 +525,37 @@ func (c *WSClient) sendMessage(msg WSMessage) {
 	}
 }
 
+// deliver sends a message, waiting for room in the send buffer instead of
+// dropping it; stream replays can exceed the buffer
+func (c *WSClient) deliver(msg WSMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.send <- data:
+		return nil
+	case <-c.done:
+		return errors.New("connection closed")
+	}
+}
+
 // sendError sends an error message to the client
-func (c *WSClient) sendError(id, message string) {
-	errorData, _ := json.Marshal(map[string]string{
-		"error": message,
+func (c *WSClient) sendError(id string, code types.ErrorCode, message string) {
+	c.sendMessage(errorMessage(id, code, message))
+}
+
+// errorMessage builds an error message
+func errorMessage(id string, code types.ErrorCode, message string) WSMessage {
+	errorData, _ := json.Marshal(types.ErrorResponse{
+		Error:     message,
+		ErrorCode: code,
 	})
-	c.sendMessage(WSMessage{
+	return WSMessage{
 		Type: "error",
 		ID:   id,
 		Data: errorData,
-	})
+	}
 }
 
 // wsHandler handles WebSocket upgrade requests
@@ -377,6 +574,7 @@ func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[WebSocket] New connection from %s", r.RemoteAddr)
 
 	client := NewWSClient(conn, s)
+	client.clientAddr = s.clientAddr(r)
 	client.Run()
 
 	log.Printf("[WebSocket] Connection closed from %s", r.RemoteAddr)
@@ -0,0 +1,63 @@
+package i18n
+
+// catalogs are the UI strings by language, then key. English has every key;
+// other languages fall back to it for missing ones.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"thinking":           "🤔 Thinking...",
+		"starting":           "🚀 *Starting* with `%s`...",
+		"result":             "🎯 RESULT",
+		"result_title":       "🎯 Result",
+		"done":               "✅ Done (%d steps)",
+		"error":              "❌ Error: %s",
+		"agent_error":        "❌ Agent error: %s",
+		"lang_current":       "Language: %s",
+		"lang_default":       "Language: default (%s)",
+		"lang_set":           "✓ Language: %s (answers in this language; code and tools stay in English)",
+		"lang_reset":         "✓ Language reset to the default",
+		"lang_invalid":       "Invalid language tag %q. Use e.g. en, pt-BR, es",
+		"lang_usage":         "Usage: /lang [tag|default], e.g. /lang pt-BR",
+		"session_clear":      "✅ Session cleared. Next message will start fresh context.",
+		"step_limit_ask":     "⏸  Step limit (%d) reached. Continue with %d more steps? [Y/n/steps] ",
+		"step_limit_partial": "⏸  Stopped at the step limit (%d steps): the result above is partial. To continue:",
+		"step_limit_reply":   "⏸ Stopped at the step limit (%d steps). Reply `continue` in this thread to go on.",
+	},
+	"pt": {
+		"thinking":           "🤔 Pensando...",
+		"starting":           "🚀 *Iniciando* com `%s`...",
+		"result":             "🎯 RESULTADO",
+		"result_title":       "🎯 Resultado",
+		"done":               "✅ Concluído (%d passos)",
+		"error":              "❌ Erro: %s",
+		"agent_error":        "❌ Erro do agente: %s",
+		"lang_current":       "Idioma: %s",
+		"lang_default":       "Idioma: padrão (%s)",
+		"lang_set":           "✓ Idioma: %s (respostas neste idioma; código e ferramentas continuam em inglês)",
+		"lang_reset":         "✓ Idioma restaurado para o padrão",
+		"lang_invalid":       "Código de idioma inválido %q. Use, por exemplo, en, pt-BR, es",
+		"lang_usage":         "Uso: /lang [código|default], por exemplo /lang pt-BR",
+		"session_clear":      "✅ Sessão limpa. A próxima mensagem começa com contexto novo.",
+		"step_limit_ask":     "⏸  Limite de passos (%d) atingido. Continuar com mais %d passos? [S/n/passos] ",
+		"step_limit_partial": "⏸  Parou no limite de passos (%d passos): o resultado acima é parcial. Para continuar:",
+		"step_limit_reply":   "⏸ Parou no limite de passos (%d passos). Responda `continue` nesta conversa para seguir.",
+	},
+	"es": {
+		"thinking":           "🤔 Pensando...",
+		"starting":           "🚀 *Iniciando* con `%s`...",
+		"result":             "🎯 RESULTADO",
+		"result_title":       "🎯 Resultado",
+		"done":               "✅ Listo (%d pasos)",
+		"error":              "❌ Error: %s",
+		"agent_error":        "❌ Error del agente: %s",
+		"lang_current":       "Idioma: %s",
+		"lang_default":       "Idioma: predeterminado (%s)",
+		"lang_set":           "✓ Idioma: %s (respuestas en este idioma; el código y las herramientas siguen en inglés)",
+		"lang_reset":         "✓ Idioma restablecido al predeterminado",
+		"lang_invalid":       "Código de idioma no válido %q. Usa, por ejemplo, en, pt-BR, es",
+		"lang_usage":         "Uso: /lang [código|default], por ejemplo /lang pt-BR",
+		"session_clear":      "✅ Sesión borrada. El próximo mensaje empieza con un contexto nuevo.",
+		"step_limit_ask":     "⏸  Límite de pasos (%d) alcanzado. ¿Continuar con %d pasos más? [S/n/pasos] ",
+		"step_limit_partial": "⏸  Se detuvo en el límite de pasos (%d pasos): el resultado de arriba es parcial. Para continuar:",
+		"step_limit_reply":   "⏸ Se detuvo en el límite de pasos (%d pasos). Responde `continue` en este hilo para seguir.",
+	},
+}
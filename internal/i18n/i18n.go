@@ -0,0 +1,101 @@
+// Package i18n holds the user's language preference helpers: language tag
+// normalization, names for prompts, and the few UI strings the CLI and Slack
+// bot show around answers. Tool output, code and logs stay in English.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLanguage is used when no preference is set
+const DefaultLanguage = "en"
+
+// names are the languages the agent is known to answer well in, by tag
+var names = map[string]string{
+	"en":    "English",
+	"pt":    "Portuguese",
+	"pt-BR": "Brazilian Portuguese",
+	"pt-PT": "European Portuguese",
+	"es":    "Spanish",
+	"fr":    "French",
+	"de":    "German",
+	"it":    "Italian",
+	"ja":    "Japanese",
+	"ko":    "Korean",
+	"zh":    "Chinese",
+	"zh-CN": "Simplified Chinese",
+	"zh-TW": "Traditional Chinese",
+}
+
+// Normalize returns a language tag in canonical case ("pt_br" -> "pt-BR"),
+// or "" when tag is empty or malformed
+func Normalize(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if i := strings.IndexByte(tag, '.'); i >= 0 { // POSIX locales: pt_BR.UTF-8
+		tag = tag[:i]
+	}
+	lang, region, _ := strings.Cut(tag, "-")
+	if len(lang) < 2 || len(lang) > 3 || !isLetters(lang) || (region != "" && !isAlnum(region)) {
+		return ""
+	}
+	if region == "" {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// Name returns the language's English name for prompts ("" = the default
+// language); unknown tags are returned as given, which models understand too
+func Name(tag string) string {
+	if tag = Normalize(tag); tag == "" {
+		tag = DefaultLanguage
+	}
+	if name, ok := names[tag]; ok {
+		return name
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if name, ok := names[base]; ok {
+		return name + " (" + tag + ")"
+	}
+	return tag
+}
+
+// T returns the UI string for key in the language (falling back to its base
+// language, then English), formatted with args
+func T(lang, key string, args ...interface{}) string {
+	lang = Normalize(lang)
+	base, _, _ := strings.Cut(lang, "-")
+	format, ok := catalogs[lang][key]
+	if !ok {
+		format, ok = catalogs[base][key]
+	}
+	if !ok {
+		format, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,54 @@
+package i18n
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"pt-BR":       "pt-BR",
+		"pt_br":       "pt-BR",
+		"PT":          "pt",
+		"pt_BR.UTF-8": "pt-BR",
+		"es-419":      "es-419",
+		"":            "",
+		"portuguese":  "",
+		"pt-BR;q=1":   "",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"pt-BR": "Brazilian Portuguese",
+		"es-MX": "Spanish (es-MX)",
+		"":      "English",
+		"sw":    "sw",
+	}
+	for in, want := range tests {
+		if got := Name(in); got != want {
+			t.Errorf("Name(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("pt-BR", "done", 3); got != "✅ Concluído (3 passos)" {
+		t.Errorf("pt-BR falls back to pt: got %q", got)
+	}
+	if got := T("fr", "done", 3); got != "✅ Done (3 steps)" {
+		t.Errorf("unknown catalog falls back to English: got %q", got)
+	}
+	if got := T("en", "no_such_key"); got != "no_such_key" {
+		t.Errorf("missing key = %q", got)
+	}
+	for lang, catalog := range catalogs {
+		for key := range catalog {
+			if _, ok := catalogs[DefaultLanguage][key]; !ok {
+				t.Errorf("%s has key %q missing from English", lang, key)
+			}
+		}
+	}
+}
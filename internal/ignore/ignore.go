@@ -0,0 +1,182 @@
+// Package ignore matches paths against a project's .gitignore and
+// .zenclawignore files, so directory walks (list_dir, search_files, the
+// code index) skip dependencies, build outputs and data dumps.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileNames are the ignore files read in every directory, in order: a
+// .zenclawignore rule wins over a .gitignore one. Both use gitignore syntax.
+var FileNames = []string{".gitignore", ".zenclawignore"}
+
+// rule is one pattern of an ignore file
+type rule struct {
+	segments []string // Pattern split at "/"
+	anchored bool     // Matched from the ignore file's directory, not at any depth
+	dirOnly  bool     // Trailing "/": directories only
+	negate   bool     // Leading "!": re-includes
+}
+
+// Matcher answers whether paths under a directory are ignored. Rules come
+// from the ignore files of the repository the directory belongs to, from
+// its top down; each directory's files are read once, when first needed.
+type Matcher struct {
+	top   string // Repository top, or the directory when not in a repository
+	rules map[string][]rule
+	mu    sync.Mutex
+}
+
+// New returns the matcher for walks starting at root
+func New(root string) *Matcher {
+	root, _ = filepath.Abs(root)
+	top := root
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			top = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return &Matcher{top: top, rules: make(map[string][]rule)}
+}
+
+// Ignored reports whether path is ignored; isDir selects the patterns that
+// only match directories ("build/"). Walks skip ignored directories, so
+// only the path itself is matched, not its parents: a walk started inside
+// an ignored directory lists it. A nil Matcher ignores nothing.
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	p, _ = filepath.Abs(p)
+	rel, err := filepath.Rel(m.top, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	// Deeper ignore files and later rules win
+	ignored := false
+	dir := m.top
+	for i := range parts {
+		for _, r := range m.load(dir) {
+			if r.match(parts[i:], isDir) {
+				ignored = !r.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
+
+// load returns the rules of the ignore files in dir
+func (m *Matcher) load(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	var rules []rule
+	files := FileNames
+	if dir == m.top {
+		files = append([]string{filepath.Join(".git", "info", "exclude")}, files...)
+	}
+	for _, name := range files {
+		rules = append(rules, parseFile(filepath.Join(dir, name))...)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// parseFile reads the rules of an ignore file (none if it doesn't exist)
+func parseFile(name string) []rule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []rule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if r, ok := parseRule(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// parseRule parses a gitignore line
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash at the start or in the middle anchors the pattern
+	r.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule{}, false
+	}
+	r.segments = strings.Split(line, "/")
+	return r, true
+}
+
+// match reports whether the rule matches rel, the path split into its
+// segments relative to the ignore file's directory
+func (r rule) match(rel []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.segments[0], rel[len(rel)-1])
+		return ok
+	}
+	return MatchSegments(r.segments, rel)
+}
+
+// MatchSegments matches a glob against a path, both split on "/", segment
+// by segment with path.Match; "**" stands for any number of segments, and
+// a trailing "**" for at least one ("dir/**" is what is inside dir)
+func MatchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(name) > 0 // "dir/**" is everything inside dir
+			}
+			for i := range len(name) + 1 {
+				if MatchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,53 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	top := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		os.MkdirAll(filepath.Join(top, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(top, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(top, ".git"), 0755)
+	write(".gitignore", "# deps\nnode_modules/\n/build\n*.log\n!keep.log\ndocs/**/*.pdf\n")
+	write(".zenclawignore", "data/\n")
+	write("svc/.gitignore", "gen/\n")
+
+	// Walks may start below the repository top
+	m := New(filepath.Join(top, "svc"))
+	for _, tc := range []struct {
+		path   string
+		isDir  bool
+		ignore bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false}, // Directory-only pattern
+		{"build", true, true},
+		{"svc/build", true, false}, // Anchored at the top
+		{"app.log", false, true},
+		{"svc/keep.log", false, false},
+		{"docs/a/b/spec.pdf", false, true},
+		{"docs/spec.pdf", false, true},
+		{"data", true, true},
+		{"svc/gen", true, true},
+		{"gen", true, false}, // svc's rule stays in svc
+		{"main.go", false, false},
+	} {
+		if got := m.Ignored(filepath.Join(top, tc.path), tc.isDir); got != tc.ignore {
+			t.Errorf("Ignored(%q, dir=%v) = %v, want %v", tc.path, tc.isDir, got, tc.ignore)
+		}
+	}
+
+	var none *Matcher
+	if none.Ignored(filepath.Join(top, "app.log"), false) {
+		t.Error("nil matcher ignored a path")
+	}
+}
@@ -0,0 +1,173 @@
+// Package jsonrepair fixes the malformed JSON models commonly produce:
+// trailing commas, raw newlines in strings, truncated output (unterminated
+// strings, missing closing braces) and prose or code fences around the value.
+package jsonrepair
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Unmarshal decodes data into v, repairing it first if it is not valid JSON.
+// repaired reports whether the repair was needed; on failure the original
+// decode error is returned.
+func Unmarshal(data string, v interface{}) (repaired bool, err error) {
+	err = json.Unmarshal([]byte(strings.TrimSpace(data)), v)
+	if err == nil {
+		return false, nil
+	}
+	fixed := Repair(data)
+	if fixed == "" {
+		return false, err
+	}
+	if json.Unmarshal([]byte(fixed), v) != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Extract returns text starting at the first JSON object or array, skipping
+// a leading ```json fence and any prose before the value
+func Extract(text string) string {
+	if i := strings.Index(text, "```json"); i >= 0 {
+		text = text[i+len("```json"):]
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+	return text[start:]
+}
+
+// Repair returns a best-effort valid JSON version of the first value in text.
+// Anything after the value (closing fences, commentary) is dropped.
+func Repair(text string) string {
+	s := Extract(text)
+	if s == "" {
+		return ""
+	}
+
+	var out []byte
+	var stack []byte // Expected closers
+	inString, escaped := false, false
+	keyString := false // Current/last string is an object key
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				out = append(out, '\\', 'n')
+				continue
+			case c == '\r':
+				out = append(out, '\\', 'r')
+				continue
+			case c == '\t':
+				out = append(out, '\\', 't')
+				continue
+			}
+			out = append(out, c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			prev := lastSignificant(out)
+			keyString = len(stack) > 0 && stack[len(stack)-1] == '}' && (prev == '{' || prev == ',')
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				continue // Stray closer
+			}
+			out = trimTrailingComma(out)
+			stack = stack[:len(stack)-1]
+			out = append(out, c)
+			if len(stack) == 0 {
+				return string(out)
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+
+	// Truncated: close the open string, then finish the dangling value
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out = append(out, '"')
+	}
+	out = completeValue(out, keyString, stack)
+	for i := len(stack) - 1; i >= 0; i-- {
+		out = trimTrailingComma(out)
+		out = append(out, stack[i])
+	}
+	return string(out)
+}
+
+// completeValue finishes a value cut off at the end of the input
+func completeValue(out []byte, keyString bool, stack []byte) []byte {
+	out = []byte(strings.TrimRight(string(out), " \t\r\n"))
+	if len(out) == 0 {
+		return out
+	}
+
+	// Partial literal or number
+	end := len(out)
+	start := end
+	for start > 0 && strings.IndexByte("abcdefghijklmnopqrstuvwxyz0123456789.+-E", out[start-1]) >= 0 {
+		start--
+	}
+	if word := string(out[start:end]); word != "" {
+		for _, lit := range []string{"true", "false", "null"} {
+			if strings.HasPrefix(lit, word) {
+				return append(out[:start], lit...)
+			}
+		}
+		out = []byte(strings.TrimRight(string(out), ".+-eE"))
+		if len(out) > start {
+			return out
+		}
+	}
+
+	switch lastSignificant(out) {
+	case ':':
+		out = append(out, "null"...)
+	case '"':
+		if keyString && len(stack) > 0 && stack[len(stack)-1] == '}' {
+			out = append(out, ":null"...)
+		}
+	}
+	return out
+}
+
+// lastSignificant returns the last non-whitespace byte of out
+func lastSignificant(out []byte) byte {
+	for i := len(out) - 1; i >= 0; i-- {
+		switch out[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return out[i]
+	}
+	return 0
+}
+
+// trimTrailingComma removes a trailing comma (and whitespace) before a closer
+func trimTrailingComma(out []byte) []byte {
+	trimmed := strings.TrimRight(string(out), " \t\r\n")
+	if strings.HasSuffix(trimmed, ",") {
+		return []byte(trimmed[:len(trimmed)-1])
+	}
+	return out
+}
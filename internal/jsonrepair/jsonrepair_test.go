@@ -0,0 +1,58 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"valid", `{"a":1}`, `{"a":1}`},
+		{"trailing comma object", `{"a":1,}`, `{"a":1}`},
+		{"trailing comma array", `{"a":[1,2, ]}`, `{"a":[1,2]}`},
+		{"unterminated string", `{"path":"main.go`, `{"path":"main.go"}`},
+		{"missing braces", `{"scores":[{"worker":"W1","score":8}`, `{"scores":[{"worker":"W1","score":8}]}`},
+		{"dangling colon", `{"a":`, `{"a":null}`},
+		{"dangling key", `{"a":1,"b"`, `{"a":1,"b":null}`},
+		{"partial literal", `{"ok":tr`, `{"ok":true}`},
+		{"partial number", `{"n":1.`, `{"n":1}`},
+		{"raw newline in string", "{\"s\":\"a\nb\"}", `{"s":"a\nb"}`},
+		{"fenced with prose", "Here you go:\n```json\n{\"a\":1}\n```\nDone.", `{"a":1}`},
+		{"dangling escape", `{"s":"a\`, `{"s":"a"}`},
+		{"no json", "nothing here", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Repair(tt.input)
+			if got != tt.want {
+				t.Errorf("Repair(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if got != "" && !json.Valid([]byte(got)) {
+				t.Errorf("Repair(%q) = %q is not valid JSON", tt.input, got)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v struct {
+		Command string `json:"command"`
+	}
+	repaired, err := Unmarshal(`{"command":"go test ./..."}`, &v)
+	if err != nil || repaired || v.Command != "go test ./..." {
+		t.Errorf("valid input: repaired=%v err=%v v=%+v", repaired, err, v)
+	}
+
+	repaired, err = Unmarshal(`{"command":"ls -la",`, &v)
+	if err != nil || !repaired || v.Command != "ls -la" {
+		t.Errorf("truncated input: repaired=%v err=%v v=%+v", repaired, err, v)
+	}
+
+	if _, err := Unmarshal(`not json`, &v); err == nil {
+		t.Error("expected error for non-JSON input")
+	}
+}
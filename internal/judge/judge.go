@@ -4,15 +4,18 @@ package judge
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/jsonrepair"
 )
 
+// maxJudgmentReprompts caps follow-up calls asking the judge for valid JSON
+const maxJudgmentReprompts = 1
+
 // Response represents a candidate response to be judged
 type Response struct {
 	Provider   string                 `json:"provider"`
@@ -38,6 +41,8 @@ type Evaluation struct {
 	Reasoning   string             `json:"reasoning"`    // Why this was chosen
 	Scores      map[string]float64 `json:"scores"`       // Provider -> score
 	Criteria    []CriteriaScore    `json:"criteria,omitempty"`
+	Preset      string             `json:"preset,omitempty"`    // Criteria preset used
+	Breakdown   []ScoreBreakdown   `json:"breakdown,omitempty"` // Weighted scores, best first
 }
 
 // Result contains the judgment result
@@ -59,6 +64,12 @@ type Request struct {
 	Criteria  []string   `json:"criteria,omitempty"` // Custom evaluation criteria
 	Context   string     `json:"context,omitempty"`  // Additional context
 	Task      string     `json:"task,omitempty"`     // Task description
+
+	// Weighted scoring: the judge scores each criterion and the winner is the
+	// response with the best weighted total. Weighted wins over Preset, and
+	// both over Criteria.
+	Preset   string      `json:"preset,omitempty"`   // Named criteria preset (code-quality, security, api-design, or custom)
+	Weighted []Criterion `json:"weighted,omitempty"` // Explicit weighted criteria
 }
 
 // Judge uses an LLM to evaluate and compare multiple responses
@@ -66,6 +77,7 @@ type Judge struct {
 	provider      ai.Provider
 	providerName  string
 	model         string
+	presets       map[string][]Criterion // Custom presets (config)
 }
 
 // NewJudge creates a new LLM judge
@@ -80,6 +92,27 @@ func NewJudge(provider ai.Provider, providerName, model string) *Judge {
 	}
 }
 
+// SetPresets sets custom criteria presets, looked up before the built-in ones
+func (j *Judge) SetPresets(presets map[string][]Criterion) {
+	j.presets = presets
+}
+
+// weightedCriteria returns the request's weighted criteria, normalized: the
+// explicit ones, else the preset's. nil when the request has neither.
+func (j *Judge) weightedCriteria(req Request) ([]Criterion, error) {
+	if len(req.Weighted) > 0 {
+		return normalizeWeights(req.Weighted), nil
+	}
+	if req.Preset != "" {
+		criteria, err := LookupPreset(req.Preset, j.presets)
+		if err != nil {
+			return nil, err
+		}
+		return normalizeWeights(criteria), nil
+	}
+	return nil, nil
+}
+
 // Judge evaluates multiple responses and selects the best one
 func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 	start := time.Now()
@@ -87,6 +120,10 @@ func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 	if len(req.Responses) == 0 {
 		return nil, fmt.Errorf("no responses to judge")
 	}
+	weighted, err := j.weightedCriteria(req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Only one response - return it directly
 	if len(req.Responses) == 1 {
@@ -116,7 +153,7 @@ func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 	}
 
 	// Build judgment prompt
-	prompt := j.buildJudgmentPrompt(req)
+	prompt := j.buildJudgmentPrompt(req, weighted)
 
 	// Call judge provider with JSON output request
 	resp, err := j.provider.Chat(ctx, ai.ChatRequest{
@@ -134,6 +171,26 @@ func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 
 	// Parse judgment from response
 	judgment, err := j.parseJudgment(resp.Content, req.Responses)
+
+	// Invalid JSON: ask the judge to resend just the JSON, with a cap
+	for attempt := 1; err != nil && attempt <= maxJudgmentReprompts; attempt++ {
+		log.Printf("[Judge] Invalid judgment (%v), reprompting (%d/%d)", err, attempt, maxJudgmentReprompts)
+		retry, retryErr := j.provider.Chat(ctx, ai.ChatRequest{
+			Model: j.model,
+			Messages: []ai.Message{
+				{Role: "user", Content: prompt},
+				{Role: "assistant", Content: resp.Content},
+				{Role: "user", Content: fmt.Sprintf("Your judgment could not be parsed (%v). Reply with ONLY the corrected JSON object in the requested format, nothing else.", err)},
+			},
+			MaxTokens:   2000,
+			Temperature: 0.1,
+		})
+		if retryErr != nil {
+			break
+		}
+		resp = retry
+		judgment, err = j.parseJudgment(resp.Content, req.Responses)
+	}
 	if err != nil {
 		log.Printf("[Judge] Warning: failed to parse judgment JSON, falling back to first response: %v", err)
 		// Fallback to first response
@@ -160,6 +217,21 @@ func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 		}, nil
 	}
 
+	// Weighted scoring: the best weighted total wins
+	var breakdown []ScoreBreakdown
+	if weighted != nil {
+		breakdown = weightedBreakdown(weighted, judgment.CriteriaScores, req.Responses)
+		if len(breakdown) > 0 {
+			judgment.Scores = make(map[string]float64)
+			for _, b := range breakdown {
+				judgment.Scores[b.Provider] = b.Score
+			}
+			judgment.Winner = breakdown[0].Provider
+		} else {
+			log.Printf("[Judge] No per-criterion scores in the judgment, using its overall scores")
+		}
+	}
+
 	// Find winner response
 	winner := req.Responses[0] // Default
 	for _, resp := range req.Responses {
@@ -178,6 +250,8 @@ func (j *Judge) Judge(ctx context.Context, req Request) (*Result, error) {
 			Reasoning:   judgment.Reasoning,
 			Scores:      judgment.Scores,
 			Criteria:    judgment.CriteriaScores,
+			Preset:      req.Preset,
+			Breakdown:   breakdown,
 		},
 		Metadata: struct {
 			JudgeProvider    string    `json:"judge_provider"`
@@ -211,18 +285,19 @@ type parsedJudgment struct {
 func (j *Judge) parseJudgment(content string, responses []Response) (*parsedJudgment, error) {
 	// Find JSON block in response
 	jsonStart := strings.Index(content, "{")
-	jsonEnd := strings.LastIndex(content, "}")
-
-	if jsonStart < 0 || jsonEnd <= jsonStart {
+	if jsonStart < 0 {
 		return nil, fmt.Errorf("no JSON found in response")
 	}
 
-	jsonStr := content[jsonStart : jsonEnd+1]
-
+	// Repairs trailing commas and output truncated by the token limit
 	var judgment parsedJudgment
-	if err := json.Unmarshal([]byte(jsonStr), &judgment); err != nil {
+	repaired, err := jsonrepair.Unmarshal(content[jsonStart:], &judgment)
+	if err != nil {
 		return nil, fmt.Errorf("JSON parse error: %w", err)
 	}
+	if repaired {
+		log.Printf("[Judge] Repaired malformed judgment JSON")
+	}
 
 	// Validate winner
 	if judgment.Winner == "" {
@@ -242,8 +317,9 @@ func (j *Judge) parseJudgment(content string, responses []Response) (*parsedJudg
 	return &judgment, nil
 }
 
-// buildJudgmentPrompt constructs the prompt for the judge LLM
-func (j *Judge) buildJudgmentPrompt(req Request) string {
+// buildJudgmentPrompt constructs the prompt for the judge LLM. With weighted
+// criteria the judge scores every criterion separately.
+func (j *Judge) buildJudgmentPrompt(req Request, weighted []Criterion) string {
 	var prompt strings.Builder
 
 	prompt.WriteString("You are an AI judge evaluating multiple responses to select the best one.\n\n")
@@ -258,7 +334,16 @@ func (j *Judge) buildJudgmentPrompt(req Request) string {
 
 	// Evaluation criteria
 	prompt.WriteString("EVALUATION CRITERIA:\n")
-	if len(req.Criteria) > 0 {
+	if len(weighted) > 0 {
+		for i, c := range weighted {
+			prompt.WriteString(fmt.Sprintf("%d. %s (weight %.0f%%)", i+1, c.Name, c.Weight*100))
+			if c.Description != "" {
+				prompt.WriteString(": " + c.Description)
+			}
+			prompt.WriteString("\n")
+		}
+		prompt.WriteString("Score every response on each criterion separately (criteria_scores); the overall score is the weighted sum.\n")
+	} else if len(req.Criteria) > 0 {
 		for i, criterion := range req.Criteria {
 			prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, criterion))
 		}
@@ -309,10 +394,31 @@ OUTPUT FORMAT (JSON only, no other text):
 		prompt.WriteString(fmt.Sprintf("    \"%s\": 0.0%s\n", resp.Provider, comma))
 	}
 
-	prompt.WriteString(`  }
+	if len(weighted) == 0 {
+		prompt.WriteString(`  }
 }
 `)
+		return prompt.String()
+	}
 
+	// Per-criterion scores for the weighted total
+	prompt.WriteString(`  },
+  "criteria_scores": [
+`)
+	for i, c := range weighted {
+		var scores []string
+		for _, resp := range req.Responses {
+			scores = append(scores, fmt.Sprintf("\"%s\": 0.0", resp.Provider))
+		}
+		comma := ","
+		if i == len(weighted)-1 {
+			comma = ""
+		}
+		prompt.WriteString(fmt.Sprintf("    {\"criterion\": \"%s\", \"scores\": {%s}}%s\n", c.Name, strings.Join(scores, ", "), comma))
+	}
+	prompt.WriteString(`  ]
+}
+`)
 	return prompt.String()
 }
 
@@ -0,0 +1,153 @@
+package judge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Criterion is an evaluation criterion and its weight in the overall score
+type Criterion struct {
+	Name        string  `json:"name" yaml:"name"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Weight      float64 `json:"weight" yaml:"weight"` // Relative; weights are normalized to sum to 1
+}
+
+// Presets are the built-in named criteria sets
+var Presets = map[string][]Criterion{
+	"code-quality": {
+		{Name: "correctness", Description: "Does the code do what the task asks, including edge cases?", Weight: 0.35},
+		{Name: "readability", Description: "Clear names, structure and idiomatic style", Weight: 0.2},
+		{Name: "maintainability", Description: "Small, cohesive units that are easy to change", Weight: 0.2},
+		{Name: "testing", Description: "Tests or a concrete way to verify the change", Weight: 0.15},
+		{Name: "performance", Description: "No needless work or allocations on hot paths", Weight: 0.1},
+	},
+	"security": {
+		{Name: "threat coverage", Description: "Identifies the relevant threats and attack surface", Weight: 0.3},
+		{Name: "least privilege", Description: "Access, secrets and permissions are minimal", Weight: 0.25},
+		{Name: "input handling", Description: "Validation, encoding and injection defenses", Weight: 0.2},
+		{Name: "secure defaults", Description: "Safe out of the box; failures fail closed", Weight: 0.15},
+		{Name: "practicality", Description: "Can be implemented and operated by a real team", Weight: 0.1},
+	},
+	"api-design": {
+		{Name: "consistency", Description: "Naming, resource modeling and conventions are uniform", Weight: 0.25},
+		{Name: "compatibility", Description: "Versioning and backward compatibility are handled", Weight: 0.2},
+		{Name: "error handling", Description: "Errors are well-typed, documented and actionable", Weight: 0.2},
+		{Name: "completeness", Description: "Covers the needed operations, pagination and limits", Weight: 0.2},
+		{Name: "security", Description: "Authentication, authorization and rate limiting", Weight: 0.15},
+	},
+}
+
+// PresetNames returns the built-in and custom preset names, sorted
+func PresetNames(custom map[string][]Criterion) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, set := range []map[string][]Criterion{Presets, custom} {
+		for name := range set {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupPreset returns a preset's criteria: a custom preset first (it may
+// replace a built-in one), then the built-in ones
+func LookupPreset(name string, custom map[string][]Criterion) ([]Criterion, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	criteria, ok := custom[name]
+	if !ok {
+		criteria, ok = Presets[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown judge preset %q (available: %s)", name, strings.Join(PresetNames(custom), ", "))
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("judge preset %q has no criteria", name)
+	}
+	return criteria, nil
+}
+
+// normalizeWeights returns the criteria with weights summing to 1. Missing
+// or negative weights count as 1, so unweighted criteria weigh the same.
+func normalizeWeights(criteria []Criterion) []Criterion {
+	out := make([]Criterion, len(criteria))
+	total := 0.0
+	for i, c := range criteria {
+		if c.Weight <= 0 {
+			c.Weight = 1
+		}
+		out[i] = c
+		total += c.Weight
+	}
+	for i := range out {
+		out[i].Weight /= total
+	}
+	return out
+}
+
+// CriterionScore is one criterion's part of a response's weighted score
+type CriterionScore struct {
+	Criterion string  `json:"criterion"`
+	Weight    float64 `json:"weight"`   // Normalized weight
+	Score     float64 `json:"score"`    // Judge's score, 0.0-1.0
+	Weighted  float64 `json:"weighted"` // Weight * score
+}
+
+// ScoreBreakdown is how a response's weighted score adds up
+type ScoreBreakdown struct {
+	Provider string           `json:"provider"`
+	Score    float64          `json:"score"` // Sum of the weighted scores
+	Criteria []CriterionScore `json:"criteria"`
+}
+
+// weightedBreakdown combines the judge's per-criterion scores with the
+// weights. Criteria the judge did not score are left out and the remaining
+// weights renormalized; providers with no criterion scored are omitted.
+func weightedBreakdown(criteria []Criterion, scored []CriteriaScore, responses []Response) []ScoreBreakdown {
+	byName := make(map[string]CriteriaScore)
+	for _, cs := range scored {
+		byName[strings.ToLower(strings.TrimSpace(cs.Criterion))] = cs
+	}
+
+	var out []ScoreBreakdown
+	for _, resp := range responses {
+		b := ScoreBreakdown{Provider: resp.Provider}
+		covered := 0.0
+		for _, c := range criteria {
+			cs, ok := byName[strings.ToLower(c.Name)]
+			if !ok {
+				continue
+			}
+			score, ok := cs.Scores[resp.Provider]
+			if !ok {
+				continue
+			}
+			score = clampScore(score)
+			b.Criteria = append(b.Criteria, CriterionScore{Criterion: c.Name, Weight: c.Weight, Score: score})
+			covered += c.Weight
+		}
+		if len(b.Criteria) == 0 {
+			continue
+		}
+		for i := range b.Criteria {
+			b.Criteria[i].Weight /= covered
+			b.Criteria[i].Weighted = b.Criteria[i].Weight * b.Criteria[i].Score
+			b.Score += b.Criteria[i].Weighted
+		}
+		out = append(out, b)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// clampScore keeps a score in 0.0-1.0, reading 1-10 scores as tenths
+func clampScore(score float64) float64 {
+	if score > 1 && score <= 10 {
+		score /= 10
+	}
+	return min(max(score, 0), 1)
+}
@@ -0,0 +1,95 @@
+package judge
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// scriptedProvider answers every chat with the same content
+type scriptedProvider struct {
+	content string
+	prompt  string
+}
+
+func (p *scriptedProvider) Name() string        { return "scripted" }
+func (p *scriptedProvider) SupportsTools() bool { return false }
+func (p *scriptedProvider) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	p.prompt = req.Messages[0].Content
+	return &ai.ChatResponse{Content: p.content}, nil
+}
+func (p *scriptedProvider) ChatStream(ctx context.Context, req ai.ChatRequest, cb ai.StreamCallback) (*ai.ChatResponse, error) {
+	return p.Chat(ctx, req)
+}
+
+func TestLookupPreset(t *testing.T) {
+	custom := map[string][]Criterion{"security": {{Name: "secrets", Weight: 1}}}
+	if c, err := LookupPreset(" Security ", custom); err != nil || len(c) != 1 || c[0].Name != "secrets" {
+		t.Errorf("custom preset did not replace the built-in one: %+v, %v", c, err)
+	}
+	if c, err := LookupPreset("api-design", custom); err != nil || len(c) != len(Presets["api-design"]) {
+		t.Errorf("api-design = %+v, %v", c, err)
+	}
+	if _, err := LookupPreset("nope", custom); err == nil || !strings.Contains(err.Error(), "code-quality") {
+		t.Errorf("unknown preset error = %v", err)
+	}
+
+	norm := normalizeWeights([]Criterion{{Name: "a", Weight: 3}, {Name: "b"}})
+	if norm[0].Weight != 0.75 || norm[1].Weight != 0.25 {
+		t.Errorf("normalized = %+v", norm)
+	}
+}
+
+func TestWeightedBreakdown(t *testing.T) {
+	criteria := normalizeWeights([]Criterion{{Name: "Correctness", Weight: 2}, {Name: "style", Weight: 1}, {Name: "tests", Weight: 1}})
+	scored := []CriteriaScore{
+		{Criterion: "correctness", Scores: map[string]float64{"a": 0.5, "b": 9}}, // 9 reads as 0.9
+		{Criterion: "style", Scores: map[string]float64{"a": 1, "b": 0.4}},
+	}
+	responses := []Response{{Provider: "a"}, {Provider: "b"}, {Provider: "c"}}
+
+	got := weightedBreakdown(criteria, scored, responses)
+	if len(got) != 2 || got[0].Provider != "b" {
+		t.Fatalf("breakdown = %+v", got)
+	}
+	// tests was not scored, so correctness weighs 2/3 and style 1/3
+	if want := 0.9*2/3 + 0.4/3; math.Abs(got[0].Score-want) > 1e-9 {
+		t.Errorf("b score = %v, want %v", got[0].Score, want)
+	}
+	if w := got[1].Criteria[0].Weight; math.Abs(w-2.0/3) > 1e-9 {
+		t.Errorf("correctness weight = %v", w)
+	}
+}
+
+func TestJudgeWeightedWinner(t *testing.T) {
+	// The judge's overall pick is a, but b wins on the weighted criteria
+	provider := &scriptedProvider{content: `{"winner": "a", "confidence": 0.8, "reasoning": "r",
+		"scores": {"a": 0.9, "b": 0.6},
+		"criteria_scores": [
+			{"criterion": "threat coverage", "scores": {"a": 0.2, "b": 0.9}},
+			{"criterion": "practicality", "scores": {"a": 1.0, "b": 0.5}}
+		]}`}
+	j := NewJudge(provider, "scripted", "m")
+
+	result, err := j.Judge(context.Background(), Request{
+		Task:      "secure it",
+		Responses: []Response{{Provider: "a", Content: "A"}, {Provider: "b", Content: "B"}},
+		Preset:    "security",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Winner.Provider != "b" || result.Evaluation.Preset != "security" || len(result.Evaluation.Breakdown) != 2 {
+		t.Errorf("winner %s, evaluation %+v", result.Winner.Provider, result.Evaluation)
+	}
+	if !strings.Contains(provider.prompt, "least privilege") {
+		t.Error("prompt does not list the preset's criteria")
+	}
+
+	if _, err := j.Judge(context.Background(), Request{Responses: []Response{{Provider: "a"}}, Preset: "nope"}); err == nil {
+		t.Error("accepted an unknown preset")
+	}
+}
@@ -0,0 +1,376 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rpcMessage is a JSON-RPC 2.0 request, response, or notification
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Client is a JSON-RPC connection to a single language server process
+type Client struct {
+	name    string
+	root    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	nextID      int64
+	pending     map[int64]chan *rpcMessage
+	diagnostics map[string][]Diagnostic // uri -> latest diagnostics
+	diagSignal  map[string]chan struct{}
+	versions    map[string]int // uri -> open document version
+	closed      bool
+}
+
+// StartClient launches a language server and performs the initialize handshake
+func StartClient(ctx context.Context, name, root string, command string, args ...string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", command, err)
+	}
+
+	c := &Client{
+		name:        name,
+		root:        root,
+		cmd:         cmd,
+		stdin:       stdin,
+		pending:     make(map[int64]chan *rpcMessage),
+		diagnostics: make(map[string][]Diagnostic),
+		diagSignal:  make(map[string]chan struct{}),
+		versions:    make(map[string]int),
+	}
+	go c.readLoop(bufio.NewReader(stdout))
+
+	initParams := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   pathToURI(root),
+		"workspaceFolders": []map[string]interface{}{
+			{"uri": pathToURI(root), "name": name},
+		},
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"definition":         map[string]interface{}{"linkSupport": true},
+				"references":         map[string]interface{}{},
+				"rename":             map[string]interface{}{},
+				"publishDiagnostics": map[string]interface{}{},
+				"synchronization":    map[string]interface{}{"didSave": true},
+			},
+			"workspace": map[string]interface{}{
+				"workspaceEdit":    map[string]interface{}{"documentChanges": true},
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+		},
+	}
+	if err := c.Call(ctx, "initialize", initParams, nil); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("initialize %s: %w", name, err)
+	}
+	if err := c.Notify("initialized", map[string]interface{}{}); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	log.Printf("[LSP] Started %s for %s", name, root)
+	return c, nil
+}
+
+// Call sends a request and decodes the result into result (if non-nil)
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return fmt.Errorf("language server %s is closed", c.name)
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.send(rpcMessage{ID: &rawID, Method: method, Params: mustMarshal(params)}); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("language server %s exited", c.name)
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", method, resp.Error.Message)
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	}
+}
+
+// Notify sends a notification (no response expected)
+func (c *Client) Notify(method string, params interface{}) error {
+	return c.send(rpcMessage{Method: method, Params: mustMarshal(params)})
+}
+
+// SyncFile opens or refreshes a document so the server sees the current on-disk content
+func (c *Client) SyncFile(path, languageID string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	uri := pathToURI(path)
+
+	// A fresh signal per sync: a publish that arrives before the wait isn't
+	// lost, and one for an earlier version doesn't end it
+	c.mu.Lock()
+	version, open := c.versions[uri]
+	version++
+	c.versions[uri] = version
+	c.diagSignal[uri] = make(chan struct{}, 1)
+	c.mu.Unlock()
+
+	if !open {
+		return uri, c.Notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri":        uri,
+				"languageId": languageID,
+				"version":    version,
+				"text":       string(content),
+			},
+		})
+	}
+	return uri, c.Notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": version},
+		"contentChanges": []map[string]interface{}{{"text": string(content)}},
+	})
+}
+
+// WaitDiagnostics waits for the server to publish diagnostics for uri since
+// the last SyncFile and returns them. On timeout it returns the latest ones
+// known, with fresh false.
+func (c *Client) WaitDiagnostics(ctx context.Context, uri string, timeout time.Duration) (diags []Diagnostic, fresh bool) {
+	c.mu.Lock()
+	ch, ok := c.diagSignal[uri]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		c.diagSignal[uri] = ch
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ch:
+		fresh = true
+		// Later waits for the same version return at once (readLoop may
+		// have refilled the slot already)
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	case <-time.After(timeout):
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diagnostics[uri], fresh
+}
+
+// Close shuts down the language server
+func (c *Client) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	c.Call(ctx, "shutdown", nil, nil)
+	cancel()
+	c.Notify("exit", nil)
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	log.Printf("[LSP] Stopped %s for %s", c.name, c.root)
+}
+
+// send writes a framed message to the server
+func (c *Client) send(msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return fmt.Errorf("write to %s: %w", c.name, err)
+	}
+	_, err = c.stdin.Write(body)
+	return err
+}
+
+// readLoop dispatches responses, notifications, and server-initiated requests
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		for id, ch := range c.pending {
+			close(ch)
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+	}()
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("[LSP] %s read error: %v", c.name, err)
+			}
+			return
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			// Server request (workspace/configuration, window/workDoneProgress/create, ...).
+			// We don't provide any of these; reply with an empty result so the server proceeds.
+			var result json.RawMessage = []byte("null")
+			if msg.Method == "workspace/configuration" {
+				result = []byte("[]")
+			}
+			c.send(rpcMessage{ID: msg.ID, Result: result})
+		case msg.Method == "textDocument/publishDiagnostics":
+			var params struct {
+				URI         string       `json:"uri"`
+				Version     *int         `json:"version"`
+				Diagnostics []Diagnostic `json:"diagnostics"`
+			}
+			if json.Unmarshal(msg.Params, &params) == nil {
+				c.mu.Lock()
+				if params.Version != nil && *params.Version < c.versions[params.URI] {
+					c.mu.Unlock()
+					continue // For content that has changed since
+				}
+				c.diagnostics[params.URI] = params.Diagnostics
+				if ch, ok := c.diagSignal[params.URI]; ok {
+					select {
+					case ch <- struct{}{}:
+					default:
+					}
+				}
+				c.mu.Unlock()
+			}
+		case msg.Method == "":
+			if msg.ID == nil {
+				continue
+			}
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+}
+
+// readMessage reads one Content-Length framed message
+func readMessage(r *bufio.Reader) (*rpcMessage, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// mustMarshal marshals params, returning nil for nil params
+func mustMarshal(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestWaitDiagnostics(t *testing.T) {
+	server, toClient := io.Pipe()
+	defer toClient.Close()
+	c := &Client{
+		name:        "test",
+		stdin:       nopWriteCloser{io.Discard},
+		pending:     make(map[int64]chan *rpcMessage),
+		diagnostics: make(map[string][]Diagnostic),
+		diagSignal:  make(map[string]chan struct{}),
+		versions:    make(map[string]int),
+	}
+	go c.readLoop(bufio.NewReader(server))
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n"), 0644)
+	publish := func(uri string, version int, message string) {
+		t.Helper()
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":%q,"version":%d,"diagnostics":[{"message":%q}]}}`, uri, version, message)
+		if _, err := fmt.Fprintf(toClient, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wait := func(uri string) ([]Diagnostic, bool) {
+		return c.WaitDiagnostics(context.Background(), uri, 200*time.Millisecond)
+	}
+
+	// Published before the wait starts
+	uri, _ := c.SyncFile(path, "go")
+	publish(uri, 1, "first")
+	if diags, fresh := wait(uri); !fresh || len(diags) != 1 || diags[0].Message != "first" {
+		t.Errorf("publish before wait: %v, fresh %v", diags, fresh)
+	}
+
+	// A late publish for version 1 doesn't answer the wait for version 2
+	c.SyncFile(path, "go")
+	publish(uri, 1, "stale")
+	if diags, fresh := wait(uri); fresh || diags[0].Message != "first" {
+		t.Errorf("stale publish: %v, fresh %v", diags, fresh)
+	}
+	publish(uri, 2, "second")
+	if diags, fresh := wait(uri); !fresh || diags[0].Message != "second" {
+		t.Errorf("publish for version 2: %v, fresh %v", diags, fresh)
+	}
+}
@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// utf16Column converts a byte offset within a line to an LSP character offset (UTF-16 units)
+func utf16Column(line string, byteOffset int) int {
+	if byteOffset > len(line) {
+		byteOffset = len(line)
+	}
+	col := 0
+	for _, r := range line[:byteOffset] {
+		col += len(utf16.Encode([]rune{r}))
+	}
+	return col
+}
+
+// byteColumn converts an LSP character offset (UTF-16 units) to a byte offset within a line
+func byteColumn(line string, character int) int {
+	col := 0
+	for i, r := range line {
+		if col >= character {
+			return i
+		}
+		col += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// offsetOf converts a position to a byte offset in content
+func offsetOf(content string, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		idx := strings.IndexByte(content[offset:], '\n')
+		if idx < 0 {
+			return 0, fmt.Errorf("line %d out of range", pos.Line+1)
+		}
+		offset += idx + 1
+	}
+	end := strings.IndexByte(content[offset:], '\n')
+	lineText := content[offset:]
+	if end >= 0 {
+		lineText = content[offset : offset+end]
+	}
+	return offset + byteColumn(lineText, pos.Character), nil
+}
+
+// ApplyTextEdits applies non-overlapping LSP edits to content
+func ApplyTextEdits(content string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offsetOf(content, e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offsetOf(content, e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("invalid edit range")
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+
+	// Apply back to front so earlier offsets stay valid
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].end > spans[i-1].start {
+			return "", fmt.Errorf("overlapping edits")
+		}
+	}
+	for _, s := range spans {
+		content = content[:s.start] + s.text + content[s.end:]
+	}
+	if !utf8.ValidString(content) {
+		return "", fmt.Errorf("edit produced invalid UTF-8")
+	}
+	return content, nil
+}
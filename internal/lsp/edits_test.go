@@ -0,0 +1,42 @@
+package lsp
+
+import "testing"
+
+func TestApplyTextEdits(t *testing.T) {
+	content := "func foo() {}\nfunc bar() { foo() }\n"
+	edits := []TextEdit{
+		{Range: Range{Start: Position{0, 5}, End: Position{0, 8}}, NewText: "baz"},
+		{Range: Range{Start: Position{1, 13}, End: Position{1, 16}}, NewText: "baz"},
+	}
+
+	got, err := ApplyTextEdits(content, edits)
+	if err != nil {
+		t.Fatalf("ApplyTextEdits() error = %v", err)
+	}
+	want := "func baz() {}\nfunc bar() { baz() }\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestApplyTextEditsOverlap(t *testing.T) {
+	edits := []TextEdit{
+		{Range: Range{Start: Position{0, 0}, End: Position{0, 4}}, NewText: "x"},
+		{Range: Range{Start: Position{0, 2}, End: Position{0, 6}}, NewText: "y"},
+	}
+	if _, err := ApplyTextEdits("abcdefgh", edits); err == nil {
+		t.Error("expected error for overlapping edits")
+	}
+}
+
+func TestUTF16Columns(t *testing.T) {
+	line := "s := \"😀\" + x"
+	byteIdx := len("s := \"😀\" + ")
+	col := utf16Column(line, byteIdx)
+	if col != 12 { // emoji is 2 UTF-16 units
+		t.Errorf("utf16Column = %d, want 12", col)
+	}
+	if back := byteColumn(line, col); back != byteIdx {
+		t.Errorf("byteColumn = %d, want %d", back, byteIdx)
+	}
+}
@@ -0,0 +1,116 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ServerSpec describes how to launch a language server
+type ServerSpec struct {
+	Name       string   // Display name (e.g., "gopls")
+	Command    string   // Executable
+	Args       []string // Arguments
+	LanguageID string   // LSP languageId for opened documents
+	RootMarker []string // Files that mark the workspace root (e.g., go.mod)
+}
+
+// DefaultServers maps file extensions to the language servers used for them
+var DefaultServers = map[string]ServerSpec{
+	".go":  {Name: "gopls", Command: "gopls", LanguageID: "go", RootMarker: []string{"go.work", "go.mod"}},
+	".ts":  {Name: "tsserver", Command: "typescript-language-server", Args: []string{"--stdio"}, LanguageID: "typescript", RootMarker: []string{"tsconfig.json", "package.json"}},
+	".tsx": {Name: "tsserver", Command: "typescript-language-server", Args: []string{"--stdio"}, LanguageID: "typescriptreact", RootMarker: []string{"tsconfig.json", "package.json"}},
+	".js":  {Name: "tsserver", Command: "typescript-language-server", Args: []string{"--stdio"}, LanguageID: "javascript", RootMarker: []string{"jsconfig.json", "package.json"}},
+	".jsx": {Name: "tsserver", Command: "typescript-language-server", Args: []string{"--stdio"}, LanguageID: "javascriptreact", RootMarker: []string{"jsconfig.json", "package.json"}},
+}
+
+// Manager starts language servers lazily, one per (server, workspace root)
+type Manager struct {
+	mu      sync.Mutex
+	clients map[string]*Client // "name|root" -> client
+}
+
+// NewManager creates a new LSP manager
+func NewManager() *Manager {
+	return &Manager{
+		clients: make(map[string]*Client),
+	}
+}
+
+// ClientFor returns a running client for the file, starting the server if needed
+func (m *Manager) ClientFor(ctx context.Context, path string) (*Client, ServerSpec, error) {
+	spec, ok := DefaultServers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, ServerSpec{}, fmt.Errorf("no language server configured for %s files", filepath.Ext(path))
+	}
+	if _, err := exec.LookPath(spec.Command); err != nil {
+		return nil, spec, fmt.Errorf("%s not installed (command %q not found in PATH)", spec.Name, spec.Command)
+	}
+
+	root := findRoot(filepath.Dir(path), spec.RootMarker)
+	key := spec.Name + "|" + root
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.clients[key]; ok {
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if !closed {
+			return c, spec, nil
+		}
+		delete(m.clients, key)
+	}
+
+	c, err := StartClient(ctx, spec.Name, root, spec.Command, spec.Args...)
+	if err != nil {
+		return nil, spec, err
+	}
+	m.clients[key] = c
+	return c, spec, nil
+}
+
+// ListServers returns the running servers as "name (root)"
+func (m *Manager) ListServers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.clients))
+	for _, c := range m.clients {
+		names = append(names, fmt.Sprintf("%s (%s)", c.name, c.root))
+	}
+	return names
+}
+
+// Close stops all language servers
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, c := range m.clients {
+		c.Close()
+		delete(m.clients, key)
+	}
+}
+
+// findRoot walks up from dir looking for a root marker; falls back to dir
+func findRoot(dir string, markers []string) string {
+	current := dir
+	for {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+				return current
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,489 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/agent"
+)
+
+// diagnosticsWait is how long to wait for the server to publish diagnostics
+const diagnosticsWait = 10 * time.Second
+
+// maxLocations caps the number of locations returned to the model
+const maxLocations = 100
+
+// GetTools returns the LSP-backed agent tools
+func (m *Manager) GetTools(workingDir string) []agent.Tool {
+	return []agent.Tool{
+		NewFindDefinitionTool(m, workingDir),
+		NewFindReferencesTool(m, workingDir),
+		NewRenameSymbolTool(m, workingDir),
+		NewDiagnosticsTool(m, workingDir),
+	}
+}
+
+// positionParams are the shared parameters for position-based tools
+func positionParams(extra map[string]interface{}, required ...string) map[string]interface{} {
+	props := map[string]interface{}{
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "File containing the symbol",
+		},
+		"line": map[string]interface{}{
+			"type":        "integer",
+			"description": "1-based line number of the symbol",
+		},
+		"symbol": map[string]interface{}{
+			"type":        "string",
+			"description": "Symbol name as it appears on the line (used to locate the column)",
+		},
+		"column": map[string]interface{}{
+			"type":        "integer",
+			"description": "1-based column (alternative to symbol)",
+		},
+	}
+	for k, v := range extra {
+		props[k] = v
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   append([]string{"path", "line"}, required...),
+	}
+}
+
+// lspTool holds shared state for LSP tools
+type lspTool struct {
+	agent.BaseTool
+	manager    *Manager
+	workingDir string
+}
+
+// resolvePath makes path absolute relative to the session/tool working directory
+func (t *lspTool) resolvePath(ctx context.Context, path string) string {
+	return agent.ResolveAbsPath(ctx, t.workingDir, path)
+}
+
+// prepare resolves the file, starts/syncs the server, and computes the LSP position
+func (t *lspTool) prepare(ctx context.Context, args map[string]interface{}) (*Client, string, Position, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, "", Position{}, fmt.Errorf("path parameter is required")
+	}
+	lineArg, ok := args["line"].(float64)
+	if !ok || lineArg < 1 {
+		return nil, "", Position{}, fmt.Errorf("line parameter is required (1-based)")
+	}
+
+	fullPath := t.resolvePath(ctx, path)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, "", Position{}, err
+	}
+	lines := strings.Split(string(content), "\n")
+	lineIdx := int(lineArg) - 1
+	if lineIdx >= len(lines) {
+		return nil, "", Position{}, fmt.Errorf("line %d out of range (file has %d lines)", lineIdx+1, len(lines))
+	}
+	lineText := lines[lineIdx]
+
+	byteCol := 0
+	if symbol, ok := args["symbol"].(string); ok && symbol != "" {
+		idx := strings.Index(lineText, symbol)
+		if idx < 0 {
+			return nil, "", Position{}, fmt.Errorf("symbol %q not found on line %d", symbol, lineIdx+1)
+		}
+		byteCol = idx
+	} else if col, ok := args["column"].(float64); ok && col >= 1 {
+		byteCol = int(col) - 1
+	} else {
+		// Default to the first non-whitespace character
+		byteCol = len(lineText) - len(strings.TrimLeft(lineText, " \t"))
+	}
+
+	client, spec, err := t.manager.ClientFor(ctx, fullPath)
+	if err != nil {
+		return nil, "", Position{}, err
+	}
+	uri, err := client.SyncFile(fullPath, spec.LanguageID)
+	if err != nil {
+		return nil, "", Position{}, err
+	}
+
+	return client, uri, Position{Line: lineIdx, Character: utf16Column(lineText, byteCol)}, nil
+}
+
+// errorResult formats a tool error as a result the model can act on
+func errorResult(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"error":   err.Error(),
+		"success": false,
+	}
+}
+
+// formatLocations converts LSP locations to compact results with a source preview
+func formatLocations(locs []Location) []map[string]interface{} {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].URI != locs[j].URI {
+			return locs[i].URI < locs[j].URI
+		}
+		return locs[i].Range.Start.Line < locs[j].Range.Start.Line
+	})
+
+	fileCache := make(map[string][]string)
+	var out []map[string]interface{}
+	for i, loc := range locs {
+		if i == maxLocations {
+			break
+		}
+		path := uriToPath(loc.URI)
+		lines, ok := fileCache[path]
+		if !ok {
+			if data, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			fileCache[path] = lines
+		}
+		entry := map[string]interface{}{
+			"file":   path,
+			"line":   loc.Range.Start.Line + 1,
+			"column": loc.Range.Start.Character + 1,
+		}
+		if loc.Range.Start.Line < len(lines) {
+			entry["text"] = strings.TrimSpace(lines[loc.Range.Start.Line])
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// decodeLocations handles the Location | Location[] | LocationLink[] result variants
+func decodeLocations(raw json.RawMessage) []Location {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var single Location
+	if json.Unmarshal(raw, &single) == nil && single.URI != "" {
+		return []Location{single}
+	}
+	var many []Location
+	if json.Unmarshal(raw, &many) == nil && len(many) > 0 && many[0].URI != "" {
+		return many
+	}
+	var links []LocationLink
+	if json.Unmarshal(raw, &links) == nil {
+		locs := make([]Location, 0, len(links))
+		for _, l := range links {
+			locs = append(locs, Location{URI: l.TargetURI, Range: l.TargetSelectionRange})
+		}
+		return locs
+	}
+	return nil
+}
+
+// FindDefinitionTool jumps to a symbol's definition
+type FindDefinitionTool struct {
+	lspTool
+}
+
+// NewFindDefinitionTool creates a find definition tool
+func NewFindDefinitionTool(m *Manager, workingDir string) *FindDefinitionTool {
+	return &FindDefinitionTool{lspTool{
+		BaseTool: agent.NewBaseTool(
+			"find_definition",
+			"Find where a symbol is defined using the language server (gopls/tsserver). Precise, type-aware alternative to text search.",
+			positionParams(nil),
+		),
+		manager:    m,
+		workingDir: workingDir,
+	}}
+}
+
+func (t *FindDefinitionTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	client, uri, pos, err := t.prepare(ctx, args)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	var raw json.RawMessage
+	err = client.Call(ctx, "textDocument/definition", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     pos,
+	}, &raw)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	locs := formatLocations(decodeLocations(raw))
+	return map[string]interface{}{
+		"definitions": locs,
+		"count":       len(locs),
+		"success":     true,
+	}, nil
+}
+
+// FindReferencesTool lists all references to a symbol
+type FindReferencesTool struct {
+	lspTool
+}
+
+// NewFindReferencesTool creates a find references tool
+func NewFindReferencesTool(m *Manager, workingDir string) *FindReferencesTool {
+	return &FindReferencesTool{lspTool{
+		BaseTool: agent.NewBaseTool(
+			"find_references",
+			"Find all references to a symbol across the workspace using the language server.",
+			positionParams(map[string]interface{}{
+				"include_declaration": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include the declaration itself (default: true)",
+				},
+			}),
+		),
+		manager:    m,
+		workingDir: workingDir,
+	}}
+}
+
+func (t *FindReferencesTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	client, uri, pos, err := t.prepare(ctx, args)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	includeDecl := true
+	if v, ok := args["include_declaration"].(bool); ok {
+		includeDecl = v
+	}
+
+	var locs []Location
+	err = client.Call(ctx, "textDocument/references", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     pos,
+		"context":      map[string]interface{}{"includeDeclaration": includeDecl},
+	}, &locs)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	result := map[string]interface{}{
+		"references": formatLocations(locs),
+		"count":      len(locs),
+		"success":    true,
+	}
+	if len(locs) > maxLocations {
+		result["truncated"] = true
+	}
+	return result, nil
+}
+
+// RenameSymbolTool renames a symbol everywhere it is used
+type RenameSymbolTool struct {
+	lspTool
+}
+
+// NewRenameSymbolTool creates a rename symbol tool
+func NewRenameSymbolTool(m *Manager, workingDir string) *RenameSymbolTool {
+	return &RenameSymbolTool{lspTool{
+		BaseTool: agent.NewBaseTool(
+			"rename_symbol",
+			"Rename a symbol and all its references across the workspace using the language server. Edits files in place.",
+			positionParams(map[string]interface{}{
+				"new_name": map[string]interface{}{
+					"type":        "string",
+					"description": "New name for the symbol",
+				},
+			}, "new_name"),
+		),
+		manager:    m,
+		workingDir: workingDir,
+	}}
+}
+
+func (t *RenameSymbolTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	newName, ok := args["new_name"].(string)
+	if !ok || newName == "" {
+		return nil, fmt.Errorf("new_name parameter is required")
+	}
+
+	newContents, editCount, err := t.plan(ctx, args, newName)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	var files []string
+	for path, content := range newContents {
+		info, err := os.Stat(path)
+		mode := os.FileMode(0644)
+		if err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			return errorResult(fmt.Errorf("write %s: %w", path, err)), nil
+		}
+		agent.RecordFileWrite(ctx, path, []byte(content))
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	return map[string]interface{}{
+		"new_name":      newName,
+		"files_changed": files,
+		"edits":         editCount,
+		"success":       true,
+	}, nil
+}
+
+// PlannedPaths returns the files the rename would change
+func (t *RenameSymbolTool) PlannedPaths(ctx context.Context, args map[string]interface{}) ([]string, error) {
+	newName, _ := args["new_name"].(string)
+	if newName == "" {
+		return nil, nil // Refused by Execute
+	}
+	newContents, _, err := t.plan(ctx, args, newName)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for path := range newContents {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// plan asks the language server for the rename and computes every file's
+// new content (path -> content) without writing anything, so a failure
+// leaves every file untouched
+func (t *RenameSymbolTool) plan(ctx context.Context, args map[string]interface{}, newName string) (map[string]string, int, error) {
+	client, uri, pos, err := t.prepare(ctx, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var edit WorkspaceEdit
+	err = client.Call(ctx, "textDocument/rename", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     pos,
+		"newName":      newName,
+	}, &edit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changes := collectEdits(edit)
+	if len(changes) == 0 {
+		return nil, 0, fmt.Errorf("language server returned no edits")
+	}
+
+	newContents := make(map[string]string, len(changes))
+	editCount := 0
+	for fileURI, edits := range changes {
+		path := uriToPath(fileURI)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("read %s: %w", path, err)
+		}
+		updated, err := ApplyTextEdits(string(data), edits)
+		if err != nil {
+			return nil, 0, fmt.Errorf("apply edits to %s: %w", path, err)
+		}
+		newContents[path] = updated
+		editCount += len(edits)
+	}
+	return newContents, editCount, nil
+}
+
+// collectEdits flattens changes and documentChanges into uri -> edits
+func collectEdits(edit WorkspaceEdit) map[string][]TextEdit {
+	out := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		out[uri] = append(out[uri], edits...)
+	}
+	for _, raw := range edit.DocumentChanges {
+		var doc TextDocumentEdit
+		// Resource operations (create/rename/delete file) have no textDocument and are skipped
+		if json.Unmarshal(raw, &doc) == nil && doc.TextDocument.URI != "" {
+			out[doc.TextDocument.URI] = append(out[doc.TextDocument.URI], doc.Edits...)
+		}
+	}
+	return out
+}
+
+// DiagnosticsTool reports compiler/linter diagnostics for a file
+type DiagnosticsTool struct {
+	lspTool
+}
+
+// NewDiagnosticsTool creates a diagnostics tool
+func NewDiagnosticsTool(m *Manager, workingDir string) *DiagnosticsTool {
+	return &DiagnosticsTool{lspTool{
+		BaseTool: agent.NewBaseTool(
+			"diagnostics",
+			"Get compiler and linter diagnostics (errors, warnings) for a file from the language server.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File to check",
+					},
+				},
+				"required": []string{"path"},
+			},
+		),
+		manager:    m,
+		workingDir: workingDir,
+	}}
+}
+
+func (t *DiagnosticsTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	fullPath := t.resolvePath(ctx, path)
+
+	client, spec, err := t.manager.ClientFor(ctx, fullPath)
+	if err != nil {
+		return errorResult(err), nil
+	}
+	uri, err := client.SyncFile(fullPath, spec.LanguageID)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	diags, fresh := client.WaitDiagnostics(ctx, uri, diagnosticsWait)
+	var items []map[string]interface{}
+	errorCount := 0
+	for _, d := range diags {
+		if d.Severity == 1 {
+			errorCount++
+		}
+		items = append(items, map[string]interface{}{
+			"line":     d.Range.Start.Line + 1,
+			"column":   d.Range.Start.Character + 1,
+			"severity": severityName(d.Severity),
+			"source":   d.Source,
+			"message":  d.Message,
+		})
+	}
+
+	result := map[string]interface{}{
+		"path":        path,
+		"diagnostics": items,
+		"count":       len(items),
+		"errors":      errorCount,
+		"success":     true,
+	}
+	if !fresh {
+		result["note"] = fmt.Sprintf("%s published no diagnostics for the current content within %s; these may be out of date", spec.Name, diagnosticsWait)
+	}
+	return result, nil
+}
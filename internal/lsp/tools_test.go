@@ -0,0 +1,100 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/agent"
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestRenameSymbolProtectedPaths(t *testing.T) {
+	dir := t.TempDir()
+	mainGo := filepath.Join(dir, "main.go")
+	keysGo := filepath.Join(dir, "secrets", "keys.go")
+	os.MkdirAll(filepath.Dir(keysGo), 0755)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644)
+	os.WriteFile(mainGo, []byte("package main\n\nfunc Old() {}\n"), 0644)
+	os.WriteFile(keysGo, []byte("package main\n\nvar _ = Old\n"), 0644)
+
+	// ClientFor only needs gopls to be on PATH; the client is a fake server
+	// answering every rename with edits to both files
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "gopls"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", bin)
+	fromServer, toClient := io.Pipe()
+	fromClient, toServer := io.Pipe()
+	defer toClient.Close()
+	c := &Client{
+		name:        "gopls",
+		root:        dir,
+		stdin:       toServer,
+		pending:     make(map[int64]chan *rpcMessage),
+		diagnostics: make(map[string][]Diagnostic),
+		diagSignal:  make(map[string]chan struct{}),
+		versions:    make(map[string]int),
+	}
+	go c.readLoop(bufio.NewReader(fromServer))
+	go func() {
+		r := bufio.NewReader(fromClient)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			if msg.Method != "textDocument/rename" {
+				continue
+			}
+			edit := func(line, col int) []TextEdit {
+				return []TextEdit{{Range: Range{Start: Position{Line: line, Character: col}, End: Position{Line: line, Character: col + 3}}, NewText: "New"}}
+			}
+			result, _ := json.Marshal(WorkspaceEdit{Changes: map[string][]TextEdit{
+				pathToURI(mainGo): edit(2, 5),
+				pathToURI(keysGo): edit(2, 8),
+			}})
+			body, _ := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: msg.ID, Result: result})
+			fmt.Fprintf(toClient, "Content-Length: %d\r\n\r\n%s", len(body), body)
+		}
+	}()
+	m := NewManager()
+	m.clients["gopls|"+dir] = c
+
+	tool := NewRenameSymbolTool(m, dir)
+	handler := agent.ProtectedPathsMiddleware()(func(ctx context.Context, inv *agent.ToolInvocation) (interface{}, error) {
+		return inv.Tool.Execute(ctx, inv.Args)
+	})
+	session := agent.NewSession("rename")
+	session.SetWorkingDir(dir)
+	ctx := agent.WithSession(context.Background(), session)
+	inv := func() *agent.ToolInvocation {
+		return &agent.ToolInvocation{Tool: tool, Name: "rename_symbol", Args: map[string]interface{}{
+			"path": "main.go", "line": 3.0, "symbol": "Old", "new_name": "New",
+		}}
+	}
+
+	// The rename would edit secrets/keys.go: refused before anything is written
+	if _, err := handler(ctx, inv()); types.CodeOf(err) != types.ErrPermissionDenied || !strings.Contains(err.Error(), "keys.go") {
+		t.Fatalf("rename touching a protected file: err = %v", err)
+	}
+	if data, _ := os.ReadFile(mainGo); strings.Contains(string(data), "New") {
+		t.Error("main.go was renamed although the call was refused")
+	}
+
+	session.AllowProtected([]string{"secrets/**"}, nil)
+	res, err := handler(ctx, inv())
+	if err != nil || res.(map[string]interface{})["success"] != true {
+		t.Fatalf("allowed rename = %v, %v", res, err)
+	}
+	for _, path := range []string{mainGo, keysGo} {
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), "New") {
+			t.Errorf("%s not renamed: %s", path, data)
+		}
+	}
+}
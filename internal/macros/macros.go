@@ -0,0 +1,240 @@
+package macros
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/prompts"
+	"gopkg.in/yaml.v3"
+)
+
+// Macros are recorded interactive sessions: the user turns sent between
+// /record start and /record stop, replayed in order by zen-claw play <name>
+// in one session. Paths in the turns become variables whose defaults are
+// the recorded paths, so a macro recorded on one project runs on another:
+//
+//	name: release-prep
+//	recorded: 2026-10-16T09:12:00Z
+//	vars:
+//	  - name: changelog_md
+//	    default: ~/git/api/CHANGELOG.md
+//	steps:
+//	  - Collect the merged PRs since the last tag
+//	  - Add them to {{.changelog_md}} under a new version heading
+//
+// Steps are Go templates over the variables, as saved prompts are.
+
+// DefaultDir returns the directory macros are saved in
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "macros")
+}
+
+// Macro is a recorded sequence of user turns
+type Macro struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description,omitempty"`
+	Recorded    time.Time     `yaml:"recorded,omitempty"`
+	Provider    string        `yaml:"provider,omitempty"` // Active when recording started
+	Model       string        `yaml:"model,omitempty"`
+	Vars        []prompts.Var `yaml:"vars,omitempty"`
+	Steps       []string      `yaml:"steps"`
+	Path        string        `yaml:"-"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Normalize turns a macro name into its canonical form (release-prep)
+func Normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "-")
+}
+
+// Recorder collects the turns of a recording and turns the paths in them
+// into variables
+type Recorder struct {
+	macro Macro
+	dir   string         // Working directory the relative paths are checked against
+	vars  map[string]int // Recorded path -> index in macro.Vars
+}
+
+// NewRecorder starts recording a macro; relative paths are recognized when
+// they exist under workingDir
+func NewRecorder(name, workingDir, provider, model string) (*Recorder, error) {
+	name = Normalize(name)
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid macro name %q (use letters, digits, - and _)", name)
+	}
+	return &Recorder{
+		macro: Macro{Name: name, Recorded: time.Now().UTC().Truncate(time.Second), Provider: provider, Model: model},
+		dir:   workingDir,
+		vars:  make(map[string]int),
+	}, nil
+}
+
+// Name returns the name of the macro being recorded
+func (r *Recorder) Name() string {
+	return r.macro.Name
+}
+
+// Steps returns the number of turns recorded so far
+func (r *Recorder) Steps() int {
+	return len(r.macro.Steps)
+}
+
+// Add records a user turn
+func (r *Recorder) Add(input string) {
+	r.macro.Steps = append(r.macro.Steps, r.template(input))
+}
+
+// Macro returns the recorded macro
+func (r *Recorder) Macro() Macro {
+	return r.macro
+}
+
+// pathToken matches a word that may be a path, with the punctuation around it
+var pathToken = regexp.MustCompile(`[^\s"'` + "`" + `(),;]+`)
+
+// template escapes template syntax in a turn and replaces its paths with
+// variables
+func (r *Recorder) template(input string) string {
+	input = strings.ReplaceAll(input, "{{", `{{"{{"}}`)
+	return pathToken.ReplaceAllStringFunc(input, func(word string) string {
+		// Sentence punctuation after a path is not part of it
+		trimmed := strings.TrimRight(word, ".:!?")
+		if !r.isPath(trimmed) {
+			return word
+		}
+		return "{{." + r.variable(trimmed) + "}}" + word[len(trimmed):]
+	})
+}
+
+// isPath reports whether a word is a path: absolute, home- or dot-relative,
+// or a file or directory that exists under the working directory
+func (r *Recorder) isPath(word string) bool {
+	if strings.Contains(word, "://") || strings.Contains(word, "{{") {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(word, "/") && len(word) > 1, strings.HasPrefix(word, "~/"), strings.HasPrefix(word, "./"), strings.HasPrefix(word, "../"):
+		return true
+	}
+	if r.dir == "" || !strings.ContainsAny(word, "/.") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(r.dir, word))
+	return err == nil
+}
+
+// nonWord matches what can't be in a variable name
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// variable returns the variable of a recorded path, declaring it on first
+// use with a name taken from the path's base name
+func (r *Recorder) variable(path string) string {
+	if i, ok := r.vars[path]; ok {
+		return r.macro.Vars[i].Name
+	}
+	base := strings.ToLower(filepath.Base(strings.TrimRight(path, "/")))
+	name := strings.Trim(nonWord.ReplaceAllString(base, "_"), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "path_" + name
+	}
+	taken := func(n string) bool {
+		for _, v := range r.macro.Vars {
+			if v.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	candidate := name
+	for n := 2; taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s_%d", name, n)
+	}
+	value := path
+	r.vars[path] = len(r.macro.Vars)
+	r.macro.Vars = append(r.macro.Vars, prompts.Var{Name: candidate, Default: &value})
+	return candidate
+}
+
+// Render returns the macro's steps with the variables filled in (vars
+// override the recorded defaults)
+func (m Macro) Render(vars map[string]string) ([]string, error) {
+	var steps []string
+	for i, step := range m.Steps {
+		p := prompts.Prompt{Name: fmt.Sprintf("%s step %d", m.Name, i+1), Vars: m.Vars, Template: step}
+		text, err := p.Render(vars)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, text)
+	}
+	return steps, nil
+}
+
+// Save writes a macro to dir and returns its path. An existing file is only
+// replaced with overwrite.
+func Save(dir string, m Macro, overwrite bool) (string, error) {
+	m.Name = Normalize(m.Name)
+	if !namePattern.MatchString(m.Name) {
+		return "", fmt.Errorf("invalid macro name %q (use letters, digits, - and _)", m.Name)
+	}
+	if len(m.Steps) == 0 {
+		return "", fmt.Errorf("macro %s has no steps", m.Name)
+	}
+	path := filepath.Join(dir, m.Name+".yaml")
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("macro file already exists: %s", path)
+	}
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// Get reads the named macro from dir
+func Get(dir, name string) (Macro, error) {
+	name = Normalize(name)
+	if !namePattern.MatchString(name) {
+		return Macro{}, fmt.Errorf("invalid macro name %q (use letters, digits, - and _)", name)
+	}
+	path := filepath.Join(dir, name+".yaml")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Macro{}, fmt.Errorf("macro %q not found in %s", name, dir)
+	}
+	if err != nil {
+		return Macro{}, err
+	}
+	var m Macro
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return Macro{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(m.Steps) == 0 {
+		return Macro{}, fmt.Errorf("%s: no steps", path)
+	}
+	m.Name, m.Path = name, path
+	return m, nil
+}
+
+// List returns the names of the macros in dir
+func List(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".yaml") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
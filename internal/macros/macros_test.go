@@ -0,0 +1,75 @@
+package macros
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "CHANGELOG.md"), []byte("# Changes\n"), 0644)
+
+	r, err := NewRecorder("Release Prep", dir, "qwen", "qwen-max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Add("Collect merged PRs since v1.3.0 and add them to CHANGELOG.md.")
+	r.Add("Copy CHANGELOG.md to ~/notes/release.md, then update docs/ (see https://x.io/a/b)")
+	r.Add("Keep {{ braces }} and 1.5 as they are")
+	m := r.Macro()
+
+	want := []string{
+		"Collect merged PRs since v1.3.0 and add them to {{.changelog_md}}.",
+		"Copy {{.changelog_md}} to {{.release_md}}, then update {{.docs}} (see https://x.io/a/b)",
+		`Keep {{"{{"}} braces }} and 1.5 as they are`,
+	}
+	if m.Name != "release-prep" || m.Provider != "qwen" || strings.Join(m.Steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("steps = %q", m.Steps)
+	}
+	if len(m.Vars) != 3 || *m.Vars[1].Default != "~/notes/release.md" {
+		t.Errorf("vars = %+v", m.Vars)
+	}
+
+	steps, err := m.Render(map[string]string{"release_md": "/tmp/r.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0] != "Collect merged PRs since v1.3.0 and add them to CHANGELOG.md." || steps[1] != "Copy CHANGELOG.md to /tmp/r.md, then update docs/ (see https://x.io/a/b)" ||
+		steps[2] != "Keep {{ braces }} and 1.5 as they are" {
+		t.Errorf("Render = %q", steps)
+	}
+	if _, err := m.Render(map[string]string{"nope": "x"}); err == nil {
+		t.Error("unknown variable accepted")
+	}
+	if _, err := NewRecorder("../x", dir, "", ""); err == nil {
+		t.Error("accepted a name with a path")
+	}
+}
+
+func TestSaveGet(t *testing.T) {
+	dir := t.TempDir()
+	r, _ := NewRecorder("prep", "", "", "")
+	if _, err := Save(dir, r.Macro(), false); err == nil {
+		t.Error("saved a macro without steps")
+	}
+	r.Add("Bump the version in /etc/app/version")
+	if _, err := Save(dir, r.Macro(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Save(dir, r.Macro(), false); err == nil {
+		t.Error("replaced an existing macro without overwrite")
+	}
+	m, err := Get(dir, "Prep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps, err := m.Render(nil); err != nil || steps[0] != "Bump the version in /etc/app/version" {
+		t.Errorf("Render = %q, %v", steps, err)
+	}
+	if names := List(dir); len(names) != 1 || names[0] != "prep" {
+		t.Errorf("List = %v", names)
+	}
+}
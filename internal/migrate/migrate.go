@@ -0,0 +1,245 @@
+// Package migrate helps upgrade a Go dependency: it bumps the module,
+// builds the code and tests, and turns the compiler errors into a worklist
+// of the call sites to fix.
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Target is the module version to upgrade to
+type Target struct {
+	Module  string `json:"module"`
+	Version string `json:"version"` // A version, a branch or commit, or latest
+}
+
+// ParseTarget parses module@version
+func ParseTarget(arg string) (Target, error) {
+	module, version, ok := strings.Cut(arg, "@")
+	if !ok || module == "" || version == "" {
+		return Target{}, fmt.Errorf("want <module>@<version>, got %q", arg)
+	}
+	if strings.HasPrefix(module, "-") || strings.HasPrefix(version, "-") || strings.ContainsAny(arg, " \t\n") {
+		return Target{}, fmt.Errorf("invalid module %q", arg)
+	}
+	return Target{Module: module, Version: version}, nil
+}
+
+func (t Target) String() string {
+	return t.Module + "@" + t.Version
+}
+
+// Bump is a dependency upgrade that was applied
+type Bump struct {
+	Module string `json:"module"`
+	From   string `json:"from,omitempty"` // Empty when the module wasn't required
+	To     string `json:"to"`
+	OldDir string `json:"old_dir,omitempty"` // Sources of both versions in the module cache
+	NewDir string `json:"new_dir,omitempty"`
+}
+
+// Upgrade runs go get module@version in the module at dir
+func Upgrade(ctx context.Context, dir string, target Target) (*Bump, error) {
+	bump := &Bump{Module: target.Module}
+	bump.From, bump.OldDir = moduleVersion(ctx, dir, target.Module)
+	if out, err := goCmd(ctx, dir, "get", target.String()); err != nil {
+		return nil, fmt.Errorf("go get %s: %s", target, strings.TrimSpace(out))
+	}
+	bump.To, bump.NewDir = moduleVersion(ctx, dir, target.Module)
+	if bump.To == "" {
+		return nil, fmt.Errorf("%s is not a dependency of the module after go get", target.Module)
+	}
+	if bump.From != "" && bump.OldDir == "" {
+		// The old version may never have been downloaded on this machine
+		if out, err := goCmd(ctx, dir, "mod", "download", "-json", target.Module+"@"+bump.From); err == nil {
+			var download struct{ Dir string }
+			if json.Unmarshal([]byte(out), &download) == nil {
+				bump.OldDir = download.Dir
+			}
+		}
+	}
+	return bump, nil
+}
+
+// moduleVersion returns the version of module the build uses and its
+// directory in the module cache
+func moduleVersion(ctx context.Context, dir, module string) (string, string) {
+	out, err := goCmd(ctx, dir, "list", "-m", "-f", "{{.Version}}\t{{.Dir}}", module)
+	if err != nil {
+		return "", ""
+	}
+	version, moduleDir, _ := strings.Cut(strings.TrimSpace(out), "\t")
+	return version, moduleDir
+}
+
+func goCmd(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// CompileError is one error of the compiler
+type CompileError struct {
+	Package string `json:"package,omitempty"`
+	File    string `json:"file"` // Relative to the module
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e CompileError) String() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Build compiles the packages and their tests in the module at dir and
+// returns the compiler errors; output is the raw output of the failing
+// step. An error means the build couldn't run at all.
+func Build(ctx context.Context, dir string) ([]CompileError, string, error) {
+	// go build covers packages without tests; go test -run=^$ compiles the
+	// tests without running them
+	for _, args := range [][]string{{"build", "./..."}, {"test", "-count=1", "-run=^$", "./..."}} {
+		out, err := goCmd(ctx, dir, args...)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, out, ctx.Err()
+		}
+		errs := ParseErrors(out, dir)
+		if len(errs) == 0 && args[0] == "build" {
+			return nil, out, fmt.Errorf("go build: %s", strings.TrimSpace(out))
+		}
+		if len(errs) == 0 {
+			// Compiled, but a test init or TestMain failed: not a compile error
+			continue
+		}
+		return errs, out, nil
+	}
+	return nil, "", nil
+}
+
+// compileErrorRe matches "path/file.go:12:5: message" ("./" and the column
+// are optional)
+var compileErrorRe = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// ParseErrors extracts the compiler errors from go build/test output, with
+// the files made relative to dir ("too many errors" is dropped: the next
+// build lists the rest); indented lines after an error (have/want
+// of a call) are added to its message. Repeated errors (a package built for
+// its tests and alone) are kept once.
+func ParseErrors(output, dir string) []CompileError {
+	var errs []CompileError
+	seen := map[string]bool{}
+	pkg := ""
+	current := -1 // The error continuation lines belong to
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(raw, "\t") && line != "" {
+			if current >= 0 {
+				errs[current].Message += "; " + line
+			}
+			continue
+		}
+		current = -1
+		if p, ok := strings.CutPrefix(line, "# "); ok {
+			pkg, _, _ = strings.Cut(p, " ") // "# pkg [pkg.test]"
+			continue
+		}
+		line = strings.TrimPrefix(line, "vet: ")
+		m := compileErrorRe.FindStringSubmatch(line)
+		if m == nil || m[4] == "too many errors" {
+			continue
+		}
+		file := m[1]
+		if filepath.IsAbs(file) {
+			if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+		}
+		file = filepath.ToSlash(filepath.Clean(file))
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		e := CompileError{Package: pkg, File: file, Line: lineNo, Column: col, Message: m[4]}
+		if key := e.String(); !seen[key] {
+			seen[key] = true
+			errs = append(errs, e)
+			current = len(errs) - 1
+		}
+	}
+	return errs
+}
+
+// WorkItem is the errors of one file
+type WorkItem struct {
+	File   string         `json:"file"`
+	Errors []CompileError `json:"errors"`
+}
+
+// Worklist groups errors by file, the files with the most errors first
+func Worklist(errs []CompileError) []WorkItem {
+	byFile := map[string]*WorkItem{}
+	var items []*WorkItem
+	for _, e := range errs {
+		item := byFile[e.File]
+		if item == nil {
+			item = &WorkItem{File: e.File}
+			byFile[e.File] = item
+			items = append(items, item)
+		}
+		item.Errors = append(item.Errors, e)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if len(items[i].Errors) != len(items[j].Errors) {
+			return len(items[i].Errors) > len(items[j].Errors)
+		}
+		return items[i].File < items[j].File
+	})
+	list := make([]WorkItem, len(items))
+	for i, item := range items {
+		sort.Slice(item.Errors, func(a, b int) bool { return item.Errors[a].Line < item.Errors[b].Line })
+		list[i] = *item
+	}
+	return list
+}
+
+// Markdown renders the worklist, at most maxErrors errors (0 = all)
+func Markdown(list []WorkItem, maxErrors int) string {
+	var b strings.Builder
+	shown, total := 0, 0
+	for _, item := range list {
+		total += len(item.Errors)
+	}
+	for _, item := range list {
+		if maxErrors > 0 && shown >= maxErrors {
+			break
+		}
+		fmt.Fprintf(&b, "- [ ] %s (%d)\n", item.File, len(item.Errors))
+		for _, e := range item.Errors {
+			if maxErrors > 0 && shown >= maxErrors {
+				break
+			}
+			fmt.Fprintf(&b, "    - %d: %s\n", e.Line, e.Message)
+			shown++
+		}
+	}
+	if shown < total {
+		fmt.Fprintf(&b, "\n(%d more errors not shown; they'll be listed after the next build)\n", total-shown)
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("github.com/redis/go-redis/v9@v9.5.1")
+	if err != nil || target.Module != "github.com/redis/go-redis/v9" || target.Version != "v9.5.1" {
+		t.Errorf("target = %+v, %v", target, err)
+	}
+	for _, bad := range []string{"github.com/spf13/cobra", "@v1.0.0", "github.com/spf13/cobra@", "-x@v1", "a@v1 -b"} {
+		if _, err := ParseTarget(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	output := `# example.com/app/store
+store/cache.go:14:9: not enough arguments in call to redis.NewClient
+	have ()
+	want (*redis.Options)
+./store/cache.go:30:2: undefined: redis.Nil
+/repo/store/cache.go:31:2: undefined: redis.Nil
+store/cache.go:14:9: too many errors
+# example.com/app/store [example.com/app/store.test]
+store/cache.go:14:9: not enough arguments in call to redis.NewClient
+	have ()
+	want (*redis.Options)
+store/cache_test.go:8:15: cannot use ctx (variable of type string) as context.Context value
+# example.com/app/api
+vet: api/handler.go:3:2: "example.com/app/store" imported and not used
+FAIL	example.com/app/api [build failed]
+`
+	errs := ParseErrors(output, "/repo")
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.String())
+	}
+	want := []string{
+		"store/cache.go:14:9: not enough arguments in call to redis.NewClient; have (); want (*redis.Options)",
+		"store/cache.go:30:2: undefined: redis.Nil",
+		"store/cache.go:31:2: undefined: redis.Nil",
+		"store/cache_test.go:8:15: cannot use ctx (variable of type string) as context.Context value",
+		`api/handler.go:3:2: "example.com/app/store" imported and not used`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if errs[3].Package != "example.com/app/store" || errs[4].Package != "example.com/app/api" {
+		t.Errorf("packages = %q, %q", errs[3].Package, errs[4].Package)
+	}
+
+	list := Worklist(errs)
+	if len(list) != 3 || list[0].File != "store/cache.go" || len(list[0].Errors) != 3 || list[0].Errors[2].Line != 31 {
+		t.Fatalf("worklist = %+v", list)
+	}
+	md := Markdown(list, 4)
+	if !strings.Contains(md, "- [ ] store/cache.go (3)") || !strings.Contains(md, "(1 more errors not shown") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
+
+func TestUpgradeAndBuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	// A dependency replaced by a local directory resolves any version offline
+	t.Setenv("GOPROXY", "off")
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("dep/go.mod", "module example.com/dep\n\ngo 1.21\n")
+	write("dep/dep.go", "package dep\n\n// Hello takes a name since v1.2.0\nfunc Hello(name string) string { return \"hello \" + name }\n")
+	write("app/go.mod", "module example.com/app\n\ngo 1.21\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n")
+	write("app/app.go", "package app\n\nimport \"example.com/dep\"\n\nfunc Greet() string { return dep.Hello() }\n")
+	write("app/app_test.go", "package app\n\nimport (\n\t\"testing\"\n\n\t\"example.com/dep\"\n)\n\nfunc TestGreet(t *testing.T) { _ = dep.Hello(1) }\n")
+	app := filepath.Join(dir, "app")
+
+	target, _ := ParseTarget("example.com/dep@v1.2.0")
+	bump, err := Upgrade(context.Background(), app, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bump.From != "v1.0.0" || bump.To != "v1.2.0" || bump.NewDir != filepath.Join(dir, "dep") {
+		t.Errorf("bump = %+v", bump)
+	}
+
+	errs, _, err := Build(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].File != "app.go" || !strings.Contains(errs[0].Message, "not enough arguments") {
+		t.Fatalf("errors = %+v", errs)
+	}
+
+	// Fixed, the build moves on to the tests
+	write("app/app.go", "package app\n\nimport \"example.com/dep\"\n\nfunc Greet() string { return dep.Hello(\"world\") }\n")
+	errs, _, err = Build(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].File != "app_test.go" {
+		t.Fatalf("errors = %+v", errs)
+	}
+
+	write("app/app_test.go", "package app\n\nimport \"testing\"\n\nfunc TestGreet(t *testing.T) { Greet() }\n")
+	if errs, out, err := Build(context.Background(), app); err != nil || len(errs) != 0 {
+		t.Errorf("errors = %+v, %v\n%s", errs, err, out)
+	}
+}
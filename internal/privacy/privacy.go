@@ -0,0 +1,267 @@
+// Package privacy removes personal data and internal hostnames from prompts
+// before they are sent to model providers.
+//
+// Each distinct value is replaced by a numbered placeholder ([EMAIL_1],
+// [PHONE_2], ...). The same value gets the same placeholder everywhere in a
+// request, so the model can still tell values apart and refer to them;
+// placeholders in its answer and tool calls are put back before anything
+// runs locally, so files and commands get the real values.
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// Kinds of scrubbed content
+const (
+	KindEmail      = "email"
+	KindPhone      = "phone"
+	KindCreditCard = "credit_card"
+	KindHostname   = "hostname"
+)
+
+// Kinds lists every kind, in the order they are scrubbed
+var Kinds = []string{KindEmail, KindCreditCard, KindPhone, KindHostname}
+
+// internalSuffixes are top-level names only used inside private networks
+var internalSuffixes = []string{"internal", "local", "corp", "lan", "intranet", "home.arpa"}
+
+var placeholderPrefix = map[string]string{
+	KindEmail:      "EMAIL",
+	KindPhone:      "PHONE",
+	KindCreditCard: "CARD",
+	KindHostname:   "HOST",
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// 13-19 digits, optionally grouped by spaces or dashes (Luhn-checked)
+	cardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// Separators are required, so plain numbers (IDs, timestamps) don't match
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,5}[ .-]\d{4}\b`)
+	hostLabel    = `(?:[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?\.)`
+)
+
+// Scrubber replaces the configured kinds of content with placeholders
+type Scrubber struct {
+	kinds       []string
+	hostPattern *regexp.Regexp
+}
+
+// New creates a scrubber for kinds (empty = all). internalDomains are domains
+// whose hosts are internal (e.g. corp.example.com), besides names ending in
+// .internal, .local, .corp, .lan, .intranet or .home.arpa.
+func New(kinds, internalDomains []string) (*Scrubber, error) {
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+	s := &Scrubber{}
+	for _, kind := range Kinds {
+		if slices.Contains(kinds, kind) {
+			s.kinds = append(s.kinds, kind)
+		}
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(Kinds, kind) {
+			return nil, fmt.Errorf("unknown kind %q (use %s)", kind, strings.Join(Kinds, ", "))
+		}
+	}
+
+	suffixes := make([]string, len(internalSuffixes))
+	for i, suffix := range internalSuffixes {
+		suffixes[i] = regexp.QuoteMeta(suffix)
+	}
+	pattern := `\b` + hostLabel + `+(?:` + strings.Join(suffixes, "|") + `)\b`
+	var domains []string
+	for _, domain := range internalDomains {
+		if domain = strings.Trim(strings.TrimSpace(domain), "."); domain != "" {
+			domains = append(domains, regexp.QuoteMeta(domain))
+		}
+	}
+	if len(domains) > 0 {
+		pattern += `|(?i:\b` + hostLabel + `*(?:` + strings.Join(domains, "|") + `)\b)`
+	}
+	// The suffixes match in lowercase only: time.Local is Go, not a host
+	s.hostPattern = regexp.MustCompile(pattern)
+	return s, nil
+}
+
+// Redactions records what was scrubbed from one request, to put it back in
+// the answer and report it
+type Redactions struct {
+	originals map[string]string // Placeholder -> original
+	byValue   map[string]string // Original -> placeholder
+	next      map[string]int    // Kind -> last placeholder number
+	Counts    map[string]int    // Occurrences replaced by kind
+}
+
+// NewRedactions creates an empty record
+func NewRedactions() *Redactions {
+	return &Redactions{
+		originals: make(map[string]string),
+		byValue:   make(map[string]string),
+		next:      make(map[string]int),
+		Counts:    make(map[string]int),
+	}
+}
+
+// Total returns the number of replaced occurrences
+func (r *Redactions) Total() int {
+	total := 0
+	for _, n := range r.Counts {
+		total += n
+	}
+	return total
+}
+
+// Summary describes the replacements by kind, e.g. "2 email, 1 hostname"
+func (r *Redactions) Summary() string {
+	var parts []string
+	for _, kind := range Kinds {
+		if n := r.Counts[kind]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// placeholder returns the value's placeholder, assigning one on first use
+func (r *Redactions) placeholder(kind, value string) string {
+	r.Counts[kind]++
+	if p, ok := r.byValue[value]; ok {
+		return p
+	}
+	r.next[kind]++
+	p := fmt.Sprintf("[%s_%d]", placeholderPrefix[kind], r.next[kind])
+	r.byValue[value] = p
+	r.originals[p] = value
+	return p
+}
+
+// Text scrubs text, recording the replacements in r
+func (s *Scrubber) Text(text string, r *Redactions) string {
+	if text == "" {
+		return text
+	}
+	for _, kind := range s.kinds {
+		switch kind {
+		case KindEmail:
+			text = replace(emailPattern, text, kind, r, nil)
+		case KindCreditCard:
+			text = replace(cardPattern, text, kind, r, luhnValid)
+		case KindPhone:
+			text = replace(phonePattern, text, kind, r, nil)
+		case KindHostname:
+			text = replace(s.hostPattern, text, kind, r, nil)
+		}
+	}
+	return text
+}
+
+// replace swaps matches (that pass valid, when set) for placeholders
+func replace(re *regexp.Regexp, text, kind string, r *Redactions, valid func(string) bool) string {
+	return re.ReplaceAllStringFunc(text, func(match string) string {
+		if valid != nil && !valid(match) {
+			return match
+		}
+		return r.placeholder(kind, match)
+	})
+}
+
+// luhnValid reports whether a digit string (spaces and dashes ignored)
+// passes the Luhn check card numbers carry
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Messages returns a scrubbed copy of messages (contents and string tool
+// call arguments)
+func (s *Scrubber) Messages(messages []ai.Message, r *Redactions) []ai.Message {
+	out := make([]ai.Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = s.Text(msg.Content, r)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ai.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Args = mapStrings(call.Args, func(v string) string { return s.Text(v, r) })
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// Restore puts the original values back in text
+func (r *Redactions) Restore(text string) string {
+	if len(r.originals) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(r.originals))
+	for p, original := range r.originals {
+		pairs = append(pairs, p, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RestoreResponse puts the original values back in a model's answer and
+// tool calls
+func (r *Redactions) RestoreResponse(resp *ai.ChatResponse) {
+	if resp == nil || len(r.originals) == 0 {
+		return
+	}
+	resp.Content = r.Restore(resp.Content)
+	for i, call := range resp.ToolCalls {
+		resp.ToolCalls[i].Args = mapStrings(call.Args, r.Restore)
+	}
+}
+
+// mapStrings applies fn to the string values in tool arguments, nested ones
+// included (returns a copy)
+func mapStrings(args map[string]interface{}, fn func(string) string) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = mapValue(v, fn)
+	}
+	return out
+}
+
+func mapValue(v interface{}, fn func(string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		return mapStrings(v, fn)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = mapValue(item, fn)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+func TestScrubText(t *testing.T) {
+	s, err := New(nil, []string{"acme.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"email", "mail ana@example.com now", "mail [EMAIL_1] now"},
+		{"same value same placeholder", "ana@example.com, bob@example.com, ana@example.com", "[EMAIL_1], [EMAIL_2], [EMAIL_1]"},
+		{"card", "card 4111 1111 1111 1111 ok", "card [CARD_1] ok"},
+		{"not a card (Luhn)", "id 1234567890123456", "id 1234567890123456"},
+		{"phone", "call +1 415 555 0100 or (11) 98765-4321", "call [PHONE_1] or [PHONE_2]"},
+		{"plain numbers kept", "port 8080, pid 123456, 2026-10-16", "port 8080, pid 123456, 2026-10-16"},
+		{"internal host", "ssh db1.prod.internal and redis.default.svc.cluster.local", "ssh [HOST_1] and [HOST_2]"},
+		{"configured domain", "https://Wiki.ACME.example/page", "https://[HOST_1]/page"},
+		{"code kept", "time.Local and example.com", "time.Local and example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Text(tt.in, NewRedactions()); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New([]string{"ssn"}, nil); err == nil {
+		t.Error("New() accepted an unknown kind")
+	}
+	emailsOnly, _ := New([]string{KindEmail}, nil)
+	if got := emailsOnly.Text("ana@example.com db.internal", NewRedactions()); got != "[EMAIL_1] db.internal" {
+		t.Errorf("emails only: %q", got)
+	}
+}
+
+func TestScrubRoundTrip(t *testing.T) {
+	s, _ := New(nil, nil)
+	r := NewRedactions()
+	messages := []ai.Message{
+		{Role: "user", Content: "Email ana@example.com about db.prod.internal"},
+		{Role: "assistant", ToolCalls: []ai.ToolCall{{ID: "1", Name: "exec", Args: map[string]interface{}{"command": "ping db.prod.internal"}}}},
+	}
+	scrubbed := s.Messages(messages, r)
+	if strings.Contains(scrubbed[0].Content, "ana@") || scrubbed[1].ToolCalls[0].Args["command"] != "ping [HOST_1]" {
+		t.Errorf("scrubbed = %+v", scrubbed)
+	}
+	if messages[0].Content != "Email ana@example.com about db.prod.internal" {
+		t.Error("Messages() changed its input")
+	}
+	if r.Summary() != "1 email, 2 hostname" || r.Total() != 3 {
+		t.Errorf("Summary() = %q, Total() = %d", r.Summary(), r.Total())
+	}
+
+	resp := &ai.ChatResponse{
+		Content:   "Sent to [EMAIL_1].",
+		ToolCalls: []ai.ToolCall{{ID: "2", Name: "write_file", Args: map[string]interface{}{"content": "host: [HOST_1]", "lines": []interface{}{"[EMAIL_1]"}}}},
+	}
+	r.RestoreResponse(resp)
+	if resp.Content != "Sent to ana@example.com." || resp.ToolCalls[0].Args["content"] != "host: db.prod.internal" ||
+		resp.ToolCalls[0].Args["lines"].([]interface{})[0] != "ana@example.com" {
+		t.Errorf("restored = %+v", resp)
+	}
+}
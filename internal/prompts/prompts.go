@@ -0,0 +1,374 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompts are saved, parameterized tasks: complex instructions written once
+// and run with zen-claw prompt run <name>, or sent in interactive mode with
+// /prompt <name>. Each is a markdown file in ~/.zen/zen-claw/prompts/<name>.md:
+//
+//	---
+//	description: Review a database migration
+//	vars:
+//	  - name: table
+//	    description: Table the migration changes
+//	  - name: engine
+//	    default: postgres
+//	---
+//	Review the migration of {{.table}} for {{.engine}}: locking, backfills,
+//	and how it rolls back.
+//
+// The body is a Go text/template over the variables. Variables without a
+// default must be given; variables the template uses but doesn't declare
+// are required too.
+
+// DefaultDir returns the directory prompts are saved in
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "prompts")
+}
+
+// Var is a declared variable of a prompt
+type Var struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description,omitempty"`
+	Default     *string `yaml:"default,omitempty"` // Nil: the variable is required
+}
+
+// Required reports whether the variable must be given
+func (v Var) Required() bool {
+	return v.Default == nil
+}
+
+// Prompt is a saved prompt
+type Prompt struct {
+	Name        string `yaml:"-"`
+	Description string `yaml:"description,omitempty"` // One line, for listings
+	Vars        []Var  `yaml:"vars,omitempty"`
+	Template    string `yaml:"-"` // The file body
+	Path        string `yaml:"-"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// varPattern is a valid variable name (a template field)
+var varPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Normalize turns a prompt name into its canonical form (review-migration)
+func Normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "-")
+}
+
+// Load returns the prompts in dir sorted by name. Files that fail to parse
+// are logged and skipped; a missing dir is not an error.
+func Load(dir string) []Prompt {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Prompts] Failed to read %s: %v", dir, err)
+		}
+		return nil
+	}
+	var list []Prompt
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		p, err := ReadFile(path)
+		if err != nil {
+			log.Printf("[Prompts] Skipping %s: %v", path, err)
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get reads the named prompt from dir
+func Get(dir, name string) (Prompt, error) {
+	name = Normalize(name)
+	if !namePattern.MatchString(name) {
+		return Prompt{}, fmt.Errorf("invalid prompt name %q (use letters, digits, - and _)", name)
+	}
+	p, err := ReadFile(filepath.Join(dir, name+".md"))
+	if os.IsNotExist(err) {
+		return p, fmt.Errorf("prompt %q not found in %s", name, dir)
+	}
+	return p, err
+}
+
+// ReadFile reads a prompt file
+func ReadFile(path string) (Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Prompt{}, err
+	}
+	p, err := Parse(strings.TrimSuffix(filepath.Base(path), ".md"), data)
+	p.Path = path
+	return p, err
+}
+
+// Parse reads a prompt file: optional YAML front matter, then the template.
+// Variables the template uses without declaring them are added as required.
+func Parse(name string, data []byte) (Prompt, error) {
+	p := Prompt{}
+	body := string(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		front, after, found := strings.Cut(rest, "\n---")
+		if !found {
+			return p, fmt.Errorf("front matter is not closed with ---")
+		}
+		if err := yaml.Unmarshal([]byte(front), &p); err != nil {
+			return p, fmt.Errorf("front matter: %w", err)
+		}
+		body = after
+		if i := strings.IndexByte(body, '\n'); i >= 0 {
+			body = body[i+1:]
+		} else {
+			body = ""
+		}
+	}
+	p.Name = Normalize(name)
+	if !namePattern.MatchString(p.Name) {
+		return p, fmt.Errorf("invalid prompt name %q (use letters, digits, - and _)", name)
+	}
+	p.Template = strings.TrimSpace(body)
+	if p.Template == "" {
+		return p, fmt.Errorf("prompt %s is empty", p.Name)
+	}
+	if err := p.declareUsed(); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// declareUsed checks the variables and the template, and declares the
+// variables the template uses but the front matter doesn't
+func (p *Prompt) declareUsed() error {
+	declared := make(map[string]bool)
+	for _, v := range p.Vars {
+		if !varPattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q (use letters, digits and _)", v.Name)
+		}
+		declared[v.Name] = true
+	}
+	tmpl, err := template.New(p.Name).Parse(p.Template)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	for _, name := range templateFields(tmpl.Tree) {
+		if !declared[name] {
+			p.Vars = append(p.Vars, Var{Name: name})
+			declared[name] = true
+		}
+	}
+	return nil
+}
+
+// templateFields lists the top-level fields ({{.name}}) a template uses, in
+// order of first use
+func templateFields(tree *parse.Tree) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	var walk func(node parse.Node)
+	walkPipe := func(pipe *parse.PipeNode) {
+		if pipe == nil {
+			return
+		}
+		for _, cmd := range pipe.Cmds {
+			for _, arg := range cmd.Args {
+				walk(arg)
+			}
+		}
+	}
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walkPipe(n.Pipe)
+		case *parse.PipeNode:
+			walkPipe(n)
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		case *parse.IfNode:
+			walkPipe(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			// Fields inside with refer to its value, not the variables
+			walkPipe(n.Pipe)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walkPipe(n.Pipe)
+			walk(n.ElseList)
+		}
+	}
+	if tree != nil {
+		walk(tree.Root)
+	}
+	return fields
+}
+
+// Render fills in the template. Unknown and missing variables are errors
+// naming the declared ones.
+func (p Prompt) Render(vars map[string]string) (string, error) {
+	values := make(map[string]string, len(p.Vars))
+	declared := make(map[string]bool, len(p.Vars))
+	var missing []string
+	for _, v := range p.Vars {
+		declared[v.Name] = true
+		if value, ok := vars[v.Name]; ok {
+			values[v.Name] = value
+		} else if v.Default != nil {
+			values[v.Name] = *v.Default
+		} else {
+			missing = append(missing, v.Name)
+		}
+	}
+	for name := range vars {
+		if !declared[name] {
+			return "", fmt.Errorf("prompt %s has no variable %q (variables: %s)", p.Name, name, p.VarNames())
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s needs %s (--var name=value)", p.Name, strings.Join(missing, ", "))
+	}
+	tmpl, err := template.New(p.Name).Option("missingkey=error").Parse(p.Template)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, values); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// VarNames lists the variables, required ones marked with *
+func (p Prompt) VarNames() string {
+	if len(p.Vars) == 0 {
+		return "none"
+	}
+	names := make([]string, len(p.Vars))
+	for i, v := range p.Vars {
+		names[i] = v.Name
+		if v.Required() {
+			names[i] += "*"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Format renders a prompt as a prompt file
+func Format(p Prompt) []byte {
+	var sb strings.Builder
+	if p.Description != "" || len(p.Vars) > 0 {
+		front, _ := yaml.Marshal(p)
+		sb.WriteString("---\n")
+		sb.Write(front)
+		sb.WriteString("---\n")
+	}
+	sb.WriteString(strings.TrimSpace(p.Template))
+	sb.WriteString("\n")
+	return []byte(sb.String())
+}
+
+// Save checks a prompt and writes it to dir, returning its path. An
+// existing file is only replaced with overwrite.
+func Save(dir string, p Prompt, overwrite bool) (string, error) {
+	checked, err := Parse(p.Name, Format(p))
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, checked.Name+".md")
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("prompt file already exists: %s", path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, Format(checked), 0644)
+}
+
+// Delete removes a saved prompt
+func Delete(dir, name string) error {
+	p, err := Get(dir, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p.Path)
+}
+
+// ParseVars parses name=value arguments (--var flags, or the words after
+// /prompt <name>)
+func ParseVars(args []string) (map[string]string, error) {
+	vars := make(map[string]string, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || !varPattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable %q (want name=value)", arg)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// SplitArgs splits an interactive command line into words, keeping quoted
+// parts ('...' or "...") together: table="user accounts" is one word
+func SplitArgs(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
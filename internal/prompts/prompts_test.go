@@ -0,0 +1,89 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAndRender(t *testing.T) {
+	p, err := Parse("Review Migration", []byte("---\ndescription: Review a migration\nvars:\n  - name: table\n    description: Table it changes\n  - name: engine\n    default: postgres\n---\nReview {{.table}} on {{.engine}}.{{if .focus}} Focus on {{.focus}}.{{end}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "review-migration" || p.Description != "Review a migration" || p.VarNames() != "table*, engine, focus*" {
+		t.Errorf("prompt = %+v (vars %s)", p, p.VarNames())
+	}
+
+	got, err := p.Render(map[string]string{"table": "users", "focus": "locking"})
+	if err != nil || got != "Review users on postgres. Focus on locking." {
+		t.Errorf("Render = %q, %v", got, err)
+	}
+	if _, err := p.Render(map[string]string{"focus": "x"}); err == nil || !strings.Contains(err.Error(), "needs table") {
+		t.Errorf("missing variable: %v", err)
+	}
+	if _, err := p.Render(map[string]string{"table": "users", "focus": "", "tabel": "x"}); err == nil || !strings.Contains(err.Error(), `no variable "tabel"`) {
+		t.Errorf("unknown variable: %v", err)
+	}
+
+	for _, bad := range []string{"", "---\nvars: [{name: a-b}]\n---\nx", "Review {{.table"} {
+		if _, err := Parse("bad", []byte(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+	if _, err := Parse("../etc", []byte("x")); err == nil {
+		t.Error("accepted a name with a path")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	def := "main"
+	path, err := Save(dir, Prompt{Name: "release notes", Vars: []Var{{Name: "branch", Default: &def}}, Template: "Write release notes for {{.branch}} since {{.tag}}."}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "release-notes.md" {
+		t.Errorf("path = %s", path)
+	}
+	if _, err := Save(dir, Prompt{Name: "release-notes", Template: "x"}, false); err == nil {
+		t.Error("replaced an existing prompt without overwrite")
+	}
+	os.WriteFile(filepath.Join(dir, "broken.md"), []byte("{{.x"), 0644)
+
+	list := Load(dir)
+	if len(list) != 1 || list[0].VarNames() != "branch, tag*" {
+		t.Fatalf("Load = %+v", list)
+	}
+	p, err := Get(dir, "Release Notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.Render(map[string]string{"tag": "v1.2.0"}); err != nil || got != "Write release notes for main since v1.2.0." {
+		t.Errorf("Render = %q, %v", got, err)
+	}
+	if err := Delete(dir, "release-notes"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(dir, "release-notes"); err == nil {
+		t.Error("deleted prompt still found")
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	words, err := SplitArgs(`review-migration table="user accounts" focus='lock "order"' x=`)
+	if err != nil || strings.Join(words, "|") != `review-migration|table=user accounts|focus=lock "order"|x=` {
+		t.Errorf("SplitArgs = %q, %v", words, err)
+	}
+	if _, err := SplitArgs(`a b="c`); err == nil {
+		t.Error("unclosed quote accepted")
+	}
+	vars, err := ParseVars(words[1:])
+	if err != nil || vars["table"] != "user accounts" || vars["x"] != "" {
+		t.Errorf("ParseVars = %v, %v", vars, err)
+	}
+	if _, err := ParseVars([]string{"novalue"}); err == nil {
+		t.Error("variable without = accepted")
+	}
+}
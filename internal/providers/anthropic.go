@@ -137,6 +137,9 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ai.ChatRequest,
 	var fullContent strings.Builder
 	var toolCalls []ai.ToolCall
 	var stopReason string
+	var toolUses []*streamedToolUse
+	blocks := make(map[int]*streamedToolUse)
+	lastBlock := -1
 
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
@@ -151,13 +154,18 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ai.ChatRequest,
 
 		var event struct {
 			Type  string `json:"type"`
+			Index int    `json:"index"`
 			Delta struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
 			} `json:"delta"`
 			ContentBlock struct {
 				Type string `json:"type"`
 				Text string `json:"text"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
 			} `json:"content_block"`
 		}
 
@@ -165,6 +173,24 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ai.ChatRequest,
 			continue
 		}
 
+		// Tool calls arrive as a tool_use block start and input_json_delta fragments
+		switch {
+		case event.Type == "content_block_start":
+			lastBlock = event.Index
+			if event.ContentBlock.Type == "tool_use" {
+				use := &streamedToolUse{id: event.ContentBlock.ID, name: event.ContentBlock.Name}
+				blocks[event.Index] = use
+				toolUses = append(toolUses, use)
+			}
+		case event.Delta.Type == "input_json_delta":
+			if use := blocks[event.Index]; use != nil {
+				use.input.WriteString(event.Delta.PartialJSON)
+			}
+			continue
+		case event.Type == "message_delta" && event.Delta.StopReason != "":
+			stopReason = event.Delta.StopReason
+		}
+
 		text := ""
 		if event.Delta.Text != "" {
 			text = event.Delta.Text
@@ -180,6 +206,17 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ai.ChatRequest,
 		}
 	}
 
+	for _, use := range toolUses {
+		toolCalls = append(toolCalls, ai.ToolCall{
+			ID:   use.id,
+			Name: use.name,
+			Args: decodeToolArgs("anthropic", use.name, use.input.String()),
+		})
+	}
+	if use := blocks[lastBlock]; use != nil && stopReason == "max_tokens" {
+		markTruncated(&toolCalls[len(toolCalls)-1], use.input.String())
+	}
+
 	return &ai.ChatResponse{
 		Content:      fullContent.String(),
 		ToolCalls:    toolCalls,
@@ -187,6 +224,12 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req ai.ChatRequest,
 	}, nil
 }
 
+// streamedToolUse is a tool_use block being assembled from stream events
+type streamedToolUse struct {
+	id, name string
+	input    strings.Builder
+}
+
 // Request/response types
 type anthropicRequest struct {
 	Model       string             `json:"model"`
@@ -196,6 +239,7 @@ type anthropicRequest struct {
 	Tools       []anthropicTool    `json:"tools,omitempty"`
 	Stream      bool               `json:"stream,omitempty"`
 	Temperature float64            `json:"temperature,omitempty"`
+	TopP        float64            `json:"top_p,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -224,11 +268,11 @@ type anthropicResponse struct {
 	Type    string `json:"type"`
 	Role    string `json:"role"`
 	Content []struct {
-		Type  string                 `json:"type"`
-		Text  string                 `json:"text,omitempty"`
-		ID    string                 `json:"id,omitempty"`
-		Name  string                 `json:"name,omitempty"`
-		Input map[string]interface{} `json:"input,omitempty"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text,omitempty"`
+		ID    string          `json:"id,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
 	} `json:"content"`
 	Model      string `json:"model"`
 	StopReason string `json:"stop_reason"`
@@ -253,6 +297,9 @@ func (p *AnthropicProvider) buildRequest(req ai.ChatRequest) anthropicRequest {
 	if req.Temperature > 0 {
 		anthropicReq.Temperature = req.Temperature
 	}
+	if req.TopP > 0 {
+		anthropicReq.TopP = req.TopP
+	}
 
 	// Convert messages
 	for _, msg := range req.Messages {
@@ -313,10 +360,14 @@ func (p *AnthropicProvider) convertResponse(resp anthropicResponse) *ai.ChatResp
 			toolCalls = append(toolCalls, ai.ToolCall{
 				ID:   c.ID,
 				Name: c.Name,
-				Args: c.Input,
+				Args: decodeToolArgs("anthropic", c.Name, string(c.Input)),
 			})
 		}
 	}
+	// A tool_use block cut off by max_tokens comes back with partial input
+	if last := len(resp.Content) - 1; resp.StopReason == "max_tokens" && last >= 0 && resp.Content[last].Type == "tool_use" {
+		markTruncated(&toolCalls[len(toolCalls)-1], string(resp.Content[last].Input))
+	}
 
 	return &ai.ChatResponse{
 		Content:      content,
@@ -86,6 +86,23 @@ func (f *Factory) CreateProvider(name string) (ai.Provider, error) {
 		}
 
 		return provider, nil
+	case "mock":
+		// Replays fixtures; no API key
+		mock := f.config.GetMock()
+		if mock == nil {
+			return nil, fmt.Errorf("mock provider not configured. Set providers.mock.fixtures or ZEN_CLAW_MOCK_FIXTURES env")
+		}
+		var recorder ai.Provider
+		if mock.Record != "" {
+			if mock.Record == "mock" {
+				return nil, fmt.Errorf("mock provider can't record itself; set providers.mock.record to a real provider")
+			}
+			var err error
+			if recorder, err = f.CreateProvider(mock.Record); err != nil {
+				return nil, fmt.Errorf("mock provider recording from %s: %w", mock.Record, err)
+			}
+		}
+		return NewReplayProvider(mock.Fixtures, recorder)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", name)
 	}
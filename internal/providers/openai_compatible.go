@@ -57,80 +57,10 @@ func (p *OpenAICompatibleProvider) SupportsTools() bool {
 
 // Chat implements the AI provider interface
 func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	// History is already fitted to the model's context window by the gateway
+	// router (token budget, /context-limit, Qwen large-context setting)
 	messages := req.Messages
 
-	// Apply context limit (0 = unlimited, default 50 from session)
-	// If ContextLimit is 0, it means unlimited was explicitly requested
-	// Otherwise, use the value from the request (which comes from session, default 50)
-	contextLimit := req.ContextLimit
-	// Note: 0 means unlimited, non-zero means that many messages
-	// The session defaults to 50, so if ContextLimit is 0 here, it means user set it to unlimited
-
-	// truncateMessages truncates messages while preserving tool call sequences.
-	// Tool messages must follow an assistant message with tool_calls, so we can't break that pattern.
-	truncateMessages := func(msgs []ai.Message, maxCount int) []ai.Message {
-		if len(msgs) <= maxCount {
-			return msgs
-		}
-
-		// Keep system message if present
-		hasSystem := len(msgs) > 0 && msgs[0].Role == "system"
-		startIdx := 0
-		if hasSystem {
-			startIdx = 1
-			maxCount-- // Reserve one slot for system message
-		}
-
-		// Calculate where to start truncation
-		truncateStart := len(msgs) - maxCount
-		if truncateStart <= startIdx {
-			return msgs // No truncation needed
-		}
-
-		// Find the start of the first complete tool call sequence after truncateStart
-		// A tool call sequence is: assistant (with tool_calls) -> tool -> tool -> ... -> assistant
-		// We need to ensure we don't cut in the middle of a sequence
-		actualStart := truncateStart
-		for i := truncateStart; i < len(msgs); i++ {
-			// If we find a tool message, we need to include its preceding assistant message
-			if msgs[i].Role == "tool" {
-				// Look backwards for the assistant message with tool_calls
-				for j := i - 1; j >= startIdx; j-- {
-					if msgs[j].Role == "assistant" && len(msgs[j].ToolCalls) > 0 {
-						// Found the assistant with tool_calls, start from here
-						actualStart = j
-						break
-					}
-					if msgs[j].Role != "tool" {
-						// Not part of this tool sequence, stop looking
-						break
-					}
-				}
-				break
-			}
-		}
-
-		// Build result
-		result := make([]ai.Message, 0, maxCount+1)
-		if hasSystem {
-			result = append(result, msgs[0])
-		}
-		result = append(result, msgs[actualStart:]...)
-
-		return result
-	}
-
-	// Apply context limit with proper tool call sequence handling
-	// For all providers including Qwen: respect user's context limit setting
-	if contextLimit > 0 {
-		messages = truncateMessages(messages, contextLimit)
-	} else if p.name == "qwen" && !req.QwenLargeContextEnabled {
-		// Qwen with large context disabled and no user limit: use safe default (10)
-		// This only applies when user hasn't set a context limit
-		// If user sets /context-limit, we respect it fully regardless of large context setting
-		messages = truncateMessages(messages, 10)
-	}
-
 	// Convert messages to OpenAI format
 	var openaiMessages []openai.ChatCompletionMessage
 	for _, msg := range messages {
@@ -217,6 +147,7 @@ func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ai.ChatRequest)
 		Messages: openaiMessages,
 		Tools:    tools,
 	}
+	p.applyToolDialect(&completionReq)
 
 	// Add thinking mode if requested
 	if req.Thinking {
@@ -225,10 +156,18 @@ func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ai.ChatRequest)
 		}
 	}
 
+	// Structured output: full JSON Schema where supported, plain JSON mode otherwise
+	if req.ResponseSchema != nil {
+		completionReq.ResponseFormat = p.responseFormat(req.ResponseSchema)
+	}
+
 	// Add temperature if specified
 	if req.Temperature > 0 {
 		completionReq.Temperature = float32(req.Temperature)
 	}
+	if req.TopP > 0 {
+		completionReq.TopP = float32(req.TopP)
+	}
 
 	// Add max tokens if specified
 	if req.MaxTokens > 0 {
@@ -244,7 +183,7 @@ func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ai.ChatRequest)
 
 	// Make API call
 	// Note: For Qwen, the context may be canceled if HTTP client times out (180s)
-	// We use small message windows (10 messages) and reduced max tokens to keep it fast
+	// The router caps Qwen's history (unless large context is on) and we reduce max tokens to keep it fast
 	resp, err := p.client.CreateChatCompletion(ctx, completionReq)
 	if err != nil {
 		return nil, fmt.Errorf("%s API error: %w", p.name, err)
@@ -259,21 +198,25 @@ func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req ai.ChatRequest)
 	// Extract tool calls if any
 	if resp.Choices[0].Message.ToolCalls != nil {
 		for _, toolCall := range resp.Choices[0].Message.ToolCalls {
-			// Parse JSON arguments
-			args := make(map[string]interface{})
-			if toolCall.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-					// If parsing fails, store raw string
-					args["_raw"] = toolCall.Function.Arguments
-				}
-			}
-
 			chatResp.ToolCalls = append(chatResp.ToolCalls, ai.ToolCall{
 				ID:   toolCall.ID,
 				Name: toolCall.Function.Name,
-				Args: args,
+				Args: decodeToolArgs(p.name, toolCall.Function.Name, toolCall.Function.Arguments),
 			})
 		}
+		// The last call of a response cut off by max_tokens may be incomplete
+		if resp.Choices[0].FinishReason == openai.FinishReasonLength {
+			last := len(chatResp.ToolCalls) - 1
+			markTruncated(&chatResp.ToolCalls[last], resp.Choices[0].Message.ToolCalls[last].Function.Arguments)
+		}
+	}
+
+	// Calls the server did not parse out of the content
+	if len(req.Tools) > 0 && len(chatResp.ToolCalls) == 0 {
+		if calls, content := p.leakedToolCalls(chatResp.Content); len(calls) > 0 {
+			chatResp.ToolCalls, chatResp.Content = calls, content
+			chatResp.FinishReason = string(openai.FinishReasonToolCalls)
+		}
 	}
 
 	return chatResp, nil
@@ -317,6 +260,9 @@ func (p *OpenAICompatibleProvider) ChatStream(ctx context.Context, req ai.ChatRe
 	if req.Temperature > 0 {
 		completionReq.Temperature = float32(req.Temperature)
 	}
+	if req.TopP > 0 {
+		completionReq.TopP = float32(req.TopP)
+	}
 
 	// Create streaming request
 	stream, err := p.client.CreateChatCompletionStream(ctx, completionReq)
@@ -361,3 +307,31 @@ func (p *OpenAICompatibleProvider) ChatStream(ctx context.Context, req ai.ChatRe
 		FinishReason: finishReason,
 	}, nil
 }
+
+// schemaJSON adapts a schema map to the json.Marshaler go-openai expects
+type schemaJSON map[string]interface{}
+
+func (s schemaJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}(s))
+}
+
+// responseFormat picks the native structured output mode for this provider
+func (p *OpenAICompatibleProvider) responseFormat(schema map[string]interface{}) *openai.ChatCompletionResponseFormat {
+	if t, _ := schema["type"].(string); t != "object" {
+		return nil // Native modes require an object at the root
+	}
+	switch p.name {
+	case "openai":
+		return &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "response",
+				Schema: schemaJSON(schema),
+			},
+		}
+	case "deepseek", "qwen", "glm", "kimi":
+		// JSON mode only: the schema itself is enforced by the prompt and validation
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return nil
+}
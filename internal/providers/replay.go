@@ -0,0 +1,236 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ReplayProvider is the "mock" provider: it answers from fixture files, so
+// the agent loop, the gateway and consensus run deterministically without
+// API keys (tests, CI, demos). With a record provider set, requests without a
+// fixture go to that provider and its answer is saved as a new fixture
+// (VCR-style); without one, they fail.
+//
+// A fixture is a JSON file in the fixture directory, either recorded:
+//
+//	{"key": "<request hash>", "request": {...}, "responses": [{"content": "..."}]}
+//
+// or hand-written, matched by the latest user message:
+//
+//	{"match": "fix the bug", "responses": [{"tool_calls": [...]}, {"content": "Done."}]}
+//
+// A recorded fixture answers the exact request it was recorded from. The
+// request hash leaves out system messages, which carry the working directory
+// and the date, so fixtures replay on other machines. A hand-written one
+// answers any request whose latest user message contains match; the response
+// used is the step within that turn (the number of assistant messages since
+// the user message), the last one repeating.
+type ReplayProvider struct {
+	dir      string
+	recorder ai.Provider // nil = replay only
+
+	mu       sync.RWMutex
+	recorded map[string]*Fixture // By key
+	scripted []*Fixture          // Hand-written, in file name order
+}
+
+// Fixture is a recorded or hand-written model interaction
+type Fixture struct {
+	Key       string            `json:"key,omitempty"`     // Hash of the recorded request
+	Match     string            `json:"match,omitempty"`   // Hand-written: text of the latest user message
+	Request   *FixtureRequest   `json:"request,omitempty"` // What was sent (for reading; not used to match)
+	Responses []ai.ChatResponse `json:"responses"`
+}
+
+// FixtureRequest is the part of a request that identifies it
+type FixtureRequest struct {
+	Model    string       `json:"model,omitempty"`
+	Messages []ai.Message `json:"messages"`
+	Tools    []string     `json:"tools,omitempty"`
+}
+
+// NewReplayProvider loads the fixtures in dir. recorder answers requests
+// without a fixture, and its answers are saved (nil = replay only).
+func NewReplayProvider(dir string, recorder ai.Provider) (*ReplayProvider, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mock provider needs a fixture directory (providers.mock.fixtures or ZEN_CLAW_MOCK_FIXTURES)")
+	}
+	p := &ReplayProvider{dir: dir, recorder: recorder, recorded: make(map[string]*Fixture)}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", path, err)
+		}
+		switch {
+		case len(f.Responses) == 0:
+			return nil, fmt.Errorf("fixture %s: no responses", path)
+		case f.Key != "":
+			p.recorded[f.Key] = &f
+		case f.Match != "":
+			p.scripted = append(p.scripted, &f)
+		default:
+			return nil, fmt.Errorf("fixture %s: needs a key or a match", path)
+		}
+	}
+	if recorder == nil && len(paths) == 0 {
+		log.Printf("Warning: mock provider has no fixtures in %s", dir)
+	}
+	return p, nil
+}
+
+func (p *ReplayProvider) Name() string {
+	return "mock"
+}
+
+func (p *ReplayProvider) SupportsTools() bool {
+	return true
+}
+
+func (p *ReplayProvider) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	return p.answer(req, func() (*ai.ChatResponse, error) { return p.recorder.Chat(ctx, req) })
+}
+
+// ChatStream sends a replayed answer as one chunk; recorded calls stream
+func (p *ReplayProvider) ChatStream(ctx context.Context, req ai.ChatRequest, callback ai.StreamCallback) (*ai.ChatResponse, error) {
+	streamed := false
+	resp, err := p.answer(req, func() (*ai.ChatResponse, error) {
+		streamed = true
+		return p.recorder.ChatStream(ctx, req, callback)
+	})
+	if err == nil && !streamed && callback != nil && resp.Content != "" {
+		callback(resp.Content)
+	}
+	return resp, err
+}
+
+// answer replays the request's fixture, or records the recorder's answer
+func (p *ReplayProvider) answer(req ai.ChatRequest, record func() (*ai.ChatResponse, error)) (*ai.ChatResponse, error) {
+	fixtureReq := fixtureRequest(req)
+	key := fixtureReq.key()
+	if resp := p.replay(key, req.Messages); resp != nil {
+		return resp, nil
+	}
+	if p.recorder == nil {
+		return nil, fmt.Errorf("mock: no fixture for request %s (latest message: %q); record one with providers.mock.record", key[:16], truncate(lastContent(req.Messages), 80))
+	}
+
+	resp, err := record()
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{Key: key, Request: fixtureReq, Responses: []ai.ChatResponse{*resp}}
+	if err := p.save(f); err != nil {
+		log.Printf("Warning: mock: failed to save fixture %s: %v", key[:16], err)
+	}
+	return resp, nil
+}
+
+// replay finds the request's answer (nil if no fixture has one)
+func (p *ReplayProvider) replay(key string, messages []ai.Message) *ai.ChatResponse {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if f, ok := p.recorded[key]; ok {
+		resp := f.Responses[0]
+		return &resp
+	}
+
+	// Hand-written: the latest user message and the step since it
+	user, step := -1, 0
+	for i := len(messages) - 1; i >= 0 && user < 0; i-- {
+		switch messages[i].Role {
+		case "user":
+			user = i
+		case "assistant":
+			step++
+		}
+	}
+	if user < 0 {
+		return nil
+	}
+	for _, f := range p.scripted {
+		if strings.Contains(messages[user].Content, f.Match) {
+			resp := f.Responses[min(step, len(f.Responses)-1)]
+			return &resp
+		}
+	}
+	return nil
+}
+
+// save writes a recorded fixture and makes it available
+func (p *ReplayProvider) save(f *Fixture) error {
+	p.mu.Lock()
+	p.recorded[f.Key] = f
+	p.mu.Unlock()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p.dir, f.Key[:16]+".json"), append(data, '\n'), 0644)
+}
+
+// fixtureRequest keeps what identifies a request: non-system messages
+// without metadata, the model and the tool names
+func fixtureRequest(req ai.ChatRequest) *FixtureRequest {
+	r := &FixtureRequest{Model: req.Model, Messages: []ai.Message{}}
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			continue
+		}
+		r.Messages = append(r.Messages, ai.Message{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	for _, tool := range req.Tools {
+		r.Tools = append(r.Tools, tool.Name)
+	}
+	slices.Sort(r.Tools)
+	return r
+}
+
+// key hashes the request (tool call arguments are maps, which encode sorted)
+func (r *FixtureRequest) key() string {
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func lastContent(messages []ai.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
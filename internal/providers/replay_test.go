@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// countingProvider answers every request and counts the calls
+type countingProvider struct {
+	MockProvider
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	p.calls++
+	return &ai.ChatResponse{Content: "live answer", FinishReason: "stop"}, nil
+}
+
+func TestReplayProviderRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	req := func(system string) ai.ChatRequest {
+		return ai.ChatRequest{
+			Model: "m",
+			Messages: []ai.Message{
+				{Role: "system", Content: system},
+				{Role: "user", Content: "hello"},
+			},
+			Tools: []ai.Tool{{Name: "read_file"}},
+		}
+	}
+
+	live := &countingProvider{}
+	recording, err := NewReplayProvider(dir, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := recording.Chat(ctx, req("cwd: /home/a"))
+		if err != nil || resp.Content != "live answer" {
+			t.Fatalf("recording call %d: %+v, %v", i, resp, err)
+		}
+	}
+	if live.calls != 1 {
+		t.Errorf("live provider called %d times, want 1 (then replayed)", live.calls)
+	}
+
+	// Replay only: another machine (system prompt differs), no provider
+	replaying, err := NewReplayProvider(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := replaying.Chat(ctx, req("cwd: /ci/workspace"))
+	if err != nil || resp.Content != "live answer" {
+		t.Errorf("replay: %+v, %v", resp, err)
+	}
+	other := req("")
+	other.Messages[1].Content = "something else"
+	if _, err := replaying.Chat(ctx, other); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("unrecorded request: err = %v, want a no fixture error", err)
+	}
+}
+
+func TestReplayProviderScripted(t *testing.T) {
+	dir := t.TempDir()
+	fixture := `{
+  "match": "fix the bug",
+  "responses": [
+    {"tool_calls": [{"id": "1", "name": "read_file", "args": {"path": "main.go"}}]},
+    {"content": "Fixed."}
+  ]
+}`
+	os.WriteFile(filepath.Join(dir, "fix.json"), []byte(fixture), 0644)
+	p, err := NewReplayProvider(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []ai.Message{{Role: "user", Content: "please fix the bug"}}
+	resp, err := p.Chat(context.Background(), ai.ChatRequest{Messages: messages})
+	if err != nil || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Args["path"] != "main.go" {
+		t.Fatalf("step 1: %+v, %v", resp, err)
+	}
+	messages = append(messages,
+		ai.Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		ai.Message{Role: "tool", ToolCallID: "1", Content: "package main"})
+	var streamed string
+	resp, err = p.ChatStream(context.Background(), ai.ChatRequest{Messages: messages}, func(token string) { streamed += token })
+	if err != nil || resp.Content != "Fixed." || streamed != "Fixed." {
+		t.Errorf("step 2: %+v, streamed %q, %v", resp, streamed, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"responses": [{"content": "x"}]}`), 0644)
+	if _, err := NewReplayProvider(dir, nil); err == nil {
+		t.Error("loaded a fixture with neither key nor match")
+	}
+}
@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/ai"
+	"github.com/neves/zen-claw/internal/jsonrepair"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Qwen, GLM and Kimi support OpenAI-style function calling, each with its
+// own deviations. The request side is adapted per provider (toolDialect).
+// On the response side, the servers sometimes fail to parse a call the model
+// wrote in its chat template's format and return it as content; those calls
+// are mapped back to native tool calls so the agent never has to fall back to
+// parsing text for these models.
+
+// toolDialect is how a provider's function calling deviates from OpenAI's
+type toolDialect struct {
+	parallelCalls   bool   // Ask for parallel calls explicitly (DashScope defaults to one call per turn)
+	toolChoice      string // tool_choice to send ("" = omit)
+	toolMessageName bool   // Tool results carry the function name (Moonshot)
+}
+
+var toolDialects = map[string]toolDialect{
+	"qwen": {parallelCalls: true},
+	"glm":  {toolChoice: "auto"}, // The only tool_choice GLM accepts
+	"kimi": {toolMessageName: true},
+}
+
+// applyToolDialect adapts a request with tools to the provider's dialect
+func (p *OpenAICompatibleProvider) applyToolDialect(req *openai.ChatCompletionRequest) {
+	dialect, ok := toolDialects[p.name]
+	if !ok || len(req.Tools) == 0 {
+		return
+	}
+	if dialect.parallelCalls {
+		req.ParallelToolCalls = true
+	}
+	if dialect.toolChoice != "" {
+		req.ToolChoice = dialect.toolChoice
+	}
+	if dialect.toolMessageName {
+		names := make(map[string]string) // Tool call ID -> function name
+		for i, msg := range req.Messages {
+			for _, tc := range msg.ToolCalls {
+				names[tc.ID] = tc.Function.Name
+			}
+			if msg.Role == openai.ChatMessageRoleTool && msg.Name == "" {
+				req.Messages[i].Name = names[msg.ToolCallID]
+			}
+		}
+	}
+}
+
+var (
+	// Kimi: <|tool_calls_section_begin|><|tool_call_begin|>functions.read_file:0<|tool_call_argument_begin|>{...}<|tool_call_end|><|tool_calls_section_end|>
+	kimiSectionPattern = regexp.MustCompile(`(?s)<\|tool_calls_section_begin\|>.*?(?:<\|tool_calls_section_end\|>|$)`)
+	kimiCallPattern    = regexp.MustCompile(`(?s)<\|tool_call_begin\|>\s*(\S+?)\s*<\|tool_call_argument_begin\|>(.*?)<\|tool_call_end\|>`)
+
+	// GLM: <tool_call>read_file\n<arg_key>path</arg_key>\n<arg_value>a.txt</arg_value>\n</tool_call>
+	glmCallPattern = regexp.MustCompile(`(?s)<tool_call>\s*([\w.-]+)\s*((?:<arg_key>.*?</arg_key>\s*<arg_value>.*?</arg_value>\s*)*)</tool_call>`)
+	glmArgPattern  = regexp.MustCompile(`(?s)<arg_key>(.*?)</arg_key>\s*<arg_value>(.*?)</arg_value>`)
+
+	// Qwen (Hermes template): <tool_call>\n{"name": "read_file", "arguments": {...}}\n</tool_call>
+	hermesCallPattern = regexp.MustCompile(`(?s)<tool_call>\s*(\{.*?\})\s*</tool_call>`)
+)
+
+// leakedToolCalls extracts tool calls left in the content in the model's
+// template format, and returns the content without them
+func (p *OpenAICompatibleProvider) leakedToolCalls(content string) ([]ai.ToolCall, string) {
+	var calls []ai.ToolCall
+	add := func(id, name string, args map[string]interface{}) {
+		if id == "" {
+			id = fmt.Sprintf("call_%d", len(calls)+1)
+		}
+		calls = append(calls, ai.ToolCall{ID: id, Name: name, Args: args})
+	}
+
+	if section := kimiSectionPattern.FindString(content); section != "" {
+		for _, m := range kimiCallPattern.FindAllStringSubmatch(section, -1) {
+			// IDs are functions.<name>:<index> and must be sent back as they are
+			name := strings.TrimPrefix(m[1], "functions.")
+			if i := strings.LastIndexByte(name, ':'); i > 0 {
+				name = name[:i]
+			}
+			add(m[1], name, p.leakedArgs(name, m[2]))
+		}
+		content = strings.Replace(content, section, "", 1)
+	}
+
+	content = glmCallPattern.ReplaceAllStringFunc(content, func(block string) string {
+		m := glmCallPattern.FindStringSubmatch(block)
+		args := make(map[string]interface{})
+		for _, arg := range glmArgPattern.FindAllStringSubmatch(m[2], -1) {
+			args[strings.TrimSpace(arg[1])] = argValue(arg[2])
+		}
+		add("", m[1], args)
+		return ""
+	})
+
+	content = hermesCallPattern.ReplaceAllStringFunc(content, func(block string) string {
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		m := hermesCallPattern.FindStringSubmatch(block)
+		if err := json.Unmarshal([]byte(m[1]), &call); err != nil || call.Name == "" {
+			return block
+		}
+		raw := string(call.Arguments)
+		var encoded string // Some templates encode the arguments as a JSON string
+		if json.Unmarshal(call.Arguments, &encoded) == nil {
+			raw = encoded
+		}
+		add("", call.Name, p.leakedArgs(call.Name, raw))
+		return ""
+	})
+
+	if len(calls) > 0 {
+		log.Printf("[%s] Mapped %d tool calls from the content to native calls", p.name, len(calls))
+	}
+	return calls, strings.TrimSpace(content)
+}
+
+// leakedArgs decodes JSON arguments like native ones
+func (p *OpenAICompatibleProvider) leakedArgs(name, raw string) map[string]interface{} {
+	return decodeToolArgs(p.name, name, raw)
+}
+
+// decodeToolArgs decodes the JSON arguments of a tool call, the same way for
+// every provider. Arguments that can't be parsed become {"_raw": raw}, and
+// the agent asks for a resend. Ones that only parse after repair were often
+// cut off by max_tokens: they keep the raw text under "_repaired", and the
+// agent runs only tools that change nothing with them.
+func decodeToolArgs(provider, name, raw string) map[string]interface{} {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(raw) == "" || strings.TrimSpace(raw) == "null" {
+		return args
+	}
+	repaired, err := jsonrepair.Unmarshal(raw, &args)
+	if err != nil {
+		return map[string]interface{}{"_raw": raw}
+	}
+	if repaired {
+		log.Printf("[%s] Repaired malformed arguments for tool call %s", provider, name)
+		args["_repaired"] = raw
+	}
+	return args
+}
+
+// markTruncated flags the arguments of a call in a response cut off by the
+// token limit: they parse, but may be missing their end
+func markTruncated(call *ai.ToolCall, raw string) {
+	if _, malformed := call.Args["_raw"]; malformed {
+		return
+	}
+	if call.Args == nil {
+		call.Args = make(map[string]interface{})
+	}
+	call.Args["_repaired"] = raw
+}
+
+// argValue decodes a GLM argument: objects and arrays are JSON, everything
+// else is kept as text (the agent coerces it to the parameter's type)
+func argValue(value string) interface{} {
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var decoded interface{}
+		if json.Unmarshal([]byte(trimmed), &decoded) == nil {
+			return decoded
+		}
+	}
+	return value
+}
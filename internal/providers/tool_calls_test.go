@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLeakedToolCalls(t *testing.T) {
+	p := &OpenAICompatibleProvider{name: "test"}
+
+	tests := []struct {
+		name    string
+		content string
+		id      string
+		tool    string
+		arg     string
+		value   interface{}
+		rest    string
+	}{
+		{
+			name:    "kimi",
+			content: "Reading it.<|tool_calls_section_begin|><|tool_call_begin|>functions.read_file:0<|tool_call_argument_begin|>{\"path\": \"a.go\"}<|tool_call_end|><|tool_calls_section_end|>",
+			id:      "functions.read_file:0",
+			tool:    "read_file",
+			arg:     "path",
+			value:   "a.go",
+			rest:    "Reading it.",
+		},
+		{
+			name:    "glm",
+			content: "<tool_call>search_files\n<arg_key>pattern</arg_key>\n<arg_value>TODO</arg_value>\n<arg_key>paths</arg_key>\n<arg_value>[\"cmd\"]</arg_value>\n</tool_call>",
+			id:      "call_1",
+			tool:    "search_files",
+			arg:     "pattern",
+			value:   "TODO",
+		},
+		{
+			name:    "hermes",
+			content: "Let me check.\n<tool_call>\n{\"name\": \"list_dir\", \"arguments\": {\"path\": \"src\"}}\n</tool_call>",
+			id:      "call_1",
+			tool:    "list_dir",
+			arg:     "path",
+			value:   "src",
+			rest:    "Let me check.",
+		},
+		{
+			name:    "hermes with encoded arguments",
+			content: "<tool_call>{\"name\": \"list_dir\", \"arguments\": \"{\\\"path\\\": \\\"src\\\"}\"}</tool_call>",
+			id:      "call_1",
+			tool:    "list_dir",
+			arg:     "path",
+			value:   "src",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, rest := p.leakedToolCalls(tt.content)
+			if len(calls) != 1 {
+				t.Fatalf("got %d calls, want 1", len(calls))
+			}
+			call := calls[0]
+			if call.ID != tt.id || call.Name != tt.tool || call.Args[tt.arg] != tt.value {
+				t.Errorf("call = %+v", call)
+			}
+			if rest != tt.rest {
+				t.Errorf("content = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+
+	// GLM arrays are decoded; prose and the agent's own text format are left alone
+	calls, _ := p.leakedToolCalls(tests[1].content)
+	if paths, ok := calls[0].Args["paths"].([]interface{}); !ok || len(paths) != 1 {
+		t.Errorf("paths = %#v", calls[0].Args["paths"])
+	}
+	text := "<tool_call>\n<function=read_file>\n<parameter=path>a.go</parameter>\n</function>\n</tool_call>"
+	if calls, rest := p.leakedToolCalls(text); len(calls) != 0 || rest != text {
+		t.Errorf("text protocol was mapped: %+v", calls)
+	}
+}
+
+func TestToolDialect(t *testing.T) {
+	req := func() openai.ChatCompletionRequest {
+		return openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{
+				{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "functions.read_file:0", Function: openai.FunctionCall{Name: "read_file"}}}},
+				{Role: "tool", ToolCallID: "functions.read_file:0", Content: "ok"},
+			},
+			Tools: []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "read_file"}}},
+		}
+	}
+
+	kimi := req()
+	(&OpenAICompatibleProvider{name: "kimi"}).applyToolDialect(&kimi)
+	if kimi.Messages[1].Name != "read_file" {
+		t.Errorf("kimi tool message name = %q", kimi.Messages[1].Name)
+	}
+
+	qwen := req()
+	(&OpenAICompatibleProvider{name: "qwen"}).applyToolDialect(&qwen)
+	if qwen.ParallelToolCalls != true || qwen.Messages[1].Name != "" {
+		t.Errorf("qwen request = %+v", qwen)
+	}
+
+	glm := req()
+	(&OpenAICompatibleProvider{name: "glm"}).applyToolDialect(&glm)
+	if glm.ToolChoice != "auto" {
+		t.Errorf("glm tool_choice = %v", glm.ToolChoice)
+	}
+}
+
+func TestDecodeToolArgs(t *testing.T) {
+	if args := decodeToolArgs("test", "write_file", `{"path": "a.go", "content": "x"}`); args["path"] != "a.go" || args["_repaired"] != nil {
+		t.Errorf("valid args = %v", args)
+	}
+	cut := `{"path": "a.go", "content": "package a`
+	if args := decodeToolArgs("test", "write_file", cut); args["path"] != "a.go" || args["_repaired"] != cut {
+		t.Errorf("repaired args = %v", args)
+	}
+	if args := decodeToolArgs("test", "write_file", "not json at all"); args["_raw"] != "not json at all" {
+		t.Errorf("garbage args = %v", args)
+	}
+
+	// Anthropic input that parses is still marked when max_tokens cut it off
+	var resp anthropicResponse
+	body := `{"stop_reason": "max_tokens", "content": [
+		{"type": "tool_use", "id": "1", "name": "read_file", "input": {"path": "a.go"}},
+		{"type": "tool_use", "id": "2", "name": "write_file", "input": {"path": "b.go", "content": "package b"}}]}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	calls := (&AnthropicProvider{}).convertResponse(resp).ToolCalls
+	if len(calls) != 2 || calls[0].Args["_repaired"] != nil || calls[1].Args["_repaired"] == nil || calls[1].Args["path"] != "b.go" {
+		t.Errorf("anthropic calls = %+v", calls)
+	}
+}
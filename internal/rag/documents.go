@@ -0,0 +1,72 @@
+package rag
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// previewMax is the preview length of a file, and of search results
+const previewMax = 500
+
+// documentTextMax caps the text of a document searched by the index
+const documentTextMax = 16000
+
+// Document is a page from outside the repository (Confluence, Notion) kept
+// in the index next to the files, so code_search and get_context find specs
+// along with the code
+type Document struct {
+	Path    string    `json:"path"` // Index path, e.g. notion:1a2b... or confluence:12345
+	Title   string    `json:"title"`
+	URL     string    `json:"url"`
+	Content string    `json:"content"` // Markdown
+	Fetched time.Time `json:"fetched"`
+}
+
+// IndexDocument adds or refreshes a document. Its title and text are
+// searched like a file's preview; language is "doc".
+func (idx *Indexer) IndexDocument(doc Document) error {
+	text := strings.Join(strings.Fields(doc.Title+"\n"+doc.Content), " ")
+	if len(text) > documentTextMax {
+		text = strings.ToValidUTF8(text[:documentTextMax], "")
+	}
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`
+		INSERT INTO documents (path, title, url, content, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			title = excluded.title, url = excluded.url, content = excluded.content, fetched_at = excluded.fetched_at
+	`, doc.Path, doc.Title, doc.URL, doc.Content, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO files (path, size, hash, language, symbols, imports, content_preview, updated_at)
+		VALUES (?, ?, '', 'doc', '', '', ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			size = excluded.size,
+			content_preview = excluded.content_preview,
+			updated_at = excluded.updated_at
+	`, doc.Path, len(doc.Content), text, time.Now())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Document returns an indexed document, or nil when path is not one
+func (idx *Indexer) Document(path string) (*Document, error) {
+	var doc Document
+	err := idx.db.QueryRow(`SELECT path, title, url, content, fetched_at FROM documents WHERE path = ?`, path).
+		Scan(&doc.Path, &doc.Title, &doc.URL, &doc.Content, &doc.Fetched)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
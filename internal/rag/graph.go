@@ -0,0 +1,287 @@
+package rag
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// graphLanguages are the languages whose files make packages in a
+// dependency graph (config and docs don't)
+var graphLanguages = map[string]bool{
+	"go": true, "python": true, "javascript": true, "typescript": true,
+	"java": true, "rust": true, "ruby": true, "c": true, "cpp": true,
+}
+
+// Files returns the indexed source files (documents excluded)
+func (idx *Indexer) Files() ([]FileInfo, error) {
+	rows, err := idx.db.Query(`SELECT path, size, hash, language, symbols, imports, updated_at FROM files WHERE language != 'doc' ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []FileInfo
+	for rows.Next() {
+		var f FileInfo
+		var symbols, imports string
+		if err := rows.Scan(&f.Path, &f.Size, &f.Hash, &f.Language, &symbols, &imports, &f.UpdatedAt); err != nil {
+			continue
+		}
+		f.Symbols = strings.Fields(symbols)
+		f.Imports = strings.Fields(imports)
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// PackageGraph is the dependency graph of a repo's packages: a package is a
+// directory holding source files, and an edge is an import of another
+// package of the repo (third-party imports aren't shown)
+type PackageGraph struct {
+	Packages []*Package          `json:"packages"`
+	Edges    map[string][]string `json:"edges"` // Package → the packages it imports, sorted
+}
+
+// Package is a directory of the graph
+type Package struct {
+	Path     string   `json:"path"` // Slash-separated, relative to the root ("." for the root)
+	Language string   `json:"language"`
+	Files    int      `json:"files"`
+	Symbols  []string `json:"symbols"` // The first symbols of its files
+}
+
+// packageSymbolsMax caps the symbols kept per package
+const packageSymbolsMax = 12
+
+// BuildPackageGraph builds the graph of the indexed files under root.
+// Go imports are resolved with the module path of root's go.mod,
+// JavaScript/TypeScript imports when relative and Python imports when they
+// name a package of the repo. Files no longer on disk are skipped.
+func BuildPackageGraph(files []FileInfo, root string) *PackageGraph {
+	module := GoModulePath(root)
+	packages := make(map[string]*Package)
+	var sources []FileInfo
+	for _, f := range files {
+		if !graphLanguages[f.Language] {
+			continue
+		}
+		if root != "" {
+			if _, err := os.Stat(filepath.Join(root, f.Path)); err != nil {
+				continue
+			}
+		}
+		dir := path.Dir(filepath.ToSlash(f.Path))
+		pkg := packages[dir]
+		if pkg == nil {
+			pkg = &Package{Path: dir, Language: f.Language}
+			packages[dir] = pkg
+		}
+		pkg.Files++
+		for _, sym := range f.Symbols {
+			if len(pkg.Symbols) < packageSymbolsMax {
+				pkg.Symbols = append(pkg.Symbols, sym)
+			}
+		}
+		sources = append(sources, f)
+	}
+
+	edges := make(map[string]map[string]bool)
+	for _, f := range sources {
+		dir := path.Dir(filepath.ToSlash(f.Path))
+		for _, imp := range f.Imports {
+			target := resolveImport(dir, imp, f.Language, module, packages)
+			if target == "" || target == dir {
+				continue
+			}
+			if edges[dir] == nil {
+				edges[dir] = make(map[string]bool)
+			}
+			edges[dir][target] = true
+		}
+	}
+
+	g := &PackageGraph{Edges: make(map[string][]string)}
+	for _, pkg := range packages {
+		g.Packages = append(g.Packages, pkg)
+	}
+	sort.Slice(g.Packages, func(i, j int) bool { return g.Packages[i].Path < g.Packages[j].Path })
+	for from, targets := range edges {
+		for to := range targets {
+			g.Edges[from] = append(g.Edges[from], to)
+		}
+		sort.Strings(g.Edges[from])
+	}
+	return g
+}
+
+// resolveImport returns the package of the repo an import names ("" for
+// third-party and unresolvable imports)
+func resolveImport(dir, imp, lang, module string, packages map[string]*Package) string {
+	var target string
+	switch lang {
+	case "go":
+		switch {
+		case module == "":
+			return ""
+		case imp == module:
+			target = "."
+		case strings.HasPrefix(imp, module+"/"):
+			target = strings.TrimPrefix(imp, module+"/")
+		}
+	case "javascript", "typescript":
+		if !strings.HasPrefix(imp, ".") {
+			return ""
+		}
+		target = path.Join(dir, imp)
+		if packages[target] == nil {
+			target = path.Dir(target) // A file of the package
+		}
+	case "python":
+		if strings.HasPrefix(imp, ".") {
+			return ""
+		}
+		parts := strings.Split(imp, ".")
+		for n := len(parts); n > 0 && target == ""; n-- {
+			if candidate := strings.Join(parts[:n], "/"); packages[candidate] != nil {
+				target = candidate
+			}
+		}
+	}
+	if packages[target] == nil {
+		return ""
+	}
+	return target
+}
+
+var goModuleRe = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+
+// GoModulePath returns the module path of root's go.mod ("" without one)
+func GoModulePath(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	if m := goModuleRe.FindSubmatch(data); m != nil {
+		return strings.Trim(string(m[1]), `"`)
+	}
+	return ""
+}
+
+// Collapse merges packages deeper than depth path segments into their
+// ancestor at that depth (internal/agent/tools → internal/agent for 2), so
+// big repos give a readable graph. depth <= 0 returns g.
+func (g *PackageGraph) Collapse(depth int) *PackageGraph {
+	if depth <= 0 {
+		return g
+	}
+	group := func(p string) string {
+		parts := strings.Split(p, "/")
+		if len(parts) > depth {
+			parts = parts[:depth]
+		}
+		return strings.Join(parts, "/")
+	}
+
+	packages := make(map[string]*Package)
+	var order []string
+	for _, pkg := range g.Packages {
+		name := group(pkg.Path)
+		merged := packages[name]
+		if merged == nil {
+			merged = &Package{Path: name, Language: pkg.Language}
+			packages[name] = merged
+			order = append(order, name)
+		}
+		merged.Files += pkg.Files
+		for _, sym := range pkg.Symbols {
+			if len(merged.Symbols) < packageSymbolsMax {
+				merged.Symbols = append(merged.Symbols, sym)
+			}
+		}
+	}
+
+	collapsed := &PackageGraph{Edges: make(map[string][]string)}
+	sort.Strings(order)
+	for _, name := range order {
+		collapsed.Packages = append(collapsed.Packages, packages[name])
+	}
+	edges := make(map[string]map[string]bool)
+	for from, targets := range g.Edges {
+		for _, to := range targets {
+			f, t := group(from), group(to)
+			if f == t {
+				continue
+			}
+			if edges[f] == nil {
+				edges[f] = make(map[string]bool)
+			}
+			edges[f][t] = true
+		}
+	}
+	for from, targets := range edges {
+		for to := range targets {
+			collapsed.Edges[from] = append(collapsed.Edges[from], to)
+		}
+		sort.Strings(collapsed.Edges[from])
+	}
+	return collapsed
+}
+
+// String renders the graph as text, one package and its imports per line
+func (g *PackageGraph) String() string {
+	var b strings.Builder
+	for _, pkg := range g.Packages {
+		fmt.Fprintf(&b, "%s (%d files)", pkg.Path, pkg.Files)
+		if deps := g.Edges[pkg.Path]; len(deps) > 0 {
+			fmt.Fprintf(&b, " → %s", strings.Join(deps, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var nodeIDRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// nodeID is a package's identifier in Mermaid and DOT
+func nodeID(p string) string {
+	if p == "." {
+		return "root"
+	}
+	return "p_" + nodeIDRe.ReplaceAllString(p, "_")
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *PackageGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, pkg := range g.Packages {
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", nodeID(pkg.Path), pkg.Path)
+	}
+	for _, pkg := range g.Packages {
+		for _, to := range g.Edges[pkg.Path] {
+			fmt.Fprintf(&b, "    %s --> %s\n", nodeID(pkg.Path), nodeID(to))
+		}
+	}
+	return b.String()
+}
+
+// DOT renders the graph for Graphviz
+func (g *PackageGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph packages {\n    rankdir=LR;\n    node [shape=box];\n")
+	for _, pkg := range g.Packages {
+		fmt.Fprintf(&b, "    %s [label=%q];\n", nodeID(pkg.Path), pkg.Path)
+	}
+	for _, pkg := range g.Packages {
+		for _, to := range g.Edges[pkg.Path] {
+			fmt.Fprintf(&b, "    %s -> %s;\n", nodeID(pkg.Path), nodeID(to))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackageGraph(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	write("go.mod", "module example.com/app\n\ngo 1.22\n")
+	write("main.go", "package main\n\nimport \"example.com/app/internal/api\"\n\nfunc main() { api.Serve() }\n")
+	write("internal/api/api.go", "package api\n\nimport (\n\t\"fmt\"\n\n\tstore \"example.com/app/internal/store\"\n\t_ \"example.com/app/internal/store/migrations\"\n)\n\nfunc Serve() { fmt.Println(store.Open()) }\n")
+	write("internal/store/store.go", "package store\n\nfunc Open() string { return \"\" }\n")
+	write("internal/store/migrations/m.go", "package migrations\n")
+	write("web/src/app.ts", "import { x } from './util/x'\nimport React from 'react'\n")
+	write("web/src/util/x.ts", "export const x = 1\n")
+	write("docs/notes.md", "# Notes\n")
+
+	// The files as the indexer records them
+	var files []FileInfo
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		content, _ := os.ReadFile(path)
+		lang := detectLanguage(rel)
+		files = append(files, FileInfo{Path: rel, Language: lang, Symbols: extractSymbols(string(content), lang), Imports: extractImports(string(content), lang)})
+		return nil
+	})
+
+	g := BuildPackageGraph(files, dir)
+	want := ". (1 files) → internal/api\n" +
+		"internal/api (1 files) → internal/store, internal/store/migrations\n" +
+		"internal/store (1 files)\n" +
+		"internal/store/migrations (1 files)\n" +
+		"web/src (1 files) → web/src/util\n" +
+		"web/src/util (1 files)\n"
+	if got := g.String(); got != want {
+		t.Errorf("graph =\n%s\nwant\n%s", got, want)
+	}
+	if m := g.Mermaid(); !strings.Contains(m, "root --> p_internal_api") || !strings.Contains(m, `p_web_src_util["web/src/util"]`) {
+		t.Errorf("mermaid =\n%s", m)
+	}
+
+	// Collapsing drops the edges inside a group
+	collapsed := g.Collapse(1)
+	want = ". (1 files) → internal\ninternal (3 files)\nweb (2 files)\n"
+	if got := collapsed.String(); got != want {
+		t.Errorf("collapsed =\n%s\nwant\n%s", got, want)
+	}
+	if d := collapsed.DOT(); !strings.Contains(d, "root -> p_internal;") || !strings.HasPrefix(d, "digraph") {
+		t.Errorf("dot =\n%s", d)
+	}
+
+	// Deleted files drop out without reindexing
+	os.RemoveAll(filepath.Join(dir, "web"))
+	if got := BuildPackageGraph(files, dir).String(); strings.Contains(got, "web") {
+		t.Errorf("graph after delete =\n%s", got)
+	}
+}
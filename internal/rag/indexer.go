@@ -15,6 +15,7 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/neves/zen-claw/internal/ignore"
 )
 
 // FileInfo represents indexed file information
@@ -35,6 +36,7 @@ type Indexer struct {
 	rootDir  string
 	patterns []string // File patterns to index
 	excludes []string // Patterns to exclude
+	ignores  bool     // Skip what ignore files exclude (unless IncludeIgnored)
 }
 
 // IndexerConfig configures the indexer
@@ -43,6 +45,8 @@ type IndexerConfig struct {
 	RootDir  string   // Root directory to index
 	Patterns []string // File patterns (e.g., "*.go", "*.ts")
 	Excludes []string // Exclude patterns (e.g., "vendor/*", "node_modules/*")
+
+	IncludeIgnored bool // Also index what .gitignore and .zenclawignore exclude
 }
 
 // DefaultIndexDBPath returns the default index database path
@@ -94,6 +98,7 @@ func NewIndexer(cfg *IndexerConfig) (*Indexer, error) {
 		rootDir:  cfg.RootDir,
 		patterns: patterns,
 		excludes: excludes,
+		ignores:  !cfg.IncludeIgnored,
 	}, nil
 }
 
@@ -141,6 +146,14 @@ func createIndexTables(db *sql.DB) error {
 		key TEXT PRIMARY KEY,
 		value TEXT
 	);
+
+	CREATE TABLE IF NOT EXISTS documents (
+		path TEXT PRIMARY KEY,
+		title TEXT,
+		url TEXT,
+		content TEXT,
+		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 	_, err := db.Exec(schema)
 	return err
@@ -150,6 +163,10 @@ func createIndexTables(db *sql.DB) error {
 func (idx *Indexer) Index() (int, error) {
 	count := 0
 	start := time.Now()
+	var ignored *ignore.Matcher
+	if idx.ignores {
+		ignored = ignore.New(idx.rootDir)
+	}
 
 	err := filepath.WalkDir(idx.rootDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -159,8 +176,8 @@ func (idx *Indexer) Index() (int, error) {
 		// Get relative path
 		relPath, _ := filepath.Rel(idx.rootDir, path)
 
-		// Skip excluded paths
-		if idx.isExcluded(relPath) {
+		// Skip excluded and ignored paths
+		if idx.isExcluded(relPath) || (relPath != "." && ignored.Ignored(path, d.IsDir())) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -225,7 +242,7 @@ func (idx *Indexer) indexFile(absPath, relPath string) error {
 	imports := extractImports(string(content), lang)
 
 	// Create content preview (first 500 chars, cleaned)
-	preview := createPreview(string(content), 500)
+	preview := createPreview(string(content), previewMax)
 
 	// Upsert into database
 	_, err = idx.db.Exec(`
@@ -304,6 +321,10 @@ func (idx *Indexer) Search(query string, limit int) ([]SearchResult, error) {
 		}
 		r.Symbols = strings.Fields(symbols)
 		r.Imports = strings.Fields(imports)
+		// Documents index much more text than a file preview; show its start
+		if r.Language == "doc" && len(r.Preview) > previewMax {
+			r.Preview = strings.ToValidUTF8(r.Preview[:previewMax], "") + "..."
+		}
 		results = append(results, r)
 	}
 
@@ -460,10 +481,16 @@ func extractSymbols(content, lang string) []string {
 var importPatterns = map[string]*regexp.Regexp{
 	"go":         regexp.MustCompile(`import\s+(?:\w+\s+)?"([^"]+)"`),
 	"python":     regexp.MustCompile(`(?:from\s+(\S+)\s+import|import\s+(\S+))`),
-	"javascript": regexp.MustCompile(`(?:import|require)\s*\(?['"]([^'"]+)['"]`),
-	"typescript": regexp.MustCompile(`(?:import|require)\s*\(?['"]([^'"]+)['"]`),
+	"javascript": regexp.MustCompile(`(?:import|require)\s*\(?['"]([^'"]+)['"]|\bfrom\s+['"]([^'"]+)['"]`),
+	"typescript": regexp.MustCompile(`(?:import|require)\s*\(?['"]([^'"]+)['"]|\bfrom\s+['"]([^'"]+)['"]`),
 }
 
+// goImportBlock matches a grouped Go import, goImportSpec a path inside it
+var (
+	goImportBlock = regexp.MustCompile(`(?s)import\s*\((.*?)\)`)
+	goImportSpec  = regexp.MustCompile(`(?m)^\s*(?:[\w.]+\s+)?"([^"]+)"`)
+)
+
 func extractImports(content, lang string) []string {
 	pattern, ok := importPatterns[lang]
 	if !ok {
@@ -474,6 +501,11 @@ func extractImports(content, lang string) []string {
 	var imports []string
 
 	matches := pattern.FindAllStringSubmatch(content, -1)
+	if lang == "go" {
+		for _, block := range goImportBlock.FindAllStringSubmatch(content, -1) {
+			matches = append(matches, goImportSpec.FindAllStringSubmatch(block[1], -1)...)
+		}
+	}
 	for _, match := range matches {
 		for i := 1; i < len(match); i++ {
 			if match[i] != "" && !seen[match[i]] {
@@ -3,6 +3,8 @@ package ratelimit
 
 import (
 	"context"
+	"maps"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,14 +24,23 @@ type Limiter struct {
 type clientLimiter struct {
 	limiter  *rate.Limiter
 	lastSeen time.Time
+	tier     string // "" = default limits
 }
 
 // Config configures the rate limiter.
 type Config struct {
-	RequestsPerSecond float64       // Max requests per second per client
-	BurstSize         int           // Max burst size (tokens)
-	CleanupInterval   time.Duration // How often to clean up stale clients
-	ClientTTL         time.Duration // How long to keep client state after last request
+	RequestsPerSecond float64           // Max requests per second per client
+	BurstSize         int               // Max burst size (tokens)
+	CleanupInterval   time.Duration     // How often to clean up stale clients
+	ClientTTL         time.Duration     // How long to keep client state after last request
+	Tiers             map[string]Tier   // Named limits for API keys
+	Keys              map[string]string // API key -> tier; other keys are limited as their address
+}
+
+// Tier is a named rate limit API keys can be assigned to.
+type Tier struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	BurstSize         int     `json:"burst"`
 }
 
 // DefaultConfig returns sensible defaults for rate limiting.
@@ -77,6 +88,21 @@ func (l *Limiter) Allow(clientID string) bool {
 	return cl.limiter.Allow()
 }
 
+// AllowKey checks a request sent with an API key. A configured key is
+// limited by its own bucket at its tier's limits; any other key (or none)
+// falls back to the client's bucket.
+func (l *Limiter) AllowKey(clientID, apiKey string) bool {
+	if apiKey != "" {
+		l.mu.RLock()
+		tier, ok := l.config.Keys[apiKey]
+		l.mu.RUnlock()
+		if ok {
+			return l.getOrCreateTier("key:"+apiKey, tier).limiter.Allow()
+		}
+	}
+	return l.Allow(clientID)
+}
+
 // Wait blocks until a request from the given client is allowed.
 // Returns an error if the context is canceled.
 func (l *Limiter) Wait(ctx context.Context, clientID string) error {
@@ -86,6 +112,11 @@ func (l *Limiter) Wait(ctx context.Context, clientID string) error {
 
 // getOrCreate returns existing or creates new limiter for client.
 func (l *Limiter) getOrCreate(clientID string) *clientLimiter {
+	return l.getOrCreateTier(clientID, "")
+}
+
+// getOrCreateTier returns existing or creates new limiter for a client of a tier.
+func (l *Limiter) getOrCreateTier(clientID, tier string) *clientLimiter {
 	l.mu.RLock()
 	cl, ok := l.limiters[clientID]
 	if ok {
@@ -104,14 +135,63 @@ func (l *Limiter) getOrCreate(clientID string) *clientLimiter {
 		return cl
 	}
 
+	rps, burst := l.limitsLocked(tier)
 	cl = &clientLimiter{
-		limiter:  rate.NewLimiter(rate.Limit(l.config.RequestsPerSecond), l.config.BurstSize),
+		limiter:  rate.NewLimiter(rps, burst),
 		lastSeen: time.Now(),
+		tier:     tier,
 	}
 	l.limiters[clientID] = cl
 	return cl
 }
 
+// limitsLocked returns a tier's limits (the defaults for "" or an unknown
+// tier). Caller holds l.mu.
+func (l *Limiter) limitsLocked(tier string) (rate.Limit, int) {
+	if t, ok := l.config.Tiers[tier]; ok && tier != "" {
+		return rate.Limit(t.RequestsPerSecond), t.BurstSize
+	}
+	return rate.Limit(l.config.RequestsPerSecond), l.config.BurstSize
+}
+
+// Update changes the limits, tiers and keys without a restart. Clients
+// already tracked get the new limits of their tier at once; a key moved to
+// another tier (or removed) starts over in its new bucket. Cleanup settings
+// are kept.
+func (l *Limiter) Update(cfg Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if cfg.RequestsPerSecond > 0 {
+		l.config.RequestsPerSecond = cfg.RequestsPerSecond
+	}
+	if cfg.BurstSize > 0 {
+		l.config.BurstSize = cfg.BurstSize
+	}
+	l.config.Tiers = maps.Clone(cfg.Tiers)
+	l.config.Keys = maps.Clone(cfg.Keys)
+
+	for id, cl := range l.limiters {
+		if key, ok := strings.CutPrefix(id, "key:"); ok && l.config.Keys[key] != cl.tier {
+			delete(l.limiters, id)
+			continue
+		}
+		rps, burst := l.limitsLocked(cl.tier)
+		cl.limiter.SetLimit(rps)
+		cl.limiter.SetBurst(burst)
+	}
+}
+
+// Config returns the current limits.
+func (l *Limiter) Config() Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	cfg := l.config
+	cfg.Tiers = maps.Clone(cfg.Tiers)
+	cfg.Keys = maps.Clone(cfg.Keys)
+	return cfg
+}
+
 // cleanupLoop removes stale client limiters.
 func (l *Limiter) cleanupLoop() {
 	for {
@@ -152,6 +232,8 @@ func (l *Limiter) Stats() map[string]interface{} {
 		"active_clients":      len(l.limiters),
 		"requests_per_second": l.config.RequestsPerSecond,
 		"burst_size":          l.config.BurstSize,
+		"tiers":               len(l.config.Tiers),
+		"keys":                len(l.config.Keys),
 	}
 }
 
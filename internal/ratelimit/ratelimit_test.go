@@ -244,3 +244,42 @@ func TestLimiterCleanup(t *testing.T) {
 		t.Errorf("expected 0 clients after cleanup, got %d", l.ClientCount())
 	}
 }
+
+func TestAllowKeyAndUpdate(t *testing.T) {
+	l := NewLimiter(Config{
+		RequestsPerSecond: 0.001,
+		BurstSize:         1,
+		CleanupInterval:   time.Hour,
+		ClientTTL:         time.Hour,
+		Tiers:             map[string]Tier{"ci": {RequestsPerSecond: 0.001, BurstSize: 3}},
+		Keys:              map[string]string{"ci-key": "ci"},
+	})
+	defer l.Close()
+
+	// A configured key gets its own bucket at its tier's burst
+	for i := 0; i < 3; i++ {
+		if !l.AllowKey("10.0.0.1", "ci-key") {
+			t.Fatalf("ci request %d should be allowed", i)
+		}
+	}
+	if l.AllowKey("10.0.0.1", "ci-key") {
+		t.Error("ci request past the tier burst should be limited")
+	}
+
+	// Unknown keys are limited as their address
+	if !l.AllowKey("10.0.0.2", "made-up") {
+		t.Error("first request should be allowed")
+	}
+	if l.AllowKey("10.0.0.2", "another-made-up") {
+		t.Error("a new unknown key must not get a fresh bucket")
+	}
+
+	// Raising the default burst applies to clients already tracked
+	l.Update(Config{RequestsPerSecond: 0.001, BurstSize: 5, Tiers: map[string]Tier{"ci": {RequestsPerSecond: 0.001, BurstSize: 3}}})
+	if got := l.Config(); got.BurstSize != 5 || got.Keys != nil {
+		t.Errorf("config = %+v, want burst 5 and no keys", got)
+	}
+	if l.AllowKey("10.0.0.1", "ci-key") != l.Allow("10.0.0.1") {
+		t.Error("a removed key should be limited as its address")
+	}
+}
@@ -0,0 +1,291 @@
+// Package recorder writes agent runs as JSONL records (request messages, final
+// response and tool trace) for building fine-tuning and eval datasets.
+// Recording is opt-in; secrets, e-mail addresses and home directories are
+// scrubbed before anything reaches disk.
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neves/zen-claw/internal/ai"
+)
+
+// ToolStep is one tool call and its result within a recorded run
+type ToolStep struct {
+	Name   string                 `json:"name"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+	Result string                 `json:"result"`
+}
+
+// Record is one request/response/tool-trace triple
+type Record struct {
+	ID        string       `json:"id"`
+	Timestamp time.Time    `json:"timestamp"`
+	SessionID string       `json:"session_id"`
+	Provider  string       `json:"provider"`
+	Model     string       `json:"model"`
+	Messages  []ai.Message `json:"messages"` // Conversation up to and including the user's request
+	Response  string       `json:"response"`
+	ToolTrace []ToolStep   `json:"tool_trace,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Rating    int          `json:"rating,omitempty"` // +1 good, -1 bad, 0 unrated
+}
+
+// ratingEntry is appended when a recorded run is rated later
+type ratingEntry struct {
+	Type   string `json:"type"` // Always "rating"
+	ID     string `json:"id"`
+	Rating int    `json:"rating"`
+}
+
+// Filter selects records when loading a dataset
+type Filter struct {
+	Provider  string // Only this provider (empty = all)
+	MinRating *int   // Only records rated at least this (nil = any, including unrated)
+}
+
+// Recorder appends records to a daily JSONL file under dir
+type Recorder struct {
+	dir       string
+	providers map[string]bool // Empty = record all providers
+	mu        sync.Mutex
+}
+
+// New creates a recorder writing to dir; providers limits which providers are recorded
+func New(dir string, providers []string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create recordings dir: %w", err)
+	}
+	r := &Recorder{dir: dir, providers: make(map[string]bool)}
+	for _, p := range providers {
+		r.providers[p] = true
+	}
+	return r, nil
+}
+
+// Dir returns the recordings directory
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Enabled reports whether runs on the given provider are recorded
+func (r *Recorder) Enabled(provider string) bool {
+	return r != nil && (len(r.providers) == 0 || r.providers[provider])
+}
+
+// Record scrubs and appends a record; the record ID is assigned if empty and returned
+func (r *Recorder) Record(rec Record) (string, error) {
+	if !r.Enabled(rec.Provider) {
+		return "", nil
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	if rec.ID == "" {
+		rec.ID = fmt.Sprintf("rec_%s_%d", rec.Timestamp.Format("20060102_150405"), rec.Timestamp.UnixNano()%1e6)
+	}
+
+	rec.Messages = append([]ai.Message(nil), rec.Messages...)
+	for i := range rec.Messages {
+		rec.Messages[i].Content = Scrub(rec.Messages[i].Content)
+		rec.Messages[i].ToolCalls = scrubToolCalls(rec.Messages[i].ToolCalls)
+		rec.Messages[i].Metadata = nil
+	}
+	rec.Response = Scrub(rec.Response)
+	rec.Error = Scrub(rec.Error)
+	rec.ToolTrace = append([]ToolStep(nil), rec.ToolTrace...)
+	for i := range rec.ToolTrace {
+		rec.ToolTrace[i].Args = scrubArgs(rec.ToolTrace[i].Args)
+		rec.ToolTrace[i].Result = Scrub(rec.ToolTrace[i].Result)
+	}
+
+	return rec.ID, r.append(rec.Timestamp, rec)
+}
+
+// Rate records a rating (+1 good, -1 bad) for a previously recorded run
+func (r *Recorder) Rate(id string, rating int) error {
+	if r == nil || id == "" {
+		return nil
+	}
+	return r.append(time.Now(), ratingEntry{Type: "rating", ID: id, Rating: rating})
+}
+
+// append writes one JSON line to the file for the given day
+func (r *Recorder) append(day time.Time, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := filepath.Join(r.dir, day.Format("2006-01-02")+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Load reads all recordings in dir, applies later ratings and returns the
+// records matching filter in chronological order
+func Load(dir string, filter Filter) ([]Record, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var records []Record
+	index := make(map[string]int)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var entry ratingEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Type == "rating" {
+				if i, ok := index[entry.ID]; ok {
+					records[i].Rating = entry.Rating
+				}
+				continue
+			}
+			var rec Record
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.ID == "" {
+				continue
+			}
+			index[rec.ID] = len(records)
+			records = append(records, rec)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+	}
+
+	var matched []Record
+	for _, rec := range records {
+		if filter.Provider != "" && rec.Provider != filter.Provider {
+			continue
+		}
+		if filter.MinRating != nil && (rec.Rating == 0 || rec.Rating < *filter.MinRating) {
+			continue
+		}
+		matched = append(matched, rec)
+	}
+	return matched, nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SCRUBBING
+// ═══════════════════════════════════════════════════════════════════════════════
+
+var scrubPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), "[REDACTED:PRIVATE_KEY]"},
+	{regexp.MustCompile(`\b(sk|pk|rk)-[A-Za-z0-9_-]{16,}`), "[REDACTED:API_KEY]"},
+	{regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{16,}`), "[REDACTED:API_KEY]"},
+	{regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr|github_pat)_[A-Za-z0-9_]{20,}`), "[REDACTED:GITHUB_TOKEN]"},
+	{regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), "[REDACTED:SLACK_TOKEN]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED:AWS_KEY]"},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), "[REDACTED:JWT]"},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]{16,}=*`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`(?i)\b([A-Z0-9_]*(?:api_?key|secret|token|password|passwd))(["']?\s*[:=]\s*["']?)[^\s"',;]{6,}`), "${1}${2}[REDACTED]"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+}
+
+// Scrub removes secrets, e-mail addresses and the home directory from text
+func Scrub(text string) string {
+	if text == "" {
+		return text
+	}
+	for _, p := range scrubPatterns {
+		text = p.re.ReplaceAllString(text, p.replacement)
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+	return text
+}
+
+// scrubArgs returns a scrubbed deep copy of tool arguments; the caller's
+// maps and slices are left untouched
+func scrubArgs(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	return scrubValue(args).(map[string]interface{})
+}
+
+// scrubValue scrubs the strings in a decoded JSON value, copying nested
+// objects and arrays
+func scrubValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return Scrub(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = scrubValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = scrubValue(item)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = Scrub(item)
+		}
+		return out
+	}
+	return v
+}
+
+func scrubToolCalls(calls []ai.ToolCall) []ai.ToolCall {
+	if calls == nil {
+		return nil
+	}
+	out := make([]ai.ToolCall, len(calls))
+	for i, c := range calls {
+		c.Args = scrubArgs(c.Args)
+		out[i] = c
+	}
+	return out
+}
+
+// TraceFromMessages builds the tool trace from the messages a run added
+func TraceFromMessages(messages []ai.Message) []ToolStep {
+	results := make(map[string]string)
+	for _, m := range messages {
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+	var trace []ToolStep
+	for _, m := range messages {
+		for _, call := range m.ToolCalls {
+			trace = append(trace, ToolStep{Name: call.Name, Args: call.Args, Result: results[call.ID]})
+		}
+	}
+	return trace
+}
@@ -118,6 +118,7 @@ func IsRetryable(err error) bool {
 		"invalid",
 		"schema validation",
 		"budget",
+		"no fixture", // Mock provider: a replay miss stays a miss
 	}
 
 	for _, s := range nonRetryable {
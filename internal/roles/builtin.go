@@ -0,0 +1,72 @@
+package roles
+
+// builtinRoles ship with zen-claw
+var builtinRoles = []Role{
+	{
+		Name:        "security_architect",
+		Description: "Zero-trust, authn/authz, encryption, threat modeling",
+		Expertise: `Your expertise includes:
+- Zero-trust architecture and defense in depth
+- Authentication, authorization, and access control
+- Encryption, key management, and secure communications
+- Threat modeling and vulnerability assessment
+- Compliance frameworks (SOC2, GDPR, HIPAA)
+- Secure coding practices and security testing`,
+		Criteria: []string{"threat coverage", "least privilege", "practicality", "compliance awareness"},
+	},
+	{
+		Name:        "software_architect",
+		Description: "System design, scalability, distributed systems",
+		Expertise: `Your expertise includes:
+- System design and component organization
+- Scalability, reliability, and performance patterns
+- Microservices and distributed systems
+- Data flow and integration patterns
+- Technology selection and trade-off analysis`,
+		Criteria: []string{"soundness of design", "trade-off analysis", "scalability", "practicality"},
+	},
+	{
+		Name:        "api_designer",
+		Description: "REST/gRPC design, versioning, error schemas",
+		Expertise: `Your expertise includes:
+- RESTful API design and OpenAPI specifications
+- gRPC and protocol buffer design
+- API versioning and backward compatibility
+- Error handling and response schemas
+- Rate limiting and API security`,
+		Criteria: []string{"consistency", "backward compatibility", "error handling", "completeness"},
+	},
+	{
+		Name:        "database_architect",
+		Description: "Schema design, indexing, replication, data modeling",
+		Expertise: `Your expertise includes:
+- Schema design and normalization
+- Query optimization and indexing strategies
+- Data modeling for different access patterns
+- Replication, sharding, and consistency models
+- Database selection (SQL vs NoSQL)`,
+		Criteria: []string{"data integrity", "query performance", "scalability", "practicality"},
+	},
+	{
+		Name:        "devops_engineer",
+		Description: "CI/CD, Kubernetes, IaC, observability",
+		Expertise: `Your expertise includes:
+- CI/CD pipeline design and automation
+- Container orchestration (Kubernetes)
+- Infrastructure as Code (Terraform, Helm)
+- Monitoring, logging, and observability
+- Deployment strategies and rollback procedures`,
+		Criteria: []string{"reliability", "automation", "rollback safety", "observability"},
+	},
+	{
+		Name:        "frontend_architect",
+		Description: "Component architecture, performance, accessibility",
+		Expertise: `Your expertise includes:
+- Component architecture and state management
+- Performance optimization and lazy loading
+- Accessibility and responsive design
+- Testing strategies (unit, integration, E2E)
+- Build tooling and bundler configuration`,
+		Criteria: []string{"maintainability", "performance", "accessibility", "completeness"},
+	},
+}
@@ -0,0 +1,215 @@
+package roles
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Roles are the expert personas consensus workers, fabric workers and factory
+// specialists are asked to play. A few are built in; users add their own as
+// markdown files in ~/.zen/zen-claw/roles/<name>.md:
+//
+//	---
+//	description: Reliability, SLOs and incident response
+//	criteria:
+//	  - Defines SLIs and SLOs with concrete targets
+//	  - Covers failure modes and rollback
+//	---
+//	Your expertise includes:
+//	- SLO design and error budgets
+//	- Incident response and postmortems
+//
+// The body is the expertise put in the prompt; the criteria are what the
+// consensus arbiter scores answers on. A file named like a built-in role
+// replaces it.
+
+// DefaultDir returns the directory user-defined roles are loaded from
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "roles")
+}
+
+// DefaultCriteria are what answers are scored on when a role defines none
+var DefaultCriteria = []string{"accuracy", "completeness", "practicality", "insight"}
+
+// Role is an expert persona
+type Role struct {
+	Name        string   `yaml:"-"`
+	Description string   `yaml:"description,omitempty"` // One line, for listings
+	Criteria    []string `yaml:"criteria,omitempty"`    // Evaluation criteria
+	Expertise   string   `yaml:"-"`                     // Prompt text (the file body)
+	Path        string   `yaml:"-"`                     // Empty for built-in roles
+}
+
+// Builtin reports whether the role ships with zen-claw
+func (r Role) Builtin() bool {
+	return r.Path == ""
+}
+
+// EvaluationCriteria returns the role's criteria, or the defaults
+func (r Role) EvaluationCriteria() []string {
+	if len(r.Criteria) > 0 {
+		return r.Criteria
+	}
+	return DefaultCriteria
+}
+
+// Registry holds the built-in and user-defined roles. A nil Registry has
+// the built-in roles only.
+type Registry struct {
+	roles map[string]Role
+}
+
+// Load returns the built-in roles overlaid with the roles in dir. Files that
+// fail to parse are logged and skipped; a missing dir is not an error.
+func Load(dir string) *Registry {
+	r := &Registry{roles: make(map[string]Role)}
+	for _, role := range builtinRoles {
+		r.roles[role.Name] = role
+	}
+	if dir == "" {
+		return r
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Roles] Failed to read %s: %v", dir, err)
+		}
+		return r
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[Roles] Failed to read %s: %v", path, err)
+			continue
+		}
+		role, err := Parse(strings.TrimSuffix(e.Name(), ".md"), data)
+		if err != nil {
+			log.Printf("[Roles] Skipping %s: %v", path, err)
+			continue
+		}
+		role.Path = path
+		r.roles[role.Name] = role
+	}
+	return r
+}
+
+// Get returns a role by name (case-insensitive; spaces and dashes match
+// underscores)
+func (r *Registry) Get(name string) (Role, bool) {
+	if r == nil {
+		r = builtins
+	}
+	role, ok := r.roles[Normalize(name)]
+	return role, ok
+}
+
+// Lookup returns the named role, or a generic one for unknown names so that
+// any role can be asked for
+func (r *Registry) Lookup(name string) Role {
+	if role, ok := r.Get(name); ok {
+		return role
+	}
+	return Role{
+		Name:      Normalize(name),
+		Expertise: fmt.Sprintf("You have deep expertise in %s. Apply your specialized knowledge to provide the best possible recommendation.", strings.ReplaceAll(Normalize(name), "_", " ")),
+	}
+}
+
+// List returns all roles sorted by name
+func (r *Registry) List() []Role {
+	if r == nil {
+		r = builtins
+	}
+	out := make([]Role, 0, len(r.roles))
+	for _, role := range r.roles {
+		out = append(out, role)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// builtins is the registry a nil Registry stands for
+var builtins = Load("")
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// Normalize turns a role name into its canonical form (security_architect)
+func Normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// Parse reads a role file: optional YAML front matter, then the expertise
+func Parse(name string, data []byte) (Role, error) {
+	role := Role{}
+	body := string(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		front, after, found := strings.Cut(rest, "\n---")
+		if !found {
+			return role, fmt.Errorf("front matter is not closed with ---")
+		}
+		if err := yaml.Unmarshal([]byte(front), &role); err != nil {
+			return role, fmt.Errorf("front matter: %w", err)
+		}
+		body = after
+		if i := strings.IndexByte(body, '\n'); i >= 0 {
+			body = body[i+1:]
+		} else {
+			body = ""
+		}
+	}
+	role.Name = Normalize(name)
+	if !namePattern.MatchString(role.Name) {
+		return role, fmt.Errorf("invalid role name %q (use letters, digits and underscores)", name)
+	}
+	role.Expertise = strings.TrimSpace(body)
+	if role.Expertise == "" {
+		return role, fmt.Errorf("role %s has no expertise text", role.Name)
+	}
+	return role, nil
+}
+
+// Format renders a role as a role file
+func Format(role Role) []byte {
+	var sb strings.Builder
+	if role.Description != "" || len(role.Criteria) > 0 {
+		front, _ := yaml.Marshal(role)
+		sb.WriteString("---\n")
+		sb.Write(front)
+		sb.WriteString("---\n")
+	}
+	sb.WriteString(strings.TrimSpace(role.Expertise))
+	sb.WriteString("\n")
+	return []byte(sb.String())
+}
+
+// Save writes a user-defined role to dir and returns its path. An existing
+// file is only replaced with overwrite.
+func Save(dir string, role Role, overwrite bool) (string, error) {
+	role.Name = Normalize(role.Name)
+	if !namePattern.MatchString(role.Name) {
+		return "", fmt.Errorf("invalid role name %q (use letters, digits and underscores)", role.Name)
+	}
+	path := filepath.Join(dir, role.Name+".md")
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return "", fmt.Errorf("role file already exists: %s", path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, Format(role), 0644)
+}
@@ -0,0 +1,68 @@
+package roles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	role, err := Parse("SRE-Lead", []byte("---\ndescription: Reliability\ncriteria:\n  - concrete SLOs\n  - rollback safety\n---\nYour expertise includes:\n- Error budgets\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.Name != "sre_lead" || role.Description != "Reliability" || len(role.Criteria) != 2 || role.Expertise != "Your expertise includes:\n- Error budgets" {
+		t.Errorf("role = %+v", role)
+	}
+
+	plain, err := Parse("tester", []byte("You test things."))
+	if err != nil || plain.Expertise != "You test things." || strings.Join(plain.EvaluationCriteria(), ",") != strings.Join(DefaultCriteria, ",") {
+		t.Errorf("plain role = %+v, %v", plain, err)
+	}
+
+	for _, bad := range []string{"---\ndescription: x\nno closing", "---\ndescription: x\n---\n"} {
+		if _, err := Parse("bad", []byte(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+	if _, err := Parse("../etc", []byte("x")); err == nil {
+		t.Error("accepted a name with a path")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Save(dir, Role{Name: "sre lead", Description: "Reliability", Expertise: "SLOs."}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Save(dir, Role{Name: "sre_lead", Expertise: "Other."}, false); err == nil {
+		t.Error("replaced an existing role without overwrite")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "security_architect.md"), []byte("Custom security."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "broken.md"), []byte(""), 0644)
+
+	r := Load(dir)
+	if role, ok := r.Get("SRE-lead"); !ok || role.Description != "Reliability" || role.Builtin() {
+		t.Errorf("user role = %+v, %v", role, ok)
+	}
+	if role, _ := r.Get("security_architect"); role.Expertise != "Custom security." {
+		t.Errorf("user file did not replace the built-in role: %q", role.Expertise)
+	}
+	if _, ok := r.Get("broken"); ok {
+		t.Error("loaded a role without expertise")
+	}
+	if len(r.List()) != len(builtinRoles)+1 {
+		t.Errorf("got %d roles", len(r.List()))
+	}
+
+	var none *Registry
+	if role, ok := none.Get("api_designer"); !ok || !role.Builtin() {
+		t.Error("nil registry lacks the built-in roles")
+	}
+	if generic := none.Lookup("kubernetes operator expert"); !strings.Contains(generic.Expertise, "kubernetes operator expert") {
+		t.Errorf("generic role = %+v", generic)
+	}
+}
@@ -0,0 +1,311 @@
+// Package schema validates decoded JSON values against a JSON Schema.
+// It covers the subset used for tool parameters and structured output:
+// type, properties, required, additionalProperties, items, enum, and the
+// numeric, string-length and array-length bounds. Coerce fixes the type
+// mistakes models commonly make in tool arguments before validation.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Validate checks value (as produced by encoding/json) against schema and
+// returns one message per violation, each prefixed with its JSON path.
+// A nil result means the value is valid.
+func Validate(schema map[string]interface{}, value interface{}) []string {
+	var errs []string
+	validate(schema, value, "$", &errs)
+	return errs
+}
+
+func validate(schema map[string]interface{}, value interface{}, path string, errs *[]string) {
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := typesOf(schema["type"]); len(types) > 0 {
+		actual := typeOf(value)
+		if !matchesType(types, value, actual) {
+			fail("expected %s, got %s", strings.Join(types, " or "), actual)
+			return
+		}
+	}
+
+	if enum := enumOf(schema["enum"]); enum != nil && !inEnum(enum, value) {
+		allowed := make([]string, len(enum))
+		for i, e := range enum {
+			b, _ := json.Marshal(e)
+			allowed[i] = string(b)
+		}
+		fail("must be one of %s", strings.Join(allowed, ", "))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range requiredOf(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := props[name].(map[string]interface{}); ok {
+				validate(sub, v[name], path+"."+name, errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", name)
+				}
+			case map[string]interface{}:
+				validate(extra, v[name], path+"."+name, errs)
+			}
+		}
+	case []interface{}:
+		if min, ok := number(schema["minItems"]); ok && float64(len(v)) < min {
+			fail("must have at least %v items", min)
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(v)) > max {
+			fail("must have at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := number(schema["minLength"]); ok && n < min {
+			fail("must be at least %v characters", min)
+		}
+		if max, ok := number(schema["maxLength"]); ok && n > max {
+			fail("must be at most %v characters", max)
+		}
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && v < min {
+			fail("must be >= %v", min)
+		}
+		if max, ok := number(schema["maximum"]); ok && v > max {
+			fail("must be <= %v", max)
+		}
+	}
+}
+
+// Coerce converts values sent as the wrong JSON type to the type schema asks
+// for: numeric and boolean strings ("5", "true"), numbers and booleans where
+// a string is expected, JSON-encoded objects and arrays, and a single value
+// where an array is expected. Optional object properties set to null are
+// dropped. Values that can't be converted are left for Validate to report.
+// Maps and slices are copied, never modified.
+func Coerce(schema map[string]interface{}, value interface{}) interface{} {
+	if schema == nil {
+		return value
+	}
+	if types := typesOf(schema["type"]); len(types) > 0 && !matchesType(types, value, typeOf(value)) {
+		for _, t := range types {
+			if converted, ok := convert(t, value); ok {
+				value = converted
+				break
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		required := make(map[string]bool)
+		for _, name := range requiredOf(schema["required"]) {
+			required[name] = true
+		}
+		out := make(map[string]interface{}, len(v))
+		for name, val := range v {
+			sub, ok := props[name].(map[string]interface{})
+			if !ok {
+				sub, _ = schema["additionalProperties"].(map[string]interface{})
+			}
+			if val == nil && !required[name] && sub != nil && !matchesType(typesOf(sub["type"]), nil, "null") {
+				continue // Optional property explicitly unset
+			}
+			out[name] = Coerce(sub, val)
+		}
+		return out
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = Coerce(items, item)
+		}
+		return out
+	}
+	return value
+}
+
+// convert tries to turn value into JSON type t
+func convert(t string, value interface{}) (interface{}, bool) {
+	switch t {
+	case "integer", "number":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || (t == "integer" && f != math.Trunc(f)) {
+			return nil, false
+		}
+		return f, true
+	case "boolean":
+		if s, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+	case "string":
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), true
+		default:
+			if f, ok := number(v); ok {
+				return strconv.FormatFloat(f, 'f', -1, 64), true
+			}
+		}
+	case "array":
+		if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var decoded []interface{}
+			if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+				return decoded, true
+			}
+		}
+		if value != nil {
+			return []interface{}{value}, true
+		}
+	case "object":
+		if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "{") {
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+				return decoded, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// enumOf accepts both []interface{} (decoded JSON) and []string (Go literals)
+func enumOf(e interface{}) []interface{} {
+	switch e := e.(type) {
+	case []interface{}:
+		return e
+	case []string:
+		enum := make([]interface{}, len(e))
+		for i, s := range e {
+			enum[i] = s
+		}
+		return enum
+	}
+	return nil
+}
+
+// typesOf normalizes "type": "x" and "type": ["x", "y"]
+func typesOf(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// requiredOf accepts both []interface{} (decoded JSON) and []string (Go literals)
+func requiredOf(r interface{}) []string {
+	switch r := r.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		var names []string
+		for _, s := range r {
+			if s, ok := s.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// typeOf returns the JSON type name of a decoded value
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, int, int64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesType(types []string, value interface{}, actual string) bool {
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "integer" && actual == "number" {
+			if f, ok := number(value); ok && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	want, _ := json.Marshal(value)
+	for _, e := range enum {
+		if got, _ := json.Marshal(e); string(got) == string(want) {
+			return true
+		}
+	}
+	return false
+}
+
+// number converts JSON and Go numeric values to float64
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
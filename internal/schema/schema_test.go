@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var s map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"status": {"type": "string", "enum": ["ok", "failed"]},
+			"count": {"type": "integer", "minimum": 0},
+			"files": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		},
+		"required": ["status"],
+		"additionalProperties": false
+	}`), &s)
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string // Substrings expected in violations (empty = valid)
+	}{
+		{"valid", `{"status":"ok","count":3,"files":["a.go"]}`, nil},
+		{"missing required", `{"count":1}`, []string{`missing required property "status"`}},
+		{"wrong type", `{"status":1}`, []string{"$.status: expected string, got number"}},
+		{"enum", `{"status":"maybe"}`, []string{"must be one of"}},
+		{"integer", `{"status":"ok","count":1.5}`, []string{"$.count: expected integer"}},
+		{"minimum", `{"status":"ok","count":-1}`, []string{"must be >= 0"}},
+		{"items", `{"status":"ok","files":["a",2]}`, []string{"$.files[1]: expected string"}},
+		{"max items", `{"status":"ok","files":["a","b","c"]}`, []string{"at most 2 items"}},
+		{"additional", `{"status":"ok","extra":true}`, []string{`unexpected property "extra"`}},
+		{"root type", `[1,2]`, []string{"$: expected object, got array"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.value), &v); err != nil {
+				t.Fatal(err)
+			}
+			errs := Validate(s, v)
+			if len(tt.want) == 0 && len(errs) > 0 {
+				t.Errorf("expected valid, got %v", errs)
+			}
+			joined := strings.Join(errs, "\n")
+			for _, w := range tt.want {
+				if !strings.Contains(joined, w) {
+					t.Errorf("violations %q missing %q", joined, w)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGoLiterals(t *testing.T) {
+	// Tool parameter schemas are Go literals with []string required lists
+	s := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"path"},
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{"type": "string"},
+		},
+	}
+	if errs := Validate(s, map[string]interface{}{}); len(errs) != 1 {
+		t.Errorf("expected 1 violation, got %v", errs)
+	}
+	if errs := Validate(s, map[string]interface{}{"path": "a.go"}); len(errs) != 0 {
+		t.Errorf("expected valid, got %v", errs)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	s := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer"},
+			"all":   map[string]interface{}{"type": "boolean"},
+			"name":  map[string]interface{}{"type": "string"},
+			"files": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"mode":  map[string]interface{}{"type": "string", "enum": []string{"a", "b"}},
+		},
+	}
+
+	var in map[string]interface{}
+	json.Unmarshal([]byte(`{"count":"50","all":"true","name":42,"files":"[\"a.go\",\"b.go\"]","mode":null}`), &in)
+	got := Coerce(s, in).(map[string]interface{})
+	if got["count"] != 50.0 || got["all"] != true || got["name"] != "42" {
+		t.Errorf("scalars not coerced: %v", got)
+	}
+	if files, ok := got["files"].([]interface{}); !ok || len(files) != 2 {
+		t.Errorf("JSON-encoded array not decoded: %v", got["files"])
+	}
+	if _, ok := got["mode"]; ok {
+		t.Error("optional null property should be dropped")
+	}
+	if in["count"] != "50" {
+		t.Error("Coerce modified its input")
+	}
+	if errs := Validate(s, got); len(errs) != 0 {
+		t.Errorf("coerced value invalid: %v", errs)
+	}
+
+	// A single value becomes a one-element array
+	got = Coerce(s, map[string]interface{}{"files": "main.go"}).(map[string]interface{})
+	if files, ok := got["files"].([]interface{}); !ok || len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("single value not wrapped: %v", got["files"])
+	}
+
+	// What can't be converted is left for Validate; []string enums are checked
+	got = Coerce(s, map[string]interface{}{"count": "many", "mode": "c"}).(map[string]interface{})
+	errs := strings.Join(Validate(s, got), "\n")
+	if !strings.Contains(errs, "$.count: expected integer, got string") || !strings.Contains(errs, `$.mode: must be one of "a", "b"`) {
+		t.Errorf("violations = %q", errs)
+	}
+}
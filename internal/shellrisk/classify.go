@@ -0,0 +1,836 @@
+package shellrisk
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Class is what a command can do, from harmless to irreversible
+type Class string
+
+const (
+	ReadOnly      Class = "read-only"      // Only reads (files, processes, the repo)
+	Mutating      Class = "mutating"       // Changes files or state, or runs code that may
+	NetworkEgress Class = "network-egress" // Talks to other hosts
+	Destructive   Class = "destructive"    // Deletes or overwrites data irrecoverably
+)
+
+// Classes lists the classes from least to most severe
+var Classes = []Class{ReadOnly, Mutating, NetworkEgress, Destructive}
+
+// Severity orders classes (0 = read-only); -1 for an unknown class
+func (c Class) Severity() int {
+	return slices.Index(Classes, c)
+}
+
+// Analysis is the classification of a command line
+type Analysis struct {
+	Class    Class    `json:"class"`              // Most severe class found
+	Classes  []Class  `json:"classes"`            // Every class found, least severe first
+	Reasons  []string `json:"reasons,omitempty"`  // Why it is not read-only
+	Programs []string `json:"programs,omitempty"` // Programs it runs
+	Writes   []string `json:"writes,omitempty"`   // Paths it visibly writes, deletes or moves
+
+	// Programs running code the command line doesn't show (interpreters,
+	// scripts, builds and tests, unknown programs)
+	RunsCode []string `json:"runs_code,omitempty"`
+
+	// Where network commands go (see egress.go)
+	Hosts         []string `json:"hosts,omitempty"`          // Hosts named on the command line
+	Remotes       []string `json:"remotes,omitempty"`        // Git remotes contacted by name
+	UnknownEgress []string `json:"unknown_egress,omitempty"` // Network commands with no visible destination
+}
+
+// Has reports whether any part of the command falls in class c
+func (a Analysis) Has(c Class) bool {
+	return slices.Contains(a.Classes, c)
+}
+
+// maxDepth bounds bash -c / eval nesting
+const maxDepth = 5
+
+// Classify parses a command line and classifies every command in it
+// (pipelines, substitutions, sh -c scripts, xargs and find -exec commands
+// included). It can't see what a program does internally, so anything it
+// doesn't know is mutating: only commands known to be read-only are.
+func Classify(command string) Analysis {
+	c := &classifier{}
+	c.script(command, 0)
+	a := Analysis{
+		Class:         ReadOnly,
+		Reasons:       c.reasons,
+		Programs:      c.programs,
+		Writes:        c.writes,
+		RunsCode:      c.runsCode,
+		Hosts:         c.hosts,
+		Remotes:       c.remotes,
+		UnknownEgress: c.unknownEgress,
+	}
+	for _, class := range Classes {
+		if c.found[class] || class == ReadOnly && len(c.found) == 0 {
+			a.Classes = append(a.Classes, class)
+			a.Class = class
+		}
+	}
+	return a
+}
+
+type classifier struct {
+	found         map[Class]bool
+	reasons       []string
+	programs      []string
+	writes        []string
+	runsCode      []string
+	hosts         []string
+	remotes       []string
+	unknownEgress []string
+}
+
+func (c *classifier) add(class Class, reason string) {
+	if c.found == nil {
+		c.found = make(map[Class]bool)
+	}
+	c.found[class] = true
+	if class != ReadOnly && reason != "" && !slices.Contains(c.reasons, reason) {
+		c.reasons = append(c.reasons, reason)
+	}
+}
+
+func (c *classifier) write(paths ...string) {
+	for _, p := range paths {
+		if p != "" {
+			c.writes = append(c.writes, p)
+		}
+	}
+}
+
+func (c *classifier) script(command string, depth int) {
+	if depth > maxDepth {
+		c.add(Mutating, "shell nesting too deep to analyze")
+		return
+	}
+	cmds, err := Parse(command)
+	if err != nil {
+		// What parsed is still classified; the rest can't be shown safe
+		c.add(Mutating, "could not parse: "+err.Error())
+	}
+	for _, cmd := range cmds {
+		c.command(cmd, depth)
+	}
+	if c.found == nil {
+		c.add(ReadOnly, "")
+	}
+}
+
+func (c *classifier) command(cmd Command, depth int) {
+	for _, r := range cmd.Redirects {
+		if !r.Output() {
+			continue
+		}
+		if isDevice(r.Target) {
+			c.add(Destructive, "writes to device "+r.Target)
+		} else {
+			c.add(Mutating, "writes "+r.Target)
+		}
+		c.write(r.Target)
+	}
+	if cmd.Name == "" {
+		c.assigns(cmd.Assigns, false)
+		return
+	}
+	c.assigns(cmd.Assigns, true)
+	c.program(cmd.Name, cmd.Args, depth)
+}
+
+// safeEnv are variables that only change how programs format their output;
+// anything else can change what a program runs or loads (LD_PRELOAD, PAGER,
+// GIT_EXTERNAL_DIFF, PATH, ...)
+var safeEnv = toSet("LANG", "LANGUAGE", "TZ", "TERM", "NO_COLOR", "CLICOLOR", "CLICOLOR_FORCE", "FORCE_COLOR", "COLUMNS", "LINES")
+
+// assigns classifies VAR=value words: prefixes of a program (or env
+// arguments) are in its environment, so only safeEnv is read-only. Bare
+// assignments set shell variables; lowercase ones by convention stay in
+// the shell, the rest may be exported to later commands.
+func (c *classifier) assigns(words []string, prefix bool) {
+	c.add(ReadOnly, "")
+	for _, word := range words {
+		name, _, _ := strings.Cut(word, "=")
+		if safeEnv[name] || strings.HasPrefix(name, "LC_") || !prefix && strings.ToLower(name) == name {
+			continue
+		}
+		c.add(Mutating, "sets "+name+", which can change what programs run")
+	}
+}
+
+// shellKeywords start compound commands; what follows them is a command
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true, "do": true, "done": true,
+	"while": true, "until": true, "!": true, "esac": true, "time": true,
+}
+
+// program classifies one program invocation, unwrapping wrappers like sudo
+func (c *classifier) program(name string, args []string, depth int) {
+	for shellKeywords[name] {
+		if len(args) == 0 {
+			c.add(ReadOnly, "")
+			return
+		}
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "for", "select", "case", "function", "in", ";;":
+		c.add(ReadOnly, "") // Loop headers, the body is classified separately
+		return
+	}
+
+	base := filepath.Base(name)
+	c.programs = appendUnique(c.programs, base)
+	switch base {
+	case "sudo", "doas", "su":
+		c.add(Mutating, base+" runs with elevated privileges")
+		if base == "su" {
+			if script := optionValue(args, "-c", "--command"); script != "" {
+				c.script(script, depth+1)
+			}
+			return
+		}
+		if rest := skipOptions(args, "-u", "-g", "-C", "-D", "-h", "-p", "-U", "-r", "-t"); len(rest) > 0 {
+			c.program(rest[0], rest[1:], depth)
+		}
+		return
+	case "env":
+		rest := skipOptions(args, "-u", "-C", "-S")
+		var assigns []string
+		for len(rest) > 0 && strings.Contains(rest[0], "=") {
+			assigns, rest = append(assigns, rest[0]), rest[1:]
+		}
+		c.assigns(assigns, true)
+		if len(rest) > 0 {
+			c.program(rest[0], rest[1:], depth)
+		} else {
+			c.add(ReadOnly, "")
+		}
+		return
+	case "nohup", "command", "builtin", "exec", "stdbuf", "ionice", "nice", "timeout", "chrt", "taskset", "unbuffer", "caffeinate":
+		rest := skipOptions(args, "-n", "-c", "-p", "-i", "-o", "-e", "-s", "-k", "--signal", "--kill-after", "--adjustment")
+		if base == "timeout" && len(rest) > 0 {
+			rest = rest[1:] // Duration
+		}
+		if len(rest) > 0 {
+			c.program(rest[0], rest[1:], depth)
+		} else {
+			c.add(ReadOnly, "")
+		}
+		return
+	case "watch":
+		rest := skipOptions(args, "-n", "--interval", "-d")
+		if len(rest) > 0 {
+			c.script(strings.Join(rest, " "), depth+1)
+		}
+		return
+	case "xargs":
+		// The input is unknown, so only the command itself can be classified
+		rest := skipOptions(args, "-I", "-L", "-n", "-P", "-d", "-E", "-s", "-a", "--max-args", "--max-procs", "--delimiter", "--arg-file", "--replace")
+		if len(rest) == 0 {
+			rest = []string{"echo"}
+		}
+		c.program(rest[0], rest[1:], depth)
+		return
+	case "sh", "bash", "zsh", "dash", "ksh", "ash", "fish":
+		if script := optionValue(args, "-c", ""); script != "" {
+			c.script(script, depth+1)
+			return
+		}
+		if rest := skipOptions(args); len(rest) > 0 {
+			c.add(Mutating, "runs script "+rest[0])
+		} else {
+			c.add(Mutating, "runs a shell reading commands from its input")
+		}
+		c.runsCode = appendUnique(c.runsCode, base)
+		return
+	case "eval":
+		c.script(strings.Join(args, " "), depth+1)
+		return
+	case "export", "declare", "typeset", "readonly", "local":
+		c.assigns(slices.DeleteFunc(slices.Clone(args), func(a string) bool { return !strings.Contains(a, "=") }), false)
+		return
+	case "find":
+		c.find(args, depth)
+		return
+	case "git":
+		c.git(args)
+		return
+	}
+
+	class, reason := classifyProgram(base, args)
+	c.add(class, reason)
+	c.write(writeTargets(base, args)...)
+	if runsHiddenCode(base, args) {
+		c.runsCode = appendUnique(c.runsCode, base)
+	}
+	if class == NetworkEgress {
+		c.egress(base, args)
+	}
+}
+
+// find is read-only unless it deletes, writes or runs other commands
+func (c *classifier) find(args []string, depth int) {
+	c.add(ReadOnly, "")
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-delete":
+			c.add(Destructive, "find -delete removes files")
+		case "-fprint", "-fprint0", "-fprintf", "-fls":
+			c.add(Mutating, "find "+args[i]+" writes a file")
+			if i+1 < len(args) {
+				c.write(args[i+1])
+			}
+		case "-exec", "-execdir", "-ok", "-okdir":
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != "+" {
+				end++
+			}
+			if end > i+1 {
+				c.program(args[i+1], args[i+2:end], depth)
+			}
+			i = end
+		}
+	}
+}
+
+// git classifies by subcommand (and the flags that make one destructive)
+func (c *classifier) git(args []string) {
+	// Global options come before the subcommand. Configuration set on the
+	// command line can run programs (core.pager, diff.external, alias.*),
+	// like a changed exec path
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch opt, _, _ := strings.Cut(args[0], "="); opt {
+		case "-c", "--config-env", "--exec-path":
+			c.add(Mutating, "git "+opt+" can make git run other programs")
+		}
+		if args[0] == "-C" || args[0] == "-c" || args[0] == "--config-env" {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		c.add(ReadOnly, "")
+		return
+	}
+	sub, rest := args[0], args[1:]
+	has := func(flags ...string) bool {
+		return slices.ContainsFunc(rest, func(a string) bool { return slices.Contains(flags, a) })
+	}
+	first := ""
+	if len(rest) > 0 {
+		first = rest[0]
+	}
+	c.gitEgress(sub, rest)
+
+	if out := optionValue(rest, "--output", "--output"); out != "" && (sub == "diff" || sub == "log" || sub == "show") {
+		c.add(Mutating, "git "+sub+" --output writes "+out)
+		c.write(out)
+		return
+	}
+
+	switch sub {
+	case "status", "log", "diff", "show", "rev-parse", "rev-list", "ls-files", "ls-tree", "blame", "grep",
+		"describe", "shortlog", "cat-file", "merge-base", "name-rev", "whatchanged", "version", "help",
+		"count-objects", "check-ignore", "for-each-ref", "show-ref", "var", "cherry", "range-diff", "annotate", "verify-commit":
+		c.add(ReadOnly, "")
+	case "fetch", "ls-remote":
+		c.add(NetworkEgress, "git "+sub+" contacts a remote")
+	case "clone":
+		c.add(NetworkEgress, "git clone contacts a remote")
+		c.add(Mutating, "git clone writes a repository")
+	case "pull":
+		c.add(NetworkEgress, "git pull contacts a remote")
+		c.add(Mutating, "git pull changes the working tree")
+	case "push":
+		c.add(NetworkEgress, "git push sends commits to a remote")
+		if has("-f", "--force", "--force-with-lease", "--mirror", "-d", "--delete", "--prune") ||
+			slices.ContainsFunc(rest, func(a string) bool {
+				return strings.HasPrefix(a, "+") || strings.HasPrefix(a, ":") || strings.HasPrefix(a, "--force")
+			}) {
+			c.add(Destructive, "git push can overwrite or delete remote history")
+		}
+	case "reset":
+		if has("--hard", "--merge", "--keep") {
+			c.add(Destructive, "git reset "+strings.Join(rest, " ")+" discards changes")
+		} else {
+			c.add(Mutating, "git reset changes the index")
+		}
+	case "clean":
+		if has("-n", "--dry-run") {
+			c.add(ReadOnly, "")
+		} else {
+			c.add(Destructive, "git clean deletes untracked files")
+		}
+	case "checkout", "switch":
+		if has("--", ".", "-f", "--force", "--discard-changes") {
+			c.add(Destructive, "git "+sub+" discards working tree changes")
+		} else {
+			c.add(Mutating, "git "+sub+" changes the working tree")
+		}
+	case "restore":
+		if has("--staged", "-S") && !has("--worktree", "-W") {
+			c.add(Mutating, "git restore --staged changes the index")
+		} else {
+			c.add(Destructive, "git restore discards working tree changes")
+		}
+	case "branch", "tag":
+		switch {
+		case has("-D") || sub == "branch" && has("-d", "--delete") && has("-f", "--force"):
+			c.add(Destructive, "git "+sub+" force-deletes")
+		case len(rest) == 0 || has("-l", "--list", "-a", "-r", "-v", "-vv", "--show-current", "--contains", "--merged", "--no-merged"):
+			c.add(ReadOnly, "")
+		default:
+			c.add(Mutating, "git "+sub+" changes refs")
+		}
+	case "stash":
+		switch first {
+		case "list", "show":
+			c.add(ReadOnly, "")
+		case "drop", "clear":
+			c.add(Destructive, "git stash "+first+" deletes stashed changes")
+		default:
+			c.add(Mutating, "git stash changes the working tree")
+		}
+	case "remote":
+		switch {
+		case len(rest) == 0 || first == "-v" || first == "get-url":
+			c.add(ReadOnly, "")
+		case first == "show" || first == "update" || first == "prune":
+			c.add(NetworkEgress, "git remote "+first+" contacts a remote")
+		default:
+			c.add(Mutating, "git remote changes the configuration")
+		}
+	case "config":
+		if has("--get", "--get-all", "--get-regexp", "-l", "--list") || len(rest) == 1 {
+			c.add(ReadOnly, "")
+		} else {
+			c.add(Mutating, "git config changes the configuration")
+		}
+	case "reflog":
+		if first == "expire" || first == "delete" {
+			c.add(Destructive, "git reflog "+first+" drops recovery points")
+		} else {
+			c.add(ReadOnly, "")
+		}
+	case "worktree", "submodule":
+		switch first {
+		case "list", "status":
+			c.add(ReadOnly, "")
+		case "update", "add":
+			c.add(Mutating, "git "+sub+" "+first+" changes the working tree")
+			if sub == "submodule" {
+				c.add(NetworkEgress, "git submodule "+first+" may fetch")
+			}
+		case "remove", "prune", "deinit":
+			c.add(Destructive, "git "+sub+" "+first+" deletes files")
+		default:
+			c.add(Mutating, "git "+sub+" changes the repository")
+		}
+	case "filter-branch", "filter-repo", "update-ref", "gc", "prune":
+		if sub == "update-ref" && !has("-d") || sub == "gc" && !slices.ContainsFunc(rest, func(a string) bool { return strings.HasPrefix(a, "--prune") }) {
+			c.add(Mutating, "git "+sub+" changes the repository")
+		} else {
+			c.add(Destructive, "git "+sub+" rewrites or drops history")
+		}
+	case "send-email", "request-pull":
+		c.add(NetworkEgress, "git "+sub+" sends data")
+	default:
+		c.add(Mutating, "git "+sub+" changes the repository")
+	}
+}
+
+// readOnlyPrograms only read (given no output redirection)
+var readOnlyPrograms = toSet(
+	"ls", "ll", "la", "dir", "cat", "head", "tail", "less", "more", "grep", "egrep", "fgrep", "rg", "ag", "ack",
+	"wc", "uniq", "cut", "tr", "diff", "cmp", "comm", "file", "stat", "du", "df", "pwd", "echo", "printf",
+	"which", "whereis", "type", "whoami", "id", "groups", "uname", "cal", "ps", "pgrep", "uptime", "free",
+	"tree", "jq", "basename", "dirname", "realpath", "readlink", "true", "false", "test", "[", "[[", "sleep",
+	"md5sum", "sha1sum", "sha256sum", "sha512sum", "cksum", "b2sum", "xxd", "hexdump", "od", "strings", "column",
+	"nl", "tac", "rev", "seq", "expr", "bc", "fold", "fmt", "paste", "join", "look", "printenv", "locale", "nproc",
+	"getconf", "man", "help", "info", "apropos", "whatis", "zcat", "zgrep", "bzcat", "xzcat", "lsof", "netstat", "ss",
+	"lsblk", "env", "history", "cd", "pushd", "popd", "export", "unset", "set", "alias", "read", "local", "declare",
+	"shift", "wait", "jobs", "hash", "ulimit", "umask", ":", "]]", "tput", "clear", "tokei", "cloc",
+	"top", "htop", "vmstat", "iostat", "getent", "base64", "sha224sum", "sha384sum",
+)
+
+// networkPrograms talk to other hosts
+var networkPrograms = toSet(
+	"curl", "wget", "ssh", "scp", "sftp", "ftp", "nc", "ncat", "netcat", "telnet", "ping", "ping6", "traceroute",
+	"dig", "nslookup", "host", "whois", "http", "https", "xh", "socat", "aria2c", "gh", "glab", "aws", "gcloud",
+	"gsutil", "az", "kubectl", "helm", "ssh-copy-id", "mosh", "lftp", "s3cmd", "rclone", "doctl", "flyctl",
+	"heroku", "vercel", "netlify",
+)
+
+// destructivePrograms delete or overwrite data
+var destructivePrograms = toSet(
+	"rm", "shred", "wipefs", "fdisk", "sfdisk", "parted", "mkswap", "truncate", "unlink", "srm",
+	"reboot", "shutdown", "halt", "poweroff",
+)
+
+// mutatingPrograms change files or state
+var mutatingPrograms = toSet(
+	"mv", "cp", "touch", "mkdir", "rmdir", "ln", "chmod", "chown", "chgrp", "install", "tee", "patch",
+	"kill", "pkill", "killall", "tar", "zip", "unzip", "gzip", "gunzip", "bzip2", "xz", "make", "cmake",
+)
+
+// packageManagers install from the network; their listing commands are read-only
+var packageManagers = map[string]struct{ readOnly, network, destructive []string }{
+	"npm":     {[]string{"ls", "list", "-v", "--version", "help", "config"}, []string{"install", "i", "ci", "add", "update", "upgrade", "publish", "outdated", "view", "info", "audit", "login", "pack"}, []string{"uninstall", "unpublish", "prune"}},
+	"yarn":    {[]string{"list", "why", "-v", "--version", "help"}, []string{"install", "add", "upgrade", "up", "publish", "info", "dlx"}, []string{"remove"}},
+	"pnpm":    {[]string{"ls", "list", "why", "-v", "--version", "help"}, []string{"install", "i", "add", "update", "up", "publish", "dlx"}, []string{"remove", "rm", "prune"}},
+	"pip":     {[]string{"list", "show", "freeze", "check", "--version", "help"}, []string{"install", "download", "search"}, []string{"uninstall"}},
+	"pip3":    {[]string{"list", "show", "freeze", "check", "--version", "help"}, []string{"install", "download", "search"}, []string{"uninstall"}},
+	"cargo":   {[]string{"--version", "tree", "metadata", "help"}, []string{"fetch", "install", "update", "publish", "add", "search"}, []string{"uninstall"}},
+	"apt":     {[]string{"list", "show", "search", "policy"}, []string{"install", "update", "upgrade", "full-upgrade", "dist-upgrade", "download"}, []string{"remove", "purge", "autoremove"}},
+	"apt-get": {nil, []string{"install", "update", "upgrade", "dist-upgrade", "download", "source"}, []string{"remove", "purge", "autoremove", "clean"}},
+	"yum":     {[]string{"list", "info", "search"}, []string{"install", "update", "upgrade"}, []string{"remove", "erase"}},
+	"dnf":     {[]string{"list", "info", "search"}, []string{"install", "update", "upgrade"}, []string{"remove", "erase"}},
+	"apk":     {[]string{"info", "search"}, []string{"add", "update", "upgrade", "fetch"}, []string{"del"}},
+	"brew":    {[]string{"list", "--version", "config", "doctor"}, []string{"install", "update", "upgrade", "tap", "fetch", "search", "info"}, []string{"uninstall", "remove", "rm", "cleanup"}},
+	"gem":     {[]string{"list", "which", "contents", "env"}, []string{"install", "update", "fetch", "push", "search"}, []string{"uninstall", "cleanup"}},
+	"docker":  {[]string{"ps", "images", "inspect", "logs", "version", "info", "stats", "top", "diff", "history", "port"}, []string{"pull", "push", "login", "search", "build", "run"}, []string{"rm", "rmi", "prune", "kill"}},
+	"podman":  {[]string{"ps", "images", "inspect", "logs", "version", "info", "stats", "top", "diff", "history", "port"}, []string{"pull", "push", "login", "search", "build", "run"}, []string{"rm", "rmi", "prune", "kill"}},
+}
+
+// interpreters run code the classifier can't see
+var interpreters = toSet("python", "python3", "python2", "node", "deno", "bun", "ruby", "perl", "php", "lua", "Rscript", "java", "dotnet")
+
+// classifyProgram classifies a program that isn't a wrapper or git/find
+func classifyProgram(base string, args []string) (Class, string) {
+	has := func(flags ...string) bool {
+		return slices.ContainsFunc(args, func(a string) bool { return slices.Contains(flags, a) })
+	}
+	hasPrefix := func(prefix string) bool {
+		return slices.ContainsFunc(args, func(a string) bool { return strings.HasPrefix(a, prefix) })
+	}
+
+	switch base {
+	case "go":
+		switch firstArg(args) {
+		case "version", "env", "list", "doc", "vet", "help", "":
+			return ReadOnly, ""
+		case "get", "install":
+			return NetworkEgress, "go " + firstArg(args) + " downloads modules"
+		case "mod":
+			if len(args) > 1 && (args[1] == "download" || args[1] == "tidy") {
+				return NetworkEgress, "go mod " + args[1] + " downloads modules"
+			}
+			if len(args) > 1 && (args[1] == "graph" || args[1] == "why" || args[1] == "verify") {
+				return ReadOnly, ""
+			}
+		}
+		return Mutating, "go " + firstArg(args) + " builds or runs code"
+	case "gofmt":
+		if has("-w") {
+			return Mutating, "gofmt -w rewrites files"
+		}
+		return ReadOnly, ""
+	case "sed":
+		if hasPrefix("-i") || has("--in-place") || hasPrefix("--in-place=") {
+			return Mutating, "sed -i edits files in place"
+		}
+		return ReadOnly, ""
+	case "awk", "gawk", "mawk", "nawk":
+		if has("-i") || slices.ContainsFunc(args, func(a string) bool {
+			return strings.Contains(a, "system(") || strings.Contains(a, "| getline") || strings.Contains(a, "print >") || strings.Contains(a, "printf >")
+		}) {
+			return Mutating, base + " program writes files or runs commands"
+		}
+		return ReadOnly, ""
+	case "sort":
+		if has("-o") || hasPrefix("--output") {
+			return Mutating, "sort -o writes a file"
+		}
+		return ReadOnly, ""
+	case "tree":
+		if out := optionValue(args, "-o", ""); out != "" {
+			return Mutating, "tree -o writes " + out
+		}
+		return ReadOnly, ""
+	case "xxd":
+		if out := xxdOutput(args); out != "" {
+			return Mutating, "xxd writes " + out
+		}
+		if has("-r", "-revert") {
+			return Mutating, "xxd -r turns a dump back into binary"
+		}
+		return ReadOnly, ""
+	case "yq":
+		if has("-i", "--inplace") {
+			return Mutating, "yq -i edits files in place"
+		}
+		return ReadOnly, ""
+	case "tee":
+		if len(fileArgs(args)) == 0 {
+			return ReadOnly, ""
+		}
+		return Mutating, "tee writes " + strings.Join(fileArgs(args), ", ")
+	case "hostname", "mount":
+		if len(args) > 0 {
+			return Mutating, base + " changes system settings"
+		}
+		return ReadOnly, ""
+	case "date":
+		if has("-s", "--set") {
+			return Mutating, "date -s sets the clock"
+		}
+		return ReadOnly, ""
+	case "dd":
+		for _, a := range args {
+			if of, ok := strings.CutPrefix(a, "of="); ok {
+				return Destructive, "dd overwrites " + of
+			}
+		}
+		return ReadOnly, ""
+	case "crontab":
+		if has("-r") {
+			return Destructive, "crontab -r deletes the crontab"
+		}
+		if has("-l") {
+			return ReadOnly, ""
+		}
+		return Mutating, "crontab changes scheduled jobs"
+	case "rsync":
+		if slices.ContainsFunc(fileArgs(args), isRemote) {
+			return NetworkEgress, "rsync copies to or from another host"
+		}
+		if has("--delete", "--delete-before", "--delete-after", "--remove-source-files") {
+			return Destructive, "rsync deletes files"
+		}
+		return Mutating, "rsync copies files"
+	case "psql", "mysql", "sqlite3", "mongo", "mongosh", "redis-cli", "clickhouse-client":
+		query := strings.ToLower(strings.Join(args, " "))
+		for _, kw := range []string{"drop ", "truncate ", "delete from", "flushall", "flushdb", "dropdatabase", ".drop("} {
+			if strings.Contains(query, kw) {
+				return Destructive, base + " query deletes data"
+			}
+		}
+		return Mutating, base + " can change the database"
+	case "terraform", "tofu", "pulumi":
+		switch firstArg(args) {
+		case "destroy":
+			return Destructive, base + " destroy deletes infrastructure"
+		case "apply", "up", "import":
+			if has("-destroy") {
+				return Destructive, base + " apply -destroy deletes infrastructure"
+			}
+			return NetworkEgress, base + " " + firstArg(args) + " changes infrastructure"
+		case "fmt", "validate", "version", "show", "graph", "output", "providers":
+			return ReadOnly, ""
+		}
+		return NetworkEgress, base + " " + firstArg(args) + " contacts providers"
+	}
+
+	if strings.HasPrefix(base, "mkfs") {
+		return Destructive, base + " formats a filesystem"
+	}
+	if pm, ok := packageManagers[base]; ok {
+		sub := firstArg(args)
+		switch {
+		case slices.Contains(pm.destructive, sub):
+			return Destructive, base + " " + sub + " removes software or data"
+		case slices.Contains(pm.network, sub):
+			return NetworkEgress, base + " " + sub + " contacts a registry"
+		case slices.Contains(pm.readOnly, sub):
+			return ReadOnly, ""
+		}
+		return Mutating, base + " " + sub + " runs or changes the project"
+	}
+	switch {
+	case readOnlyPrograms[base]:
+		return ReadOnly, ""
+	case networkPrograms[base]:
+		return NetworkEgress, base + " contacts other hosts"
+	case destructivePrograms[base]:
+		return Destructive, base + " deletes or overwrites data"
+	case mutatingPrograms[base]:
+		return Mutating, base + " changes files or processes"
+	case interpreters[base]:
+		return Mutating, base + " runs code"
+	case base == "source" || base == ".":
+		return Mutating, "sources " + firstArg(args)
+	}
+	return Mutating, "unknown program " + base
+}
+
+// runsHiddenCode reports whether a program runs code the command line
+// doesn't show: interpreters, scripts, builds and tests, package manager
+// scripts and hooks, and programs the classifier doesn't know
+func runsHiddenCode(base string, args []string) bool {
+	switch base {
+	case "go":
+		switch firstArg(args) {
+		case "run", "test", "generate", "tool":
+			return true
+		}
+		return false
+	case "awk", "gawk", "mawk", "nawk":
+		return slices.ContainsFunc(args, func(a string) bool { return strings.Contains(a, "system(") || strings.Contains(a, "| getline") })
+	case "make", "cmake", "source", ".":
+		return true
+	}
+	if pm, ok := packageManagers[base]; ok {
+		return !slices.Contains(pm.readOnly, firstArg(args))
+	}
+	return interpreters[base] || !(readOnlyPrograms[base] || networkPrograms[base] || destructivePrograms[base] ||
+		mutatingPrograms[base] || knownPrograms[base] || strings.HasPrefix(base, "mkfs"))
+}
+
+// knownPrograms are classified by classifyProgram's own rules and don't run
+// code of their own choosing
+var knownPrograms = toSet("gofmt", "sed", "sort", "yq", "hostname", "mount", "date", "dd", "crontab", "rsync",
+	"psql", "mysql", "sqlite3", "mongo", "mongosh", "redis-cli", "clickhouse-client", "terraform", "tofu", "pulumi")
+
+// writeTargets lists the files a program's arguments say it writes,
+// deletes or moves
+func writeTargets(base string, args []string) []string {
+	files := fileArgs(args)
+	switch base {
+	case "rm", "rmdir", "mv", "touch", "truncate", "tee", "ln", "shred", "unlink", "mkdir":
+		return files
+	case "cp", "install":
+		if len(files) > 0 {
+			return files[len(files)-1:]
+		}
+	case "chmod", "chown", "chgrp":
+		if len(files) > 1 {
+			return files[1:] // First is the mode/owner
+		}
+	case "sed", "perl":
+		if slices.ContainsFunc(args, func(a string) bool { return strings.HasPrefix(a, "-i") || strings.HasPrefix(a, "-pi") }) && len(files) > 1 {
+			return files[1:] // First is the script
+		}
+	case "dd":
+		for _, a := range args {
+			if of, ok := strings.CutPrefix(a, "of="); ok {
+				return []string{of}
+			}
+		}
+	case "tree":
+		if out := optionValue(args, "-o", ""); out != "" {
+			return []string{out}
+		}
+	case "xxd":
+		if out := xxdOutput(args); out != "" {
+			return []string{out}
+		}
+	case "curl", "wget", "sort":
+		if out := optionValue(args, "-o", "--output"); out != "" && base != "wget" {
+			return []string{out}
+		}
+		if out := optionValue(args, "-O", "--output-document"); out != "" && base == "wget" {
+			return []string{out}
+		}
+	}
+	return nil
+}
+
+// xxdOutput returns the output file of xxd [options] [infile [outfile]]
+func xxdOutput(args []string) string {
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case slices.Contains([]string{"-c", "-cols", "-g", "-groupsize", "-l", "-len", "-s", "-seek", "-o", "-n", "-name"}, a):
+			i++ // Value
+		case a == "-":
+			files = append(files, a)
+		case !strings.HasPrefix(a, "-"):
+			files = append(files, a)
+		}
+	}
+	if len(files) == 2 && files[1] != "-" {
+		return files[1]
+	}
+	return ""
+}
+
+// fileArgs returns the non-flag arguments
+func fileArgs(args []string) []string {
+	var files []string
+	for _, a := range args {
+		if a != "" && !strings.HasPrefix(a, "-") {
+			files = append(files, a)
+		}
+	}
+	return files
+}
+
+// skipOptions drops leading flags, and the value of those in withValue
+func skipOptions(args []string, withValue ...string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "--" {
+			return args[1:]
+		}
+		if slices.Contains(withValue, args[0]) && len(args) > 1 {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	return args
+}
+
+// optionValue returns the value of a short (-o v, -ov) or long (--out v,
+// --out=v) option
+func optionValue(args []string, short, long string) string {
+	for i, a := range args {
+		if a == short || long != "" && a == long {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+		if long != "" {
+			if v, ok := strings.CutPrefix(a, long+"="); ok {
+				return v
+			}
+		}
+		if len(short) == 2 && strings.HasPrefix(a, short) && len(a) > 2 && !strings.HasPrefix(a, "--") {
+			return a[2:]
+		}
+	}
+	return ""
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// isRemote reports whether an rsync/scp argument names another host (host:path)
+func isRemote(arg string) bool {
+	host, _, ok := strings.Cut(arg, ":")
+	return ok && host != "" && !strings.Contains(host, "/")
+}
+
+// isDevice reports whether a path is a block device writes would clobber
+func isDevice(path string) bool {
+	for _, prefix := range []string{"/dev/sd", "/dev/hd", "/dev/nvme", "/dev/vd", "/dev/xvd", "/dev/disk", "/dev/mmcblk", "/dev/mapper/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+func appendUnique(list []string, item string) []string {
+	if slices.Contains(list, item) {
+		return list
+	}
+	return append(list, item)
+}
@@ -0,0 +1,228 @@
+package shellrisk
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Network commands name their destination in different ways: URLs (curl,
+// git clone), scp-style host:path (scp, rsync, git@host:repo), a bare host
+// (ssh, ping, dig), a git remote name (git push origin), or nothing at all
+// (npm install talks to its registry). Hosts records what can be told;
+// Remotes the git remotes to resolve; UnknownEgress the commands whose
+// destination can't be told from the command line.
+
+// hostArgPrograms take the host as their first non-option argument
+var hostArgPrograms = map[string][]string{
+	"ssh":         {"-p", "-i", "-l", "-o", "-F", "-J", "-L", "-R", "-D", "-b", "-c", "-E", "-e", "-m", "-O", "-Q", "-S", "-W", "-w"},
+	"mosh":        {"--ssh", "-p"},
+	"ssh-copy-id": {"-i", "-p", "-o"},
+	"sftp":        {"-P", "-i", "-o", "-F", "-J", "-b", "-c", "-B", "-R", "-s"},
+	"telnet":      nil,
+	"nc":          {"-p", "-s", "-w", "-i", "-x", "-X", "-q"},
+	"ncat":        {"-p", "-s", "-w", "-i"},
+	"netcat":      {"-p", "-s", "-w", "-i"},
+	"ping":        {"-c", "-i", "-W", "-w", "-s", "-t", "-I"},
+	"ping6":       {"-c", "-i", "-W", "-w", "-s", "-t", "-I"},
+	"traceroute":  {"-m", "-p", "-q", "-w", "-f", "-s", "-i"},
+	"host":        {"-t", "-c", "-W"},
+	"nslookup":    nil,
+	"whois":       {"-h", "-p"},
+	"dig":         {"-t", "-p", "-b", "-x", "-q", "-c", "-k", "-y"},
+}
+
+// urlValueOptions are curl/wget/httpie options whose value is not the URL
+var urlValueOptions = []string{
+	"-o", "--output", "-O", "--output-document", "-H", "--header", "-d", "--data", "--data-raw", "--data-binary",
+	"--data-urlencode", "-X", "--request", "-u", "--user", "-A", "--user-agent", "-e", "--referer", "-T",
+	"--upload-file", "-F", "--form", "-b", "--cookie", "-c", "--cookie-jar", "-m", "--max-time", "-w",
+	"--write-out", "-x", "--proxy", "-P", "--directory-prefix", "--connect-timeout", "-K", "--config",
+	"-E", "--cert", "--key", "--cacert", "-r", "--range", "-Y", "-y", "--retry", "-t", "--tries", "-a",
+	"--append-output", "--header-file", "-U",
+}
+
+// registryHosts are where package managers download from by default
+var registryHosts = map[string][]string{
+	"npm":   {"registry.npmjs.org"},
+	"pnpm":  {"registry.npmjs.org"},
+	"yarn":  {"registry.yarnpkg.com"},
+	"pip":   {"pypi.org", "files.pythonhosted.org"},
+	"pip3":  {"pypi.org", "files.pythonhosted.org"},
+	"go":    {"proxy.golang.org", "sum.golang.org"},
+	"cargo": {"crates.io", "index.crates.io", "static.crates.io"},
+	"gem":   {"rubygems.org"},
+	"gh":    {"api.github.com", "github.com"},
+}
+
+// egress records where a network program goes
+func (c *classifier) egress(base string, args []string) {
+	switch base {
+	case "curl", "wget", "http", "https", "xh", "aria2c", "lftp", "ftp":
+		var found bool
+		for i, a := range args {
+			if strings.HasPrefix(a, "-") || i > 0 && slices.Contains(urlValueOptions, args[i-1]) {
+				continue
+			}
+			host := HostOf(a)
+			if bare, _, _ := strings.Cut(a, "/"); host == "" && looksLikeHost(bare) {
+				host = bare // curl example.com/path
+			}
+			if host != "" {
+				c.host(host)
+				found = true
+			}
+		}
+		if !found {
+			c.unknown(base)
+		}
+		return
+	case "scp", "rsync", "rclone":
+		var found bool
+		for _, a := range fileArgs(args) {
+			if host := HostOf(a); host != "" {
+				c.host(host)
+				found = true
+			}
+		}
+		if !found && base != "rsync" {
+			c.unknown(base)
+		}
+		return
+	case "dig":
+		for _, a := range args {
+			if server, ok := strings.CutPrefix(a, "@"); ok {
+				c.host(server)
+			}
+		}
+	}
+
+	if valueOpts, ok := hostArgPrograms[base]; ok {
+		if rest := skipOptions(args, valueOpts...); len(rest) > 0 && !strings.HasPrefix(rest[0], "@") {
+			host := rest[0]
+			if _, h, ok := strings.Cut(host, "@"); ok {
+				host = h // user@host
+			}
+			c.host(host)
+			return
+		}
+		if base != "dig" {
+			c.unknown(base)
+		}
+		return
+	}
+	if hosts, ok := registryHosts[base]; ok {
+		for _, h := range hosts {
+			c.host(h)
+		}
+		return
+	}
+	c.unknown(strings.TrimSpace(base + " " + firstArg(args)))
+}
+
+// gitEgress records where a git network subcommand goes
+func (c *classifier) gitEgress(sub string, rest []string) {
+	switch sub {
+	case "clone", "fetch", "pull", "push", "ls-remote":
+	case "remote":
+		if first := firstArg(rest); first == "show" || first == "update" || first == "prune" {
+			c.remote(firstOr(fileArgs(rest[1:]), "origin"))
+		}
+		return
+	case "submodule", "send-email", "request-pull":
+		if sub != "submodule" || firstArg(rest) == "update" || firstArg(rest) == "add" {
+			c.unknown("git " + sub)
+		}
+		return
+	default:
+		return
+	}
+
+	args := skipOptions(rest, "-o", "--origin", "-b", "--branch", "--depth", "-j", "--jobs", "--upload-pack", "-u", "--reference", "-c", "--config", "--repo", "--receive-pack", "--exec")
+	if len(args) == 0 {
+		if sub == "clone" {
+			c.unknown("git clone")
+		} else {
+			c.remote("origin") // The upstream's remote, usually origin
+		}
+		return
+	}
+	if host := HostOf(args[0]); host != "" {
+		c.host(host)
+	} else if sub == "clone" || strings.ContainsAny(args[0], "/.") {
+		c.unknown("git " + sub + " " + args[0]) // Local path, or something unrecognized
+	} else {
+		c.remote(args[0])
+	}
+}
+
+func (c *classifier) host(host string) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host != "" {
+		c.hosts = appendUnique(c.hosts, host)
+	}
+}
+
+func (c *classifier) remote(name string) {
+	c.remotes = appendUnique(c.remotes, name)
+}
+
+func (c *classifier) unknown(what string) {
+	c.unknownEgress = appendUnique(c.unknownEgress, what)
+}
+
+// HostOf returns the host of a URL (scheme://[user@]host[:port]/...) or of
+// an scp-style target ([user@]host:path); "" when s is neither
+func HostOf(s string) string {
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+	target, _, ok := strings.Cut(s, ":")
+	if !ok || target == "" || strings.ContainsAny(target, "/=") {
+		return ""
+	}
+	if _, h, ok := strings.Cut(target, "@"); ok {
+		target = h
+	} else if !looksLikeHost(target) {
+		return "" // A bare word before : (C:, a remote name) is not a host
+	}
+	return strings.ToLower(target)
+}
+
+// looksLikeHost reports whether s is shaped like a domain name or IP address
+func looksLikeHost(s string) bool {
+	if s == "localhost" {
+		return true
+	}
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if l == "" || strings.Trim(l, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-") != "" {
+			return false
+		}
+	}
+	// Files like notes.txt look like hosts too; a host has no file extension
+	// as its last label unless it's numeric (an IP) or a plausible TLD
+	last := labels[len(labels)-1]
+	return strings.Trim(last, "0123456789") == "" || !fileExtensions[strings.ToLower(last)]
+}
+
+// fileExtensions are common file extensions that are not TLDs
+var fileExtensions = toSet(
+	"txt", "json", "yaml", "yml", "go", "py", "js", "ts", "sh", "md", "log", "html", "css", "xml", "csv",
+	"tar", "gz", "tgz", "zip", "mod", "sum", "lock", "toml", "cfg", "conf", "ini", "pem", "key", "crt",
+	"out", "bin", "exe", "c", "h", "rs", "rb", "java", "php", "sql", "env", "tmp", "bak", "png", "jpg", "pdf",
+)
+
+func firstOr(items []string, fallback string) string {
+	if len(items) == 0 {
+		return fallback
+	}
+	return items[0]
+}
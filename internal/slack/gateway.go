@@ -144,14 +144,8 @@ func (c *GatewayClient) Chat(req ChatRequest, onProgress func(ProgressEvent)) (*
 			switch msg.Type {
 			case "progress":
 				if onProgress != nil {
-					var progressData map[string]interface{}
-					if err := json.Unmarshal(msg.Data, &progressData); err == nil {
-						event := ProgressEvent{
-							Type:    getString(progressData, "type"),
-							Step:    getInt(progressData, "step"),
-							Message: getString(progressData, "message"),
-							Data:    progressData["data"],
-						}
+					var event ProgressEvent
+					if err := json.Unmarshal(msg.Data, &event); err == nil {
 						onProgress(event)
 					}
 				}
@@ -230,6 +224,133 @@ func (c *GatewayClient) ListSessions() ([]map[string]interface{}, error) {
 	}
 }
 
+// Rate rates the last answer in a session ("good" or "bad") and returns the
+// gateway's confirmation message
+func (c *GatewayClient) Rate(sessionID, rating, comment string) (string, error) {
+	msgID := c.NextMsgID()
+	responseChan := make(chan WSMessage, 1)
+
+	c.callbackMu.Lock()
+	c.callbacks[msgID] = responseChan
+	c.callbackMu.Unlock()
+
+	defer func() {
+		c.callbackMu.Lock()
+		delete(c.callbacks, msgID)
+		c.callbackMu.Unlock()
+	}()
+
+	reqData, _ := json.Marshal(map[string]string{
+		"session_id": sessionID,
+		"action":     "feedback",
+		"rating":     rating,
+		"comment":    comment,
+	})
+	err := c.Send(WSMessage{
+		Type: "session",
+		ID:   msgID,
+		Data: reqData,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case msg := <-responseChan:
+		var data struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return "", err
+		}
+		if msg.Type == "error" {
+			return "", fmt.Errorf("%s", data.Error)
+		}
+		return data.Message, nil
+
+	case <-time.After(10 * time.Second):
+		return "", fmt.Errorf("timeout")
+	}
+}
+
+// SessionUsage returns the usage recorded for a session
+func (c *GatewayClient) SessionUsage(sessionID string) (*SessionUsage, error) {
+	msg, err := c.call("session", map[string]string{"session_id": sessionID, "action": "usage"})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Usage SessionUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return nil, err
+	}
+	return &data.Usage, nil
+}
+
+// DeleteSession deletes a session from the gateway
+func (c *GatewayClient) DeleteSession(sessionID string) error {
+	msg, err := c.call("session", map[string]string{"session_id": sessionID, "action": "delete"})
+	if err != nil {
+		return err
+	}
+	var data struct {
+		Deleted bool `json:"deleted"`
+	}
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return err
+	}
+	if !data.Deleted {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	return nil
+}
+
+// SessionUsage is a session's estimated model usage
+type SessionUsage struct {
+	Calls        int64   `json:"calls"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// call sends a request and waits for its reply; an "error" reply is returned
+// as an error
+func (c *GatewayClient) call(msgType string, data interface{}) (WSMessage, error) {
+	msgID := c.NextMsgID()
+	responseChan := make(chan WSMessage, 1)
+
+	c.callbackMu.Lock()
+	c.callbacks[msgID] = responseChan
+	c.callbackMu.Unlock()
+
+	defer func() {
+		c.callbackMu.Lock()
+		delete(c.callbacks, msgID)
+		c.callbackMu.Unlock()
+	}()
+
+	reqData, _ := json.Marshal(data)
+	if err := c.Send(WSMessage{Type: msgType, ID: msgID, Data: reqData}); err != nil {
+		return WSMessage{}, err
+	}
+
+	select {
+	case msg := <-responseChan:
+		if msg.Type == "error" {
+			var errData struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(msg.Data, &errData)
+			return msg, fmt.Errorf("%s", errData.Error)
+		}
+		return msg, nil
+	case <-time.After(10 * time.Second):
+		return WSMessage{}, fmt.Errorf("timeout")
+	}
+}
+
 // Reconnect attempts to reconnect to the gateway
 func (c *GatewayClient) Reconnect() error {
 	c.mu.Lock()
@@ -259,21 +380,6 @@ func (c *GatewayClient) Reconnect() error {
 	return nil
 }
 
-// Helper functions
-func getString(m map[string]interface{}, key string) string {
-	if v, ok := m[key].(string); ok {
-		return v
-	}
-	return ""
-}
-
-func getInt(m map[string]interface{}, key string) int {
-	if v, ok := m[key].(float64); ok {
-		return int(v)
-	}
-	return 0
-}
-
 // HealthCheck checks if the gateway is healthy
 func (c *GatewayClient) HealthCheck() error {
 	msgID := c.NextMsgID()
@@ -0,0 +1,208 @@
+package slack
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// The App Home tab lists the sessions of the threads a user started, with
+// buttons to resume one in a new DM thread, see its cost, or delete it.
+
+// Action IDs of the App Home buttons (the value is the session ID)
+const (
+	homeResumeAction = "home_resume"
+	homeCostAction   = "home_cost"
+	homeDeleteAction = "home_delete"
+)
+
+// homeMaxSessions bounds the sessions listed (Slack allows 100 blocks)
+const homeMaxSessions = 25
+
+// homeSession is a session listed in the App Home
+type homeSession struct {
+	SessionID    string
+	Title        string
+	WorkingDir   string
+	MessageCount int
+	LastUsedAt   time.Time
+}
+
+// userSessions returns the sessions of a user's threads that the gateway
+// still has, most recently used first
+func (b *Bot) userSessions(userID string) ([]homeSession, error) {
+	gatewaySessions, err := b.gateway.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]map[string]interface{}, len(gatewaySessions))
+	for _, gs := range gatewaySessions {
+		if id, ok := gs["session_id"].(string); ok {
+			known[id] = gs
+		}
+	}
+
+	b.sessionsMu.RLock()
+	byID := make(map[string]homeSession)
+	for _, s := range b.sessions {
+		gs, ok := known[s.SessionID]
+		if s.UserID != userID || s.SessionID == "" || !ok {
+			continue
+		}
+		if prev, seen := byID[s.SessionID]; seen && prev.LastUsedAt.After(s.LastUsedAt) {
+			continue // Resumed in several threads: keep the latest
+		}
+		hs := homeSession{SessionID: s.SessionID, WorkingDir: s.WorkingDir, LastUsedAt: s.LastUsedAt}
+		hs.Title, _ = gs["title"].(string)
+		if n, ok := gs["message_count"].(float64); ok {
+			hs.MessageCount = int(n)
+		}
+		byID[s.SessionID] = hs
+	}
+	b.sessionsMu.RUnlock()
+
+	sessions := make([]homeSession, 0, len(byID))
+	for _, hs := range byID {
+		sessions = append(sessions, hs)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	if len(sessions) > homeMaxSessions {
+		sessions = sessions[:homeMaxSessions]
+	}
+	return sessions, nil
+}
+
+// publishHome renders a user's App Home tab
+func (b *Bot) publishHome(userID string) {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "🤖 Your Zen Claw sessions", false, false)),
+	}
+
+	sessions, err := b.userSessions(userID)
+	switch {
+	case err != nil:
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("❌ Couldn't reach the gateway: %s", err.Error()), false, false), nil, nil))
+	case len(sessions) == 0:
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", "No sessions yet. Mention me in a channel or DM me a task to start one.", false, false), nil, nil))
+	}
+
+	for _, hs := range sessions {
+		title := hs.Title
+		if title == "" {
+			title = hs.SessionID
+		}
+		text := fmt.Sprintf("*%s*\n`%s` · %d msgs · `%s` · last used %s",
+			title, hs.SessionID, hs.MessageCount, hs.WorkingDir, hs.LastUsedAt.Format("2006-01-02 15:04"))
+
+		resume := slack.NewButtonBlockElement(homeResumeAction, hs.SessionID,
+			slack.NewTextBlockObject("plain_text", "▶️ Resume", true, false))
+		resume.Style = slack.StylePrimary
+		cost := slack.NewButtonBlockElement(homeCostAction, hs.SessionID,
+			slack.NewTextBlockObject("plain_text", "💰 Cost", true, false))
+		del := slack.NewButtonBlockElement(homeDeleteAction, hs.SessionID,
+			slack.NewTextBlockObject("plain_text", "🗑️ Delete", true, false))
+		del.Style = slack.StyleDanger
+		del.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject("plain_text", "Delete session?", false, false),
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*%s* and its history are deleted from the gateway.", title), false, false),
+			slack.NewTextBlockObject("plain_text", "Delete", false, false),
+			slack.NewTextBlockObject("plain_text", "Cancel", false, false),
+		)
+
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("", resume, cost, del),
+		)
+	}
+
+	if _, err := b.client.PublishView(userID, slack.HomeTabViewRequest{Type: slack.VTHomeTab, Blocks: slack.Blocks{BlockSet: blocks}}, ""); err != nil {
+		log.Printf("[Slack] Failed to publish App Home for %s: %v", userID, err)
+	}
+}
+
+// resumeFromHome continues a session in a new thread of the user's DM with
+// the bot, with the settings of the thread it was last used in
+func (b *Bot) resumeFromHome(userID, sessionID string) {
+	channel, _, _, err := b.client.OpenConversation(&slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		log.Printf("[Slack] Failed to open DM with %s: %v", userID, err)
+		return
+	}
+	_, ts, err := b.client.PostMessage(channel.ID, slack.MsgOptionText(
+		fmt.Sprintf("▶️ Resumed session `%s`. Reply in this thread to continue.", sessionID), false))
+	if err != nil {
+		log.Printf("[Slack] Failed to resume session %s: %v", sessionID, err)
+		return
+	}
+
+	b.sessionsMu.Lock()
+	resumed := &Session{
+		ThreadTS:   ts,
+		ChannelID:  channel.ID,
+		UserID:     userID,
+		SessionID:  sessionID,
+		WorkingDir: b.config.DefaultDir,
+		Provider:   b.config.Provider,
+		Model:      b.config.Model,
+		Language:   b.config.Language,
+		CreatedAt:  time.Now(),
+		LastUsedAt: time.Now(),
+	}
+	var latest time.Time
+	for _, s := range b.sessions {
+		if s.SessionID == sessionID && s.LastUsedAt.After(latest) {
+			latest = s.LastUsedAt
+			resumed.WorkingDir, resumed.Provider, resumed.Model, resumed.Language = s.WorkingDir, s.Provider, s.Model, s.Language
+		}
+	}
+	b.sessions[ts] = resumed
+	b.sessionsMu.Unlock()
+	b.saveSessions()
+	b.publishHome(userID)
+}
+
+// showCostFromHome opens a modal with a session's estimated usage
+func (b *Bot) showCostFromHome(triggerID, sessionID string) {
+	var text string
+	usage, err := b.gateway.SessionUsage(sessionID)
+	if err != nil {
+		text = fmt.Sprintf("❌ Couldn't get the usage: %s", err.Error())
+	} else {
+		text = fmt.Sprintf("*Session:* `%s`\n*Model calls:* %d\n*Tokens:* %d in / %d out\n*Estimated cost:* $%.4f",
+			sessionID, usage.Calls, usage.InputTokens, usage.OutputTokens, usage.CostUSD)
+	}
+
+	_, err = b.client.OpenView(triggerID, slack.ModalViewRequest{
+		Type:   slack.VTModal,
+		Title:  slack.NewTextBlockObject("plain_text", "Session cost", false, false),
+		Close:  slack.NewTextBlockObject("plain_text", "Close", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)}},
+	})
+	if err != nil {
+		log.Printf("[Slack] Failed to open cost view: %v", err)
+	}
+}
+
+// deleteFromHome deletes a session from the gateway and forgets the threads
+// that used it
+func (b *Bot) deleteFromHome(userID, sessionID string) {
+	if err := b.gateway.DeleteSession(sessionID); err != nil {
+		log.Printf("[Slack] Failed to delete session %s: %v", sessionID, err)
+	}
+
+	b.sessionsMu.Lock()
+	for ts, s := range b.sessions {
+		if s.SessionID == sessionID {
+			delete(b.sessions, ts)
+		}
+	}
+	b.sessionsMu.Unlock()
+	b.saveSessions()
+	b.publishHome(userID)
+}
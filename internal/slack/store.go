@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Which zen-claw session each thread talks to, and the thread's settings,
+// are kept in a state file so a restarted bot picks its threads up where
+// they left off (the sessions themselves live in the gateway).
+
+// threadTTL is how long an unused thread is remembered
+const threadTTL = 30 * 24 * time.Hour
+
+// DefaultStatePath returns the default thread state file
+func DefaultStatePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "slack_threads.json"
+	}
+	return filepath.Join(home, ".zen", "zen-claw", "data", "slack_threads.json")
+}
+
+// threadState is the state file's content
+type threadState struct {
+	Threads   map[string]*Session `json:"threads"` // thread_ts -> session
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// loadSessions reads the thread sessions saved by an earlier run, dropping
+// threads unused for longer than threadTTL. A missing file is no sessions.
+func loadSessions(path string) (map[string]*Session, error) {
+	sessions := make(map[string]*Session)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return sessions, err
+	}
+
+	var state threadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return sessions, err
+	}
+	cutoff := time.Now().Add(-threadTTL)
+	for ts, session := range state.Threads {
+		if session == nil || session.LastUsedAt.Before(cutoff) {
+			continue
+		}
+		session.ThreadTS = ts
+		sessions[ts] = session
+	}
+	return sessions, nil
+}
+
+// saveSessions writes the thread sessions to the state file. The file is
+// replaced atomically so an interrupted write never loses the previous state.
+func (b *Bot) saveSessions() {
+	if b.config.StatePath == "" {
+		return
+	}
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+
+	b.sessionsMu.RLock()
+	data, err := json.MarshalIndent(threadState{Threads: b.sessions, UpdatedAt: time.Now()}, "", "  ")
+	b.sessionsMu.RUnlock()
+	if err != nil {
+		log.Printf("[Slack] Failed to encode thread sessions: %v", err)
+		return
+	}
+
+	path := b.config.StatePath
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("[Slack] Failed to save thread sessions: %v", err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("[Slack] Failed to save thread sessions: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("[Slack] Failed to save thread sessions: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package slack
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slack_threads.json")
+	b := &Bot{config: Config{StatePath: path, DefaultDir: "/src"}, sessions: make(map[string]*Session)}
+
+	b.updateSession("C1", "1700000000.000100", func(s *Session) { s.SessionID = "sess-1"; s.Provider = "qwen" })
+	b.getOrCreateSession("C2", "1700000000.000200")
+	b.sessionsMu.Lock()
+	b.sessions["1700000000.000200"].LastUsedAt = time.Now().Add(-2 * threadTTL)
+	b.sessionsMu.Unlock()
+	b.saveSessions()
+
+	sessions, err := loadSessions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sessions["1700000000.000100"]
+	if len(sessions) != 1 || s == nil {
+		t.Fatalf("sessions = %+v, want only the recent thread", sessions)
+	}
+	if s.ThreadTS != "1700000000.000100" || s.ChannelID != "C1" || s.SessionID != "sess-1" || s.Provider != "qwen" || s.WorkingDir != "/src" {
+		t.Errorf("restored session = %+v", s)
+	}
+
+	if sessions, err := loadSessions(filepath.Join(t.TempDir(), "missing.json")); err != nil || len(sessions) != 0 {
+		t.Errorf("missing file: %v, %v", sessions, err)
+	}
+}
@@ -0,0 +1,210 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// jiraFields are the issue fields read for a ticket
+const jiraFields = "summary,description,status,assignee,labels"
+
+// jqlLike matches queries that are already JQL rather than free text
+var jqlLike = regexp.MustCompile(`(?i)(=|~|\bORDER BY\b|\bIN\s*\(|\bIS\s+(NOT\s+)?EMPTY\b)`)
+
+// Jira is a client of the Jira REST API (v2: plain-text descriptions and
+// comments; Cloud, Server and Data Center)
+type Jira struct {
+	URL   string // Site, e.g. https://acme.atlassian.net
+	Email string // Jira Cloud account; with it Token is an API token (basic auth), without it a bearer personal access token
+	Token string
+	HTTP  *http.Client
+}
+
+// NewJira creates a client for a Jira site
+func NewJira(siteURL, email, token string) *Jira {
+	return &Jira{URL: strings.TrimSuffix(siteURL, "/"), Email: email, Token: token, HTTP: httpClient}
+}
+
+// jiraIssue is the part of a Jira issue the tools use
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+		Comment struct {
+			Comments []struct {
+				Body    string `json:"body"`
+				Created string `json:"created"`
+				Author  struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+func (j *Jira) ticket(issue jiraIssue) Ticket {
+	t := Ticket{
+		Key:         issue.Key,
+		Title:       issue.Fields.Summary,
+		Description: issue.Fields.Description,
+		Status:      issue.Fields.Status.Name,
+		Labels:      issue.Fields.Labels,
+		URL:         j.URL + "/browse/" + issue.Key,
+	}
+	if issue.Fields.Assignee != nil {
+		t.Assignee = issue.Fields.Assignee.DisplayName
+	}
+	for _, c := range issue.Fields.Comment.Comments {
+		t.Comments = append(t.Comments, Comment{Author: c.Author.DisplayName, Body: c.Body, Created: c.Created})
+	}
+	return t
+}
+
+// Search runs a JQL query; free text becomes a text search, most recently
+// updated first
+func (j *Jira) Search(ctx context.Context, query string, limit int) ([]Ticket, error) {
+	jql := strings.TrimSpace(query)
+	if !jqlLike.MatchString(jql) {
+		jql = fmt.Sprintf("text ~ %s ORDER BY updated DESC", strconv.Quote(jql))
+	}
+	params := url.Values{"jql": {jql}, "maxResults": {strconv.Itoa(limit)}, "fields": {jiraFields}}
+	var result struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	// Jira Cloud serves search at /search/jql; Server and Data Center only at /search
+	err := j.do(ctx, http.MethodGet, "/rest/api/2/search/jql?"+params.Encode(), nil, &result)
+	if types.CodeOf(err) == types.ErrNotFound {
+		err = j.do(ctx, http.MethodGet, "/rest/api/2/search?"+params.Encode(), nil, &result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		tickets = append(tickets, j.ticket(issue))
+	}
+	return tickets, nil
+}
+
+// Get returns an issue with its comments
+func (j *Jira) Get(ctx context.Context, key string) (*Ticket, error) {
+	if err := requireKey(key); err != nil {
+		return nil, err
+	}
+	var issue jiraIssue
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields="+jiraFields+",comment", nil, &issue); err != nil {
+		return nil, err
+	}
+	t := j.ticket(issue)
+	return &t, nil
+}
+
+// Comment adds a comment to an issue
+func (j *Jira) Comment(ctx context.Context, key, body string) error {
+	if err := requireKey(key); err != nil {
+		return err
+	}
+	return j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, nil)
+}
+
+// Transition moves an issue through the workflow transition leading to
+// status (or named status)
+func (j *Jira) Transition(ctx context.Context, key, status string) (string, error) {
+	if err := requireKey(key); err != nil {
+		return "", err
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	var result struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return "", err
+	}
+	targets := make([]string, len(result.Transitions))
+	for i, tr := range result.Transitions {
+		targets[i] = tr.To.Name
+	}
+	i, err := matchStatus(status, targets)
+	if err != nil {
+		// Workflows may name the transition rather than its target ("Start review")
+		names := make([]string, len(result.Transitions))
+		for k, tr := range result.Transitions {
+			names[k] = tr.Name
+		}
+		if i, _ = matchStatus(status, names); i < 0 {
+			return "", err
+		}
+	}
+	tr := result.Transitions[i]
+	if err := j.do(ctx, http.MethodPost, path, map[string]interface{}{"transition": map[string]string{"id": tr.ID}}, nil); err != nil {
+		return "", err
+	}
+	return tr.To.Name, nil
+}
+
+// do makes one API request, sending in and decoding the response into out
+// as JSON
+func (j *Jira) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.URL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if j.Email != "" {
+		req.SetBasicAuth(j.Email, j.Token)
+	} else if j.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+
+	resp, err := j.HTTP.Do(req)
+	if err != nil {
+		return types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError("Jira", resp, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Jira response: %w", err)
+	}
+	return nil
+}
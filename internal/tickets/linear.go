@@ -0,0 +1,255 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// DefaultLinearAPI is Linear's GraphQL endpoint
+const DefaultLinearAPI = "https://api.linear.app/graphql"
+
+// linearIssueFields are the issue fields read for a ticket
+const linearIssueFields = `identifier title description url state { name } assignee { name } labels { nodes { name } }`
+
+// Linear is a client of the Linear GraphQL API
+type Linear struct {
+	APIURL string // Default DefaultLinearAPI
+	Token  string // Personal API key (lin_api_...) or OAuth token
+	HTTP   *http.Client
+}
+
+// NewLinear creates a client for a Linear workspace
+func NewLinear(token string) *Linear {
+	return &Linear{APIURL: DefaultLinearAPI, Token: token, HTTP: httpClient}
+}
+
+// linearIssue is the part of a Linear issue the tools use
+type linearIssue struct {
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	State       struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Comments struct {
+		Nodes []struct {
+			Body      string `json:"body"`
+			CreatedAt string `json:"createdAt"`
+			User      *struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"nodes"`
+	} `json:"comments"`
+}
+
+func (i linearIssue) ticket() Ticket {
+	t := Ticket{
+		Key:         i.Identifier,
+		Title:       i.Title,
+		Description: i.Description,
+		Status:      i.State.Name,
+		URL:         i.URL,
+	}
+	if i.Assignee != nil {
+		t.Assignee = i.Assignee.Name
+	}
+	for _, l := range i.Labels.Nodes {
+		t.Labels = append(t.Labels, l.Name)
+	}
+	// Linear lists comments newest first
+	for k := len(i.Comments.Nodes) - 1; k >= 0; k-- {
+		c := i.Comments.Nodes[k]
+		comment := Comment{Body: c.Body, Created: c.CreatedAt}
+		if c.User != nil {
+			comment.Author = c.User.Name
+		}
+		t.Comments = append(t.Comments, comment)
+	}
+	return t
+}
+
+// Search finds issues by text
+func (l *Linear) Search(ctx context.Context, query string, limit int) ([]Ticket, error) {
+	var result struct {
+		SearchIssues struct {
+			Nodes []linearIssue `json:"nodes"`
+		} `json:"searchIssues"`
+	}
+	q := `query($term: String!, $first: Int) { searchIssues(term: $term, first: $first) { nodes { ` + linearIssueFields + ` } } }`
+	if err := l.do(ctx, q, map[string]interface{}{"term": query, "first": limit}, &result); err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(result.SearchIssues.Nodes))
+	for _, issue := range result.SearchIssues.Nodes {
+		tickets = append(tickets, issue.ticket())
+	}
+	return tickets, nil
+}
+
+// Get returns an issue (by identifier, e.g. LIN-423) with its comments
+func (l *Linear) Get(ctx context.Context, key string) (*Ticket, error) {
+	if err := requireKey(key); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Issue *linearIssue `json:"issue"`
+	}
+	q := `query($id: String!) { issue(id: $id) { ` + linearIssueFields + ` comments(first: 50) { nodes { body createdAt user { name } } } } }`
+	if err := l.do(ctx, q, map[string]interface{}{"id": key}, &result); err != nil {
+		return nil, err
+	}
+	if result.Issue == nil {
+		return nil, types.Errorf(types.ErrNotFound, "Linear: issue %s not found", key)
+	}
+	t := result.Issue.ticket()
+	return &t, nil
+}
+
+// Comment adds a comment to an issue
+func (l *Linear) Comment(ctx context.Context, key, body string) error {
+	if err := requireKey(key); err != nil {
+		return err
+	}
+	var result struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	q := `mutation($id: String!, $body: String!) { commentCreate(input: {issueId: $id, body: $body}) { success } }`
+	if err := l.do(ctx, q, map[string]interface{}{"id": key, "body": body}, &result); err != nil {
+		return err
+	}
+	if !result.CommentCreate.Success {
+		return types.Errorf(types.ErrInternal, "Linear did not create the comment on %s", key)
+	}
+	return nil
+}
+
+// Transition moves an issue to one of its team's workflow states
+func (l *Linear) Transition(ctx context.Context, key, status string) (string, error) {
+	if err := requireKey(key); err != nil {
+		return "", err
+	}
+	var issue struct {
+		Issue *struct {
+			ID   string `json:"id"`
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	q := `query($id: String!) { issue(id: $id) { id team { states { nodes { id name } } } } }`
+	if err := l.do(ctx, q, map[string]interface{}{"id": key}, &issue); err != nil {
+		return "", err
+	}
+	if issue.Issue == nil {
+		return "", types.Errorf(types.ErrNotFound, "Linear: issue %s not found", key)
+	}
+	states := issue.Issue.Team.States.Nodes
+	names := make([]string, len(states))
+	for i, s := range states {
+		names[i] = s.Name
+	}
+	i, err := matchStatus(status, names)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		IssueUpdate struct {
+			Success bool `json:"success"`
+		} `json:"issueUpdate"`
+	}
+	m := `mutation($id: String!, $state: String!) { issueUpdate(id: $id, input: {stateId: $state}) { success } }`
+	if err := l.do(ctx, m, map[string]interface{}{"id": issue.Issue.ID, "state": states[i].ID}, &result); err != nil {
+		return "", err
+	}
+	if !result.IssueUpdate.Success {
+		return "", types.Errorf(types.ErrInternal, "Linear did not update %s", key)
+	}
+	return states[i].Name, nil
+}
+
+// do runs one GraphQL operation, decoding its data into out
+func (l *Linear) do(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.APIURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token := l.Token
+	if !strings.HasPrefix(token, "lin_api_") && token != "" {
+		// OAuth tokens are bearer tokens; personal API keys are sent as is
+		token = "Bearer " + token
+	}
+	req.Header.Set("Authorization", token)
+
+	resp, err := l.HTTP.Do(req)
+	if err != nil {
+		return types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode != http.StatusBadRequest {
+			return statusError("Linear", resp, strings.TrimSpace(string(body[:min(len(body), 512)])))
+		}
+		// GraphQL errors come back as 400 with an errors list
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse Linear response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		e := result.Errors[0]
+		switch {
+		case strings.Contains(strings.ToLower(e.Message), "not found"):
+			return types.Errorf(types.ErrNotFound, "Linear: %s", e.Message)
+		case e.Extensions.Code == "AUTHENTICATION_ERROR" || e.Extensions.Code == "FORBIDDEN":
+			return types.Errorf(types.ErrPermissionDenied, "Linear: %s", e.Message)
+		case e.Extensions.Code == "RATELIMITED":
+			return types.Errorf(types.ErrRateLimited, "Linear rate limit exceeded")
+		}
+		return types.Errorf(types.ErrInvalidArgument, "Linear: %s", e.Message)
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("failed to parse Linear response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package tickets talks to issue trackers for the agent's ticket tools: it
+// searches and reads tickets on Jira and Linear, comments on them and moves
+// them between workflow states.
+package tickets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// Ticket is a Jira issue or Linear issue
+type Ticket struct {
+	Key         string    `json:"key"` // e.g. OPS-12, LIN-423
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	Assignee    string    `json:"assignee,omitempty"`
+	Labels      []string  `json:"labels,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Comments    []Comment `json:"comments,omitempty"` // Set by Get, oldest first
+}
+
+// Comment is a comment on a ticket
+type Comment struct {
+	Author  string `json:"author"`
+	Body    string `json:"body"`
+	Created string `json:"created,omitempty"`
+}
+
+// Tracker is an issue tracker workspace
+type Tracker interface {
+	// Search finds tickets matching a query: free text, or the tracker's
+	// own query language (JQL on Jira)
+	Search(ctx context.Context, query string, limit int) ([]Ticket, error)
+	// Get returns a ticket with its description and comments
+	Get(ctx context.Context, key string) (*Ticket, error)
+	// Comment adds a comment to a ticket
+	Comment(ctx context.Context, key, body string) error
+	// Transition moves a ticket to a status (matched case-insensitively)
+	// and returns the status it ended in
+	Transition(ctx context.Context, key, status string) (string, error)
+}
+
+// Workspace is a named tracker and the ticket key prefixes it owns
+type Workspace struct {
+	Name     string
+	Kind     string // jira or linear
+	Projects []string
+	Tracker  Tracker
+}
+
+// Workspaces routes ticket keys to the configured workspaces
+type Workspaces struct {
+	byName      map[string]*Workspace
+	defaultName string
+}
+
+// NewWorkspaces creates the routing for workspaces; defaultName is used for
+// keys no workspace claims ("" = the only workspace, if there is one)
+func NewWorkspaces(defaultName string, workspaces ...*Workspace) *Workspaces {
+	w := &Workspaces{byName: make(map[string]*Workspace), defaultName: defaultName}
+	for _, ws := range workspaces {
+		w.byName[ws.Name] = ws
+	}
+	return w
+}
+
+// Names returns the workspace names, sorted
+func (w *Workspaces) Names() []string {
+	names := make([]string, 0, len(w.byName))
+	for name := range w.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve picks the workspace for a call: the named one, else the one
+// whose projects include the key's prefix, else the default
+func (w *Workspaces) Resolve(name, key string) (*Workspace, error) {
+	if len(w.byName) == 0 {
+		return nil, types.Errorf(types.ErrUnavailable, "no ticket workspaces configured (tickets.workspaces in the gateway config)")
+	}
+	if name != "" {
+		ws, ok := w.byName[name]
+		if !ok {
+			return nil, types.Errorf(types.ErrNotFound, "unknown ticket workspace %q (configured: %s)", name, strings.Join(w.Names(), ", "))
+		}
+		return ws, nil
+	}
+	if prefix, _, ok := strings.Cut(key, "-"); ok {
+		for _, ws := range w.byName {
+			for _, project := range ws.Projects {
+				if strings.EqualFold(project, prefix) {
+					return ws, nil
+				}
+			}
+		}
+	}
+	if ws, ok := w.byName[w.defaultName]; ok {
+		return ws, nil
+	}
+	if len(w.byName) == 1 {
+		for _, ws := range w.byName {
+			return ws, nil
+		}
+	}
+	return nil, types.Errorf(types.ErrInvalidArgument, "several ticket workspaces are configured (%s): pass workspace", strings.Join(w.Names(), ", "))
+}
+
+// httpClient is shared by the trackers
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// statusError maps an HTTP error status of a tracker API to an error code
+func statusError(tracker string, resp *http.Response, detail string) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return types.Errorf(types.ErrNotFound, "%s: not found", tracker)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return types.Errorf(types.ErrPermissionDenied, "%s: %s: %s", tracker, resp.Status, detail)
+	case http.StatusTooManyRequests:
+		return types.Errorf(types.ErrRateLimited, "%s rate limit exceeded (%s)", tracker, resp.Status)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return types.Errorf(types.ErrInvalidArgument, "%s: %s", tracker, detail)
+	}
+	return types.Errorf(types.ErrUnavailable, "%s: %s: %s", tracker, resp.Status, detail)
+}
+
+// matchStatus finds status among names, case-insensitively
+func matchStatus(status string, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(strings.TrimSpace(status), name) {
+			return i, nil
+		}
+	}
+	return -1, types.Errorf(types.ErrInvalidArgument, "no transition to %q (available: %s)", status, strings.Join(names, ", "))
+}
+
+// requireKey rejects empty ticket keys
+func requireKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("ticket key is required")
+	}
+	return nil
+}
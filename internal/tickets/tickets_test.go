@@ -0,0 +1,154 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestResolve(t *testing.T) {
+	jira := &Workspace{Name: "acme-jira", Kind: "jira", Projects: []string{"OPS"}}
+	linear := &Workspace{Name: "acme", Kind: "linear", Projects: []string{"lin"}}
+	w := NewWorkspaces("acme", jira, linear)
+
+	tests := []struct{ name, key, want string }{
+		{"", "OPS-12", "acme-jira"},
+		{"", "LIN-423", "acme"},
+		{"", "ENG-1", "acme"},
+		{"acme-jira", "LIN-423", "acme-jira"},
+	}
+	for _, tt := range tests {
+		ws, err := w.Resolve(tt.name, tt.key)
+		if err != nil || ws.Name != tt.want {
+			t.Errorf("Resolve(%q, %q) = %v, %v; want %s", tt.name, tt.key, ws, err, tt.want)
+		}
+	}
+	if _, err := w.Resolve("nope", ""); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("unknown workspace: %v", err)
+	}
+	if _, err := NewWorkspaces("", jira, linear).Resolve("", "ENG-1"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("ambiguous workspace: %v", err)
+	}
+	if ws, err := NewWorkspaces("", jira).Resolve("", ""); err != nil || ws != jira {
+		t.Errorf("single workspace = %v, %v", ws, err)
+	}
+	if _, err := NewWorkspaces("").Resolve("", "OPS-1"); types.CodeOf(err) != types.ErrUnavailable {
+		t.Errorf("no workspaces: %v", err)
+	}
+}
+
+func TestJira(t *testing.T) {
+	var jql, transitioned, comment string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me@acme.com" || pass != "t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/api/2/search":
+			// Server and Data Center: no /search/jql
+			jql = r.URL.Query().Get("jql")
+			w.Write([]byte(`{"issues": [{"key": "OPS-12", "fields": {"summary": "Disk full", "status": {"name": "To Do"}}}]}`))
+		case "GET /rest/api/2/issue/OPS-12":
+			w.Write([]byte(`{"key": "OPS-12", "fields": {"summary": "Disk full", "description": "AC: alert at 80%", "status": {"name": "To Do"},
+				"assignee": {"displayName": "Ana"}, "comment": {"comments": [{"body": "seen on db-2", "author": {"displayName": "Bo"}}]}}}`))
+		case "GET /rest/api/2/issue/OPS-12/transitions":
+			w.Write([]byte(`{"transitions": [{"id": "11", "name": "Start", "to": {"name": "In Progress"}}, {"id": "21", "name": "Send to review", "to": {"name": "In Review"}}]}`))
+		case "POST /rest/api/2/issue/OPS-12/transitions":
+			var body struct{ Transition struct{ ID string } }
+			json.NewDecoder(r.Body).Decode(&body)
+			transitioned = body.Transition.ID
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /rest/api/2/issue/OPS-12/comment":
+			var body struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&body)
+			comment = body.Body
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	j := NewJira(srv.URL+"/", "me@acme.com", "t0ken")
+	ctx := context.Background()
+	found, err := j.Search(ctx, "disk full", 5)
+	if err != nil || len(found) != 1 || found[0].Key != "OPS-12" || jql != `text ~ "disk full" ORDER BY updated DESC` {
+		t.Fatalf("Search = %+v, %v (jql %q)", found, err, jql)
+	}
+	if _, err := j.Search(ctx, "project = OPS", 5); err != nil || jql != "project = OPS" {
+		t.Errorf("JQL search: %v (jql %q)", err, jql)
+	}
+
+	ticket, err := j.Get(ctx, "OPS-12")
+	if err != nil || ticket.Description != "AC: alert at 80%" || ticket.Assignee != "Ana" || len(ticket.Comments) != 1 || ticket.URL != srv.URL+"/browse/OPS-12" {
+		t.Errorf("Get = %+v, %v", ticket, err)
+	}
+	if err := j.Comment(ctx, "OPS-12", "Fixed in #42"); err != nil || comment != "Fixed in #42" {
+		t.Errorf("Comment: %v (%q)", err, comment)
+	}
+
+	if status, err := j.Transition(ctx, "OPS-12", "in review"); err != nil || status != "In Review" || transitioned != "21" {
+		t.Errorf("Transition = %q, %v (id %s)", status, err, transitioned)
+	}
+	if status, err := j.Transition(ctx, "OPS-12", "start"); err != nil || status != "In Progress" {
+		t.Errorf("Transition by name = %q, %v", status, err)
+	}
+	if _, err := j.Transition(ctx, "OPS-12", "Done"); err == nil || !strings.Contains(err.Error(), "available: In Progress, In Review") {
+		t.Errorf("unknown status: %v", err)
+	}
+	if _, err := j.Get(ctx, "OPS-99"); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("missing issue: %v", err)
+	}
+}
+
+func TestLinear(t *testing.T) {
+	var stateID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case strings.Contains(req.Query, "issueUpdate"):
+			stateID, _ = req.Variables["state"].(string)
+			w.Write([]byte(`{"data": {"issueUpdate": {"success": true}}}`))
+		case strings.Contains(req.Query, "team { states"):
+			w.Write([]byte(`{"data": {"issue": {"id": "uuid-1", "team": {"states": {"nodes": [{"id": "s1", "name": "Todo"}, {"id": "s2", "name": "In Review"}]}}}}}`))
+		case req.Variables["id"] == "LIN-404":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": [{"message": "Entity not found: Issue"}]}`))
+		case strings.Contains(req.Query, "issue(id"):
+			w.Write([]byte(`{"data": {"issue": {"identifier": "LIN-423", "title": "Add export", "description": "- [ ] CSV", "state": {"name": "Todo"},
+				"labels": {"nodes": [{"name": "feature"}]}, "comments": {"nodes": [{"body": "second", "user": {"name": "Bo"}}, {"body": "first", "user": null}]}}}}`))
+		default:
+			w.Write([]byte(`{"errors": [{"message": "unexpected query"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	l := NewLinear("lin_api_k3y")
+	l.APIURL = srv.URL
+	ctx := context.Background()
+	ticket, err := l.Get(ctx, "LIN-423")
+	if err != nil || ticket.Title != "Add export" || ticket.Labels[0] != "feature" || ticket.Comments[0].Body != "first" || ticket.Comments[1].Author != "Bo" {
+		t.Fatalf("Get = %+v, %v", ticket, err)
+	}
+	if status, err := l.Transition(ctx, "LIN-423", "in review"); err != nil || status != "In Review" || stateID != "s2" {
+		t.Errorf("Transition = %q, %v (state %s)", status, err, stateID)
+	}
+	if _, err := l.Get(ctx, "LIN-404"); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("missing issue: %v", err)
+	}
+}
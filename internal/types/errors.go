@@ -0,0 +1,160 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// ErrorCode is a machine-readable error category shared by tools, the agent
+// service and gateway responses, so clients can react without parsing messages.
+type ErrorCode string
+
+const (
+	ErrInvalidArgument  ErrorCode = "INVALID_ARGUMENT"  // Missing/malformed parameter or request
+	ErrNotFound         ErrorCode = "NOT_FOUND"         // File, session, tool or symbol does not exist
+	ErrAlreadyExists    ErrorCode = "ALREADY_EXISTS"    // Target already exists
+	ErrPermissionDenied ErrorCode = "PERMISSION_DENIED" // OS permission or policy refusal
+	ErrConflict         ErrorCode = "CONFLICT"          // Concurrent modification (e.g. file changed since read)
+	ErrTimeout          ErrorCode = "TIMEOUT"           // Deadline exceeded
+	ErrCanceled         ErrorCode = "CANCELED"          // Canceled by the client
+	ErrBudgetExceeded   ErrorCode = "BUDGET_EXCEEDED"   // Step, token, cost or resource limit reached
+	ErrRateLimited      ErrorCode = "RATE_LIMITED"      // Too many requests (gateway or provider)
+	ErrUnavailable      ErrorCode = "UNAVAILABLE"       // Provider down or required binary missing
+	ErrCommandFailed    ErrorCode = "COMMAND_FAILED"    // Command ran but exited non-zero
+	ErrInternal         ErrorCode = "INTERNAL"          // Anything else
+)
+
+// Error is an error carrying an ErrorCode
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error // Wrapped cause (optional)
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return string(e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf creates a coded error; a %w verb wraps the cause like fmt.Errorf
+func Errorf(code ErrorCode, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// messageCodes maps common message fragments to codes, for errors that were
+// never typed (tool result strings, provider errors, exec errors)
+var messageCodes = []struct {
+	fragment string
+	code     ErrorCode
+}{
+	{"no such file", ErrNotFound},
+	{"not found", ErrNotFound},
+	{"does not exist", ErrNotFound},
+	{"permission denied", ErrPermissionDenied},
+	{"operation not permitted", ErrPermissionDenied},
+	{"already exists", ErrAlreadyExists},
+	{"file exists", ErrAlreadyExists},
+	{"timed out", ErrTimeout},
+	{"timeout", ErrTimeout},
+	{"deadline exceeded", ErrTimeout},
+	{"time limit", ErrTimeout},
+	{"context canceled", ErrCanceled},
+	{"rate limit", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+	{"status 429", ErrRateLimited},
+	{"status code: 429", ErrRateLimited},
+	{"http 429", ErrRateLimited},
+	{"(429)", ErrRateLimited},
+	{"exceeded maximum steps", ErrBudgetExceeded},
+	{"budget", ErrBudgetExceeded},
+	{"not installed", ErrUnavailable},
+	{"executable file not found", ErrUnavailable},
+	{"connection refused", ErrUnavailable},
+	{"circuit", ErrUnavailable},
+	{"exit status", ErrCommandFailed},
+	{"is required", ErrInvalidArgument},
+	{"invalid", ErrInvalidArgument},
+	{"unknown action", ErrInvalidArgument},
+}
+
+// CodeOf classifies an error: coded errors keep their code, well-known
+// sentinels map directly, and the message is used as a last resort.
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var coded *Error
+	if errors.As(err, &coded) && coded.Code != "" {
+		return coded.Code
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrPermissionDenied
+	case errors.Is(err, fs.ErrExist):
+		return ErrAlreadyExists
+	}
+	return CodeOfMessage(err.Error())
+}
+
+// CodeOfMessage classifies a free-form error message
+func CodeOfMessage(msg string) ErrorCode {
+	lower := strings.ToLower(msg)
+	for _, mc := range messageCodes {
+		if strings.Contains(lower, mc.fragment) {
+			return mc.code
+		}
+	}
+	return ErrInternal
+}
+
+// HTTPStatus returns the HTTP status for an error code
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case ErrInvalidArgument:
+		return http.StatusBadRequest
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrAlreadyExists, ErrConflict:
+		return http.StatusConflict
+	case ErrPermissionDenied:
+		return http.StatusForbidden
+	case ErrTimeout:
+		return http.StatusGatewayTimeout
+	case ErrCanceled:
+		return 499 // Client closed request
+	case ErrBudgetExceeded:
+		return http.StatusPaymentRequired
+	case ErrRateLimited:
+		return http.StatusTooManyRequests
+	case ErrUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorResponse is the JSON body of gateway error responses
+type ErrorResponse struct {
+	Error     string    `json:"error"`
+	ErrorCode ErrorCode `json:"error_code"`
+}
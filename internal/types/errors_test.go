@@ -0,0 +1,35 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/zen-claw")
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"nil", nil, ""},
+		{"coded", Errorf(ErrBudgetExceeded, "exceeded maximum steps (%d)", 5), ErrBudgetExceeded},
+		{"wrapped coded", fmt.Errorf("run: %w", Errorf(ErrConflict, "changed")), ErrConflict},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), ErrTimeout},
+		{"canceled", context.Canceled, ErrCanceled},
+		{"fs not exist", statErr, ErrNotFound},
+		{"message", fmt.Errorf("openai: 429 Too Many Requests"), ErrRateLimited},
+		{"status in parens", fmt.Errorf("anthropic API error (429): slow down"), ErrRateLimited},
+		{"429 elsewhere", fmt.Errorf("syntax error at line 429"), ErrInternal},
+		{"unknown", fmt.Errorf("something odd"), ErrInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 // Package types provides shared types used across zen-claw packages.
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // ChatRequest represents a chat request to the gateway.
 // Used by CLI, Slack bot, and gateway service.
 type ChatRequest struct {
@@ -11,7 +17,156 @@ type ChatRequest struct {
 	Model         string `json:"model,omitempty"`
 	MaxSteps      int    `json:"max_steps,omitempty"`
 	ThinkingLevel string `json:"thinking_level,omitempty"` // off, low, medium, high
+	Language      string `json:"language,omitempty"`       // Language tag for answers, e.g. pt-BR ("default" resets)
 	Stream        bool   `json:"stream,omitempty"`         // Enable token-by-token streaming
+
+	// Env is merged into the session environment injected into exec/process tools.
+	// Values may be secret refs (keyring:<service>/<account>, op://vault/item/field);
+	// an empty value removes the variable.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Limits tightens the gateway's resource limits for exec/process tools in this session
+	Limits *ResourceLimits `json:"limits,omitempty"`
+
+	// Params overrides the configured sampling settings for this request
+	Params *ModelParams `json:"params,omitempty"`
+
+	// ResponseSchema is a JSON Schema the final answer must match; the validated
+	// answer is returned as JSON in ChatResponse.Output
+	ResponseSchema map[string]interface{} `json:"response_schema,omitempty"`
+
+	// Tags are added to the session's labels; Project overrides the project
+	// derived from the working directory's git remote
+	Tags    []string `json:"tags,omitempty"`
+	Project string   `json:"project,omitempty"`
+
+	// Pin keeps this message (e.g. requirements or a schema) in the context
+	// verbatim; history trimming and summarization never drop it
+	Pin bool `json:"pin,omitempty"`
+
+	// Review overrides the configured self-review pass (agent.review)
+	Review *SelfReview `json:"review,omitempty"`
+
+	// DirtyTree overrides how uncommitted changes the agent didn't make are
+	// treated (agent.dirty_tree): warn, stash or off
+	DirtyTree string `json:"dirty_tree,omitempty"`
+
+	// Queue waits for a turn already running in the session instead of
+	// failing with CONFLICT
+	Queue bool `json:"queue,omitempty"`
+
+	// ReadOnly runs the turn with only the tools that change nothing
+	// (reading, searching, git status/diff/log); no shell, no writes
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// Explain answers a question about the code: only the tools that read,
+	// search and navigate code, with their results shared by the explain
+	// runs of the working tree until it changes
+	Explain bool `json:"explain,omitempty"`
+}
+
+// SelfReview configures the review pass before a run that changed files is
+// finalized: a reviewer checks the diff against the request and the build/
+// test output, and either approves or sends fix instructions back.
+type SelfReview struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	Provider      string `json:"provider,omitempty" yaml:"provider"`             // Reviewer (default: the session's provider)
+	Model         string `json:"model,omitempty" yaml:"model"`                   // Default: the provider's model
+	VerifyCommand string `json:"verify_command,omitempty" yaml:"verify_command"` // Build/test command (default: detected; "none" skips)
+	MaxRounds     int    `json:"max_rounds,omitempty" yaml:"max_rounds"`         // Fix rounds (default 2)
+}
+
+// ReviewOutcome is how the self-review of a run went
+type ReviewOutcome struct {
+	Approved      bool     `json:"approved"`
+	Rounds        int      `json:"rounds"` // Reviews run
+	Issues        []string `json:"issues,omitempty"`
+	VerifyCommand string   `json:"verify_command,omitempty"`
+	VerifyPassed  *bool    `json:"verify_passed,omitempty"`
+}
+
+// ResourceLimits bounds commands launched by the exec and process tools.
+// Zero fields mean "no limit".
+type ResourceLimits struct {
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty" yaml:"timeout_seconds"` // Wall clock (exec only; background processes run until killed)
+	CPUSeconds     int  `json:"cpu_seconds,omitempty" yaml:"cpu_seconds"`         // RLIMIT_CPU
+	MemoryMB       int  `json:"memory_mb,omitempty" yaml:"memory_mb"`             // RLIMIT_AS, or cgroup MemoryMax
+	MaxProcesses   int  `json:"max_processes,omitempty" yaml:"max_processes"`     // cgroup TasksMax (needs Cgroup)
+	FileSizeMB     int  `json:"file_size_mb,omitempty" yaml:"file_size_mb"`       // RLIMIT_FSIZE
+	CPUPercent     int  `json:"cpu_percent,omitempty" yaml:"cpu_percent"`         // cgroup CPUQuota, 100 = one core (needs Cgroup)
+	Cgroup         bool `json:"cgroup,omitempty" yaml:"cgroup"`                   // Run in a systemd-run scope (Linux; refused without systemd-run)
+}
+
+// ModelParams are sampling settings for model calls. Zero fields are unset:
+// the next layer down (or the provider's default) applies.
+type ModelParams struct {
+	Temperature float64 `json:"temperature,omitempty" yaml:"temperature"`
+	MaxTokens   int     `json:"max_tokens,omitempty" yaml:"max_tokens"`
+	TopP        float64 `json:"top_p,omitempty" yaml:"top_p"`
+}
+
+// Merge returns p with the fields set in over replacing its own
+func (p ModelParams) Merge(over ModelParams) ModelParams {
+	if over.Temperature > 0 {
+		p.Temperature = over.Temperature
+	}
+	if over.MaxTokens > 0 {
+		p.MaxTokens = over.MaxTokens
+	}
+	if over.TopP > 0 {
+		p.TopP = over.TopP
+	}
+	return p
+}
+
+// ModelCapabilities are what a probe found a model supports. The gateway
+// probes models it knows nothing about on first use and picks the
+// interaction protocol from the results.
+type ModelCapabilities struct {
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Tools        bool      `json:"tools"`         // Native function calling
+	JSONMode     bool      `json:"json_mode"`     // Accepts JSON mode and answers with JSON
+	SystemPrompt bool      `json:"system_prompt"` // Follows instructions in the system prompt
+	ProbedAt     time.Time `json:"probed_at"`
+}
+
+// Run kinds and statuses of a RunRecord
+const (
+	RunConsensus = "consensus"
+	RunFabric    = "fabric"
+	RunFactory   = "factory"
+
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+	RunPaused    = "paused" // Factory waiting for a human
+)
+
+// RunRecord is a consensus, fabric or factory run. These run in the CLI,
+// which reports them to the gateway so they show up in /stats and /runs
+// next to agent sessions.
+type RunRecord struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Task       string    `json:"task"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	CostUSD    float64   `json:"cost_usd"` // Estimated
+	Tokens     int       `json:"tokens,omitempty"`
+	Calls      []RunCall `json:"calls"`
+}
+
+// RunCall is one model call of a run
+type RunCall struct {
+	Provider   string  `json:"provider"`
+	Model      string  `json:"model,omitempty"`
+	Role       string  `json:"role"` // worker, draft, arbiter, coordinator, or the fabric worker / factory phase name
+	DurationMs int64   `json:"duration_ms,omitempty"`
+	CostUSD    float64 `json:"cost_usd,omitempty"`
+	Score      int     `json:"score,omitempty"` // Consensus arbiter score, 1-10
+	Error      string  `json:"error,omitempty"`
 }
 
 // ChatResponse represents a chat response from the gateway.
@@ -19,15 +174,112 @@ type ChatResponse struct {
 	SessionID   string                 `json:"session_id"`
 	Result      string                 `json:"result"`
 	Error       string                 `json:"error,omitempty"`
+	ErrorCode   ErrorCode              `json:"error_code,omitempty"`
+	Output      json.RawMessage        `json:"output,omitempty"`    // Set when response_schema was given
+	RecordID    string                 `json:"record_id,omitempty"` // Dataset record, when recording is enabled
 	SessionInfo map[string]interface{} `json:"session_info,omitempty"`
+	StepLimit   *StepLimit             `json:"step_limit,omitempty"` // Set when the run stopped at max_steps
+	Review      *ReviewOutcome         `json:"review,omitempty"`     // Set when the changes were self-reviewed
+}
+
+// ContinuePrompt resumes a task that stopped at the step limit
+const ContinuePrompt = "Continue the task from where you stopped."
+
+// StepLimit tells a client the run stopped at max_steps before finishing.
+// The result summarizes the progress and all work stays in the session:
+// sending ContinuePrompt on the session with max_steps Extend resumes it.
+type StepLimit struct {
+	Steps  int `json:"steps"`  // Steps the run used (its max_steps)
+	Extend int `json:"extend"` // Suggested max_steps for continuing
+}
+
+// ExtendSteps suggests how many steps to continue a run that used steps:
+// half as many again, at least 10
+func ExtendSteps(steps int) int {
+	return max(steps/2, 10)
 }
 
+// ProgressSchemaVersion is the version of ProgressEvent sent by the gateway.
+// Version 2 keeps the v1 fields (type, step, message, data) and adds typed
+// timing, tool and usage payloads, so clients render them without parsing
+// messages. Events without "v" are version 1.
+const ProgressSchemaVersion = 2
+
 // ProgressEvent represents a progress event during agent execution.
 type ProgressEvent struct {
-	Type    string      `json:"type"`    // start, step, thinking, ai_response, tool_call, complete, error, done
-	Step    int         `json:"step"`    // Current step number
-	Message string      `json:"message"` // Human-readable message
+	Version int         `json:"v,omitempty"` // ProgressSchemaVersion
+	Type    string      `json:"type"`        // start, queued, session_resumed, dirty_tree, step, thinking, ai_response, tool_call, token, complete, error
+	Step    int         `json:"step"`        // Current step number
+	Message string      `json:"message"`     // Human-readable message
 	Data    interface{} `json:"data,omitempty"`
+
+	ElapsedMs  int64 `json:"elapsed_ms,omitempty"`  // Since the run started
+	DurationMs int64 `json:"duration_ms,omitempty"` // Model call (ai_response), tool call (tool_call) or whole run (complete)
+
+	Provider  string        `json:"provider,omitempty"`   // start
+	Model     string        `json:"model,omitempty"`      // start
+	SessionID string        `json:"session_id,omitempty"` // start, session_resumed
+	Tool      *ToolProgress `json:"tool,omitempty"`       // tool_call (absent for batch notices)
+	Usage     *Usage        `json:"usage,omitempty"`      // ai_response, complete: the step's model call
+	Total     *Usage        `json:"total,omitempty"`      // Running total of the request so far (from the first model call)
+}
+
+// Duration formats DurationMs for display ("340ms", "2.4s"; "" when unset)
+func (e ProgressEvent) Duration() string {
+	switch {
+	case e.DurationMs <= 0:
+		return ""
+	case e.DurationMs < 1000:
+		return fmt.Sprintf("%dms", e.DurationMs)
+	default:
+		return fmt.Sprintf("%.1fs", float64(e.DurationMs)/1000)
+	}
+}
+
+// ToolProgress describes one finished tool call in a progress event
+type ToolProgress struct {
+	Name       string `json:"name"`
+	CallID     string `json:"call_id,omitempty"`
+	Args       string `json:"args,omitempty"`        // Short summary for display
+	ArgsDigest string `json:"args_digest,omitempty"` // Hash of the arguments, equal for repeated calls
+	Status     string `json:"status"`                // ok, error, invalid_args, not_found, canceled
+	Result     string `json:"result,omitempty"`      // Short summary of the result or error
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Tool call statuses in ToolProgress
+const (
+	ToolStatusOK          = "ok"
+	ToolStatusError       = "error"
+	ToolStatusInvalidArgs = "invalid_args"
+	ToolStatusNotFound    = "not_found"
+	ToolStatusCanceled    = "canceled" // Not run: the run was canceled
+)
+
+// Usage is the estimated tokens and cost of model calls
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+		CostUSD:      u.CostUSD + other.CostUSD,
+	}
+}
+
+// String formats the usage for display: "≈$0.042, 18.3K tokens"
+func (u Usage) String() string {
+	tokens := u.InputTokens + u.OutputTokens
+	count := fmt.Sprintf("%d", tokens)
+	if tokens >= 1000 {
+		count = fmt.Sprintf("%.1fK", float64(tokens)/1000)
+	}
+	return fmt.Sprintf("≈$%.3f, %s tokens", u.CostUSD, count)
 }
 
 // ProgressCallback is called with progress events during execution.
@@ -0,0 +1,65 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The CLI hints at new releases without slowing down: it reads the result
+// of the last check, and refreshes it in the background once a day.
+
+// CheckInterval is how often the background check runs
+const CheckInterval = 24 * time.Hour
+
+// CheckResult is the outcome of the last background check
+type CheckResult struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Channel   string    `json:"channel"`
+	Latest    string    `json:"latest,omitempty"` // "" = no release on the channel
+	URL       string    `json:"url,omitempty"`
+}
+
+// DefaultCheckPath returns where the last check result is kept
+func DefaultCheckPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".zen", "zen-claw", "update-check.json")
+}
+
+// ReadCheck returns the last check result (nil when none was recorded)
+func ReadCheck(path string) *CheckResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var result CheckResult
+	if json.Unmarshal(data, &result) != nil {
+		return nil
+	}
+	return &result
+}
+
+// Stale reports whether the result is missing, older than CheckInterval, or
+// for another channel
+func (r *CheckResult) Stale(channel string) bool {
+	return r == nil || r.Channel != channel || time.Since(r.CheckedAt) > CheckInterval
+}
+
+// Check looks up the latest release on channel and records the result
+func (c *Client) Check(ctx context.Context, path, channel string) (*CheckResult, error) {
+	release, err := c.Latest(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	result := &CheckResult{CheckedAt: time.Now(), Channel: channel}
+	if release != nil {
+		result.Latest, result.URL = release.Version(), release.URL
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return result, err
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	return result, os.WriteFile(path, data, 0644)
+}
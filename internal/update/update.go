@@ -0,0 +1,380 @@
+// Package update finds zen-claw releases on GitHub, downloads the binary for
+// this platform, verifies it against the release checksums (and their
+// signature, when a public key is configured), and swaps it in place of the
+// running executable.
+//
+// A release carries one binary per platform, named zen-claw-<os>-<arch>
+// (.exe on Windows), and checksums.txt in sha256sum format. Signed releases
+// add checksums.txt.sig, an ed25519 signature of checksums.txt (raw or
+// base64).
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+	"github.com/neves/zen-claw/internal/version"
+)
+
+const (
+	DefaultRepo = "kube-zen/zen-claw"
+
+	ChannelStable = "stable" // Releases only
+	ChannelBeta   = "beta"   // Pre-releases too
+
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+
+	maxBinarySize = 256 << 20 // Refuse downloads larger than this
+)
+
+// Release is a GitHub release
+type Release struct {
+	Tag         string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	URL         string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Version returns the release version without the leading v
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Client talks to the GitHub releases API
+type Client struct {
+	Repo      string            // owner/name
+	APIURL    string            // GitHub API base URL
+	PublicKey ed25519.PublicKey // checksums.txt must be signed with it
+	HTTP      *http.Client
+
+	// SkipSignature installs releases verified by checksum alone. Without
+	// it, Download refuses every release when PublicKey is nil.
+	SkipSignature bool
+}
+
+// NewClient creates a client for repo ("" = DefaultRepo) that verifies
+// releases against the public key embedded in the build
+func NewClient(repo string) *Client {
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	c := &Client{
+		Repo:   repo,
+		APIURL: "https://api.github.com",
+		HTTP:   &http.Client{Timeout: 5 * time.Minute},
+	}
+	if version.PublicKey != "" {
+		c.PublicKey, _ = ParsePublicKey(version.PublicKey) // A bad key leaves nil, which refuses downloads
+	}
+	return c
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: want %d base64-encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Latest returns the newest release on a channel, or nil when there is none
+func (c *Client) Latest(ctx context.Context, channel string) (*Release, error) {
+	if channel == "" {
+		channel = ChannelStable
+	}
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, types.Errorf(types.ErrInvalidArgument, "unknown channel %q (use %s or %s)", channel, ChannelStable, ChannelBeta)
+	}
+
+	data, err := c.get(ctx, fmt.Sprintf("%s/repos/%s/releases?per_page=30", c.APIURL, c.Repo), 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases: %w", err)
+	}
+
+	var latest *Release
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || r.Prerelease && channel != ChannelBeta {
+			continue
+		}
+		if latest == nil || Compare(r.Version(), latest.Version()) > 0 {
+			latest = r
+		}
+	}
+	return latest, nil
+}
+
+// AssetName returns the release binary name for a platform
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("zen-claw-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Download fetches the release binary for a platform and verifies it. It
+// refuses releases without checksums, and unsigned ones unless
+// SkipSignature is set.
+func (c *Client) Download(ctx context.Context, release *Release, goos, goarch string) ([]byte, error) {
+	if c.PublicKey == nil && !c.SkipSignature {
+		return nil, types.Errorf(types.ErrPermissionDenied, "no public key to verify release signatures with; set update.public_key (or update.skip_signature: true to trust checksums alone)")
+	}
+	name := AssetName(goos, goarch)
+	binary := release.asset(name)
+	if binary == nil {
+		return nil, types.Errorf(types.ErrNotFound, "release %s has no binary for %s/%s (%s)", release.Tag, goos, goarch, name)
+	}
+	sumsAsset := release.asset(checksumsAsset)
+	if sumsAsset == nil {
+		return nil, types.Errorf(types.ErrPermissionDenied, "release %s has no %s; refusing to install an unverified binary", release.Tag, checksumsAsset)
+	}
+	sums, err := c.get(ctx, sumsAsset.URL, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.SkipSignature {
+		sigAsset := release.asset(signatureAsset)
+		if sigAsset == nil {
+			return nil, types.Errorf(types.ErrPermissionDenied, "release %s is not signed (no %s)", release.Tag, signatureAsset)
+		}
+		sig, err := c.get(ctx, sigAsset.URL, 4<<10)
+		if err != nil {
+			return nil, err
+		}
+		if !verifySignature(c.PublicKey, sums, sig) {
+			return nil, types.Errorf(types.ErrPermissionDenied, "release %s: %s signature does not match the public key", release.Tag, checksumsAsset)
+		}
+	}
+
+	want, ok := checksumFor(sums, name)
+	if !ok {
+		return nil, types.Errorf(types.ErrPermissionDenied, "release %s: %s has no entry for %s", release.Tag, checksumsAsset, name)
+	}
+	data, err := c.get(ctx, binary.URL, maxBinarySize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return nil, types.Errorf(types.ErrPermissionDenied, "checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	return data, nil
+}
+
+// checksumFor finds a file's hash in sha256sum output ("<hex>  name", or
+// "<hex> *name" for binary mode)
+func checksumFor(sums []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+// verifySignature checks an ed25519 signature given raw or base64-encoded
+func verifySignature(key ed25519.PublicKey, message, sig []byte) bool {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return false
+		}
+		sig = decoded
+	}
+	return len(sig) == ed25519.SignatureSize && ed25519.Verify(key, message, sig)
+}
+
+func (c *Client) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "zen-claw-update")
+	if strings.HasPrefix(url, c.APIURL) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, types.Errorf(types.ErrNotFound, "%s: not found", url)
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, types.Errorf(types.ErrRateLimited, "GitHub refused the request (%s); set GITHUB_TOKEN to raise the rate limit", resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, types.Errorf(types.ErrUnavailable, "%s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, types.Errorf(types.ErrBudgetExceeded, "%s is larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// Install atomically replaces the executable at exe with data: the new
+// binary is written next to it and renamed over it, so a crash leaves
+// either the old or the new binary, never a partial one.
+func Install(exe string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".zen-claw-update-*")
+	if err != nil {
+		return types.Errorf(types.ErrPermissionDenied, "can't write next to %s: %v", exe, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Windows can't replace a running executable, but can rename it
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// Compare compares two semantic versions (leading v optional): -1 when a
+// is older than b, 0 when equal, 1 when newer. A pre-release is older than
+// its release (1.2.0-beta.1 < 1.2.0).
+func Compare(a, b string) int {
+	coreA, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	coreB, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	partsA, partsB := strings.Split(coreA, "."), strings.Split(coreB, ".")
+	n := max(len(partsA), len(partsB)) // 1.2 == 1.2.0
+	if c := compareIdentifiers(padZero(partsA, n), padZero(partsB, n)); c != 0 {
+		return c
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return compareIdentifiers(strings.Split(preA, "."), strings.Split(preB, "."))
+}
+
+// compareIdentifiers compares dot-separated parts: numerically when both
+// are numbers, else as strings. With an equal prefix, fewer parts is older.
+func compareIdentifiers(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i >= len(a) {
+			return -1
+		}
+		if i >= len(b) {
+			return 1
+		}
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			return -1 // Numeric identifiers sort before alphanumeric ones
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func padZero(parts []string, n int) []string {
+	for len(parts) < n {
+		parts = append(parts, "0")
+	}
+	return parts
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
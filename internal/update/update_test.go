@@ -0,0 +1,219 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"0.1.0", "0.2.0", -1},
+		{"1.2.0-beta.1", "1.2.0", -1},
+		{"1.2.0-beta.2", "1.2.0-beta.10", -1},
+		{"1.2.0-rc.1", "1.2.0-beta.3", 1},
+		{"1.2.0-beta", "1.2.0-beta.1", -1},
+		{"1.3.0-beta.1", "1.2.9", 1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// fakeGitHub serves a releases list and the assets of one release
+type fakeGitHub struct {
+	*httptest.Server
+	releases []Release
+	files    map[string][]byte
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	gh := &fakeGitHub{files: map[string][]byte{}}
+	gh.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/kube-zen/zen-claw/releases" {
+			json.NewEncoder(w).Encode(gh.releases)
+			return
+		}
+		data, ok := gh.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(gh.Close)
+	return gh
+}
+
+// release adds a release whose assets are files (name -> content)
+func (gh *fakeGitHub) release(tag string, prerelease bool, files map[string][]byte) *Release {
+	r := Release{Tag: tag, Prerelease: prerelease}
+	for name, data := range files {
+		path := fmt.Sprintf("/download/%s/%s", tag, name)
+		gh.files[path] = data
+		r.Assets = append(r.Assets, Asset{Name: name, URL: gh.URL + path})
+	}
+	gh.releases = append(gh.releases, r)
+	return &gh.releases[len(gh.releases)-1]
+}
+
+func (gh *fakeGitHub) client() *Client {
+	c := NewClient("")
+	c.APIURL = gh.URL
+	return c
+}
+
+func checksums(files map[string][]byte) []byte {
+	var out []byte
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		out = append(out, fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)...)
+	}
+	return out
+}
+
+func TestLatest(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.release("v1.2.0", false, nil)
+	gh.release("v1.10.0", false, nil)
+	gh.release("v1.11.0-beta.1", true, nil)
+	gh.releases = append(gh.releases, Release{Tag: "v2.0.0", Draft: true})
+
+	ctx := context.Background()
+	for channel, want := range map[string]string{ChannelStable: "1.10.0", "": "1.10.0", ChannelBeta: "1.11.0-beta.1"} {
+		r, err := gh.client().Latest(ctx, channel)
+		if err != nil || r == nil || r.Version() != want {
+			t.Errorf("Latest(%q) = %+v, %v; want %s", channel, r, err, want)
+		}
+	}
+	if _, err := gh.client().Latest(ctx, "nightly"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("unknown channel: %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ctx := context.Background()
+	name := AssetName("linux", "amd64")
+	binary := []byte("#!new zen-claw")
+	pub, priv, _ := ed25519.GenerateKey(nil)
+
+	t.Run("verified", func(t *testing.T) {
+		gh := newFakeGitHub(t)
+		sums := checksums(map[string][]byte{name: binary})
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums))
+		r := gh.release("v1.0.0", false, map[string][]byte{name: binary, checksumsAsset: sums, signatureAsset: []byte(sig)})
+
+		c := gh.client()
+		c.PublicKey = pub
+		data, err := c.Download(ctx, r, "linux", "amd64")
+		if err != nil || string(data) != string(binary) {
+			t.Fatalf("Download = %q, %v", data, err)
+		}
+		if _, err := c.Download(ctx, r, "plan9", "386"); types.CodeOf(err) != types.ErrNotFound {
+			t.Errorf("missing platform: %v", err)
+		}
+	})
+
+	refused := map[string]map[string][]byte{
+		"no checksums":      {name: binary},
+		"checksum mismatch": {name: binary, checksumsAsset: checksums(map[string][]byte{name: []byte("other")})},
+		"not listed":        {name: binary, checksumsAsset: checksums(map[string][]byte{"zen-claw-darwin-arm64": binary})},
+	}
+	for what, files := range refused {
+		gh := newFakeGitHub(t)
+		r := gh.release("v1.0.0", false, files)
+		c := gh.client()
+		c.SkipSignature = true
+		if _, err := c.Download(ctx, r, "linux", "amd64"); types.CodeOf(err) != types.ErrPermissionDenied {
+			t.Errorf("%s: err = %v, want PERMISSION_DENIED", what, err)
+		}
+	}
+
+	// Without a public key, only an explicit SkipSignature installs
+	sums := checksums(map[string][]byte{name: binary})
+	gh := newFakeGitHub(t)
+	r := gh.release("v1.0.0", false, map[string][]byte{name: binary, checksumsAsset: sums})
+	c := gh.client()
+	if _, err := c.Download(ctx, r, "linux", "amd64"); types.CodeOf(err) != types.ErrPermissionDenied {
+		t.Errorf("no public key: err = %v, want PERMISSION_DENIED", err)
+	}
+	c.SkipSignature = true
+	if data, err := c.Download(ctx, r, "linux", "amd64"); err != nil || string(data) != string(binary) {
+		t.Errorf("skip signature: Download = %q, %v", data, err)
+	}
+
+	// With a public key, checksums must be signed by it
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	for what, files := range map[string]map[string][]byte{
+		"unsigned":  {name: binary, checksumsAsset: sums},
+		"wrong key": {name: binary, checksumsAsset: sums, signatureAsset: ed25519.Sign(otherKey, sums)},
+	} {
+		gh := newFakeGitHub(t)
+		r := gh.release("v1.0.0", false, files)
+		c := gh.client()
+		c.PublicKey = pub
+		if _, err := c.Download(ctx, r, "linux", "amd64"); types.CodeOf(err) != types.ErrPermissionDenied {
+			t.Errorf("%s: err = %v, want PERMISSION_DENIED", what, err)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "zen-claw")
+	os.WriteFile(exe, []byte("old"), 0750)
+	link := filepath.Join(dir, "link")
+	os.Symlink(exe, link)
+
+	if err := Install(link, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(exe)
+	info, _ := os.Stat(exe)
+	if string(data) != "new" || info.Mode().Perm() != 0750 {
+		t.Errorf("exe = %q %v, want new with mode 0750", data, info.Mode().Perm())
+	}
+	if l, err := os.Lstat(link); err != nil || l.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced instead of its target")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.release("v3.1.0", false, nil)
+	path := filepath.Join(t.TempDir(), "update-check.json")
+
+	if !ReadCheck(path).Stale(ChannelStable) {
+		t.Error("missing check is not stale")
+	}
+	if _, err := gh.client().Check(context.Background(), path, ChannelStable); err != nil {
+		t.Fatal(err)
+	}
+	last := ReadCheck(path)
+	if last == nil || last.Latest != "3.1.0" || last.Stale(ChannelStable) || !last.Stale(ChannelBeta) {
+		t.Errorf("check = %+v", last)
+	}
+}
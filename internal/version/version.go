@@ -0,0 +1,12 @@
+// Package version holds the zen-claw release version.
+package version
+
+// Version is the release this binary was built from. Release builds set it
+// with -ldflags "-X github.com/neves/zen-claw/internal/version.Version=1.2.3".
+var Version = "0.1.0"
+
+// PublicKey is the base64 ed25519 key release checksums are signed with.
+// Release builds set it with -ldflags "-X
+// github.com/neves/zen-claw/internal/version.PublicKey=<base64>"; zen-claw
+// update refuses to install a release it can't verify against it.
+var PublicKey = ""
@@ -0,0 +1,137 @@
+package wiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// confluencePageID finds the page ID in Confluence page URLs:
+// /wiki/spaces/ENG/pages/12345/Title and /pages/viewpage.action?pageId=12345
+var confluencePageID = regexp.MustCompile(`/pages/(\d+)(?:/|$)|[?&]pageId=(\d+)`)
+
+// cqlLike matches queries that are already CQL rather than free text
+var cqlLike = regexp.MustCompile(`(?i)(=|~|\bORDER BY\b|\bIN\s*\()`)
+
+// Confluence is a client of the Confluence REST API (Cloud, Server and Data
+// Center)
+type Confluence struct {
+	URL   string // Base URL, e.g. https://acme.atlassian.net/wiki (Cloud) or https://confluence.acme.com
+	Email string // Cloud account; with it Token is an API token (basic auth), without it a bearer personal access token
+	Token string
+	HTTP  *http.Client
+}
+
+// NewConfluence creates a client for a Confluence site
+func NewConfluence(baseURL, email, token string) *Confluence {
+	return &Confluence{URL: strings.TrimSuffix(baseURL, "/"), Email: email, Token: token, HTTP: httpClient}
+}
+
+// confluenceContent is the part of a content object the tools use
+type confluenceContent struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Space struct {
+		Key string `json:"key"`
+	} `json:"space"`
+	Version struct {
+		When string `json:"when"`
+	} `json:"version"`
+	Body struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+	Links struct {
+		WebUI string `json:"webui"`
+	} `json:"_links"`
+}
+
+func (c *Confluence) page(content confluenceContent) Page {
+	p := Page{
+		ID:      content.ID,
+		Title:   content.Title,
+		Space:   content.Space.Key,
+		Updated: content.Version.When,
+	}
+	if content.Links.WebUI != "" {
+		p.URL = c.URL + content.Links.WebUI
+	}
+	return p
+}
+
+// Search runs a CQL query over pages; free text becomes a text search
+func (c *Confluence) Search(ctx context.Context, query string, limit int) ([]Page, error) {
+	cql := strings.TrimSpace(query)
+	if !cqlLike.MatchString(cql) {
+		cql = fmt.Sprintf("type = page AND text ~ %s", strconv.Quote(cql))
+	}
+	params := url.Values{"cql": {cql}, "limit": {strconv.Itoa(limit)}, "expand": {"space,version"}}
+	var result struct {
+		Results []confluenceContent `json:"results"`
+	}
+	if err := c.do(ctx, "/rest/api/content/search?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+	pages := make([]Page, 0, len(result.Results))
+	for _, content := range result.Results {
+		pages = append(pages, c.page(content))
+	}
+	return pages, nil
+}
+
+// Fetch reads a page by ID or URL; the body is Confluence storage format
+// (XHTML)
+func (c *Confluence) Fetch(ctx context.Context, ref string) (*Page, error) {
+	id := strings.TrimSpace(ref)
+	if m := confluencePageID.FindStringSubmatch(id); m != nil {
+		id = m[1] + m[2]
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, types.Errorf(types.ErrInvalidArgument, "no Confluence page ID in %q (use the page URL or its numeric ID)", ref)
+	}
+	var content confluenceContent
+	if err := c.do(ctx, "/rest/api/content/"+id+"?expand=body.storage,space,version", &content); err != nil {
+		return nil, err
+	}
+	p := c.page(content)
+	p.Body = content.Body.Storage.Value
+	p.Format = "html"
+	return &p, nil
+}
+
+// do makes one GET request and decodes the JSON response into out
+func (c *Confluence) do(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Email != "" {
+		req.SetBasicAuth(c.Email, c.Token)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError("Confluence", resp, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Confluence response: %w", err)
+	}
+	return nil
+}
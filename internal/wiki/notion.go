@@ -0,0 +1,314 @@
+package wiki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// DefaultNotionAPI is the Notion API
+const DefaultNotionAPI = "https://api.notion.com"
+
+// notionVersion is the API version the client speaks
+const notionVersion = "2022-06-28"
+
+// notionMaxBlocks caps the blocks read for one page
+const notionMaxBlocks = 2000
+
+// notionMaxDepth caps how deep nested blocks (toggles, list children) are read
+const notionMaxDepth = 3
+
+// notionPageID finds a page ID: 32 hex digits, dashed or not, at the end of
+// a notion.so URL path or on its own
+var notionPageID = regexp.MustCompile(`([0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12})(?:[?#]|$)`)
+
+// Notion is a client of the Notion API
+type Notion struct {
+	APIURL string // Default DefaultNotionAPI
+	Token  string // Integration token; pages must be shared with the integration
+	HTTP   *http.Client
+}
+
+// NewNotion creates a client for a Notion integration
+func NewNotion(token string) *Notion {
+	return &Notion{APIURL: DefaultNotionAPI, Token: token, HTTP: httpClient}
+}
+
+// notionRichText is a run of text
+type notionRichText struct {
+	PlainText string `json:"plain_text"`
+	Href      string `json:"href"`
+}
+
+// notionPage is the part of a page object the tools use
+type notionPage struct {
+	ID             string `json:"id"`
+	URL            string `json:"url"`
+	LastEditedTime string `json:"last_edited_time"`
+	Properties     map[string]struct {
+		Type  string           `json:"type"`
+		Title []notionRichText `json:"title"`
+	} `json:"properties"`
+}
+
+func (p notionPage) page() Page {
+	page := Page{ID: p.ID, URL: p.URL, Updated: p.LastEditedTime}
+	for _, prop := range p.Properties {
+		if prop.Type == "title" {
+			page.Title = plainText(prop.Title)
+		}
+	}
+	return page
+}
+
+// notionBlock is a block; its content sits under the key named by Type
+type notionBlock struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	HasChildren bool   `json:"has_children"`
+	content     struct {
+		RichText []notionRichText   `json:"rich_text"`
+		Checked  bool               `json:"checked"`
+		Language string             `json:"language"`
+		Title    string             `json:"title"`
+		URL      string             `json:"url"`
+		Caption  []notionRichText   `json:"caption"`
+		Cells    [][]notionRichText `json:"cells"`
+	}
+}
+
+func (b *notionBlock) UnmarshalJSON(data []byte) error {
+	var head struct {
+		ID          string `json:"id"`
+		Type        string `json:"type"`
+		HasChildren bool   `json:"has_children"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	b.ID, b.Type, b.HasChildren = head.ID, head.Type, head.HasChildren
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if content, ok := raw[head.Type]; ok {
+		json.Unmarshal(content, &b.content)
+	}
+	return nil
+}
+
+// Search finds pages shared with the integration by title
+func (n *Notion) Search(ctx context.Context, query string, limit int) ([]Page, error) {
+	body := map[string]interface{}{
+		"query":     query,
+		"filter":    map[string]string{"property": "object", "value": "page"},
+		"page_size": limit,
+	}
+	var result struct {
+		Results []notionPage `json:"results"`
+	}
+	if err := n.do(ctx, http.MethodPost, "/v1/search", body, &result); err != nil {
+		return nil, err
+	}
+	pages := make([]Page, 0, len(result.Results))
+	for _, p := range result.Results {
+		pages = append(pages, p.page())
+	}
+	return pages, nil
+}
+
+// Fetch reads a page by URL or ID and renders its blocks as markdown
+func (n *Notion) Fetch(ctx context.Context, ref string) (*Page, error) {
+	ref = strings.TrimSpace(ref)
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		ref = u.Path
+	}
+	m := notionPageID.FindStringSubmatch(ref)
+	if m == nil {
+		return nil, types.Errorf(types.ErrInvalidArgument, "no Notion page ID in %q (use the page URL or its ID)", ref)
+	}
+	id := strings.ReplaceAll(m[1], "-", "")
+
+	var page notionPage
+	if err := n.do(ctx, http.MethodGet, "/v1/pages/"+id, nil, &page); err != nil {
+		return nil, err
+	}
+	p := page.page()
+	var b strings.Builder
+	blocks := 0
+	if err := n.render(ctx, id, 0, &blocks, &b); err != nil {
+		return nil, err
+	}
+	if blocks >= notionMaxBlocks {
+		fmt.Fprintf(&b, "\n[page cut at %d blocks]\n", notionMaxBlocks)
+	}
+	p.Body = strings.TrimSpace(b.String())
+	p.Format = "markdown"
+	return &p, nil
+}
+
+// render writes the children of a block as markdown, indenting nested ones
+func (n *Notion) render(ctx context.Context, id string, depth int, count *int, b *strings.Builder) error {
+	cursor := ""
+	indent := strings.Repeat("  ", depth)
+	number := 0
+	for {
+		path := "/v1/blocks/" + id + "/children?page_size=100"
+		if cursor != "" {
+			path += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		var result struct {
+			Results    []notionBlock `json:"results"`
+			HasMore    bool          `json:"has_more"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		if err := n.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return err
+		}
+		for _, block := range result.Results {
+			if *count >= notionMaxBlocks {
+				return nil
+			}
+			*count++
+			if block.Type == "numbered_list_item" {
+				number++
+			} else {
+				number = 0
+			}
+			b.WriteString(renderBlock(block, indent, number))
+			if block.HasChildren && depth < notionMaxDepth && block.Type != "child_page" && block.Type != "child_database" {
+				if err := n.render(ctx, block.ID, depth+1, count, b); err != nil {
+					return err
+				}
+			}
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			return nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// renderBlock renders one block as markdown lines
+func renderBlock(block notionBlock, indent string, number int) string {
+	c := block.content
+	text := plainText(c.RichText)
+	switch block.Type {
+	case "heading_1":
+		return "\n# " + text + "\n\n"
+	case "heading_2":
+		return "\n## " + text + "\n\n"
+	case "heading_3":
+		return "\n### " + text + "\n\n"
+	case "bulleted_list_item", "toggle":
+		return indent + "- " + text + "\n"
+	case "numbered_list_item":
+		return fmt.Sprintf("%s%d. %s\n", indent, number, text)
+	case "to_do":
+		box := "[ ]"
+		if c.Checked {
+			box = "[x]"
+		}
+		return indent + "- " + box + " " + text + "\n"
+	case "quote", "callout":
+		return indent + "> " + text + "\n\n"
+	case "code":
+		return "```" + c.Language + "\n" + text + "\n```\n\n"
+	case "divider":
+		return "---\n\n"
+	case "child_page", "child_database":
+		return indent + "[" + block.Type + ": " + c.Title + "]\n"
+	case "table_row":
+		cells := make([]string, len(c.Cells))
+		for i, cell := range c.Cells {
+			cells[i] = plainText(cell)
+		}
+		return indent + "| " + strings.Join(cells, " | ") + " |\n"
+	case "bookmark", "embed", "link_preview", "image", "file", "pdf", "video":
+		label := plainText(c.Caption)
+		if label == "" {
+			label = block.Type
+		}
+		if c.URL != "" {
+			return indent + "[" + label + "](" + c.URL + ")\n\n"
+		}
+		return indent + "[" + label + "]\n\n"
+	case "paragraph":
+		if text == "" {
+			return "\n"
+		}
+		return indent + text + "\n\n"
+	}
+	if text != "" {
+		return indent + text + "\n"
+	}
+	return ""
+}
+
+// plainText joins rich text runs, keeping links
+func plainText(runs []notionRichText) string {
+	var b strings.Builder
+	for _, r := range runs {
+		if r.Href != "" && r.Href != r.PlainText {
+			fmt.Fprintf(&b, "[%s](%s)", r.PlainText, r.Href)
+		} else {
+			b.WriteString(r.PlainText)
+		}
+	}
+	return b.String()
+}
+
+// do makes one API request, sending in and decoding the response into out
+// as JSON
+func (n *Notion) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(n.APIURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.Token)
+	req.Header.Set("Notion-Version", notionVersion)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.HTTP.Do(req)
+	if err != nil {
+		return types.Errorf(types.ErrUnavailable, "failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		detail := strings.TrimSpace(string(msg))
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Message != "" {
+			detail = apiErr.Message
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			// Pages not shared with the integration are not found too; the message says so
+			return types.Errorf(types.ErrNotFound, "Notion: %s", detail)
+		}
+		return statusError("Notion", resp, detail)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Notion response: %w", err)
+	}
+	return nil
+}
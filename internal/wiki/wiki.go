@@ -0,0 +1,49 @@
+// Package wiki reads pages from Confluence and Notion for the agent's docs
+// tools, so specs kept outside the repository can be searched and read.
+package wiki
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+// Page is a Confluence or Notion page
+type Page struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`
+	Space   string `json:"space,omitempty"` // Confluence space key
+	Updated string `json:"updated,omitempty"`
+	Body    string `json:"body,omitempty"`    // Set by Fetch
+	Format  string `json:"format,omitempty"`  // Body format: html (Confluence storage) or markdown
+	Excerpt string `json:"excerpt,omitempty"` // Set by Search when the source has one
+}
+
+// Source is a wiki the tools read from
+type Source interface {
+	// Search finds pages matching a query: free text, or CQL on Confluence
+	Search(ctx context.Context, query string, limit int) ([]Page, error)
+	// Fetch reads a page by URL or ID
+	Fetch(ctx context.Context, ref string) (*Page, error)
+}
+
+// httpClient is shared by the sources
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// statusError maps an HTTP error status of a wiki API to an error code
+func statusError(source string, resp *http.Response, detail string) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return types.Errorf(types.ErrNotFound, "%s: page not found", source)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return types.Errorf(types.ErrPermissionDenied, "%s: %s: %s", source, resp.Status, detail)
+	case http.StatusTooManyRequests:
+		return types.Errorf(types.ErrRateLimited, "%s rate limit exceeded (%s)", source, resp.Status)
+	case http.StatusBadRequest:
+		return types.Errorf(types.ErrInvalidArgument, "%s: %s", source, detail)
+	}
+	return types.Errorf(types.ErrUnavailable, "%s: %s: %s", source, resp.Status, detail)
+}
@@ -0,0 +1,105 @@
+package wiki
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neves/zen-claw/internal/types"
+)
+
+func TestConfluence(t *testing.T) {
+	var cql string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me@acme.com" || pass != "t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/wiki/rest/api/content/search":
+			cql = r.URL.Query().Get("cql")
+			w.Write([]byte(`{"results": [{"id": "12345", "title": "Export spec", "space": {"key": "ENG"}, "_links": {"webui": "/spaces/ENG/pages/12345/Export+spec"}}]}`))
+		case "/wiki/rest/api/content/12345":
+			w.Write([]byte(`{"id": "12345", "title": "Export spec", "body": {"storage": {"value": "<h2>Acceptance</h2><ul><li>CSV</li></ul>"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewConfluence(srv.URL+"/wiki/", "me@acme.com", "t0ken")
+	ctx := context.Background()
+	pages, err := c.Search(ctx, "export", 5)
+	if err != nil || len(pages) != 1 || pages[0].Space != "ENG" || pages[0].URL != srv.URL+"/wiki/spaces/ENG/pages/12345/Export+spec" {
+		t.Fatalf("Search = %+v, %v", pages, err)
+	}
+	if cql != `type = page AND text ~ "export"` {
+		t.Errorf("cql = %q", cql)
+	}
+
+	for _, ref := range []string{"12345", srv.URL + "/wiki/spaces/ENG/pages/12345/Export+spec", srv.URL + "/pages/viewpage.action?pageId=12345"} {
+		page, err := c.Fetch(ctx, ref)
+		if err != nil || page.Format != "html" || !strings.Contains(page.Body, "<h2>Acceptance</h2>") {
+			t.Errorf("Fetch(%q) = %+v, %v", ref, page, err)
+		}
+	}
+	if _, err := c.Fetch(ctx, "Export spec"); types.CodeOf(err) != types.ErrInvalidArgument {
+		t.Errorf("Fetch without ID: %v", err)
+	}
+	if _, err := c.Fetch(ctx, "999"); types.CodeOf(err) != types.ErrNotFound {
+		t.Errorf("missing page: %v", err)
+	}
+}
+
+func TestNotion(t *testing.T) {
+	const id = "1a2b3c4d5e6f70819293a4b5c6d7e8f9"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret_x" || r.Header.Get("Notion-Version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1/pages/"+id:
+			w.Write([]byte(`{"id": "` + id + `", "url": "https://www.notion.so/Export-` + id + `", "properties": {"Name": {"type": "title", "title": [{"plain_text": "Export spec"}]}}}`))
+		case r.URL.Path == "/v1/blocks/"+id+"/children" && r.URL.Query().Get("start_cursor") == "":
+			w.Write([]byte(`{"results": [
+				{"id": "b1", "type": "heading_2", "heading_2": {"rich_text": [{"plain_text": "Acceptance"}]}},
+				{"id": "b2", "type": "to_do", "has_children": true, "to_do": {"checked": true, "rich_text": [{"plain_text": "CSV"}]}},
+				{"id": "b3", "type": "numbered_list_item", "numbered_list_item": {"rich_text": [{"plain_text": "see "}, {"plain_text": "RFC", "href": "https://x/rfc"}]}}
+			], "has_more": true, "next_cursor": "c2"}`))
+		case r.URL.Path == "/v1/blocks/"+id+"/children":
+			w.Write([]byte(`{"results": [{"id": "b4", "type": "code", "code": {"language": "sql", "rich_text": [{"plain_text": "SELECT 1"}]}}], "has_more": false}`))
+		case r.URL.Path == "/v1/blocks/b2/children":
+			w.Write([]byte(`{"results": [{"id": "b5", "type": "bulleted_list_item", "bulleted_list_item": {"rich_text": [{"plain_text": "UTF-8 with BOM"}]}}]}`))
+		case r.URL.Path == "/v1/search":
+			w.Write([]byte(`{"results": [{"id": "` + id + `", "properties": {"title": {"type": "title", "title": [{"plain_text": "Export spec"}]}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Could not find page. Make sure the relevant pages and databases are shared with your integration."}`))
+		}
+	}))
+	defer srv.Close()
+
+	n := NewNotion("secret_x")
+	n.APIURL = srv.URL
+	ctx := context.Background()
+	page, err := n.Fetch(ctx, "https://www.notion.so/acme/Export-spec-"+id+"?pvs=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Acceptance\n\n- [x] CSV\n  - UTF-8 with BOM\n1. see [RFC](https://x/rfc)\n```sql\nSELECT 1\n```"
+	if page.Title != "Export spec" || page.Format != "markdown" || page.Body != want {
+		t.Errorf("Fetch = %q (title %q), want %q", page.Body, page.Title, want)
+	}
+
+	pages, err := n.Search(ctx, "export", 5)
+	if err != nil || len(pages) != 1 || pages[0].Title != "Export spec" {
+		t.Errorf("Search = %+v, %v", pages, err)
+	}
+	_, err = n.Fetch(ctx, "ffffffffffffffffffffffffffffffff")
+	if types.CodeOf(err) != types.ErrNotFound || !strings.Contains(err.Error(), "shared with your integration") {
+		t.Errorf("unshared page: %v", err)
+	}
+}